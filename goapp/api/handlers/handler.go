@@ -4,12 +4,14 @@ import (
 	"goapp/internal/container"
 	"goapp/internal/db/postgres"
 	"goapp/internal/logging"
+	"goapp/internal/tokens"
 )
 
 // Handler contains all dependencies for HTTP handlers
 type Handler struct {
 	Logger   logging.Logger
 	Database postgres.Database
+	JWTKeys  *tokens.KeySet
 }
 
 // New creates a new handler with injected dependencies
@@ -17,5 +19,6 @@ func New(container *container.Container) *Handler {
 	return &Handler{
 		Logger:   container.Logger,
 		Database: container.Database,
+		JWTKeys:  container.JWTKeys,
 	}
 }
\ No newline at end of file
@@ -0,0 +1,259 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/api/middleware"
+	"goapp/internal/apikeys"
+	"goapp/internal/auth"
+	"goapp/internal/authz"
+	"goapp/internal/container"
+	"goapp/internal/logging"
+	"goapp/internal/models"
+)
+
+// CreateAPIKeyRequest describes a new API key
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required"` // e.g. ["posts:read"], ["posts:*"] or ["*"]
+	// ExpiresIn is the key lifetime in seconds; omitted or 0 uses the server default
+	ExpiresIn int64 `json:"expires_in"`
+	// ServiceAccount creates the key for the named service account instead of the caller (admins only)
+	ServiceAccount string `json:"service_account"`
+}
+
+// APIKeyResponse describes an API key without its secret
+type APIKeyResponse struct {
+	ID             uint       `json:"id"`
+	Name           string     `json:"name"`
+	KeyID          string     `json:"key_id"`
+	Owner          string     `json:"owner"`
+	ServiceAccount bool       `json:"service_account"`
+	Scopes         []string   `json:"scopes"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP     string     `json:"last_used_ip,omitempty"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreateAPIKeyResponse carries a new key; the key is only ever shown once
+type CreateAPIKeyResponse struct {
+	Key    string         `json:"key"`
+	APIKey APIKeyResponse `json:"api_key"`
+}
+
+// NewAPIKeyResponse describes key, whose User must be loaded
+func NewAPIKeyResponse(key *models.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:             key.ID,
+		Name:           key.Name,
+		KeyID:          key.KeyID,
+		Owner:          key.User.Username,
+		ServiceAccount: key.User.ServiceAccount,
+		Scopes:         key.ScopeList(),
+		CreatedAt:      key.CreatedAt,
+		ExpiresAt:      key.ExpiresAt,
+		LastUsedAt:     key.LastUsedAt,
+		LastUsedIP:     key.LastUsedIP,
+		RevokedAt:      key.RevokedAt,
+	}
+}
+
+// APIKeyHandler lets users manage their API keys and admins all keys
+type APIKeyHandler struct {
+	Logger logging.Logger
+	Keys   apikeys.Service
+}
+
+// NewAPIKeyHandler creates a new API key handler with injected dependencies
+func NewAPIKeyHandler(container *container.Container) *APIKeyHandler {
+	return &APIKeyHandler{
+		Logger: container.Logger,
+		Keys:   container.APIKeys,
+	}
+}
+
+// RegisterRoutes registers the API key routes; they are disabled without a database
+func (h *APIKeyHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	if h.Keys == nil {
+		return
+	}
+
+	group := rg.Group("/api-keys")
+	group.GET("", h.List)
+	group.POST("", h.Create)
+	group.DELETE("/:id", h.Revoke)
+}
+
+// List godoc
+// @Summary List API keys
+// @Description List the caller's API keys, or with all=true every key (admins only)
+// @Tags v1,api-keys
+// @Produce json
+// @Security BearerAuth
+// @Param all query bool false "List the keys of all users"
+// @Success 200 {array} v1.APIKeyResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/api-keys [get]
+func (h *APIKeyHandler) List(c *gin.Context) {
+	// Listing your keys means reading keys you own
+	own := &models.APIKey{}
+	if user := middleware.CurrentUser(c); user != nil {
+		own.UserID = user.ID
+	}
+	if !middleware.Authorize(c, authz.ActionRead, own) {
+		return
+	}
+
+	userID := own.UserID
+	if c.Query("all") == "true" {
+		if !middleware.Authorize(c, authz.ActionRead, authz.Type(authz.ResourceAPIKeys)) {
+			return
+		}
+		userID = 0
+	}
+
+	keys, err := h.Keys.List(c.Request.Context(), userID)
+	if err != nil {
+		h.Logger.Error("Failed to list API keys", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to list API keys"))
+		return
+	}
+
+	response := make([]APIKeyResponse, len(keys))
+	for i := range keys {
+		response[i] = NewAPIKeyResponse(&keys[i])
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Create godoc
+// @Summary Create API key
+// @Description Create an API key for the caller or, for admins, a service account. The key is only returned once. When the caller uses an API key, the new key cannot have wider scopes or outlive it.
+// @Tags v1,api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body v1.CreateAPIKeyRequest true "API key"
+// @Success 201 {object} v1.CreateAPIKeyResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/api-keys [post]
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	if !middleware.Authorize(c, authz.ActionCreate, authz.Type(authz.ResourceAPIKeys)) {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "name and scopes are required"))
+		return
+	}
+
+	// A key cannot be used to mint a more powerful or longer-lived one
+	current := middleware.CurrentAPIKey(c)
+	if current != nil {
+		for _, scope := range req.Scopes {
+			if !current.HasScope(scope) {
+				_ = c.Error(middleware.NewHTTPError(http.StatusForbidden, "scopes exceed those of the calling API key"))
+				return
+			}
+		}
+	}
+
+	owner := middleware.CurrentUser(c)
+	if req.ServiceAccount != "" {
+		if !middleware.Authorize(c, authz.ActionCreate, authz.Type(authz.ResourceServiceAccounts)) {
+			return
+		}
+		account, err := h.Keys.ServiceAccount(c.Request.Context(), req.ServiceAccount)
+		if err != nil {
+			h.fail(c, err, "failed to create service account")
+			return
+		}
+		owner = account
+	}
+
+	input := apikeys.CreateInput{
+		Name:      req.Name,
+		UserID:    owner.ID,
+		Scopes:    req.Scopes,
+		ExpiresIn: time.Duration(req.ExpiresIn) * time.Second,
+	}
+	if current != nil {
+		input.NotAfter = current.ExpiresAt
+	}
+	token, key, err := h.Keys.Create(c.Request.Context(), input)
+	if err != nil {
+		h.fail(c, err, "failed to create API key")
+		return
+	}
+	key.User = *owner
+
+	h.Logger.Info("API key created",
+		zap.String("key_id", key.KeyID),
+		zap.String("owner", owner.Username),
+		zap.Uint("created_by", middleware.CurrentUser(c).ID))
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, CreateAPIKeyResponse{Key: token, APIKey: NewAPIKeyResponse(key)})
+}
+
+// Revoke godoc
+// @Summary Revoke API key
+// @Description Revoke one of the caller's API keys, or any key for admins
+// @Tags v1,api-keys
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/api-keys/{id} [delete]
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusNotFound, "API key not found"))
+		return
+	}
+
+	key, err := h.Keys.Get(c.Request.Context(), uint(id))
+	if errors.Is(err, apikeys.ErrKeyNotFound) {
+		_ = c.Error(middleware.NewHTTPError(http.StatusNotFound, "API key not found"))
+		return
+	}
+	if err != nil {
+		h.Logger.Error("Failed to load API key", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to revoke API key"))
+		return
+	}
+	if !middleware.Authorize(c, authz.ActionDelete, key) {
+		return
+	}
+
+	if err := h.Keys.Revoke(c.Request.Context(), key.ID); err != nil {
+		h.Logger.Error("Failed to revoke API key", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to revoke API key"))
+		return
+	}
+	h.Logger.Info("API key revoked", zap.String("key_id", key.KeyID), zap.Uint("revoked_by", middleware.CurrentUser(c).ID))
+	c.Status(http.StatusNoContent)
+}
+
+// fail reports validation errors as 400 and anything else as 500
+func (h *APIKeyHandler) fail(c *gin.Context, err error, message string) {
+	var validationErr *auth.ValidationError
+	if errors.As(err, &validationErr) {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, validationErr.Error()))
+		return
+	}
+	h.Logger.Error("API key request failed", zap.Error(err))
+	_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, message))
+}
@@ -0,0 +1,175 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goapp/api/middleware"
+	"goapp/internal/apikeys"
+	"goapp/internal/authz"
+	"goapp/internal/config"
+	"goapp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupAPIKeyRouter returns the router and an all-scopes key for each of
+// jane, bob and the admin, plus a key of jane's limited to managing keys
+func setupAPIKeyRouter(t *testing.T) (*gin.Engine, map[string]string) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Permission{}, &models.Role{}, &models.User{}, &models.APIKey{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	roles := authz.NewRoleStore(db)
+	if err := roles.Seed(ctx); err != nil {
+		t.Fatalf("Seed() error = %v", err)
+	}
+
+	c := setupTestContainer(t)
+	c.APIKeys = apikeys.NewService(db, config.APIKeyConfig{TokenPrefix: "goapp", DefaultExpiration: time.Hour})
+
+	keys := map[string]string{}
+	for _, name := range []string{"jane", "bob", "admin"} {
+		user := &models.User{Email: name + "@example.com", Username: name, PasswordHash: "hash", Active: true}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		if name == "admin" {
+			if err := roles.Grant(ctx, user.ID, authz.RoleAdmin); err != nil {
+				t.Fatalf("Grant() error = %v", err)
+			}
+		}
+		token, _, err := c.APIKeys.Create(ctx, apikeys.CreateInput{Name: "bootstrap", UserID: user.ID, Scopes: []string{"*"}})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		keys[name] = token
+		if name == "jane" {
+			limited, _, err := c.APIKeys.Create(ctx, apikeys.CreateInput{Name: "limited", UserID: user.ID, Scopes: []string{"api_keys:*"}})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			keys["jane-limited"] = limited
+		}
+	}
+
+	router := gin.New()
+	group := router.Group("/api/v1",
+		middleware.JSONErrors(),
+		middleware.APIKeyAuth(c.APIKeys, c.Logger),
+		middleware.Policies(authz.DefaultPolicy()))
+	NewAPIKeyHandler(c).RegisterRoutes(group)
+	return router, keys
+}
+
+func TestAPIKeyHandler(t *testing.T) {
+	router, keys := setupAPIKeyRouter(t)
+
+	w := sendJSON(router, http.MethodPost, "/api/v1/api-keys", `{"name":"reporting","scopes":["posts:read"]}`, keys["jane"])
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created CreateAPIKeyResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if created.Key == "" || created.APIKey.Owner != "jane" || created.APIKey.ExpiresAt == nil {
+		t.Errorf("Unexpected create response %+v", created)
+	}
+
+	t.Run("List", func(t *testing.T) {
+		w := sendJSON(router, http.MethodGet, "/api/v1/api-keys", "", keys["jane"])
+		var list []APIKeyResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("Expected key list, got %d: %s", w.Code, w.Body.String())
+		}
+		if len(list) != 3 {
+			t.Errorf("Expected jane's 3 keys, got %d", len(list))
+		}
+
+		if w := sendJSON(router, http.MethodGet, "/api/v1/api-keys?all=true", "", keys["jane"]); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d listing all keys as a user, got %d", http.StatusForbidden, w.Code)
+		}
+		w = sendJSON(router, http.MethodGet, "/api/v1/api-keys?all=true", "", keys["admin"])
+		if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list) != 5 {
+			t.Errorf("Expected admin to list all 5 keys, got %d: %s", len(list), w.Body.String())
+		}
+	})
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		if w := sendJSON(router, http.MethodGet, "/api/v1/api-keys", "", ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		w := sendJSON(router, http.MethodPost, "/api/v1/api-keys", `{"name":"bad","scopes":["everything"]}`, keys["jane"])
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("ScopeEscalation", func(t *testing.T) {
+		w := sendJSON(router, http.MethodPost, "/api/v1/api-keys", `{"name":"wide","scopes":["*"]}`, keys["jane-limited"])
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d", http.StatusForbidden, w.Code)
+		}
+		w = sendJSON(router, http.MethodPost, "/api/v1/api-keys", `{"name":"narrow","scopes":["api_keys:read"]}`, keys["jane-limited"])
+		var narrow CreateAPIKeyResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &narrow); err != nil || w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+
+		// The new key expires no later than the key that created it
+		var list []APIKeyResponse
+		json.Unmarshal(sendJSON(router, http.MethodGet, "/api/v1/api-keys", "", keys["jane"]).Body.Bytes(), &list)
+		for _, key := range list {
+			if key.Name == "limited" && (narrow.APIKey.ExpiresAt == nil || !narrow.APIKey.ExpiresAt.Equal(*key.ExpiresAt)) {
+				t.Errorf("Expected expiry %v, got %v", key.ExpiresAt, narrow.APIKey.ExpiresAt)
+			}
+		}
+	})
+
+	t.Run("ServiceAccount", func(t *testing.T) {
+		body := `{"name":"billing","scopes":["posts:read"],"service_account":"billing"}`
+		if w := sendJSON(router, http.MethodPost, "/api/v1/api-keys", body, keys["jane"]); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d for a user, got %d", http.StatusForbidden, w.Code)
+		}
+
+		w := sendJSON(router, http.MethodPost, "/api/v1/api-keys", body, keys["admin"])
+		var created CreateAPIKeyResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+		if created.APIKey.Owner != "billing" || !created.APIKey.ServiceAccount {
+			t.Errorf("Expected key owned by service account billing, got %+v", created.APIKey)
+		}
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		path := fmt.Sprintf("/api/v1/api-keys/%d", created.APIKey.ID)
+		if w := sendJSON(router, http.MethodDelete, path, "", keys["bob"]); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d revoking another user's key, got %d", http.StatusForbidden, w.Code)
+		}
+		if w := sendJSON(router, http.MethodDelete, path, "", keys["jane"]); w.Code != http.StatusNoContent {
+			t.Errorf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+		}
+		if w := sendJSON(router, http.MethodGet, "/api/v1/api-keys", "", created.Key); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected revoked key to be rejected, got %d", w.Code)
+		}
+		if w := sendJSON(router, http.MethodDelete, "/api/v1/api-keys/999", "", keys["jane"]); w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
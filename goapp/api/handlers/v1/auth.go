@@ -0,0 +1,225 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/container"
+	"goapp/internal/logging"
+	"goapp/internal/tokens"
+	"goapp/internal/twofactor"
+)
+
+// TokenRequest exchanges user credentials for a token pair
+type TokenRequest struct {
+	Identifier string `json:"identifier" binding:"required"` // email or username
+	Password   string `json:"password" binding:"required"`
+	OTP        string `json:"otp,omitempty"` // TOTP or recovery code; required when two-factor authentication is on
+}
+
+// RefreshRequest carries a refresh token
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// MeResponse describes the authenticated token subject
+type MeResponse struct {
+	UserID    uint   `json:"user_id"`
+	Username  string `json:"username"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// AuthHandler issues and refreshes JWT bearer tokens
+type AuthHandler struct {
+	Logger    logging.Logger
+	Auth      auth.Service
+	Tokens    tokens.Service
+	TwoFactor twofactor.Service
+}
+
+// NewAuthHandler creates a new token handler with injected dependencies
+func NewAuthHandler(container *container.Container) *AuthHandler {
+	return &AuthHandler{
+		Logger:    container.Logger,
+		Auth:      container.Auth,
+		Tokens:    container.Tokens,
+		TwoFactor: container.TwoFactor,
+	}
+}
+
+// RegisterRoutes registers the token routes; they are disabled without JWT keys or a database
+func (h *AuthHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	if h.Auth == nil || h.Tokens == nil {
+		return
+	}
+
+	group := rg.Group("/auth")
+	group.POST("/token", h.Token)
+	group.POST("/refresh", h.Refresh)
+	group.POST("/revoke", h.Revoke)
+	group.GET("/me", middleware.RequireJWT(), h.Me)
+}
+
+// Token godoc
+// @Summary Issue tokens
+// @Description Exchange an email or username and password for an access and refresh token. Users with two-factor authentication must also send a current code as otp.
+// @Tags v1,auth
+// @Accept json
+// @Produce json
+// @Param request body v1.TokenRequest true "Credentials"
+// @Success 200 {object} tokens.Pair
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /api/v1/auth/token [post]
+func (h *AuthHandler) Token(c *gin.Context) {
+	var req TokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "identifier and password are required"))
+		return
+	}
+
+	user, err := h.Auth.Authenticate(c.Request.Context(), req.Identifier, req.Password, auth.SessionMeta{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if errors.Is(err, auth.ErrInvalidCredentials) || errors.Is(err, auth.ErrInactiveUser) {
+		_ = c.Error(middleware.NewHTTPError(http.StatusUnauthorized, "invalid credentials"))
+		return
+	}
+	if errors.Is(err, auth.ErrAccountLocked) {
+		_ = c.Error(middleware.NewHTTPError(http.StatusTooManyRequests, "too many failed sign-in attempts"))
+		return
+	}
+	if err != nil {
+		h.Logger.Error("Failed to authenticate user", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to issue tokens"))
+		return
+	}
+	if !h.verifySecondFactor(c, user.ID, user.TwoFactorEnabled, req.OTP) {
+		return
+	}
+
+	pair, err := h.Tokens.Issue(c.Request.Context(), user)
+	if err != nil {
+		h.Logger.Error("Failed to issue tokens", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to issue tokens"))
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, pair)
+}
+
+// verifySecondFactor checks otp for users with two-factor authentication
+// and reports an error response when it fails
+func (h *AuthHandler) verifySecondFactor(c *gin.Context, userID uint, enabled bool, otp string) bool {
+	if !enabled || h.TwoFactor == nil {
+		return true
+	}
+	if otp == "" {
+		_ = c.Error(middleware.NewHTTPError(http.StatusUnauthorized, "two-factor code required"))
+		return false
+	}
+
+	err := h.TwoFactor.Verify(c.Request.Context(), userID, otp)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, twofactor.ErrInvalidCode):
+		_ = c.Error(middleware.NewHTTPError(http.StatusUnauthorized, "invalid two-factor code"))
+	case errors.Is(err, twofactor.ErrTooManyAttempts):
+		_ = c.Error(middleware.NewHTTPError(http.StatusTooManyRequests, "too many failed two-factor attempts"))
+	default:
+		h.Logger.Error("Failed to verify two-factor code", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to issue tokens"))
+	}
+	return false
+}
+
+// Refresh godoc
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new token pair. Refresh tokens are single-use; reusing one revokes all tokens issued from the same login.
+// @Tags v1,auth
+// @Accept json
+// @Produce json
+// @Param request body v1.RefreshRequest true "Refresh token"
+// @Success 200 {object} tokens.Pair
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /api/v1/auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "refresh_token is required"))
+		return
+	}
+
+	pair, err := h.Tokens.Refresh(c.Request.Context(), req.RefreshToken)
+	switch {
+	case errors.Is(err, tokens.ErrRefreshTokenReused):
+		h.Logger.Warn("Refresh token reuse detected, token family revoked", zap.String("client_ip", c.ClientIP()))
+		_ = c.Error(middleware.NewHTTPError(http.StatusUnauthorized, "refresh token reuse detected"))
+		return
+	case errors.Is(err, tokens.ErrInvalidRefreshToken):
+		_ = c.Error(middleware.NewHTTPError(http.StatusUnauthorized, "invalid refresh token"))
+		return
+	case err != nil:
+		h.Logger.Error("Failed to refresh tokens", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to refresh tokens"))
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, pair)
+}
+
+// Revoke godoc
+// @Summary Revoke tokens
+// @Description Revoke a refresh token and all tokens issued from the same login
+// @Tags v1,auth
+// @Accept json
+// @Param request body v1.RefreshRequest true "Refresh token"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/auth/revoke [post]
+func (h *AuthHandler) Revoke(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "refresh_token is required"))
+		return
+	}
+
+	// Unknown tokens are not an error (RFC 7009): the outcome is the same
+	if err := h.Tokens.Revoke(c.Request.Context(), req.RefreshToken); err != nil && !errors.Is(err, tokens.ErrInvalidRefreshToken) {
+		h.Logger.Error("Failed to revoke tokens", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to revoke tokens"))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Me godoc
+// @Summary Current token subject
+// @Description Describe the user the access token was issued to
+// @Tags v1,auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} v1.MeResponse
+// @Failure 401 {object} map[string]string
+// @Router /api/v1/auth/me [get]
+func (h *AuthHandler) Me(c *gin.Context) {
+	claims := middleware.TokenClaims(c)
+	userID, _ := claims.UserID()
+
+	c.JSON(http.StatusOK, MeResponse{
+		UserID:    userID,
+		Username:  claims.Username,
+		ExpiresAt: claims.ExpiresAt.Unix(),
+	})
+}
@@ -0,0 +1,148 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/config"
+	"goapp/internal/models"
+	"goapp/internal/tokens"
+	"goapp/internal/twofactor"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupAuthRouter(t *testing.T) (*gin.Engine, twofactor.Service) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.TwoFactor{}, &models.RecoveryCode{}, &models.LoginEvent{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	c := setupTestContainer(t)
+	authCfg := config.AuthConfig{PasswordHasher: auth.HasherBcrypt, BcryptCost: bcrypt.MinCost, MinPasswordLength: 8}
+	hasher, _ := auth.NewHasher(authCfg)
+	c.Auth = auth.NewService(db, hasher, authCfg)
+	if _, err := c.Auth.Register(context.Background(), auth.RegisterInput{Email: "jane@example.com", Username: "jane", Password: "s3cret-password"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	jwtCfg := config.JWTConfig{Issuer: "goapp", Audience: "goapp-api", Expiration: 15 * time.Minute, RefreshExpiration: time.Hour}
+	keys, err := tokens.NewKeySet([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewKeySet() error = %v", err)
+	}
+	c.Tokens = tokens.NewService(db, keys, jwtCfg)
+	c.TwoFactor = twofactor.NewService(db, config.TwoFactorConfig{Issuer: "goapp", MaxAttempts: 5, LockDuration: time.Minute, RecoveryCodes: 2})
+
+	router := gin.New()
+	group := router.Group("/api/v1", middleware.JSONErrors(), middleware.JWTAuth(c.Tokens, c.Tokens, c.Logger))
+	NewAuthHandler(c).RegisterRoutes(group)
+	return router, c.TwoFactor
+}
+
+func sendJSON(router *gin.Engine, method, path, body, bearer string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthHandler(t *testing.T) {
+	router, _ := setupAuthRouter(t)
+
+	t.Run("InvalidCredentials", func(t *testing.T) {
+		w := sendJSON(router, http.MethodPost, "/api/v1/auth/token", `{"identifier":"jane","password":"wrong-password"}`, "")
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+	})
+
+	w := sendJSON(router, http.MethodPost, "/api/v1/auth/token", `{"identifier":"jane","password":"s3cret-password"}`, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var pair tokens.Pair
+	if err := json.Unmarshal(w.Body.Bytes(), &pair); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	t.Run("Me", func(t *testing.T) {
+		w := sendJSON(router, http.MethodGet, "/api/v1/auth/me", "", pair.AccessToken)
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"username":"jane"`) {
+			t.Errorf("Unexpected response %d: %s", w.Code, w.Body.String())
+		}
+
+		if w := sendJSON(router, http.MethodGet, "/api/v1/auth/me", "", ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status %d without token, got %d", http.StatusUnauthorized, w.Code)
+		}
+	})
+
+	t.Run("RefreshAndReuse", func(t *testing.T) {
+		body := `{"refresh_token":"` + pair.RefreshToken + `"}`
+		if w := sendJSON(router, http.MethodPost, "/api/v1/auth/refresh", body, ""); w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+
+		w := sendJSON(router, http.MethodPost, "/api/v1/auth/refresh", body, "")
+		if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "reuse") {
+			t.Errorf("Expected reuse to be rejected, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		w := sendJSON(router, http.MethodPost, "/api/v1/auth/revoke", `{"refresh_token":"unknown"}`, "")
+		if w.Code != http.StatusNoContent {
+			t.Errorf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+		}
+	})
+}
+
+func TestAuthHandler_TwoFactor(t *testing.T) {
+	router, twoFactor := setupAuthRouter(t)
+	ctx := context.Background()
+
+	user := &models.User{Email: "jane@example.com"}
+	user.ID = 1
+	enrollment, err := twoFactor.Begin(ctx, user)
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	code, _ := twofactor.Code(enrollment.Secret, time.Now())
+	codes, err := twoFactor.Confirm(ctx, 1, code)
+	if err != nil {
+		t.Fatalf("Confirm() error = %v", err)
+	}
+
+	w := sendJSON(router, http.MethodPost, "/api/v1/auth/token", `{"identifier":"jane","password":"s3cret-password"}`, "")
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "two-factor code required") {
+		t.Errorf("Expected code to be required, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = sendJSON(router, http.MethodPost, "/api/v1/auth/token", `{"identifier":"jane","password":"s3cret-password","otp":"000000"}`, "")
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "invalid two-factor code") {
+		t.Errorf("Expected invalid code to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = sendJSON(router, http.MethodPost, "/api/v1/auth/token", `{"identifier":"jane","password":"s3cret-password","otp":"`+codes[0]+`"}`, "")
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected recovery code to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+}
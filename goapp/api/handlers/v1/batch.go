@@ -0,0 +1,196 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"goapp/api/middleware"
+	"goapp/internal/container"
+	"goapp/internal/logging"
+)
+
+// batchPath is where the batch endpoint is mounted; batches cannot nest
+const batchPath = "/api/" + Version + "/batch"
+
+// batchMethods are the methods sub-requests may use
+var batchMethods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// batchRequestHeaders are the headers a sub-request may set; credentials and
+// the client address always come from the batch request
+var batchRequestHeaders = map[string]bool{
+	"Accept":          true,
+	"If-Match":        true,
+	"If-None-Match":   true,
+	"Idempotency-Key": true,
+}
+
+// batchResponseHeaders are the headers of sub-responses passed back to the client
+var batchResponseHeaders = []string{"Location", "ETag", "Link", "Retry-After"}
+
+// BatchOperation is one sub-request of a batch
+type BatchOperation struct {
+	ID      string            `json:"id"` // echoed in the result, to match results to requests
+	Method  string            `json:"method" binding:"required"`
+	Path    string            `json:"path" binding:"required"` // e.g. /api/v1/posts?limit=5
+	Headers map[string]string `json:"headers"`                 // only Accept, If-Match, If-None-Match and Idempotency-Key
+	Body    json.RawMessage   `json:"body" swaggertype:"object"`
+}
+
+// BatchRequest lists the sub-requests to run, in order
+type BatchRequest struct {
+	Requests []BatchOperation `json:"requests" binding:"required"`
+}
+
+// BatchResult is the response to one sub-request
+type BatchResult struct {
+	ID      string            `json:"id,omitempty"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty" swaggertype:"object"`
+}
+
+// BatchResponse lists the results in request order
+type BatchResponse struct {
+	Responses []BatchResult `json:"responses"`
+}
+
+// BatchHandler runs several API requests in one round-trip
+type BatchHandler struct {
+	Logger      logging.Logger
+	Handler     http.Handler // the router sub-requests are sent to
+	MaxRequests int
+}
+
+// NewBatchHandler creates a new batch handler sending sub-requests to router
+func NewBatchHandler(container *container.Container, router http.Handler) *BatchHandler {
+	return &BatchHandler{
+		Logger:      container.Logger,
+		Handler:     router,
+		MaxRequests: container.Config.Bulk.MaxBatchRequests,
+	}
+}
+
+// RegisterRoutes registers the batch route
+func (h *BatchHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.POST("/batch", h.Batch)
+}
+
+// Batch godoc
+// @Summary Batch requests
+// @Description Run several v1 API requests in one round-trip. They run one after the other through the same middleware as separate requests, except load shedding, with the credentials of the batch request, and each gets its own status. They do not share a transaction; use the bulk endpoints for that.
+// @Tags v1,bulk
+// @Accept json
+// @Produce json
+// @Param request body v1.BatchRequest true "Sub-requests"
+// @Success 200 {object} v1.BatchResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/batch [post]
+func (h *BatchHandler) Batch(c *gin.Context) {
+	// Sub-requests are marked with middleware.WithSubRequest; they may not run batches
+	if middleware.IsSubRequest(c.Request.Context()) {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "batches cannot be nested"))
+		return
+	}
+
+	var req BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "requests is required"))
+		return
+	}
+	if len(req.Requests) == 0 || len(req.Requests) > h.MaxRequests {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("between 1 and %d requests are allowed", h.MaxRequests)))
+		return
+	}
+
+	response := BatchResponse{Responses: make([]BatchResult, len(req.Requests))}
+	for i, op := range req.Requests {
+		response.Responses[i] = h.serve(c, op)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// serve runs one sub-request through the router
+func (h *BatchHandler) serve(c *gin.Context, op BatchOperation) BatchResult {
+	method := strings.ToUpper(op.Method)
+	// Paths are checked decoded and cleaned, as the router sees them
+	u, err := url.Parse("/" + strings.TrimLeft(op.Path, "/"))
+	switch {
+	case !batchMethods[method]:
+		return batchError(op, http.StatusBadRequest, "unsupported method")
+	case err != nil || u.Host != "" || u.Fragment != "" || strings.Contains(op.Path, "#"):
+		return batchError(op, http.StatusBadRequest, "invalid path")
+	}
+	u.Path, u.RawPath = path.Clean(u.Path), ""
+	switch {
+	case !strings.HasPrefix(u.Path, "/api/"+Version+"/"):
+		return batchError(op, http.StatusBadRequest, "path must be under /api/"+Version)
+	case u.Path == batchPath:
+		return batchError(op, http.StatusBadRequest, "batches cannot be nested")
+	}
+	for name := range op.Headers {
+		if !batchRequestHeaders[http.CanonicalHeaderKey(name)] {
+			return batchError(op, http.StatusBadRequest, fmt.Sprintf("header %s is not allowed", http.CanonicalHeaderKey(name)))
+		}
+	}
+
+	var body []byte
+	if len(op.Body) > 0 && string(op.Body) != "null" {
+		body = op.Body
+	}
+	// The batch holds a load shedding slot, which its sub-requests share
+	sub, err := http.NewRequestWithContext(middleware.WithSubRequest(c.Request.Context()), method, u.RequestURI(), bytes.NewReader(body))
+	if err != nil {
+		return batchError(op, http.StatusBadRequest, "invalid path")
+	}
+	// Sub-requests carry the batch's credentials and client address
+	sub.Header = c.Request.Header.Clone()
+	for _, name := range []string{"Content-Length", "Content-Type", "Accept-Encoding", "Idempotency-Key"} {
+		sub.Header.Del(name)
+	}
+	if body != nil {
+		sub.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range op.Headers {
+		sub.Header.Set(name, value)
+	}
+	sub.RemoteAddr = c.Request.RemoteAddr
+
+	recorder := httptest.NewRecorder()
+	h.Handler.ServeHTTP(recorder, sub)
+
+	result := BatchResult{ID: op.ID, Status: recorder.Code}
+	for _, name := range batchResponseHeaders {
+		if value := recorder.Header().Get(name); value != "" {
+			if result.Headers == nil {
+				result.Headers = map[string]string{}
+			}
+			result.Headers[name] = value
+		}
+	}
+	if out := recorder.Body.Bytes(); len(out) > 0 {
+		if json.Valid(out) {
+			result.Body = out
+		} else {
+			result.Body, _ = json.Marshal(string(out))
+		}
+	}
+	return result
+}
+
+func batchError(op BatchOperation, status int, message string) BatchResult {
+	body, _ := json.Marshal(gin.H{"error": message})
+	return BatchResult{ID: op.ID, Status: status, Body: body}
+}
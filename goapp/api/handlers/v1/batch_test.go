@@ -0,0 +1,98 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"goapp/api/middleware"
+)
+
+func TestBatchHandler(t *testing.T) {
+	router, keys := setupPostRouter(t)
+
+	body := `{"requests":[
+		{"id":"list","method":"GET","path":"/api/v1/posts?limit=1"},
+		{"id":"create","method":"POST","path":"/api/v1/posts","body":{"title":"Batched Post"}},
+		{"id":"missing","method":"delete","path":"/api/v1/posts/missing"},
+		{"id":"nested","method":"POST","path":"/api/v1/batch","body":{"requests":[]}},
+		{"id":"outside","method":"GET","path":"/health"}
+	]}`
+	w := sendJSON(router, http.MethodPost, "/api/v1/batch", body, keys["jane"])
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp BatchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	want := map[string]int{
+		"list":    http.StatusOK,
+		"create":  http.StatusCreated,
+		"missing": http.StatusNotFound,
+		"nested":  http.StatusBadRequest,
+		"outside": http.StatusBadRequest,
+	}
+	if len(resp.Responses) != len(want) {
+		t.Fatalf("Expected %d responses, got %d", len(want), len(resp.Responses))
+	}
+	for _, result := range resp.Responses {
+		if result.Status != want[result.ID] {
+			t.Errorf("Expected %s to answer %d, got %d: %s", result.ID, want[result.ID], result.Status, result.Body)
+		}
+	}
+
+	created := resp.Responses[1]
+	var post PostResponse
+	if err := json.Unmarshal(created.Body, &post); err != nil || post.Author.Username != "jane" {
+		t.Errorf("Expected the post to be written by the batch's caller, got %s", created.Body)
+	}
+	if !strings.HasPrefix(created.Headers["Location"], "/api/v1/posts/") {
+		t.Errorf("Expected the Location header to be passed back, got %v", created.Headers)
+	}
+
+	// Encoded, dotted and fragment paths to the batch endpoint are refused too
+	nestedBody := `{"requests":[{"method":"GET","path":"/api/v1/posts"}]}`
+	for _, path := range []string{"/api/v1/%62atch", "/api/v1/batch#x", "/api/v1/posts/../batch", "api/v1/batch/"} {
+		w := sendJSON(router, http.MethodPost, "/api/v1/batch", `{"requests":[{"method":"POST","path":"`+path+`","body":`+nestedBody+`}]}`, keys["jane"])
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || len(resp.Responses) != 1 || resp.Responses[0].Status != http.StatusBadRequest {
+			t.Errorf("Expected %q not to run a nested batch, got %s", path, w.Body.String())
+		}
+	}
+
+	// Sub-requests cannot re-enter the batch handler, however they reach it
+	sub, _ := http.NewRequestWithContext(middleware.WithSubRequest(context.Background()), http.MethodPost, "/api/v1/batch", strings.NewReader(nestedBody))
+	sub.Header.Set("Content-Type", "application/json")
+	sub.Header.Set("Authorization", "Bearer "+keys["jane"])
+	nested := httptest.NewRecorder()
+	router.ServeHTTP(nested, sub)
+	if nested.Code != http.StatusBadRequest {
+		t.Errorf("Expected nested batches to be refused, got %d", nested.Code)
+	}
+
+	// Sub-requests may only set per-operation headers, not credentials or the client address
+	for _, header := range []string{"Authorization", "cookie", "X-Forwarded-For", "X-Real-IP"} {
+		w := sendJSON(router, http.MethodPost, "/api/v1/batch", `{"requests":[{"method":"GET","path":"/api/v1/posts","headers":{"`+header+`":"Bearer `+keys["bob"]+`"}}]}`, keys["jane"])
+		if !strings.Contains(w.Body.String(), `"status":400`) || !strings.Contains(w.Body.String(), "is not allowed") {
+			t.Errorf("Expected header %s to be refused, got %s", header, w.Body.String())
+		}
+	}
+	w = sendJSON(router, http.MethodPost, "/api/v1/batch", `{"requests":[{"method":"GET","path":"/api/v1/posts","headers":{"if-none-match":"\"x\"","Accept":"application/json"}}]}`, keys["jane"])
+	if !strings.Contains(w.Body.String(), `"status":200`) {
+		t.Errorf("Expected per-operation headers to be allowed, got %s", w.Body.String())
+	}
+
+	w = sendJSON(router, http.MethodPost, "/api/v1/batch", `{"requests":[{"method":"POST","path":"/api/v1/posts","body":{"title":"Anonymous"}}]}`, "")
+	if !strings.Contains(w.Body.String(), `"status":401`) {
+		t.Errorf("Expected anonymous sub-requests to be refused, got %s", w.Body.String())
+	}
+
+	requests := strings.TrimSuffix(strings.Repeat(`{"method":"GET","path":"/api/v1/posts"},`, 6), ",")
+	if w := sendJSON(router, http.MethodPost, "/api/v1/batch", `{"requests":[`+requests+`]}`, keys["jane"]); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected too many requests to be refused, got %d", w.Code)
+	}
+}
@@ -0,0 +1,461 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/authz"
+	"goapp/internal/container"
+	"goapp/internal/db/postgres"
+	"goapp/internal/logging"
+	"goapp/internal/models"
+	"goapp/internal/posts"
+	"goapp/internal/tags"
+	"gorm.io/gorm"
+)
+
+// BulkItemResult is the outcome of one item of a bulk request
+type BulkItemResult struct {
+	Index  int         `json:"index"`
+	Status int         `json:"status"` // the status the single-item endpoint would answer with
+	Data   interface{} `json:"data,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// BulkResponse lists the outcome of every item, in request order. Committed
+// is false when the transaction was rolled back, including the items that
+// succeeded.
+type BulkResponse struct {
+	Committed bool             `json:"committed"`
+	Results   []BulkItemResult `json:"results"`
+}
+
+// BulkCreatePostsRequest lists posts to create
+type BulkCreatePostsRequest struct {
+	// Atomic rolls every item back when one fails; it defaults to true
+	Atomic *bool               `json:"atomic"`
+	Items  []CreatePostRequest `json:"items" binding:"required"`
+}
+
+// BulkUpdatePost names a post and the fields to change
+type BulkUpdatePost struct {
+	ID string `json:"id"` // post ID or slug
+	UpdatePostRequest
+}
+
+// BulkUpdatePostsRequest lists posts to update
+type BulkUpdatePostsRequest struct {
+	Atomic *bool            `json:"atomic"`
+	Items  []BulkUpdatePost `json:"items" binding:"required"`
+}
+
+// BulkDeletePostsRequest lists posts to delete
+type BulkDeletePostsRequest struct {
+	Atomic *bool    `json:"atomic"`
+	IDs    []string `json:"ids" binding:"required"` // post IDs or slugs
+}
+
+// BulkCreateTagsRequest lists the names of tags to create
+type BulkCreateTagsRequest struct {
+	Atomic *bool    `json:"atomic"`
+	Names  []string `json:"names" binding:"required"`
+}
+
+// BulkRenameTag names a tag and its new name
+type BulkRenameTag struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// BulkRenameTagsRequest lists tags to rename
+type BulkRenameTagsRequest struct {
+	Atomic *bool           `json:"atomic"`
+	Items  []BulkRenameTag `json:"items" binding:"required"`
+}
+
+// BulkDeleteTagsRequest lists the slugs of tags to delete
+type BulkDeleteTagsRequest struct {
+	Atomic *bool    `json:"atomic"`
+	Slugs  []string `json:"slugs" binding:"required"`
+}
+
+// bulkError fails a single item with status
+type bulkError struct {
+	status  int
+	message string
+}
+
+func (e *bulkError) Error() string {
+	return e.message
+}
+
+// errRollback makes a bulk transaction roll back after a failed item
+var errRollback = errors.New("bulk request rolled back")
+
+// bulkItem performs item i of a bulk request on tx and returns its status and body
+type bulkItem func(tx *gorm.DB, i int) (int, interface{}, error)
+
+// BulkHandler creates, updates and deletes many posts or tags in one
+// database transaction
+type BulkHandler struct {
+	Logger   logging.Logger
+	Database postgres.Database
+	MaxItems int
+}
+
+// NewBulkHandler creates a new bulk handler with injected dependencies
+func NewBulkHandler(container *container.Container) *BulkHandler {
+	return &BulkHandler{
+		Logger:   container.Logger,
+		Database: container.Database,
+		MaxItems: container.Config.Bulk.MaxItems,
+	}
+}
+
+// RegisterRoutes registers the bulk routes; they are disabled without a database
+func (h *BulkHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	if h.Database == nil || h.Database.DB() == nil {
+		return
+	}
+
+	group := rg.Group("/bulk")
+	group.POST("/posts", h.CreatePosts)
+	group.PATCH("/posts", h.UpdatePosts)
+	group.DELETE("/posts", h.DeletePosts)
+	group.POST("/tags", h.CreateTags)
+	group.PATCH("/tags", h.RenameTags)
+	group.DELETE("/tags", h.DeleteTags)
+}
+
+// CreatePosts godoc
+// @Summary Create posts in bulk
+// @Description Create many posts written by the caller in one transaction. Each item is validated like POST /api/v1/posts and gets its own result. Unless atomic is false, one failed item rolls every item back.
+// @Tags v1,bulk,posts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body v1.BulkCreatePostsRequest true "Posts"
+// @Success 200 {object} v1.BulkResponse
+// @Failure 400 {object} v1.BulkResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/bulk/posts [post]
+func (h *BulkHandler) CreatePosts(c *gin.Context) {
+	if !middleware.Authorize(c, authz.ActionCreate, authz.Type(authz.ResourcePosts)) {
+		return
+	}
+	var req BulkCreatePostsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "items is required"))
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	h.run(c, "Posts created", req.Atomic, len(req.Items), func(tx *gorm.DB, i int) (int, interface{}, error) {
+		input := req.Items[i].input(user.ID)
+		if createNeedsPublish(input) {
+			if err := h.authorize(c, authz.ActionPublish, &models.Post{UserID: user.ID}); err != nil {
+				return 0, nil, err
+			}
+		}
+		post, err := posts.NewService(tx).Create(c.Request.Context(), input)
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusCreated, NewPostResponse(post), nil
+	})
+}
+
+// UpdatePosts godoc
+// @Summary Update posts in bulk
+// @Description Change the given fields of many posts in one transaction. Each item is checked like PATCH /api/v1/posts/{id} and gets its own result. Unless atomic is false, one failed item rolls every item back.
+// @Tags v1,bulk,posts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body v1.BulkUpdatePostsRequest true "Posts and the fields to change"
+// @Success 200 {object} v1.BulkResponse
+// @Failure 400 {object} v1.BulkResponse
+// @Failure 401 {object} map[string]string
+// @Router /api/v1/bulk/posts [patch]
+func (h *BulkHandler) UpdatePosts(c *gin.Context) {
+	if !h.signedIn(c) {
+		return
+	}
+	var req BulkUpdatePostsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "items is required"))
+		return
+	}
+
+	h.run(c, "Posts updated", req.Atomic, len(req.Items), func(tx *gorm.DB, i int) (int, interface{}, error) {
+		item := req.Items[i]
+		service := posts.NewService(tx)
+		post, err := h.post(c, service, item.ID, authz.ActionUpdate)
+		if err != nil {
+			return 0, nil, err
+		}
+		input := item.input(middleware.CurrentUser(c).ID)
+		if updateNeedsPublish(post, input) {
+			if err := h.authorize(c, authz.ActionPublish, post); err != nil {
+				return 0, nil, err
+			}
+		}
+		if err := service.Update(c.Request.Context(), post, input); err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, NewPostResponse(post), nil
+	})
+}
+
+// DeletePosts godoc
+// @Summary Delete posts in bulk
+// @Description Delete many posts in one transaction. Each post gets its own result. Unless atomic is false, one failed item rolls every item back.
+// @Tags v1,bulk,posts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body v1.BulkDeletePostsRequest true "Post IDs or slugs"
+// @Success 200 {object} v1.BulkResponse
+// @Failure 400 {object} v1.BulkResponse
+// @Failure 401 {object} map[string]string
+// @Router /api/v1/bulk/posts [delete]
+func (h *BulkHandler) DeletePosts(c *gin.Context) {
+	if !h.signedIn(c) {
+		return
+	}
+	var req BulkDeletePostsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "ids is required"))
+		return
+	}
+
+	h.run(c, "Posts deleted", req.Atomic, len(req.IDs), func(tx *gorm.DB, i int) (int, interface{}, error) {
+		service := posts.NewService(tx)
+		post, err := h.post(c, service, req.IDs[i], authz.ActionDelete)
+		if err != nil {
+			return 0, nil, err
+		}
+		if err := service.Delete(c.Request.Context(), post); err != nil {
+			return 0, nil, err
+		}
+		return http.StatusNoContent, nil, nil
+	})
+}
+
+// CreateTags godoc
+// @Summary Create tags in bulk
+// @Description Create many tags in one transaction. Each name gets its own result. Unless atomic is false, one failed item rolls every item back.
+// @Tags v1,bulk,tags
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body v1.BulkCreateTagsRequest true "Tag names"
+// @Success 200 {object} v1.BulkResponse
+// @Failure 400 {object} v1.BulkResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/bulk/tags [post]
+func (h *BulkHandler) CreateTags(c *gin.Context) {
+	if !middleware.Authorize(c, authz.ActionCreate, authz.Type(authz.ResourceTags)) {
+		return
+	}
+	var req BulkCreateTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "names is required"))
+		return
+	}
+
+	h.run(c, "Tags created", req.Atomic, len(req.Names), func(tx *gorm.DB, i int) (int, interface{}, error) {
+		tag, err := tags.NewService(tx).Create(c.Request.Context(), req.Names[i])
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusCreated, NewTagResponse(tag), nil
+	})
+}
+
+// RenameTags godoc
+// @Summary Rename tags in bulk
+// @Description Rename many tags in one transaction. As with PATCH /api/v1/tags/{slug}, the name or slug of another tag is rejected. Unless atomic is false, one failed item rolls every item back.
+// @Tags v1,bulk,tags
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body v1.BulkRenameTagsRequest true "Tags and their new names"
+// @Success 200 {object} v1.BulkResponse
+// @Failure 400 {object} v1.BulkResponse
+// @Failure 401 {object} map[string]string
+// @Router /api/v1/bulk/tags [patch]
+func (h *BulkHandler) RenameTags(c *gin.Context) {
+	if !h.signedIn(c) {
+		return
+	}
+	var req BulkRenameTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "items is required"))
+		return
+	}
+
+	h.run(c, "Tags renamed", req.Atomic, len(req.Items), func(tx *gorm.DB, i int) (int, interface{}, error) {
+		service := tags.NewService(tx)
+		tag, err := service.GetBySlug(c.Request.Context(), req.Items[i].Slug)
+		if err != nil {
+			return 0, nil, err
+		}
+		if err := h.authorize(c, authz.ActionUpdate, tag); err != nil {
+			return 0, nil, err
+		}
+		renamed, err := service.Rename(c.Request.Context(), tag, req.Items[i].Name)
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, NewTagResponse(renamed), nil
+	})
+}
+
+// DeleteTags godoc
+// @Summary Delete tags in bulk
+// @Description Remove many tags from their posts and delete them in one transaction. Unless atomic is false, one failed item rolls every item back.
+// @Tags v1,bulk,tags
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body v1.BulkDeleteTagsRequest true "Tag slugs"
+// @Success 200 {object} v1.BulkResponse
+// @Failure 400 {object} v1.BulkResponse
+// @Failure 401 {object} map[string]string
+// @Router /api/v1/bulk/tags [delete]
+func (h *BulkHandler) DeleteTags(c *gin.Context) {
+	if !h.signedIn(c) {
+		return
+	}
+	var req BulkDeleteTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "slugs is required"))
+		return
+	}
+
+	h.run(c, "Tags deleted", req.Atomic, len(req.Slugs), func(tx *gorm.DB, i int) (int, interface{}, error) {
+		service := tags.NewService(tx)
+		tag, err := service.GetBySlug(c.Request.Context(), req.Slugs[i])
+		if err != nil {
+			return 0, nil, err
+		}
+		if err := h.authorize(c, authz.ActionDelete, tag); err != nil {
+			return 0, nil, err
+		}
+		if err := service.Delete(c.Request.Context(), tag); err != nil {
+			return 0, nil, err
+		}
+		return http.StatusNoContent, nil, nil
+	})
+}
+
+// run performs count items in one transaction and answers with their
+// results. Each item runs in a savepoint, so a failed item leaves the
+// transaction usable for the next one. When an item fails and atomic is not
+// false, the whole transaction is rolled back.
+func (h *BulkHandler) run(c *gin.Context, message string, atomic *bool, count int, item bulkItem) {
+	if count == 0 {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "no items given"))
+		return
+	}
+	if count > h.MaxItems {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("at most %d items per request", h.MaxItems)))
+		return
+	}
+
+	response := BulkResponse{Results: make([]BulkItemResult, count)}
+	failed, status := 0, http.StatusBadRequest
+	err := h.Database.Transaction(c.Request.Context(), func(tx *gorm.DB) error {
+		for i := 0; i < count; i++ {
+			result := BulkItemResult{Index: i}
+			err := tx.Transaction(func(savepoint *gorm.DB) error {
+				var err error
+				result.Status, result.Data, err = item(savepoint, i)
+				return err
+			})
+			if err != nil {
+				result.Status, result.Error = h.itemError(err)
+				if result.Status >= http.StatusInternalServerError {
+					status = result.Status
+				}
+				failed++
+			}
+			response.Results[i] = result
+		}
+		if failed > 0 && (atomic == nil || *atomic) {
+			return errRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errRollback) {
+		h.Logger.Error("Bulk transaction failed", zap.String("message", message), zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to commit bulk request"))
+		return
+	}
+
+	response.Committed = err == nil
+	if !response.Committed {
+		c.JSON(status, response)
+		return
+	}
+	h.Logger.Info(message, zap.Int("count", count-failed), zap.Int("failed", failed), zap.Uint("user_id", middleware.CurrentUser(c).ID))
+	c.JSON(http.StatusOK, response)
+}
+
+// itemError maps the error of a failed item to a status and message the
+// way the single-item endpoints do
+func (h *BulkHandler) itemError(err error) (int, string) {
+	var itemErr *bulkError
+	var validationErr *auth.ValidationError
+	switch {
+	case errors.As(err, &itemErr):
+		return itemErr.status, itemErr.message
+	case errors.As(err, &validationErr):
+		return http.StatusBadRequest, validationErr.Error()
+	case errors.Is(err, posts.ErrNotFound):
+		return http.StatusNotFound, "post not found"
+	case errors.Is(err, tags.ErrNotFound):
+		return http.StatusNotFound, "tag not found"
+	}
+	h.Logger.Error("Bulk item failed", zap.Error(err))
+	return http.StatusInternalServerError, "internal error"
+}
+
+// post finds the post named by ref and checks the caller may perform action on it.
+// Posts the caller may not read are reported as not found.
+func (h *BulkHandler) post(c *gin.Context, service posts.Service, ref string, action authz.Action) (*models.Post, error) {
+	post, err := findPost(c.Request.Context(), service, ref)
+	if err != nil {
+		return nil, err
+	}
+	if !middleware.Can(c, authz.ActionRead, post) {
+		return nil, posts.ErrNotFound
+	}
+	return post, h.authorize(c, action, post)
+}
+
+// authorize fails an item with 403 when the caller may not perform action on resource
+func (h *BulkHandler) authorize(c *gin.Context, action authz.Action, resource authz.Resource) error {
+	if !middleware.Can(c, action, resource) {
+		return &bulkError{status: http.StatusForbidden, message: authz.ErrForbidden.Error()}
+	}
+	return nil
+}
+
+// signedIn refuses anonymous callers with 401 before a request that checks
+// permissions per item
+func (h *BulkHandler) signedIn(c *gin.Context) bool {
+	if middleware.CurrentUser(c) == nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusUnauthorized, authz.ErrUnauthenticated.Error()))
+		return false
+	}
+	return true
+}
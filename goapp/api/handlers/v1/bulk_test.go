@@ -0,0 +1,199 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"goapp/internal/apikeys"
+	"goapp/internal/db/postgres"
+	"gorm.io/gorm"
+)
+
+// testDatabase runs the transactions of the bulk handler on a test database
+type testDatabase struct {
+	postgres.Database
+	db *gorm.DB
+}
+
+func (d testDatabase) DB() *gorm.DB {
+	return d.db
+}
+
+func (d testDatabase) Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return d.db.WithContext(ctx).Transaction(fn)
+}
+
+func bulkResults(t *testing.T, body []byte) BulkResponse {
+	t.Helper()
+	var resp BulkResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp
+}
+
+func statuses(resp BulkResponse) []int {
+	list := make([]int, len(resp.Results))
+	for i, result := range resp.Results {
+		list[i] = result.Status
+	}
+	return list
+}
+
+func equalStatuses(got, want []int) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBulkHandler_Posts(t *testing.T) {
+	router, keys := setupPostRouter(t)
+
+	t.Run("AtomicCreateRollsBack", func(t *testing.T) {
+		w := sendJSON(router, http.MethodPost, "/api/v1/bulk/posts", `{"items":[{"title":"First Import"},{"title":""}]}`, keys["bob"])
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+		}
+		resp := bulkResults(t, w.Body.Bytes())
+		if resp.Committed || !equalStatuses(statuses(resp), []int{http.StatusCreated, http.StatusBadRequest}) {
+			t.Errorf("Expected a rolled back create with per-item results, got %+v", resp)
+		}
+		if list := listPosts(t, router, "author=bob", keys["bob"]); len(list.Data) != 0 {
+			t.Errorf("Expected no posts after the rollback, got %d", len(list.Data))
+		}
+	})
+
+	t.Run("PartialCreate", func(t *testing.T) {
+		w := sendJSON(router, http.MethodPost, "/api/v1/bulk/posts", `{"atomic":false,"items":[{"title":"First Import"},{"title":"Published Import","published":true},{"title":""}]}`, keys["bob"])
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		resp := bulkResults(t, w.Body.Bytes())
+		if !resp.Committed || !equalStatuses(statuses(resp), []int{http.StatusCreated, http.StatusCreated, http.StatusBadRequest}) {
+			t.Errorf("Expected the valid posts to be committed, got %+v", resp)
+		}
+		if resp.Results[2].Error == "" {
+			t.Error("Expected the failed item to say why")
+		}
+		if list := listPosts(t, router, "author=bob", keys["bob"]); len(list.Data) != 2 {
+			t.Errorf("Expected 2 imported posts, got %d", len(list.Data))
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		body := `{"atomic":false,"items":[{"id":"first-import","title":"Renamed Import"},{"id":"hello-world","title":"Hijacked"},{"id":"secret-draft","title":"Peek"}]}`
+		w := sendJSON(router, http.MethodPatch, "/api/v1/bulk/posts", body, keys["bob"])
+		resp := bulkResults(t, w.Body.Bytes())
+		if !equalStatuses(statuses(resp), []int{http.StatusOK, http.StatusForbidden, http.StatusNotFound}) {
+			t.Errorf("Expected other users' posts to be refused, got %+v", resp)
+		}
+		if w := sendJSON(router, http.MethodGet, "/api/v1/posts/first-import", "", keys["bob"]); !strings.Contains(w.Body.String(), "Renamed Import") {
+			t.Errorf("Expected the post to be renamed, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("AtomicDeleteRollsBack", func(t *testing.T) {
+		w := sendJSON(router, http.MethodDelete, "/api/v1/bulk/posts", `{"ids":["first-import","missing"]}`, keys["bob"])
+		resp := bulkResults(t, w.Body.Bytes())
+		if w.Code != http.StatusBadRequest || !equalStatuses(statuses(resp), []int{http.StatusNoContent, http.StatusNotFound}) {
+			t.Errorf("Expected a rolled back delete, got %d %+v", w.Code, resp)
+		}
+		if w := sendJSON(router, http.MethodGet, "/api/v1/posts/first-import", "", keys["bob"]); w.Code != http.StatusOK {
+			t.Errorf("Expected the post to survive the rollback, got %d", w.Code)
+		}
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		items := strings.TrimSuffix(strings.Repeat(`{"title":"Too Many"},`, 11), ",")
+		if w := sendJSON(router, http.MethodPost, "/api/v1/bulk/posts", `{"items":[`+items+`]}`, keys["bob"]); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected too many items to be refused, got %d", w.Code)
+		}
+		if w := sendJSON(router, http.MethodPost, "/api/v1/bulk/posts", `{"items":[]}`, keys["bob"]); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected an empty request to be refused, got %d", w.Code)
+		}
+		if w := sendJSON(router, http.MethodDelete, "/api/v1/bulk/posts", `{"ids":["first-import"]}`, ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+		if w := sendJSON(router, http.MethodPost, "/api/v1/bulk/posts", `{"items":[{"title":"Read Only"}]}`, keys["bob-readonly"]); w.Code != http.StatusForbidden {
+			t.Errorf("Expected keys without posts:create to be refused, got %d", w.Code)
+		}
+	})
+}
+
+func TestBulkHandler_Tags(t *testing.T) {
+	router, keys := setupPostRouter(t)
+
+	w := sendJSON(router, http.MethodPost, "/api/v1/bulk/tags", `{"atomic":false,"names":["Go","Rust","go"]}`, keys["editor"])
+	resp := bulkResults(t, w.Body.Bytes())
+	if w.Code != http.StatusOK || !equalStatuses(statuses(resp), []int{http.StatusCreated, http.StatusCreated, http.StatusBadRequest}) {
+		t.Fatalf("Expected duplicate names to fail, got %d %+v", w.Code, resp)
+	}
+	if w := sendJSON(router, http.MethodPost, "/api/v1/bulk/tags", `{"names":["Zig"]}`, keys["bob"]); w.Code != http.StatusForbidden {
+		t.Errorf("Expected users without tags:create to be refused, got %d", w.Code)
+	}
+
+	w = sendJSON(router, http.MethodPatch, "/api/v1/bulk/tags", `{"items":[{"slug":"rust","name":"Rustlang"}]}`, keys["editor"])
+	if resp := bulkResults(t, w.Body.Bytes()); !resp.Committed || !strings.Contains(w.Body.String(), `"slug":"rustlang"`) {
+		t.Errorf("Expected the tag to be renamed, got %s", w.Body.String())
+	}
+	w = sendJSON(router, http.MethodPatch, "/api/v1/bulk/tags", `{"items":[{"slug":"go","name":"Golang"}]}`, keys["bob"])
+	if resp := bulkResults(t, w.Body.Bytes()); !equalStatuses(statuses(resp), []int{http.StatusForbidden}) {
+		t.Errorf("Expected users without tags:update to be refused, got %+v", resp)
+	}
+
+	w = sendJSON(router, http.MethodDelete, "/api/v1/bulk/tags", `{"slugs":["go","nope"]}`, keys["editor"])
+	if resp := bulkResults(t, w.Body.Bytes()); resp.Committed || !equalStatuses(statuses(resp), []int{http.StatusNoContent, http.StatusNotFound}) {
+		t.Errorf("Expected a rolled back delete, got %+v", resp)
+	}
+	if w := sendJSON(router, http.MethodGet, "/api/v1/tags/go", "", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected the tag to survive the rollback, got %d", w.Code)
+	}
+}
+
+func TestBulkHandler_PostStatus(t *testing.T) {
+	router, keys, c := setupPostAPI(t)
+	ctx := context.Background()
+	noPublish, _, err := c.APIKeys.Create(ctx, apikeys.CreateInput{Name: "writer", UserID: 2, Scopes: []string{"posts:read", "posts:create", "posts:update"}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	// Statuses other than draft and in_review need posts:publish, however they are given
+	for _, item := range []string{`{"title":"Live","status":"published"}`, `{"title":"Later","status":"scheduled","scheduled_at":"` + future + `"}`} {
+		w := sendJSON(router, http.MethodPost, "/api/v1/bulk/posts", `{"items":[`+item+`]}`, noPublish)
+		if resp := bulkResults(t, w.Body.Bytes()); !equalStatuses(statuses(resp), []int{http.StatusForbidden}) {
+			t.Errorf("Expected %s to need the publish permission, got %+v", item, resp)
+		}
+	}
+
+	w := sendJSON(router, http.MethodPost, "/api/v1/bulk/posts", `{"items":[{"title":"Review Me","status":"in_review"},{"title":"Scheduled","status":"scheduled","scheduled_at":"`+future+`"}]}`, keys["bob"])
+	resp := bulkResults(t, w.Body.Bytes())
+	if !resp.Committed || !strings.Contains(w.Body.String(), `"status":"in_review"`) || !strings.Contains(w.Body.String(), `"status":"scheduled"`) {
+		t.Fatalf("Expected the statuses to be saved, got %s", w.Body.String())
+	}
+
+	body := `{"items":[{"id":"review-me","status":"published"}]}`
+	w = sendJSON(router, http.MethodPatch, "/api/v1/bulk/posts", body, noPublish)
+	if resp := bulkResults(t, w.Body.Bytes()); !equalStatuses(statuses(resp), []int{http.StatusForbidden}) {
+		t.Errorf("Expected publishing to need the publish permission, got %+v", resp)
+	}
+	w = sendJSON(router, http.MethodPatch, "/api/v1/bulk/posts", `{"items":[{"id":"scheduled","scheduled_at":"`+future+`"}]}`, noPublish)
+	if resp := bulkResults(t, w.Body.Bytes()); !equalStatuses(statuses(resp), []int{http.StatusForbidden}) {
+		t.Errorf("Expected rescheduling to need the publish permission, got %+v", resp)
+	}
+	w = sendJSON(router, http.MethodPatch, "/api/v1/bulk/posts", body, keys["bob"])
+	if resp := bulkResults(t, w.Body.Bytes()); !resp.Committed || !strings.Contains(w.Body.String(), `"status":"published"`) {
+		t.Errorf("Expected the post to be published, got %s", w.Body.String())
+	}
+}
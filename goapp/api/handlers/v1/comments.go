@@ -0,0 +1,296 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/api/middleware"
+	"goapp/internal/authz"
+	"goapp/internal/comments"
+	"goapp/internal/container"
+	"goapp/internal/logging"
+	"goapp/internal/models"
+	"goapp/internal/posts"
+)
+
+// CommentResponse describes a comment and its replies. Deleted comments are
+// only listed while they have replies, without content or author.
+type CommentResponse struct {
+	ID        uint              `json:"id"`
+	ParentID  *uint             `json:"parent_id,omitempty"`
+	Content   string            `json:"content"` // Markdown, or sanitized HTML with format=html
+	Author    *AuthorResponse   `json:"author,omitempty"`
+	Deleted   bool              `json:"deleted"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Replies   []CommentResponse `json:"replies"`
+}
+
+// CommentTreeResponse lists the comments of a post as a tree
+type CommentTreeResponse struct {
+	Data []CommentResponse `json:"data"`
+	// Depth is the number of reply levels loaded below top-level comments
+	Depth int `json:"depth"`
+}
+
+// CreateCommentRequest describes a new comment
+type CreateCommentRequest struct {
+	Content  string `json:"content" binding:"required"`
+	ParentID *uint  `json:"parent_id"` // omit for a top-level comment
+}
+
+// UpdateCommentRequest carries the new content of a comment
+type UpdateCommentRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// NewCommentResponse describes comment and its replies, whose User must be loaded
+func NewCommentResponse(comment *models.Comment) CommentResponse {
+	return newCommentResponseIn(comment, formatMarkdown)
+}
+
+// newCommentResponseIn describes comment and its replies with their content in format
+func newCommentResponseIn(comment *models.Comment, format string) CommentResponse {
+	response := CommentResponse{
+		ID:        comment.ID,
+		ParentID:  comment.ParentID,
+		Deleted:   comment.DeletedAt.Valid,
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+		Replies:   make([]CommentResponse, len(comment.Replies)),
+	}
+	if !response.Deleted {
+		response.Content = comment.Content
+		if format == formatHTML {
+			response.Content = comments.ContentHTML(comment)
+		}
+		response.Author = &AuthorResponse{ID: comment.User.ID, Username: comment.User.Username, Name: comment.User.FullName()}
+	}
+	for i := range comment.Replies {
+		response.Replies[i] = newCommentResponseIn(&comment.Replies[i], format)
+	}
+	return response
+}
+
+// CommentHandler serves the comments of posts
+type CommentHandler struct {
+	Logger   logging.Logger
+	Comments comments.Service
+	// posts finds the post named in /posts/{id}/comments
+	posts *PostHandler
+}
+
+// NewCommentHandler creates a new comments handler with injected dependencies
+func NewCommentHandler(container *container.Container) *CommentHandler {
+	return &CommentHandler{
+		Logger:   container.Logger,
+		Comments: container.Comments,
+		posts:    NewPostHandler(container),
+	}
+}
+
+// RegisterRoutes registers the comments routes; they are disabled without a database
+func (h *CommentHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	if h.Comments == nil || h.posts.Posts == nil {
+		return
+	}
+
+	rg.GET("/posts/:id/comments", h.List)
+	rg.POST("/posts/:id/comments", h.Create)
+	rg.PATCH("/comments/:id", h.Update)
+	rg.DELETE("/comments/:id", h.Delete)
+}
+
+// List godoc
+// @Summary List comments
+// @Description List the comments of a post as a tree, oldest first. Deleted comments stay in place, without content, while they have replies.
+// @Tags v1,comments
+// @Produce json
+// @Param id path string true "Post ID or slug"
+// @Param depth query int false "Levels of replies to load; defaults to and is capped at COMMENTS_MAX_DEPTH"
+// @Param format query string false "Content as Markdown or sanitized HTML" Enums(markdown,html) default(markdown)
+// @Success 200 {object} v1.CommentTreeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/posts/{id}/comments [get]
+func (h *CommentHandler) List(c *gin.Context) {
+	post, ok := h.posts.load(c)
+	if !ok {
+		return
+	}
+
+	depth := h.Comments.MaxDepth()
+	if v := c.Query("depth"); v != "" {
+		requested, err := strconv.Atoi(v)
+		if err != nil || requested < 0 {
+			_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "depth must be a non-negative number"))
+			return
+		}
+		if requested < depth {
+			depth = requested
+		}
+	}
+	format, ok := contentFormat(c)
+	if !ok {
+		return
+	}
+
+	tree, err := h.Comments.Tree(c.Request.Context(), post.ID, depth)
+	if err != nil {
+		h.Logger.Error("Failed to list comments", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to list comments"))
+		return
+	}
+
+	response := CommentTreeResponse{Data: make([]CommentResponse, len(tree)), Depth: depth}
+	for i := range tree {
+		response.Data[i] = newCommentResponseIn(&tree[i], format)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Create godoc
+// @Summary Create comment
+// @Description Comment on a post, or reply to one of its comments with parent_id. Replies may nest at most COMMENTS_MAX_DEPTH levels deep.
+// @Tags v1,comments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post ID or slug"
+// @Param request body v1.CreateCommentRequest true "Comment"
+// @Success 201 {object} v1.CommentResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/posts/{id}/comments [post]
+func (h *CommentHandler) Create(c *gin.Context) {
+	post, ok := h.posts.load(c)
+	if !ok {
+		return
+	}
+	if !middleware.Authorize(c, authz.ActionCreate, authz.Type(authz.ResourceComments)) {
+		return
+	}
+
+	var req CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "content is required"))
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	comment, err := h.Comments.Create(c.Request.Context(), comments.CreateInput{
+		PostID:   post.ID,
+		ParentID: req.ParentID,
+		AuthorID: user.ID,
+		Content:  req.Content,
+	})
+	if err != nil {
+		h.posts.fail(c, err, "failed to create comment")
+		return
+	}
+
+	h.Logger.Info("Comment created", zap.Uint("comment_id", comment.ID), zap.Uint("post_id", post.ID), zap.Uint("user_id", user.ID))
+	c.JSON(http.StatusCreated, NewCommentResponse(comment))
+}
+
+// Update godoc
+// @Summary Update comment
+// @Description Replace the content of a comment
+// @Tags v1,comments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Comment ID"
+// @Param request body v1.UpdateCommentRequest true "New content"
+// @Success 200 {object} v1.CommentResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/comments/{id} [patch]
+func (h *CommentHandler) Update(c *gin.Context) {
+	comment, ok := h.load(c)
+	if !ok {
+		return
+	}
+	if !middleware.Authorize(c, authz.ActionUpdate, comment) {
+		return
+	}
+
+	var req UpdateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "content is required"))
+		return
+	}
+	if err := h.Comments.Update(c.Request.Context(), comment, req.Content); err != nil {
+		h.posts.fail(c, err, "failed to update comment")
+		return
+	}
+
+	h.Logger.Info("Comment updated", zap.Uint("comment_id", comment.ID), zap.Uint("user_id", middleware.CurrentUser(c).ID))
+	c.JSON(http.StatusOK, NewCommentResponse(comment))
+}
+
+// Delete godoc
+// @Summary Delete comment
+// @Description Soft-delete a comment. Its replies remain, under a placeholder.
+// @Tags v1,comments
+// @Security BearerAuth
+// @Param id path int true "Comment ID"
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/comments/{id} [delete]
+func (h *CommentHandler) Delete(c *gin.Context) {
+	comment, ok := h.load(c)
+	if !ok {
+		return
+	}
+	if !middleware.Authorize(c, authz.ActionDelete, comment) {
+		return
+	}
+
+	if err := h.Comments.Delete(c.Request.Context(), comment); err != nil {
+		h.Logger.Error("Failed to delete comment", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to delete comment"))
+		return
+	}
+	h.Logger.Info("Comment deleted", zap.Uint("comment_id", comment.ID), zap.Uint("user_id", middleware.CurrentUser(c).ID))
+	c.Status(http.StatusNoContent)
+}
+
+// load finds the comment named by the id path parameter, reporting 404 when
+// it does not exist or its post may not be read
+func (h *CommentHandler) load(c *gin.Context) (*models.Comment, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusNotFound, "comment not found"))
+		return nil, false
+	}
+
+	comment, err := h.Comments.Get(c.Request.Context(), uint(id))
+	if err == nil {
+		var post *models.Post
+		post, err = h.posts.Posts.Get(c.Request.Context(), comment.PostID)
+		if err == nil && !middleware.Can(c, authz.ActionRead, post) {
+			err = posts.ErrNotFound
+		}
+	}
+	if errors.Is(err, comments.ErrNotFound) || errors.Is(err, posts.ErrNotFound) {
+		_ = c.Error(middleware.NewHTTPError(http.StatusNotFound, "comment not found"))
+		return nil, false
+	}
+	if err != nil {
+		h.Logger.Error("Failed to load comment", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to load comment"))
+		return nil, false
+	}
+	return comment, true
+}
@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestCommentHandler(t *testing.T) {
+	router, keys := setupPostRouter(t)
+
+	create := func(body, key string) (CommentResponse, int) {
+		w := sendJSON(router, http.MethodPost, "/api/v1/posts/hello-world/comments", body, key)
+		var comment CommentResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &comment)
+		return comment, w.Code
+	}
+
+	if _, code := create(`{"content":"Hi"}`, ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected anonymous comments to be rejected with %d, got %d", http.StatusUnauthorized, code)
+	}
+	if w := sendJSON(router, http.MethodPost, "/api/v1/posts/secret-draft/comments", `{"content":"Hi"}`, keys["bob"]); w.Code != http.StatusNotFound {
+		t.Errorf("Expected comments on unreadable drafts to be rejected with %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	top, code := create(`{"content":"First!"}`, keys["bob"])
+	if code != http.StatusCreated || top.Author == nil || top.Author.Username != "bob" {
+		t.Fatalf("Expected comment by bob, got %d %+v", code, top)
+	}
+	child, code := create(fmt.Sprintf(`{"content":"Welcome","parent_id":%d}`, top.ID), keys["jane"])
+	if code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, code)
+	}
+	if _, code := create(fmt.Sprintf(`{"content":"Too deep","parent_id":%d}`, child.ID), keys["bob"]); code != http.StatusBadRequest {
+		t.Errorf("Expected replies past the max depth to be rejected with %d, got %d", http.StatusBadRequest, code)
+	}
+
+	if w := sendJSON(router, http.MethodPatch, fmt.Sprintf("/api/v1/comments/%d", top.ID), `{"content":"Edited"}`, keys["jane"]); w.Code != http.StatusForbidden {
+		t.Errorf("Expected users not to edit others' comments, got %d", w.Code)
+	}
+	if w := sendJSON(router, http.MethodPatch, fmt.Sprintf("/api/v1/comments/%d", top.ID), `{"content":"Edited"}`, keys["bob"]); w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if w := sendJSON(router, http.MethodDelete, fmt.Sprintf("/api/v1/comments/%d", top.ID), "", keys["bob"]); w.Code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+
+	w := sendJSON(router, http.MethodGet, "/api/v1/posts/hello-world/comments", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var tree CommentTreeResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &tree); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(tree.Data) != 1 || !tree.Data[0].Deleted || tree.Data[0].Content != "" || tree.Data[0].Author != nil {
+		t.Fatalf("Expected a placeholder for the deleted comment, got %+v", tree.Data)
+	}
+	if len(tree.Data[0].Replies) != 1 || tree.Data[0].Replies[0].Content != "Welcome" {
+		t.Errorf("Expected the reply to survive its parent, got %+v", tree.Data[0].Replies)
+	}
+
+	w = sendJSON(router, http.MethodGet, "/api/v1/posts/hello-world/comments?depth=0", "", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &tree); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if tree.Depth != 0 || len(tree.Data) != 0 {
+		t.Errorf("Expected no replies to be loaded, and so no placeholder, got %+v", tree)
+	}
+
+	w = sendJSON(router, http.MethodGet, "/api/v1/posts/hello-world/comments?format=html", "", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &tree); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(tree.Data) != 1 || tree.Data[0].Content != "" || tree.Data[0].Replies[0].Content != "<p>Welcome</p>\n" {
+		t.Errorf("Expected replies as HTML, got %+v", tree.Data)
+	}
+	if w := sendJSON(router, http.MethodGet, "/api/v1/posts/hello-world/comments?format=pdf", "", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected unknown formats to be rejected with %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
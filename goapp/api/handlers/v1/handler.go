@@ -0,0 +1,30 @@
+package v1
+
+import (
+	"goapp/internal/container"
+	"goapp/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version is the path segment this package's handlers are mounted under
+const Version = "v1"
+
+// Handler serves version-level endpoints of the v1 JSON API
+type Handler struct {
+	Logger  logging.Logger
+	AppName string
+}
+
+// New creates a new v1 handler with injected dependencies
+func New(container *container.Container) *Handler {
+	return &Handler{
+		Logger:  container.Logger,
+		AppName: container.Config.App.Name,
+	}
+}
+
+// RegisterRoutes registers the handler's routes on the v1 group
+func (h *Handler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.GET("/status", h.Status)
+}
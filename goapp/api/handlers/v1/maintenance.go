@@ -0,0 +1,110 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/api/middleware"
+	"goapp/internal/container"
+	"goapp/internal/logging"
+	"goapp/internal/maintenance"
+)
+
+// MaintenanceRequest toggles maintenance mode
+type MaintenanceRequest struct {
+	Enabled           bool   `json:"enabled"`
+	Message           string `json:"message"`
+	RetryAfterSeconds *int   `json:"retry_after_seconds"`
+}
+
+// MaintenanceHandler exposes the maintenance mode admin endpoint
+type MaintenanceHandler struct {
+	Logger            logging.Logger
+	Manager           *maintenance.Manager
+	AdminToken        string
+	DefaultRetryAfter time.Duration
+}
+
+// NewMaintenanceHandler creates a new maintenance handler with injected dependencies
+func NewMaintenanceHandler(container *container.Container) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		Logger:            container.Logger,
+		Manager:           container.Maintenance,
+		AdminToken:        container.Config.Maintenance.AdminToken,
+		DefaultRetryAfter: container.Config.Maintenance.DefaultRetryAfter,
+	}
+}
+
+// RegisterRoutes registers the admin routes; they are disabled when no admin token is configured
+func (h *MaintenanceHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	if h.Manager == nil || h.AdminToken == "" {
+		return
+	}
+
+	admin := rg.Group("/admin", middleware.RequireToken(h.AdminToken))
+	admin.GET("/maintenance", h.GetMaintenance)
+	admin.PUT("/maintenance", h.SetMaintenance)
+}
+
+// GetMaintenance godoc
+// @Summary Get maintenance mode
+// @Description Report whether maintenance mode is enabled
+// @Tags v1,admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} maintenance.Status
+// @Failure 401 {object} map[string]string
+// @Router /api/v1/admin/maintenance [get]
+func (h *MaintenanceHandler) GetMaintenance(c *gin.Context) {
+	status, err := h.Manager.Refresh(c.Request.Context())
+	if err != nil {
+		h.Logger.Error("Failed to load maintenance state", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to load maintenance state"))
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// SetMaintenance godoc
+// @Summary Set maintenance mode
+// @Description Enable or disable maintenance mode for all instances
+// @Tags v1,admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body v1.MaintenanceRequest true "Maintenance mode"
+// @Success 200 {object} maintenance.Status
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /api/v1/admin/maintenance [put]
+func (h *MaintenanceHandler) SetMaintenance(c *gin.Context) {
+	var req MaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "invalid request body"))
+		return
+	}
+
+	var (
+		status maintenance.Status
+		err    error
+	)
+	if req.Enabled {
+		retryAfter := h.DefaultRetryAfter
+		if req.RetryAfterSeconds != nil {
+			retryAfter = time.Duration(*req.RetryAfterSeconds) * time.Second
+		}
+		status, err = h.Manager.Enable(c.Request.Context(), req.Message, retryAfter)
+	} else {
+		status, err = h.Manager.Disable(c.Request.Context())
+	}
+	if err != nil {
+		h.Logger.Error("Failed to update maintenance state", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to update maintenance state"))
+		return
+	}
+
+	h.Logger.Info("Maintenance mode updated", zap.Bool("enabled", status.Enabled))
+	c.JSON(http.StatusOK, status)
+}
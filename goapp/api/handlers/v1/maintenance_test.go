@@ -0,0 +1,108 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goapp/api/middleware"
+	"goapp/internal/maintenance"
+)
+
+func setupMaintenanceRouter(t *testing.T, token string) (*gin.Engine, *maintenance.Manager) {
+	gin.SetMode(gin.TestMode)
+
+	c := setupTestContainer(t)
+	c.Config.Maintenance.AdminToken = token
+	c.Config.Maintenance.DefaultRetryAfter = 5 * time.Minute
+	c.Maintenance = maintenance.NewManager(maintenance.NewMemoryStore(), time.Minute)
+
+	router := gin.New()
+	group := router.Group("/api/v1", middleware.JSONErrors())
+	NewMaintenanceHandler(c).RegisterRoutes(group)
+	return router, c.Maintenance
+}
+
+func TestMaintenanceHandler(t *testing.T) {
+	router, manager := setupMaintenanceRouter(t, "secret")
+
+	send := func(method, body, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/api/v1/admin/maintenance", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("RequiresToken", func(t *testing.T) {
+		for _, token := range []string{"", "wrong"} {
+			if w := send(http.MethodGet, "", token); w.Code != http.StatusUnauthorized {
+				t.Errorf("Expected status %d for token %q, got %d", http.StatusUnauthorized, token, w.Code)
+			}
+		}
+	})
+
+	t.Run("Enable", func(t *testing.T) {
+		w := send(http.MethodPut, `{"enabled":true,"message":"Upgrading"}`, "secret")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var status maintenance.Status
+		if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if !status.Enabled || status.Message != "Upgrading" || status.RetryAfterSeconds != 300 {
+			t.Errorf("Unexpected status: %+v", status)
+		}
+		if !manager.Status(context.Background()).Enabled {
+			t.Error("Expected manager to report maintenance mode on")
+		}
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		w := send(http.MethodGet, "", "secret")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), `"enabled":true`) {
+			t.Errorf("Expected enabled state in response, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		w := send(http.MethodPut, `{"enabled":false}`, "secret")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		if manager.Status(context.Background()).Enabled {
+			t.Error("Expected manager to report maintenance mode off")
+		}
+	})
+
+	t.Run("InvalidBody", func(t *testing.T) {
+		if w := send(http.MethodPut, `{`, "secret"); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
+
+func TestMaintenanceHandlerDisabledWithoutToken(t *testing.T) {
+	router, _ := setupMaintenanceRouter(t, "")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/admin/maintenance", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
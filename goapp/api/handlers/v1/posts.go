@@ -0,0 +1,752 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/authz"
+	"goapp/internal/container"
+	"goapp/internal/logging"
+	"goapp/internal/markdown"
+	"goapp/internal/models"
+	"goapp/internal/posts"
+	"goapp/internal/query"
+	"goapp/internal/views"
+)
+
+// Content formats of post and comment responses
+const (
+	formatMarkdown = "markdown"
+	formatHTML     = "html"
+)
+
+// AuthorResponse describes the author of a post
+type AuthorResponse struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
+
+// PostResponse describes a post
+type PostResponse struct {
+	ID          uint           `json:"id"`
+	Title       string         `json:"title"`
+	Slug        string         `json:"slug"`
+	Summary     string         `json:"summary"`
+	Content     string         `json:"content"` // Markdown, or sanitized HTML with format=html
+	Status      string         `json:"status" enums:"draft,in_review,scheduled,published,archived"`
+	Published   bool           `json:"published"`
+	PublishedAt *time.Time     `json:"published_at,omitempty"`
+	ScheduledAt *time.Time     `json:"scheduled_at,omitempty"`
+	ViewCount   uint           `json:"view_count"`
+	Author      AuthorResponse `json:"author"`
+	Tags        []string       `json:"tags"` // tag slugs
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	// TOC lists the headings of the content with format=html
+	TOC []markdown.Heading `json:"toc,omitempty"`
+}
+
+// PostListResponse is a page of posts. Total, Limit and Offset describe
+// offset pagination; pass NextCursor as cursor to continue after this page.
+type PostListResponse struct {
+	Data       []PostResponse `json:"data"`
+	Total      *int64         `json:"total,omitempty"`
+	Limit      int            `json:"limit"`
+	Offset     int            `json:"offset,omitempty"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// CreatePostRequest describes a new post
+type CreatePostRequest struct {
+	Title   string `json:"title" binding:"required"`
+	Slug    string `json:"slug"` // derived from the title when omitted
+	Summary string `json:"summary"`
+	Content string `json:"content"`
+	// Published requires the posts:publish permission or authorship
+	Published bool `json:"published"`
+	// Status overrides published; any status but draft and in_review needs the publish permission
+	Status      string     `json:"status" enums:"draft,in_review,scheduled,published,archived"`
+	ScheduledAt *time.Time `json:"scheduled_at"` // required for scheduled posts
+}
+
+// UpdatePostRequest lists the fields to change; omitted fields are kept
+type UpdatePostRequest struct {
+	Title       *string    `json:"title"`
+	Slug        *string    `json:"slug"`
+	Summary     *string    `json:"summary"`
+	Content     *string    `json:"content"`
+	Published   *bool      `json:"published"`
+	Status      *string    `json:"status" enums:"draft,in_review,scheduled,published,archived"`
+	ScheduledAt *time.Time `json:"scheduled_at"`
+}
+
+// RevisionResponse describes a revision of a post
+type RevisionResponse struct {
+	Number    int             `json:"number"`
+	Title     string          `json:"title"`
+	Slug      string          `json:"slug"`
+	Summary   string          `json:"summary"`
+	Content   string          `json:"content"`
+	Status    string          `json:"status"`
+	Editor    *AuthorResponse `json:"editor,omitempty"` // omitted when unknown
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// RevisionDiffResponse is a revision with the line changes of its content
+// since an earlier revision
+type RevisionDiffResponse struct {
+	RevisionResponse
+	Against int              `json:"against"` // the earlier revision, 0 for none
+	Diff    []posts.DiffLine `json:"diff"`
+}
+
+// PostViewsResponse describes the views of a post
+type PostViewsResponse struct {
+	Total uint                 `json:"total"` // all views, up to the last write of counted views
+	Days  []DailyViewsResponse `json:"days"`
+}
+
+// DailyViewsResponse holds the views of a post on a UTC day
+type DailyViewsResponse struct {
+	Date  string `json:"date"` // YYYY-MM-DD
+	Views uint   `json:"views"`
+}
+
+// NewPostResponse describes post, whose User and Tags must be loaded
+func NewPostResponse(post *models.Post) PostResponse {
+	tags := make([]string, len(post.Tags))
+	for i, tag := range post.Tags {
+		tags[i] = tag.Slug
+	}
+	return PostResponse{
+		ID:          post.ID,
+		Title:       post.Title,
+		Slug:        post.Slug,
+		Summary:     post.Summary,
+		Content:     post.Content,
+		Status:      post.Status,
+		Published:   post.Published,
+		PublishedAt: post.PublishedAt,
+		ScheduledAt: post.ScheduledAt,
+		ViewCount:   post.ViewCount,
+		Author:      AuthorResponse{ID: post.User.ID, Username: post.User.Username, Name: post.User.FullName()},
+		Tags:        tags,
+		CreatedAt:   post.CreatedAt,
+		UpdatedAt:   post.UpdatedAt,
+	}
+}
+
+// newPostResponseIn describes post with its content in format
+func newPostResponseIn(post *models.Post, format string) PostResponse {
+	response := NewPostResponse(post)
+	if format == formatHTML {
+		response.Content = posts.ContentHTML(post)
+		response.TOC = markdown.TableOfContents(post.Content)
+	}
+	return response
+}
+
+// contentFormat reads the format parameter, markdown by default, and
+// reports a bad request for other values
+func contentFormat(c *gin.Context) (string, bool) {
+	switch format := c.DefaultQuery("format", formatMarkdown); format {
+	case formatMarkdown, formatHTML:
+		return format, true
+	}
+	_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "format must be markdown or html"))
+	return "", false
+}
+
+// NewRevisionResponse describes revision, whose User must be loaded
+func NewRevisionResponse(revision *models.PostRevision) RevisionResponse {
+	response := RevisionResponse{
+		Number:    revision.Number,
+		Title:     revision.Title,
+		Slug:      revision.Slug,
+		Summary:   revision.Summary,
+		Content:   revision.Content,
+		Status:    revision.Status,
+		CreatedAt: revision.CreatedAt,
+	}
+	if revision.User != nil {
+		response.Editor = &AuthorResponse{ID: revision.User.ID, Username: revision.User.Username, Name: revision.User.FullName()}
+	}
+	return response
+}
+
+// PostHandler serves the posts resource
+type PostHandler struct {
+	Logger logging.Logger
+	Posts  posts.Service
+	Views  *views.Counter // nil when views are not counted
+}
+
+// NewPostHandler creates a new posts handler with injected dependencies
+func NewPostHandler(container *container.Container) *PostHandler {
+	return &PostHandler{
+		Logger: container.Logger,
+		Posts:  container.Posts,
+		Views:  container.Views,
+	}
+}
+
+// RegisterRoutes registers the posts routes; they are disabled without a database
+func (h *PostHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	if h.Posts == nil {
+		return
+	}
+
+	group := rg.Group("/posts")
+	group.GET("", h.List)
+	group.POST("", h.Create)
+	group.GET("/:id", h.Get)
+	group.PATCH("/:id", h.Update)
+	group.DELETE("/:id", h.Delete)
+	group.GET("/:id/revisions", h.Revisions)
+	group.GET("/:id/revisions/:number", h.Revision)
+	group.POST("/:id/revisions/:number/restore", h.Restore)
+	if h.Views != nil {
+		group.GET("/:id/views", h.ViewStats)
+	}
+}
+
+// List godoc
+// @Summary List posts
+// @Description List published posts, plus the caller's drafts, or every draft for users with posts:read. Without cursor, pages are selected with page[number] or page[offset] and the response includes the total. With cursor, the page continues after the one that returned it as next_cursor, which stays fast however deep you page.
+// @Tags v1,posts
+// @Produce json
+// @Param published query bool false "Alias of filter[published]: only published (true) or unpublished (false) posts"
+// @Param status query string false "Alias of filter[status]" Enums(draft,in_review,scheduled,published,archived)
+// @Param author query string false "Author ID or username"
+// @Param tag query string false "Tag slug"
+// @Param from query string false "Alias of filter[created_at][gte]: created at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param to query string false "Alias of filter[created_at][lt]: created before (RFC 3339 or YYYY-MM-DD)"
+// @Param sort query string false "comma separated created_at, updated_at, title or view_count, each prefixed with - for descending; cursors need a single key" default(-created_at)
+// @Param filter[field] query string false "Filter on title, slug, status, published, user_id, view_count, created_at, updated_at or published_at; filter[field][op] takes ne, in, lt, lte, gt, gte or contains"
+// @Param page[number] query int false "Page number, from 1; without cursor, the response has a Link header to the other pages" default(1)
+// @Param page[size] query int false "Page size, at most 100" default(20)
+// @Param page[offset] query int false "Posts to skip, instead of page[number]"
+// @Param limit query int false "Alias of page[size]" default(20)
+// @Param offset query int false "Alias of page[offset]"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param format query string false "Content as Markdown or sanitized HTML" Enums(markdown,html) default(markdown)
+// @Success 200 {object} v1.PostListResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/posts [get]
+func (h *PostHandler) List(c *gin.Context) {
+	if key := middleware.CurrentAPIKey(c); key != nil && !key.HasScope(authz.Permission(authz.ResourcePosts, authz.ActionRead)) {
+		_ = c.Error(middleware.NewHTTPError(http.StatusForbidden, authz.ErrForbidden.Error()))
+		return
+	}
+
+	opts, q, paged, err := listOptions(c)
+	if err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, err.Error()))
+		return
+	}
+	format, ok := contentFormat(c)
+	if !ok {
+		return
+	}
+	opts.IncludeDrafts = middleware.Can(c, authz.ActionRead, authz.Type(authz.ResourcePosts))
+	if user := middleware.CurrentUser(c); user != nil {
+		opts.ViewerID = user.ID
+	}
+
+	page, err := h.Posts.List(c.Request.Context(), opts)
+	var validationErr *auth.ValidationError
+	if errors.As(err, &validationErr) || errors.Is(err, posts.ErrInvalidCursor) {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, err.Error()))
+		return
+	}
+	if err != nil {
+		h.Logger.Error("Failed to list posts", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to list posts"))
+		return
+	}
+
+	response := PostListResponse{
+		Data:       make([]PostResponse, len(page.Posts)),
+		Total:      page.Total,
+		Limit:      opts.Limit,
+		NextCursor: page.NextCursor,
+	}
+	if page.Total != nil {
+		response.Offset = opts.Offset
+	}
+	for i := range page.Posts {
+		response.Data[i] = newPostResponseIn(&page.Posts[i], format)
+	}
+	if paged && page.Total != nil {
+		c.Header("Link", query.Links(c.Request.URL, q.Meta(*page.Total)))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// listOptions reads the filters and pagination of a List request; the
+// older published, status, from, to, limit and offset parameters are
+// aliases of posts.Schema. paged reports that page[number] selected the page.
+func listOptions(c *gin.Context) (opts posts.ListOptions, q *query.Query, paged bool, err error) {
+	q, err = posts.Schema.Parse(c.Request.URL.Query())
+	if err != nil {
+		return opts, nil, false, err
+	}
+	opts = posts.ListOptions{
+		Tag:    c.Query("tag"),
+		Sort:   c.Query("sort"),
+		Cursor: c.Query("cursor"),
+		Filter: q,
+		Limit:  q.Size,
+		Offset: q.Offset(),
+	}
+	if v := c.Query("author"); v != "" {
+		if id, err := strconv.ParseUint(v, 10, 64); err == nil {
+			opts.AuthorID = uint(id)
+		} else {
+			opts.AuthorUsername = v
+		}
+	}
+	return opts, q, q.Numbered() && opts.Cursor == "", nil
+}
+
+// maxViewDays limits the days of post views returned at once
+const maxViewDays = 366
+
+// ViewStats godoc
+// @Summary Get post views
+// @Description Get the total views of a post and its views on each UTC day from from to to, for charts. Bots and repeat views by a viewer within VIEWS_UNIQUE_WINDOW are not counted, and views are written every VIEWS_FLUSH_INTERVAL. Requires the update permission on the post.
+// @Tags v1,posts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post ID or slug"
+// @Param from query string false "First day (YYYY-MM-DD); defaults to 29 days before to"
+// @Param to query string false "Last day (YYYY-MM-DD); defaults to today"
+// @Success 200 {object} v1.PostViewsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/posts/{id}/views [get]
+func (h *PostHandler) ViewStats(c *gin.Context) {
+	post, ok := h.load(c)
+	if !ok || !middleware.Authorize(c, authz.ActionUpdate, post) {
+		return
+	}
+
+	to := views.Day(time.Now())
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "to must be a date (YYYY-MM-DD)"))
+			return
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -29)
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "from must be a date (YYYY-MM-DD)"))
+			return
+		}
+		from = t
+	}
+	if from.After(to) || to.Sub(from) >= maxViewDays*24*time.Hour {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("from must be before to and at most %d days earlier", maxViewDays-1)))
+		return
+	}
+
+	days, err := h.Views.Daily(c.Request.Context(), post.ID, from, to)
+	if err != nil {
+		h.Logger.Error("Failed to load post views", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to load post views"))
+		return
+	}
+	response := PostViewsResponse{Total: post.ViewCount, Days: make([]DailyViewsResponse, len(days))}
+	for i, day := range days {
+		response.Days[i] = DailyViewsResponse{Date: day.Day.Format("2006-01-02"), Views: day.Views}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Get godoc
+// @Summary Get post
+// @Description Get a post by numeric ID or by slug. Drafts are only found by users who may read them. Old slugs redirect to the current one, with 308 for methods other than GET.
+// @Tags v1,posts
+// @Produce json
+// @Param id path string true "Post ID or slug"
+// @Param format query string false "Content as Markdown or sanitized HTML with a table of contents" Enums(markdown,html) default(markdown)
+// @Success 200 {object} v1.PostResponse
+// @Success 301 "Moved to the post's current slug"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/posts/{id} [get]
+func (h *PostHandler) Get(c *gin.Context) {
+	format, ok := contentFormat(c)
+	if !ok {
+		return
+	}
+	post, ok := h.load(c)
+	if !ok {
+		return
+	}
+	middleware.CountView(c, h.Views, post)
+	c.JSON(http.StatusOK, newPostResponseIn(post, format))
+}
+
+// Create godoc
+// @Summary Create post
+// @Description Create a post written by the caller
+// @Tags v1,posts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body v1.CreatePostRequest true "Post"
+// @Success 201 {object} v1.PostResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/posts [post]
+func (h *PostHandler) Create(c *gin.Context) {
+	if !middleware.Authorize(c, authz.ActionCreate, authz.Type(authz.ResourcePosts)) {
+		return
+	}
+
+	var req CreatePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "title is required"))
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	input := req.input(user.ID)
+	if createNeedsPublish(input) && !middleware.Authorize(c, authz.ActionPublish, &models.Post{UserID: user.ID}) {
+		return
+	}
+
+	post, err := h.Posts.Create(c.Request.Context(), input)
+	if err != nil {
+		h.fail(c, err, "failed to create post")
+		return
+	}
+
+	h.Logger.Info("Post created", zap.Uint("post_id", post.ID), zap.Uint("user_id", user.ID))
+	c.Header("Location", "/api/v1/posts/"+strconv.FormatUint(uint64(post.ID), 10))
+	c.JSON(http.StatusCreated, NewPostResponse(post))
+}
+
+// Update godoc
+// @Summary Update post
+// @Description Change the given fields of a post, and record them as a new revision. Moving between draft and in_review only needs the update permission; other status changes and rescheduling also need the publish permission. Allowed status changes: draft to in_review, scheduled, published or archived; in_review to draft, scheduled or published; scheduled to draft or published; published to draft or archived; archived to draft or published.
+// @Tags v1,posts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post ID or slug"
+// @Param request body v1.UpdatePostRequest true "Fields to change"
+// @Success 200 {object} v1.PostResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/posts/{id} [patch]
+func (h *PostHandler) Update(c *gin.Context) {
+	post, ok := h.load(c)
+	if !ok {
+		return
+	}
+	if !middleware.Authorize(c, authz.ActionUpdate, post) {
+		return
+	}
+
+	var req UpdatePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "invalid request body"))
+		return
+	}
+	input := req.input(middleware.CurrentUser(c).ID)
+	if updateNeedsPublish(post, input) && !middleware.Authorize(c, authz.ActionPublish, post) {
+		return
+	}
+
+	err := h.Posts.Update(c.Request.Context(), post, input)
+	if err != nil {
+		h.fail(c, err, "failed to update post")
+		return
+	}
+
+	h.Logger.Info("Post updated", zap.Uint("post_id", post.ID), zap.Uint("user_id", middleware.CurrentUser(c).ID))
+	c.JSON(http.StatusOK, NewPostResponse(post))
+}
+
+// Delete godoc
+// @Summary Delete post
+// @Description Delete a post
+// @Tags v1,posts
+// @Security BearerAuth
+// @Param id path string true "Post ID or slug"
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/posts/{id} [delete]
+func (h *PostHandler) Delete(c *gin.Context) {
+	post, ok := h.load(c)
+	if !ok {
+		return
+	}
+	if !middleware.Authorize(c, authz.ActionDelete, post) {
+		return
+	}
+
+	if err := h.Posts.Delete(c.Request.Context(), post); err != nil {
+		h.Logger.Error("Failed to delete post", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to delete post"))
+		return
+	}
+	h.Logger.Info("Post deleted", zap.Uint("post_id", post.ID), zap.Uint("user_id", middleware.CurrentUser(c).ID))
+	c.Status(http.StatusNoContent)
+}
+
+// Revisions godoc
+// @Summary List post revisions
+// @Description List the revisions of a post, newest first. Every create, edit and restore records one. Requires the update permission on the post.
+// @Tags v1,posts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post ID or slug"
+// @Success 200 {array} v1.RevisionResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/posts/{id}/revisions [get]
+func (h *PostHandler) Revisions(c *gin.Context) {
+	post, ok := h.load(c)
+	if !ok || !middleware.Authorize(c, authz.ActionUpdate, post) {
+		return
+	}
+
+	revisions, err := h.Posts.Revisions(c.Request.Context(), post)
+	if err != nil {
+		h.Logger.Error("Failed to list revisions", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to list revisions"))
+		return
+	}
+	response := make([]RevisionResponse, len(revisions))
+	for i := range revisions {
+		response[i] = NewRevisionResponse(&revisions[i])
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Revision godoc
+// @Summary Get post revision
+// @Description Get a revision of a post with the line changes of its content since an earlier revision, by default the one before it. Requires the update permission on the post.
+// @Tags v1,posts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post ID or slug"
+// @Param number path int true "Revision number"
+// @Param against query int false "Earlier revision number to compare with"
+// @Success 200 {object} v1.RevisionDiffResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/posts/{id}/revisions/{number} [get]
+func (h *PostHandler) Revision(c *gin.Context) {
+	post, ok := h.load(c)
+	if !ok || !middleware.Authorize(c, authz.ActionUpdate, post) {
+		return
+	}
+	revision, ok := h.revision(c, post, c.Param("number"))
+	if !ok {
+		return
+	}
+
+	against := revision.Number - 1
+	if v := c.Query("against"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "against must be a revision number"))
+			return
+		}
+		against = n
+	}
+	var before string
+	if against > 0 {
+		earlier, ok := h.revision(c, post, strconv.Itoa(against))
+		if !ok {
+			return
+		}
+		before = earlier.Content
+	}
+
+	c.JSON(http.StatusOK, RevisionDiffResponse{
+		RevisionResponse: NewRevisionResponse(revision),
+		Against:          against,
+		Diff:             posts.Diff(before, revision.Content),
+	})
+}
+
+// Restore godoc
+// @Summary Restore post revision
+// @Description Copy the title, summary and content of a revision back into the post, which records a new revision. The slug and status are kept. Requires the update permission on the post.
+// @Tags v1,posts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post ID or slug"
+// @Param number path int true "Revision number"
+// @Success 200 {object} v1.PostResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/posts/{id}/revisions/{number}/restore [post]
+func (h *PostHandler) Restore(c *gin.Context) {
+	post, ok := h.load(c)
+	if !ok || !middleware.Authorize(c, authz.ActionUpdate, post) {
+		return
+	}
+	revision, ok := h.revision(c, post, c.Param("number"))
+	if !ok {
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	if err := h.Posts.Restore(c.Request.Context(), post, revision.Number, user.ID); err != nil {
+		h.fail(c, err, "failed to restore revision")
+		return
+	}
+	h.Logger.Info("Post revision restored", zap.Uint("post_id", post.ID), zap.Int("revision", revision.Number), zap.Uint("user_id", user.ID))
+	c.JSON(http.StatusOK, NewPostResponse(post))
+}
+
+// revision loads revision number of post, reporting 404 when it does not exist
+func (h *PostHandler) revision(c *gin.Context, post *models.Post, number string) (*models.PostRevision, bool) {
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusNotFound, posts.ErrRevisionNotFound.Error()))
+		return nil, false
+	}
+	revision, err := h.Posts.Revision(c.Request.Context(), post, n)
+	if errors.Is(err, posts.ErrRevisionNotFound) {
+		_ = c.Error(middleware.NewHTTPError(http.StatusNotFound, err.Error()))
+		return nil, false
+	}
+	if err != nil {
+		h.Logger.Error("Failed to load revision", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to load revision"))
+		return nil, false
+	}
+	return revision, true
+}
+
+// input is the service input creating req as authorID
+func (req CreatePostRequest) input(authorID uint) posts.CreateInput {
+	return posts.CreateInput{
+		Title:       req.Title,
+		Slug:        req.Slug,
+		Summary:     req.Summary,
+		Content:     req.Content,
+		Published:   req.Published,
+		Status:      req.Status,
+		ScheduledAt: req.ScheduledAt,
+		AuthorID:    authorID,
+	}
+}
+
+// input is the service input applying req as editorID
+func (req UpdatePostRequest) input(editorID uint) posts.UpdateInput {
+	return posts.UpdateInput{
+		Title:       req.Title,
+		Slug:        req.Slug,
+		Summary:     req.Summary,
+		Content:     req.Content,
+		Published:   req.Published,
+		Status:      req.Status,
+		ScheduledAt: req.ScheduledAt,
+		EditorID:    editorID,
+	}
+}
+
+// createNeedsPublish reports whether creating a post with input needs the
+// publish permission. Invalid statuses are left for Create to report.
+func createNeedsPublish(input posts.CreateInput) bool {
+	status := input.Status
+	if status == "" && input.Published {
+		status = models.PostPublished
+	}
+	return validStatus(status) && posts.NeedsPublish(models.PostDraft, status)
+}
+
+// updateNeedsPublish reports whether applying input to post needs the publish
+// permission: status changes other than between draft and in_review, and
+// rescheduling. Invalid statuses are left for Update to report.
+func updateNeedsPublish(post *models.Post, input posts.UpdateInput) bool {
+	status := input.StatusFor(post)
+	return (validStatus(status) && posts.NeedsPublish(post.Status, status)) || (input.ScheduledAt != nil && status == models.PostScheduled)
+}
+
+// validStatus reports whether status is a post status
+func validStatus(status string) bool {
+	for _, s := range posts.Statuses() {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// load finds the post named by the id path parameter, an ID or a slug, and
+// reports 404 when it does not exist or the caller may not read it
+func (h *PostHandler) load(c *gin.Context) (*models.Post, bool) {
+	post, err := findPost(c.Request.Context(), h.Posts, c.Param("id"))
+	if errors.Is(err, posts.ErrNotFound) {
+		// Old slugs redirect to the post's current one
+		moved, movedErr := h.Posts.GetByOldSlug(c.Request.Context(), c.Param("id"))
+		if movedErr == nil && middleware.Can(c, authz.ActionRead, moved) {
+			middleware.RedirectParam(c, "id", moved.Slug)
+			return nil, false
+		}
+	}
+	if errors.Is(err, posts.ErrNotFound) || (err == nil && !middleware.Can(c, authz.ActionRead, post)) {
+		_ = c.Error(middleware.NewHTTPError(http.StatusNotFound, "post not found"))
+		return nil, false
+	}
+	if err != nil {
+		h.Logger.Error("Failed to load post", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to load post"))
+		return nil, false
+	}
+	return post, true
+}
+
+// findPost returns the post named by ref, an ID or a slug
+func findPost(ctx context.Context, service posts.Service, ref string) (*models.Post, error) {
+	if id, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		return service.Get(ctx, uint(id))
+	}
+	return service.GetBySlug(ctx, ref)
+}
+
+// fail reports validation errors as 400 and anything else as 500
+func (h *PostHandler) fail(c *gin.Context, err error, message string) {
+	var validationErr *auth.ValidationError
+	if errors.As(err, &validationErr) {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, validationErr.Error()))
+		return
+	}
+	h.Logger.Error("Request failed", zap.String("message", message), zap.Error(err))
+	_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, message))
+}
@@ -0,0 +1,458 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goapp/api/middleware"
+	"goapp/internal/apikeys"
+	"goapp/internal/authz"
+	"goapp/internal/comments"
+	"goapp/internal/config"
+	"goapp/internal/container"
+	"goapp/internal/models"
+	"goapp/internal/posts"
+	"goapp/internal/search"
+	"goapp/internal/tags"
+	"goapp/internal/tokens"
+	"goapp/internal/views"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupPostRouter returns the posts, comments, tags, bulk and batch router and
+// an all-scopes key for each of jane, bob and the editor, plus a read-only key of bob's.
+// Jane has written a published post and a draft; replies nest one level deep.
+func setupPostRouter(t *testing.T) (*gin.Engine, map[string]string) {
+	router, keys, _ := setupPostAPI(t)
+	return router, keys
+}
+
+// setupPostAPI is setupPostRouter that also returns the container, whose
+// view counter writes views when flushed and whose token service issues
+// access tokens the router accepts
+func setupPostAPI(t *testing.T) (*gin.Engine, map[string]string, *container.Container) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Permission{}, &models.Role{}, &models.User{}, &models.APIKey{}, &models.Post{}, &models.PostRevision{}, &models.Tag{}, &models.SlugRedirect{}, &models.Comment{}, &models.PostDailyView{}, &models.RefreshToken{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	if err := search.Migrate(db); err != nil {
+		t.Fatalf("Failed to create search index: %v", err)
+	}
+	roles := authz.NewRoleStore(db)
+	if err := roles.Seed(ctx); err != nil {
+		t.Fatalf("Seed() error = %v", err)
+	}
+
+	c := setupTestContainer(t)
+	c.APIKeys = apikeys.NewService(db, config.APIKeyConfig{TokenPrefix: "goapp", DefaultExpiration: time.Hour})
+	c.Posts = posts.NewService(db)
+	c.Comments = comments.NewService(db, config.CommentsConfig{MaxDepth: 1, MaxLength: 1000})
+	c.Tags = tags.NewService(db)
+	c.Search = search.NewService(db)
+	c.Views = views.NewCounter(db, config.ViewsConfig{FlushInterval: time.Minute, UniqueWindow: time.Hour}, c.Logger)
+	c.Database = testDatabase{db: db}
+	jwtKeys, err := tokens.NewKeySet([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewKeySet() error = %v", err)
+	}
+	c.Tokens = tokens.NewService(db, jwtKeys, config.JWTConfig{Issuer: "goapp", Audience: "goapp-api", Expiration: time.Minute, RefreshExpiration: time.Hour})
+	c.Config.Bulk = config.BulkConfig{MaxItems: 10, MaxBatchRequests: 5}
+
+	keys := map[string]string{}
+	for _, name := range []string{"jane", "bob", "editor"} {
+		user := &models.User{Email: name + "@example.com", Username: name, PasswordHash: "hash", Active: true}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		if name == "editor" {
+			if err := roles.Grant(ctx, user.ID, authz.RoleEditor); err != nil {
+				t.Fatalf("Grant() error = %v", err)
+			}
+		}
+		token, _, err := c.APIKeys.Create(ctx, apikeys.CreateInput{Name: "bootstrap", UserID: user.ID, Scopes: []string{"*"}})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		keys[name] = token
+
+		switch name {
+		case "jane":
+			for _, input := range []posts.CreateInput{
+				{Title: "Hello World", Published: true, AuthorID: user.ID},
+				{Title: "Secret Draft", AuthorID: user.ID},
+			} {
+				if _, err := c.Posts.Create(ctx, input); err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+			}
+		case "bob":
+			readOnly, _, err := c.APIKeys.Create(ctx, apikeys.CreateInput{Name: "reader", UserID: user.ID, Scopes: []string{"posts:read"}})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			keys["bob-readonly"] = readOnly
+		}
+	}
+
+	router := gin.New()
+	group := router.Group("/api/v1",
+		middleware.JSONErrors(),
+		middleware.JWTAuth(c.Tokens, c.Tokens, c.Logger),
+		middleware.APIKeyAuth(c.APIKeys, c.Logger),
+		middleware.Policies(authz.DefaultPolicy()))
+	NewPostHandler(c).RegisterRoutes(group)
+	NewCommentHandler(c).RegisterRoutes(group)
+	NewTagHandler(c).RegisterRoutes(group)
+	NewSearchHandler(c).RegisterRoutes(group)
+	NewBulkHandler(c).RegisterRoutes(group)
+	NewBatchHandler(c, router).RegisterRoutes(group)
+	NewTransferHandler(c).RegisterRoutes(group)
+	return router, keys, c
+}
+
+func listPosts(t *testing.T, router *gin.Engine, query, key string) PostListResponse {
+	t.Helper()
+	w := sendJSON(router, http.MethodGet, "/api/v1/posts?"+query, "", key)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var list PostListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return list
+}
+
+func TestPostHandler_List(t *testing.T) {
+	router, keys := setupPostRouter(t)
+
+	tests := []struct {
+		name  string
+		query string
+		key   string
+		want  int
+	}{
+		{"Anonymous", "", "", 1},
+		{"Author", "", keys["jane"], 2},
+		{"OtherUser", "", keys["bob"], 1},
+		{"Editor", "", keys["editor"], 2},
+		{"Drafts", "published=false", keys["editor"], 1},
+		{"ByUsername", "author=bob", keys["editor"], 0},
+		{"DateRange", "from=2000-01-01&to=2000-01-02", keys["editor"], 0},
+		{"Filter", "filter[title][contains]=HELLO", keys["editor"], 1},
+		{"FilterVisibility", "filter[published]=false", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := listPosts(t, router, tt.query, tt.key)
+			if len(list.Data) != tt.want || list.Total == nil || *list.Total != int64(tt.want) {
+				t.Errorf("Expected %d posts, got %+v", tt.want, list)
+			}
+		})
+	}
+
+	t.Run("Cursor", func(t *testing.T) {
+		first := listPosts(t, router, "limit=1&sort=title", keys["editor"])
+		if len(first.Data) != 1 || first.Data[0].Slug != "hello-world" || first.NextCursor == "" {
+			t.Fatalf("Unexpected first page %+v", first)
+		}
+		second := listPosts(t, router, "limit=1&sort=title&cursor="+url.QueryEscape(first.NextCursor), keys["editor"])
+		if len(second.Data) != 1 || second.Data[0].Slug != "secret-draft" || second.NextCursor != "" || second.Total != nil {
+			t.Errorf("Unexpected second page %+v", second)
+		}
+	})
+
+	t.Run("MultipleKeys", func(t *testing.T) {
+		list := listPosts(t, router, "sort=-view_count,title", keys["editor"])
+		if len(list.Data) != 2 || list.Data[0].Slug != "hello-world" || list.NextCursor != "" {
+			t.Errorf("Unexpected posts %+v", list)
+		}
+	})
+
+	t.Run("Pages", func(t *testing.T) {
+		w := sendJSON(router, http.MethodGet, "/api/v1/posts?sort=title&page[size]=1&page[number]=2", "", keys["editor"])
+		var list PostListResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list.Data) != 1 || list.Data[0].Slug != "secret-draft" || list.Offset != 1 {
+			t.Fatalf("Expected the second post by title, got %s", w.Body.String())
+		}
+		if link := w.Header().Get("Link"); !strings.Contains(link, `page%5Bnumber%5D=1&page%5Bsize%5D=1&sort=title>; rel="prev"`) {
+			t.Errorf("Expected a Link header to the first page, got %q", link)
+		}
+	})
+
+	for _, query := range []string{"limit=0", "sort=password", "from=yesterday", "published=maybe", "cursor=bogus", "filter[content]=x", "page[number]=100001"} {
+		if w := sendJSON(router, http.MethodGet, "/api/v1/posts?"+query, "", ""); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, query, w.Code)
+		}
+	}
+}
+
+func TestPostHandler_CRUD(t *testing.T) {
+	router, keys := setupPostRouter(t)
+
+	if w := sendJSON(router, http.MethodGet, "/api/v1/posts/secret-draft", "", keys["bob"]); w.Code != http.StatusNotFound {
+		t.Errorf("Expected other users not to find drafts, got %d", w.Code)
+	}
+	if w := sendJSON(router, http.MethodGet, "/api/v1/posts/1", "", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected published post by ID, got %d", w.Code)
+	}
+
+	if w := sendJSON(router, http.MethodPost, "/api/v1/posts", `{"title":"Anonymous"}`, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+	if w := sendJSON(router, http.MethodPost, "/api/v1/posts", `{"title":"Read only"}`, keys["bob-readonly"]); w.Code != http.StatusForbidden {
+		t.Errorf("Expected read-only key to be rejected, got %d", w.Code)
+	}
+	if w := sendJSON(router, http.MethodPost, "/api/v1/posts", `{"title":"Taken","slug":"hello-world"}`, keys["bob"]); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected duplicate slug to be rejected, got %d", w.Code)
+	}
+
+	w := sendJSON(router, http.MethodPost, "/api/v1/posts", `{"title":"Bob's Post","content":"Hi","published":true}`, keys["bob"])
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created PostResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if created.Slug != "bob-s-post" || created.Author.Username != "bob" || !created.Published {
+		t.Errorf("Unexpected post %+v", created)
+	}
+
+	if w := sendJSON(router, http.MethodPatch, "/api/v1/posts/bob-s-post", `{"title":"Hijacked"}`, keys["jane"]); w.Code != http.StatusForbidden {
+		t.Errorf("Expected users not to update others' posts, got %d", w.Code)
+	}
+	w = sendJSON(router, http.MethodPatch, "/api/v1/posts/bob-s-post", `{"title":"Edited","published":false}`, keys["editor"])
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var updated PostResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &updated); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if updated.Title != "Edited" || updated.Published || updated.Content != "Hi" {
+		t.Errorf("Unexpected post %+v", updated)
+	}
+
+	if w := sendJSON(router, http.MethodPatch, "/api/v1/posts/bob-s-post", `{"slug":"bobs-post"}`, keys["bob"]); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	w = sendJSON(router, http.MethodGet, "/api/v1/posts/bob-s-post?format=html", "", keys["bob"])
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/api/v1/posts/bobs-post?format=html" {
+		t.Errorf("Expected old slug to redirect, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := sendJSON(router, http.MethodGet, "/api/v1/posts/bob-s-post", "", keys["jane"]); w.Code != http.StatusNotFound {
+		t.Errorf("Expected old slugs of unpublished posts not to redirect for others, got %d", w.Code)
+	}
+	w = sendJSON(router, http.MethodPatch, "/api/v1/posts/bob-s-post", `{"title":"Moved"}`, keys["bob"])
+	if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != "/api/v1/posts/bobs-post" {
+		t.Errorf("Expected old slug to redirect with 308, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	if w := sendJSON(router, http.MethodDelete, "/api/v1/posts/bobs-post", "", keys["bob"]); w.Code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if w := sendJSON(router, http.MethodGet, "/api/v1/posts/bobs-post", "", keys["bob"]); w.Code != http.StatusNotFound {
+		t.Errorf("Expected deleted post to be gone, got %d", w.Code)
+	}
+}
+
+func TestPostHandler_Format(t *testing.T) {
+	router, keys := setupPostRouter(t)
+
+	body := `{"title":"Guide","content":"# Install\n\nRun **make**.\n\n## Usage\n\n<script>alert(1)</script>"}`
+	if w := sendJSON(router, http.MethodPost, "/api/v1/posts", body, keys["jane"]); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var post PostResponse
+	w := sendJSON(router, http.MethodGet, "/api/v1/posts/guide", "", keys["jane"])
+	if err := json.Unmarshal(w.Body.Bytes(), &post); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !strings.HasPrefix(post.Content, "# Install") || post.TOC != nil || post.Summary != "Run make." {
+		t.Errorf("Expected Markdown and a generated summary by default, got %+v", post)
+	}
+
+	post = PostResponse{}
+	w = sendJSON(router, http.MethodGet, "/api/v1/posts/guide?format=html", "", keys["jane"])
+	if err := json.Unmarshal(w.Body.Bytes(), &post); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !strings.Contains(post.Content, "<strong>make</strong>") || strings.Contains(post.Content, "<script") {
+		t.Errorf("Expected sanitized HTML, got %q", post.Content)
+	}
+	if len(post.TOC) != 2 || post.TOC[1].ID != "section-usage" {
+		t.Errorf("Expected a table of contents, got %+v", post.TOC)
+	}
+
+	if list := listPosts(t, router, "format=html&sort=-created_at", keys["jane"]); !strings.Contains(list.Data[0].Content, "<h1 id=\"section-install\">") {
+		t.Errorf("Expected listed posts as HTML, got %q", list.Data[0].Content)
+	}
+	if w := sendJSON(router, http.MethodGet, "/api/v1/posts/guide?format=pdf", "", keys["jane"]); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected unknown formats to be rejected with %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestPostHandler_Workflow(t *testing.T) {
+	router, keys := setupPostRouter(t)
+
+	patch := func(body, key string) PostResponse {
+		t.Helper()
+		w := sendJSON(router, http.MethodPatch, "/api/v1/posts/secret-draft", body, key)
+		if w.Code != http.StatusOK {
+			t.Fatalf("PATCH %s: expected status %d, got %d: %s", body, http.StatusOK, w.Code, w.Body.String())
+		}
+		var post PostResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &post); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return post
+	}
+
+	if post := patch(`{"status":"in_review"}`, keys["jane"]); post.Status != "in_review" || post.Published {
+		t.Errorf("Expected the post in review, got %+v", post)
+	}
+	if w := sendJSON(router, http.MethodPatch, "/api/v1/posts/secret-draft", `{"status":"archived"}`, keys["jane"]); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "cannot change from in_review to archived") {
+		t.Errorf("Expected the transition to be refused, got %d: %s", w.Code, w.Body.String())
+	}
+	scheduledAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	post := patch(`{"status":"scheduled","scheduled_at":"`+scheduledAt+`"}`, keys["editor"])
+	if post.Status != "scheduled" || post.ScheduledAt == nil || post.Published {
+		t.Errorf("Expected a scheduled post, got %+v", post)
+	}
+	if list := listPosts(t, router, "status=scheduled", keys["editor"]); len(list.Data) != 1 {
+		t.Errorf("Expected one scheduled post, got %d", len(list.Data))
+	}
+	if w := sendJSON(router, http.MethodGet, "/api/v1/posts?status=deleted", "", keys["editor"]); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected unknown statuses to be refused, got %d", w.Code)
+	}
+	patch(`{"content":"New body"}`, keys["jane"])
+
+	if w := sendJSON(router, http.MethodGet, "/api/v1/posts/hello-world/revisions", "", keys["bob"]); w.Code != http.StatusForbidden {
+		t.Errorf("Expected other users not to see revisions, got %d", w.Code)
+	}
+	w := sendJSON(router, http.MethodGet, "/api/v1/posts/secret-draft/revisions", "", keys["jane"])
+	var revisions []RevisionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &revisions); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(revisions) != 4 || revisions[0].Number != 4 || revisions[0].Editor == nil || revisions[0].Editor.Username != "jane" || revisions[1].Editor.Username != "editor" {
+		t.Errorf("Expected four revisions with their editors, got %+v", revisions)
+	}
+
+	w = sendJSON(router, http.MethodGet, "/api/v1/posts/secret-draft/revisions/4", "", keys["jane"])
+	var diff RevisionDiffResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &diff); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if diff.Against != 3 || len(diff.Diff) != 1 || diff.Diff[0].Op != posts.DiffInsert || diff.Diff[0].Text != "New body" {
+		t.Errorf("Expected the added line, got %+v", diff)
+	}
+	if w := sendJSON(router, http.MethodGet, "/api/v1/posts/secret-draft/revisions/99", "", keys["jane"]); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	w = sendJSON(router, http.MethodPost, "/api/v1/posts/secret-draft/revisions/1/restore", "", keys["jane"])
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"content":""`) || !strings.Contains(w.Body.String(), `"status":"scheduled"`) {
+		t.Errorf("Expected the content restored and the status kept, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestPostHandler_Views(t *testing.T) {
+	router, keys, c := setupPostAPI(t)
+
+	view := func(key, userAgent string) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/posts/hello-world", nil)
+		req.Header.Set("User-Agent", userAgent)
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	}
+	browser := "Mozilla/5.0 (X11; Linux x86_64) Firefox/130.0"
+	view("", browser)
+	view("", browser)                  // the same address again
+	view(keys["bob"], browser)         // another reader
+	view(keys["jane"], browser)        // the author
+	view(keys["editor"], "curl/8.5.0") // a program
+	if err := c.Views.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	if w := sendJSON(router, http.MethodGet, "/api/v1/posts/hello-world/views", "", keys["bob"]); w.Code != http.StatusForbidden {
+		t.Errorf("Expected readers not to see views, got %d", w.Code)
+	}
+	today := time.Now().UTC().Format("2006-01-02")
+	w := sendJSON(router, http.MethodGet, "/api/v1/posts/hello-world/views?from="+today, "", keys["jane"])
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var stats PostViewsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if stats.Total != 2 || len(stats.Days) != 1 || stats.Days[0] != (DailyViewsResponse{Date: today, Views: 2}) {
+		t.Errorf("Expected 2 views today, got %+v", stats)
+	}
+
+	w = sendJSON(router, http.MethodGet, "/api/v1/posts/hello-world/views", "", keys["editor"])
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil || len(stats.Days) != 30 || stats.Days[29].Date != today {
+		t.Errorf("Expected the last 30 days by default, got %d days, %v", len(stats.Days), err)
+	}
+	for _, query := range []string{"from=yesterday", "from=2024-02-01&to=2024-01-01", "from=2020-01-01&to=2024-01-01"} {
+		if w := sendJSON(router, http.MethodGet, "/api/v1/posts/hello-world/views?"+query, "", keys["jane"]); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected %q to be rejected, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestPostHandler_AccessToken(t *testing.T) {
+	router, _, c := setupPostAPI(t)
+	ctx := context.Background()
+	db := c.Database.DB()
+
+	var jane models.User
+	if err := db.Where("username = ?", "jane").First(&jane).Error; err != nil {
+		t.Fatalf("Failed to load user: %v", err)
+	}
+	pair, err := c.Tokens.Issue(ctx, &jane)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	w := sendJSON(router, http.MethodPost, "/api/v1/posts", `{"title":"Token Post"}`, pair.AccessToken)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var post PostResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &post); err != nil || post.Author.Username != "jane" {
+		t.Errorf("Expected the post to be written by the token's user, got %s", w.Body.String())
+	}
+
+	// Signing out everywhere invalidates tokens already issued
+	if err := db.Model(&jane).UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error; err != nil {
+		t.Fatalf("Failed to update user: %v", err)
+	}
+	if w := sendJSON(router, http.MethodPost, "/api/v1/posts", `{"title":"Stale"}`, pair.AccessToken); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a revoked token to be refused, got %d", w.Code)
+	}
+}
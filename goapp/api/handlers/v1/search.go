@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/container"
+	"goapp/internal/logging"
+	"goapp/internal/search"
+)
+
+// SearchResponse is a page of search results, best first
+type SearchResponse struct {
+	Data   []search.Result `json:"data"`
+	Total  int64           `json:"total"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset,omitempty"`
+}
+
+// SearchHandler searches posts and comments
+type SearchHandler struct {
+	Logger logging.Logger
+	Search search.Service
+}
+
+// NewSearchHandler creates a new search handler with injected dependencies
+func NewSearchHandler(container *container.Container) *SearchHandler {
+	return &SearchHandler{
+		Logger: container.Logger,
+		Search: container.Search,
+	}
+}
+
+// RegisterRoutes registers the search route; it is disabled without a database
+func (h *SearchHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	if h.Search == nil {
+		return
+	}
+	rg.GET("/search", h.Find)
+}
+
+// Find godoc
+// @Summary Search posts and comments
+// @Description Full-text search of published posts and the comments on them, best match first. Every word must match, the last one also as a prefix. Snippets are HTML with the matches in <mark>.
+// @Tags v1,search
+// @Produce json
+// @Param q query string true "Words to search for"
+// @Param type query string false "post or comment"
+// @Param tag query string false "Slug of a tag of the post"
+// @Param author query string false "ID or username of the author"
+// @Param page[number] query int false "Page number, from 1" default(1)
+// @Param page[size] query int false "Page size, at most 100" default(20)
+// @Param page[offset] query int false "Results to skip, instead of page[number]"
+// @Param limit query int false "Alias of page[size]" default(20)
+// @Param offset query int false "Alias of page[offset]"
+// @Success 200 {object} v1.SearchResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/search [get]
+func (h *SearchHandler) Find(c *gin.Context) {
+	paged, err := search.Schema.Parse(c.Request.URL.Query())
+	if err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, err.Error()))
+		return
+	}
+	q := search.Query{
+		Text:   c.Query("q"),
+		Kind:   c.Query("type"),
+		Tag:    c.Query("tag"),
+		Author: c.Query("author"),
+		Limit:  paged.Size,
+		Offset: paged.Offset(),
+	}
+	if len(search.Terms(q.Text)) == 0 {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, (&auth.ValidationError{Fields: map[string]string{"q": "must contain a word"}}).Error()))
+		return
+	}
+
+	page, err := h.Search.Search(c.Request.Context(), q)
+	var validationErr *auth.ValidationError
+	if errors.As(err, &validationErr) {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, validationErr.Error()))
+		return
+	}
+	if err != nil {
+		h.Logger.Error("Failed to search", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to search"))
+		return
+	}
+	c.JSON(http.StatusOK, SearchResponse{Data: page.Results, Total: page.Total, Limit: q.Limit, Offset: q.Offset})
+}
@@ -0,0 +1,36 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSearchHandler(t *testing.T) {
+	router, _ := setupPostRouter(t)
+
+	w := sendJSON(router, http.MethodGet, "/api/v1/search?q=hello+wor&limit=5", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response SearchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Total != 1 || response.Limit != 5 || len(response.Data) != 1 {
+		t.Fatalf("Expected one result, got %+v", response)
+	}
+	if result := response.Data[0]; result.PostSlug != "hello-world" || result.Author != "jane" || !strings.Contains(result.Snippet, "<mark>") {
+		t.Errorf("Expected the published post with a snippet, got %+v", result)
+	}
+
+	if w := sendJSON(router, http.MethodGet, "/api/v1/search?q=secret", "", ""); !strings.Contains(w.Body.String(), `"total":0`) {
+		t.Errorf("Expected drafts not to be found, got %s", w.Body.String())
+	}
+	for _, query := range []string{"", "q=%3F", "q=go&type=user", "q=go&limit=101", "q=go&offset=-1", "q=go&offset=10000001", "q=go&page[number]=100001"} {
+		if w := sendJSON(router, http.MethodGet, "/api/v1/search?"+query, "", ""); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected %q to be rejected, got %d", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatusResponse describes the running API version
+type StatusResponse struct {
+	App     string `json:"app"`
+	Version string `json:"version"`
+	Status  string `json:"status"`
+}
+
+// Status godoc
+// @Summary API status
+// @Description Report the API version serving the request
+// @Tags v1
+// @Produce json
+// @Success 200 {object} v1.StatusResponse
+// @Router /api/v1/status [get]
+func (h *Handler) Status(c *gin.Context) {
+	c.JSON(http.StatusOK, StatusResponse{
+		App:     h.AppName,
+		Version: Version,
+		Status:  "UP",
+	})
+}
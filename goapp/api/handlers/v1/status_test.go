@@ -0,0 +1,57 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/config"
+	"goapp/internal/container"
+	"goapp/internal/logging"
+)
+
+func setupTestContainer(t *testing.T) *container.Container {
+	cfg := config.Config{
+		App: config.AppConfig{Name: "test-app"},
+		Logger: config.LoggerConfig{
+			Environment: "test",
+			WriteStdout: true,
+		},
+	}
+
+	logger, err := logging.New(cfg.Logger)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	return &container.Container{
+		Config: cfg,
+		Logger: logger,
+	}
+}
+
+func TestStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := New(setupTestContainer(t))
+
+	router := gin.New()
+	handler.RegisterRoutes(router.Group("/api/v1"))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/status", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp StatusResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Version != "v1" || resp.App != "test-app" || resp.Status != "UP" {
+		t.Errorf("Unexpected status response: %+v", resp)
+	}
+}
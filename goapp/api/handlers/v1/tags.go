@@ -0,0 +1,356 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/api/middleware"
+	"goapp/internal/authz"
+	"goapp/internal/container"
+	"goapp/internal/logging"
+	"goapp/internal/models"
+	"goapp/internal/tags"
+)
+
+// TagResponse describes a tag
+type TagResponse struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+	// PostCount is the number of published posts carrying the tag; it is only set when listing tags
+	PostCount *int64 `json:"post_count,omitempty"`
+}
+
+// TagRequest names a tag to create, or the new name of a tag
+type TagRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// MergeTagRequest names the tag to merge into
+type MergeTagRequest struct {
+	Into string `json:"into" binding:"required"` // slug of the surviving tag
+}
+
+// AttachTagsRequest lists tags to add to a post; unknown names create tags
+type AttachTagsRequest struct {
+	Tags []string `json:"tags" binding:"required"`
+}
+
+// NewTagResponse describes tag
+func NewTagResponse(tag *models.Tag) TagResponse {
+	return TagResponse{ID: tag.ID, Name: tag.Name, Slug: tag.Slug}
+}
+
+func newTagResponses(list []models.Tag) []TagResponse {
+	responses := make([]TagResponse, len(list))
+	for i := range list {
+		responses[i] = NewTagResponse(&list[i])
+	}
+	return responses
+}
+
+// TagHandler serves tags and the tags of posts
+type TagHandler struct {
+	Logger logging.Logger
+	Tags   tags.Service
+	// posts finds the post named in /posts/{id}/tags
+	posts *PostHandler
+}
+
+// NewTagHandler creates a new tags handler with injected dependencies
+func NewTagHandler(container *container.Container) *TagHandler {
+	return &TagHandler{
+		Logger: container.Logger,
+		Tags:   container.Tags,
+		posts:  NewPostHandler(container),
+	}
+}
+
+// RegisterRoutes registers the tags routes; they are disabled without a database
+func (h *TagHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	if h.Tags == nil || h.posts.Posts == nil {
+		return
+	}
+
+	group := rg.Group("/tags")
+	group.GET("", h.List)
+	group.POST("", h.Create)
+	group.GET("/:slug", h.Get)
+	group.PATCH("/:slug", h.Rename)
+	group.POST("/:slug/merge", h.Merge)
+	group.DELETE("/:slug", h.Delete)
+
+	rg.POST("/posts/:id/tags", h.Attach)
+	rg.DELETE("/posts/:id/tags/:slug", h.Detach)
+}
+
+// List godoc
+// @Summary List tags
+// @Description List every tag by name with the number of published posts carrying it
+// @Tags v1,tags
+// @Produce json
+// @Success 200 {array} v1.TagResponse
+// @Router /api/v1/tags [get]
+func (h *TagHandler) List(c *gin.Context) {
+	counts, err := h.Tags.List(c.Request.Context())
+	if err != nil {
+		h.Logger.Error("Failed to list tags", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to list tags"))
+		return
+	}
+
+	response := make([]TagResponse, len(counts))
+	for i := range counts {
+		response[i] = NewTagResponse(&counts[i].Tag)
+		response[i].PostCount = &counts[i].PostCount
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Get godoc
+// @Summary Get tag
+// @Description Get a tag by slug. List its posts with GET /api/v1/posts?tag={slug}. Slugs of renamed or merged tags redirect to the current one.
+// @Tags v1,tags
+// @Produce json
+// @Param slug path string true "Tag slug"
+// @Success 200 {object} v1.TagResponse
+// @Success 301 "Moved to the tag's current slug"
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/tags/{slug} [get]
+func (h *TagHandler) Get(c *gin.Context) {
+	tag, ok := h.load(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, NewTagResponse(tag))
+}
+
+// Create godoc
+// @Summary Create tag
+// @Description Create a tag; its slug is derived from the name
+// @Tags v1,tags
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body v1.TagRequest true "Tag"
+// @Success 201 {object} v1.TagResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/tags [post]
+func (h *TagHandler) Create(c *gin.Context) {
+	if !middleware.Authorize(c, authz.ActionCreate, authz.Type(authz.ResourceTags)) {
+		return
+	}
+
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "name is required"))
+		return
+	}
+
+	tag, err := h.Tags.Create(c.Request.Context(), req.Name)
+	if err != nil {
+		h.posts.fail(c, err, "failed to create tag")
+		return
+	}
+	h.Logger.Info("Tag created", zap.Uint("tag_id", tag.ID), zap.Uint("user_id", middleware.CurrentUser(c).ID))
+	c.JSON(http.StatusCreated, NewTagResponse(tag))
+}
+
+// Rename godoc
+// @Summary Rename tag
+// @Description Change the name and slug of a tag. The name or slug of another tag is rejected as taken; merge the tags instead.
+// @Tags v1,tags
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param slug path string true "Tag slug"
+// @Param request body v1.TagRequest true "New name"
+// @Success 200 {object} v1.TagResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/tags/{slug} [patch]
+func (h *TagHandler) Rename(c *gin.Context) {
+	tag, ok := h.load(c)
+	if !ok || !middleware.Authorize(c, authz.ActionUpdate, tag) {
+		return
+	}
+
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "name is required"))
+		return
+	}
+
+	renamed, err := h.Tags.Rename(c.Request.Context(), tag, req.Name)
+	if err != nil {
+		h.posts.fail(c, err, "failed to rename tag")
+		return
+	}
+	h.Logger.Info("Tag renamed", zap.Uint("tag_id", renamed.ID), zap.Uint("user_id", middleware.CurrentUser(c).ID))
+	c.JSON(http.StatusOK, NewTagResponse(renamed))
+}
+
+// Merge godoc
+// @Summary Merge tags
+// @Description Move the posts of a tag to another tag and delete it
+// @Tags v1,tags
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param slug path string true "Slug of the tag to merge away"
+// @Param request body v1.MergeTagRequest true "Surviving tag"
+// @Success 200 {object} v1.TagResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/tags/{slug}/merge [post]
+func (h *TagHandler) Merge(c *gin.Context) {
+	source, ok := h.load(c)
+	if !ok {
+		return
+	}
+
+	var req MergeTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "into is required"))
+		return
+	}
+	target, err := h.Tags.GetBySlug(c.Request.Context(), req.Into)
+	if errors.Is(err, tags.ErrNotFound) {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "into: tag not found"))
+		return
+	}
+	if err != nil {
+		h.posts.fail(c, err, "failed to merge tags")
+		return
+	}
+	if !middleware.Authorize(c, authz.ActionUpdate, target) || !middleware.Authorize(c, authz.ActionDelete, source) {
+		return
+	}
+
+	if err := h.Tags.Merge(c.Request.Context(), source, target); err != nil {
+		h.posts.fail(c, err, "failed to merge tags")
+		return
+	}
+	h.Logger.Info("Tags merged", zap.Uint("source_id", source.ID), zap.Uint("target_id", target.ID), zap.Uint("user_id", middleware.CurrentUser(c).ID))
+	c.JSON(http.StatusOK, NewTagResponse(target))
+}
+
+// Delete godoc
+// @Summary Delete tag
+// @Description Remove a tag from its posts and delete it
+// @Tags v1,tags
+// @Security BearerAuth
+// @Param slug path string true "Tag slug"
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/tags/{slug} [delete]
+func (h *TagHandler) Delete(c *gin.Context) {
+	tag, ok := h.load(c)
+	if !ok || !middleware.Authorize(c, authz.ActionDelete, tag) {
+		return
+	}
+
+	if err := h.Tags.Delete(c.Request.Context(), tag); err != nil {
+		h.Logger.Error("Failed to delete tag", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to delete tag"))
+		return
+	}
+	h.Logger.Info("Tag deleted", zap.Uint("tag_id", tag.ID), zap.Uint("user_id", middleware.CurrentUser(c).ID))
+	c.Status(http.StatusNoContent)
+}
+
+// Attach godoc
+// @Summary Tag post
+// @Description Add tags to a post by name, creating tags that do not exist yet, and return the post's tags
+// @Tags v1,tags,posts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post ID or slug"
+// @Param request body v1.AttachTagsRequest true "Tag names"
+// @Success 200 {array} v1.TagResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/posts/{id}/tags [post]
+func (h *TagHandler) Attach(c *gin.Context) {
+	post, ok := h.posts.load(c)
+	if !ok || !middleware.Authorize(c, authz.ActionUpdate, post) {
+		return
+	}
+
+	var req AttachTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "tags is required"))
+		return
+	}
+
+	list, err := h.Tags.Attach(c.Request.Context(), post, req.Tags)
+	if err != nil {
+		h.posts.fail(c, err, "failed to tag post")
+		return
+	}
+	c.JSON(http.StatusOK, newTagResponses(list))
+}
+
+// Detach godoc
+// @Summary Untag post
+// @Description Remove a tag from a post and return the post's remaining tags
+// @Tags v1,tags,posts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post ID or slug"
+// @Param slug path string true "Tag slug"
+// @Success 200 {array} v1.TagResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/posts/{id}/tags/{slug} [delete]
+func (h *TagHandler) Detach(c *gin.Context) {
+	post, ok := h.posts.load(c)
+	if !ok || !middleware.Authorize(c, authz.ActionUpdate, post) {
+		return
+	}
+	tag, ok := h.load(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.Tags.Detach(c.Request.Context(), post, tag)
+	if err != nil {
+		h.posts.fail(c, err, "failed to untag post")
+		return
+	}
+	c.JSON(http.StatusOK, newTagResponses(list))
+}
+
+// load finds the tag named by the slug path parameter, reporting 404 when it does not exist
+func (h *TagHandler) load(c *gin.Context) (*models.Tag, bool) {
+	tag, err := h.Tags.GetBySlug(c.Request.Context(), c.Param("slug"))
+	if errors.Is(err, tags.ErrNotFound) {
+		// Old slugs redirect to the tag's current one
+		if moved, movedErr := h.Tags.GetByOldSlug(c.Request.Context(), c.Param("slug")); movedErr == nil {
+			middleware.RedirectParam(c, "slug", moved.Slug)
+			return nil, false
+		}
+		_ = c.Error(middleware.NewHTTPError(http.StatusNotFound, "tag not found"))
+		return nil, false
+	}
+	if err != nil {
+		h.Logger.Error("Failed to load tag", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to load tag"))
+		return nil, false
+	}
+	return tag, true
+}
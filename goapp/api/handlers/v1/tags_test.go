@@ -0,0 +1,77 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTagHandler(t *testing.T) {
+	router, keys := setupPostRouter(t)
+
+	if w := sendJSON(router, http.MethodPost, "/api/v1/posts/secret-draft/tags", `{"tags":["Go"]}`, keys["bob"]); w.Code != http.StatusNotFound {
+		t.Errorf("Expected other users' drafts not to be found, got %d", w.Code)
+	}
+	if w := sendJSON(router, http.MethodPost, "/api/v1/posts/hello-world/tags", `{"tags":["Go"]}`, keys["bob"]); w.Code != http.StatusForbidden {
+		t.Errorf("Expected users not to tag others' posts, got %d", w.Code)
+	}
+
+	w := sendJSON(router, http.MethodPost, "/api/v1/posts/hello-world/tags", `{"tags":["Go","Golang"]}`, keys["jane"])
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var attached []TagResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &attached); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(attached) != 2 || attached[0].Slug != "go" {
+		t.Errorf("Expected go and golang, got %+v", attached)
+	}
+	if list := listPosts(t, router, "tag=golang", ""); len(list.Data) != 1 {
+		t.Errorf("Expected the post to be listed under golang, got %d posts", len(list.Data))
+	}
+
+	if w := sendJSON(router, http.MethodPost, "/api/v1/tags", `{"name":"Rust"}`, keys["jane"]); w.Code != http.StatusForbidden {
+		t.Errorf("Expected users not to manage tags, got %d", w.Code)
+	}
+	if w := sendJSON(router, http.MethodPost, "/api/v1/tags", `{"name":"GO"}`, keys["editor"]); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a duplicate tag to be rejected, got %d", w.Code)
+	}
+	if w := sendJSON(router, http.MethodPatch, "/api/v1/tags/golang", `{"name":"Go"}`, keys["editor"]); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "already taken") {
+		t.Errorf("Expected renaming onto another tag to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := sendJSON(router, http.MethodPost, "/api/v1/tags/golang/merge", `{"into":"go"}`, keys["editor"]); w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if w := sendJSON(router, http.MethodPatch, "/api/v1/tags/go", `{"name":"Go Language"}`, keys["editor"]); w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	w = sendJSON(router, http.MethodGet, "/api/v1/tags", "", "")
+	var list []TagResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(list) != 1 || list[0].Slug != "go-language" || list[0].PostCount == nil || *list[0].PostCount != 1 {
+		t.Errorf("Expected go-language on one post, got %+v", list)
+	}
+
+	// Old slugs, including those of merged tags, redirect
+	for _, slug := range []string{"go", "golang"} {
+		w := sendJSON(router, http.MethodGet, "/api/v1/tags/"+slug, "", "")
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/api/v1/tags/go-language" {
+			t.Errorf("Expected %s to redirect to go-language, got %d %q", slug, w.Code, w.Header().Get("Location"))
+		}
+	}
+
+	if w := sendJSON(router, http.MethodDelete, "/api/v1/posts/hello-world/tags/go-language", "", keys["jane"]); w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if w := sendJSON(router, http.MethodDelete, "/api/v1/tags/go-language", "", keys["editor"]); w.Code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if w := sendJSON(router, http.MethodGet, "/api/v1/tags/go-language", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -0,0 +1,161 @@
+package v1
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/api/middleware"
+	"goapp/internal/authz"
+	"goapp/internal/container"
+	"goapp/internal/logging"
+	"goapp/internal/transfer"
+)
+
+// maxImportBytes limits the size of a Markdown import upload
+const maxImportBytes = 32 << 20
+
+// TransferHandler exports content as JSON Lines or CSV and imports Markdown posts
+type TransferHandler struct {
+	Logger   logging.Logger
+	Exporter *transfer.Exporter
+	Importer *transfer.Importer
+}
+
+// NewTransferHandler creates a new transfer handler with injected dependencies
+func NewTransferHandler(container *container.Container) *TransferHandler {
+	h := &TransferHandler{Logger: container.Logger}
+	if container.Database != nil && container.Database.DB() != nil {
+		h.Exporter = transfer.NewExporter(container.Database.DB())
+		h.Importer = transfer.NewImporter(container.Database.DB())
+	}
+	return h
+}
+
+// RegisterRoutes registers the export and import routes; they are disabled without a database
+func (h *TransferHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	if h.Exporter == nil {
+		return
+	}
+
+	rg.GET("/export/:kind", h.Export)
+	rg.POST("/import/markdown", h.ImportMarkdown)
+}
+
+// Export godoc
+// @Summary Export content
+// @Description Stream every user, post or comment, ordered by ID. Posts include their tag slugs, and in JSON Lines their comments. Deleted records and password hashes are left out. Exporting users requires users:read; posts and comments require posts:read.
+// @Tags v1,transfer
+// @Produce json
+// @Produce text/csv
+// @Security BearerAuth
+// @Param kind path string true "users, posts or comments"
+// @Param format query string false "jsonl or csv" default(jsonl)
+// @Success 200 {string} string "One record per line"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/export/{kind} [get]
+func (h *TransferHandler) Export(c *gin.Context) {
+	kind := c.Param("kind")
+	format := c.DefaultQuery("format", transfer.FormatJSONL)
+	if err := transfer.Check(kind, format); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, err.Error()))
+		return
+	}
+	resource := authz.Type(authz.ResourcePosts)
+	if kind == transfer.KindUsers {
+		resource = authz.Type(authz.ResourceUsers)
+	}
+	if !middleware.Authorize(c, authz.ActionRead, resource) {
+		return
+	}
+
+	filename := fmt.Sprintf("%s-%s.%s", kind, time.Now().UTC().Format("20060102"), format)
+	c.Header("Content-Type", transfer.ContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	count, err := h.Exporter.Export(c.Request.Context(), c.Writer, kind, format)
+	if err != nil {
+		// The status has been sent; the client sees a truncated file
+		h.Logger.Error("Export failed", zap.String("kind", kind), zap.Int("written", count), zap.Error(err))
+		return
+	}
+	h.Logger.Info("Content exported", zap.String("kind", kind), zap.String("format", format), zap.Int("count", count), zap.Uint("user_id", middleware.CurrentUser(c).ID))
+}
+
+// ImportMarkdown godoc
+// @Summary Import Markdown posts
+// @Description Create a post from each uploaded Markdown file with YAML front matter (title, slug, summary, published, tags, author, date). Files without an author are written by the caller; naming another author requires users:update. Files without a slug are named after the file. Posts whose slug exists are skipped, or updated with upsert. Each file gets its own result; dry_run reports them without saving anything. Requires posts:update and posts:publish.
+// @Tags v1,transfer
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param files formData file true "Markdown files"
+// @Param dry_run formData bool false "Validate and report without saving"
+// @Param upsert formData bool false "Update posts whose slug exists"
+// @Success 200 {object} transfer.ImportReport
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/import/markdown [post]
+func (h *TransferHandler) ImportMarkdown(c *gin.Context) {
+	if !middleware.Authorize(c, authz.ActionUpdate, authz.Type(authz.ResourcePosts)) ||
+		!middleware.Authorize(c, authz.ActionPublish, authz.Type(authz.ResourcePosts)) {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["files"]) == 0 {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("files is required, up to %d MB in total", maxImportBytes>>20)))
+		return
+	}
+	docs := make([]transfer.Document, 0, len(form.File["files"]))
+	for _, header := range form.File["files"] {
+		if ext := strings.ToLower(path.Ext(header.Filename)); ext != ".md" && ext != ".markdown" {
+			_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, header.Filename+" is not a Markdown file"))
+			return
+		}
+		data, err := readUpload(header)
+		if err != nil {
+			_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "failed to read "+header.Filename))
+			return
+		}
+		docs = append(docs, transfer.Document{Name: path.Base(header.Filename), Data: data})
+	}
+
+	dryRun, _ := strconv.ParseBool(c.PostForm("dry_run"))
+	upsert, _ := strconv.ParseBool(c.PostForm("upsert"))
+	user := middleware.CurrentUser(c)
+	report, err := h.Importer.ImportMarkdown(c.Request.Context(), docs, transfer.ImportOptions{
+		AuthorID:  user.ID,
+		AnyAuthor: middleware.Can(c, authz.ActionUpdate, authz.Type(authz.ResourceUsers)),
+		DryRun:    dryRun,
+		Upsert:    upsert,
+	})
+	if err != nil {
+		h.Logger.Error("Import failed", zap.Error(err))
+		_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, "failed to import posts"))
+		return
+	}
+	h.Logger.Info("Markdown imported", zap.Bool("dry_run", dryRun), zap.Int("created", report.Created), zap.Int("updated", report.Updated), zap.Int("failed", report.Failed), zap.Uint("user_id", user.ID))
+	c.JSON(http.StatusOK, report)
+}
+
+func readUpload(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
@@ -0,0 +1,125 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/transfer"
+)
+
+func uploadMarkdown(t *testing.T, router *gin.Engine, key string, fields map[string]string, files map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range fields {
+		form.WriteField(name, value)
+	}
+	for name, content := range files {
+		part, err := form.CreateFormFile("files", name)
+		if err != nil {
+			t.Fatalf("Failed to create form file: %v", err)
+		}
+		part.Write([]byte(content))
+	}
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/import/markdown", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestTransferHandler_Export(t *testing.T) {
+	router, keys := setupPostRouter(t)
+
+	tests := []struct {
+		name string
+		path string
+		key  string
+		want int
+	}{
+		{"Anonymous", "/api/v1/export/posts", "", http.StatusUnauthorized},
+		{"User", "/api/v1/export/posts", keys["bob"], http.StatusForbidden},
+		{"EditorUsers", "/api/v1/export/users", keys["editor"], http.StatusForbidden},
+		{"UnknownKind", "/api/v1/export/secrets", keys["editor"], http.StatusBadRequest},
+		{"UnknownFormat", "/api/v1/export/posts?format=xml", keys["editor"], http.StatusBadRequest},
+		{"Editor", "/api/v1/export/posts", keys["editor"], http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := sendJSON(router, http.MethodGet, tt.path, "", tt.key); w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w := sendJSON(router, http.MethodGet, "/api/v1/export/posts?format=csv", "", keys["editor"])
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Errorf("Expected CSV content type, got %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, `attachment; filename="posts-`) {
+		t.Errorf("Expected an attachment, got %q", cd)
+	}
+	if lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n"); len(lines) != 3 || !strings.Contains(lines[1], "hello-world") {
+		t.Errorf("Expected a header and both posts, got %q", w.Body.String())
+	}
+}
+
+func TestTransferHandler_ImportMarkdown(t *testing.T) {
+	router, keys := setupPostRouter(t)
+	files := map[string]string{
+		"imported.md":    "---\ntitle: Imported Post\ntags: [go]\n---\nBody.",
+		"hello-world.md": "---\ntitle: Hello Again\n---\nChanged.",
+	}
+
+	if w := uploadMarkdown(t, router, keys["bob"], nil, files); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusForbidden, w.Code, w.Body.String())
+	}
+	if w := uploadMarkdown(t, router, keys["editor"], nil, map[string]string{"notes.txt": "text"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	w := uploadMarkdown(t, router, keys["editor"], map[string]string{"dry_run": "true"}, files)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var report transfer.ImportReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !report.DryRun || report.Created != 1 || report.Skipped != 1 {
+		t.Errorf("Expected one new and one existing post, got %+v", report)
+	}
+	if w := sendJSON(router, http.MethodGet, "/api/v1/posts/imported", "", keys["editor"]); w.Code != http.StatusNotFound {
+		t.Errorf("Expected a dry run to save nothing, got status %d", w.Code)
+	}
+
+	w = uploadMarkdown(t, router, keys["editor"], map[string]string{"upsert": "true"}, files)
+	report = transfer.ImportReport{}
+	json.Unmarshal(w.Body.Bytes(), &report)
+	if w.Code != http.StatusOK || report.Created != 1 || report.Updated != 1 {
+		t.Errorf("Expected one created and one updated post, got %d: %s", w.Code, w.Body.String())
+	}
+	w = sendJSON(router, http.MethodGet, "/api/v1/posts/hello-world", "", "")
+	if !strings.Contains(w.Body.String(), "Hello Again") {
+		t.Errorf("Expected the existing post to be updated, got %s", w.Body.String())
+	}
+
+	// Editors may not publish posts in another user's name
+	w = uploadMarkdown(t, router, keys["editor"], nil, map[string]string{"by-jane.md": "---\ntitle: By Jane\nauthor: jane\n---\nBody."})
+	report = transfer.ImportReport{}
+	json.Unmarshal(w.Body.Bytes(), &report)
+	if w.Code != http.StatusOK || report.Failed != 1 || !strings.Contains(report.Results[0].Error, "may not import posts by") {
+		t.Errorf("Expected the import to fail, got %d: %s", w.Code, w.Body.String())
+	}
+}
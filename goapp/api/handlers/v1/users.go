@@ -0,0 +1,540 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/authz"
+	"goapp/internal/config"
+	"goapp/internal/container"
+	"goapp/internal/logging"
+	"goapp/internal/mail"
+	"goapp/internal/models"
+	"goapp/internal/query"
+	"goapp/internal/users"
+	"goapp/web/templates/emails"
+)
+
+// UserResponse describes a user account
+type UserResponse struct {
+	ID               uint       `json:"id"`
+	Email            string     `json:"email"`
+	Username         string     `json:"username"`
+	FirstName        string     `json:"first_name"`
+	LastName         string     `json:"last_name"`
+	Active           bool       `json:"active"`
+	EmailVerified    bool       `json:"email_verified"`
+	ServiceAccount   bool       `json:"service_account"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	Roles            []string   `json:"roles"`
+	PendingEmail     string     `json:"pending_email,omitempty"`
+	LastLoginAt      *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty"`
+}
+
+// UserListResponse is a page of users
+type UserListResponse struct {
+	Data   []UserResponse `json:"data"`
+	Total  int64          `json:"total"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset,omitempty"`
+}
+
+// UpdateUserRequest deactivates or reactivates a user
+type UpdateUserRequest struct {
+	Active *bool `json:"active" binding:"required"`
+}
+
+// UpdateProfileRequest changes the caller's own details; omitted fields are kept
+type UpdateProfileRequest struct {
+	Email     *string `json:"email"` // a new email is used once confirmed
+	Username  *string `json:"username"`
+	FirstName *string `json:"first_name"`
+	LastName  *string `json:"last_name"`
+
+	// CurrentPassword is required to change the email
+	CurrentPassword string `json:"current_password"`
+}
+
+// NewUserResponse describes user; its roles must be loaded
+func NewUserResponse(user *models.User) UserResponse {
+	roles := make([]string, len(user.Roles))
+	for i, role := range user.Roles {
+		roles[i] = role.Name
+	}
+	response := UserResponse{
+		ID:               user.ID,
+		Email:            user.Email,
+		Username:         user.Username,
+		FirstName:        user.FirstName,
+		LastName:         user.LastName,
+		Active:           user.Active,
+		EmailVerified:    user.EmailVerified,
+		ServiceAccount:   user.ServiceAccount,
+		TwoFactorEnabled: user.TwoFactorEnabled,
+		Roles:            roles,
+		PendingEmail:     user.PendingEmail,
+		LastLoginAt:      user.LastLoginAt,
+		CreatedAt:        user.CreatedAt,
+	}
+	if user.DeletedAt.Valid {
+		response.DeletedAt = &user.DeletedAt.Time
+	}
+	return response
+}
+
+// UserHandler serves user administration for admins and profiles for everyone
+type UserHandler struct {
+	Logger logging.Logger
+	Users  users.Service
+	Roles  authz.RoleStore
+
+	// Accounts and Mailer send the link confirming a new email address;
+	// without Accounts email changes stay pending
+	Accounts auth.AccountService
+	Mailer   mail.Mailer
+	Mail     config.MailConfig
+	LinkTTL  time.Duration
+}
+
+// NewUserHandler creates a new users handler with injected dependencies
+func NewUserHandler(container *container.Container) *UserHandler {
+	return &UserHandler{
+		Logger:   container.Logger,
+		Users:    container.Users,
+		Roles:    container.Roles,
+		Accounts: container.Accounts,
+		Mailer:   container.Mailer,
+		Mail:     container.Config.Mail,
+		LinkTTL:  container.Config.Auth.EmailVerificationTTL,
+	}
+}
+
+// RegisterRoutes registers the users and profile routes; they are disabled without a database
+func (h *UserHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	if h.Users == nil || h.Roles == nil {
+		return
+	}
+
+	group := rg.Group("/users")
+	group.GET("", h.List)
+	group.GET("/:id", h.Get)
+	group.PATCH("/:id", h.Update)
+	group.DELETE("/:id", h.Delete)
+	group.POST("/:id/restore", h.Restore)
+	group.PUT("/:id/roles/:role", h.GrantRole)
+	group.DELETE("/:id/roles/:role", h.RevokeRole)
+
+	rg.GET("/profile", h.Profile)
+	rg.PATCH("/profile", h.UpdateProfile)
+}
+
+// List godoc
+// @Summary List users
+// @Description Search users by username, email or name, a page at a time. Admins only.
+// @Tags v1,users
+// @Produce json
+// @Security BearerAuth
+// @Param q query string false "Text in the username, email or full name"
+// @Param status query string false "active, inactive or deleted; deleted users are only listed with deleted"
+// @Param role query string false "Role name"
+// @Param filter[field] query string false "Filter on username, email, first_name, last_name, active, email_verified, created_at or last_login_at; filter[field][op] takes ne, in, lt, lte, gt, gte or contains"
+// @Param sort query string false "username, email, created_at or last_login_at, comma separated; prefix with - for descending" default(username)
+// @Param page[number] query int false "Page number, from 1; the response has a Link header to the other pages" default(1)
+// @Param page[size] query int false "Page size, at most 100" default(20)
+// @Param page[offset] query int false "Users to skip, instead of page[number]"
+// @Param limit query int false "Alias of page[size]" default(20)
+// @Param offset query int false "Alias of page[offset]"
+// @Success 200 {object} v1.UserListResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/users [get]
+func (h *UserHandler) List(c *gin.Context) {
+	if !middleware.Authorize(c, authz.ActionRead, authz.Type(authz.ResourceUsers)) {
+		return
+	}
+
+	q, err := users.Schema.Parse(c.Request.URL.Query())
+	if err != nil {
+		h.fail(c, err, "failed to list users")
+		return
+	}
+	opts := users.ListOptions{
+		Query:  c.Query("q"),
+		Status: c.Query("status"),
+		Role:   c.Query("role"),
+		Filter: q,
+		Limit:  q.Size,
+		Offset: q.Offset(),
+	}
+
+	page, err := h.Users.List(c.Request.Context(), opts)
+	if err != nil {
+		h.fail(c, err, "failed to list users")
+		return
+	}
+
+	response := UserListResponse{
+		Data:   make([]UserResponse, len(page.Users)),
+		Total:  page.Total,
+		Limit:  opts.Limit,
+		Offset: opts.Offset,
+	}
+	for i := range page.Users {
+		response.Data[i] = NewUserResponse(&page.Users[i])
+	}
+	if q.Numbered() {
+		c.Header("Link", query.Links(c.Request.URL, q.Meta(page.Total)))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Get godoc
+// @Summary Get user
+// @Description Get a user by ID, including deleted users. Admins only.
+// @Tags v1,users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} v1.UserResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/users/{id} [get]
+func (h *UserHandler) Get(c *gin.Context) {
+	user, ok := h.load(c, authz.ActionRead)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, NewUserResponse(user))
+}
+
+// Update godoc
+// @Summary Deactivate or reactivate user
+// @Description Set whether a user may sign in. Deactivating signs the user out everywhere and disables their API keys and tokens. Admins only, and not on themselves.
+// @Tags v1,users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param request body v1.UpdateUserRequest true "Active flag"
+// @Success 200 {object} v1.UserResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/users/{id} [patch]
+func (h *UserHandler) Update(c *gin.Context) {
+	user, ok := h.load(c, authz.ActionUpdate)
+	if !ok {
+		return
+	}
+
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "active is required"))
+		return
+	}
+	if !*req.Active && h.self(c, user, "you cannot deactivate your own account") {
+		return
+	}
+
+	if err := h.Users.SetActive(c.Request.Context(), user, *req.Active); err != nil {
+		h.fail(c, err, "failed to update user")
+		return
+	}
+	h.Logger.Info("User active flag changed", zap.Uint("target_id", user.ID), zap.Bool("active", *req.Active), zap.Uint("user_id", middleware.CurrentUser(c).ID))
+	c.JSON(http.StatusOK, NewUserResponse(user))
+}
+
+// Delete godoc
+// @Summary Delete user
+// @Description Soft-delete a user and sign them out everywhere. Their email and username stay reserved so the user can be restored. Admins only, and not on themselves.
+// @Tags v1,users
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/users/{id} [delete]
+func (h *UserHandler) Delete(c *gin.Context) {
+	user, ok := h.load(c, authz.ActionDelete)
+	if !ok || h.self(c, user, "you cannot delete your own account") {
+		return
+	}
+	if user.DeletedAt.Valid {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	if err := h.Users.Delete(c.Request.Context(), user); err != nil {
+		h.fail(c, err, "failed to delete user")
+		return
+	}
+	h.Logger.Info("User deleted", zap.Uint("target_id", user.ID), zap.Uint("user_id", middleware.CurrentUser(c).ID))
+	c.Status(http.StatusNoContent)
+}
+
+// Restore godoc
+// @Summary Restore user
+// @Description Undelete a deleted user. Admins only.
+// @Tags v1,users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} v1.UserResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/users/{id}/restore [post]
+func (h *UserHandler) Restore(c *gin.Context) {
+	user, ok := h.load(c, authz.ActionDelete)
+	if !ok {
+		return
+	}
+
+	if err := h.Users.Restore(c.Request.Context(), user); err != nil {
+		h.fail(c, err, "failed to restore user")
+		return
+	}
+	h.Logger.Info("User restored", zap.Uint("target_id", user.ID), zap.Uint("user_id", middleware.CurrentUser(c).ID))
+	c.JSON(http.StatusOK, NewUserResponse(user))
+}
+
+// GrantRole godoc
+// @Summary Grant role
+// @Description Give a user a role; granting a held role does nothing. Admins only.
+// @Tags v1,users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param role path string true "Role name"
+// @Success 200 {object} v1.UserResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/users/{id}/roles/{role} [put]
+func (h *UserHandler) GrantRole(c *gin.Context) {
+	h.changeRole(c, true)
+}
+
+// RevokeRole godoc
+// @Summary Revoke role
+// @Description Take a role away from a user. Admins only, and admins cannot revoke their own admin role.
+// @Tags v1,users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param role path string true "Role name"
+// @Success 200 {object} v1.UserResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/users/{id}/roles/{role} [delete]
+func (h *UserHandler) RevokeRole(c *gin.Context) {
+	h.changeRole(c, false)
+}
+
+func (h *UserHandler) changeRole(c *gin.Context, grant bool) {
+	if !middleware.Authorize(c, authz.ActionUpdate, authz.Type(authz.ResourceRoles)) {
+		return
+	}
+	user, ok := h.load(c, authz.ActionUpdate)
+	if !ok {
+		return
+	}
+	role := c.Param("role")
+	if !grant && role == authz.RoleAdmin && h.self(c, user, "you cannot revoke your own admin role") {
+		return
+	}
+
+	ctx := c.Request.Context()
+	var err error
+	if grant {
+		err = h.Roles.Grant(ctx, user.ID, role)
+	} else {
+		err = h.Roles.Revoke(ctx, user.ID, role)
+	}
+	switch {
+	case errors.Is(err, authz.ErrRoleNotFound):
+		_ = c.Error(middleware.NewHTTPError(http.StatusNotFound, "role not found"))
+		return
+	case errors.Is(err, authz.ErrUserNotFound):
+		_ = c.Error(middleware.NewHTTPError(http.StatusNotFound, "user not found"))
+		return
+	case err != nil:
+		h.fail(c, err, "failed to change roles")
+		return
+	}
+	h.Logger.Info("User roles changed", zap.Uint("target_id", user.ID), zap.String("role", role), zap.Bool("granted", grant), zap.Uint("user_id", middleware.CurrentUser(c).ID))
+
+	if user, err = h.Users.Get(ctx, user.ID); err != nil {
+		h.fail(c, err, "failed to load user")
+		return
+	}
+	c.JSON(http.StatusOK, NewUserResponse(user))
+}
+
+// Profile godoc
+// @Summary Get profile
+// @Description Get the caller's own account
+// @Tags v1,users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} v1.UserResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/profile [get]
+func (h *UserHandler) Profile(c *gin.Context) {
+	user, ok := h.profile(c, authz.ActionRead)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, NewUserResponse(user))
+}
+
+// UpdateProfile godoc
+// @Summary Update profile
+// @Description Change the caller's email, username or name. Changing the email needs current_password; the new address is emailed a confirmation link and replaces the current one, shown as pending_email until then.
+// @Tags v1,users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body v1.UpdateProfileRequest true "Fields to change"
+// @Success 200 {object} v1.UserResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/profile [patch]
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	user, ok := h.profile(c, authz.ActionUpdate)
+	if !ok {
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, "invalid request body"))
+		return
+	}
+	input := users.ProfileInput{Email: user.Email, Username: user.Username, FirstName: user.FirstName, LastName: user.LastName, CurrentPassword: req.CurrentPassword}
+	if req.Email != nil {
+		input.Email = *req.Email
+	}
+	if req.Username != nil {
+		input.Username = *req.Username
+	}
+	if req.FirstName != nil {
+		input.FirstName = *req.FirstName
+	}
+	if req.LastName != nil {
+		input.LastName = *req.LastName
+	}
+
+	if err := h.Users.UpdateProfile(c.Request.Context(), user, input); err != nil {
+		h.fail(c, err, "failed to update profile")
+		return
+	}
+	if req.Email != nil && user.PendingEmail == auth.NormalizeEmail(*req.Email) {
+		if err := h.sendEmailChange(c.Request.Context(), user); err != nil {
+			h.fail(c, err, "failed to send the confirmation email")
+			return
+		}
+	}
+	c.JSON(http.StatusOK, NewUserResponse(user))
+}
+
+// sendEmailChange emails user's pending address the link that confirms it
+func (h *UserHandler) sendEmailChange(ctx context.Context, user *models.User) error {
+	if h.Accounts == nil || h.Mailer == nil {
+		return nil
+	}
+	link := strings.TrimSuffix(h.Mail.BaseURL, "/") + "/confirm-email?token=" + url.QueryEscape(h.Accounts.EmailChangeToken(user))
+	html, err := mail.Render(ctx, emails.ConfirmEmailChange(user, link, h.LinkTTL))
+	if err != nil {
+		return err
+	}
+	return h.Mailer.Send(ctx, mail.Message{
+		To:      []string{user.PendingEmail},
+		Subject: "Confirm your new email address",
+		Text:    emails.ConfirmEmailChangeText(user, link, h.LinkTTL),
+		HTML:    html,
+	})
+}
+
+// load finds the user named by the id path parameter after checking the
+// caller may perform action on users as a whole
+func (h *UserHandler) load(c *gin.Context, action authz.Action) (*models.User, bool) {
+	if !middleware.Authorize(c, action, authz.Type(authz.ResourceUsers)) {
+		return nil, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusNotFound, "user not found"))
+		return nil, false
+	}
+	user, err := h.Users.Get(c.Request.Context(), uint(id))
+	if errors.Is(err, users.ErrNotFound) {
+		_ = c.Error(middleware.NewHTTPError(http.StatusNotFound, "user not found"))
+		return nil, false
+	}
+	if err != nil {
+		h.fail(c, err, "failed to load user")
+		return nil, false
+	}
+	return user, true
+}
+
+// profile loads the caller's own account with its roles
+func (h *UserHandler) profile(c *gin.Context, action authz.Action) (*models.User, bool) {
+	current := middleware.CurrentUser(c)
+	if current == nil {
+		_ = c.Error(middleware.NewHTTPError(http.StatusUnauthorized, "authentication required"))
+		return nil, false
+	}
+	if !middleware.Authorize(c, action, current) {
+		return nil, false
+	}
+
+	user, err := h.Users.Get(c.Request.Context(), current.ID)
+	if err != nil {
+		h.fail(c, err, "failed to load profile")
+		return nil, false
+	}
+	return user, true
+}
+
+// self reports, with 400, when user is the caller
+func (h *UserHandler) self(c *gin.Context, user *models.User, message string) bool {
+	if current := middleware.CurrentUser(c); current != nil && current.ID == user.ID {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, message))
+		return true
+	}
+	return false
+}
+
+// fail reports validation errors as 400 and anything else as 500
+func (h *UserHandler) fail(c *gin.Context, err error, message string) {
+	var validationErr *auth.ValidationError
+	if errors.As(err, &validationErr) {
+		_ = c.Error(middleware.NewHTTPError(http.StatusBadRequest, validationErr.Error()))
+		return
+	}
+	h.Logger.Error("Request failed", zap.String("message", message), zap.Error(err))
+	_ = c.Error(middleware.NewHTTPError(http.StatusInternalServerError, message))
+}
@@ -0,0 +1,232 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goapp/api/middleware"
+	"goapp/internal/apikeys"
+	"goapp/internal/auth"
+	"goapp/internal/authz"
+	"goapp/internal/config"
+	"goapp/internal/models"
+	"goapp/internal/users"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupUserRouter returns the users router and an all-scopes key for each
+// of the admin (user 1), jane (2) and bob (3)
+func setupUserRouter(t *testing.T) (*gin.Engine, map[string]string) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Permission{}, &models.Role{}, &models.User{}, &models.APIKey{}, &models.Session{}, &models.RefreshToken{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	roles := authz.NewRoleStore(db)
+	if err := roles.Seed(ctx); err != nil {
+		t.Fatalf("Seed() error = %v", err)
+	}
+	// support may manage users but not their roles
+	support := models.Role{Name: "support", Permissions: []models.Permission{{Name: "users:read"}, {Name: "users:update"}}}
+	if err := db.Create(&support).Error; err != nil {
+		t.Fatalf("Failed to create role: %v", err)
+	}
+
+	c := setupTestContainer(t)
+	c.APIKeys = apikeys.NewService(db, config.APIKeyConfig{TokenPrefix: "goapp", DefaultExpiration: time.Hour})
+	c.Roles = roles
+	hasher, err := auth.NewHasher(config.AuthConfig{PasswordHasher: auth.HasherBcrypt, BcryptCost: bcrypt.MinCost})
+	if err != nil {
+		t.Fatalf("NewHasher() error = %v", err)
+	}
+	c.Users = users.NewService(db, hasher)
+	hash, _ := hasher.Hash("s3cret-password")
+
+	keys := map[string]string{}
+	for _, name := range []string{"admin", "jane", "bob"} {
+		user := &models.User{Email: name + "@example.com", Username: name, PasswordHash: hash, Active: true}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		if name == "admin" {
+			if err := roles.Grant(ctx, user.ID, authz.RoleAdmin); err != nil {
+				t.Fatalf("Grant() error = %v", err)
+			}
+		}
+		token, _, err := c.APIKeys.Create(ctx, apikeys.CreateInput{Name: "bootstrap", UserID: user.ID, Scopes: []string{"*"}})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		keys[name] = token
+	}
+
+	router := gin.New()
+	group := router.Group("/api/v1",
+		middleware.JSONErrors(),
+		middleware.APIKeyAuth(c.APIKeys, c.Logger),
+		middleware.Policies(authz.DefaultPolicy()))
+	NewUserHandler(c).RegisterRoutes(group)
+	return router, keys
+}
+
+func decodeUser(t *testing.T, body []byte) UserResponse {
+	t.Helper()
+	var user UserResponse
+	if err := json.Unmarshal(body, &user); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return user
+}
+
+func TestUserHandler_List(t *testing.T) {
+	router, keys := setupUserRouter(t)
+
+	if w := sendJSON(router, http.MethodGet, "/api/v1/users", "", keys["jane"]); w.Code != http.StatusForbidden {
+		t.Errorf("Expected users not to list users, got %d", w.Code)
+	}
+	if w := sendJSON(router, http.MethodGet, "/api/v1/users", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	w := sendJSON(router, http.MethodGet, "/api/v1/users?q=EXAMPLE&limit=1&offset=1", "", keys["admin"])
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var list UserListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	// admin, bob and jane, by username
+	if list.Total != 3 || len(list.Data) != 1 || list.Data[0].Username != "bob" {
+		t.Errorf("Expected bob on the second page, got %+v", list)
+	}
+
+	w = sendJSON(router, http.MethodGet, "/api/v1/users?role=admin", "", keys["admin"])
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list.Data) != 1 || list.Data[0].Roles[0] != "admin" {
+		t.Errorf("Expected the admin with their roles, got %s", w.Body.String())
+	}
+
+	w = sendJSON(router, http.MethodGet, "/api/v1/users?filter[username][contains]=A&sort=-username&page[size]=1&page[number]=2", "", keys["admin"])
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || list.Total != 2 || len(list.Data) != 1 || list.Data[0].Username != "admin" {
+		t.Errorf("Expected admin on the second page after jane, got %s", w.Body.String())
+	}
+	if link := w.Header().Get("Link"); !strings.Contains(link, `rel="prev"`) || strings.Contains(link, `rel="next"`) {
+		t.Errorf("Expected a Link header to the previous page only, got %q", link)
+	}
+
+	for _, query := range []string{"status=banned", "limit=0", "offset=-1", "filter[password_hash]=x", "sort=password_hash", "page[number]=100001"} {
+		if w := sendJSON(router, http.MethodGet, "/api/v1/users?"+query, "", keys["admin"]); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestUserHandler_Manage(t *testing.T) {
+	router, keys := setupUserRouter(t)
+
+	if w := sendJSON(router, http.MethodPatch, "/api/v1/users/2", `{"active": false}`, keys["jane"]); w.Code != http.StatusForbidden {
+		t.Errorf("Expected users not to deactivate themselves, got %d", w.Code)
+	}
+	if w := sendJSON(router, http.MethodPatch, "/api/v1/users/1", `{"active": false}`, keys["admin"]); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected admins not to deactivate themselves, got %d", w.Code)
+	}
+	if w := sendJSON(router, http.MethodDelete, "/api/v1/users/1/roles/admin", "", keys["admin"]); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected admins not to drop their own admin role, got %d", w.Code)
+	}
+
+	w := sendJSON(router, http.MethodPatch, "/api/v1/users/2", `{"active": false}`, keys["admin"])
+	if w.Code != http.StatusOK || decodeUser(t, w.Body.Bytes()).Active {
+		t.Fatalf("Expected jane to be deactivated, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := sendJSON(router, http.MethodGet, "/api/v1/profile", "", keys["jane"]); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a deactivated user's key to stop working, got %d", w.Code)
+	}
+	if w := sendJSON(router, http.MethodPatch, "/api/v1/users/2", `{"active": true}`, keys["admin"]); w.Code != http.StatusOK {
+		t.Errorf("Expected jane to be reactivated, got %d", w.Code)
+	}
+
+	// Managing users does not extend to their roles
+	if w := sendJSON(router, http.MethodPut, "/api/v1/users/2/roles/support", "", keys["admin"]); w.Code != http.StatusOK {
+		t.Fatalf("Expected jane to join support, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := sendJSON(router, http.MethodGet, "/api/v1/users/3", "", keys["jane"]); w.Code != http.StatusOK {
+		t.Errorf("Expected support to read users, got %d", w.Code)
+	}
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		if w := sendJSON(router, method, "/api/v1/users/2/roles/admin", "", keys["jane"]); w.Code != http.StatusForbidden {
+			t.Errorf("%s: expected support not to change roles, got %d", method, w.Code)
+		}
+	}
+	if w := sendJSON(router, http.MethodDelete, "/api/v1/users/2/roles/support", "", keys["admin"]); w.Code != http.StatusOK {
+		t.Errorf("Expected jane to leave support, got %d", w.Code)
+	}
+
+	w = sendJSON(router, http.MethodPut, "/api/v1/users/3/roles/editor", "", keys["admin"])
+	if w.Code != http.StatusOK || len(decodeUser(t, w.Body.Bytes()).Roles) != 1 {
+		t.Errorf("Expected bob to be an editor, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := sendJSON(router, http.MethodPut, "/api/v1/users/3/roles/superuser", "", keys["admin"]); w.Code != http.StatusNotFound {
+		t.Errorf("Expected unknown roles to be 404, got %d", w.Code)
+	}
+	w = sendJSON(router, http.MethodDelete, "/api/v1/users/3/roles/editor", "", keys["admin"])
+	if w.Code != http.StatusOK || len(decodeUser(t, w.Body.Bytes()).Roles) != 0 {
+		t.Errorf("Expected bob to lose the editor role, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := sendJSON(router, http.MethodDelete, "/api/v1/users/3", "", keys["admin"]); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	w = sendJSON(router, http.MethodGet, "/api/v1/users/3", "", keys["admin"])
+	if w.Code != http.StatusOK || decodeUser(t, w.Body.Bytes()).DeletedAt == nil {
+		t.Errorf("Expected deleted users to be found with deleted_at, got %d: %s", w.Code, w.Body.String())
+	}
+	w = sendJSON(router, http.MethodPost, "/api/v1/users/3/restore", "", keys["admin"])
+	if w.Code != http.StatusOK || decodeUser(t, w.Body.Bytes()).DeletedAt != nil {
+		t.Errorf("Expected bob to be restored, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := sendJSON(router, http.MethodGet, "/api/v1/users/99", "", keys["admin"]); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestUserHandler_Profile(t *testing.T) {
+	router, keys := setupUserRouter(t)
+
+	w := sendJSON(router, http.MethodGet, "/api/v1/profile", "", keys["jane"])
+	if w.Code != http.StatusOK || decodeUser(t, w.Body.Bytes()).Username != "jane" {
+		t.Fatalf("Expected jane's profile, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = sendJSON(router, http.MethodPatch, "/api/v1/profile", `{"first_name": "Jane", "last_name": "Doe"}`, keys["jane"])
+	if profile := decodeUser(t, w.Body.Bytes()); w.Code != http.StatusOK || profile.FirstName != "Jane" || profile.Email != "jane@example.com" {
+		t.Errorf("Expected the name to change and the rest to be kept, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := sendJSON(router, http.MethodPatch, "/api/v1/profile", `{"username": "bob"}`, keys["jane"]); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a taken username to be rejected, got %d", w.Code)
+	}
+
+	// A new email needs the current password and waits for confirmation
+	if w := sendJSON(router, http.MethodPatch, "/api/v1/profile", `{"email": "janet@example.com"}`, keys["jane"]); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "current_password") {
+		t.Errorf("Expected an email change without the password to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+	w = sendJSON(router, http.MethodPatch, "/api/v1/profile", `{"email": "janet@example.com", "current_password": "s3cret-password"}`, keys["jane"])
+	if profile := decodeUser(t, w.Body.Bytes()); w.Code != http.StatusOK || profile.Email != "jane@example.com" || profile.PendingEmail != "janet@example.com" {
+		t.Errorf("Expected the email change to be pending, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := sendJSON(router, http.MethodGet, "/api/v1/profile", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
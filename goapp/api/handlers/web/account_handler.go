@@ -0,0 +1,187 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/container"
+	"goapp/internal/mail"
+	"goapp/internal/models"
+	"goapp/web/templates/emails"
+	"goapp/web/templates/pages"
+)
+
+// AccountHandler handles email verification and password reset
+type AccountHandler struct {
+	container *container.Container
+}
+
+// NewAccountHandler creates a new account handler
+func NewAccountHandler(c *container.Container) *AccountHandler {
+	return &AccountHandler{container: c}
+}
+
+// VerifyEmail redeems the link sent by SendVerification
+func (h *AccountHandler) VerifyEmail(c *gin.Context) {
+	user, err := h.container.Accounts.VerifyEmail(c.Request.Context(), c.Query("token"))
+	if errors.Is(err, auth.ErrInvalidToken) {
+		h.auth().render(c, http.StatusBadRequest, pages.AccountNotice("Email not verified", "This link is invalid, has expired or was already used.", false))
+		return
+	}
+	if err != nil {
+		h.container.Logger.Error("Failed to verify email", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to verify email")
+		return
+	}
+
+	h.container.Logger.Info("Email verified", zap.Uint("user_id", user.ID))
+	h.auth().render(c, http.StatusOK, pages.AccountNotice("Email verified", "Thanks, "+user.Email+" is confirmed.", true))
+}
+
+// ResendVerification emails the signed-in user a new verification link
+func (h *AccountHandler) ResendVerification(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	if user.EmailVerified {
+		h.auth().render(c, http.StatusOK, pages.AccountNotice("Email verified", user.Email+" is already confirmed.", true))
+		return
+	}
+
+	if err := h.SendVerification(c.Request.Context(), user); err != nil {
+		h.container.Logger.Error("Failed to send verification email", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to send email")
+		return
+	}
+	h.auth().render(c, http.StatusOK, pages.AccountNotice("Check your email", "We have sent a confirmation link to "+user.Email+".", true))
+}
+
+// SendVerification emails user a link that confirms their address
+func (h *AccountHandler) SendVerification(ctx context.Context, user *models.User) error {
+	ttl := h.container.Config.Auth.EmailVerificationTTL
+	link := h.link("/verify-email", h.container.Accounts.EmailVerificationToken(user))
+	return h.send(ctx, user.Email, "Confirm your email address", emails.VerifyEmail(user, link, ttl), emails.VerifyEmailText(user, link, ttl))
+}
+
+// ConfirmEmail redeems the link sent by SendEmailChange
+func (h *AccountHandler) ConfirmEmail(c *gin.Context) {
+	user, err := h.container.Accounts.ConfirmEmailChange(c.Request.Context(), c.Query("token"))
+	switch {
+	case errors.Is(err, auth.ErrInvalidToken):
+		h.auth().render(c, http.StatusBadRequest, pages.AccountNotice("Email not changed", "This link is invalid, has expired or was already used.", false))
+		return
+	case errors.Is(err, auth.ErrEmailTaken):
+		h.auth().render(c, http.StatusConflict, pages.AccountNotice("Email not changed", "Another account uses this email address now.", false))
+		return
+	case err != nil:
+		h.container.Logger.Error("Failed to change email", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to change email")
+		return
+	}
+
+	h.container.Logger.Info("Email changed", zap.Uint("user_id", user.ID))
+	h.auth().render(c, http.StatusOK, pages.AccountNotice("Email changed", "Thanks, your account now uses "+user.Email+".", true))
+}
+
+// SendEmailChange emails user's pending address a link that makes it
+// their email address
+func (h *AccountHandler) SendEmailChange(ctx context.Context, user *models.User) error {
+	ttl := h.container.Config.Auth.EmailVerificationTTL
+	link := h.link("/confirm-email", h.container.Accounts.EmailChangeToken(user))
+	return h.send(ctx, user.PendingEmail, "Confirm your new email address", emails.ConfirmEmailChange(user, link, ttl), emails.ConfirmEmailChangeText(user, link, ttl))
+}
+
+// ForgotPasswordPage renders the form asking for an email address
+func (h *AccountHandler) ForgotPasswordPage(c *gin.Context) {
+	h.auth().render(c, http.StatusOK, pages.ForgotPassword(pages.ForgotPasswordForm{}))
+}
+
+// ForgotPassword emails a reset link. The response is the same whether or
+// not the address has an account, so the form cannot be used to find accounts.
+func (h *AccountHandler) ForgotPassword(c *gin.Context) {
+	form := pages.ForgotPasswordForm{Email: strings.TrimSpace(c.PostForm("email")), Sent: true}
+
+	user, token, err := h.container.Accounts.PasswordResetToken(c.Request.Context(), form.Email)
+	if err != nil {
+		h.container.Logger.Error("Failed to create password reset token", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to send email")
+		return
+	}
+	if user != nil {
+		ttl := h.container.Config.Auth.PasswordResetTTL
+		link := h.link("/reset-password", token)
+		if err := h.send(c.Request.Context(), user.Email, "Reset your password", emails.ResetPassword(user, link, ttl), emails.ResetPasswordText(user, link, ttl)); err != nil {
+			h.container.Logger.Error("Failed to send password reset email", zap.Error(err))
+			c.String(http.StatusInternalServerError, "Failed to send email")
+			return
+		}
+		h.container.Logger.Info("Password reset requested", zap.Uint("user_id", user.ID))
+	}
+
+	h.auth().render(c, http.StatusOK, pages.ForgotPassword(form))
+}
+
+// ResetPasswordPage renders the new password form for a valid reset link
+func (h *AccountHandler) ResetPasswordPage(c *gin.Context) {
+	token := c.Query("token")
+	if err := h.container.Accounts.CheckPasswordReset(c.Request.Context(), token); err != nil {
+		h.invalidResetLink(c, err)
+		return
+	}
+	h.auth().render(c, http.StatusOK, pages.ResetPassword(pages.ResetPasswordForm{Token: token}))
+}
+
+// ResetPassword sets the new password and signs the user in
+func (h *AccountHandler) ResetPassword(c *gin.Context) {
+	form := pages.ResetPasswordForm{Token: c.PostForm("token")}
+
+	user, err := h.container.Accounts.ResetPassword(c.Request.Context(), form.Token, c.PostForm("password"))
+	var validationErr *auth.ValidationError
+	if errors.As(err, &validationErr) {
+		form.Errors = validationErr.Fields
+		h.auth().render(c, http.StatusUnprocessableEntity, pages.ResetPassword(form))
+		return
+	}
+	if err != nil {
+		h.invalidResetLink(c, err)
+		return
+	}
+
+	h.container.Logger.Info("Password reset", zap.Uint("user_id", user.ID))
+	h.auth().startSession(c, user, "/")
+}
+
+func (h *AccountHandler) invalidResetLink(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidToken):
+		h.auth().render(c, http.StatusBadRequest, pages.AccountNotice("Password not reset", "This link is invalid, has expired or was already used. Please request a new one.", false))
+	case errors.Is(err, auth.ErrInactiveUser):
+		h.auth().render(c, http.StatusForbidden, pages.AccountNotice("Password not reset", "This account has been deactivated.", false))
+	default:
+		h.container.Logger.Error("Failed to reset password", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to reset password")
+	}
+}
+
+// link builds an absolute link for emails, since they are opened outside the site
+func (h *AccountHandler) link(path, token string) string {
+	return strings.TrimSuffix(h.container.Config.Mail.BaseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+func (h *AccountHandler) send(ctx context.Context, to, subject string, body templ.Component, text string) error {
+	html, err := mail.Render(ctx, body)
+	if err != nil {
+		return err
+	}
+	return h.container.Mailer.Send(ctx, mail.Message{To: []string{to}, Subject: subject, Text: text, HTML: html})
+}
+
+func (h *AccountHandler) auth() *AuthHandler {
+	return NewAuthHandler(h.container)
+}
@@ -0,0 +1,205 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/config"
+	"goapp/internal/mail"
+	"goapp/internal/models"
+	"goapp/internal/users"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var emailLink = regexp.MustCompile(`http://app\.example/\S+`)
+
+func setupAccountRouter(t *testing.T) (*gin.Engine, *mail.MemoryMailer, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Session{}, &models.RefreshToken{}, &models.LoginEvent{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	container := setupTestContainer(t)
+	container.Config.Auth = config.AuthConfig{
+		PasswordHasher:       auth.HasherBcrypt,
+		BcryptCost:           bcrypt.MinCost,
+		MinPasswordLength:    8,
+		RegistrationEnabled:  true,
+		SessionCookieName:    "session",
+		SessionTTL:           time.Hour,
+		TokenSecret:          "test-secret",
+		EmailVerificationTTL: 48 * time.Hour,
+		PasswordResetTTL:     time.Hour,
+	}
+	container.Config.Mail = config.MailConfig{BaseURL: "http://app.example/"}
+	hasher, _ := auth.NewHasher(container.Config.Auth)
+	container.Auth = auth.NewService(db, hasher, container.Config.Auth)
+	container.Sessions = auth.NewSessionStore(db, time.Hour)
+	container.Users = users.NewService(db, hasher)
+	container.Accounts, err = auth.NewAccountService(db, hasher, container.Config.Auth)
+	if err != nil {
+		t.Fatalf("NewAccountService() error = %v", err)
+	}
+	mailer := mail.NewMemoryMailer("no-reply@app.example")
+	container.Mailer = mailer
+
+	authHandler := NewAuthHandler(container)
+	handler := NewAccountHandler(container)
+	partials := NewPartialsHandler(container)
+
+	router := gin.New()
+	router.Use(middleware.Session(container.Sessions, "session", false, container.Logger))
+	router.POST("/register", authHandler.Register)
+	router.POST("/login", authHandler.Login)
+	router.GET("/verify-email", handler.VerifyEmail)
+	router.GET("/confirm-email", handler.ConfirmEmail)
+	router.POST("/settings", middleware.RequireUser("/login"), NewSettingsHandler(container).Update)
+	router.POST("/verify-email/resend", middleware.RequireUser("/login"), handler.ResendVerification)
+	router.GET("/forgot-password", handler.ForgotPasswordPage)
+	router.POST("/forgot-password", handler.ForgotPassword)
+	router.GET("/reset-password", handler.ResetPasswordPage)
+	router.POST("/reset-password", handler.ResetPassword)
+	router.GET("/partials/user-menu", partials.UserMenu)
+	return router, mailer, db
+}
+
+// lastLink returns the path and query of the link in the last email
+func lastLink(t *testing.T, mailer *mail.MemoryMailer) string {
+	msg, ok := mailer.Last()
+	if !ok {
+		t.Fatal("Expected an email to be sent")
+	}
+	link, err := url.Parse(emailLink.FindString(msg.Text))
+	if err != nil || link.Path == "" {
+		t.Fatalf("Expected a link in the email, got:\n%s", msg.Text)
+	}
+	return link.RequestURI()
+}
+
+func get(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAccountHandler_VerifyEmail(t *testing.T) {
+	router, mailer, db := setupAccountRouter(t)
+
+	w := postForm(router, "/register", url.Values{"email": {"jane@example.com"}, "username": {"jane"}, "password": {"s3cret-password"}}, nil)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("Expected status %d, got %d", http.StatusSeeOther, w.Code)
+	}
+	msg, _ := mailer.Last()
+	if msg.To[0] != "jane@example.com" || msg.Subject != "Confirm your email address" || !contains(msg.HTML, "Confirm email address") {
+		t.Errorf("Unexpected verification email %+v", msg)
+	}
+	if body := userMenu(router, sessionCookie(w)); !contains(body, "Verify your email") {
+		t.Error("Expected user menu to offer email verification")
+	}
+
+	link := lastLink(t, mailer)
+	if w := get(router, link); w.Code != http.StatusOK || !contains(w.Body.String(), "Email verified") {
+		t.Errorf("Expected email to be verified, got %d", w.Code)
+	}
+	var user models.User
+	db.Where("email = ?", "jane@example.com").First(&user)
+	if !user.EmailVerified {
+		t.Error("Expected EmailVerified to be set")
+	}
+
+	if w := get(router, link); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected used link to be rejected, got %d", w.Code)
+	}
+}
+
+func TestAccountHandler_ConfirmEmail(t *testing.T) {
+	router, mailer, db := setupAccountRouter(t)
+	w := postForm(router, "/register", url.Values{"email": {"jane@example.com"}, "username": {"jane"}, "password": {"s3cret-password"}}, nil)
+	session := sessionCookie(w)
+	get(router, lastLink(t, mailer))
+	mailer.Reset()
+
+	form := url.Values{"email": {"janet@example.com"}, "username": {"jane"}, "current_password": {"wrong-password"}}
+	if w := postForm(router, "/settings", form, session); w.Code != http.StatusUnprocessableEntity || !contains(w.Body.String(), "Current password is incorrect") {
+		t.Errorf("Expected a wrong password to be refused, got %d", w.Code)
+	}
+	form.Set("current_password", "s3cret-password")
+	if w := postForm(router, "/settings", form, session); w.Code != http.StatusOK || !contains(w.Body.String(), "confirmation link to janet@example.com") {
+		t.Errorf("Expected the change to wait for confirmation, got %d", w.Code)
+	}
+	msg, _ := mailer.Last()
+	if msg.To[0] != "janet@example.com" || msg.Subject != "Confirm your new email address" {
+		t.Errorf("Unexpected confirmation email %+v", msg)
+	}
+	var user models.User
+	db.First(&user)
+	if user.Email != "jane@example.com" || !user.EmailVerified {
+		t.Errorf("Expected the old address to be kept until confirmed, got %+v", user)
+	}
+
+	link := lastLink(t, mailer)
+	if w := get(router, link); w.Code != http.StatusOK || !contains(w.Body.String(), "janet@example.com") {
+		t.Errorf("Expected the email to change, got %d", w.Code)
+	}
+	db.First(&user)
+	if user.Email != "janet@example.com" || user.PendingEmail != "" || !user.EmailVerified {
+		t.Errorf("Expected the new address to be verified, got %+v", user)
+	}
+	if w := get(router, link); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected used link to be rejected, got %d", w.Code)
+	}
+}
+
+func TestAccountHandler_ResetPassword(t *testing.T) {
+	router, mailer, _ := setupAccountRouter(t)
+	postForm(router, "/register", url.Values{"email": {"jane@example.com"}, "username": {"jane"}, "password": {"s3cret-password"}}, nil)
+	mailer.Reset()
+
+	// Unknown addresses get the same response and no email
+	w := postForm(router, "/forgot-password", url.Values{"email": {"nobody@example.com"}}, nil)
+	if w.Code != http.StatusOK || !contains(w.Body.String(), "If an account exists") {
+		t.Errorf("Expected neutral response, got %d", w.Code)
+	}
+	if len(mailer.Messages()) != 0 {
+		t.Error("Expected no email for unknown address")
+	}
+
+	postForm(router, "/forgot-password", url.Values{"email": {"jane@example.com"}}, nil)
+	link := lastLink(t, mailer)
+	if w := get(router, link); w.Code != http.StatusOK || !contains(w.Body.String(), "Choose a new password") {
+		t.Fatalf("Expected reset form, got %d", w.Code)
+	}
+	token, _ := url.ParseQuery(link[len("/reset-password?"):])
+
+	w = postForm(router, "/reset-password", url.Values{"token": token["token"], "password": {"short"}}, nil)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+
+	w = postForm(router, "/reset-password", url.Values{"token": token["token"], "password": {"brand-new-password"}}, nil)
+	if w.Code != http.StatusSeeOther || sessionCookie(w) == nil {
+		t.Fatalf("Expected sign-in after reset, got %d", w.Code)
+	}
+
+	if w := postForm(router, "/login", url.Values{"identifier": {"jane"}, "password": {"brand-new-password"}}, nil); w.Code != http.StatusSeeOther {
+		t.Errorf("Expected login with new password, got %d", w.Code)
+	}
+	if w := get(router, link); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected used link to be rejected, got %d", w.Code)
+	}
+}
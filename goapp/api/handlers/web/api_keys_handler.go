@@ -0,0 +1,178 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/api/middleware"
+	"goapp/internal/apikeys"
+	"goapp/internal/auth"
+	"goapp/internal/authz"
+	"goapp/internal/container"
+	"goapp/web/templates/pages"
+)
+
+// APIKeysHandler handles the API key management page
+type APIKeysHandler struct {
+	container *container.Container
+}
+
+// NewAPIKeysHandler creates a new API keys handler
+func NewAPIKeysHandler(c *container.Container) *APIKeysHandler {
+	return &APIKeysHandler{container: c}
+}
+
+// Index lists the user's API keys, or every key for admins
+func (h *APIKeysHandler) Index(c *gin.Context) {
+	h.renderPage(c, http.StatusOK, pages.APIKeysPage{})
+}
+
+// Create issues a new key and shows it once
+func (h *APIKeysHandler) Create(c *gin.Context) {
+	form := pages.APIKeyForm{
+		Name:           c.PostForm("name"),
+		Scopes:         c.PostForm("scopes"),
+		ExpiresInDays:  c.PostForm("expires_in_days"),
+		ServiceAccount: c.PostForm("service_account"),
+		Errors:         map[string]string{},
+	}
+
+	var expiresIn time.Duration
+	if form.ExpiresInDays != "" {
+		days, err := strconv.Atoi(form.ExpiresInDays)
+		if err != nil || days <= 0 {
+			form.Errors["expires_in"] = "must be a positive number of days"
+			h.renderPage(c, http.StatusUnprocessableEntity, pages.APIKeysPage{Form: form})
+			return
+		}
+		expiresIn = time.Duration(days) * 24 * time.Hour
+	}
+
+	owner := middleware.CurrentUser(c)
+	if form.ServiceAccount != "" {
+		if !middleware.Authorize(c, authz.ActionCreate, authz.Type(authz.ResourceServiceAccounts)) {
+			return
+		}
+		account, err := h.container.APIKeys.ServiceAccount(c.Request.Context(), form.ServiceAccount)
+		if h.invalid(c, err, form) {
+			return
+		}
+		owner = account
+	}
+
+	token, key, err := h.container.APIKeys.Create(c.Request.Context(), apikeys.CreateInput{
+		Name:      form.Name,
+		UserID:    owner.ID,
+		Scopes:    strings.FieldsFunc(form.Scopes, func(r rune) bool { return r == ',' || r == ' ' }),
+		ExpiresIn: expiresIn,
+	})
+	if h.invalid(c, err, form) {
+		return
+	}
+
+	h.container.Logger.Info("API key created",
+		zap.String("key_id", key.KeyID),
+		zap.String("owner", owner.Username),
+		zap.Uint("created_by", middleware.CurrentUser(c).ID))
+	c.Header("Cache-Control", "no-store")
+	h.renderPage(c, http.StatusCreated, pages.APIKeysPage{NewKey: token})
+}
+
+// Revoke disables a key. HTMX requests get the updated table row back.
+func (h *APIKeysHandler) Revoke(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusNotFound, "404 page not found")
+		return
+	}
+
+	key, err := h.container.APIKeys.Get(c.Request.Context(), uint(id))
+	if errors.Is(err, apikeys.ErrKeyNotFound) {
+		c.String(http.StatusNotFound, "404 page not found")
+		return
+	}
+	if err != nil {
+		h.container.Logger.Error("Failed to load API key", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to revoke API key")
+		return
+	}
+	if !middleware.Authorize(c, authz.ActionDelete, key) {
+		return
+	}
+
+	if err := h.container.APIKeys.Revoke(c.Request.Context(), key.ID); err != nil {
+		h.container.Logger.Error("Failed to revoke API key", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to revoke API key")
+		return
+	}
+	h.container.Logger.Info("API key revoked", zap.String("key_id", key.KeyID), zap.Uint("revoked_by", middleware.CurrentUser(c).ID))
+
+	if c.GetHeader("HX-Request") != "true" {
+		c.Redirect(http.StatusSeeOther, "/settings/api-keys")
+		return
+	}
+	key, err = h.container.APIKeys.Get(c.Request.Context(), key.ID)
+	if err != nil {
+		h.container.Logger.Error("Failed to reload API key", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to render page")
+		return
+	}
+	h.render(c, http.StatusOK, pages.APIKeyRow(*key, h.showAll(c), time.Now()))
+}
+
+// invalid renders the form again for validation errors and reports whether err was handled
+func (h *APIKeysHandler) invalid(c *gin.Context, err error, form pages.APIKeyForm) bool {
+	if err == nil {
+		return false
+	}
+	var validationErr *auth.ValidationError
+	if errors.As(err, &validationErr) {
+		for field, msg := range validationErr.Fields {
+			form.Errors[field] = msg
+		}
+		h.renderPage(c, http.StatusUnprocessableEntity, pages.APIKeysPage{Form: form})
+		return true
+	}
+	h.container.Logger.Error("Failed to create API key", zap.Error(err))
+	c.String(http.StatusInternalServerError, "Failed to create API key")
+	return true
+}
+
+// showAll reports whether the user may see the keys of all users
+func (h *APIKeysHandler) showAll(c *gin.Context) bool {
+	return middleware.Can(c, authz.ActionRead, authz.Type(authz.ResourceAPIKeys))
+}
+
+func (h *APIKeysHandler) renderPage(c *gin.Context, status int, page pages.APIKeysPage) {
+	var userID uint
+	page.ShowOwner = h.showAll(c)
+	if !page.ShowOwner {
+		userID = middleware.CurrentUser(c).ID
+	}
+
+	keys, err := h.container.APIKeys.List(c.Request.Context(), userID)
+	if err != nil {
+		h.container.Logger.Error("Failed to list API keys", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to list API keys")
+		return
+	}
+	page.Keys = keys
+	page.Now = time.Now()
+	h.render(c, status, pages.APIKeys(page))
+}
+
+func (h *APIKeysHandler) render(c *gin.Context, status int, component templ.Component) {
+	c.Header("Content-Type", "text/html")
+	c.Status(status)
+	if err := component.Render(c.Request.Context(), c.Writer); err != nil {
+		h.container.Logger.Error("Failed to render API keys page", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to render page")
+		return
+	}
+}
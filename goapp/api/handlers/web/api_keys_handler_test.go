@@ -0,0 +1,133 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goapp/api/middleware"
+	"goapp/internal/apikeys"
+	"goapp/internal/authz"
+	"goapp/internal/config"
+	"goapp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupAPIKeysRouter signs requests in as the user named in the X-User header
+func setupAPIKeysRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Permission{}, &models.Role{}, &models.User{}, &models.APIKey{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	for _, name := range []string{"jane", "bob"} {
+		user := &models.User{Email: name + "@example.com", Username: name, PasswordHash: "hash", Active: true}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	container := setupTestContainer(t)
+	container.APIKeys = apikeys.NewService(db, config.APIKeyConfig{TokenPrefix: "goapp"})
+	handler := NewAPIKeysHandler(container)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		var user models.User
+		if err := db.Where("username = ?", c.GetHeader("X-User")).First(&user).Error; err == nil {
+			middleware.SetCurrentUser(c, &user)
+		}
+		c.Next()
+	})
+	router.Use(middleware.Policies(authz.DefaultPolicy()))
+	settings := router.Group("/settings/api-keys", middleware.RequireUser("/login"))
+	settings.GET("", handler.Index)
+	settings.POST("", handler.Create)
+	settings.POST("/:id/revoke", handler.Revoke)
+	return router, db
+}
+
+func apiKeysRequest(router *gin.Engine, method, path, user string, form url.Values, htmx bool) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-User", user)
+	if htmx {
+		req.Header.Set("HX-Request", "true")
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAPIKeysHandler(t *testing.T) {
+	router, db := setupAPIKeysRouter(t)
+
+	if w := apiKeysRequest(router, http.MethodGet, "/settings/api-keys", "", nil, false); w.Code != http.StatusSeeOther {
+		t.Errorf("Expected anonymous users to be redirected, got %d", w.Code)
+	}
+
+	w := apiKeysRequest(router, http.MethodPost, "/settings/api-keys", "jane", url.Values{"name": {"ci"}, "scopes": {"posts:read, comments:*"}}, false)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "goapp_") || !strings.Contains(w.Body.String(), "will not be shown again") {
+		t.Error("Expected the new key to be shown once")
+	}
+	if strings.Contains(w.Body.String(), "Service account") {
+		t.Error("Expected the service account field to be hidden from users")
+	}
+
+	var key models.APIKey
+	db.First(&key)
+	if key.Scopes != "comments:* posts:read" {
+		t.Errorf("Expected scopes from the form, got %q", key.Scopes)
+	}
+
+	t.Run("Validation", func(t *testing.T) {
+		w := apiKeysRequest(router, http.MethodPost, "/settings/api-keys", "jane", url.Values{"name": {"ci"}, "expires_in_days": {"soon"}}, false)
+		if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "positive number of days") {
+			t.Errorf("Expected expiry validation error, got %d", w.Code)
+		}
+		w = apiKeysRequest(router, http.MethodPost, "/settings/api-keys", "jane", url.Values{"name": {"ci"}}, false)
+		if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "at least one scope") {
+			t.Errorf("Expected scope validation error, got %d", w.Code)
+		}
+		w = apiKeysRequest(router, http.MethodPost, "/settings/api-keys", "jane", url.Values{"name": {"ci"}, "scopes": {"*"}, "service_account": {"billing"}}, false)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d creating a service account key as a user, got %d", http.StatusForbidden, w.Code)
+		}
+	})
+
+	t.Run("OtherUsersKeysHidden", func(t *testing.T) {
+		w := apiKeysRequest(router, http.MethodGet, "/settings/api-keys", "bob", nil, false)
+		if strings.Contains(w.Body.String(), key.KeyID) {
+			t.Error("Expected bob not to see jane's keys")
+		}
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		path := fmt.Sprintf("/settings/api-keys/%d/revoke", key.ID)
+		if w := apiKeysRequest(router, http.MethodPost, path, "bob", nil, true); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d", http.StatusForbidden, w.Code)
+		}
+
+		w := apiKeysRequest(router, http.MethodPost, path, "jane", nil, true)
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Revoked") {
+			t.Errorf("Expected revoked row, got %d: %s", w.Code, w.Body.String())
+		}
+		db.First(&key, key.ID)
+		if key.Active(time.Now()) {
+			t.Error("Expected key to be revoked")
+		}
+	})
+}
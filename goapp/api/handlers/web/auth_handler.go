@@ -0,0 +1,240 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/container"
+	"goapp/internal/models"
+	"goapp/web/templates/pages"
+)
+
+// AuthHandler handles the login, registration and logout pages
+type AuthHandler struct {
+	container *container.Container
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(c *container.Container) *AuthHandler {
+	return &AuthHandler{container: c}
+}
+
+// LoginPage renders the login form
+func (h *AuthHandler) LoginPage(c *gin.Context) {
+	next := safeRedirect(c.Query("next"))
+	if middleware.CurrentUser(c) != nil {
+		c.Redirect(http.StatusSeeOther, next)
+		return
+	}
+
+	h.render(c, http.StatusOK, pages.Login(pages.LoginForm{Next: next, SSO: h.ssoName()}, h.container.Config.Auth.RegistrationEnabled))
+}
+
+// Login checks the submitted credentials and starts a session
+func (h *AuthHandler) Login(c *gin.Context) {
+	form := pages.LoginForm{
+		Identifier: c.PostForm("identifier"),
+		Next:       safeRedirect(c.PostForm("next")),
+		SSO:        h.ssoName(),
+	}
+
+	user, err := h.container.Auth.Authenticate(c.Request.Context(), form.Identifier, c.PostForm("password"), clientMeta(c))
+	if errors.Is(err, auth.ErrInvalidCredentials) || errors.Is(err, auth.ErrInactiveUser) || errors.Is(err, auth.ErrAccountLocked) {
+		h.container.Logger.Info("Failed login attempt", zap.String("identifier", form.Identifier), zap.Error(err))
+		status := http.StatusUnauthorized
+		switch {
+		case errors.Is(err, auth.ErrInactiveUser):
+			form.Error = "This account has been deactivated."
+		case errors.Is(err, auth.ErrAccountLocked):
+			form.Error = "Too many failed sign-in attempts. Please wait a few minutes or reset your password."
+			status = http.StatusTooManyRequests
+		default:
+			form.Error = "Invalid email, username or password."
+		}
+		h.render(c, status, pages.Login(form, h.container.Config.Auth.RegistrationEnabled))
+		return
+	}
+	if err != nil {
+		h.container.Logger.Error("Failed to authenticate user", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to sign in")
+		return
+	}
+
+	h.startSession(c, user, form.Next)
+}
+
+// RegisterPage renders the registration form
+func (h *AuthHandler) RegisterPage(c *gin.Context) {
+	if !h.container.Config.Auth.RegistrationEnabled {
+		c.String(http.StatusNotFound, "404 page not found")
+		return
+	}
+
+	h.render(c, http.StatusOK, pages.Register(pages.RegisterForm{Next: safeRedirect(c.Query("next"))}))
+}
+
+// Register creates an account and signs the new user in
+func (h *AuthHandler) Register(c *gin.Context) {
+	if !h.container.Config.Auth.RegistrationEnabled {
+		c.String(http.StatusNotFound, "404 page not found")
+		return
+	}
+
+	form := pages.RegisterForm{
+		Email:     c.PostForm("email"),
+		Username:  c.PostForm("username"),
+		FirstName: c.PostForm("first_name"),
+		LastName:  c.PostForm("last_name"),
+		Next:      safeRedirect(c.PostForm("next")),
+	}
+
+	user, err := h.container.Auth.Register(c.Request.Context(), auth.RegisterInput{
+		Email:     form.Email,
+		Username:  form.Username,
+		FirstName: form.FirstName,
+		LastName:  form.LastName,
+		Password:  c.PostForm("password"),
+	})
+	var validationErr *auth.ValidationError
+	if errors.As(err, &validationErr) {
+		form.Errors = validationErr.Fields
+		h.render(c, http.StatusUnprocessableEntity, pages.Register(form))
+		return
+	}
+	if err != nil {
+		h.container.Logger.Error("Failed to register user", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to create account")
+		return
+	}
+
+	h.container.Logger.Info("User registered", zap.Uint("user_id", user.ID))
+	if h.container.Accounts != nil {
+		// The account works without a verified email, so a mail failure should not fail registration
+		if err := NewAccountHandler(h.container).SendVerification(c.Request.Context(), user); err != nil {
+			h.container.Logger.Error("Failed to send verification email", zap.Uint("user_id", user.ID), zap.Error(err))
+		}
+	}
+	h.startSession(c, user, form.Next)
+}
+
+// Logout ends the current session
+func (h *AuthHandler) Logout(c *gin.Context) {
+	cookieName := h.container.Config.Auth.SessionCookieName
+	if token, err := c.Cookie(cookieName); err == nil && token != "" {
+		if err := h.container.Sessions.Delete(c.Request.Context(), token); err != nil {
+			h.container.Logger.Error("Failed to delete session", zap.Error(err))
+		}
+	}
+
+	http.SetCookie(c.Writer, auth.ExpiredSessionCookie(cookieName, h.secureCookie(c)))
+	c.Redirect(http.StatusSeeOther, "/login")
+}
+
+// startSession signs user in after their password, single sign-on or a
+// password reset link was accepted. Users with two-factor authentication
+// are sent to enter a code first.
+func (h *AuthHandler) startSession(c *gin.Context, user *models.User, next string) {
+	if user.TwoFactorEnabled && h.container.TwoFactor != nil {
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     twoFactorCookie,
+			Value:    h.container.Accounts.LoginChallenge(user),
+			Path:     "/login/2fa",
+			MaxAge:   int(twoFactorCookieMaxAge.Seconds()),
+			HttpOnly: true,
+			Secure:   h.secureCookie(c),
+			SameSite: http.SameSiteLaxMode,
+		})
+		c.Redirect(http.StatusSeeOther, withNext("/login/2fa", next))
+		return
+	}
+	h.createSession(c, user, next)
+}
+
+// createSession replaces any existing session with a new one for user, so a
+// session token planted before login cannot be reused, and redirects to next
+func (h *AuthHandler) createSession(c *gin.Context, user *models.User, next string) {
+	cookieName := h.container.Config.Auth.SessionCookieName
+	if token, err := c.Cookie(cookieName); err == nil && token != "" {
+		if err := h.container.Sessions.Delete(c.Request.Context(), token); err != nil {
+			h.container.Logger.Warn("Failed to delete previous session", zap.Error(err))
+		}
+	}
+
+	token, _, err := h.container.Sessions.Create(c.Request.Context(), user.ID, clientMeta(c))
+	if err != nil {
+		h.container.Logger.Error("Failed to create session", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to sign in")
+		return
+	}
+
+	http.SetCookie(c.Writer, auth.SessionCookie(cookieName, token, h.secureCookie(c)))
+	h.container.Logger.Info("User signed in", zap.Uint("user_id", user.ID))
+	c.Redirect(http.StatusSeeOther, next)
+}
+
+// recordLogin adds a sign-in step handled outside auth.Service to the login history
+func (h *AuthHandler) recordLogin(c *gin.Context, user *models.User, method, outcome string) {
+	if h.container.LoginHistory == nil {
+		return
+	}
+	err := h.container.LoginHistory.Record(c.Request.Context(), auth.LoginAttempt{
+		UserID:     user.ID,
+		Identifier: user.Email,
+		Method:     method,
+		Outcome:    outcome,
+		Client:     clientMeta(c),
+	})
+	if err != nil {
+		h.container.Logger.Error("Failed to record login", zap.Uint("user_id", user.ID), zap.Error(err))
+	}
+}
+
+// clientMeta describes the client making the request
+func clientMeta(c *gin.Context) auth.SessionMeta {
+	return auth.SessionMeta{IPAddress: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+// ssoName returns the name of the single sign-on provider, or "" without one
+func (h *AuthHandler) ssoName() string {
+	if h.container.OIDC == nil {
+		return ""
+	}
+	return h.container.OIDC.Name()
+}
+
+func (h *AuthHandler) secureCookie(c *gin.Context) bool {
+	return h.container.Config.Security.CookieSecure || c.Request.TLS != nil
+}
+
+func (h *AuthHandler) render(c *gin.Context, status int, component templ.Component) {
+	c.Header("Content-Type", "text/html")
+	c.Status(status)
+	if err := component.Render(c.Request.Context(), c.Writer); err != nil {
+		h.container.Logger.Error("Failed to render auth page", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to render page")
+		return
+	}
+}
+
+// withNext appends next to path as a query parameter unless it is the default
+func withNext(path, next string) string {
+	if next == "" || next == "/" {
+		return path
+	}
+	return path + "?next=" + url.QueryEscape(next)
+}
+
+// safeRedirect only allows local paths as post-login targets to prevent open redirects
+func safeRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
@@ -0,0 +1,199 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/config"
+	"goapp/internal/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupAuthRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Session{}, &models.LoginEvent{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	container := setupTestContainer(t)
+	container.Config.Auth = config.AuthConfig{
+		PasswordHasher:      auth.HasherBcrypt,
+		BcryptCost:          bcrypt.MinCost,
+		MinPasswordLength:   8,
+		RegistrationEnabled: true,
+		SessionCookieName:   "session",
+		SessionTTL:          time.Hour,
+	}
+	hasher, err := auth.NewHasher(container.Config.Auth)
+	if err != nil {
+		t.Fatalf("NewHasher() error = %v", err)
+	}
+	container.Auth = auth.NewService(db, hasher, container.Config.Auth)
+	container.Sessions = auth.NewSessionStore(db, time.Hour)
+
+	handler := NewAuthHandler(container)
+	partials := NewPartialsHandler(container)
+
+	router := gin.New()
+	router.Use(middleware.Session(container.Sessions, "session", false, container.Logger))
+	router.GET("/login", handler.LoginPage)
+	router.POST("/login", handler.Login)
+	router.GET("/register", handler.RegisterPage)
+	router.POST("/register", handler.Register)
+	router.POST("/logout", handler.Logout)
+	router.GET("/partials/user-menu", partials.UserMenu)
+	return router
+}
+
+func postForm(router *gin.Engine, path string, form url.Values, cookie *http.Cookie) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func sessionCookie(w *httptest.ResponseRecorder) *http.Cookie {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "session" {
+			return c
+		}
+	}
+	return nil
+}
+
+func userMenu(router *gin.Engine, cookie *http.Cookie) string {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/partials/user-menu", nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	router.ServeHTTP(w, req)
+	return w.Body.String()
+}
+
+func TestAuthHandler_Pages(t *testing.T) {
+	router := setupAuthRouter(t)
+
+	for path, expected := range map[string]string{
+		"/login":    "Sign in to your account",
+		"/register": "Create your account",
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path+"?next=/posts", nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status %d, got %d", path, http.StatusOK, w.Code)
+		}
+		if !contains(w.Body.String(), expected) {
+			t.Errorf("%s: expected response to contain '%s'", path, expected)
+		}
+		if !contains(w.Body.String(), `name="next" value="/posts"`) {
+			t.Errorf("%s: expected next to be carried in the form", path)
+		}
+	}
+}
+
+func TestAuthHandler_RegisterLoginLogout(t *testing.T) {
+	router := setupAuthRouter(t)
+
+	// Register signs the new user in
+	w := postForm(router, "/register", url.Values{
+		"email":      {"jane@example.com"},
+		"username":   {"jane"},
+		"first_name": {"Jane"},
+		"last_name":  {"Doe"},
+		"password":   {"s3cret-password"},
+		"next":       {"/posts"},
+	}, nil)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/posts" {
+		t.Fatalf("Expected redirect to /posts, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	cookie := sessionCookie(w)
+	if cookie == nil || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("Expected HttpOnly SameSite=Lax session cookie, got %+v", cookie)
+	}
+	if body := userMenu(router, cookie); !contains(body, "Jane Doe") {
+		t.Errorf("Expected user menu for Jane Doe, got %s", body)
+	}
+
+	// Logout ends the session
+	w = postForm(router, "/logout", nil, cookie)
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("Expected status %d, got %d", http.StatusSeeOther, w.Code)
+	}
+	if body := userMenu(router, cookie); contains(body, "Jane Doe") {
+		t.Error("Expected session to be invalid after logout")
+	}
+
+	// Wrong password re-renders the form
+	w = postForm(router, "/login", url.Values{"identifier": {"jane"}, "password": {"wrong-password"}}, nil)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+	if !contains(w.Body.String(), "Invalid email, username or password.") || sessionCookie(w) != nil {
+		t.Error("Expected login error without a session cookie")
+	}
+
+	// Correct password starts a new session; external redirects are ignored
+	w = postForm(router, "/login", url.Values{"identifier": {"jane@example.com"}, "password": {"s3cret-password"}, "next": {"//evil.example"}}, nil)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/" {
+		t.Fatalf("Expected redirect to /, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if body := userMenu(router, sessionCookie(w)); !contains(body, "Jane Doe") {
+		t.Error("Expected user menu for Jane Doe after login")
+	}
+}
+
+func TestAuthHandler_RegisterValidation(t *testing.T) {
+	router := setupAuthRouter(t)
+
+	w := postForm(router, "/register", url.Values{
+		"email":    {"not-an-email"},
+		"username": {"jane"},
+		"password": {"short"},
+	}, nil)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+	body := w.Body.String()
+	for _, expected := range []string{"Email must be a valid email address", "Password must be at least 8 characters", `value="jane"`} {
+		if !contains(body, expected) {
+			t.Errorf("Expected response to contain '%s'", expected)
+		}
+	}
+}
+
+func TestSafeRedirect(t *testing.T) {
+	tests := map[string]string{
+		"":                     "/",
+		"/posts?page=2":        "/posts?page=2",
+		"//evil.example":       "/",
+		"/\\evil.example":      "/",
+		"https://evil.example": "/",
+	}
+	for next, want := range tests {
+		if got := safeRedirect(next); got != want {
+			t.Errorf("safeRedirect(%q) = %q, want %q", next, got, want)
+		}
+	}
+}
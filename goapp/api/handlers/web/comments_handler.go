@@ -0,0 +1,188 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/authz"
+	"goapp/internal/comments"
+	"goapp/internal/container"
+	"goapp/internal/models"
+	"goapp/web/templates/partials"
+)
+
+// CommentsHandler renders the comments section of post pages and handles its forms.
+// Every form answers HTMX requests with the updated section and other requests
+// with a redirect back to the post.
+type CommentsHandler struct {
+	container *container.Container
+}
+
+// NewCommentsHandler creates a new comments handler
+func NewCommentsHandler(c *container.Container) *CommentsHandler {
+	return &CommentsHandler{container: c}
+}
+
+// Section renders the comments of a post
+func (h *CommentsHandler) Section(c *gin.Context) {
+	post, ok := NewPostsHandler(h.container).find(c)
+	if !ok {
+		return
+	}
+	h.renderSection(c, http.StatusOK, post.ID, "")
+}
+
+// Create adds a comment, or a reply when the form has a parent_id
+func (h *CommentsHandler) Create(c *gin.Context) {
+	post, ok := NewPostsHandler(h.container).find(c)
+	if !ok {
+		return
+	}
+	if !middleware.Authorize(c, authz.ActionCreate, authz.Type(authz.ResourceComments)) {
+		return
+	}
+
+	input := comments.CreateInput{
+		PostID:   post.ID,
+		AuthorID: middleware.CurrentUser(c).ID,
+		Content:  c.PostForm("content"),
+	}
+	if v := c.PostForm("parent_id"); v != "" {
+		parentID, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			h.renderSection(c, http.StatusUnprocessableEntity, post.ID, "The comment you replied to does not exist.")
+			return
+		}
+		id := uint(parentID)
+		input.ParentID = &id
+	}
+
+	comment, err := h.container.Comments.Create(c.Request.Context(), input)
+	if err != nil {
+		h.fail(c, post.ID, err, "Failed to add comment")
+		return
+	}
+	h.container.Logger.Info("Comment created", zap.Uint("comment_id", comment.ID), zap.Uint("post_id", post.ID), zap.Uint("user_id", input.AuthorID))
+	h.done(c, post.ID)
+}
+
+// Update replaces the content of a comment
+func (h *CommentsHandler) Update(c *gin.Context) {
+	comment, ok := h.load(c)
+	if !ok || !middleware.Authorize(c, authz.ActionUpdate, comment) {
+		return
+	}
+
+	if err := h.container.Comments.Update(c.Request.Context(), comment, c.PostForm("content")); err != nil {
+		h.fail(c, comment.PostID, err, "Failed to update comment")
+		return
+	}
+	h.container.Logger.Info("Comment updated", zap.Uint("comment_id", comment.ID), zap.Uint("user_id", middleware.CurrentUser(c).ID))
+	h.done(c, comment.PostID)
+}
+
+// Delete soft-deletes a comment
+func (h *CommentsHandler) Delete(c *gin.Context) {
+	comment, ok := h.load(c)
+	if !ok || !middleware.Authorize(c, authz.ActionDelete, comment) {
+		return
+	}
+
+	if err := h.container.Comments.Delete(c.Request.Context(), comment); err != nil {
+		h.container.Logger.Error("Failed to delete comment", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to delete comment")
+		return
+	}
+	h.container.Logger.Info("Comment deleted", zap.Uint("comment_id", comment.ID), zap.Uint("user_id", middleware.CurrentUser(c).ID))
+	h.done(c, comment.PostID)
+}
+
+// load finds the comment named by the id path parameter, responding 404
+// when it does not exist or its post may not be read
+func (h *CommentsHandler) load(c *gin.Context) (*models.Comment, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusNotFound, "404 page not found")
+		return nil, false
+	}
+
+	comment, err := h.container.Comments.Get(c.Request.Context(), uint(id))
+	if errors.Is(err, comments.ErrNotFound) {
+		c.String(http.StatusNotFound, "404 page not found")
+		return nil, false
+	}
+	if err != nil {
+		h.container.Logger.Error("Failed to load comment", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to load comment")
+		return nil, false
+	}
+
+	post, err := h.container.Posts.Get(c.Request.Context(), comment.PostID)
+	if err != nil || !middleware.Can(c, authz.ActionRead, post) {
+		c.String(http.StatusNotFound, "404 page not found")
+		return nil, false
+	}
+	return comment, true
+}
+
+// fail shows validation errors in the section and reports anything else
+func (h *CommentsHandler) fail(c *gin.Context, postID uint, err error, message string) {
+	var validationErr *auth.ValidationError
+	if errors.As(err, &validationErr) {
+		h.renderSection(c, http.StatusUnprocessableEntity, postID, describe(validationErr))
+		return
+	}
+	h.container.Logger.Error(message, zap.Error(err))
+	c.String(http.StatusInternalServerError, message)
+}
+
+// describe turns field errors into one sentence, such as "Content is required."
+func describe(err *auth.ValidationError) string {
+	names := make([]string, 0, len(err.Fields))
+	for name := range err.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	messages := make([]string, len(names))
+	for i, name := range names {
+		label := "Content"
+		if name == "parent_id" {
+			label = "Reply"
+		}
+		messages[i] = fmt.Sprintf("%s %s.", label, err.Fields[name])
+	}
+	return strings.Join(messages, " ")
+}
+
+// done answers a successful form with the updated section, or a redirect to the post
+func (h *CommentsHandler) done(c *gin.Context, postID uint) {
+	if c.GetHeader("HX-Request") != "true" {
+		c.Redirect(http.StatusSeeOther, fmt.Sprintf("/posts/%d#comments", postID))
+		return
+	}
+	h.renderSection(c, http.StatusOK, postID, "")
+}
+
+func (h *CommentsHandler) renderSection(c *gin.Context, status int, postID uint, message string) {
+	tree, err := h.container.Comments.Tree(c.Request.Context(), postID, -1)
+	if err != nil {
+		h.container.Logger.Error("Failed to load comments", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to load comments")
+		return
+	}
+	NewAuthHandler(h.container).render(c, status, partials.Comments(partials.CommentsSection{
+		PostID:   postID,
+		Comments: tree,
+		MaxDepth: h.container.Comments.MaxDepth(),
+		Error:    message,
+	}))
+}
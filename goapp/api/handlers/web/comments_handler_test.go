@@ -0,0 +1,114 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goapp/api/middleware"
+	"goapp/internal/authz"
+	"goapp/internal/comments"
+	"goapp/internal/config"
+	"goapp/internal/models"
+	"goapp/internal/posts"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupCommentsRouter signs requests in as the user named in the X-User
+// header. Jane has written the published post "hello"; replies nest one level deep.
+func setupCommentsRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Post{}, &models.PostRevision{}, &models.Tag{}, &models.SlugRedirect{}, &models.Comment{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	for _, name := range []string{"jane", "bob"} {
+		user := &models.User{Email: name + "@example.com", Username: name, PasswordHash: "hash", Active: true}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	if err := db.Create(&models.Post{Title: "Hello", Slug: "hello", Content: "Body text", Published: true, UserID: 1}).Error; err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	container := setupTestContainer(t)
+	container.Posts = posts.NewService(db)
+	container.Comments = comments.NewService(db, config.CommentsConfig{MaxDepth: 1, MaxLength: 100})
+	postsHandler := NewPostsHandler(container)
+	handler := NewCommentsHandler(container)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		var user models.User
+		if err := db.Where("username = ?", c.GetHeader("X-User")).First(&user).Error; err == nil {
+			middleware.SetCurrentUser(c, &user)
+		}
+		c.Next()
+	})
+	router.Use(middleware.Policies(authz.DefaultPolicy()))
+	router.GET("/posts/:id", postsHandler.Show)
+	router.GET("/posts/:id/comments", handler.Section)
+	router.POST("/posts/:id/comments", handler.Create)
+	router.POST("/comments/:id", handler.Update)
+	router.POST("/comments/:id/delete", handler.Delete)
+	return router, db
+}
+
+func TestCommentsHandler(t *testing.T) {
+	router, db := setupCommentsRouter(t)
+
+	w := apiKeysRequest(router, http.MethodGet, "/posts/hello", "", nil, false)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Body text") || !strings.Contains(w.Body.String(), `hx-get="/posts/1/comments"`) {
+		t.Fatalf("Expected the post page to load its comments, got %d", w.Code)
+	}
+	if w := apiKeysRequest(router, http.MethodGet, "/posts/missing", "", nil, false); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	w = apiKeysRequest(router, http.MethodGet, "/posts/1/comments", "", nil, true)
+	if !strings.Contains(w.Body.String(), "No comments yet") || strings.Contains(w.Body.String(), "Add comment") {
+		t.Error("Expected an empty section without a form for anonymous users")
+	}
+	if w := apiKeysRequest(router, http.MethodPost, "/posts/1/comments", "", url.Values{"content": {"Hi"}}, true); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	w = apiKeysRequest(router, http.MethodPost, "/posts/1/comments", "bob", url.Values{"content": {"Nice post"}}, true)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Nice post") || !strings.Contains(w.Body.String(), "Reply") {
+		t.Fatalf("Expected the section with bob's comment, got %d", w.Code)
+	}
+	w = apiKeysRequest(router, http.MethodPost, "/posts/1/comments", "jane", url.Values{"content": {"Thanks"}, "parent_id": {"1"}}, false)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/posts/1#comments" {
+		t.Errorf("Expected a redirect to the post, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	w = apiKeysRequest(router, http.MethodPost, "/posts/1/comments", "bob", url.Values{"content": {"Too deep"}, "parent_id": {"2"}}, true)
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "nested more than 1 levels") {
+		t.Errorf("Expected a depth error, got %d", w.Code)
+	}
+
+	if w := apiKeysRequest(router, http.MethodPost, "/comments/1", "jane", url.Values{"content": {"Hijacked"}}, true); w.Code != http.StatusForbidden {
+		t.Errorf("Expected users not to edit others' comments, got %d", w.Code)
+	}
+	w = apiKeysRequest(router, http.MethodPost, "/comments/1", "bob", url.Values{"content": {"Great post"}}, true)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Great post") {
+		t.Errorf("Expected the edited comment, got %d", w.Code)
+	}
+
+	w = apiKeysRequest(router, http.MethodPost, "/comments/1/delete", "bob", nil, true)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "This comment was deleted.") || !strings.Contains(w.Body.String(), "Thanks") {
+		t.Errorf("Expected a placeholder above jane's reply, got %d", w.Code)
+	}
+	var count int64
+	db.Unscoped().Model(&models.Comment{}).Where("deleted_at IS NOT NULL").Count(&count)
+	if count != 1 {
+		t.Errorf("Expected 1 soft-deleted comment, got %d", count)
+	}
+}
@@ -0,0 +1,33 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/internal/container"
+	"goapp/internal/maintenance"
+	"goapp/web/templates/pages"
+)
+
+// MaintenanceHandler renders the page shown while in maintenance mode
+type MaintenanceHandler struct {
+	container *container.Container
+}
+
+// NewMaintenanceHandler creates a new maintenance handler
+func NewMaintenanceHandler(c *container.Container) *MaintenanceHandler {
+	return &MaintenanceHandler{container: c}
+}
+
+// Render renders the maintenance page; the caller sets the status code
+func (h *MaintenanceHandler) Render(c *gin.Context, status maintenance.Status) {
+	component := pages.Maintenance(status)
+
+	c.Header("Content-Type", "text/html")
+	if err := component.Render(c.Request.Context(), c.Writer); err != nil {
+		h.container.Logger.Error("Failed to render maintenance page", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to render page")
+		return
+	}
+}
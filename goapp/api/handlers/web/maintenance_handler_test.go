@@ -0,0 +1,39 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/maintenance"
+)
+
+func TestMaintenanceHandler_Render(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewMaintenanceHandler(setupTestContainer(t))
+
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		c.Status(http.StatusServiceUnavailable)
+		handler.Render(c, maintenance.Status{Enabled: true, Message: "Upgrading the database", RetryAfterSeconds: 120})
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "text/html" {
+		t.Errorf("Expected content type 'text/html', got '%s'", contentType)
+	}
+
+	body := w.Body.String()
+	for _, expected := range []string{"Upgrading the database", "2m0s"} {
+		if !contains(body, expected) {
+			t.Errorf("Expected response to contain '%s'", expected)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package web
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/internal/auth"
+	"goapp/internal/container"
+	"goapp/internal/models"
+	"goapp/internal/oidc"
+	"goapp/web/templates/pages"
+)
+
+const (
+	// oidcFlowCookie carries the state, nonce and PKCE verifier of a sign-in in progress
+	oidcFlowCookie = "oidc_flow"
+	oidcFlowPath   = "/auth/oidc"
+	oidcFlowMaxAge = 600
+)
+
+// oidcFlow is the per-attempt data remembered between login and callback
+type oidcFlow struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Next     string `json:"next"`
+}
+
+// OIDCHandler signs users in through an OpenID Connect provider
+type OIDCHandler struct {
+	container *container.Container
+}
+
+// NewOIDCHandler creates a new OIDC handler
+func NewOIDCHandler(c *container.Container) *OIDCHandler {
+	return &OIDCHandler{container: c}
+}
+
+// Login redirects to the provider's authorization endpoint
+func (h *OIDCHandler) Login(c *gin.Context) {
+	flow := oidcFlow{Next: safeRedirect(c.Query("next"))}
+	var err error
+	if flow.State, err = oidc.RandomString(16); err == nil {
+		if flow.Nonce, err = oidc.RandomString(16); err == nil {
+			flow.Verifier, err = oidc.NewCodeVerifier()
+		}
+	}
+	if err != nil {
+		h.container.Logger.Error("Failed to start OIDC sign-in", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to sign in")
+		return
+	}
+
+	authURL, err := h.container.OIDC.AuthCodeURL(c.Request.Context(), flow.State, flow.Nonce, oidc.CodeChallenge(flow.Verifier))
+	if err != nil {
+		h.container.Logger.Error("OIDC provider unavailable", zap.Error(err))
+		h.fail(c, http.StatusBadGateway, flow.Next, h.container.OIDC.Name()+" sign-in is unavailable right now.")
+		return
+	}
+
+	data, _ := json.Marshal(flow)
+	h.setFlowCookie(c, base64.RawURLEncoding.EncodeToString(data), oidcFlowMaxAge)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback completes the sign-in when the provider redirects back
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	flow, ok := h.flow(c)
+	// The flow is single use whatever the outcome
+	h.setFlowCookie(c, "", -1)
+
+	// Comparing state ties the response to this browser's request (CSRF protection)
+	if !ok || subtle.ConstantTimeCompare([]byte(c.Query("state")), []byte(flow.State)) != 1 {
+		h.fail(c, http.StatusBadRequest, flow.Next, "Your sign-in attempt expired. Please try again.")
+		return
+	}
+	if providerErr := c.Query("error"); providerErr != "" {
+		h.container.Logger.Info("OIDC sign-in refused by provider", zap.String("error", providerErr))
+		h.fail(c, http.StatusUnauthorized, flow.Next, h.container.OIDC.Name()+" sign-in was cancelled or refused.")
+		return
+	}
+
+	ctx := c.Request.Context()
+	tokens, err := h.container.OIDC.Exchange(ctx, c.Query("code"), flow.Verifier)
+	if err != nil {
+		h.container.Logger.Warn("OIDC code exchange failed", zap.Error(err))
+		h.fail(c, http.StatusBadGateway, flow.Next, h.container.OIDC.Name()+" sign-in failed. Please try again.")
+		return
+	}
+	claims, err := h.container.OIDC.Verify(ctx, tokens.IDToken, flow.Nonce)
+	if err != nil {
+		h.container.Logger.Warn("OIDC ID token rejected", zap.Error(err))
+		h.fail(c, http.StatusUnauthorized, flow.Next, h.container.OIDC.Name()+" sign-in failed. Please try again.")
+		return
+	}
+
+	user, err := h.container.Identities.SignIn(ctx, claims)
+	switch {
+	case errors.Is(err, oidc.ErrEmailNotVerified), errors.Is(err, oidc.ErrRegistrationDisabled):
+		h.container.Logger.Info("OIDC sign-in without a local account", zap.String("subject", claims.Subject), zap.Error(err))
+		h.fail(c, http.StatusForbidden, flow.Next, "Your "+h.container.OIDC.Name()+" account is not linked to an account here.")
+		return
+	case errors.Is(err, oidc.ErrAccountNotVerified):
+		h.fail(c, http.StatusForbidden, flow.Next, "Sign in with your password and verify your email address before using "+h.container.OIDC.Name()+".")
+		return
+	case errors.Is(err, auth.ErrInactiveUser):
+		h.fail(c, http.StatusForbidden, flow.Next, "This account has been deactivated.")
+		return
+	case err != nil:
+		h.container.Logger.Error("Failed to sign in OIDC identity", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to sign in")
+		return
+	}
+
+	authHandler := NewAuthHandler(h.container)
+	outcome := models.LoginSucceeded
+	if user.TwoFactorEnabled {
+		outcome = models.LoginTwoFactorRequired
+	}
+	authHandler.recordLogin(c, user, models.LoginMethodSSO, outcome)
+	authHandler.startSession(c, user, flow.Next)
+}
+
+// flow decodes the flow cookie
+func (h *OIDCHandler) flow(c *gin.Context) (oidcFlow, bool) {
+	var flow oidcFlow
+	value, err := c.Cookie(oidcFlowCookie)
+	if err != nil || value == "" {
+		return oidcFlow{Next: "/"}, false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || json.Unmarshal(data, &flow) != nil || flow.State == "" {
+		return oidcFlow{Next: "/"}, false
+	}
+	flow.Next = safeRedirect(flow.Next)
+	return flow, true
+}
+
+func (h *OIDCHandler) setFlowCookie(c *gin.Context, value string, maxAge int) {
+	// SameSite=Lax still sends the cookie on the provider's top-level redirect back
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    value,
+		Path:     oidcFlowPath,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   h.container.Config.Security.CookieSecure || c.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// fail shows the login page with message
+func (h *OIDCHandler) fail(c *gin.Context, status int, next, message string) {
+	form := pages.LoginForm{Next: next, Error: message, SSO: h.container.OIDC.Name()}
+	NewAuthHandler(h.container).render(c, status, pages.Login(form, h.container.Config.Auth.RegistrationEnabled))
+}
@@ -0,0 +1,145 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/config"
+	"goapp/internal/models"
+	"goapp/internal/oidc"
+	"goapp/internal/oidc/oidctest"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupOIDCRouter(t *testing.T) (*gin.Engine, *oidctest.Server) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Session{}, &models.Identity{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	server := oidctest.NewServer()
+	t.Cleanup(server.Close)
+
+	container := setupTestContainer(t)
+	container.Config.Auth = config.AuthConfig{SessionCookieName: "session", SessionTTL: time.Hour}
+	container.Config.OIDC = config.OIDCConfig{
+		ProviderName: "Test SSO",
+		Issuer:       server.Issuer(),
+		ClientID:     server.ClientID,
+		ClientSecret: server.ClientSecret,
+		RedirectURL:  "http://localhost/auth/oidc/callback",
+		Scopes:       []string{"openid", "email"},
+		AutoRegister: true,
+	}
+	container.Sessions = auth.NewSessionStore(db, time.Hour)
+	container.OIDC = oidc.NewClient(container.Config.OIDC, http.DefaultClient)
+	container.Identities = oidc.NewIdentityStore(db, true)
+
+	handler := NewOIDCHandler(container)
+	partials := NewPartialsHandler(container)
+
+	router := gin.New()
+	router.Use(middleware.Session(container.Sessions, "session", false, container.Logger))
+	router.GET("/auth/oidc/login", handler.Login)
+	router.GET("/auth/oidc/callback", handler.Callback)
+	router.GET("/partials/user-menu", partials.UserMenu)
+	return router, server
+}
+
+// startOIDCLogin begins a sign-in and returns the flow cookie and the
+// callback URL the provider redirected to
+func startOIDCLogin(t *testing.T, router *gin.Engine, server *oidctest.Server) (*http.Cookie, string) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/auth/oidc/login?next=/posts", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusFound {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusFound, w.Code, w.Body.String())
+	}
+
+	var flow *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == oidcFlowCookie {
+			flow = c
+		}
+	}
+	if flow == nil || !flow.HttpOnly || flow.Path != "/auth/oidc" {
+		t.Fatalf("Expected HttpOnly flow cookie, got %+v", flow)
+	}
+
+	callback, err := server.Authorize(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+	return flow, callback.RequestURI()
+}
+
+func oidcCallback(router *gin.Engine, uri string, flow *http.Cookie) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, uri, nil)
+	if flow != nil {
+		req.AddCookie(flow)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestOIDCHandler_SignIn(t *testing.T) {
+	router, server := setupOIDCRouter(t)
+	flow, callback := startOIDCLogin(t, router, server)
+
+	w := oidcCallback(router, callback, flow)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/posts" {
+		t.Fatalf("Expected redirect to /posts, got %d %q: %s", w.Code, w.Header().Get("Location"), w.Body.String())
+	}
+	if body := userMenu(router, sessionCookie(w)); !contains(body, "Jane Doe") {
+		t.Errorf("Expected user menu for Jane Doe, got %s", body)
+	}
+
+	// Replaying the callback fails: the code is spent and the flow cookie is cleared
+	w = oidcCallback(router, callback, nil)
+	if w.Code != http.StatusBadRequest || sessionCookie(w) != nil {
+		t.Errorf("Expected replay to be rejected, got %d", w.Code)
+	}
+}
+
+func TestOIDCHandler_Failures(t *testing.T) {
+	t.Run("StateMismatch", func(t *testing.T) {
+		router, server := setupOIDCRouter(t)
+		flow, _ := startOIDCLogin(t, router, server)
+		w := oidcCallback(router, "/auth/oidc/callback?code=abc&state=forged", flow)
+		if w.Code != http.StatusBadRequest || !contains(w.Body.String(), "expired") {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("Denied", func(t *testing.T) {
+		router, server := setupOIDCRouter(t)
+		server.Deny = true
+		flow, callback := startOIDCLogin(t, router, server)
+		w := oidcCallback(router, callback, flow)
+		if w.Code != http.StatusUnauthorized || !contains(w.Body.String(), "Test SSO sign-in was cancelled") {
+			t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+	})
+
+	t.Run("UnverifiedEmail", func(t *testing.T) {
+		router, server := setupOIDCRouter(t)
+		server.SetUser(oidctest.User{Subject: "user-2", Email: "bob@example.com"})
+		flow, callback := startOIDCLogin(t, router, server)
+		w := oidcCallback(router, callback, flow)
+		if w.Code != http.StatusForbidden || sessionCookie(w) != nil {
+			t.Errorf("Expected status %d without a session, got %d", http.StatusForbidden, w.Code)
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"goapp/api/middleware"
 	"goapp/internal/container"
 	"goapp/web/templates/partials"
 )
@@ -89,10 +90,7 @@ func (h *PartialsHandler) Notifications(c *gin.Context) {
 
 // UserMenu renders the user menu dropdown
 func (h *PartialsHandler) UserMenu(c *gin.Context) {
-	// In real app, get username from session/context
-	username := "John Doe"
-	
-	component := partials.UserMenuDropdown(username)
+	component := partials.UserMenuDropdown(middleware.CurrentUser(c))
 	
 	c.Header("Content-Type", "text/html")
 	if err := component.Render(c.Request.Context(), c.Writer); err != nil {
@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"goapp/api/middleware"
+	"goapp/internal/models"
 )
 
 func TestPartialsHandler_ActivityFeed(t *testing.T) {
@@ -93,8 +95,11 @@ func TestPartialsHandler_UserMenu(t *testing.T) {
 	container := setupTestContainer(t)
 	handler := NewPartialsHandler(container)
 	
-	// Create test router
+	// Create test router with a signed-in user
 	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		middleware.SetCurrentUser(c, &models.User{FirstName: "John", LastName: "Doe", Email: "john@example.com"})
+	})
 	router.GET("/partials/user-menu", handler.UserMenu)
 	
 	// Create test request
@@ -116,6 +121,7 @@ func TestPartialsHandler_UserMenu(t *testing.T) {
 	body := w.Body.String()
 	expectedStrings := []string{
 		"John Doe",
+		"john@example.com",
 		"Your Profile",
 		"Settings",
 		"Sign out",
@@ -152,4 +158,23 @@ func TestPartialsHandler_MarkNotificationRead(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
 	}
-}
\ No newline at end of file
+}
+func TestPartialsHandler_UserMenuAnonymous(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewPartialsHandler(setupTestContainer(t))
+
+	router := gin.New()
+	router.GET("/partials/user-menu", handler.UserMenu)
+
+	req, _ := http.NewRequest(http.MethodGet, "/partials/user-menu", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	if !contains(body, "Sign in") {
+		t.Error("Expected anonymous user menu to offer 'Sign in'")
+	}
+	if contains(body, "Sign out") {
+		t.Error("Expected anonymous user menu not to offer 'Sign out'")
+	}
+}
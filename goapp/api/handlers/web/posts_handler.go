@@ -1,13 +1,22 @@
 package web
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/authz"
 	"goapp/internal/container"
 	"goapp/internal/models"
+	"goapp/internal/posts"
 	"goapp/web/templates/pages"
+	"goapp/web/templates/partials"
 )
 
 // PostsHandler handles post-related web pages
@@ -20,27 +29,43 @@ func NewPostsHandler(c *container.Container) *PostsHandler {
 	return &PostsHandler{container: c}
 }
 
-// Index renders the posts list page
+// Index renders a page of posts, newest first. The cursor query parameter
+// continues after the previous page.
 func (h *PostsHandler) Index(c *gin.Context) {
-	var posts []models.Post
+	var visible []models.Post
+	var nextCursor string
 	
 	// Fetch posts from database
-	if h.container.Database != nil {
-		db := h.container.Database.DB()
-		if err := db.Preload("User").Order("created_at DESC").Find(&posts).Error; err != nil {
+	if h.container.Posts != nil {
+		opts := posts.ListOptions{
+			// Drafts are only listed for users allowed to read them
+			IncludeDrafts: middleware.Can(c, authz.ActionRead, authz.Type(authz.ResourcePosts)),
+			Cursor:        c.Query("cursor"),
+		}
+		if user := middleware.CurrentUser(c); user != nil {
+			opts.ViewerID = user.ID
+		}
+		page, err := h.container.Posts.List(c.Request.Context(), opts)
+		if errors.Is(err, posts.ErrInvalidCursor) {
+			c.Redirect(http.StatusSeeOther, "/posts")
+			return
+		}
+		if err != nil {
 			h.container.Logger.Error("Failed to fetch posts", zap.Error(err))
 			c.String(http.StatusInternalServerError, "Failed to fetch posts")
 			return
 		}
+		visible, nextCursor = page.Posts, page.NextCursor
 	} else {
 		// Mock data when database is not available
 		h.container.Logger.Warn("Database not available, using mock data")
-		posts = []models.Post{
+		mock := []models.Post{
 			{
 				BaseModel: models.BaseModel{ID: 1},
 				Title:     "Welcome to GoApp",
 				Slug:      "welcome-to-goapp",
 				Summary:   "This is a demo post showing the web UI capabilities",
+				Status:    models.PostPublished,
 				Published: true,
 				ViewCount: 42,
 			},
@@ -49,13 +74,19 @@ func (h *PostsHandler) Index(c *gin.Context) {
 				Title:     "Building with HTMX and Templ",
 				Slug:      "building-with-htmx-templ",
 				Summary:   "Learn how to build dynamic web apps with Go",
+				Status:    models.PostPublished,
 				Published: true,
 				ViewCount: 128,
 			},
 		}
+		for i := range mock {
+			if middleware.Can(c, authz.ActionRead, &mock[i]) {
+				visible = append(visible, mock[i])
+			}
+		}
 	}
 	
-	component := pages.PostsIndex(posts)
+	component := pages.PostsIndex(visible, nextCursor)
 	
 	c.Header("Content-Type", "text/html")
 	if err := component.Render(c.Request.Context(), c.Writer); err != nil {
@@ -63,4 +94,194 @@ func (h *PostsHandler) Index(c *gin.Context) {
 		c.String(http.StatusInternalServerError, "Failed to render page")
 		return
 	}
-}
\ No newline at end of file
+}
+
+// Show renders a post by ID or slug
+func (h *PostsHandler) Show(c *gin.Context) {
+	post, ok := h.find(c)
+	if !ok {
+		return
+	}
+	middleware.CountView(c, h.container.Views, post)
+	NewAuthHandler(h.container).render(c, http.StatusOK, pages.PostShow(*post))
+}
+
+// find loads the post named by the id path parameter, an ID or a slug. It
+// responds 404 when the post does not exist or may not be read.
+func (h *PostsHandler) find(c *gin.Context) (*models.Post, bool) {
+	var post *models.Post
+	var err error
+	if id, parseErr := strconv.ParseUint(c.Param("id"), 10, 64); parseErr == nil {
+		post, err = h.container.Posts.Get(c.Request.Context(), uint(id))
+	} else {
+		post, err = h.container.Posts.GetBySlug(c.Request.Context(), c.Param("id"))
+		if errors.Is(err, posts.ErrNotFound) {
+			// Links to old slugs lead to the post's current one
+			moved, movedErr := h.container.Posts.GetByOldSlug(c.Request.Context(), c.Param("id"))
+			if movedErr == nil && middleware.Can(c, authz.ActionRead, moved) {
+				middleware.RedirectParam(c, "id", moved.Slug)
+				return nil, false
+			}
+		}
+	}
+
+	if errors.Is(err, posts.ErrNotFound) || (err == nil && !middleware.Can(c, authz.ActionRead, post)) {
+		c.String(http.StatusNotFound, "404 page not found")
+		return nil, false
+	}
+	if err != nil {
+		h.container.Logger.Error("Failed to load post", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to load post")
+		return nil, false
+	}
+	return post, true
+}
+
+// SetStatus moves a post to the status of the form. Scheduling reads
+// scheduled_at as a UTC datetime-local value.
+func (h *PostsHandler) SetStatus(c *gin.Context) {
+	post, ok := h.find(c)
+	if !ok || !middleware.Authorize(c, authz.ActionUpdate, post) {
+		return
+	}
+
+	status := c.PostForm("status")
+	input := posts.UpdateInput{Status: &status, EditorID: middleware.CurrentUser(c).ID}
+	if v := c.PostForm("scheduled_at"); v != "" {
+		scheduledAt, err := time.Parse("2006-01-02T15:04", v)
+		if err != nil {
+			h.workflow(c, http.StatusUnprocessableEntity, post, "Enter the publication time as a date and time.")
+			return
+		}
+		input.ScheduledAt = &scheduledAt
+	}
+	if posts.NeedsPublish(post.Status, status) && !middleware.Authorize(c, authz.ActionPublish, post) {
+		return
+	}
+
+	err := h.container.Posts.Update(c.Request.Context(), post, input)
+	var validationErr *auth.ValidationError
+	if errors.As(err, &validationErr) {
+		h.workflow(c, http.StatusUnprocessableEntity, post, validationMessage(validationErr))
+		return
+	}
+	if err != nil {
+		h.container.Logger.Error("Failed to change post status", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to change post status")
+		return
+	}
+	h.container.Logger.Info("Post status changed", zap.Uint("post_id", post.ID), zap.String("status", post.Status))
+	if c.GetHeader("HX-Request") != "true" {
+		c.Redirect(http.StatusSeeOther, fmt.Sprintf("/posts/%d", post.ID))
+		return
+	}
+	h.workflow(c, http.StatusOK, post, "")
+}
+
+// workflow renders the status of a post and its workflow form
+func (h *PostsHandler) workflow(c *gin.Context, status int, post *models.Post, errMsg string) {
+	NewAuthHandler(h.container).render(c, status, partials.PostWorkflow(*post, errMsg))
+}
+
+// validationMessage turns the field errors of the status form into a sentence
+func validationMessage(err *auth.ValidationError) string {
+	switch {
+	case err.Fields["status"] != "":
+		return "Status " + err.Fields["status"] + "."
+	case err.Fields["scheduled_at"] != "":
+		return "Publication time " + err.Fields["scheduled_at"] + "."
+	}
+	return "The post is not valid: " + err.Error() + "."
+}
+
+// Revisions lists the revisions of a post, newest first
+func (h *PostsHandler) Revisions(c *gin.Context) {
+	post, ok := h.find(c)
+	if !ok || !middleware.Authorize(c, authz.ActionUpdate, post) {
+		return
+	}
+	h.revisions(c, post, "")
+}
+
+func (h *PostsHandler) revisions(c *gin.Context, post *models.Post, notice string) {
+	revisions, err := h.container.Posts.Revisions(c.Request.Context(), post)
+	if err != nil {
+		h.container.Logger.Error("Failed to list revisions", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to list revisions")
+		return
+	}
+	NewAuthHandler(h.container).render(c, http.StatusOK, pages.PostRevisions(pages.PostRevisionsPage{
+		Post:      *post,
+		Revisions: revisions,
+		Notice:    notice,
+	}))
+}
+
+// Revision shows the changes of a revision's content since the one before it
+func (h *PostsHandler) Revision(c *gin.Context) {
+	post, ok := h.find(c)
+	if !ok || !middleware.Authorize(c, authz.ActionUpdate, post) {
+		return
+	}
+	revision, ok := h.revision(c, post)
+	if !ok {
+		return
+	}
+
+	page := pages.RevisionDiffPage{Post: *post, Revision: *revision}
+	var before string
+	if revision.Number > 1 {
+		earlier, err := h.container.Posts.Revision(c.Request.Context(), post, revision.Number-1)
+		if err != nil && !errors.Is(err, posts.ErrRevisionNotFound) {
+			h.container.Logger.Error("Failed to load revision", zap.Error(err))
+			c.String(http.StatusInternalServerError, "Failed to load revision")
+			return
+		}
+		if earlier != nil {
+			page.Against, before = earlier.Number, earlier.Content
+		}
+	}
+	page.Diff = posts.Diff(before, revision.Content)
+	NewAuthHandler(h.container).render(c, http.StatusOK, pages.RevisionDiff(page))
+}
+
+// Restore copies a revision back into its post and shows the revisions
+func (h *PostsHandler) Restore(c *gin.Context) {
+	post, ok := h.find(c)
+	if !ok || !middleware.Authorize(c, authz.ActionUpdate, post) {
+		return
+	}
+	revision, ok := h.revision(c, post)
+	if !ok {
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	if err := h.container.Posts.Restore(c.Request.Context(), post, revision.Number, user.ID); err != nil {
+		h.container.Logger.Error("Failed to restore revision", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to restore revision")
+		return
+	}
+	h.container.Logger.Info("Post revision restored", zap.Uint("post_id", post.ID), zap.Int("revision", revision.Number), zap.Uint("user_id", user.ID))
+	h.revisions(c, post, fmt.Sprintf("Revision %d restored.", revision.Number))
+}
+
+// revision loads the revision named by the number path parameter, responding 404 when it does not exist
+func (h *PostsHandler) revision(c *gin.Context, post *models.Post) (*models.PostRevision, bool) {
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		c.String(http.StatusNotFound, "404 page not found")
+		return nil, false
+	}
+	revision, err := h.container.Posts.Revision(c.Request.Context(), post, number)
+	if errors.Is(err, posts.ErrRevisionNotFound) {
+		c.String(http.StatusNotFound, "404 page not found")
+		return nil, false
+	}
+	if err != nil {
+		h.container.Logger.Error("Failed to load revision", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to load revision")
+		return nil, false
+	}
+	return revision, true
+}
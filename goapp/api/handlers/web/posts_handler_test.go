@@ -0,0 +1,189 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goapp/api/middleware"
+	"goapp/internal/authz"
+	"goapp/internal/models"
+	"goapp/internal/posts"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func postsPage(t *testing.T, user *models.User) string {
+	gin.SetMode(gin.TestMode)
+	container := setupTestContainer(t)
+	handler := NewPostsHandler(container)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if user != nil {
+			middleware.SetCurrentUser(c, user)
+		}
+		c.Next()
+	})
+	router.Use(middleware.Policies(authz.DefaultPolicy()))
+	router.GET("/posts", handler.Index)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/posts", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	return w.Body.String()
+}
+
+func TestPostsHandler_Index(t *testing.T) {
+	anonymous := postsPage(t, nil)
+	if !strings.Contains(anonymous, "Welcome to GoApp") {
+		t.Error("Expected published posts to be listed")
+	}
+	if strings.Contains(anonymous, "New Post") || strings.Contains(anonymous, "/edit") {
+		t.Error("Expected anonymous users not to see post actions")
+	}
+
+	member := postsPage(t, &models.User{BaseModel: models.BaseModel{ID: 7}})
+	if !strings.Contains(member, "New Post") {
+		t.Error("Expected signed-in users to see New Post")
+	}
+	if strings.Contains(member, "/edit") {
+		t.Error("Expected users not to see Edit on other users' posts")
+	}
+
+	admin := &models.User{
+		BaseModel: models.BaseModel{ID: 8},
+		Roles:     []models.Role{{Name: authz.RoleAdmin, Permissions: []models.Permission{{Name: models.PermissionAll}}}},
+	}
+	if !strings.Contains(postsPage(t, admin), "/posts/1/edit") {
+		t.Error("Expected admins to see Edit on every post")
+	}
+}
+
+// setupWorkflowRouter signs requests in as the user named in the X-User header.
+// Jane has written the draft "draft" and edited it once.
+func setupWorkflowRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Post{}, &models.PostRevision{}, &models.Tag{}, &models.SlugRedirect{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	for _, name := range []string{"jane", "bob"} {
+		user := &models.User{Email: name + "@example.com", Username: name, PasswordHash: "hash", Active: true}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	container := setupTestContainer(t)
+	container.Posts = posts.NewService(db)
+	ctx := context.Background()
+	post, err := container.Posts.Create(ctx, posts.CreateInput{Title: "Draft", Content: "First line", AuthorID: 1})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	content, slug := "First line\nSecond line", "first-draft"
+	if err := container.Posts.Update(ctx, post, posts.UpdateInput{Content: &content, Slug: &slug, EditorID: 1}); err != nil {
+		t.Fatalf("Failed to update post: %v", err)
+	}
+	handler := NewPostsHandler(container)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		var user models.User
+		if err := db.Where("username = ?", c.GetHeader("X-User")).First(&user).Error; err == nil {
+			middleware.SetCurrentUser(c, &user)
+		}
+		c.Next()
+	})
+	router.Use(middleware.Policies(authz.DefaultPolicy()))
+	router.GET("/posts/:id", handler.Show)
+	router.POST("/posts/:id/status", handler.SetStatus)
+	router.GET("/posts/:id/revisions", handler.Revisions)
+	router.GET("/posts/:id/revisions/:number", handler.Revision)
+	router.POST("/posts/:id/revisions/:number/restore", handler.Restore)
+	return router
+}
+
+func TestPostsHandler_Workflow(t *testing.T) {
+	router := setupWorkflowRouter(t)
+
+	if w := apiKeysRequest(router, http.MethodPost, "/posts/1/status", "bob", url.Values{"status": {"in_review"}}, true); w.Code != http.StatusNotFound {
+		t.Errorf("Expected others' drafts to be hidden, got %d", w.Code)
+	}
+	w := apiKeysRequest(router, http.MethodPost, "/posts/1/status", "jane", url.Values{"status": {"in_review"}}, true)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `id="post-workflow"`) || !strings.Contains(w.Body.String(), "In review") {
+		t.Fatalf("Expected the workflow partial, got %d", w.Code)
+	}
+	w = apiKeysRequest(router, http.MethodPost, "/posts/1/status", "jane", url.Values{"status": {"archived"}}, true)
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "cannot change from in_review to archived") {
+		t.Errorf("Expected a validation error, got %d", w.Code)
+	}
+	w = apiKeysRequest(router, http.MethodPost, "/posts/1/status", "jane", url.Values{"status": {"scheduled"}, "scheduled_at": {"2000-01-01T10:00"}}, true)
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "must be in the future") {
+		t.Errorf("Expected past publication times to be refused, got %d", w.Code)
+	}
+	w = apiKeysRequest(router, http.MethodPost, "/posts/1/status", "jane", url.Values{"status": {"published"}}, false)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/posts/1" {
+		t.Errorf("Expected a redirect to the post, got %d", w.Code)
+	}
+
+	if w := apiKeysRequest(router, http.MethodGet, "/posts/1/revisions", "bob", nil, false); w.Code != http.StatusForbidden {
+		t.Errorf("Expected users not to see the revisions of others' posts, got %d", w.Code)
+	}
+	w = apiKeysRequest(router, http.MethodGet, "/posts/1/revisions", "jane", nil, false)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/posts/1/revisions/1/restore") || strings.Contains(w.Body.String(), "/posts/1/revisions/4/restore") {
+		t.Errorf("Expected restore forms on all but the current revision, got %d", w.Code)
+	}
+	w = apiKeysRequest(router, http.MethodGet, "/posts/1/revisions/2", "jane", nil, false)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Changes since revision 1.") || !strings.Contains(w.Body.String(), "+ Second line") {
+		t.Errorf("Expected the changes since the first revision, got %d", w.Code)
+	}
+	if w := apiKeysRequest(router, http.MethodGet, "/posts/1/revisions/9", "jane", nil, false); w.Code != http.StatusNotFound {
+		t.Errorf("Expected unknown revisions to be not found, got %d", w.Code)
+	}
+
+	w = apiKeysRequest(router, http.MethodPost, "/posts/1/revisions/1/restore", "jane", nil, false)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Revision 1 restored.") || !strings.Contains(w.Body.String(), "#5") {
+		t.Errorf("Expected the restore to be saved as a new revision, got %d", w.Code)
+	}
+}
+
+func TestPostsHandler_ShowMarkdown(t *testing.T) {
+	router := setupWorkflowRouter(t)
+
+	w := apiKeysRequest(router, http.MethodGet, "/posts/1", "jane", nil, false)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `<div class="markdown mt-4 text-gray-700"><p>First line`) {
+		t.Errorf("Expected the rendered content, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "Table of contents") {
+		t.Error("Expected no table of contents without headings")
+	}
+}
+
+func TestPostsHandler_OldSlug(t *testing.T) {
+	router := setupWorkflowRouter(t)
+
+	w := apiKeysRequest(router, http.MethodGet, "/posts/draft/revisions", "jane", nil, false)
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/posts/first-draft/revisions" {
+		t.Errorf("Expected a redirect to the current slug, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	w = apiKeysRequest(router, http.MethodPost, "/posts/draft/status", "jane", url.Values{"status": {"in_review"}}, true)
+	if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != "/posts/first-draft/status" {
+		t.Errorf("Expected forms to be redirected with 308, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := apiKeysRequest(router, http.MethodGet, "/posts/draft", "bob", nil, false); w.Code != http.StatusNotFound {
+		t.Errorf("Expected old slugs of hidden posts not to redirect, got %d", w.Code)
+	}
+}
@@ -0,0 +1,67 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/internal/auth"
+	"goapp/internal/container"
+	"goapp/internal/query"
+	"goapp/internal/search"
+	"goapp/web/templates/pages"
+)
+
+// SearchHandler renders the search page
+type SearchHandler struct {
+	container *container.Container
+}
+
+// NewSearchHandler creates a new search handler
+func NewSearchHandler(c *container.Container) *SearchHandler {
+	return &SearchHandler{container: c}
+}
+
+// Index searches published posts and comments, rendering only the results
+// for HTMX requests made while typing
+func (h *SearchHandler) Index(c *gin.Context) {
+	page := pages.SearchPage{
+		Query:    c.Query("q"),
+		Kind:     c.Query("type"),
+		Tag:      c.Query("tag"),
+		Author:   c.Query("author"),
+		Page:     1,
+		PageSize: search.DefaultLimit,
+	}
+	if n, err := strconv.Atoi(c.Query("page")); err == nil && n > 1 && n <= query.MaxPage {
+		page.Page = n
+	}
+
+	result, err := h.container.Search.Search(c.Request.Context(), search.Query{
+		Text:   page.Query,
+		Kind:   page.Kind,
+		Tag:    page.Tag,
+		Author: page.Author,
+		Limit:  page.PageSize,
+		Offset: (page.Page - 1) * page.PageSize,
+	})
+	var validationErr *auth.ValidationError
+	if errors.As(err, &validationErr) {
+		c.Redirect(http.StatusSeeOther, "/search")
+		return
+	}
+	if err != nil {
+		h.container.Logger.Error("Failed to search", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to search")
+		return
+	}
+	page.Results, page.Total = result.Results, result.Total
+
+	if c.GetHeader("HX-Request") == "true" {
+		NewAuthHandler(h.container).render(c, http.StatusOK, pages.SearchResults(page))
+		return
+	}
+	NewAuthHandler(h.container).render(c, http.StatusOK, pages.Search(page))
+}
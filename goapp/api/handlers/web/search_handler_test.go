@@ -0,0 +1,71 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/models"
+	"goapp/internal/search"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupSearchRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Post{}, &models.Comment{}, &models.Tag{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	if err := search.Migrate(db); err != nil {
+		t.Fatalf("Failed to create search index: %v", err)
+	}
+	user := &models.User{Email: "jane@example.com", Username: "jane", PasswordHash: "hash", Active: true}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	post := &models.Post{Title: "Goroutines", Slug: "goroutines", Content: "Start <b>goroutines</b> with go", Published: true, UserID: user.ID}
+	if err := db.Create(post).Error; err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	comment := &models.Comment{Content: "Goroutines are cheap", UserID: user.ID, PostID: post.ID}
+	if err := db.Create(comment).Error; err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+
+	container := setupTestContainer(t)
+	container.Search = search.NewService(db)
+	router := gin.New()
+	router.GET("/search", NewSearchHandler(container).Index)
+	return router
+}
+
+func TestSearchHandler(t *testing.T) {
+	router := setupSearchRouter(t)
+
+	w := apiKeysRequest(router, http.MethodGet, "/search?q=gorout", "", nil, false)
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(body, `hx-get="/search"`) || !strings.Contains(body, "2 results") {
+		t.Fatalf("Expected the search page with 2 results, got %d", w.Code)
+	}
+	if !strings.Contains(body, `href="/posts/goroutines#comment-1"`) || !strings.Contains(body, "<mark>") || strings.Contains(body, "<b>") {
+		t.Error("Expected links to the results with escaped, highlighted snippets")
+	}
+
+	w = apiKeysRequest(router, http.MethodGet, "/search?q=cheap&type=comment", "", nil, true)
+	body = w.Body.String()
+	if !strings.HasPrefix(body, `<div id="search-results"`) || !strings.Contains(body, "1 results") {
+		t.Errorf("Expected the results partial, got %q", body)
+	}
+	if w := apiKeysRequest(router, http.MethodGet, "/search?q=go&type=user", "", nil, false); w.Code != http.StatusSeeOther {
+		t.Errorf("Expected unknown types to redirect, got %d", w.Code)
+	}
+	if w := apiKeysRequest(router, http.MethodGet, "/search", "", nil, false); w.Code != http.StatusOK || strings.Contains(w.Body.String(), " results</p>") {
+		t.Errorf("Expected an empty search form, got %d", w.Code)
+	}
+}
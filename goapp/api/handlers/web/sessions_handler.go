@@ -0,0 +1,105 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/container"
+	"goapp/web/templates/pages"
+)
+
+// loginHistoryLimit is how many sign-in attempts the sessions page shows
+const loginHistoryLimit = 20
+
+// SessionsHandler lists the user's sessions and sign-in attempts and signs out other browsers
+type SessionsHandler struct {
+	container *container.Container
+}
+
+// NewSessionsHandler creates a new sessions handler
+func NewSessionsHandler(c *container.Container) *SessionsHandler {
+	return &SessionsHandler{container: c}
+}
+
+// Index lists the active sessions and recent sign-in attempts
+func (h *SessionsHandler) Index(c *gin.Context) {
+	h.renderPage(c, "")
+}
+
+// Revoke signs out one of the user's other sessions. HTMX requests get an
+// empty body, removing the table row.
+func (h *SessionsHandler) Revoke(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusNotFound, "404 page not found")
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	err = h.container.Sessions.Revoke(c.Request.Context(), user.ID, uint(id))
+	if errors.Is(err, auth.ErrSessionNotFound) {
+		c.String(http.StatusNotFound, "404 page not found")
+		return
+	}
+	if err != nil {
+		h.container.Logger.Error("Failed to revoke session", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to sign out session")
+		return
+	}
+	h.container.Logger.Info("Session revoked", zap.Uint("user_id", user.ID), zap.Uint64("session_id", id))
+
+	if c.GetHeader("HX-Request") != "true" {
+		c.Redirect(http.StatusSeeOther, "/settings/sessions")
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// RevokeOthers signs out every session except the current one
+func (h *SessionsHandler) RevokeOthers(c *gin.Context) {
+	var keep uint
+	if session := middleware.CurrentSession(c); session != nil {
+		keep = session.ID
+	}
+
+	user := middleware.CurrentUser(c)
+	revoked, err := h.container.Sessions.RevokeOthers(c.Request.Context(), user.ID, keep)
+	if err != nil {
+		h.container.Logger.Error("Failed to revoke sessions", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to sign out sessions")
+		return
+	}
+	h.container.Logger.Info("Other sessions revoked", zap.Uint("user_id", user.ID), zap.Int64("count", revoked))
+	h.renderPage(c, fmt.Sprintf("Signed out %d other sessions.", revoked))
+}
+
+func (h *SessionsHandler) renderPage(c *gin.Context, notice string) {
+	ctx := c.Request.Context()
+	user := middleware.CurrentUser(c)
+	page := pages.SessionsPage{Notice: notice}
+	if session := middleware.CurrentSession(c); session != nil {
+		page.Current = session.ID
+	}
+
+	var err error
+	if page.Sessions, err = h.container.Sessions.List(ctx, user.ID); err != nil {
+		h.container.Logger.Error("Failed to list sessions", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to list sessions")
+		return
+	}
+	if h.container.LoginHistory != nil {
+		if page.History, err = h.container.LoginHistory.Recent(ctx, user.ID, loginHistoryLimit); err != nil {
+			h.container.Logger.Error("Failed to load login history", zap.Error(err))
+			c.String(http.StatusInternalServerError, "Failed to list sessions")
+			return
+		}
+	}
+
+	NewAuthHandler(h.container).render(c, http.StatusOK, pages.Sessions(page))
+}
@@ -0,0 +1,112 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/config"
+	"goapp/internal/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupSessionsRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Permission{}, &models.Role{}, &models.User{}, &models.Session{}, &models.LoginEvent{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	container := setupTestContainer(t)
+	container.Config.Auth = config.AuthConfig{
+		PasswordHasher:      auth.HasherBcrypt,
+		BcryptCost:          bcrypt.MinCost,
+		MinPasswordLength:   8,
+		RegistrationEnabled: true,
+		SessionCookieName:   "session",
+		SessionTTL:          time.Hour,
+	}
+	hasher, _ := auth.NewHasher(container.Config.Auth)
+	container.Auth = auth.NewService(db, hasher, container.Config.Auth)
+	container.Sessions = auth.NewSessionStore(db, time.Hour)
+	container.LoginHistory = auth.NewLoginHistory(db)
+
+	authHandler := NewAuthHandler(container)
+	handler := NewSessionsHandler(container)
+
+	router := gin.New()
+	router.Use(middleware.Session(container.Sessions, "session", false, container.Logger))
+	router.POST("/register", authHandler.Register)
+	router.POST("/login", authHandler.Login)
+	sessions := router.Group("/settings/sessions", middleware.RequireUser("/login"))
+	sessions.GET("", handler.Index)
+	sessions.POST("/:id/revoke", handler.Revoke)
+	sessions.POST("/revoke-others", handler.RevokeOthers)
+	return router, db
+}
+
+func sessionsPage(router *gin.Engine, cookie *http.Cookie) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/settings/sessions", nil)
+	req.AddCookie(cookie)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSessionsHandler(t *testing.T) {
+	router, db := setupSessionsRouter(t)
+
+	postForm(router, "/register", url.Values{"email": {"jane@example.com"}, "username": {"jane"}, "password": {"s3cret-password"}}, nil)
+	postForm(router, "/login", url.Values{"identifier": {"jane"}, "password": {"wrong-password"}}, nil)
+	login := func() *http.Cookie {
+		return sessionCookie(postForm(router, "/login", url.Values{"identifier": {"jane"}, "password": {"s3cret-password"}}, nil))
+	}
+	current, other, third := login(), login(), login()
+
+	w := sessionsPage(router, current)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	body := w.Body.String()
+	for _, expected := range []string{"This browser", "Sign out all other sessions", "Failed", "Signed in"} {
+		if !contains(body, expected) {
+			t.Errorf("Expected response to contain '%s'", expected)
+		}
+	}
+
+	var otherSession models.Session
+	db.Order("id").Offset(2).First(&otherSession)
+	w = postForm(router, "/settings/sessions/"+strconv.FormatUint(uint64(otherSession.ID), 10)+"/revoke", nil, current)
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("Expected redirect after revoking, got %d", w.Code)
+	}
+	if w := sessionsPage(router, other); w.Code != http.StatusSeeOther {
+		t.Errorf("Expected revoked session to be signed out, got %d", w.Code)
+	}
+	if w := postForm(router, "/settings/sessions/999/revoke", nil, current); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown session, got %d", http.StatusNotFound, w.Code)
+	}
+
+	w = postForm(router, "/settings/sessions/revoke-others", nil, current)
+	if w.Code != http.StatusOK || !contains(w.Body.String(), "Signed out 2 other sessions.") {
+		t.Errorf("Expected other sessions to be signed out, got %d", w.Code)
+	}
+	if w := sessionsPage(router, third); w.Code != http.StatusSeeOther {
+		t.Errorf("Expected other session to be signed out, got %d", w.Code)
+	}
+	if w := sessionsPage(router, current); w.Code != http.StatusOK {
+		t.Errorf("Expected current session to stay signed in, got %d", w.Code)
+	}
+}
@@ -0,0 +1,94 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/container"
+	"goapp/internal/models"
+	"goapp/internal/users"
+	"goapp/web/templates/pages"
+)
+
+// SettingsHandler lets users edit their own profile
+type SettingsHandler struct {
+	container *container.Container
+}
+
+// NewSettingsHandler creates a new settings handler
+func NewSettingsHandler(c *container.Container) *SettingsHandler {
+	return &SettingsHandler{container: c}
+}
+
+// Index shows the profile form and links to the security settings
+func (h *SettingsHandler) Index(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	h.render(c, http.StatusOK, user, profileForm(user), "")
+}
+
+// Update saves the profile form. Changing the email address needs the
+// current password and sends a confirmation link to the new address.
+func (h *SettingsHandler) Update(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	form := pages.ProfileForm{
+		Email:     c.PostForm("email"),
+		Username:  c.PostForm("username"),
+		FirstName: c.PostForm("first_name"),
+		LastName:  c.PostForm("last_name"),
+		Errors:    map[string]string{},
+	}
+
+	err := h.container.Users.UpdateProfile(c.Request.Context(), user, users.ProfileInput{
+		Email:           form.Email,
+		Username:        form.Username,
+		FirstName:       form.FirstName,
+		LastName:        form.LastName,
+		CurrentPassword: c.PostForm("current_password"),
+	})
+	var validationErr *auth.ValidationError
+	if errors.As(err, &validationErr) {
+		form.Errors = validationErr.Fields
+		h.render(c, http.StatusUnprocessableEntity, user, form, "")
+		return
+	}
+	if err != nil {
+		h.container.Logger.Error("Failed to update profile", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to update profile")
+		return
+	}
+	h.container.Logger.Info("Profile updated", zap.Uint("user_id", user.ID))
+
+	notice := "Profile saved."
+	if user.PendingEmail != "" && user.PendingEmail == auth.NormalizeEmail(form.Email) && h.container.Accounts != nil {
+		if err := NewAccountHandler(h.container).SendEmailChange(c.Request.Context(), user); err != nil {
+			h.container.Logger.Error("Failed to send email change confirmation", zap.Error(err))
+			notice = "Profile saved, but the confirmation email could not be sent."
+		} else {
+			notice = "Profile saved. We have sent a confirmation link to " + user.PendingEmail + "."
+		}
+	}
+	h.render(c, http.StatusOK, user, profileForm(user), notice)
+}
+
+func (h *SettingsHandler) render(c *gin.Context, status int, user *models.User, form pages.ProfileForm, notice string) {
+	NewAuthHandler(h.container).render(c, status, pages.Settings(pages.SettingsPage{
+		Form:          form,
+		EmailVerified: user.EmailVerified,
+		Notice:        notice,
+		PendingEmail:  user.PendingEmail,
+	}))
+}
+
+// profileForm fills the profile form with the user's current details
+func profileForm(user *models.User) pages.ProfileForm {
+	return pages.ProfileForm{
+		Email:     user.Email,
+		Username:  user.Username,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+	}
+}
@@ -0,0 +1,39 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"goapp/internal/models"
+)
+
+func TestSettingsHandler(t *testing.T) {
+	router, db := setupUsersRouter(t)
+
+	if w := apiKeysRequest(router, http.MethodGet, "/settings", "", nil, false); w.Code != http.StatusSeeOther {
+		t.Errorf("Expected anonymous users to be redirected, got %d", w.Code)
+	}
+	w := apiKeysRequest(router, http.MethodGet, "/settings", "jane", nil, false)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `value="jane@example.com"`) {
+		t.Fatalf("Expected the profile form, got %d", w.Code)
+	}
+
+	form := url.Values{"email": {"jane@example.com"}, "username": {"bob"}, "first_name": {"Jane"}}
+	w = apiKeysRequest(router, http.MethodPost, "/settings", "jane", form, false)
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "Username is already taken") {
+		t.Errorf("Expected a taken username to be refused, got %d", w.Code)
+	}
+
+	form.Set("username", "jane")
+	w = apiKeysRequest(router, http.MethodPost, "/settings", "jane", form, false)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Profile saved.") {
+		t.Errorf("Expected the profile to be saved, got %d", w.Code)
+	}
+	var jane models.User
+	db.First(&jane, 2)
+	if jane.FirstName != "Jane" {
+		t.Errorf("Expected first name Jane, got %q", jane.FirstName)
+	}
+}
@@ -0,0 +1,160 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/authz"
+	"goapp/internal/container"
+	"goapp/internal/models"
+	"goapp/internal/posts"
+	"goapp/internal/tags"
+	"goapp/web/templates/pages"
+	"goapp/web/templates/partials"
+)
+
+// tagSuggestionLimit is how many tags the autocomplete offers
+const tagSuggestionLimit = 10
+
+// TagsHandler renders tag pages and manages the tags of posts
+type TagsHandler struct {
+	container *container.Container
+}
+
+// NewTagsHandler creates a new tags handler
+func NewTagsHandler(c *container.Container) *TagsHandler {
+	return &TagsHandler{container: c}
+}
+
+// Index lists every tag with its post count
+func (h *TagsHandler) Index(c *gin.Context) {
+	counts, err := h.container.Tags.List(c.Request.Context())
+	if err != nil {
+		h.container.Logger.Error("Failed to list tags", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to list tags")
+		return
+	}
+	NewAuthHandler(h.container).render(c, http.StatusOK, pages.TagsIndex(counts))
+}
+
+// Show lists a page of the posts carrying a tag, newest first
+func (h *TagsHandler) Show(c *gin.Context) {
+	tag, ok := h.find(c)
+	if !ok {
+		return
+	}
+
+	opts := posts.ListOptions{
+		Tag:           tag.Slug,
+		IncludeDrafts: middleware.Can(c, authz.ActionRead, authz.Type(authz.ResourcePosts)),
+		Cursor:        c.Query("cursor"),
+	}
+	if user := middleware.CurrentUser(c); user != nil {
+		opts.ViewerID = user.ID
+	}
+	page, err := h.container.Posts.List(c.Request.Context(), opts)
+	if errors.Is(err, posts.ErrInvalidCursor) {
+		c.Redirect(http.StatusSeeOther, "/tags/"+tag.Slug)
+		return
+	}
+	if err != nil {
+		h.container.Logger.Error("Failed to fetch posts", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to fetch posts")
+		return
+	}
+	NewAuthHandler(h.container).render(c, http.StatusOK, pages.TagShow(*tag, page.Posts, page.NextCursor))
+}
+
+// Autocomplete renders datalist options for the tags starting with the tag query parameter.
+// Only the text after the last comma is completed.
+func (h *TagsHandler) Autocomplete(c *gin.Context) {
+	query := c.Query("tag")
+	if i := strings.LastIndex(query, ","); i >= 0 {
+		query = query[i+1:]
+	}
+
+	suggestions, err := h.container.Tags.Suggest(c.Request.Context(), query, tagSuggestionLimit)
+	if err != nil {
+		h.container.Logger.Error("Failed to suggest tags", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to suggest tags")
+		return
+	}
+	NewAuthHandler(h.container).render(c, http.StatusOK, partials.TagSuggestions(suggestions))
+}
+
+// Attach adds the comma separated tags of the form to a post
+func (h *TagsHandler) Attach(c *gin.Context) {
+	post, ok := NewPostsHandler(h.container).find(c)
+	if !ok || !middleware.Authorize(c, authz.ActionUpdate, post) {
+		return
+	}
+
+	tagged, err := h.container.Tags.Attach(c.Request.Context(), post, strings.Split(c.PostForm("tag"), ","))
+	var validationErr *auth.ValidationError
+	if errors.As(err, &validationErr) {
+		NewAuthHandler(h.container).render(c, http.StatusUnprocessableEntity, partials.PostTags(*post, "Tag name "+validationErr.Fields["name"]+"."))
+		return
+	}
+	if err != nil {
+		h.container.Logger.Error("Failed to tag post", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to tag post")
+		return
+	}
+	post.Tags = tagged
+	h.done(c, post)
+}
+
+// Detach removes a tag from a post
+func (h *TagsHandler) Detach(c *gin.Context) {
+	post, ok := NewPostsHandler(h.container).find(c)
+	if !ok || !middleware.Authorize(c, authz.ActionUpdate, post) {
+		return
+	}
+	tag, ok := h.find(c)
+	if !ok {
+		return
+	}
+
+	var err error
+	if post.Tags, err = h.container.Tags.Detach(c.Request.Context(), post, tag); err != nil {
+		h.container.Logger.Error("Failed to untag post", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to untag post")
+		return
+	}
+	h.done(c, post)
+}
+
+// find loads the tag named by the slug path parameter, responding 404 when it does not exist
+func (h *TagsHandler) find(c *gin.Context) (*models.Tag, bool) {
+	tag, err := h.container.Tags.GetBySlug(c.Request.Context(), c.Param("slug"))
+	if errors.Is(err, tags.ErrNotFound) {
+		// Tags renamed or merged away from slug redirect to their new one
+		if moved, movedErr := h.container.Tags.GetByOldSlug(c.Request.Context(), c.Param("slug")); movedErr == nil {
+			middleware.RedirectParam(c, "slug", moved.Slug)
+			return nil, false
+		}
+		c.String(http.StatusNotFound, "404 page not found")
+		return nil, false
+	}
+	if err != nil {
+		h.container.Logger.Error("Failed to load tag", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to load tag")
+		return nil, false
+	}
+	return tag, true
+}
+
+// done answers HTMX requests with the post's tags and others with a redirect to the post
+func (h *TagsHandler) done(c *gin.Context, post *models.Post) {
+	if c.GetHeader("HX-Request") != "true" {
+		c.Redirect(http.StatusSeeOther, fmt.Sprintf("/posts/%d", post.ID))
+		return
+	}
+	NewAuthHandler(h.container).render(c, http.StatusOK, partials.PostTags(*post, ""))
+}
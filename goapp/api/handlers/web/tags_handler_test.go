@@ -0,0 +1,131 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goapp/api/middleware"
+	"goapp/internal/authz"
+	"goapp/internal/container"
+	"goapp/internal/models"
+	"goapp/internal/posts"
+	"goapp/internal/tags"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTagsRouter signs requests in as the user named in the X-User header.
+// Jane has written the published post "hello" and a draft.
+func setupTagsRouter(t *testing.T) (*gin.Engine, *container.Container) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Post{}, &models.PostRevision{}, &models.Tag{}, &models.SlugRedirect{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	for _, name := range []string{"jane", "bob"} {
+		user := &models.User{Email: name + "@example.com", Username: name, PasswordHash: "hash", Active: true}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	for _, post := range []models.Post{
+		{Title: "Hello", Slug: "hello", Published: true, UserID: 1},
+		{Title: "Draft", Slug: "draft", UserID: 1},
+	} {
+		if err := db.Create(&post).Error; err != nil {
+			t.Fatalf("Failed to create post: %v", err)
+		}
+	}
+
+	container := setupTestContainer(t)
+	container.Posts = posts.NewService(db)
+	container.Tags = tags.NewService(db)
+	handler := NewTagsHandler(container)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		var user models.User
+		if err := db.Where("username = ?", c.GetHeader("X-User")).First(&user).Error; err == nil {
+			middleware.SetCurrentUser(c, &user)
+		}
+		c.Next()
+	})
+	router.Use(middleware.Policies(authz.DefaultPolicy()))
+	router.GET("/posts/:id", NewPostsHandler(container).Show)
+	router.GET("/tags", handler.Index)
+	router.GET("/tags/:slug", handler.Show)
+	router.POST("/posts/:id/tags", handler.Attach)
+	router.POST("/posts/:id/tags/:slug/detach", handler.Detach)
+	router.GET("/partials/tags/autocomplete", handler.Autocomplete)
+	return router, container
+}
+
+func TestTagsHandler(t *testing.T) {
+	router, container := setupTagsRouter(t)
+
+	if w := apiKeysRequest(router, http.MethodPost, "/posts/1/tags", "bob", url.Values{"tag": {"Go"}}, true); w.Code != http.StatusForbidden {
+		t.Errorf("Expected users not to tag others' posts, got %d", w.Code)
+	}
+	w := apiKeysRequest(router, http.MethodPost, "/posts/1/tags", "jane", url.Values{"tag": {"Go, Web Dev"}}, true)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `href="/tags/web-dev"`) {
+		t.Fatalf("Expected the post's tags, got %d", w.Code)
+	}
+	w = apiKeysRequest(router, http.MethodPost, "/posts/2/tags", "jane", url.Values{"tag": {"Go"}}, false)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/posts/2" {
+		t.Errorf("Expected a redirect to the post, got %d", w.Code)
+	}
+	w = apiKeysRequest(router, http.MethodPost, "/posts/1/tags", "jane", url.Values{"tag": {"???"}}, true)
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "must contain a letter or digit") {
+		t.Errorf("Expected a validation error, got %d", w.Code)
+	}
+
+	w = apiKeysRequest(router, http.MethodGet, "/posts/hello", "", nil, false)
+	if !strings.Contains(w.Body.String(), `href="/tags/go"`) || strings.Contains(w.Body.String(), "Add tags") {
+		t.Error("Expected anonymous users to see tags without the tag form")
+	}
+	if w := apiKeysRequest(router, http.MethodGet, "/posts/hello", "jane", nil, false); !strings.Contains(w.Body.String(), "Add tags") {
+		t.Error("Expected the author to see the tag form")
+	}
+
+	w = apiKeysRequest(router, http.MethodGet, "/tags", "", nil, false)
+	if !strings.Contains(w.Body.String(), "Web Dev") {
+		t.Error("Expected tags to be listed")
+	}
+	w = apiKeysRequest(router, http.MethodGet, "/tags/go", "", nil, false)
+	if !strings.Contains(w.Body.String(), "Hello") || strings.Contains(w.Body.String(), "/posts/2") {
+		t.Error("Expected the tag page to list published posts only")
+	}
+	if w := apiKeysRequest(router, http.MethodGet, "/tags/missing", "", nil, false); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	w = apiKeysRequest(router, http.MethodGet, "/partials/tags/autocomplete?tag="+url.QueryEscape("rust, we"), "jane", nil, true)
+	if strings.TrimSpace(w.Body.String()) != `<option value="Web Dev"></option>` {
+		t.Errorf("Expected Web Dev to be suggested, got %q", w.Body.String())
+	}
+
+	webDev, err := container.Tags.GetBySlug(context.Background(), "web-dev")
+	if err != nil {
+		t.Fatalf("GetBySlug() error = %v", err)
+	}
+	if _, err := container.Tags.Rename(context.Background(), webDev, "Web Development"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	w = apiKeysRequest(router, http.MethodGet, "/tags/web-dev?page=2", "", nil, false)
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/tags/web-development?page=2" {
+		t.Errorf("Expected a redirect to the renamed tag, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	w = apiKeysRequest(router, http.MethodPost, "/posts/1/tags/go/detach", "jane", nil, true)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), `href="/tags/go"`) {
+		t.Errorf("Expected go to be removed, got %d", w.Code)
+	}
+}
@@ -0,0 +1,237 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/container"
+	"goapp/internal/models"
+	"goapp/internal/twofactor"
+	"goapp/web/templates/pages"
+)
+
+const (
+	// twoFactorCookie carries the login challenge between the password and code steps
+	twoFactorCookie       = "two_factor"
+	twoFactorCookieMaxAge = 5 * time.Minute
+)
+
+// TwoFactorHandler handles the second sign-in step and two-factor settings
+type TwoFactorHandler struct {
+	container *container.Container
+}
+
+// NewTwoFactorHandler creates a new two-factor handler
+func NewTwoFactorHandler(c *container.Container) *TwoFactorHandler {
+	return &TwoFactorHandler{container: c}
+}
+
+// ChallengePage asks for a code after the password was accepted
+func (h *TwoFactorHandler) ChallengePage(c *gin.Context) {
+	next := safeRedirect(c.Query("next"))
+	if _, err := c.Cookie(twoFactorCookie); err != nil {
+		c.Redirect(http.StatusSeeOther, withNext("/login", next))
+		return
+	}
+	h.auth().render(c, http.StatusOK, pages.TwoFactorChallenge(pages.TwoFactorChallengeForm{Next: next}))
+}
+
+// Challenge checks the code and starts the session
+func (h *TwoFactorHandler) Challenge(c *gin.Context) {
+	form := pages.TwoFactorChallengeForm{Next: safeRedirect(c.PostForm("next"))}
+	ctx := c.Request.Context()
+
+	token, _ := c.Cookie(twoFactorCookie)
+	user, err := h.container.Accounts.RedeemLoginChallenge(ctx, token)
+	if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrInactiveUser) {
+		h.clearChallenge(c)
+		c.Redirect(http.StatusSeeOther, withNext("/login", form.Next))
+		return
+	}
+	if err != nil {
+		h.container.Logger.Error("Failed to load login challenge", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to sign in")
+		return
+	}
+
+	err = h.container.TwoFactor.Verify(ctx, user.ID, c.PostForm("code"))
+	switch {
+	case errors.Is(err, twofactor.ErrInvalidCode):
+		h.container.Logger.Info("Failed two-factor attempt", zap.Uint("user_id", user.ID))
+		h.auth().recordLogin(c, user, models.LoginMethodTwoFactor, models.LoginFailed)
+		form.Error = "That code is not valid."
+		h.auth().render(c, http.StatusUnauthorized, pages.TwoFactorChallenge(form))
+		return
+	case errors.Is(err, twofactor.ErrTooManyAttempts):
+		h.container.Logger.Warn("Two-factor verification locked", zap.Uint("user_id", user.ID))
+		h.auth().recordLogin(c, user, models.LoginMethodTwoFactor, models.LoginLocked)
+		form.Error = "Too many failed attempts. Please wait a few minutes and try again."
+		h.auth().render(c, http.StatusTooManyRequests, pages.TwoFactorChallenge(form))
+		return
+	case err != nil:
+		h.container.Logger.Error("Failed to verify two-factor code", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to sign in")
+		return
+	}
+
+	h.clearChallenge(c)
+	h.auth().recordLogin(c, user, models.LoginMethodTwoFactor, models.LoginSucceeded)
+	h.auth().createSession(c, user, form.Next)
+}
+
+// Settings shows whether two-factor authentication is on
+func (h *TwoFactorHandler) Settings(c *gin.Context) {
+	page, err := h.page(c)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	if !page.Enabled {
+		// Resume an enrollment that was started but not confirmed
+		if pending, err := h.container.TwoFactor.Pending(c.Request.Context(), middleware.CurrentUser(c)); err == nil {
+			page.Secret, page.URI = pending.Secret, pending.URI
+		}
+	}
+	h.render(c, http.StatusOK, page)
+}
+
+// Enroll creates a new secret and shows it as a QR code
+func (h *TwoFactorHandler) Enroll(c *gin.Context) {
+	page, err := h.page(c)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	enrollment, err := h.container.TwoFactor.Begin(c.Request.Context(), middleware.CurrentUser(c))
+	if err != nil && !errors.Is(err, twofactor.ErrAlreadyEnrolled) {
+		h.fail(c, err)
+		return
+	}
+	if enrollment != nil {
+		page.Secret, page.URI = enrollment.Secret, enrollment.URI
+	}
+	h.render(c, http.StatusOK, page)
+}
+
+// Confirm turns two-factor authentication on once the app's code matches
+func (h *TwoFactorHandler) Confirm(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	codes, err := h.container.TwoFactor.Confirm(c.Request.Context(), user.ID, c.PostForm("code"))
+	if errors.Is(err, twofactor.ErrInvalidCode) || errors.Is(err, twofactor.ErrTooManyAttempts) {
+		page, pageErr := h.page(c)
+		if pageErr != nil {
+			h.fail(c, pageErr)
+			return
+		}
+		if pending, err := h.container.TwoFactor.Pending(c.Request.Context(), user); err == nil {
+			page.Secret, page.URI = pending.Secret, pending.URI
+		}
+		page.Error = "That code is not valid. Check the time on your phone and try again."
+		if errors.Is(err, twofactor.ErrTooManyAttempts) {
+			page.Error = "Too many failed attempts. Please wait a few minutes and try again."
+		}
+		h.render(c, http.StatusUnprocessableEntity, page)
+		return
+	}
+	if err != nil && !errors.Is(err, twofactor.ErrAlreadyEnrolled) {
+		h.fail(c, err)
+		return
+	}
+
+	h.container.Logger.Info("Two-factor authentication enabled", zap.Uint("user_id", user.ID))
+	user.TwoFactorEnabled = true
+	page, err := h.page(c)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	page.RecoveryCodes = codes
+	h.render(c, http.StatusOK, page)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes and shows the new ones
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	codes, err := h.container.TwoFactor.RegenerateRecoveryCodes(c.Request.Context(), user.ID)
+	if err != nil && !errors.Is(err, twofactor.ErrNotEnrolled) {
+		h.fail(c, err)
+		return
+	}
+	page, err := h.page(c)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	page.RecoveryCodes = codes
+	h.render(c, http.StatusOK, page)
+}
+
+// Disable turns two-factor authentication off after checking a current code
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	page, err := h.page(c)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	if page.Required {
+		page.Error = "Your role requires two-factor authentication."
+		h.render(c, http.StatusForbidden, page)
+		return
+	}
+
+	err = h.container.TwoFactor.Verify(c.Request.Context(), user.ID, c.PostForm("code"))
+	if errors.Is(err, twofactor.ErrInvalidCode) || errors.Is(err, twofactor.ErrTooManyAttempts) {
+		page.Error = "That code is not valid."
+		h.render(c, http.StatusUnprocessableEntity, page)
+		return
+	}
+	if err == nil {
+		err = h.container.TwoFactor.Disable(c.Request.Context(), user.ID)
+	}
+	if err != nil && !errors.Is(err, twofactor.ErrNotEnrolled) {
+		h.fail(c, err)
+		return
+	}
+
+	h.container.Logger.Info("Two-factor authentication disabled", zap.Uint("user_id", user.ID))
+	c.Redirect(http.StatusSeeOther, "/settings/two-factor")
+}
+
+// page loads the current user's two-factor status
+func (h *TwoFactorHandler) page(c *gin.Context) (pages.TwoFactorPage, error) {
+	user := middleware.CurrentUser(c)
+	page := pages.TwoFactorPage{Enabled: user.TwoFactorEnabled, Required: h.container.TwoFactor.Required(user)}
+	if page.Enabled {
+		remaining, err := h.container.TwoFactor.RemainingRecoveryCodes(c.Request.Context(), user.ID)
+		if err != nil {
+			return page, err
+		}
+		page.Remaining = remaining
+	}
+	return page, nil
+}
+
+func (h *TwoFactorHandler) render(c *gin.Context, status int, page pages.TwoFactorPage) {
+	// Secrets and recovery codes must not be cached
+	c.Header("Cache-Control", "no-store")
+	h.auth().render(c, status, pages.TwoFactor(page))
+}
+
+func (h *TwoFactorHandler) fail(c *gin.Context, err error) {
+	h.container.Logger.Error("Two-factor settings failed", zap.Error(err))
+	c.String(http.StatusInternalServerError, "Failed to update two-factor authentication")
+}
+
+func (h *TwoFactorHandler) clearChallenge(c *gin.Context) {
+	http.SetCookie(c.Writer, &http.Cookie{Name: twoFactorCookie, Path: "/login/2fa", MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteLaxMode})
+}
+
+func (h *TwoFactorHandler) auth() *AuthHandler {
+	return NewAuthHandler(h.container)
+}
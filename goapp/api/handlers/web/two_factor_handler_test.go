@@ -0,0 +1,152 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/config"
+	"goapp/internal/models"
+	"goapp/internal/twofactor"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTwoFactorRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Permission{}, &models.Role{}, &models.User{}, &models.Session{}, &models.TwoFactor{}, &models.RecoveryCode{}, &models.LoginEvent{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	container := setupTestContainer(t)
+	container.Config.Auth = config.AuthConfig{
+		PasswordHasher:    auth.HasherBcrypt,
+		BcryptCost:        bcrypt.MinCost,
+		MinPasswordLength: 8,
+		SessionCookieName: "session",
+		SessionTTL:        time.Hour,
+		TokenSecret:       "test-secret",
+	}
+	hasher, _ := auth.NewHasher(container.Config.Auth)
+	container.Auth = auth.NewService(db, hasher, container.Config.Auth)
+	container.Sessions = auth.NewSessionStore(db, time.Hour)
+	container.Accounts, _ = auth.NewAccountService(db, hasher, container.Config.Auth)
+	container.TwoFactor = twofactor.NewService(db, config.TwoFactorConfig{Issuer: "goapp", RequiredRoles: []string{"admin"}, MaxAttempts: 5, LockDuration: time.Minute, RecoveryCodes: 2})
+	if _, err := container.Auth.Register(context.Background(), auth.RegisterInput{Email: "jane@example.com", Username: "jane", Password: "s3cret-password"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	authHandler := NewAuthHandler(container)
+	handler := NewTwoFactorHandler(container)
+
+	router := gin.New()
+	router.Use(middleware.Session(container.Sessions, "session", false, container.Logger))
+	router.Use(middleware.RequireTwoFactorEnrollment(container.TwoFactor.Required, "/settings/two-factor", "/logout"))
+	router.POST("/login", authHandler.Login)
+	router.GET("/login/2fa", handler.ChallengePage)
+	router.POST("/login/2fa", handler.Challenge)
+	router.GET("/posts", func(c *gin.Context) { c.String(http.StatusOK, "posts") })
+	settings := router.Group("/settings/two-factor", middleware.RequireUser("/login"))
+	settings.GET("", handler.Settings)
+	settings.POST("/enroll", handler.Enroll)
+	settings.POST("/confirm", handler.Confirm)
+	settings.POST("/disable", handler.Disable)
+	return router, db
+}
+
+func cookieNamed(w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func getWithCookie(router *gin.Engine, path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestTwoFactorHandler_EnrollAndSignIn(t *testing.T) {
+	router, db := setupTwoFactorRouter(t)
+	login := url.Values{"identifier": {"jane"}, "password": {"s3cret-password"}, "next": {"/posts"}}
+	session := sessionCookie(postForm(router, "/login", login, nil))
+
+	w := postForm(router, "/settings/two-factor/enroll", nil, session)
+	if w.Code != http.StatusOK || !contains(w.Body.String(), "otpauth://totp/goapp:jane@example.com") {
+		t.Fatalf("Expected QR provisioning URI, got %d", w.Code)
+	}
+	var record models.TwoFactor
+	db.First(&record)
+
+	if w := postForm(router, "/settings/two-factor/confirm", url.Values{"code": {"000000"}}, session); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected wrong code to be rejected, got %d", w.Code)
+	}
+	code, _ := twofactor.Code(record.Secret, time.Now())
+	w = postForm(router, "/settings/two-factor/confirm", url.Values{"code": {code}}, session)
+	if w.Code != http.StatusOK || !contains(w.Body.String(), "Save these recovery codes") {
+		t.Fatalf("Expected recovery codes, got %d", w.Code)
+	}
+
+	// The password alone no longer signs in
+	w = postForm(router, "/login", login, nil)
+	challenge := cookieNamed(w, twoFactorCookie)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login/2fa?next=%2Fposts" || challenge == nil || sessionCookie(w) != nil {
+		t.Fatalf("Expected redirect to the code step without a session, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := getWithCookie(router, "/login/2fa?next=/posts", challenge); w.Code != http.StatusOK || !contains(w.Body.String(), "authenticator app") {
+		t.Errorf("Expected code form, got %d", w.Code)
+	}
+
+	if w := postForm(router, "/login/2fa", url.Values{"code": {"000000"}, "next": {"/posts"}}, challenge); w.Code != http.StatusUnauthorized || sessionCookie(w) != nil {
+		t.Errorf("Expected wrong code to be rejected, got %d", w.Code)
+	}
+
+	// The confirmation code's step is used up, so take the next one
+	code, _ = twofactor.Code(record.Secret, time.Now().Add(30*time.Second))
+	w = postForm(router, "/login/2fa", url.Values{"code": {code}, "next": {"/posts"}}, challenge)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/posts" || sessionCookie(w) == nil {
+		t.Fatalf("Expected sign-in, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	if w := postForm(router, "/login/2fa", url.Values{"code": {code}}, nil); w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login" {
+		t.Errorf("Expected missing challenge to restart sign-in, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestTwoFactorHandler_RequiredForRole(t *testing.T) {
+	router, db := setupTwoFactorRouter(t)
+
+	var user models.User
+	db.First(&user)
+	admin := models.Role{Name: "admin"}
+	db.Create(&admin)
+	db.Model(&user).Association("Roles").Append(&admin)
+
+	session := sessionCookie(postForm(router, "/login", url.Values{"identifier": {"jane"}, "password": {"s3cret-password"}}, nil))
+	w := getWithCookie(router, "/posts", session)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/settings/two-factor" {
+		t.Errorf("Expected redirect to enrollment, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := getWithCookie(router, "/settings/two-factor", session); w.Code != http.StatusOK || !contains(w.Body.String(), "Your role requires two-factor authentication") {
+		t.Errorf("Expected enrollment page, got %d", w.Code)
+	}
+}
@@ -0,0 +1,280 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/authz"
+	"goapp/internal/container"
+	"goapp/internal/models"
+	"goapp/internal/users"
+	"goapp/web/templates/pages"
+)
+
+// UsersHandler renders the admin user list and manages user accounts
+type UsersHandler struct {
+	container *container.Container
+}
+
+// NewUsersHandler creates a new users handler
+func NewUsersHandler(c *container.Container) *UsersHandler {
+	return &UsersHandler{container: c}
+}
+
+// Index lists a page of the users matching the search. HTMX requests get
+// only the results, so the list updates while typing.
+func (h *UsersHandler) Index(c *gin.Context) {
+	if !middleware.Authorize(c, authz.ActionRead, authz.Type(authz.ResourceUsers)) {
+		return
+	}
+
+	page := pages.UsersPage{
+		Query:    c.Query("q"),
+		Status:   c.Query("status"),
+		Role:     c.Query("role"),
+		Page:     1,
+		PageSize: users.DefaultLimit,
+	}
+	if n, err := strconv.Atoi(c.Query("page")); err == nil && n > 1 {
+		page.Page = n
+	}
+
+	ctx := c.Request.Context()
+	result, err := h.container.Users.List(ctx, users.ListOptions{
+		Query:  page.Query,
+		Status: page.Status,
+		Role:   page.Role,
+		Limit:  page.PageSize,
+		Offset: (page.Page - 1) * page.PageSize,
+	})
+	var validationErr *auth.ValidationError
+	if errors.As(err, &validationErr) {
+		c.Redirect(http.StatusSeeOther, "/users")
+		return
+	}
+	if err != nil {
+		h.container.Logger.Error("Failed to list users", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to list users")
+		return
+	}
+	page.Users, page.Total = result.Users, result.Total
+
+	if c.GetHeader("HX-Request") == "true" {
+		NewAuthHandler(h.container).render(c, http.StatusOK, pages.UserResults(page))
+		return
+	}
+	if page.Roles, err = h.container.Roles.List(ctx); err != nil {
+		h.container.Logger.Error("Failed to list roles", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to list users")
+		return
+	}
+	NewAuthHandler(h.container).render(c, http.StatusOK, pages.UsersIndex(page))
+}
+
+// Show renders the admin page of a user, including deleted users
+func (h *UsersHandler) Show(c *gin.Context) {
+	user, ok := h.find(c, authz.ActionRead)
+	if !ok {
+		return
+	}
+
+	detail, ok := h.detail(c, user, "")
+	if !ok {
+		return
+	}
+	if h.container.LoginHistory != nil {
+		var err error
+		if detail.History, err = h.container.LoginHistory.Recent(c.Request.Context(), user.ID, loginHistoryLimit); err != nil {
+			h.container.Logger.Error("Failed to load login history", zap.Error(err))
+			c.String(http.StatusInternalServerError, "Failed to load user")
+			return
+		}
+	}
+	NewAuthHandler(h.container).render(c, http.StatusOK, pages.UserShow(detail))
+}
+
+// Deactivate stops a user from signing in and signs them out everywhere
+func (h *UsersHandler) Deactivate(c *gin.Context) {
+	h.setActive(c, false)
+}
+
+// Reactivate lets a deactivated user sign in again
+func (h *UsersHandler) Reactivate(c *gin.Context) {
+	h.setActive(c, true)
+}
+
+func (h *UsersHandler) setActive(c *gin.Context, active bool) {
+	user, ok := h.find(c, authz.ActionUpdate)
+	if !ok || (!active && h.self(c, user, "You cannot deactivate your own account.")) {
+		return
+	}
+
+	if err := h.container.Users.SetActive(c.Request.Context(), user, active); err != nil {
+		h.container.Logger.Error("Failed to update user", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to update user")
+		return
+	}
+	h.container.Logger.Info("User active flag changed", zap.Uint("target_id", user.ID), zap.Bool("active", active), zap.Uint("user_id", middleware.CurrentUser(c).ID))
+	h.done(c, user)
+}
+
+// Delete soft-deletes a user
+func (h *UsersHandler) Delete(c *gin.Context) {
+	user, ok := h.find(c, authz.ActionDelete)
+	if !ok || h.self(c, user, "You cannot delete your own account.") {
+		return
+	}
+
+	if !user.DeletedAt.Valid {
+		if err := h.container.Users.Delete(c.Request.Context(), user); err != nil {
+			h.container.Logger.Error("Failed to delete user", zap.Error(err))
+			c.String(http.StatusInternalServerError, "Failed to delete user")
+			return
+		}
+		h.container.Logger.Info("User deleted", zap.Uint("target_id", user.ID), zap.Uint("user_id", middleware.CurrentUser(c).ID))
+	}
+	h.done(c, user)
+}
+
+// Restore undeletes a user
+func (h *UsersHandler) Restore(c *gin.Context) {
+	user, ok := h.find(c, authz.ActionDelete)
+	if !ok {
+		return
+	}
+
+	if err := h.container.Users.Restore(c.Request.Context(), user); err != nil {
+		h.container.Logger.Error("Failed to restore user", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to restore user")
+		return
+	}
+	h.container.Logger.Info("User restored", zap.Uint("target_id", user.ID), zap.Uint("user_id", middleware.CurrentUser(c).ID))
+	h.done(c, user)
+}
+
+// GrantRole gives a user the role named in the form
+func (h *UsersHandler) GrantRole(c *gin.Context) {
+	h.changeRole(c, c.PostForm("role"), true)
+}
+
+// RevokeRole takes the role named in the path away from a user
+func (h *UsersHandler) RevokeRole(c *gin.Context) {
+	h.changeRole(c, c.Param("role"), false)
+}
+
+func (h *UsersHandler) changeRole(c *gin.Context, role string, grant bool) {
+	if !middleware.Authorize(c, authz.ActionUpdate, authz.Type(authz.ResourceRoles)) {
+		return
+	}
+	user, ok := h.find(c, authz.ActionUpdate)
+	if !ok {
+		return
+	}
+	if !grant && role == authz.RoleAdmin && h.self(c, user, "You cannot revoke your own admin role.") {
+		return
+	}
+
+	ctx := c.Request.Context()
+	var err error
+	if grant {
+		err = h.container.Roles.Grant(ctx, user.ID, role)
+	} else {
+		err = h.container.Roles.Revoke(ctx, user.ID, role)
+	}
+	switch {
+	case errors.Is(err, authz.ErrRoleNotFound):
+		h.renderAdmin(c, http.StatusUnprocessableEntity, user, "Unknown role.")
+		return
+	case errors.Is(err, authz.ErrUserNotFound):
+		h.renderAdmin(c, http.StatusUnprocessableEntity, user, "Restore the user before changing their roles.")
+		return
+	case err != nil:
+		h.container.Logger.Error("Failed to change roles", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to change roles")
+		return
+	}
+	h.container.Logger.Info("User roles changed", zap.Uint("target_id", user.ID), zap.String("role", role), zap.Bool("granted", grant), zap.Uint("user_id", middleware.CurrentUser(c).ID))
+	h.done(c, user)
+}
+
+// find loads the user named by the id path parameter after checking the
+// signed-in user may perform action on users, responding 404 when there is none
+func (h *UsersHandler) find(c *gin.Context, action authz.Action) (*models.User, bool) {
+	if !middleware.Authorize(c, action, authz.Type(authz.ResourceUsers)) {
+		return nil, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusNotFound, "404 page not found")
+		return nil, false
+	}
+	user, err := h.container.Users.Get(c.Request.Context(), uint(id))
+	if errors.Is(err, users.ErrNotFound) {
+		c.String(http.StatusNotFound, "404 page not found")
+		return nil, false
+	}
+	if err != nil {
+		h.container.Logger.Error("Failed to load user", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to load user")
+		return nil, false
+	}
+	return user, true
+}
+
+// self refuses, with 422, an action the signed-in user may not take on their own account
+func (h *UsersHandler) self(c *gin.Context, user *models.User, message string) bool {
+	if middleware.CurrentUser(c).ID != user.ID {
+		return false
+	}
+	h.renderAdmin(c, http.StatusUnprocessableEntity, user, message)
+	return true
+}
+
+// detail gathers what the admin panel of user shows
+func (h *UsersHandler) detail(c *gin.Context, user *models.User, message string) (pages.UserDetail, bool) {
+	detail := pages.UserDetail{User: *user, Self: middleware.CurrentUser(c).ID == user.ID, Error: message}
+	var err error
+	if detail.Roles, err = h.container.Roles.List(c.Request.Context()); err != nil {
+		h.container.Logger.Error("Failed to list roles", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to load user")
+		return detail, false
+	}
+	return detail, true
+}
+
+// renderAdmin answers with the admin panel of user, or the whole page for
+// requests that are not from HTMX
+func (h *UsersHandler) renderAdmin(c *gin.Context, status int, user *models.User, message string) {
+	detail, ok := h.detail(c, user, message)
+	if !ok {
+		return
+	}
+	if c.GetHeader("HX-Request") != "true" {
+		NewAuthHandler(h.container).render(c, status, pages.UserShow(detail))
+		return
+	}
+	NewAuthHandler(h.container).render(c, status, pages.UserAdmin(detail))
+}
+
+// done answers HTMX requests with the updated admin panel and others with a
+// redirect to the user's page
+func (h *UsersHandler) done(c *gin.Context, user *models.User) {
+	if c.GetHeader("HX-Request") != "true" {
+		c.Redirect(http.StatusSeeOther, fmt.Sprintf("/users/%d", user.ID))
+		return
+	}
+	user, err := h.container.Users.Get(c.Request.Context(), user.ID)
+	if err != nil {
+		h.container.Logger.Error("Failed to reload user", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Failed to render page")
+		return
+	}
+	h.renderAdmin(c, http.StatusOK, user, "")
+}
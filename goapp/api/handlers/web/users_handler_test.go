@@ -0,0 +1,184 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goapp/api/middleware"
+	"goapp/internal/auth"
+	"goapp/internal/authz"
+	"goapp/internal/config"
+	"goapp/internal/models"
+	"goapp/internal/users"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupUsersRouter signs requests in as the user named in the X-User header.
+// The admin is user 1, jane 2 and bob 3.
+func setupUsersRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Permission{}, &models.Role{}, &models.User{}, &models.Session{}, &models.RefreshToken{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	roles := authz.NewRoleStore(db)
+	if err := roles.Seed(context.Background()); err != nil {
+		t.Fatalf("Seed() error = %v", err)
+	}
+	for _, name := range []string{"admin", "jane", "bob"} {
+		user := &models.User{Email: name + "@example.com", Username: name, PasswordHash: "hash", Active: true}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	if err := roles.Grant(context.Background(), 1, authz.RoleAdmin); err != nil {
+		t.Fatalf("Grant() error = %v", err)
+	}
+
+	container := setupTestContainer(t)
+	container.Roles = roles
+	hasher, _ := auth.NewHasher(config.AuthConfig{PasswordHasher: auth.HasherBcrypt, BcryptCost: bcrypt.MinCost})
+	container.Users = users.NewService(db, hasher)
+	handler := NewUsersHandler(container)
+	settingsHandler := NewSettingsHandler(container)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		var user models.User
+		if err := db.Preload("Roles.Permissions").Where("username = ?", c.GetHeader("X-User")).First(&user).Error; err == nil {
+			middleware.SetCurrentUser(c, &user)
+		}
+		c.Next()
+	})
+	router.Use(middleware.Policies(authz.DefaultPolicy()))
+	router.GET("/settings", middleware.RequireUser("/login"), settingsHandler.Index)
+	router.POST("/settings", middleware.RequireUser("/login"), settingsHandler.Update)
+	admin := router.Group("/users", middleware.RequireUser("/login"))
+	admin.GET("", handler.Index)
+	admin.GET("/:id", handler.Show)
+	admin.POST("/:id/deactivate", handler.Deactivate)
+	admin.POST("/:id/reactivate", handler.Reactivate)
+	admin.POST("/:id/delete", handler.Delete)
+	admin.POST("/:id/restore", handler.Restore)
+	admin.POST("/:id/roles", handler.GrantRole)
+	admin.POST("/:id/roles/:role/revoke", handler.RevokeRole)
+	return router, db
+}
+
+func TestUsersHandler_Index(t *testing.T) {
+	router, _ := setupUsersRouter(t)
+
+	if w := apiKeysRequest(router, http.MethodGet, "/users", "jane", nil, false); w.Code != http.StatusForbidden {
+		t.Errorf("Expected users other than admins to be refused, got %d", w.Code)
+	}
+
+	w := apiKeysRequest(router, http.MethodGet, "/users", "admin", nil, false)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `href="/users/3"`) || !strings.Contains(w.Body.String(), `<option value="editor"`) {
+		t.Fatalf("Expected the user list with role filter, got %d", w.Code)
+	}
+
+	w = apiKeysRequest(router, http.MethodGet, "/users?q=jan", "admin", nil, true)
+	body := w.Body.String()
+	if !strings.Contains(body, `id="user-results"`) || strings.Contains(body, "<html") {
+		t.Error("Expected HTMX searches to get only the results")
+	}
+	if !strings.Contains(body, "jane@example.com") || strings.Contains(body, "bob@example.com") {
+		t.Error("Expected only jane to match")
+	}
+}
+
+func TestUsersHandler_Manage(t *testing.T) {
+	router, db := setupUsersRouter(t)
+
+	if w := apiKeysRequest(router, http.MethodPost, "/users/2/deactivate", "bob", nil, true); w.Code != http.StatusForbidden {
+		t.Errorf("Expected users not to deactivate others, got %d", w.Code)
+	}
+	w := apiKeysRequest(router, http.MethodPost, "/users/1/deactivate", "admin", nil, true)
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "You cannot deactivate your own account.") {
+		t.Errorf("Expected admins not to deactivate themselves, got %d", w.Code)
+	}
+
+	w = apiKeysRequest(router, http.MethodPost, "/users/2/deactivate", "admin", nil, false)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/users/2" {
+		t.Errorf("Expected a redirect to the user, got %d", w.Code)
+	}
+	var jane models.User
+	db.First(&jane, 2)
+	if jane.Active {
+		t.Error("Expected jane to be deactivated")
+	}
+	w = apiKeysRequest(router, http.MethodPost, "/users/2/reactivate", "admin", nil, true)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Deactivate") {
+		t.Errorf("Expected the panel of the reactivated user, got %d", w.Code)
+	}
+
+	w = apiKeysRequest(router, http.MethodPost, "/users/3/roles", "admin", url.Values{"role": {"editor"}}, true)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/users/3/roles/editor/revoke") {
+		t.Errorf("Expected bob to be an editor, got %d", w.Code)
+	}
+	if w := apiKeysRequest(router, http.MethodPost, "/users/3/roles", "admin", url.Values{"role": {"superuser"}}, true); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected unknown roles to be refused, got %d", w.Code)
+	}
+	w = apiKeysRequest(router, http.MethodPost, "/users/3/roles/editor/revoke", "admin", nil, true)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "/users/3/roles/editor/revoke") {
+		t.Errorf("Expected bob to lose the editor role, got %d", w.Code)
+	}
+
+	w = apiKeysRequest(router, http.MethodPost, "/users/3/delete", "admin", nil, true)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Restore") {
+		t.Errorf("Expected the panel of the deleted user, got %d", w.Code)
+	}
+	w = apiKeysRequest(router, http.MethodGet, "/users/3", "admin", nil, false)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Deleted") {
+		t.Errorf("Expected the page of the deleted user, got %d", w.Code)
+	}
+	if w := apiKeysRequest(router, http.MethodPost, "/users/3/restore", "admin", nil, true); w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var count int64
+	db.Model(&models.User{}).Count(&count)
+	if count != 3 {
+		t.Errorf("Expected bob to be restored, got %d users", count)
+	}
+
+	if w := apiKeysRequest(router, http.MethodGet, "/users/99", "admin", nil, false); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestUsersHandler_RolesNeedPermission(t *testing.T) {
+	router, db := setupUsersRouter(t)
+
+	// support may manage users but not their roles
+	support := models.Role{Name: "support", Permissions: []models.Permission{{Name: "users:read"}, {Name: "users:update"}}}
+	if err := db.Create(&support).Error; err != nil {
+		t.Fatalf("Failed to create role: %v", err)
+	}
+	if err := db.Exec("INSERT INTO user_roles (user_id, role_id) VALUES (2, ?)", support.ID).Error; err != nil {
+		t.Fatalf("Failed to grant role: %v", err)
+	}
+
+	w := apiKeysRequest(router, http.MethodGet, "/users/3", "jane", nil, false)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "Grant role") {
+		t.Errorf("Expected the user page without the role form, got %d", w.Code)
+	}
+	if w := apiKeysRequest(router, http.MethodPost, "/users/2/roles", "jane", url.Values{"role": {"admin"}}, true); w.Code != http.StatusForbidden {
+		t.Errorf("Expected support not to grant roles, got %d", w.Code)
+	}
+	if w := apiKeysRequest(router, http.MethodPost, "/users/2/roles/support/revoke", "jane", nil, true); w.Code != http.StatusForbidden {
+		t.Errorf("Expected support not to revoke roles, got %d", w.Code)
+	}
+	if w := apiKeysRequest(router, http.MethodPost, "/users/3/deactivate", "jane", nil, true); w.Code != http.StatusOK {
+		t.Errorf("Expected support to deactivate users, got %d", w.Code)
+	}
+}
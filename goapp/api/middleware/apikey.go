@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/internal/apikeys"
+	"goapp/internal/logging"
+	"goapp/internal/models"
+)
+
+// apiKeyKey is the gin context key holding the authenticated *models.APIKey
+const apiKeyKey = "auth.api_key"
+
+// APIKeyAuth authenticates requests whose Authorization header carries an API
+// key as a bearer token. The key's owner becomes the current user, and
+// Authorize additionally limits the request to the key's scopes. Invalid
+// keys are rejected with 401; other bearer credentials pass through.
+func APIKeyAuth(keys apikeys.Service, logger logging.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := bearerToken(c)
+		if !ok || !keys.LooksLikeKey(token) {
+			c.Next()
+			return
+		}
+
+		key, err := keys.Authenticate(c.Request.Context(), token, c.ClientIP())
+		if errors.Is(err, apikeys.ErrInvalidKey) {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired API key"})
+			return
+		}
+		if err != nil {
+			logger.Error("Failed to authenticate API key", zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		c.Set(apiKeyKey, key)
+		SetCurrentUser(c, &key.User)
+		c.Next()
+	}
+}
+
+// CurrentAPIKey returns the API key the request was authenticated with, or nil
+func CurrentAPIKey(c *gin.Context) *models.APIKey {
+	if v, ok := c.Get(apiKeyKey); ok {
+		if key, ok := v.(*models.APIKey); ok {
+			return key
+		}
+	}
+	return nil
+}
+
+// RequireAuth rejects requests that carry neither a session, an API key nor
+// a JWT access token. It is meant for JSON endpoints; web pages should use
+// RequireUser, which redirects to the login page.
+func RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if CurrentUser(c) == nil && TokenClaims(c) == nil {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/apikeys"
+	"goapp/internal/authz"
+	"goapp/internal/config"
+	"goapp/internal/logging"
+	"goapp/internal/models"
+)
+
+// fakeAPIKeys accepts "key_valid", scoped to reading posts, as a key of user 1
+type fakeAPIKeys struct {
+	apikeys.Service
+}
+
+func (f *fakeAPIKeys) LooksLikeKey(token string) bool {
+	return len(token) > 4 && token[:4] == "key_"
+}
+
+func (f *fakeAPIKeys) Authenticate(ctx context.Context, token, clientIP string) (*models.APIKey, error) {
+	if token != "key_valid" {
+		return nil, apikeys.ErrInvalidKey
+	}
+	return &models.APIKey{
+		UserID: 1,
+		Scopes: "posts:read",
+		User:   models.User{BaseModel: models.BaseModel{ID: 1}, Username: "billing"},
+	}, nil
+}
+
+func setupAPIKeyRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	logger, err := logging.New(config.LoggerConfig{Environment: "test", WriteStdout: true})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	draft := &models.Post{UserID: 1}
+	router := gin.New()
+	router.Use(APIKeyAuth(&fakeAPIKeys{}, logger), Policies(authz.DefaultPolicy()))
+	router.GET("/api/posts/1", RequireAuth(), func(c *gin.Context) {
+		if Authorize(c, authz.ActionRead, draft) {
+			c.String(http.StatusOK, CurrentUser(c).Username)
+		}
+	})
+	router.PUT("/api/posts/1", RequireAuth(), func(c *gin.Context) {
+		if Authorize(c, authz.ActionUpdate, draft) {
+			c.Status(http.StatusNoContent)
+		}
+	})
+	return router
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	router := setupAPIKeyRouter(t)
+
+	tests := []struct {
+		name     string
+		method   string
+		bearer   string
+		expected int
+	}{
+		{"valid key within scope", http.MethodGet, "key_valid", http.StatusOK},
+		{"valid key outside scope", http.MethodPut, "key_valid", http.StatusForbidden},
+		{"invalid key", http.MethodGet, "key_revoked", http.StatusUnauthorized},
+		{"other bearer passes through", http.MethodGet, "static-admin-token", http.StatusUnauthorized},
+		{"anonymous", http.MethodGet, "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, "/api/posts/1", nil)
+			if tt.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/posts/1", nil)
+	req.Header.Set("Authorization", "Bearer key_valid")
+	router.ServeHTTP(w, req)
+	if w.Body.String() != "billing" {
+		t.Errorf("Expected key owner to be the current user, got %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/api/posts/1", nil)
+	req.Header.Set("Authorization", "Bearer key_revoked")
+	router.ServeHTTP(w, req)
+	if w.Header().Get("WWW-Authenticate") == "" {
+		t.Error("Expected WWW-Authenticate header for an invalid key")
+	}
+}
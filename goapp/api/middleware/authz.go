@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/authz"
+)
+
+// policyKey is the gin context key holding the *authz.Policy
+const policyKey = "authz.policy"
+
+// Policies makes policy available to handlers through Can and Authorize
+// and to templates through authz.Can
+func Policies(policy *authz.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(policyKey, policy)
+		c.Request = c.Request.WithContext(authz.WithPolicy(c.Request.Context(), policy))
+		c.Next()
+	}
+}
+
+// Can reports whether the current user may perform action on resource.
+// Without the Policies middleware everything is denied.
+func Can(c *gin.Context, action authz.Action, resource authz.Resource) bool {
+	return authorize(c, action, resource) == nil
+}
+
+// Authorize checks that the current user may perform action on resource, and
+// for API key requests that the key's scopes cover it.
+// When they may not it aborts with 401 for anonymous users or 403 otherwise
+// and returns false, so handlers can simply return.
+func Authorize(c *gin.Context, action authz.Action, resource authz.Resource) bool {
+	err := authorize(c, action, resource)
+	if err == nil {
+		return true
+	}
+
+	status := http.StatusForbidden
+	if errors.Is(err, authz.ErrUnauthenticated) {
+		status = http.StatusUnauthorized
+	}
+	if wantsJSON(c) {
+		c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+	} else {
+		c.String(status, err.Error())
+		c.Abort()
+	}
+	return false
+}
+
+// RequirePermission rejects requests whose user may not perform action on
+// resourceType as a whole, such as creating posts
+func RequirePermission(resourceType string, action authz.Action) gin.HandlerFunc {
+	resource := authz.Type(resourceType)
+	return func(c *gin.Context) {
+		if Authorize(c, action, resource) {
+			c.Next()
+		}
+	}
+}
+
+func authorize(c *gin.Context, action authz.Action, resource authz.Resource) error {
+	var policy *authz.Policy
+	if v, ok := c.Get(policyKey); ok {
+		policy, _ = v.(*authz.Policy)
+	}
+	if err := policy.Authorize(CurrentUser(c), action, resource); err != nil {
+		return err
+	}
+	// API keys act as their owner but only within their scopes
+	if key := CurrentAPIKey(c); key != nil && !key.HasScope(authz.Permission(resource.ResourceType(), action)) {
+		return authz.ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/authz"
+	"goapp/internal/models"
+)
+
+func setupAuthzRouter(user *models.User) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	post := &models.Post{UserID: 1}
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if user != nil {
+			SetCurrentUser(c, user)
+		}
+		c.Next()
+	})
+	router.Use(Policies(authz.DefaultPolicy()))
+	router.PUT("/posts/1", func(c *gin.Context) {
+		if !Authorize(c, authz.ActionUpdate, post) {
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+	router.POST("/api/posts", RequirePermission(authz.ResourcePosts, authz.ActionCreate), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+	router.GET("/template", func(c *gin.Context) {
+		if authz.Can(c.Request.Context(), authz.ActionUpdate, post) {
+			c.String(http.StatusOK, "editable")
+			return
+		}
+		c.String(http.StatusOK, "read-only")
+	})
+	return router
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     *models.User
+		method   string
+		path     string
+		expected int
+	}{
+		{"owner updates", &models.User{BaseModel: models.BaseModel{ID: 1}}, http.MethodPut, "/posts/1", http.StatusNoContent},
+		{"other user updates", &models.User{BaseModel: models.BaseModel{ID: 2}}, http.MethodPut, "/posts/1", http.StatusForbidden},
+		{"anonymous updates", nil, http.MethodPut, "/posts/1", http.StatusUnauthorized},
+		{"user creates", &models.User{BaseModel: models.BaseModel{ID: 2}}, http.MethodPost, "/api/posts", http.StatusCreated},
+		{"anonymous creates", nil, http.MethodPost, "/api/posts", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			setupAuthzRouter(tt.user).ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestPoliciesInRequestContext(t *testing.T) {
+	for user, expected := range map[*models.User]string{
+		{BaseModel: models.BaseModel{ID: 1}}: "editable",
+		{BaseModel: models.BaseModel{ID: 2}}: "read-only",
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/template", nil)
+		setupAuthzRouter(user).ServeHTTP(w, req)
+
+		if w.Body.String() != expected {
+			t.Errorf("Expected body %q, got %q", expected, w.Body.String())
+		}
+	}
+}
+
+func TestCanWithoutPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest(http.MethodGet, "/", nil)
+	SetCurrentUser(c, &models.User{BaseModel: models.BaseModel{ID: 1}})
+
+	if Can(c, authz.ActionUpdate, &models.Post{UserID: 1}) {
+		t.Error("Expected denial without the Policies middleware")
+	}
+}
@@ -0,0 +1,224 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"goapp/internal/config"
+)
+
+// Supported content encodings
+const (
+	encodingGzip   = "gzip"
+	encodingBrotli = "br"
+)
+
+// Compress negotiates gzip or brotli encoding with the client and compresses
+// responses whose content type is allowlisted and whose body reaches the
+// configured minimum size. Smaller bodies are sent uncompressed.
+func Compress(cfg config.CompressionConfig) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(cfg.ContentTypes))
+	for _, ct := range cfg.ContentTypes {
+		allowed[strings.ToLower(strings.TrimSpace(ct))] = true
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || c.GetHeader("Range") != "" || c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"), cfg.BrotliEnabled)
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: c.Writer,
+			encoding:       encoding,
+			level:          cfg.Level,
+			minSize:        cfg.MinSize,
+			allowed:        allowed,
+		}
+		c.Writer = cw
+		defer func() {
+			cw.finish()
+			c.Writer = cw.ResponseWriter
+		}()
+
+		c.Next()
+	}
+}
+
+// compressWriter buffers the start of a response until it knows whether the
+// body is large enough to be worth compressing
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	level    int
+	minSize  int
+	allowed  map[string]bool
+
+	decided bool
+	skip    bool
+	buf     []byte
+	encoder io.WriteCloser
+}
+
+// Write implements io.Writer
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.decide(p)
+	}
+	if w.skip {
+		return w.ResponseWriter.Write(p)
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.minSize {
+		if err := w.startEncoder(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// WriteString implements io.StringWriter
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends any buffered data to the client, compressing it if eligible
+func (w *compressWriter) Flush() {
+	if !w.skip && w.encoder == nil && len(w.buf) > 0 {
+		_ = w.startEncoder()
+	}
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide checks on the first write whether the response may be compressed
+func (w *compressWriter) decide(p []byte) {
+	w.decided = true
+
+	h := w.Header()
+	status := w.Status()
+	if h.Get("Content-Encoding") != "" || status < http.StatusOK ||
+		status == http.StatusNoContent || status == http.StatusNotModified {
+		w.skip = true
+		return
+	}
+
+	contentType := h.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(p)
+		h.Set("Content-Type", contentType)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !w.allowed[strings.ToLower(mediaType)] {
+		w.skip = true
+		return
+	}
+
+	h.Add("Vary", "Accept-Encoding")
+}
+
+// startEncoder switches the response to compressed output and drains the buffer
+func (w *compressWriter) startEncoder() error {
+	h := w.Header()
+	h.Set("Content-Encoding", w.encoding)
+	h.Del("Content-Length")
+
+	switch w.encoding {
+	case encodingBrotli:
+		w.encoder = brotli.NewWriterLevel(w.ResponseWriter, brotliLevel(w.level))
+	default:
+		gz, err := gzip.NewWriterLevel(w.ResponseWriter, w.level)
+		if err != nil {
+			gz = gzip.NewWriter(w.ResponseWriter)
+		}
+		w.encoder = gz
+	}
+
+	buf := w.buf
+	w.buf = nil
+	_, err := w.encoder.Write(buf)
+	return err
+}
+
+// finish closes the encoder or writes out a body that stayed below the threshold
+func (w *compressWriter) finish() {
+	if w.encoder != nil {
+		_ = w.encoder.Close()
+		return
+	}
+	if len(w.buf) > 0 {
+		_, _ = w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+	}
+}
+
+// brotliLevel maps a gzip level from 0 to 9 onto brotli's range from 0 to
+// 11; other levels use brotli's default
+func brotliLevel(level int) int {
+	if level < gzip.NoCompression || level > gzip.BestCompression {
+		return brotli.DefaultCompression
+	}
+	return (level*brotli.BestCompression + gzip.BestCompression/2) / gzip.BestCompression
+}
+
+// negotiateEncoding picks the preferred supported encoding from an
+// Accept-Encoding header, favouring brotli when weights are equal
+func negotiateEncoding(header string, brotliEnabled bool) string {
+	if header == "" {
+		return ""
+	}
+
+	weights := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		weights[name] = q
+	}
+
+	weight := func(encoding string) float64 {
+		if q, ok := weights[encoding]; ok {
+			return q
+		}
+		if q, ok := weights["*"]; ok {
+			return q
+		}
+		return 0
+	}
+
+	best, bestQ := "", 0.0
+	if brotliEnabled {
+		if q := weight(encodingBrotli); q > bestQ {
+			best, bestQ = encodingBrotli, q
+		}
+	}
+	if q := weight(encodingGzip); q > bestQ {
+		best = encodingGzip
+	}
+	return best
+}
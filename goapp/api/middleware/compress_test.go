@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"goapp/internal/config"
+)
+
+func testCompressionConfig() config.CompressionConfig {
+	return config.CompressionConfig{
+		Enabled:       true,
+		BrotliEnabled: true,
+		Level:         -1,
+		MinSize:       64,
+		ContentTypes:  []string{"text/html", "application/json"},
+	}
+}
+
+func setupCompressRouter(body string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(Compress(testCompressionConfig()))
+	router.GET("/html", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(body))
+	})
+	router.GET("/image", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", []byte(body))
+	})
+	return router
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("<p>GoApp</p>", 100)
+	router := setupCompressRouter(large)
+
+	t.Run("Gzip", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/html", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		router.ServeHTTP(w, req)
+
+		if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+			t.Fatalf("Expected gzip encoding, got '%s'", got)
+		}
+		if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+			t.Errorf("Expected Vary 'Accept-Encoding', got '%s'", got)
+		}
+
+		reader, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatalf("Failed to create gzip reader: %v", err)
+		}
+		decoded, _ := io.ReadAll(reader)
+		if string(decoded) != large {
+			t.Error("Expected decompressed body to match original")
+		}
+	})
+
+	t.Run("BrotliPreferred", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/html", nil)
+		req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+		router.ServeHTTP(w, req)
+
+		if got := w.Header().Get("Content-Encoding"); got != "br" {
+			t.Fatalf("Expected br encoding, got '%s'", got)
+		}
+
+		decoded, _ := io.ReadAll(brotli.NewReader(w.Body))
+		if string(decoded) != large {
+			t.Error("Expected decompressed body to match original")
+		}
+	})
+
+	t.Run("NoAcceptEncoding", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/html", nil)
+		router.ServeHTTP(w, req)
+
+		if got := w.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("Expected no encoding, got '%s'", got)
+		}
+		if w.Body.String() != large {
+			t.Error("Expected uncompressed body")
+		}
+	})
+
+	t.Run("ContentTypeNotAllowed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/image", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		router.ServeHTTP(w, req)
+
+		if got := w.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("Expected no encoding for image/png, got '%s'", got)
+		}
+	})
+}
+
+func TestCompressBelowMinSize(t *testing.T) {
+	router := setupCompressRouter("<p>small</p>")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/html", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Expected small body to stay uncompressed, got '%s'", got)
+	}
+	if w.Body.String() != "<p>small</p>" {
+		t.Errorf("Expected original body, got '%s'", w.Body.String())
+	}
+}
+
+func TestBrotliLevel(t *testing.T) {
+	for level, want := range map[int]int{-1: 6, 0: 0, 1: 1, 5: 6, 9: 11, 10: 6} {
+		if got := brotliLevel(level); got != want {
+			t.Errorf("brotliLevel(%d) = %d, want %d", level, got, want)
+		}
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	testCases := []struct {
+		header string
+		brotli bool
+		want   string
+	}{
+		{"", true, ""},
+		{"gzip", true, "gzip"},
+		{"br", false, ""},
+		{"gzip, br", true, "br"},
+		{"gzip, br", false, "gzip"},
+		{"br;q=0.5, gzip;q=0.8", true, "gzip"},
+		{"gzip;q=0", true, ""},
+		{"*", true, "br"},
+		{"identity", true, ""},
+	}
+
+	for _, tc := range testCases {
+		if got := negotiateEncoding(tc.header, tc.brotli); got != tc.want {
+			t.Errorf("negotiateEncoding(%q, %v) = %q, want %q", tc.header, tc.brotli, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecation advertises that an API version is deprecated using the
+// Deprecation (RFC 9745) and Sunset (RFC 8594) headers. A zero sunset omits
+// the Sunset header; a non-empty successor is linked as successor-version.
+func Deprecation(deprecatedAt, sunset time.Time, successor string) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", deprecatedAt.Unix())
+	sunsetValue := ""
+	if !sunset.IsZero() {
+		sunsetValue = sunset.UTC().Format(http.TimeFormat)
+	}
+	link := ""
+	if successor != "" {
+		link = fmt.Sprintf(`<%s>; rel="successor-version"`, successor)
+	}
+
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("Deprecation", deprecation)
+		if sunsetValue != "" {
+			h.Set("Sunset", sunsetValue)
+		}
+		if link != "" {
+			h.Add("Link", link)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDeprecation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	deprecatedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2025, 12, 31, 23, 59, 59, 0, time.UTC)
+
+	router := gin.New()
+	router.Use(Deprecation(deprecatedAt, sunset, "/api/v2"))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Deprecation"); got != "@1735689600" {
+		t.Errorf("Expected Deprecation '@1735689600', got '%s'", got)
+	}
+	if got := w.Header().Get("Sunset"); got != "Wed, 31 Dec 2025 23:59:59 GMT" {
+		t.Errorf("Expected Sunset 'Wed, 31 Dec 2025 23:59:59 GMT', got '%s'", got)
+	}
+	if got := w.Header().Get("Link"); got != `</api/v2>; rel="successor-version"` {
+		t.Errorf("Expected successor Link header, got '%s'", got)
+	}
+}
+
+func TestDeprecationWithoutSunset(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(Deprecation(time.Unix(0, 0), time.Time{}, ""))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Header().Get("Sunset") != "" || w.Header().Get("Link") != "" {
+		t.Error("Expected Sunset and Link headers to be omitted")
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HTTPError is an error carrying the HTTP status it should be reported with
+type HTTPError struct {
+	Status  int
+	Message string
+}
+
+// Error implements the error interface
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+// NewHTTPError creates an HTTPError, defaulting the message to the status text
+func NewHTTPError(status int, message string) *HTTPError {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return &HTTPError{Status: status, Message: message}
+}
+
+// JSONErrors renders errors attached with c.Error as {"error": "..."} when
+// the handler has not written a response itself. An HTTPError sets the
+// status; any other error is reported as 500 without leaking its message.
+func JSONErrors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) {
+			c.JSON(httpErr.Status, gin.H{"error": httpErr.Message})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+	}
+}
+
+// JSONNotFound responds with a JSON 404, for use as a NoRoute handler under /api
+func JSONNotFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, gin.H{"error": "resource not found"})
+}
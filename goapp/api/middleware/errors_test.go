@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestJSONErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(JSONErrors())
+	router.GET("/http-error", func(c *gin.Context) {
+		_ = c.Error(NewHTTPError(http.StatusNotFound, "post not found"))
+	})
+	router.GET("/internal", func(c *gin.Context) {
+		_ = c.Error(errors.New("database exploded"))
+	})
+	router.GET("/handled", func(c *gin.Context) {
+		_ = c.Error(errors.New("logged only"))
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	testCases := []struct {
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"/http-error", http.StatusNotFound, `{"error":"post not found"}`},
+		{"/internal", http.StatusInternalServerError, `{"error":"Internal Server Error"}`},
+		{"/handled", http.StatusOK, `{"ok":true}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.wantStatus {
+				t.Errorf("Expected status %d, got %d", tc.wantStatus, w.Code)
+			}
+			if w.Body.String() != tc.wantBody {
+				t.Errorf("Expected body %s, got %s", tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestNewHTTPErrorDefaultMessage(t *testing.T) {
+	err := NewHTTPError(http.StatusForbidden, "")
+	if err.Error() != "Forbidden" {
+		t.Errorf("Expected message 'Forbidden', got '%s'", err.Error())
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETag buffers successful GET responses, tags them with a weak ETag derived
+// from the body and answers 304 Not Modified when If-None-Match matches
+func ETag() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		ew := &etagWriter{ResponseWriter: original}
+		c.Writer = ew
+		c.Next()
+		c.Writer = original
+
+		if ew.Status() != http.StatusOK || ew.Header().Get("ETag") != "" {
+			_, _ = original.Write(ew.buf.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(ew.buf.Bytes())
+		etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+		h := original.Header()
+		h.Set("ETag", etag)
+		if h.Get("Cache-Control") == "" {
+			h.Set("Cache-Control", "no-cache")
+		}
+
+		if etagMatches(c.GetHeader("If-None-Match"), etag) {
+			h.Del("Content-Length")
+			original.WriteHeader(http.StatusNotModified)
+			original.WriteHeaderNow()
+			return
+		}
+
+		_, _ = original.Write(ew.buf.Bytes())
+	}
+}
+
+// etagWriter captures the response body so it can be hashed
+type etagWriter struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+// Write implements io.Writer
+func (w *etagWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+// WriteString implements io.StringWriter
+func (w *etagWriter) WriteString(s string) (int, error) {
+	return w.buf.WriteString(s)
+}
+
+// Written reports whether the handler produced any output
+func (w *etagWriter) Written() bool {
+	return w.buf.Len() > 0 || w.ResponseWriter.Written()
+}
+
+// etagMatches implements the weak comparison used by If-None-Match
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	target := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == target {
+			return true
+		}
+	}
+	return false
+}
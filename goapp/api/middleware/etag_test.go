@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupETagRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(ETag())
+	router.GET("/page", func(c *gin.Context) {
+		c.String(http.StatusOK, "hello")
+	})
+	router.GET("/missing", func(c *gin.Context) {
+		c.String(http.StatusNotFound, "not found")
+	})
+	return router
+}
+
+func TestETag(t *testing.T) {
+	router := setupETagRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/page", nil)
+	router.ServeHTTP(w, req)
+
+	etag := w.Header().Get("ETag")
+	if len(etag) < 4 || etag[:3] != `W/"` {
+		t.Fatalf("Expected weak ETag, got '%s'", etag)
+	}
+	if w.Body.String() != "hello" {
+		t.Errorf("Expected body 'hello', got '%s'", w.Body.String())
+	}
+
+	t.Run("NotModified", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/page", nil)
+		req.Header.Set("If-None-Match", etag)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotModified {
+			t.Errorf("Expected status %d, got %d", http.StatusNotModified, w.Code)
+		}
+		if w.Body.Len() != 0 {
+			t.Error("Expected empty body for 304 response")
+		}
+	})
+
+	t.Run("StrongFormMatchesWeakly", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/page", nil)
+		req.Header.Set("If-None-Match", `"other", `+etag[2:])
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotModified {
+			t.Errorf("Expected status %d, got %d", http.StatusNotModified, w.Code)
+		}
+	})
+
+	t.Run("Modified", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/page", nil)
+		req.Header.Set("If-None-Match", `W/"stale"`)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		if w.Body.String() != "hello" {
+			t.Errorf("Expected body 'hello', got '%s'", w.Body.String())
+		}
+	})
+}
+
+func TestETagSkipsErrors(t *testing.T) {
+	router := setupETagRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/missing", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+	if w.Header().Get("ETag") != "" {
+		t.Error("Expected no ETag on error responses")
+	}
+	if w.Body.String() != "not found" {
+		t.Errorf("Expected body 'not found', got '%s'", w.Body.String())
+	}
+}
+
+func TestETagWithCompression(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	body := strings.Repeat("<p>GoApp</p>", 100)
+	router := gin.New()
+	router.Use(Compress(testCompressionConfig()), ETag())
+	router.GET("/page", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html", []byte(body))
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/page", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Expected gzip encoding, got '%s'", got)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected ETag on compressed response")
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/page", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("If-None-Match", etag)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Expected status %d, got %d", http.StatusNotModified, w.Code)
+	}
+	if w.Header().Get("Content-Encoding") != "" || w.Body.Len() != 0 {
+		t.Error("Expected bare 304 without encoded body")
+	}
+}
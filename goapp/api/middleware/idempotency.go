@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/internal/idempotency"
+	"goapp/internal/logging"
+)
+
+// IdempotencyKeyHeader is the request header carrying the client's idempotency key
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength bounds the key size accepted from clients
+const maxIdempotencyKeyLength = 255
+
+// idempotentHeaders are the response headers replayed besides Content-Type
+var idempotentHeaders = []string{"Location", "ETag", "Last-Modified", "Link", "Content-Location"}
+
+// Idempotency makes POST and PATCH requests carrying an Idempotency-Key header
+// safe to retry. The first request is executed and its response stored; later
+// requests with the same key and body replay the stored response, while reuse
+// of a key with a different body is rejected with 409 Conflict. Server errors
+// are not stored so that retries re-execute the request.
+//
+// Keys belong to the caller, by API key, user or else client IP, and to the
+// method and route, so it must run after the authentication middleware.
+func Idempotency(store idempotency.Store, logger logging.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		method := c.Request.Method
+		if key == "" || (method != http.MethodPost && method != http.MethodPatch) {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key header is too long"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		key = idempotencyScope(c, key)
+
+		ctx := c.Request.Context()
+		stored, err := store.Begin(ctx, key, requestFingerprint(method, c.Request.URL.RequestURI(), body))
+		switch {
+		case errors.Is(err, idempotency.ErrFingerprintMismatch), errors.Is(err, idempotency.ErrInProgress):
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			logger.Error("Idempotency store unavailable", zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to process idempotency key"})
+			return
+		case stored != nil:
+			for name, values := range stored.Header {
+				for _, value := range values {
+					c.Writer.Header().Add(name, value)
+				}
+			}
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(stored.StatusCode, stored.ContentType, stored.Body)
+			c.Abort()
+			return
+		}
+
+		// Release the key unless a response is stored, including when the
+		// handler panics, so the client can retry
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := store.Release(ctx, key); err != nil {
+				logger.Error("Failed to release idempotency key", zap.String("key", key), zap.Error(err))
+			}
+		}()
+
+		rec := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = rec
+		c.Next()
+		c.Writer = rec.ResponseWriter
+
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			return
+		}
+
+		resp := idempotency.Response{
+			StatusCode:  c.Writer.Status(),
+			ContentType: c.Writer.Header().Get("Content-Type"),
+			Header:      http.Header{},
+			Body:        rec.buf.Bytes(),
+		}
+		for _, name := range idempotentHeaders {
+			if values := c.Writer.Header().Values(name); len(values) > 0 {
+				resp.Header[name] = values
+			}
+		}
+		if err := store.Complete(ctx, key, resp); err != nil {
+			logger.Error("Failed to store idempotent response", zap.String("key", key), zap.Error(err))
+			return
+		}
+		completed = true
+	}
+}
+
+// idempotencyScope returns the stored key for the client's key: a hash of
+// the key with the caller, method and route, so that callers cannot replay
+// each other's responses
+func idempotencyScope(c *gin.Context, key string) string {
+	caller := "ip:" + c.ClientIP()
+	if apiKey := CurrentAPIKey(c); apiKey != nil {
+		caller = "api_key:" + strconv.FormatUint(uint64(apiKey.ID), 10)
+	} else if user := CurrentUser(c); user != nil {
+		caller = "user:" + strconv.FormatUint(uint64(user.ID), 10)
+	}
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+
+	h := sha256.New()
+	for _, part := range []string{caller, c.Request.Method, route, key} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// requestFingerprint identifies a request by method, target and body
+func requestFingerprint(method, target string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(target))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter copies the response body while passing it through
+type recordingWriter struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+// Write implements io.Writer
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+// WriteString implements io.StringWriter
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.buf.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/config"
+	"goapp/internal/idempotency"
+	"goapp/internal/logging"
+	"goapp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestLogger(t *testing.T) logging.Logger {
+	logger, err := logging.New(config.LoggerConfig{Environment: "test", WriteStdout: true})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	return logger
+}
+
+func setupIdempotencyRouter(t *testing.T) (*gin.Engine, *int) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.IdempotencyKey{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	calls := 0
+	router := gin.New()
+	// Signs requests in as the user whose ID is in the X-User header
+	router.Use(func(c *gin.Context) {
+		if id, err := strconv.ParseUint(c.GetHeader("X-User"), 10, 64); err == nil {
+			SetCurrentUser(c, &models.User{BaseModel: models.BaseModel{ID: uint(id)}})
+		}
+		c.Next()
+	})
+	router.Use(Idempotency(idempotency.NewStore(db, time.Hour), setupTestLogger(t)))
+	router.POST("/posts", func(c *gin.Context) {
+		calls++
+		body, _ := io.ReadAll(c.Request.Body)
+		c.Header("Location", "/posts/"+strconv.Itoa(calls))
+		c.Header("ETag", `"`+strconv.Itoa(calls)+`"`)
+		c.JSON(http.StatusCreated, gin.H{"call": calls, "body": string(body), "user": c.GetHeader("X-User")})
+	})
+	router.POST("/fail", func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "unavailable"})
+	})
+	return router, &calls
+}
+
+func postWithKey(router *gin.Engine, path, key, body string) *httptest.ResponseRecorder {
+	return postAsUser(router, path, key, body, "")
+}
+
+// postAsUser posts with an idempotency key as the user with ID user, if any
+func postAsUser(router *gin.Engine, path, key, body, user string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency(t *testing.T) {
+	router, calls := setupIdempotencyRouter(t)
+
+	first := postWithKey(router, "/posts", "abc", `{"title":"a"}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, first.Code)
+	}
+
+	t.Run("ReplaysStoredResponse", func(t *testing.T) {
+		w := postWithKey(router, "/posts", "abc", `{"title":"a"}`)
+
+		if w.Code != http.StatusCreated {
+			t.Errorf("Expected status %d, got %d", http.StatusCreated, w.Code)
+		}
+		if w.Body.String() != first.Body.String() {
+			t.Errorf("Expected replayed body %s, got %s", first.Body.String(), w.Body.String())
+		}
+		if w.Header().Get("Idempotent-Replayed") != "true" {
+			t.Error("Expected Idempotent-Replayed header")
+		}
+		if w.Header().Get("Location") != "/posts/1" || w.Header().Get("ETag") != `"1"` {
+			t.Errorf("Expected Location and ETag to be replayed, got %v", w.Header())
+		}
+		if *calls != 1 {
+			t.Errorf("Expected handler to run once, ran %d times", *calls)
+		}
+	})
+
+	t.Run("DifferentBodyConflicts", func(t *testing.T) {
+		w := postWithKey(router, "/posts", "abc", `{"title":"b"}`)
+
+		if w.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d", http.StatusConflict, w.Code)
+		}
+	})
+
+	t.Run("NoKeyAlwaysExecutes", func(t *testing.T) {
+		before := *calls
+		postWithKey(router, "/posts", "", `{"title":"a"}`)
+		postWithKey(router, "/posts", "", `{"title":"a"}`)
+
+		if *calls != before+2 {
+			t.Errorf("Expected handler to run twice without a key, ran %d times", *calls-before)
+		}
+	})
+}
+
+func TestIdempotencyServerErrorNotStored(t *testing.T) {
+	router, calls := setupIdempotencyRouter(t)
+
+	postWithKey(router, "/fail", "retry-me", `{}`)
+	w := postWithKey(router, "/fail", "retry-me", `{}`)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if *calls != 2 {
+		t.Errorf("Expected server errors to be re-executed, handler ran %d times", *calls)
+	}
+}
+
+func TestIdempotencyScopedToCaller(t *testing.T) {
+	router, calls := setupIdempotencyRouter(t)
+
+	postAsUser(router, "/posts", "shared", `{"title":"a"}`, "1")
+	w := postAsUser(router, "/posts", "shared", `{"title":"a"}`, "2")
+	if w.Header().Get("Idempotent-Replayed") != "" || !strings.Contains(w.Body.String(), `"user":"2"`) {
+		t.Errorf("Expected another user's key not to be replayed, got %s", w.Body.String())
+	}
+	if w := postAsUser(router, "/posts", "shared", `{"title":"a"}`, ""); w.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("Expected anonymous callers not to replay users' keys")
+	}
+	if w := postAsUser(router, "/posts", "shared", `{"title":"a"}`, "1"); w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("Expected the caller's own key to be replayed")
+	}
+	if *calls != 3 {
+		t.Errorf("Expected the handler to run once per caller, ran %d times", *calls)
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/internal/logging"
+	"goapp/internal/tokens"
+)
+
+// tokenClaimsKey is the gin context key holding the verified *tokens.Claims
+const tokenClaimsKey = "auth.claims"
+
+// JWTAuth verifies JWT bearer tokens. Valid claims are exposed through
+// TokenClaims and tokens.ClaimsFromContext, and with subjects the token's
+// user becomes the current user; tokens of inactive or signed out users
+// and other invalid tokens are rejected with 401. Requests without a JWT
+// pass through unauthenticated, so other bearer credentials and public
+// endpoints keep working; use RequireJWT to demand one.
+func JWTAuth(verifier tokens.Verifier, subjects tokens.Subjects, logger logging.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := bearerToken(c)
+		if !ok || !tokens.LooksLikeJWT(token) {
+			c.Next()
+			return
+		}
+
+		claims, err := verifier.Verify(token)
+		if err != nil {
+			rejectToken(c)
+			return
+		}
+		if subjects != nil {
+			user, err := subjects.Subject(c.Request.Context(), claims)
+			if errors.Is(err, tokens.ErrInvalidToken) {
+				rejectToken(c)
+				return
+			}
+			if err != nil {
+				logger.Error("Failed to load token subject", zap.Error(err))
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+				return
+			}
+			SetCurrentUser(c, user)
+		}
+
+		c.Set(tokenClaimsKey, claims)
+		c.Request = c.Request.WithContext(tokens.WithClaims(c.Request.Context(), claims))
+		c.Next()
+	}
+}
+
+// rejectToken answers 401 for an invalid access token
+func rejectToken(c *gin.Context) {
+	c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired access token"})
+}
+
+// TokenClaims returns the verified access token claims, or nil
+func TokenClaims(c *gin.Context) *tokens.Claims {
+	if v, ok := c.Get(tokenClaimsKey); ok {
+		if claims, ok := v.(*tokens.Claims); ok {
+			return claims
+		}
+	}
+	return nil
+}
+
+// RequireJWT rejects requests that were not authenticated by JWTAuth
+func RequireJWT() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if TokenClaims(c) == nil {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/models"
+	"goapp/internal/tokens"
+)
+
+// fakeVerifier accepts the JWT-shaped token "eyJ.valid.sig"
+type fakeVerifier struct{}
+
+func (fakeVerifier) Verify(token string) (*tokens.Claims, error) {
+	if token != "eyJ.valid.sig" {
+		return nil, errors.New("bad signature")
+	}
+	claims := &tokens.Claims{Username: "jane"}
+	claims.Subject = "1"
+	return claims, nil
+}
+
+// fakeSubjects returns jane for tokens of user 1, or err
+type fakeSubjects struct {
+	err error
+}
+
+func (f fakeSubjects) Subject(ctx context.Context, claims *tokens.Claims) (*models.User, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &models.User{BaseModel: models.BaseModel{ID: 1}, Username: "jane"}, nil
+}
+
+func TestJWTAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(JWTAuth(fakeVerifier{}, nil, setupTestLogger(t)))
+	router.GET("/public", func(c *gin.Context) {
+		name := "anonymous"
+		if claims := TokenClaims(c); claims != nil {
+			name = claims.Username
+		}
+		c.String(http.StatusOK, name)
+	})
+	router.GET("/private", RequireJWT(), func(c *gin.Context) {
+		c.String(http.StatusOK, tokens.ClaimsFromContext(c.Request.Context()).Username)
+	})
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		wantStatus    int
+		wantBody      string
+	}{
+		{"Anonymous", "/public", "", http.StatusOK, "anonymous"},
+		{"ValidToken", "/public", "Bearer eyJ.valid.sig", http.StatusOK, "jane"},
+		{"SchemeCaseInsensitive", "/private", "bearer eyJ.valid.sig", http.StatusOK, "jane"},
+		{"InvalidToken", "/public", "Bearer eyJ.forged.sig", http.StatusUnauthorized, ""},
+		{"OtherBearerCredential", "/public", "Bearer static-admin-token", http.StatusOK, "anonymous"},
+		{"RequiredMissing", "/private", "", http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("Expected body %q, got %q", tt.wantBody, w.Body.String())
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("Expected WWW-Authenticate header on 401")
+			}
+		})
+	}
+}
+
+func TestJWTAuth_Subjects(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		subjects   fakeSubjects
+		wantStatus int
+	}{
+		{"CurrentUser", fakeSubjects{}, http.StatusOK},
+		{"Revoked", fakeSubjects{err: fmt.Errorf("%w: revoked", tokens.ErrInvalidToken)}, http.StatusUnauthorized},
+		{"DatabaseDown", fakeSubjects{err: errors.New("connection refused")}, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(JWTAuth(fakeVerifier{}, tt.subjects, setupTestLogger(t)))
+			router.GET("/me", func(c *gin.Context) {
+				c.String(http.StatusOK, CurrentUser(c).Username)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/me", nil)
+			req.Header.Set("Authorization", "Bearer eyJ.valid.sig")
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus == http.StatusOK && w.Body.String() != "jane" {
+				t.Errorf("Expected jane to be the current user, got %q", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/config"
+)
+
+// subRequestKey marks the context of requests made on behalf of another one
+type subRequestKey struct{}
+
+// WithSubRequest marks ctx as that of a sub-request, such as one of a batch,
+// served while its parent request is still being processed
+func WithSubRequest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, subRequestKey{}, true)
+}
+
+// IsSubRequest reports whether ctx was marked by WithSubRequest
+func IsSubRequest(ctx context.Context) bool {
+	return ctx.Value(subRequestKey{}) != nil
+}
+
+// LoadShed bounds the number of requests processed concurrently. Requests
+// beyond MaxInFlight wait up to QueueTimeout for a slot; once MaxQueue
+// requests are already waiting, or the wait times out, the request is
+// rejected with 503 and a Retry-After header instead of piling up latency.
+// Sub-requests run in their parent's slot, so they never wait for another
+// one the parent could be holding up.
+func LoadShed(cfg config.LoadShedConfig) gin.HandlerFunc {
+	slots := make(chan struct{}, max(cfg.MaxInFlight, 1))
+	var waiting atomic.Int64
+	retryAfter := strconv.Itoa(int(math.Max(1, math.Ceil(cfg.RetryAfter.Seconds()))))
+
+	reject := func(c *gin.Context) {
+		c.Header("Retry-After", retryAfter)
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "server is overloaded, try again later"})
+	}
+
+	return func(c *gin.Context) {
+		if IsSubRequest(c.Request.Context()) || hasPathPrefix(c.Request.URL.Path, cfg.ExemptPaths) {
+			c.Next()
+			return
+		}
+
+		select {
+		case slots <- struct{}{}:
+		default:
+			if waiting.Add(1) > int64(cfg.MaxQueue) {
+				waiting.Add(-1)
+				reject(c)
+				return
+			}
+
+			timer := time.NewTimer(cfg.QueueTimeout)
+			select {
+			case slots <- struct{}{}:
+				timer.Stop()
+				waiting.Add(-1)
+			case <-timer.C:
+				waiting.Add(-1)
+				reject(c)
+				return
+			case <-c.Request.Context().Done():
+				timer.Stop()
+				waiting.Add(-1)
+				c.Abort()
+				return
+			}
+		}
+
+		defer func() { <-slots }()
+		c.Next()
+	}
+}
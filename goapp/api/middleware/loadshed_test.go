@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/config"
+)
+
+// setupLoadShedRouter returns a router whose "/slow" handler blocks until release is closed
+func setupLoadShedRouter(cfg config.LoadShedConfig, started chan<- struct{}, release <-chan struct{}) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(LoadShed(cfg))
+	router.GET("/slow", func(c *gin.Context) {
+		started <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	})
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func serve(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestLoadShed(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxQueue int
+	}{
+		{"QueueFull", 0},
+		{"QueueTimeout", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{}, 1)
+			release := make(chan struct{})
+			router := setupLoadShedRouter(config.LoadShedConfig{
+				MaxInFlight:  1,
+				MaxQueue:     tt.maxQueue,
+				QueueTimeout: 20 * time.Millisecond,
+				RetryAfter:   2 * time.Second,
+				ExemptPaths:  []string{"/health"},
+			}, started, release)
+
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				serve(router, "/slow")
+			}()
+			<-started
+
+			w := serve(router, "/slow")
+			if w.Code != http.StatusServiceUnavailable {
+				t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+			}
+			if w.Header().Get("Retry-After") != "2" {
+				t.Errorf("Expected Retry-After 2, got %q", w.Header().Get("Retry-After"))
+			}
+
+			if w := serve(router, "/health"); w.Code != http.StatusOK {
+				t.Errorf("Expected exempt path to bypass load shedding, got %d", w.Code)
+			}
+
+			// A sub-request of the request holding the slot must not wait for it
+			sub := make(chan int, 1)
+			go func() {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequestWithContext(WithSubRequest(context.Background()), http.MethodGet, "/slow", nil)
+				router.ServeHTTP(w, req)
+				sub <- w.Code
+			}()
+			select {
+			case <-started:
+			case code := <-sub:
+				t.Fatalf("Expected sub-requests to bypass load shedding, got %d", code)
+			}
+
+			close(release)
+			wg.Wait()
+			if code := <-sub; code != http.StatusOK {
+				t.Errorf("Expected sub-requests to bypass load shedding, got %d", code)
+			}
+		})
+	}
+}
+
+func TestLoadShedQueuedRequestProceeds(t *testing.T) {
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	router := setupLoadShedRouter(config.LoadShedConfig{
+		MaxInFlight:  1,
+		MaxQueue:     1,
+		QueueTimeout: time.Second,
+		RetryAfter:   time.Second,
+	}, started, release)
+
+	codes := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() { codes <- serve(router, "/slow").Code }()
+	}
+	<-started
+	close(release)
+
+	for i := 0; i < 2; i++ {
+		if code := <-codes; code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, code)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/maintenance"
+)
+
+// MaintenancePageFunc renders the HTML page shown while in maintenance mode
+type MaintenancePageFunc func(c *gin.Context, status maintenance.Status)
+
+// Maintenance answers every non-exempt request with 503 Service Unavailable
+// and a Retry-After header while maintenance mode is enabled. API clients
+// receive JSON; browsers get the page rendered by page.
+func Maintenance(manager *maintenance.Manager, exemptPaths []string, page MaintenancePageFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if hasPathPrefix(c.Request.URL.Path, exemptPaths) {
+			c.Next()
+			return
+		}
+
+		status := manager.Status(c.Request.Context())
+		if !status.Enabled {
+			c.Next()
+			return
+		}
+
+		if status.RetryAfterSeconds > 0 {
+			c.Header("Retry-After", strconv.Itoa(status.RetryAfterSeconds))
+		}
+		c.Header("Cache-Control", "no-store")
+
+		if page == nil || wantsJSON(c) {
+			message := status.Message
+			if message == "" {
+				message = "service is under maintenance"
+			}
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": message})
+			return
+		}
+
+		c.Status(http.StatusServiceUnavailable)
+		page(c, status)
+		c.Abort()
+	}
+}
+
+// wantsJSON reports whether the request targets the JSON API
+func wantsJSON(c *gin.Context) bool {
+	if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+		return true
+	}
+	accept := c.GetHeader("Accept")
+	return strings.Contains(accept, "application/json") && !strings.Contains(accept, "text/html")
+}
+
+// hasPathPrefix reports whether path equals or starts with any of the prefixes
+func hasPathPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if prefix != "" && strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/maintenance"
+)
+
+func setupMaintenanceRouter(t *testing.T, enabled bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	manager := maintenance.NewManager(maintenance.NewMemoryStore(), time.Minute)
+	if enabled {
+		if _, err := manager.Enable(context.Background(), "Upgrading", 90*time.Second); err != nil {
+			t.Fatalf("Enable() error = %v", err)
+		}
+	}
+
+	page := func(c *gin.Context, status maintenance.Status) {
+		c.String(c.Writer.Status(), "page: "+status.Message)
+	}
+
+	router := gin.New()
+	router.Use(Maintenance(manager, []string{"/health"}, page))
+	router.GET("/", func(c *gin.Context) { c.String(http.StatusOK, "home") })
+	router.GET("/health", func(c *gin.Context) { c.String(http.StatusOK, "UP") })
+	router.GET("/api/v1/status", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "UP"}) })
+	return router
+}
+
+func TestMaintenance(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		router := setupMaintenanceRouter(t, false)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	router := setupMaintenanceRouter(t, true)
+
+	tests := []struct {
+		name       string
+		path       string
+		accept     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"WebPage", "/", "text/html", http.StatusServiceUnavailable, "page: Upgrading"},
+		{"API", "/api/v1/status", "", http.StatusServiceUnavailable, `{"error":"Upgrading"}`},
+		{"AcceptsJSON", "/", "application/json", http.StatusServiceUnavailable, `{"error":"Upgrading"}`},
+		{"ExemptHealth", "/health", "", http.StatusOK, "UP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("Expected body %q, got %q", tt.wantBody, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusServiceUnavailable && w.Header().Get("Retry-After") != "90" {
+				t.Errorf("Expected Retry-After 90, got %q", w.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
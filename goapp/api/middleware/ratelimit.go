@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/config"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitKeyFunc identifies the client a request is counted against
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// ClientIPKey counts requests per client IP address
+func ClientIPKey(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// RateLimit applies a per-client token bucket refilled at RequestsPerMinute
+// with the configured burst. Rejected requests receive 429 with Retry-After.
+func RateLimit(cfg config.RateLimitConfig, keyFunc RateLimitKeyFunc) gin.HandlerFunc {
+	if keyFunc == nil {
+		keyFunc = ClientIPKey
+	}
+	limiters := newLimiterSet(cfg)
+
+	return func(c *gin.Context) {
+		limiter := limiters.get(keyFunc(c), time.Now())
+		reservation := limiter.Reserve()
+		delay := reservation.Delay()
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(cfg.RequestsPerMinute))
+		if delay > 0 {
+			reservation.Cancel()
+			c.Header("X-RateLimit-Remaining", "0")
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(int(limiter.Tokens())))
+		c.Next()
+	}
+}
+
+// limiterEntry tracks a client's limiter and when it was last used
+type limiterEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// limiterSet holds one limiter per client and evicts idle clients
+type limiterSet struct {
+	mu          sync.Mutex
+	entries     map[string]*limiterEntry
+	limit       rate.Limit
+	burst       int
+	idle        time.Duration
+	lastCleanup time.Time
+}
+
+func newLimiterSet(cfg config.RateLimitConfig) *limiterSet {
+	burst := cfg.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return &limiterSet{
+		entries:     make(map[string]*limiterEntry),
+		limit:       rate.Limit(float64(cfg.RequestsPerMinute) / 60),
+		burst:       burst,
+		idle:        cfg.CleanupInterval,
+		lastCleanup: time.Now(),
+	}
+}
+
+// get returns the limiter for key, sweeping idle entries once per interval
+func (s *limiterSet) get(key string, now time.Time) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.idle > 0 && now.Sub(s.lastCleanup) >= s.idle {
+		for k, e := range s.entries {
+			if now.Sub(e.lastSeen) >= s.idle {
+				delete(s.entries, k)
+			}
+		}
+		s.lastCleanup = now
+	}
+
+	e, ok := s.entries[key]
+	if !ok {
+		e = &limiterEntry{limiter: rate.NewLimiter(s.limit, s.burst)}
+		s.entries[key] = e
+	}
+	e.lastSeen = now
+	return e.limiter
+}
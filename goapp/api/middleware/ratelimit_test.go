@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/config"
+)
+
+func setupRateLimitRouter(cfg config.RateLimitConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(RateLimit(cfg, ClientIPKey))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func requestFrom(router *gin.Engine, remoteAddr string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit(t *testing.T) {
+	router := setupRateLimitRouter(config.RateLimitConfig{
+		Enabled:           true,
+		RequestsPerMinute: 1,
+		Burst:             2,
+		CleanupInterval:   time.Minute,
+	})
+
+	for i := 0; i < 2; i++ {
+		if w := requestFrom(router, "10.0.0.1:1234"); w.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected status %d, got %d", i+1, http.StatusOK, w.Code)
+		}
+	}
+
+	w := requestFrom(router, "10.0.0.1:1234")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header on rejected request")
+	}
+	if w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("Expected X-RateLimit-Remaining '0', got '%s'", w.Header().Get("X-RateLimit-Remaining"))
+	}
+
+	// Other clients have their own bucket
+	if w := requestFrom(router, "10.0.0.2:1234"); w.Code != http.StatusOK {
+		t.Errorf("Expected other client to be allowed, got %d", w.Code)
+	}
+}
+
+func TestLimiterSetEvictsIdleClients(t *testing.T) {
+	set := newLimiterSet(config.RateLimitConfig{RequestsPerMinute: 60, Burst: 1, CleanupInterval: time.Minute})
+
+	now := time.Now()
+	set.get("a", now)
+	set.get("b", now.Add(30*time.Second))
+	set.get("b", now.Add(2*time.Minute))
+
+	if _, ok := set.entries["a"]; ok {
+		t.Error("Expected idle client to be evicted")
+	}
+	if _, ok := set.entries["b"]; !ok {
+		t.Error("Expected active client to be kept")
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RedirectParam redirects the request to its route with the path parameter
+// name set to value, keeping the other parameters and the query, as when a
+// resource moved to a new slug. GET and HEAD requests are redirected with
+// 301 and others with 308, so that clients repeat the method and body.
+func RedirectParam(c *gin.Context, name, value string) {
+	segments := strings.Split(c.FullPath(), "/")
+	for i, segment := range segments {
+		if len(segment) < 2 || (segment[0] != ':' && segment[0] != '*') {
+			continue
+		}
+		param := c.Param(segment[1:])
+		if segment[1:] == name {
+			param = url.PathEscape(value)
+		}
+		segments[i] = strings.TrimPrefix(param, "/")
+	}
+	location := strings.Join(segments, "/")
+	if c.Request.URL.RawQuery != "" {
+		location += "?" + c.Request.URL.RawQuery
+	}
+
+	status := http.StatusPermanentRedirect
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		status = http.StatusMovedPermanently
+	}
+	c.Redirect(status, location)
+	c.Abort()
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedirectParam(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	redirect := func(c *gin.Context) { RedirectParam(c, "id", "new slug") }
+	router.GET("/posts/:id/comments/:comment", redirect)
+	router.PUT("/posts/:id", redirect)
+
+	testCases := []struct {
+		method       string
+		path         string
+		wantStatus   int
+		wantLocation string
+	}{
+		{http.MethodGet, "/posts/old/comments/3?format=html", http.StatusMovedPermanently, "/posts/new%20slug/comments/3?format=html"},
+		{http.MethodPut, "/posts/old", http.StatusPermanentRedirect, "/posts/new%20slug"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.method, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+
+			if w.Code != tc.wantStatus {
+				t.Errorf("Expected status %d, got %d", tc.wantStatus, w.Code)
+			}
+			if got := w.Header().Get("Location"); got != tc.wantLocation {
+				t.Errorf("Expected Location %q, got %q", tc.wantLocation, got)
+			}
+		})
+	}
+}
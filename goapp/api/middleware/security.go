@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/config"
+	"goapp/internal/security"
+)
+
+// Default CSRF names used when the configuration leaves them empty
+const (
+	defaultCSRFCookieName = "_csrf"
+	defaultCSRFHeaderName = "X-CSRF-Token"
+	defaultCSRFFieldName  = "_csrf"
+)
+
+// SecurityHeaders sets browser security headers on every response
+func SecurityHeaders(cfg config.SecurityConfig) gin.HandlerFunc {
+	hsts := ""
+	if cfg.HSTSMaxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d", cfg.HSTSMaxAge)
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+	}
+
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("Cross-Origin-Opener-Policy", "same-origin")
+		if hsts != "" {
+			h.Set("Strict-Transport-Security", hsts)
+		}
+		if cfg.ContentSecurityPolicy != "" {
+			h.Set("Content-Security-Policy", cfg.ContentSecurityPolicy)
+		}
+		if cfg.FrameOptions != "" {
+			h.Set("X-Frame-Options", cfg.FrameOptions)
+		}
+		if cfg.ReferrerPolicy != "" {
+			h.Set("Referrer-Policy", cfg.ReferrerPolicy)
+		}
+		if cfg.PermissionsPolicy != "" {
+			h.Set("Permissions-Policy", cfg.PermissionsPolicy)
+		}
+		c.Next()
+	}
+}
+
+// CSRF implements double-submit cookie protection. Every request gets a token
+// cookie and the token is exposed to templates through the request context;
+// unsafe methods must echo it back in the configured header or form field.
+// Requests carrying an Authorization header are not cookie-authenticated and
+// are therefore exempt, as are JSON requests: browsers cannot send those
+// cross-site without a CORS preflight, which this app does not allow.
+func CSRF(cfg config.SecurityConfig) gin.HandlerFunc {
+	cookieName := valueOrDefault(cfg.CSRFCookieName, defaultCSRFCookieName)
+	headerName := valueOrDefault(cfg.CSRFHeaderName, defaultCSRFHeaderName)
+	fieldName := valueOrDefault(cfg.CSRFFieldName, defaultCSRFFieldName)
+
+	return func(c *gin.Context) {
+		token, err := c.Cookie(cookieName)
+		if err != nil || token == "" {
+			token, err = security.NewCSRFToken()
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to issue CSRF token"})
+				return
+			}
+			http.SetCookie(c.Writer, &http.Cookie{
+				Name:     cookieName,
+				Value:    token,
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   cfg.CookieSecure || c.Request.TLS != nil,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+
+		c.Request = c.Request.WithContext(security.WithCSRFToken(c.Request.Context(), token))
+
+		if isSafeMethod(c.Request.Method) || c.GetHeader("Authorization") != "" || c.ContentType() == "application/json" {
+			c.Next()
+			return
+		}
+
+		submitted := c.GetHeader(headerName)
+		if submitted == "" {
+			submitted = c.PostForm(fieldName)
+		}
+		if !security.ValidCSRFToken(token, submitted) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "invalid or missing CSRF token"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// isSafeMethod reports whether the method is defined as safe by RFC 9110
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func valueOrDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
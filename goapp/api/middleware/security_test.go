@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/config"
+	"goapp/internal/security"
+)
+
+func testSecurityConfig() config.SecurityConfig {
+	return config.SecurityConfig{
+		HSTSMaxAge:            3600,
+		HSTSIncludeSubdomains: true,
+		ContentSecurityPolicy: "default-src 'self'",
+		FrameOptions:          "DENY",
+		ReferrerPolicy:        "no-referrer",
+		CSRFEnabled:           true,
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(SecurityHeaders(testSecurityConfig()))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	router.ServeHTTP(w, req)
+
+	expected := map[string]string{
+		"Strict-Transport-Security": "max-age=3600; includeSubDomains",
+		"Content-Security-Policy":   "default-src 'self'",
+		"X-Frame-Options":           "DENY",
+		"Referrer-Policy":           "no-referrer",
+		"X-Content-Type-Options":    "nosniff",
+	}
+	for header, value := range expected {
+		if got := w.Header().Get(header); got != value {
+			t.Errorf("Expected %s '%s', got '%s'", header, value, got)
+		}
+	}
+	if w.Header().Get("Permissions-Policy") != "" {
+		t.Error("Expected empty Permissions-Policy to be omitted")
+	}
+}
+
+func TestSecurityHeadersHSTSDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := testSecurityConfig()
+	cfg.HSTSMaxAge = 0
+
+	router := gin.New()
+	router.Use(SecurityHeaders(cfg))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("Expected no HSTS header, got '%s'", got)
+	}
+}
+
+func setupCSRFRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(CSRF(testSecurityConfig()))
+	router.GET("/form", func(c *gin.Context) {
+		c.String(http.StatusOK, security.CSRFToken(c.Request.Context()))
+	})
+	router.POST("/submit", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+// issueCSRFToken performs a GET and returns the cookie and token it was issued
+func issueCSRFToken(t *testing.T, router *gin.Engine) (*http.Cookie, string) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/form", nil)
+	router.ServeHTTP(w, req)
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != defaultCSRFCookieName {
+		t.Fatalf("Expected a single %s cookie, got %v", defaultCSRFCookieName, cookies)
+	}
+	if !cookies[0].HttpOnly {
+		t.Error("Expected CSRF cookie to be HttpOnly")
+	}
+	if w.Body.String() != cookies[0].Value {
+		t.Fatalf("Expected context token to match cookie value")
+	}
+	return cookies[0], cookies[0].Value
+}
+
+func TestCSRF(t *testing.T) {
+	router := setupCSRFRouter()
+	cookie, token := issueCSRFToken(t, router)
+
+	t.Run("SafeMethodReusesCookie", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/form", nil)
+		req.AddCookie(cookie)
+		router.ServeHTTP(w, req)
+
+		if len(w.Result().Cookies()) != 0 {
+			t.Error("Expected existing CSRF cookie to be reused")
+		}
+		if w.Body.String() != token {
+			t.Error("Expected context token to match existing cookie")
+		}
+	})
+
+	t.Run("MissingToken", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/submit", nil)
+		req.AddCookie(cookie)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d", http.StatusForbidden, w.Code)
+		}
+	})
+
+	t.Run("WrongToken", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/submit", nil)
+		req.AddCookie(cookie)
+		req.Header.Set(defaultCSRFHeaderName, "not-the-token")
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d", http.StatusForbidden, w.Code)
+		}
+	})
+
+	t.Run("HeaderToken", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/submit", nil)
+		req.AddCookie(cookie)
+		req.Header.Set(defaultCSRFHeaderName, token)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("FormToken", func(t *testing.T) {
+		form := url.Values{defaultCSRFFieldName: {token}}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/submit", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(cookie)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("AuthorizationHeaderExempt", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/submit", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("JSONRequestExempt", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/submit", strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"goapp/internal/auth"
+	"goapp/internal/logging"
+	"goapp/internal/models"
+)
+
+// currentUserKey is the gin context key holding the signed-in *models.User
+const currentUserKey = "auth.user"
+
+// currentSessionKey is the gin context key holding the *models.Session of the request
+const currentSessionKey = "auth.session"
+
+// Session authenticates requests carrying a session cookie. The signed-in
+// user is exposed to handlers through CurrentUser and to templates through
+// auth.UserFromContext. Unknown or expired cookies are cleared and the
+// request continues anonymously.
+func Session(store auth.SessionStore, cookieName string, cookieSecure bool, logger logging.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := c.Cookie(cookieName)
+		if err != nil || token == "" {
+			c.Next()
+			return
+		}
+
+		session, err := store.Lookup(c.Request.Context(), token)
+		switch {
+		case errors.Is(err, auth.ErrSessionNotFound):
+			http.SetCookie(c.Writer, auth.ExpiredSessionCookie(cookieName, cookieSecure || c.Request.TLS != nil))
+		case err != nil:
+			logger.Error("Failed to load session", zap.Error(err))
+		default:
+			c.Set(currentSessionKey, session)
+			SetCurrentUser(c, &session.User)
+		}
+
+		c.Next()
+	}
+}
+
+// SetCurrentUser makes user the signed-in user for the rest of the request
+func SetCurrentUser(c *gin.Context, user *models.User) {
+	c.Set(currentUserKey, user)
+	c.Request = c.Request.WithContext(auth.WithUser(c.Request.Context(), user))
+}
+
+// CurrentUser returns the signed-in user, or nil for anonymous requests
+func CurrentUser(c *gin.Context) *models.User {
+	if v, ok := c.Get(currentUserKey); ok {
+		if user, ok := v.(*models.User); ok {
+			return user
+		}
+	}
+	return nil
+}
+
+// CurrentSession returns the session the request was signed in with, or
+// nil for anonymous requests and other kinds of authentication
+func CurrentSession(c *gin.Context) *models.Session {
+	if v, ok := c.Get(currentSessionKey); ok {
+		if session, ok := v.(*models.Session); ok {
+			return session
+		}
+	}
+	return nil
+}
+
+// RequireUser rejects anonymous requests. API clients get 401 JSON, HTMX
+// requests are redirected through HX-Redirect and browsers are sent to
+// loginPath with the original URL in the next query parameter.
+func RequireUser(loginPath string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if CurrentUser(c) != nil {
+			c.Next()
+			return
+		}
+
+		if wantsJSON(c) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		target := loginPath + "?next=" + url.QueryEscape(c.Request.URL.RequestURI())
+		if c.GetHeader("HX-Request") == "true" {
+			// Return to the page the partial was loaded from, not the partial itself
+			if current, err := url.Parse(c.GetHeader("HX-Current-URL")); err == nil && current.Path != "" {
+				target = loginPath + "?next=" + url.QueryEscape(current.RequestURI())
+			}
+			c.Header("HX-Redirect", target)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Redirect(http.StatusSeeOther, target)
+		c.Abort()
+	}
+}
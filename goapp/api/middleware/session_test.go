@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/auth"
+	"goapp/internal/config"
+	"goapp/internal/logging"
+	"goapp/internal/models"
+)
+
+// fakeSessionStore serves a single session for the token "valid"
+type fakeSessionStore struct {
+	auth.SessionStore
+	err error
+}
+
+func (s *fakeSessionStore) Lookup(ctx context.Context, token string) (*models.Session, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if token != "valid" {
+		return nil, auth.ErrSessionNotFound
+	}
+	return &models.Session{User: models.User{Username: "jane"}}, nil
+}
+
+func setupSessionRouter(t *testing.T, store auth.SessionStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	logger, err := logging.New(config.LoggerConfig{Environment: "test", WriteStdout: true})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(Session(store, "session", false, logger))
+	router.GET("/whoami", func(c *gin.Context) {
+		name := "anonymous"
+		if user := CurrentUser(c); user != nil {
+			name = user.Username
+		}
+		if user := auth.UserFromContext(c.Request.Context()); user != nil && user.Username != name {
+			name = "mismatch"
+		}
+		c.String(http.StatusOK, name)
+	})
+	router.GET("/private", RequireUser("/login"), func(c *gin.Context) { c.String(http.StatusOK, "secret") })
+	router.GET("/api/v1/private", RequireUser("/login"), func(c *gin.Context) { c.String(http.StatusOK, "secret") })
+	return router
+}
+
+func TestSession(t *testing.T) {
+	router := setupSessionRouter(t, &fakeSessionStore{})
+
+	tests := []struct {
+		name        string
+		cookie      string
+		wantBody    string
+		wantCleared bool
+	}{
+		{"NoCookie", "", "anonymous", false},
+		{"ValidSession", "valid", "jane", false},
+		{"UnknownSession", "stale", "anonymous", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/whoami", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "session", Value: tt.cookie})
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("Expected body %q, got %q", tt.wantBody, w.Body.String())
+			}
+			cleared := false
+			for _, c := range w.Result().Cookies() {
+				if c.Name == "session" && c.MaxAge < 0 {
+					cleared = true
+				}
+			}
+			if cleared != tt.wantCleared {
+				t.Errorf("Expected cookie cleared=%v, got %v", tt.wantCleared, cleared)
+			}
+		})
+	}
+}
+
+func TestSessionStoreError(t *testing.T) {
+	router := setupSessionRouter(t, &fakeSessionStore{err: errors.New("database down")})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/whoami", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "valid"})
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Body.String() != "anonymous" {
+		t.Errorf("Expected anonymous request on store error, got %d %q", w.Code, w.Body.String())
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Error("Expected session cookie to be kept on store error")
+	}
+}
+
+func TestRequireUser(t *testing.T) {
+	router := setupSessionRouter(t, &fakeSessionStore{})
+
+	tests := []struct {
+		name       string
+		path       string
+		cookie     string
+		headers    map[string]string
+		wantStatus int
+		wantHeader string
+		wantValue  string
+	}{
+		{"SignedIn", "/private", "valid", nil, http.StatusOK, "", ""},
+		{"BrowserRedirect", "/private?tab=1", "", nil, http.StatusSeeOther, "Location", "/login?next=%2Fprivate%3Ftab%3D1"},
+		{"HTMXRedirect", "/private", "", map[string]string{"HX-Request": "true", "HX-Current-URL": "http://localhost/posts"}, http.StatusUnauthorized, "HX-Redirect", "/login?next=%2Fposts"},
+		{"API", "/api/v1/private", "", nil, http.StatusUnauthorized, "Content-Type", "application/json; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "session", Value: tt.cookie})
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantHeader != "" && w.Header().Get(tt.wantHeader) != tt.wantValue {
+				t.Errorf("Expected %s %q, got %q", tt.wantHeader, tt.wantValue, w.Header().Get(tt.wantHeader))
+			}
+		})
+	}
+}
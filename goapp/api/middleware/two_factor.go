@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/models"
+)
+
+// RequireTwoFactorEnrollment sends signed-in users whose roles require
+// two-factor authentication to setupPath until they have enrolled. Paths
+// starting with setupPath or one of the exempt prefixes stay reachable, so
+// the user can enroll or sign out. API key requests are not affected.
+func RequireTwoFactorEnrollment(required func(*models.User) bool, setupPath string, exempt ...string) gin.HandlerFunc {
+	allowed := append([]string{setupPath}, exempt...)
+	return func(c *gin.Context) {
+		user := CurrentUser(c)
+		if user == nil || user.TwoFactorEnabled || CurrentAPIKey(c) != nil || hasPathPrefix(c.Request.URL.Path, allowed) || !required(user) {
+			c.Next()
+			return
+		}
+
+		switch {
+		case wantsJSON(c):
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "two-factor authentication must be enabled for this account"})
+		case c.GetHeader("HX-Request") == "true":
+			c.Header("HX-Redirect", setupPath)
+			c.AbortWithStatus(http.StatusForbidden)
+		default:
+			c.Redirect(http.StatusSeeOther, setupPath)
+			c.Abort()
+		}
+	}
+}
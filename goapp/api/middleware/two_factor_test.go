@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/models"
+)
+
+func TestRequireTwoFactorEnrollment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		user       *models.User
+		path       string
+		wantStatus int
+	}{
+		{"Anonymous", nil, "/posts", http.StatusOK},
+		{"NotRequired", &models.User{Username: "jane"}, "/posts", http.StatusOK},
+		{"Enrolled", &models.User{Username: "admin", TwoFactorEnabled: true}, "/posts", http.StatusOK},
+		{"NotEnrolled", &models.User{Username: "admin"}, "/posts", http.StatusSeeOther},
+		{"SetupPage", &models.User{Username: "admin"}, "/settings/two-factor/enroll", http.StatusOK},
+		{"Logout", &models.User{Username: "admin"}, "/logout", http.StatusOK},
+		{"API", &models.User{Username: "admin"}, "/api/v1/posts", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if tt.user != nil {
+					c.Set(currentUserKey, tt.user)
+				}
+			})
+			router.Use(RequireTwoFactorEnrollment(func(u *models.User) bool { return u.Username == "admin" }, "/settings/two-factor", "/logout"))
+			router.NoRoute(func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus == http.StatusSeeOther && w.Header().Get("Location") != "/settings/two-factor" {
+				t.Errorf("Expected redirect to /settings/two-factor, got %q", w.Header().Get("Location"))
+			}
+		})
+	}
+}
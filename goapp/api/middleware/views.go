@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/models"
+	"goapp/internal/views"
+)
+
+// CountView records a view of post by the client of c with counter, which
+// is nil when views are not counted. Only published posts are counted, and
+// not when their authors read them. Signed-in viewers are told apart by
+// user and others by IP address.
+func CountView(c *gin.Context, counter *views.Counter, post *models.Post) {
+	if counter == nil || !post.Published {
+		return
+	}
+	viewer := "ip:" + c.ClientIP()
+	if user := CurrentUser(c); user != nil {
+		if user.ID == post.UserID {
+			return
+		}
+		viewer = "user:" + strconv.FormatUint(uint64(user.ID), 10)
+	}
+	counter.Record(post.ID, viewer, c.Request.UserAgent())
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goapp/internal/config"
+	"goapp/internal/logging"
+	"goapp/internal/models"
+	"goapp/internal/views"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCountView(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Post{}, &models.PostDailyView{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	author := &models.User{Email: "jane@example.com", Username: "jane", PasswordHash: "hash", Active: true}
+	db.Create(author)
+	published := &models.Post{Title: "Published", Slug: "published", Published: true, UserID: author.ID}
+	draft := &models.Post{Title: "Draft", Slug: "draft", UserID: author.ID}
+	db.Create(published)
+	db.Create(draft)
+
+	logger, err := logging.New(config.LoggerConfig{Environment: "test", WriteStdout: true})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	counter := views.NewCounter(db, config.ViewsConfig{UniqueWindow: time.Hour}, logger)
+
+	router := gin.New()
+	router.GET("/:slug", func(c *gin.Context) {
+		if c.GetHeader("X-User") == "jane" {
+			SetCurrentUser(c, author)
+		}
+		post := published
+		if c.Param("slug") == "draft" {
+			post = draft
+		}
+		CountView(c, counter, post)
+		CountView(c, nil, post)
+	})
+	for _, tc := range []struct{ path, user string }{
+		{"/published", ""},
+		{"/published", "jane"},
+		{"/draft", ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set("User-Agent", "Mozilla/5.0")
+		req.Header.Set("X-User", tc.user)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if err := counter.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	db.First(published, published.ID)
+	db.First(draft, draft.ID)
+	if published.ViewCount != 1 || draft.ViewCount != 0 {
+		t.Errorf("Expected one view of the published post only, got %d and %d", published.ViewCount, draft.ViewCount)
+	}
+}
@@ -0,0 +1,48 @@
+package routes
+
+import (
+	"time"
+
+	"goapp/api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Module registers a group of related routes on an API version
+type Module interface {
+	RegisterRoutes(rg *gin.RouterGroup)
+}
+
+// ModuleFunc adapts a plain function to the Module interface
+type ModuleFunc func(rg *gin.RouterGroup)
+
+// RegisterRoutes implements Module
+func (f ModuleFunc) RegisterRoutes(rg *gin.RouterGroup) {
+	f(rg)
+}
+
+// APIVersion describes a versioned JSON API mounted at /api/<Name>
+type APIVersion struct {
+	Name       string
+	Middleware []gin.HandlerFunc
+	Modules    []Module
+
+	// Deprecation metadata; a zero DeprecatedAt means the version is current
+	DeprecatedAt time.Time
+	Sunset       time.Time
+	Successor    string
+}
+
+// RegisterAPIVersion mounts an API version and its modules on the router
+func RegisterAPIVersion(router *gin.Engine, version APIVersion) *gin.RouterGroup {
+	group := router.Group("/api/" + version.Name)
+	if !version.DeprecatedAt.IsZero() {
+		group.Use(middleware.Deprecation(version.DeprecatedAt, version.Sunset, version.Successor))
+	}
+	group.Use(version.Middleware...)
+
+	for _, module := range version.Modules {
+		module.RegisterRoutes(group)
+	}
+	return group
+}
@@ -0,0 +1,68 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegisterAPIVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	chainRan := false
+	RegisterAPIVersion(router, APIVersion{
+		Name: "v0",
+		Middleware: []gin.HandlerFunc{func(c *gin.Context) {
+			chainRan = true
+			c.Next()
+		}},
+		Modules: []Module{
+			ModuleFunc(func(rg *gin.RouterGroup) {
+				rg.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+			}),
+		},
+		DeprecatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:       time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Successor:    "/api/v1",
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v0/ping", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Body.String() != "pong" {
+		t.Fatalf("Expected module route to respond 'pong', got %d '%s'", w.Code, w.Body.String())
+	}
+	if !chainRan {
+		t.Error("Expected version middleware to run")
+	}
+	if w.Header().Get("Deprecation") == "" || w.Header().Get("Sunset") == "" {
+		t.Error("Expected deprecated version to send Deprecation and Sunset headers")
+	}
+}
+
+func TestCurrentAPIVersionNotDeprecated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	RegisterAPIVersion(router, APIVersion{
+		Name: "v1",
+		Modules: []Module{
+			ModuleFunc(func(rg *gin.RouterGroup) {
+				rg.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+			}),
+		},
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/ping", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Header().Get("Deprecation") != "" {
+		t.Error("Expected no Deprecation header on the current version")
+	}
+}
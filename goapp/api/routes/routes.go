@@ -1,8 +1,12 @@
 package routes
 
 import (
+	"net/http"
+	"strings"
+
 	"goapp/api/handlers"
 	"goapp/api/handlers/web"
+	"goapp/api/middleware"
 	"goapp/internal/container"
 
 	_ "goapp/docs" // Import generated docs
@@ -10,6 +14,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
 	swaggerFiles "github.com/swaggo/files"
 )
@@ -17,6 +22,41 @@ import (
 // SetupRouter sets up the Gin router with all the routes
 func SetupRouter(container *container.Container) *gin.Engine {
 	router := gin.Default()
+
+	// Trace every request; middleware only applies to routes added after it
+	if container.Config.Observability.Enabled {
+		router.Use(otelgin.Middleware(container.Config.Observability.ServiceName))
+	}
+
+	if container.Config.LoadShed.Enabled {
+		router.Use(middleware.LoadShed(container.Config.LoadShed))
+	}
+
+	// Security middleware
+	router.Use(middleware.SecurityHeaders(container.Config.Security))
+	if container.Config.Security.CSRFEnabled {
+		router.Use(middleware.CSRF(container.Config.Security))
+	}
+	if container.Sessions != nil {
+		cookieSecure := container.Config.Security.CookieSecure
+		router.Use(middleware.Session(container.Sessions, container.Config.Auth.SessionCookieName, cookieSecure, container.Logger))
+	}
+	if container.APIKeys != nil {
+		router.Use(middleware.APIKeyAuth(container.APIKeys, container.Logger))
+	}
+	if container.Policy != nil {
+		router.Use(middleware.Policies(container.Policy))
+	}
+	if container.TwoFactor != nil {
+		router.Use(middleware.RequireTwoFactorEnrollment(container.TwoFactor.Required, "/settings/two-factor", "/logout", "/static/", "/health"))
+	}
+	if container.Config.Compression.Enabled {
+		router.Use(middleware.Compress(container.Config.Compression))
+	}
+	if container.Maintenance != nil {
+		maintenancePage := web.NewMaintenanceHandler(container)
+		router.Use(middleware.Maintenance(container.Maintenance, container.Config.Maintenance.ExemptPaths, maintenancePage.Render))
+	}
 	
 	// Static files
 	router.Static("/static", "./web/static")
@@ -38,13 +78,129 @@ func SetupRouter(container *container.Container) *gin.Engine {
 
 	// Health check endpoint
 	router.GET("/health", h.HealthCheckHandler)
+
+	// Public keys for verifying access tokens
+	if container.JWTKeys != nil {
+		router.GET("/.well-known/jwks.json", h.JWKSHandler)
+	}
+
+	// Versioned JSON API
+	apiV1Group := RegisterAPIVersion(router, apiV1(container, router))
+	apiV1Group.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.NewHandler(), ginSwagger.InstanceName("v1")))
+	router.NoRoute(func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			middleware.JSONNotFound(c)
+			return
+		}
+		c.String(http.StatusNotFound, "404 page not found")
+	})
 	
 	// Web routes
-	router.GET("/", homeHandler.Index)
-	router.GET("/posts", postsHandler.Index)
+	router.GET("/", middleware.ETag(), homeHandler.Index)
+	router.GET("/posts", middleware.ETag(), postsHandler.Index)
+	if container.Posts != nil {
+		router.GET("/posts/:id", middleware.ETag(), postsHandler.Show)
+		router.POST("/posts/:id/status", postsHandler.SetStatus)
+		router.GET("/posts/:id/revisions", postsHandler.Revisions)
+		router.GET("/posts/:id/revisions/:number", postsHandler.Revision)
+		router.POST("/posts/:id/revisions/:number/restore", postsHandler.Restore)
+	}
+	if container.Posts != nil && container.Comments != nil {
+		commentsHandler := web.NewCommentsHandler(container)
+		router.GET("/posts/:id/comments", commentsHandler.Section)
+		router.POST("/posts/:id/comments", commentsHandler.Create)
+		router.POST("/comments/:id", commentsHandler.Update)
+		router.POST("/comments/:id/delete", commentsHandler.Delete)
+	}
+	if container.Posts != nil && container.Tags != nil {
+		tagsHandler := web.NewTagsHandler(container)
+		router.GET("/tags", middleware.ETag(), tagsHandler.Index)
+		router.GET("/tags/:slug", middleware.ETag(), tagsHandler.Show)
+		router.POST("/posts/:id/tags", tagsHandler.Attach)
+		router.POST("/posts/:id/tags/:slug/detach", tagsHandler.Detach)
+		router.GET("/partials/tags/autocomplete", tagsHandler.Autocomplete)
+	}
+	if container.Search != nil {
+		router.GET("/search", web.NewSearchHandler(container).Index)
+	}
+	
+	// Authentication routes
+	if container.Auth != nil && container.Sessions != nil {
+		authHandler := web.NewAuthHandler(container)
+		router.GET("/login", authHandler.LoginPage)
+		router.POST("/login", authHandler.Login)
+		router.GET("/register", authHandler.RegisterPage)
+		router.POST("/register", authHandler.Register)
+		router.POST("/logout", authHandler.Logout)
+	}
+	if container.TwoFactor != nil && container.Accounts != nil && container.Sessions != nil {
+		twoFactorHandler := web.NewTwoFactorHandler(container)
+		router.GET("/login/2fa", twoFactorHandler.ChallengePage)
+		router.POST("/login/2fa", twoFactorHandler.Challenge)
+
+		settings := router.Group("/settings/two-factor", middleware.RequireUser("/login"))
+		settings.GET("", twoFactorHandler.Settings)
+		settings.POST("/enroll", twoFactorHandler.Enroll)
+		settings.POST("/confirm", twoFactorHandler.Confirm)
+		settings.POST("/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+		settings.POST("/disable", twoFactorHandler.Disable)
+	}
+	if container.Accounts != nil {
+		accountHandler := web.NewAccountHandler(container)
+		router.GET("/verify-email", accountHandler.VerifyEmail)
+		router.GET("/confirm-email", accountHandler.ConfirmEmail)
+		router.POST("/verify-email/resend", middleware.RequireUser("/login"), accountHandler.ResendVerification)
+		router.GET("/forgot-password", accountHandler.ForgotPasswordPage)
+		router.POST("/forgot-password", accountHandler.ForgotPassword)
+		router.GET("/reset-password", accountHandler.ResetPasswordPage)
+		router.POST("/reset-password", accountHandler.ResetPassword)
+	}
+	if container.OIDC != nil && container.Identities != nil && container.Sessions != nil {
+		oidcHandler := web.NewOIDCHandler(container)
+		router.GET("/auth/oidc/login", oidcHandler.Login)
+		router.GET("/auth/oidc/callback", oidcHandler.Callback)
+	}
+	
+	// Session management
+	if container.Sessions != nil {
+		sessionsHandler := web.NewSessionsHandler(container)
+		sessions := router.Group("/settings/sessions", middleware.RequireUser("/login"))
+		sessions.GET("", sessionsHandler.Index)
+		sessions.POST("/:id/revoke", sessionsHandler.Revoke)
+		sessions.POST("/revoke-others", sessionsHandler.RevokeOthers)
+	}
+	
+	// Profile settings and user administration
+	if container.Users != nil {
+		settingsHandler := web.NewSettingsHandler(container)
+		settings := router.Group("/settings", middleware.RequireUser("/login"))
+		settings.GET("", settingsHandler.Index)
+		settings.POST("", settingsHandler.Update)
+	}
+	if container.Users != nil && container.Roles != nil {
+		usersHandler := web.NewUsersHandler(container)
+		admin := router.Group("/users", middleware.RequireUser("/login"))
+		admin.GET("", usersHandler.Index)
+		admin.GET("/:id", usersHandler.Show)
+		admin.POST("/:id/deactivate", usersHandler.Deactivate)
+		admin.POST("/:id/reactivate", usersHandler.Reactivate)
+		admin.POST("/:id/delete", usersHandler.Delete)
+		admin.POST("/:id/restore", usersHandler.Restore)
+		admin.POST("/:id/roles", usersHandler.GrantRole)
+		admin.POST("/:id/roles/:role/revoke", usersHandler.RevokeRole)
+	}
+	
+	// API key management
+	if container.APIKeys != nil {
+		apiKeysHandler := web.NewAPIKeysHandler(container)
+		settings := router.Group("/settings/api-keys", middleware.RequireUser("/login"))
+		settings.GET("", apiKeysHandler.Index)
+		settings.POST("", apiKeysHandler.Create)
+		settings.POST("/:id/revoke", apiKeysHandler.Revoke)
+	}
 	
 	// Partial routes for HTMX
-	partials := router.Group("/partials")
+	partials := router.Group("/partials", middleware.ETag())
 	{
 		partials.GET("/activity-feed", partialsHandler.ActivityFeed)
 		partials.GET("/notifications", partialsHandler.Notifications)
@@ -0,0 +1,49 @@
+package routes
+
+import (
+	"net/http"
+
+	v1 "goapp/api/handlers/v1"
+	"goapp/api/middleware"
+	"goapp/internal/container"
+	"goapp/internal/idempotency"
+	"goapp/internal/tokens"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiV1 assembles the middleware chain and modules of the v1 JSON API.
+// Batch sub-requests are sent back through router.
+func apiV1(container *container.Container, router http.Handler) APIVersion {
+	chain := []gin.HandlerFunc{middleware.JSONErrors()}
+	if container.Config.RateLimit.Enabled {
+		chain = append(chain, middleware.RateLimit(container.Config.RateLimit, middleware.ClientIPKey))
+	}
+	if container.JWTKeys != nil {
+		chain = append(chain, middleware.JWTAuth(tokens.NewVerifier(container.JWTKeys, container.Config.JWT), container.Tokens, container.Logger))
+	}
+	// Idempotency keys belong to the caller, so they are looked up after authentication
+	if container.Config.Idempotency.Enabled && container.Database != nil && container.Database.DB() != nil {
+		store := idempotency.NewStore(container.Database.DB(), container.Config.Idempotency.TTL)
+		chain = append(chain, middleware.Idempotency(store, container.Logger))
+	}
+
+	return APIVersion{
+		Name:       v1.Version,
+		Middleware: chain,
+		Modules: []Module{
+			v1.New(container),
+			v1.NewMaintenanceHandler(container),
+			v1.NewAuthHandler(container),
+			v1.NewAPIKeyHandler(container),
+			v1.NewPostHandler(container),
+			v1.NewCommentHandler(container),
+			v1.NewTagHandler(container),
+			v1.NewSearchHandler(container),
+			v1.NewUserHandler(container),
+			v1.NewBulkHandler(container),
+			v1.NewBatchHandler(container, router),
+			v1.NewTransferHandler(container),
+		},
+	}
+}
@@ -1,6 +1,7 @@
 package docs
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/swaggo/swag"
@@ -94,4 +95,24 @@ func findSubstring(s, substr string) bool {
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
+func TestSwaggerInfoV1(t *testing.T) {
+	if SwaggerInfov1 == nil {
+		t.Fatal("SwaggerInfov1 should not be nil")
+	}
+
+	if SwaggerInfov1.InfoInstanceName != "v1" {
+		t.Errorf("Expected instance name 'v1', got '%s'", SwaggerInfov1.InfoInstanceName)
+	}
+
+	doc, err := swag.ReadDoc("v1")
+	if err != nil {
+		t.Fatalf("Failed to read v1 swagger doc: %v", err)
+	}
+	if !strings.Contains(doc, "/api/v1/status") {
+		t.Error("Expected v1 doc to contain /api/v1/status")
+	}
+	if strings.Contains(doc, `"/health"`) {
+		t.Error("Expected v1 doc to exclude unversioned routes")
+	}
+}
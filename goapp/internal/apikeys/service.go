@@ -0,0 +1,323 @@
+// Package apikeys issues and verifies API keys, the credentials other
+// services use to call the API machine-to-machine. A key acts as its owner,
+// a user or service account, limited to the key's scopes.
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"goapp/internal/auth"
+	"goapp/internal/config"
+	"goapp/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	keyIDBytes     = 6
+	keySecretBytes = 32
+	// touchInterval limits how often LastUsedAt is written for a busy key
+	touchInterval = time.Minute
+	// serviceAccountDomain is the email domain of service account users;
+	// .invalid is reserved and never receives mail (RFC 2606)
+	serviceAccountDomain = "service-accounts.invalid"
+)
+
+var (
+	// ErrInvalidKey is returned for unknown, malformed, expired or revoked keys
+	ErrInvalidKey = errors.New("invalid API key")
+	// ErrKeyNotFound is returned when an API key ID does not exist
+	ErrKeyNotFound = errors.New("API key not found")
+
+	scopePattern = regexp.MustCompile(`^(\*|[a-z_]+:(\*|[a-z_]+))$`)
+	namePattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{2,49}$`)
+)
+
+// CreateInput describes a new API key
+type CreateInput struct {
+	Name      string
+	UserID    uint
+	Scopes    []string      // permission names such as "posts:read", "posts:*" or "*"
+	ExpiresIn time.Duration // zero uses the configured default
+	// NotAfter, when set, caps the expiry, such as at that of the key
+	// creating this one
+	NotAfter *time.Time
+}
+
+// Service manages API keys
+type Service interface {
+	// Create issues a key and returns it in full; only its hash is kept, so
+	// it cannot be shown again
+	Create(ctx context.Context, input CreateInput) (string, *models.APIKey, error)
+	// Authenticate returns the active key for token with its User and the
+	// user's roles loaded, and records its use
+	Authenticate(ctx context.Context, token, clientIP string) (*models.APIKey, error)
+	// List returns keys with their User loaded, newest first; userID 0 lists
+	// the keys of all users
+	List(ctx context.Context, userID uint) ([]models.APIKey, error)
+	// Get returns the key with the given ID
+	Get(ctx context.Context, id uint) (*models.APIKey, error)
+	// Revoke disables a key immediately
+	Revoke(ctx context.Context, id uint) error
+	// ServiceAccount returns the service account called name, creating it if needed
+	ServiceAccount(ctx context.Context, name string) (*models.User, error)
+	// LooksLikeKey reports whether token is in the format of this service's keys
+	LooksLikeKey(token string) bool
+}
+
+// service implements Service on the api_keys table
+type service struct {
+	db  *gorm.DB
+	cfg config.APIKeyConfig
+	now func() time.Time
+}
+
+// NewService creates an API key Service
+func NewService(db *gorm.DB, cfg config.APIKeyConfig) Service {
+	return &service{db: db, cfg: cfg, now: time.Now}
+}
+
+// Create implements Service
+func (s *service) Create(ctx context.Context, input CreateInput) (string, *models.APIKey, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	scopes, fields := s.validate(input)
+	if len(fields) > 0 {
+		return "", nil, &auth.ValidationError{Fields: fields}
+	}
+
+	keyID, err := randomString(keyIDBytes, hex.EncodeToString)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomString(keySecretBytes, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return "", nil, err
+	}
+	token := s.cfg.TokenPrefix + "_" + keyID + "_" + secret
+
+	key := &models.APIKey{
+		Name:    input.Name,
+		KeyID:   keyID,
+		KeyHash: hashKey(token),
+		UserID:  input.UserID,
+		Scopes:  strings.Join(scopes, " "),
+	}
+	if expiresIn := s.expiresIn(input.ExpiresIn); expiresIn > 0 {
+		expiresAt := s.now().Add(expiresIn)
+		key.ExpiresAt = &expiresAt
+	}
+	if input.NotAfter != nil && (key.ExpiresAt == nil || key.ExpiresAt.After(*input.NotAfter)) {
+		notAfter := *input.NotAfter
+		key.ExpiresAt = &notAfter
+	}
+	if err := s.db.WithContext(ctx).Create(key).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+	return token, key, nil
+}
+
+// Authenticate implements Service
+func (s *service) Authenticate(ctx context.Context, token, clientIP string) (*models.APIKey, error) {
+	keyID, ok := s.parse(token)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	var key models.APIKey
+	err := s.db.WithContext(ctx).Preload("User.Roles.Permissions").Where("key_id = ?", keyID).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load API key: %w", err)
+	}
+
+	now := s.now()
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hashKey(token))) != 1 ||
+		!key.Active(now) || key.User.ID == 0 || !key.User.Active {
+		return nil, ErrInvalidKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= touchInterval || key.LastUsedIP != clientIP {
+		key.LastUsedAt = &now
+		key.LastUsedIP = truncate(clientIP, 45)
+		err := s.db.WithContext(ctx).Model(&key).UpdateColumns(map[string]interface{}{
+			"last_used_at": key.LastUsedAt,
+			"last_used_ip": key.LastUsedIP,
+		}).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to record API key use: %w", err)
+		}
+	}
+	return &key, nil
+}
+
+// List implements Service
+func (s *service) List(ctx context.Context, userID uint) ([]models.APIKey, error) {
+	query := s.db.WithContext(ctx).Preload("User").Order("created_at DESC, id DESC")
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var keys []models.APIKey
+	if err := query.Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Get implements Service
+func (s *service) Get(ctx context.Context, id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	err := s.db.WithContext(ctx).Preload("User").First(&key, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load API key: %w", err)
+	}
+	return &key, nil
+}
+
+// Revoke implements Service
+func (s *service) Revoke(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		UpdateColumn("revoked_at", s.now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke API key: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		// Revoking an already revoked key is not an error
+		if _, err := s.Get(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServiceAccount implements Service
+func (s *service) ServiceAccount(ctx context.Context, name string) (*models.User, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !namePattern.MatchString(name) {
+		return nil, &auth.ValidationError{Fields: map[string]string{"service_account": "must be 3-50 lowercase letters, digits, '.', '_' or '-'"}}
+	}
+
+	var user models.User
+	err := s.db.WithContext(ctx).Where("username = ?", name).First(&user).Error
+	if err == nil {
+		if !user.ServiceAccount {
+			return nil, &auth.ValidationError{Fields: map[string]string{"service_account": "is the name of a user account"}}
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to load service account: %w", err)
+	}
+
+	user = models.User{
+		Email:          name + "@" + serviceAccountDomain,
+		Username:       name,
+		PasswordHash:   auth.NoPassword,
+		Active:         true,
+		ServiceAccount: true,
+	}
+	if err := s.db.WithContext(ctx).Create(&user).Error; err != nil {
+		return nil, fmt.Errorf("failed to create service account: %w", err)
+	}
+	return &user, nil
+}
+
+// LooksLikeKey implements Service
+func (s *service) LooksLikeKey(token string) bool {
+	_, ok := s.parse(token)
+	return ok
+}
+
+// parse returns the key ID of a token in the <prefix>_<id>_<secret> format
+func (s *service) parse(token string) (string, bool) {
+	rest, ok := strings.CutPrefix(token, s.cfg.TokenPrefix+"_")
+	if !ok {
+		return "", false
+	}
+	keyID, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(keyID) != 2*keyIDBytes || secret == "" {
+		return "", false
+	}
+	return keyID, true
+}
+
+func (s *service) validate(input CreateInput) ([]string, map[string]string) {
+	fields := map[string]string{}
+	if input.Name == "" || len(input.Name) > 100 {
+		fields["name"] = "must be 1-100 characters"
+	}
+	if input.UserID == 0 {
+		fields["user_id"] = "is required"
+	}
+	if input.ExpiresIn < 0 || (s.cfg.MaxExpiration > 0 && s.expiresIn(input.ExpiresIn) > s.cfg.MaxExpiration) {
+		fields["expires_in"] = fmt.Sprintf("must be between 0 and %s", s.cfg.MaxExpiration)
+	}
+
+	seen := map[string]bool{}
+	var scopes []string
+	for _, scope := range input.Scopes {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || seen[scope] {
+			continue
+		}
+		if !scopePattern.MatchString(scope) {
+			fields["scopes"] = fmt.Sprintf("%q is not a valid scope", scope)
+			continue
+		}
+		seen[scope] = true
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 && fields["scopes"] == "" {
+		fields["scopes"] = "at least one scope is required"
+	}
+	sort.Strings(scopes)
+	return scopes, fields
+}
+
+// expiresIn returns the lifetime of a new key; zero means it never expires
+func (s *service) expiresIn(requested time.Duration) time.Duration {
+	if requested == 0 {
+		requested = s.cfg.DefaultExpiration
+	}
+	if requested == 0 {
+		requested = s.cfg.MaxExpiration
+	}
+	return requested
+}
+
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	return encode(b), nil
+}
+
+// hashKey returns the hex SHA-256 of an API key as stored in the database
+func hashKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
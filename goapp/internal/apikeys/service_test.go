@@ -0,0 +1,182 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"goapp/internal/auth"
+	"goapp/internal/config"
+	"goapp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestService(t *testing.T) (*service, *gorm.DB, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Permission{}, &models.Role{}, &models.User{}, &models.APIKey{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	user := &models.User{Email: "jane@example.com", Username: "jane", PasswordHash: "hash", Active: true}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	cfg := config.APIKeyConfig{TokenPrefix: "goapp", DefaultExpiration: 24 * time.Hour, MaxExpiration: 48 * time.Hour}
+	return NewService(db, cfg).(*service), db, user
+}
+
+func TestCreateAndAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	s, db, user := setupTestService(t)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	token, key, err := s.Create(ctx, CreateInput{Name: "billing", UserID: user.ID, Scopes: []string{"posts:read", " comments:* ", "posts:read"}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !strings.HasPrefix(token, "goapp_"+key.KeyID+"_") {
+		t.Errorf("Expected token to start with prefix and key ID, got %q", token)
+	}
+	if key.KeyHash == token || strings.Contains(key.KeyHash, token) {
+		t.Error("Expected only the key hash to be stored")
+	}
+	if key.Scopes != "comments:* posts:read" {
+		t.Errorf("Expected normalized scopes, got %q", key.Scopes)
+	}
+	if key.ExpiresAt == nil || !key.ExpiresAt.Equal(now.Add(24*time.Hour)) {
+		t.Errorf("Expected default expiry, got %v", key.ExpiresAt)
+	}
+	if !s.LooksLikeKey(token) || s.LooksLikeKey("eyJhbGciOi.x.y") {
+		t.Error("Expected LooksLikeKey to recognise API keys only")
+	}
+
+	got, err := s.Authenticate(ctx, token, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if got.User.Username != "jane" {
+		t.Errorf("Expected key owner jane, got %q", got.User.Username)
+	}
+
+	var stored models.APIKey
+	db.First(&stored, key.ID)
+	if stored.LastUsedAt == nil || stored.LastUsedIP != "10.0.0.1" {
+		t.Errorf("Expected last use to be recorded, got %v from %q", stored.LastUsedAt, stored.LastUsedIP)
+	}
+
+	t.Run("NotAfter", func(t *testing.T) {
+		notAfter := now.Add(time.Hour)
+		_, child, err := s.Create(ctx, CreateInput{Name: "child", UserID: user.ID, Scopes: []string{"posts:read"}, NotAfter: &notAfter})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if child.ExpiresAt == nil || !child.ExpiresAt.Equal(notAfter) {
+			t.Errorf("Expected the expiry to be capped at %v, got %v", notAfter, child.ExpiresAt)
+		}
+	})
+
+	t.Run("WrongSecret", func(t *testing.T) {
+		forged := token[:strings.LastIndex(token, "_")+1] + "forged"
+		if _, err := s.Authenticate(ctx, forged, ""); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Expected ErrInvalidKey, got %v", err)
+		}
+		if _, err := s.Authenticate(ctx, "goapp_000000000000_secret", ""); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Expected ErrInvalidKey for unknown key, got %v", err)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		s.now = func() time.Time { return now.Add(25 * time.Hour) }
+		defer func() { s.now = func() time.Time { return now } }()
+		if _, err := s.Authenticate(ctx, token, ""); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Expected ErrInvalidKey, got %v", err)
+		}
+	})
+
+	t.Run("InactiveOwner", func(t *testing.T) {
+		db.Model(user).Update("active", false)
+		defer db.Model(user).Update("active", true)
+		if _, err := s.Authenticate(ctx, token, ""); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Expected ErrInvalidKey, got %v", err)
+		}
+	})
+
+	t.Run("Revoked", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if err := s.Revoke(ctx, key.ID); err != nil {
+				t.Fatalf("Revoke() error = %v", err)
+			}
+		}
+		if _, err := s.Authenticate(ctx, token, ""); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Expected ErrInvalidKey, got %v", err)
+		}
+		if err := s.Revoke(ctx, 999); !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("Expected ErrKeyNotFound, got %v", err)
+		}
+	})
+}
+
+func TestCreateValidation(t *testing.T) {
+	ctx := context.Background()
+	s, _, user := setupTestService(t)
+
+	tests := []struct {
+		name  string
+		input CreateInput
+		field string
+	}{
+		{"MissingName", CreateInput{UserID: user.ID, Scopes: []string{"*"}}, "name"},
+		{"MissingScopes", CreateInput{Name: "k", UserID: user.ID}, "scopes"},
+		{"InvalidScope", CreateInput{Name: "k", UserID: user.ID, Scopes: []string{"posts"}}, "scopes"},
+		{"TooLong", CreateInput{Name: "k", UserID: user.ID, Scopes: []string{"*"}, ExpiresIn: 72 * time.Hour}, "expires_in"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := s.Create(ctx, tt.input)
+			var validationErr *auth.ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Fields[tt.field] == "" {
+				t.Errorf("Expected validation error for %s, got %v", tt.field, err)
+			}
+		})
+	}
+}
+
+func TestServiceAccount(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := setupTestService(t)
+
+	account, err := s.ServiceAccount(ctx, "Billing")
+	if err != nil {
+		t.Fatalf("ServiceAccount() error = %v", err)
+	}
+	if !account.ServiceAccount || account.Username != "billing" {
+		t.Errorf("Expected service account billing, got %+v", account)
+	}
+
+	again, err := s.ServiceAccount(ctx, "billing")
+	if err != nil || again.ID != account.ID {
+		t.Errorf("Expected the existing service account, got %v, %v", again, err)
+	}
+
+	var validationErr *auth.ValidationError
+	if _, err := s.ServiceAccount(ctx, "jane"); !errors.As(err, &validationErr) {
+		t.Errorf("Expected validation error for a user account name, got %v", err)
+	}
+	if _, err := s.ServiceAccount(ctx, "x"); !errors.As(err, &validationErr) {
+		t.Errorf("Expected validation error for a short name, got %v", err)
+	}
+
+	keys, err := s.List(ctx, 0)
+	if err != nil || len(keys) != 0 {
+		t.Errorf("Expected no keys, got %v, %v", keys, err)
+	}
+}
@@ -0,0 +1,284 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"goapp/internal/config"
+	"goapp/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidToken is returned for email verification and password reset
+// links that are malformed, expired, forged or already used
+var ErrInvalidToken = errors.New("invalid or expired link")
+
+// ErrEmailTaken is returned when confirming an email change to an address
+// another account registered in the meantime
+var ErrEmailTaken = errors.New("email address is already registered")
+
+// Token purposes, mixed into the signature so a token for one cannot be used for another
+const (
+	purposeEmailVerification = "verify_email"
+	purposePasswordReset     = "reset_password"
+	purposeLoginChallenge    = "login_challenge"
+	purposeEmailChange       = "change_email"
+)
+
+// loginChallengeTTL is how long a user has to enter their second factor
+const loginChallengeTTL = 5 * time.Minute
+
+// AccountService issues and redeems the links emailed to users to verify
+// their address or reset their password.
+//
+// A token is "<user ID>.<expiry>.<signature>". The signature covers the
+// account state the link changes, such as the password hash, so a token
+// stops working once it has been used and no used-token table is needed.
+type AccountService interface {
+	// EmailVerificationToken returns a token that marks the user's current email as verified
+	EmailVerificationToken(user *models.User) string
+	// VerifyEmail redeems an email verification token
+	VerifyEmail(ctx context.Context, token string) (*models.User, error)
+	// EmailChangeToken returns a token that replaces the user's email with
+	// their pending email, to be sent to the pending address
+	EmailChangeToken(user *models.User) string
+	// ConfirmEmailChange redeems an email change token; the new address is verified
+	ConfirmEmailChange(ctx context.Context, token string) (*models.User, error)
+	// PasswordResetToken returns a password reset token for the active user
+	// with email. It returns no user and an empty token when there is none.
+	PasswordResetToken(ctx context.Context, email string) (*models.User, string, error)
+	// CheckPasswordReset reports whether a password reset token is valid
+	CheckPasswordReset(ctx context.Context, token string) error
+	// ResetPassword redeems a password reset token, sets the new password
+	// and signs the user out everywhere
+	ResetPassword(ctx context.Context, token, password string) (*models.User, error)
+	// LoginChallenge returns a short-lived token proving the user passed the
+	// first sign-in step, to be exchanged for a session with a second factor
+	LoginChallenge(user *models.User) string
+	// RedeemLoginChallenge returns the user a login challenge was issued for
+	RedeemLoginChallenge(ctx context.Context, token string) (*models.User, error)
+}
+
+// accountService implements AccountService on the users table
+type accountService struct {
+	db     *gorm.DB
+	hasher Hasher
+	cfg    config.AuthConfig
+	secret []byte
+	now    func() time.Time
+}
+
+// NewAccountService creates an AccountService. Tokens are signed with
+// cfg.TokenSecret, or with a random key when it is empty, in which case
+// links stop working when the process restarts.
+func NewAccountService(db *gorm.DB, hasher Hasher, cfg config.AuthConfig) (AccountService, error) {
+	secret := []byte(cfg.TokenSecret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+	return &accountService{db: db, hasher: hasher, cfg: cfg, secret: secret, now: time.Now}, nil
+}
+
+// EmailVerificationToken implements AccountService
+func (s *accountService) EmailVerificationToken(user *models.User) string {
+	return s.sign(purposeEmailVerification, user, s.now().Add(s.cfg.EmailVerificationTTL))
+}
+
+// VerifyEmail implements AccountService
+func (s *accountService) VerifyEmail(ctx context.Context, token string) (*models.User, error) {
+	user, err := s.redeem(ctx, purposeEmailVerification, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Model(user).UpdateColumn("email_verified", true).Error; err != nil {
+		return nil, fmt.Errorf("failed to verify email: %w", err)
+	}
+	user.EmailVerified = true
+	return user, nil
+}
+
+// EmailChangeToken implements AccountService
+func (s *accountService) EmailChangeToken(user *models.User) string {
+	return s.sign(purposeEmailChange, user, s.now().Add(s.cfg.EmailVerificationTTL))
+}
+
+// ConfirmEmailChange implements AccountService
+func (s *accountService) ConfirmEmailChange(ctx context.Context, token string) (*models.User, error) {
+	user, err := s.redeem(ctx, purposeEmailChange, token)
+	if err != nil {
+		return nil, err
+	}
+	if user.PendingEmail == "" {
+		return nil, ErrInvalidToken
+	}
+
+	// Deleted accounts keep their email in the unique index
+	var count int64
+	err = s.db.WithContext(ctx).Unscoped().Model(&models.User{}).Where("email = ? AND id <> ?", user.PendingEmail, user.ID).Count(&count).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to check email: %w", err)
+	}
+	if count > 0 {
+		return nil, ErrEmailTaken
+	}
+
+	email := user.PendingEmail
+	updates := map[string]interface{}{"email": email, "pending_email": "", "email_verified": true}
+	err = s.db.WithContext(ctx).Model(user).UpdateColumns(updates).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, ErrEmailTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to change email: %w", err)
+	}
+	user.Email, user.PendingEmail, user.EmailVerified = email, "", true
+	return user, nil
+}
+
+// PasswordResetToken implements AccountService
+func (s *accountService) PasswordResetToken(ctx context.Context, email string) (*models.User, string, error) {
+	var user models.User
+	err := s.db.WithContext(ctx).Where("email = ?", NormalizeEmail(email)).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load user: %w", err)
+	}
+	if !user.Active || user.ServiceAccount {
+		return nil, "", nil
+	}
+	return &user, s.sign(purposePasswordReset, &user, s.now().Add(s.cfg.PasswordResetTTL)), nil
+}
+
+// CheckPasswordReset implements AccountService
+func (s *accountService) CheckPasswordReset(ctx context.Context, token string) error {
+	_, err := s.redeem(ctx, purposePasswordReset, token)
+	return err
+}
+
+// ResetPassword implements AccountService
+func (s *accountService) ResetPassword(ctx context.Context, token, password string) (*models.User, error) {
+	user, err := s.redeem(ctx, purposePasswordReset, token)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active {
+		return nil, ErrInactiveUser
+	}
+	if err := validatePassword(s.cfg, password); err != nil {
+		return nil, &ValidationError{Fields: map[string]string{"password": err.Error()}}
+	}
+
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Receiving the link proves the user owns the address, and unlocks the account
+		updates := map[string]interface{}{"password_hash": hash, "email_verified": true, "failed_login_attempts": 0, "locked_until": nil,
+			"token_version": gorm.Expr("token_version + 1")}
+		if err := tx.Model(user).UpdateColumns(updates).Error; err != nil {
+			return err
+		}
+		// Whoever knew the old password must not stay signed in
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Update("revoked_at", now).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset password: %w", err)
+	}
+	user.PasswordHash = hash
+	user.EmailVerified = true
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+	return user, nil
+}
+
+// LoginChallenge implements AccountService
+func (s *accountService) LoginChallenge(user *models.User) string {
+	return s.sign(purposeLoginChallenge, user, s.now().Add(loginChallengeTTL))
+}
+
+// RedeemLoginChallenge implements AccountService
+func (s *accountService) RedeemLoginChallenge(ctx context.Context, token string) (*models.User, error) {
+	user, err := s.redeem(ctx, purposeLoginChallenge, token)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active {
+		return nil, ErrInactiveUser
+	}
+	return user, nil
+}
+
+// sign returns a token for purpose bound to the user's current state
+func (s *accountService) sign(purpose string, user *models.User, expires time.Time) string {
+	payload := strconv.FormatUint(uint64(user.ID), 10) + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(purpose, payload, user))
+}
+
+// redeem checks a token for purpose and returns its user
+func (s *accountService) redeem(ctx context.Context, purpose, token string) (*models.User, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	userID, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || !s.now().Before(time.Unix(expires, 0)) {
+		return nil, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var user models.User
+	err = s.db.WithContext(ctx).First(&user, userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	if !hmac.Equal(signature, s.mac(purpose, parts[0]+"."+parts[1], &user)) {
+		return nil, ErrInvalidToken
+	}
+	return &user, nil
+}
+
+// mac signs payload together with the state that redeeming the token changes
+func (s *accountService) mac(purpose, payload string, user *models.User) []byte {
+	var state string
+	switch purpose {
+	case purposeEmailVerification:
+		state = user.Email + "\x00" + strconv.FormatBool(user.EmailVerified)
+	case purposePasswordReset, purposeLoginChallenge:
+		state = user.Email + "\x00" + user.PasswordHash
+	case purposeEmailChange:
+		state = user.Email + "\x00" + user.PendingEmail
+	}
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(purpose + "\x00" + payload + "\x00" + state))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"goapp/internal/models"
+)
+
+func setupAccountService(t *testing.T) (*accountService, *service) {
+	s, db := setupTestService(t)
+	if err := db.AutoMigrate(&models.RefreshToken{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	cfg := s.cfg
+	cfg.TokenSecret = "test-secret"
+	cfg.EmailVerificationTTL = 48 * time.Hour
+	cfg.PasswordResetTTL = time.Hour
+	accounts, err := NewAccountService(db, s.hasher, cfg)
+	if err != nil {
+		t.Fatalf("NewAccountService() error = %v", err)
+	}
+	return accounts.(*accountService), s
+}
+
+func TestVerifyEmail(t *testing.T) {
+	ctx := context.Background()
+	accounts, s := setupAccountService(t)
+	user, err := s.Register(ctx, RegisterInput{Email: "jane@example.com", Username: "jane", Password: "s3cret-password"})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	token := accounts.EmailVerificationToken(user)
+
+	if _, err := accounts.VerifyEmail(ctx, token+"x"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected tampered token to be rejected, got %v", err)
+	}
+	if _, err := accounts.ResetPassword(ctx, token, "new-password"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected verification token to be rejected for password reset, got %v", err)
+	}
+
+	verified, err := accounts.VerifyEmail(ctx, token)
+	if err != nil {
+		t.Fatalf("VerifyEmail() error = %v", err)
+	}
+	if !verified.EmailVerified {
+		t.Error("Expected email to be verified")
+	}
+
+	if _, err := accounts.VerifyEmail(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected used token to be rejected, got %v", err)
+	}
+}
+
+func TestVerifyEmailExpired(t *testing.T) {
+	ctx := context.Background()
+	accounts, s := setupAccountService(t)
+	user, _ := s.Register(ctx, RegisterInput{Email: "jane@example.com", Username: "jane", Password: "s3cret-password"})
+
+	token := accounts.EmailVerificationToken(user)
+	accounts.now = func() time.Time { return time.Now().Add(49 * time.Hour) }
+	if _, err := accounts.VerifyEmail(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected expired token to be rejected, got %v", err)
+	}
+}
+
+func TestConfirmEmailChange(t *testing.T) {
+	ctx := context.Background()
+	accounts, s := setupAccountService(t)
+	user, _ := s.Register(ctx, RegisterInput{Email: "jane@example.com", Username: "jane", Password: "s3cret-password"})
+
+	if _, err := accounts.ConfirmEmailChange(ctx, accounts.EmailChangeToken(user)); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected a token without a pending email to be rejected, got %v", err)
+	}
+
+	s.db.Model(user).Update("pending_email", "janet@example.com")
+	token := accounts.EmailChangeToken(user)
+	if _, err := accounts.VerifyEmail(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected email change token to be rejected for verification, got %v", err)
+	}
+
+	// Another account registering the address first wins it
+	bob, _ := s.Register(ctx, RegisterInput{Email: "janet@example.com", Username: "bob", Password: "s3cret-password"})
+	if _, err := accounts.ConfirmEmailChange(ctx, token); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("Expected ErrEmailTaken, got %v", err)
+	}
+	s.db.Unscoped().Delete(bob)
+
+	changed, err := accounts.ConfirmEmailChange(ctx, token)
+	if err != nil {
+		t.Fatalf("ConfirmEmailChange() error = %v", err)
+	}
+	if changed.Email != "janet@example.com" || changed.PendingEmail != "" || !changed.EmailVerified {
+		t.Errorf("Expected the pending email to be applied and verified, got %+v", changed)
+	}
+	if _, err := accounts.ConfirmEmailChange(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected used token to be rejected, got %v", err)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	ctx := context.Background()
+	accounts, s := setupAccountService(t)
+	user, _ := s.Register(ctx, RegisterInput{Email: "jane@example.com", Username: "jane", Password: "s3cret-password"})
+
+	sessions := NewSessionStore(s.db, time.Hour)
+	if _, _, err := sessions.Create(ctx, user.ID, SessionMeta{}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if found, token, err := accounts.PasswordResetToken(ctx, "nobody@example.com"); err != nil || found != nil || token != "" {
+		t.Errorf("Expected no token for unknown email, got %v %q %v", found, token, err)
+	}
+	_, token, err := accounts.PasswordResetToken(ctx, "Jane@Example.com")
+	if err != nil || token == "" {
+		t.Fatalf("PasswordResetToken() = %q, %v", token, err)
+	}
+
+	if err := accounts.CheckPasswordReset(ctx, token); err != nil {
+		t.Errorf("CheckPasswordReset() error = %v", err)
+	}
+	var validationErr *ValidationError
+	if _, err := accounts.ResetPassword(ctx, token, "short"); !errors.As(err, &validationErr) {
+		t.Errorf("Expected ValidationError, got %v", err)
+	}
+
+	if _, err := accounts.ResetPassword(ctx, token, "brand-new-password"); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+	if _, err := s.Authenticate(ctx, "jane", "brand-new-password", SessionMeta{}); err != nil {
+		t.Errorf("Expected new password to work, got %v", err)
+	}
+	if _, err := s.Authenticate(ctx, "jane", "s3cret-password", SessionMeta{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected old password to be rejected, got %v", err)
+	}
+
+	var count int64
+	s.db.Model(&models.Session{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected sessions to be deleted, got %d", count)
+	}
+
+	if _, err := accounts.ResetPassword(ctx, token, "another-password"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected used token to be rejected, got %v", err)
+	}
+}
+
+func TestResetPasswordIgnoresInactiveUsers(t *testing.T) {
+	ctx := context.Background()
+	accounts, s := setupAccountService(t)
+	user, _ := s.Register(ctx, RegisterInput{Email: "jane@example.com", Username: "jane", Password: "s3cret-password"})
+	s.db.Model(user).Update("active", false)
+
+	if found, token, err := accounts.PasswordResetToken(ctx, "jane@example.com"); err != nil || found != nil || token != "" {
+		t.Errorf("Expected no token for inactive user, got %v %q %v", found, token, err)
+	}
+}
+
+func TestLoginChallenge(t *testing.T) {
+	ctx := context.Background()
+	accounts, s := setupAccountService(t)
+	user, _ := s.Register(ctx, RegisterInput{Email: "jane@example.com", Username: "jane", Password: "s3cret-password"})
+
+	token := accounts.LoginChallenge(user)
+	if redeemed, err := accounts.RedeemLoginChallenge(ctx, token); err != nil || redeemed.ID != user.ID {
+		t.Errorf("RedeemLoginChallenge() = %v, %v", redeemed, err)
+	}
+	if _, err := accounts.VerifyEmail(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected challenge to be rejected for email verification, got %v", err)
+	}
+
+	accounts.now = func() time.Time { return time.Now().Add(6 * time.Minute) }
+	if _, err := accounts.RedeemLoginChallenge(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected expired challenge to be rejected, got %v", err)
+	}
+}
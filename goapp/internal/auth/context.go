@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"context"
+
+	"goapp/internal/models"
+)
+
+type userContextKey struct{}
+
+// WithUser returns a copy of ctx carrying the signed-in user for handlers and templates
+func WithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// UserFromContext returns the signed-in user stored in ctx, or nil for anonymous requests
+func UserFromContext(ctx context.Context) *models.User {
+	user, _ := ctx.Value(userContextKey{}).(*models.User)
+	return user
+}
@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"net/http"
+)
+
+// SessionCookie returns the cookie carrying a session token. It is a browser
+// session cookie: expiry is enforced server-side by the SessionStore, which
+// extends the session while it is in use. The cookie is HttpOnly and
+// SameSite=Lax; secure should be true whenever served over TLS.
+func SessionCookie(name, token string, secure bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// ExpiredSessionCookie returns a cookie that removes the session cookie
+func ExpiredSessionCookie(name string, secure bool) *http.Cookie {
+	cookie := SessionCookie(name, "", secure)
+	cookie.MaxAge = -1
+	return cookie
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"goapp/internal/models"
+	"gorm.io/gorm"
+)
+
+// LoginAttempt describes a sign-in attempt to record in the login history
+type LoginAttempt struct {
+	UserID     uint // zero when the identifier matched no account
+	Identifier string
+	Method     string // one of the models.LoginMethod constants
+	Outcome    string // one of the models.Login outcome constants
+	Client     SessionMeta
+}
+
+// LoginHistory records sign-in attempts so users can spot logins they do not recognise
+type LoginHistory interface {
+	// Record stores an attempt
+	Record(ctx context.Context, attempt LoginAttempt) error
+	// Recent returns the user's latest attempts, newest first
+	Recent(ctx context.Context, userID uint, limit int) ([]models.LoginEvent, error)
+	// DeleteBefore removes attempts older than cutoff and returns how many were deleted
+	DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// loginHistory implements LoginHistory on the login_events table
+type loginHistory struct {
+	db *gorm.DB
+}
+
+// NewLoginHistory creates a LoginHistory
+func NewLoginHistory(db *gorm.DB) LoginHistory {
+	return &loginHistory{db: db}
+}
+
+// Record implements LoginHistory
+func (h *loginHistory) Record(ctx context.Context, attempt LoginAttempt) error {
+	event := &models.LoginEvent{
+		Identifier: truncate(attempt.Identifier, 255),
+		Method:     attempt.Method,
+		Outcome:    attempt.Outcome,
+		IPAddress:  truncate(attempt.Client.IPAddress, 45),
+		UserAgent:  truncate(attempt.Client.UserAgent, 255),
+	}
+	if attempt.UserID != 0 {
+		event.UserID = &attempt.UserID
+	}
+	if err := h.db.WithContext(ctx).Create(event).Error; err != nil {
+		return fmt.Errorf("failed to record login: %w", err)
+	}
+	return nil
+}
+
+// Recent implements LoginHistory
+func (h *loginHistory) Recent(ctx context.Context, userID uint, limit int) ([]models.LoginEvent, error) {
+	var events []models.LoginEvent
+	err := h.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC, id DESC").Limit(limit).Find(&events).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load login history: %w", err)
+	}
+	return events, nil
+}
+
+// DeleteBefore implements LoginHistory
+func (h *loginHistory) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := h.db.WithContext(ctx).Where("created_at < ?", cutoff).Delete(&models.LoginEvent{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete login history: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"goapp/internal/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Supported password hashing algorithms
+const (
+	HasherBcrypt   = "bcrypt"
+	HasherArgon2id = "argon2id"
+)
+
+const (
+	argon2SaltBytes = 16
+	argon2KeyBytes  = 32
+)
+
+// NoPassword is stored as the password hash of accounts that cannot sign in
+// with a password, such as service accounts and users created by single
+// sign-on. It matches no password.
+const NoPassword = "!"
+
+// ErrUnknownHashFormat is returned when a stored hash was produced by no supported algorithm
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+// Hasher hashes and verifies passwords
+type Hasher interface {
+	// Hash returns an encoded hash of password including algorithm and parameters
+	Hash(password string) (string, error)
+	// Verify reports whether password matches hash. Hashes of every supported
+	// algorithm are accepted, so the configured algorithm can change over time.
+	Verify(hash, password string) (bool, error)
+	// NeedsRehash reports whether hash was produced with another algorithm or
+	// weaker parameters than currently configured
+	NeedsRehash(hash string) bool
+}
+
+// hasher implements Hasher with bcrypt and argon2id
+type hasher struct {
+	algorithm  string
+	bcryptCost int
+	argon2     argon2Params
+}
+
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+// NewHasher creates a Hasher using the algorithm and cost from cfg
+func NewHasher(cfg config.AuthConfig) (Hasher, error) {
+	h := &hasher{
+		algorithm:  cfg.PasswordHasher,
+		bcryptCost: cfg.BcryptCost,
+		argon2: argon2Params{
+			time:    cfg.Argon2Time,
+			memory:  cfg.Argon2MemoryKiB,
+			threads: cfg.Argon2Threads,
+		},
+	}
+
+	switch h.algorithm {
+	case HasherBcrypt:
+		if h.bcryptCost < bcrypt.MinCost || h.bcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, h.bcryptCost)
+		}
+	case HasherArgon2id:
+		if h.argon2.time == 0 || h.argon2.memory == 0 || h.argon2.threads == 0 {
+			return nil, errors.New("argon2id time, memory and threads must be positive")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported password hasher %q", h.algorithm)
+	}
+	return h, nil
+}
+
+// Hash implements Hasher
+func (h *hasher) Hash(password string) (string, error) {
+	if h.algorithm == HasherArgon2id {
+		return h.hashArgon2id(password)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// Verify implements Hasher
+func (h *hasher) Verify(hash, password string) (bool, error) {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false, err
+		}
+		computed := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+		return subtle.ConstantTimeCompare(computed, key) == 1, nil
+	}
+
+	if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+		return false, ErrUnknownHashFormat
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to verify password: %w", err)
+	}
+	return true, nil
+}
+
+// NeedsRehash implements Hasher
+func (h *hasher) NeedsRehash(hash string) bool {
+	if h.algorithm == HasherArgon2id {
+		params, _, _, err := decodeArgon2id(hash)
+		return err != nil || params != h.argon2
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost < h.bcryptCost
+}
+
+// hashArgon2id encodes the hash in the PHC string format used by the
+// reference implementation: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+func (h *hasher) hashArgon2id(password string) (string, error) {
+	salt := make([]byte, argon2SaltBytes)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.argon2.time, h.argon2.memory, h.argon2.threads, argon2KeyBytes)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.argon2.memory, h.argon2.time, h.argon2.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func decodeArgon2id(hash string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrUnknownHashFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrUnknownHashFormat
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return params, nil, nil, ErrUnknownHashFormat
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrUnknownHashFormat
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrUnknownHashFormat
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"goapp/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func testAuthConfig(hasher string) config.AuthConfig {
+	return config.AuthConfig{
+		PasswordHasher:    hasher,
+		BcryptCost:        bcrypt.MinCost,
+		Argon2Time:        1,
+		Argon2MemoryKiB:   1024,
+		Argon2Threads:     1,
+		MinPasswordLength: 8,
+	}
+}
+
+func TestHasher(t *testing.T) {
+	for _, algorithm := range []string{HasherBcrypt, HasherArgon2id} {
+		t.Run(algorithm, func(t *testing.T) {
+			h, err := NewHasher(testAuthConfig(algorithm))
+			if err != nil {
+				t.Fatalf("NewHasher() error = %v", err)
+			}
+
+			hash, err := h.Hash("correct horse")
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+			if algorithm == HasherArgon2id && !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+				t.Errorf("Unexpected argon2id encoding: %s", hash)
+			}
+
+			if ok, err := h.Verify(hash, "correct horse"); err != nil || !ok {
+				t.Errorf("Expected password to verify, got ok=%v err=%v", ok, err)
+			}
+			if ok, err := h.Verify(hash, "wrong horse"); err != nil || ok {
+				t.Errorf("Expected wrong password to fail, got ok=%v err=%v", ok, err)
+			}
+			if h.NeedsRehash(hash) {
+				t.Error("Expected fresh hash not to need rehashing")
+			}
+		})
+	}
+}
+
+func TestHasherMigration(t *testing.T) {
+	bcryptHasher, _ := NewHasher(testAuthConfig(HasherBcrypt))
+	argonHasher, _ := NewHasher(testAuthConfig(HasherArgon2id))
+
+	bcryptHash, _ := bcryptHasher.Hash("password1")
+	argonHash, _ := argonHasher.Hash("password1")
+
+	// Either hasher verifies hashes of the other algorithm
+	if ok, err := argonHasher.Verify(bcryptHash, "password1"); err != nil || !ok {
+		t.Errorf("Expected argon2id hasher to verify bcrypt hash, got ok=%v err=%v", ok, err)
+	}
+	if ok, err := bcryptHasher.Verify(argonHash, "password1"); err != nil || !ok {
+		t.Errorf("Expected bcrypt hasher to verify argon2id hash, got ok=%v err=%v", ok, err)
+	}
+
+	if !argonHasher.NeedsRehash(bcryptHash) {
+		t.Error("Expected bcrypt hash to need rehashing when argon2id is configured")
+	}
+
+	stronger := testAuthConfig(HasherBcrypt)
+	stronger.BcryptCost = bcrypt.MinCost + 1
+	strongerHasher, _ := NewHasher(stronger)
+	if !strongerHasher.NeedsRehash(bcryptHash) {
+		t.Error("Expected hash with lower cost to need rehashing")
+	}
+
+	if _, err := bcryptHasher.Verify("plaintext", "password1"); err != ErrUnknownHashFormat {
+		t.Errorf("Expected ErrUnknownHashFormat, got %v", err)
+	}
+}
+
+func TestNewHasherInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*config.AuthConfig)
+	}{
+		{"UnknownAlgorithm", func(c *config.AuthConfig) { c.PasswordHasher = "md5" }},
+		{"BcryptCostTooLow", func(c *config.AuthConfig) { c.BcryptCost = 1 }},
+		{"Argon2ZeroMemory", func(c *config.AuthConfig) {
+			c.PasswordHasher = HasherArgon2id
+			c.Argon2MemoryKiB = 0
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testAuthConfig(HasherBcrypt)
+			tt.modify(&cfg)
+			if _, err := NewHasher(cfg); err == nil {
+				t.Error("Expected error for invalid configuration")
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"goapp/internal/config"
+	"goapp/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidCredentials is returned when the identifier or password is wrong
+	ErrInvalidCredentials = errors.New("invalid email, username or password")
+	// ErrInactiveUser is returned when a deactivated user tries to sign in
+	ErrInactiveUser = errors.New("account is deactivated")
+	// ErrAccountLocked is returned while password sign-in is locked after repeated failures
+	ErrAccountLocked = errors.New("account is temporarily locked")
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{3,50}$`)
+
+// ValidationError reports invalid input, keyed by form field
+type ValidationError struct {
+	Fields map[string]string
+}
+
+// Error implements error
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for field, msg := range e.Fields {
+		fields = append(fields, field+" "+msg)
+	}
+	sort.Strings(fields)
+	return "validation failed: " + strings.Join(fields, ", ")
+}
+
+// RegisterInput holds the details of a new account
+type RegisterInput struct {
+	Email     string
+	Username  string
+	FirstName string
+	LastName  string
+	Password  string
+}
+
+// Service registers and authenticates users with passwords
+type Service interface {
+	// Register validates input and creates an active user
+	Register(ctx context.Context, input RegisterInput) (*models.User, error)
+	// Authenticate checks the password of the user identified by email or
+	// username. Every attempt is added to the login history with client's
+	// details, and repeated failures lock the account for a growing period.
+	Authenticate(ctx context.Context, identifier, password string, client SessionMeta) (*models.User, error)
+}
+
+// service implements Service on the users table
+type service struct {
+	db      *gorm.DB
+	hasher  Hasher
+	cfg     config.AuthConfig
+	history LoginHistory
+	now     func() time.Time
+
+	dummyOnce sync.Once
+	dummyHash string
+}
+
+// NewService creates a password authentication Service
+func NewService(db *gorm.DB, hasher Hasher, cfg config.AuthConfig) Service {
+	return &service{db: db, hasher: hasher, cfg: cfg, history: NewLoginHistory(db), now: time.Now}
+}
+
+// Register implements Service
+func (s *service) Register(ctx context.Context, input RegisterInput) (*models.User, error) {
+	input.Email = NormalizeEmail(input.Email)
+	input.Username = strings.TrimSpace(input.Username)
+	input.FirstName = strings.TrimSpace(input.FirstName)
+	input.LastName = strings.TrimSpace(input.LastName)
+
+	fields := map[string]string{}
+	ValidateIdentity(input.Email, input.Username, fields)
+	if err := validatePassword(s.cfg, input.Password); err != nil {
+		fields["password"] = err.Error()
+	}
+	if len(fields) == 0 {
+		if err := s.checkAvailable(ctx, input, fields); err != nil {
+			return nil, err
+		}
+	}
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+
+	hash, err := s.hasher.Hash(input.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Email:        input.Email,
+		Username:     input.Username,
+		FirstName:    input.FirstName,
+		LastName:     input.LastName,
+		PasswordHash: hash,
+		Active:       true,
+	}
+	if err := s.db.WithContext(ctx).Create(user).Error; err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	return user, nil
+}
+
+// ValidateIdentity checks the format of a normalized email and username,
+// adding a message to fields for each that is invalid
+func ValidateIdentity(email, username string, fields map[string]string) {
+	if _, err := mail.ParseAddress(email); err != nil || strings.ContainsAny(email, "<> ") {
+		fields["email"] = "must be a valid email address"
+	}
+	if !usernamePattern.MatchString(username) {
+		fields["username"] = "must be 3-50 letters, digits, '.', '_' or '-'"
+	}
+}
+
+// validatePassword checks a new password against the configured policy
+func validatePassword(cfg config.AuthConfig, password string) error {
+	if len([]rune(password)) < cfg.MinPasswordLength {
+		return fmt.Errorf("must be at least %d characters", cfg.MinPasswordLength)
+	}
+	// bcrypt silently ignores everything after 72 bytes
+	if len(password) > 72 {
+		return errors.New("must be at most 72 bytes")
+	}
+	return nil
+}
+
+func (s *service) checkAvailable(ctx context.Context, input RegisterInput, fields map[string]string) error {
+	var existing []models.User
+	err := s.db.WithContext(ctx).Unscoped().Select("email", "username").
+		Where("email = ? OR username = ?", input.Email, input.Username).Find(&existing).Error
+	if err != nil {
+		return fmt.Errorf("failed to check existing users: %w", err)
+	}
+
+	for _, u := range existing {
+		if u.Email == input.Email {
+			fields["email"] = "is already registered"
+		}
+		if strings.EqualFold(u.Username, input.Username) {
+			fields["username"] = "is already taken"
+		}
+	}
+	return nil
+}
+
+// Authenticate implements Service
+func (s *service) Authenticate(ctx context.Context, identifier, password string, client SessionMeta) (*models.User, error) {
+	identifier = strings.TrimSpace(identifier)
+	attempt := LoginAttempt{Identifier: identifier, Method: models.LoginMethodPassword, Client: client}
+
+	var user models.User
+	err := s.db.WithContext(ctx).
+		Where("email = ? OR username = ?", NormalizeEmail(identifier), identifier).
+		First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Spend the same time as a real check so response times do not reveal which accounts exist
+		_, _ = s.hasher.Verify(s.dummy(), password)
+		return nil, s.fail(ctx, attempt, models.LoginFailed, ErrInvalidCredentials)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	attempt.UserID = user.ID
+
+	if user.ServiceAccount || user.PasswordHash == NoPassword {
+		_, _ = s.hasher.Verify(s.dummy(), password)
+		return nil, s.fail(ctx, attempt, models.LoginFailed, ErrInvalidCredentials)
+	}
+
+	now := s.now()
+	if user.Locked(now) {
+		// Do not check the password, so guessing gains nothing while locked
+		_, _ = s.hasher.Verify(s.dummy(), password)
+		return nil, s.fail(ctx, attempt, models.LoginLocked, ErrAccountLocked)
+	}
+
+	ok, err := s.hasher.Verify(user.PasswordHash, password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, s.recordFailure(ctx, &user, attempt, now)
+	}
+	if !user.Active {
+		return nil, s.fail(ctx, attempt, models.LoginInactive, ErrInactiveUser)
+	}
+
+	updates := map[string]interface{}{"last_login_at": now, "failed_login_attempts": 0, "locked_until": nil}
+	if s.hasher.NeedsRehash(user.PasswordHash) {
+		if hash, err := s.hasher.Hash(password); err == nil {
+			updates["password_hash"] = hash
+			user.PasswordHash = hash
+		}
+	}
+	if err := s.db.WithContext(ctx).Model(&user).UpdateColumns(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to record login: %w", err)
+	}
+	user.LastLoginAt = &now
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+
+	attempt.Outcome = models.LoginSucceeded
+	if user.TwoFactorEnabled {
+		attempt.Outcome = models.LoginTwoFactorRequired
+	}
+	if err := s.history.Record(ctx, attempt); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// recordFailure counts a wrong password and locks the account once the
+// failures reach the threshold, for LockoutDuration doubled with each
+// further failure up to LockoutMaxDuration
+func (s *service) recordFailure(ctx context.Context, user *models.User, attempt LoginAttempt, now time.Time) error {
+	failures := user.FailedLoginAttempts + 1
+	updates := map[string]interface{}{"failed_login_attempts": gorm.Expr("failed_login_attempts + 1")}
+
+	locked := s.cfg.LockoutThreshold > 0 && failures >= s.cfg.LockoutThreshold
+	if locked {
+		until := now.Add(lockoutDuration(s.cfg, failures-s.cfg.LockoutThreshold))
+		updates["locked_until"] = until
+	}
+	if err := s.db.WithContext(ctx).Model(user).UpdateColumns(updates).Error; err != nil {
+		return fmt.Errorf("failed to record failed login: %w", err)
+	}
+
+	if locked {
+		return s.fail(ctx, attempt, models.LoginLocked, ErrAccountLocked)
+	}
+	return s.fail(ctx, attempt, models.LoginFailed, ErrInvalidCredentials)
+}
+
+// lockoutDuration returns how long to lock an account after the threshold
+// was exceeded n times. A LockoutMaxDuration of 0 leaves the lock uncapped.
+func lockoutDuration(cfg config.AuthConfig, n int) time.Duration {
+	d := cfg.LockoutDuration
+	for i := 0; i < n; i++ {
+		if (cfg.LockoutMaxDuration > 0 && d >= cfg.LockoutMaxDuration) || d > math.MaxInt64/2 {
+			break
+		}
+		d *= 2
+	}
+	if cfg.LockoutMaxDuration > 0 && d > cfg.LockoutMaxDuration {
+		d = cfg.LockoutMaxDuration
+	}
+	return d
+}
+
+// fail records a failed attempt and returns err, or the error recording it
+func (s *service) fail(ctx context.Context, attempt LoginAttempt, outcome string, err error) error {
+	attempt.Outcome = outcome
+	if recordErr := s.history.Record(ctx, attempt); recordErr != nil {
+		return recordErr
+	}
+	return err
+}
+
+func (s *service) dummy() string {
+	s.dummyOnce.Do(func() {
+		s.dummyHash, _ = s.hasher.Hash("dummy password for timing")
+	})
+	return s.dummyHash
+}
+
+// NormalizeEmail lower-cases and trims an email address for storage and lookup
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,264 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"goapp/internal/config"
+	"goapp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Permission{}, &models.Role{}, &models.User{}, &models.Session{}, &models.LoginEvent{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	return db
+}
+
+func setupTestService(t *testing.T) (*service, *gorm.DB) {
+	db := setupTestDB(t)
+	cfg := testAuthConfig(HasherBcrypt)
+	hasher, err := NewHasher(cfg)
+	if err != nil {
+		t.Fatalf("NewHasher() error = %v", err)
+	}
+	return NewService(db, hasher, cfg).(*service), db
+}
+
+func TestRegister(t *testing.T) {
+	ctx := context.Background()
+	s, _ := setupTestService(t)
+
+	user, err := s.Register(ctx, RegisterInput{
+		Email:     "  Jane@Example.com ",
+		Username:  "jane",
+		FirstName: "Jane",
+		Password:  "s3cret-password",
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if user.Email != "jane@example.com" {
+		t.Errorf("Expected normalized email, got %q", user.Email)
+	}
+	if !user.Active || user.PasswordHash == "" || user.PasswordHash == "s3cret-password" {
+		t.Errorf("Unexpected user: %+v", user)
+	}
+
+	tests := []struct {
+		name   string
+		input  RegisterInput
+		fields []string
+	}{
+		{"Invalid", RegisterInput{Email: "not-an-email", Username: "x", Password: "short"}, []string{"email", "username", "password"}},
+		{"Duplicate", RegisterInput{Email: "jane@example.com", Username: "JANE", Password: "s3cret-password"}, []string{"email", "username"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Register(ctx, tt.input)
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected ValidationError, got %v", err)
+			}
+			for _, field := range tt.fields {
+				if _, ok := validationErr.Fields[field]; !ok {
+					t.Errorf("Expected error for field %q, got %v", field, validationErr.Fields)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	s, db := setupTestService(t)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	if _, err := s.Register(ctx, RegisterInput{Email: "jane@example.com", Username: "jane", Password: "s3cret-password"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	for _, identifier := range []string{"jane", "JANE@example.com"} {
+		user, err := s.Authenticate(ctx, identifier, "s3cret-password", SessionMeta{})
+		if err != nil {
+			t.Fatalf("Authenticate(%q) error = %v", identifier, err)
+		}
+		if user.LastLoginAt == nil || !user.LastLoginAt.Equal(now) {
+			t.Errorf("Expected LastLoginAt %v, got %v", now, user.LastLoginAt)
+		}
+	}
+
+	var stored models.User
+	db.First(&stored, "username = ?", "jane")
+	if stored.LastLoginAt == nil {
+		t.Error("Expected LastLoginAt to be persisted")
+	}
+
+	if _, err := s.Authenticate(ctx, "jane", "wrong-password", SessionMeta{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials for wrong password, got %v", err)
+	}
+	if _, err := s.Authenticate(ctx, "nobody", "s3cret-password", SessionMeta{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials for unknown user, got %v", err)
+	}
+
+	db.Model(&stored).UpdateColumn("active", false)
+	if _, err := s.Authenticate(ctx, "jane", "s3cret-password", SessionMeta{}); !errors.Is(err, ErrInactiveUser) {
+		t.Errorf("Expected ErrInactiveUser, got %v", err)
+	}
+}
+
+func TestAuthenticateRehashes(t *testing.T) {
+	ctx := context.Background()
+	s, db := setupTestService(t)
+
+	if _, err := s.Register(ctx, RegisterInput{Email: "jane@example.com", Username: "jane", Password: "s3cret-password"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	// Switch to argon2id; the bcrypt hash is upgraded on the next login
+	s.hasher, _ = NewHasher(testAuthConfig(HasherArgon2id))
+	if _, err := s.Authenticate(ctx, "jane", "s3cret-password", SessionMeta{}); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+
+	var stored models.User
+	db.First(&stored, "username = ?", "jane")
+	if s.hasher.NeedsRehash(stored.PasswordHash) {
+		t.Errorf("Expected password to be rehashed with argon2id, got %s", stored.PasswordHash)
+	}
+}
+
+func TestAuthenticateServiceAccount(t *testing.T) {
+	s, db := setupTestService(t)
+
+	account := &models.User{Email: "billing@service-accounts.invalid", Username: "billing", PasswordHash: "!", Active: true, ServiceAccount: true}
+	if err := db.Create(account).Error; err != nil {
+		t.Fatalf("Failed to create service account: %v", err)
+	}
+
+	if _, err := s.Authenticate(context.Background(), "billing", "!", SessionMeta{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+}
+
+func TestAuthenticateLockout(t *testing.T) {
+	ctx := context.Background()
+	s, db := setupTestService(t)
+	s.cfg.LockoutThreshold = 3
+	s.cfg.LockoutDuration = time.Minute
+	s.cfg.LockoutMaxDuration = 3 * time.Minute
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	if _, err := s.Register(ctx, RegisterInput{Email: "jane@example.com", Username: "jane", Password: "s3cret-password"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.Authenticate(ctx, "jane", "wrong-password", SessionMeta{}); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("Expected ErrInvalidCredentials, got %v", err)
+		}
+	}
+	if _, err := s.Authenticate(ctx, "jane", "wrong-password", SessionMeta{}); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("Expected the third failure to lock the account, got %v", err)
+	}
+	if _, err := s.Authenticate(ctx, "jane", "s3cret-password", SessionMeta{}); !errors.Is(err, ErrAccountLocked) {
+		t.Errorf("Expected the right password to be refused while locked, got %v", err)
+	}
+
+	// Each failure after the threshold doubles the lock, up to the maximum
+	var stored models.User
+	for _, want := range []time.Duration{2 * time.Minute, 3 * time.Minute} {
+		now = now.Add(4 * time.Minute)
+		if _, err := s.Authenticate(ctx, "jane", "wrong-password", SessionMeta{}); !errors.Is(err, ErrAccountLocked) {
+			t.Fatalf("Expected ErrAccountLocked, got %v", err)
+		}
+		db.First(&stored, "username = ?", "jane")
+		if stored.LockedUntil == nil || !stored.LockedUntil.Equal(now.Add(want)) {
+			t.Errorf("Expected lock until %v, got %v", now.Add(want), stored.LockedUntil)
+		}
+	}
+
+	now = now.Add(4 * time.Minute)
+	if _, err := s.Authenticate(ctx, "jane", "s3cret-password", SessionMeta{}); err != nil {
+		t.Fatalf("Expected sign-in after the lock expired, got %v", err)
+	}
+	var reset models.User
+	db.First(&reset, "username = ?", "jane")
+	if reset.FailedLoginAttempts != 0 || reset.LockedUntil != nil {
+		t.Errorf("Expected success to reset the failures, got %d until %v", reset.FailedLoginAttempts, reset.LockedUntil)
+	}
+}
+
+func TestLockoutDuration(t *testing.T) {
+	tests := []struct {
+		name string
+		max  time.Duration
+		n    int
+		want time.Duration
+	}{
+		{"First", time.Hour, 0, time.Minute},
+		{"Doubles", time.Hour, 3, 8 * time.Minute},
+		{"Capped", time.Hour, 10, time.Hour},
+		{"Uncapped", 0, 10, 1024 * time.Minute},
+		{"NoOverflow", 0, 100, time.Minute << 27},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.AuthConfig{LockoutDuration: time.Minute, LockoutMaxDuration: tt.max}
+			if got := lockoutDuration(cfg, tt.n); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAuthenticateRecordsHistory(t *testing.T) {
+	ctx := context.Background()
+	s, db := setupTestService(t)
+
+	user, err := s.Register(ctx, RegisterInput{Email: "jane@example.com", Username: "jane", Password: "s3cret-password"})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	client := SessionMeta{IPAddress: "10.0.0.1", UserAgent: "test"}
+	s.Authenticate(ctx, "jane", "wrong-password", client)
+	s.Authenticate(ctx, "nobody", "s3cret-password", client)
+	s.Authenticate(ctx, "jane", "s3cret-password", client)
+
+	events, err := s.history.Recent(ctx, user.ID, 10)
+	if err != nil {
+		t.Fatalf("Recent() error = %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events for jane, got %d", len(events))
+	}
+	if events[0].Outcome != models.LoginSucceeded || events[1].Outcome != models.LoginFailed {
+		t.Errorf("Expected success after failure, got %q, %q", events[0].Outcome, events[1].Outcome)
+	}
+	if events[0].IPAddress != "10.0.0.1" || events[0].UserAgent != "test" || events[0].Method != models.LoginMethodPassword {
+		t.Errorf("Expected client details to be recorded, got %+v", events[0])
+	}
+
+	var unknown models.LoginEvent
+	if err := db.Where("user_id IS NULL").First(&unknown).Error; err != nil || unknown.Identifier != "nobody" {
+		t.Errorf("Expected attempt for an unknown account to be recorded, got %+v, %v", unknown, err)
+	}
+
+	deleted, err := s.history.DeleteBefore(ctx, time.Now().Add(time.Minute))
+	if err != nil || deleted != 3 {
+		t.Errorf("Expected 3 events to be deleted, got %d, %v", deleted, err)
+	}
+}
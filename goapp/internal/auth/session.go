@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"goapp/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// sessionTokenBytes is the amount of entropy in a session token
+	sessionTokenBytes = 32
+	// touchInterval limits how often a session's LastSeenAt and expiry are written
+	touchInterval = time.Minute
+)
+
+// ErrSessionNotFound is returned when a token does not belong to a live session
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionMeta describes the client a session was created for
+type SessionMeta struct {
+	IPAddress string
+	UserAgent string
+}
+
+// SessionStore persists server-side login sessions
+type SessionStore interface {
+	// Create starts a session for the user and returns its secret token
+	Create(ctx context.Context, userID uint, meta SessionMeta) (string, *models.Session, error)
+	// Lookup returns the live session for token with its User and the user's
+	// roles loaded, extending its expiry
+	Lookup(ctx context.Context, token string) (*models.Session, error)
+	// Delete ends the session for token
+	Delete(ctx context.Context, token string) error
+	// List returns the user's live sessions, most recently used first
+	List(ctx context.Context, userID uint) ([]models.Session, error)
+	// Revoke ends one of the user's sessions by ID
+	Revoke(ctx context.Context, userID, sessionID uint) error
+	// RevokeOthers ends all of the user's sessions except keepID and
+	// returns how many were ended
+	RevokeOthers(ctx context.Context, userID, keepID uint) (int64, error)
+	// DeleteExpired removes expired sessions and returns how many were deleted
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// sessionStore implements SessionStore on the sessions table
+type sessionStore struct {
+	db  *gorm.DB
+	ttl time.Duration
+	now func() time.Time
+}
+
+// NewSessionStore creates a SessionStore whose sessions expire after ttl of inactivity
+func NewSessionStore(db *gorm.DB, ttl time.Duration) SessionStore {
+	return &sessionStore{db: db, ttl: ttl, now: time.Now}
+}
+
+// Create implements SessionStore
+func (s *sessionStore) Create(ctx context.Context, userID uint, meta SessionMeta) (string, *models.Session, error) {
+	token, err := newSessionToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := s.now()
+	session := &models.Session{
+		TokenHash:  hashToken(token),
+		UserID:     userID,
+		IPAddress:  truncate(meta.IPAddress, 45),
+		UserAgent:  truncate(meta.UserAgent, 255),
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.ttl),
+	}
+	if err := s.db.WithContext(ctx).Create(session).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return token, session, nil
+}
+
+// Lookup implements SessionStore
+func (s *sessionStore) Lookup(ctx context.Context, token string) (*models.Session, error) {
+	if token == "" {
+		return nil, ErrSessionNotFound
+	}
+
+	var session models.Session
+	err := s.db.WithContext(ctx).Preload("User.Roles.Permissions").Where("token_hash = ?", hashToken(token)).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+
+	now := s.now()
+	if session.Expired(now) || session.User.ID == 0 || !session.User.Active {
+		return nil, ErrSessionNotFound
+	}
+
+	if now.Sub(session.LastSeenAt) >= touchInterval {
+		session.LastSeenAt = now
+		session.ExpiresAt = now.Add(s.ttl)
+		err := s.db.WithContext(ctx).Model(&session).UpdateColumns(map[string]interface{}{
+			"last_seen_at": session.LastSeenAt,
+			"expires_at":   session.ExpiresAt,
+		}).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to extend session: %w", err)
+		}
+	}
+	return &session, nil
+}
+
+// Delete implements SessionStore
+func (s *sessionStore) Delete(ctx context.Context, token string) error {
+	err := s.db.WithContext(ctx).Unscoped().Where("token_hash = ?", hashToken(token)).Delete(&models.Session{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// List implements SessionStore
+func (s *sessionStore) List(ctx context.Context, userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := s.db.WithContext(ctx).Where("user_id = ? AND expires_at > ?", userID, s.now()).
+		Order("last_seen_at DESC, id DESC").Find(&sessions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// Revoke implements SessionStore
+func (s *sessionStore) Revoke(ctx context.Context, userID, sessionID uint) error {
+	result := s.db.WithContext(ctx).Unscoped().Where("id = ? AND user_id = ?", sessionID, userID).Delete(&models.Session{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke session: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeOthers implements SessionStore
+func (s *sessionStore) RevokeOthers(ctx context.Context, userID, keepID uint) (int64, error) {
+	result := s.db.WithContext(ctx).Unscoped().Where("user_id = ? AND id <> ?", userID, keepID).Delete(&models.Session{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// DeleteExpired implements SessionStore
+func (s *sessionStore) DeleteExpired(ctx context.Context) (int64, error) {
+	result := s.db.WithContext(ctx).Unscoped().Where("expires_at <= ?", s.now()).Delete(&models.Session{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func newSessionToken() (string, error) {
+	b := make([]byte, sessionTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hex SHA-256 of a session token as stored in the database
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
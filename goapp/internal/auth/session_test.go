@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"goapp/internal/models"
+)
+
+func TestSessionStore(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+
+	user := models.User{
+		Email: "jane@example.com", Username: "jane", PasswordHash: "hash", Active: true,
+		Roles: []models.Role{{Name: "editor", Permissions: []models.Permission{{Name: "posts:update"}}}},
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	store := NewSessionStore(db, time.Hour).(*sessionStore)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	token, session, err := store.Create(ctx, user.ID, SessionMeta{IPAddress: "10.0.0.1", UserAgent: "test"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if session.TokenHash == token {
+		t.Error("Expected only the token hash to be stored")
+	}
+
+	t.Run("Lookup", func(t *testing.T) {
+		got, err := store.Lookup(ctx, token)
+		if err != nil {
+			t.Fatalf("Lookup() error = %v", err)
+		}
+		if got.User.Username != "jane" {
+			t.Errorf("Expected session user jane, got %q", got.User.Username)
+		}
+		if !got.User.HasPermission("posts:update") {
+			t.Error("Expected session user's roles and permissions to be loaded")
+		}
+
+		if _, err := store.Lookup(ctx, "unknown"); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("Expected ErrSessionNotFound, got %v", err)
+		}
+	})
+
+	t.Run("SlidingExpiry", func(t *testing.T) {
+		now = now.Add(50 * time.Minute)
+		got, err := store.Lookup(ctx, token)
+		if err != nil {
+			t.Fatalf("Lookup() error = %v", err)
+		}
+		if !got.ExpiresAt.Equal(now.Add(time.Hour)) {
+			t.Errorf("Expected expiry to be extended to %v, got %v", now.Add(time.Hour), got.ExpiresAt)
+		}
+
+		now = now.Add(50 * time.Minute)
+		if _, err := store.Lookup(ctx, token); err != nil {
+			t.Errorf("Expected extended session to be valid, got %v", err)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		now = now.Add(2 * time.Hour)
+		if _, err := store.Lookup(ctx, token); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("Expected ErrSessionNotFound for expired session, got %v", err)
+		}
+
+		deleted, err := store.DeleteExpired(ctx)
+		if err != nil {
+			t.Fatalf("DeleteExpired() error = %v", err)
+		}
+		if deleted != 1 {
+			t.Errorf("Expected 1 expired session deleted, got %d", deleted)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		token, _, err := store.Create(ctx, user.ID, SessionMeta{})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if err := store.Delete(ctx, token); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if _, err := store.Lookup(ctx, token); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("Expected ErrSessionNotFound after Delete, got %v", err)
+		}
+	})
+
+	t.Run("InactiveUser", func(t *testing.T) {
+		token, _, err := store.Create(ctx, user.ID, SessionMeta{})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		db.Model(&user).UpdateColumn("active", false)
+		if _, err := store.Lookup(ctx, token); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("Expected ErrSessionNotFound for inactive user, got %v", err)
+		}
+	})
+}
+
+func TestSessionStoreRevoke(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+
+	jane := models.User{Email: "jane@example.com", Username: "jane", PasswordHash: "hash", Active: true}
+	john := models.User{Email: "john@example.com", Username: "john", PasswordHash: "hash", Active: true}
+	db.Create(&jane)
+	db.Create(&john)
+
+	store := NewSessionStore(db, time.Hour)
+	var ids []uint
+	for i := 0; i < 3; i++ {
+		_, session, err := store.Create(ctx, jane.ID, SessionMeta{})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		ids = append(ids, session.ID)
+	}
+	_, other, _ := store.Create(ctx, john.ID, SessionMeta{})
+
+	if err := store.Revoke(ctx, jane.ID, other.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound for another user's session, got %v", err)
+	}
+	if err := store.Revoke(ctx, jane.ID, ids[0]); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+
+	sessions, err := store.List(ctx, jane.ID)
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %d, %v", len(sessions), err)
+	}
+
+	revoked, err := store.RevokeOthers(ctx, jane.ID, ids[2])
+	if err != nil || revoked != 1 {
+		t.Errorf("Expected 1 session to be revoked, got %d, %v", revoked, err)
+	}
+	sessions, _ = store.List(ctx, jane.ID)
+	if len(sessions) != 1 || sessions[0].ID != ids[2] {
+		t.Errorf("Expected only the kept session, got %+v", sessions)
+	}
+	if sessions, _ := store.List(ctx, john.ID); len(sessions) != 1 {
+		t.Errorf("Expected other users' sessions to be kept, got %d", len(sessions))
+	}
+}
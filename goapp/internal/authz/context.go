@@ -0,0 +1,27 @@
+package authz
+
+import (
+	"context"
+
+	"goapp/internal/auth"
+)
+
+type policyContextKey struct{}
+
+// WithPolicy returns a copy of ctx carrying the policy for handlers and templates
+func WithPolicy(ctx context.Context, policy *Policy) context.Context {
+	return context.WithValue(ctx, policyContextKey{}, policy)
+}
+
+// PolicyFromContext returns the policy stored in ctx, or nil when there is none
+func PolicyFromContext(ctx context.Context) *Policy {
+	policy, _ := ctx.Value(policyContextKey{}).(*Policy)
+	return policy
+}
+
+// Can reports whether the signed-in user in ctx may perform action on
+// resource. It is meant for templates, to hide actions the user cannot take;
+// without a policy in ctx everything is denied.
+func Can(ctx context.Context, action Action, resource Resource) bool {
+	return PolicyFromContext(ctx).Can(auth.UserFromContext(ctx), action, resource)
+}
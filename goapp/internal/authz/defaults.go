@@ -0,0 +1,85 @@
+package authz
+
+import "goapp/internal/models"
+
+// Resource types covered by the default policy
+const (
+	ResourcePosts    = "posts"
+	ResourceComments = "comments"
+	ResourceAPIKeys  = "api_keys"
+	ResourceTags     = "tags"
+	// ResourceServiceAccounts has no rules; only admins may create keys for service accounts
+	ResourceServiceAccounts = "service_accounts"
+	// ResourceUsers only lets users read and edit their own profile; listing
+	// and managing other users is for admins
+	ResourceUsers = "users"
+	// ResourceRoles has no rules; granting and revoking roles takes the
+	// roles:update permission, which only admins have
+	ResourceRoles = "roles"
+)
+
+// Built-in roles created by RoleStore.Seed
+const (
+	RoleAdmin     = "admin"
+	RoleEditor    = "editor"
+	RoleModerator = "moderator"
+)
+
+// DefaultRoles lists the built-in roles and the permissions they grant
+func DefaultRoles() []models.Role {
+	return []models.Role{
+		{Name: RoleAdmin, Description: "Full access to everything", Permissions: permissions(models.PermissionAll)},
+		{Name: RoleEditor, Description: "Manages all posts and tags", Permissions: permissions(
+			Permission(ResourcePosts, ActionRead),
+			Permission(ResourcePosts, ActionUpdate),
+			Permission(ResourcePosts, ActionDelete),
+			Permission(ResourcePosts, ActionPublish),
+			Permission(ResourceTags, ActionCreate),
+			Permission(ResourceTags, ActionUpdate),
+			Permission(ResourceTags, ActionDelete),
+		)},
+		{Name: RoleModerator, Description: "Manages all comments", Permissions: permissions(
+			Permission(ResourceComments, ActionUpdate),
+			Permission(ResourceComments, ActionDelete),
+		)},
+	}
+}
+
+// DefaultPolicy lets authors manage their own posts and comments, users
+// their own API keys and profile, and everyone read published posts and
+// tags. Editors, moderators and admins get the rest through their role
+// permissions.
+func DefaultPolicy() *Policy {
+	return NewPolicy().
+		Allow(ResourcePosts, ActionRead, published(), Owner()).
+		Allow(ResourcePosts, ActionCreate, Authenticated()).
+		Allow(ResourcePosts, ActionUpdate, Owner()).
+		Allow(ResourcePosts, ActionDelete, Owner()).
+		Allow(ResourcePosts, ActionPublish, Owner()).
+		Allow(ResourceComments, ActionRead, Anyone()).
+		Allow(ResourceComments, ActionCreate, Authenticated()).
+		Allow(ResourceComments, ActionUpdate, Owner()).
+		Allow(ResourceComments, ActionDelete, Owner()).
+		Allow(ResourceAPIKeys, ActionRead, Owner()).
+		Allow(ResourceAPIKeys, ActionCreate, Authenticated()).
+		Allow(ResourceAPIKeys, ActionDelete, Owner()).
+		Allow(ResourceTags, ActionRead, Anyone()).
+		Allow(ResourceUsers, ActionRead, Owner()).
+		Allow(ResourceUsers, ActionUpdate, Owner())
+}
+
+// published allows reading posts that have been published
+func published() Rule {
+	return func(_ *models.User, resource Resource) bool {
+		post, ok := resource.(*models.Post)
+		return ok && post.Published
+	}
+}
+
+func permissions(names ...string) []models.Permission {
+	perms := make([]models.Permission, len(names))
+	for i, name := range names {
+		perms[i] = models.Permission{Name: name}
+	}
+	return perms
+}
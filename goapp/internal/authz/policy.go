@@ -0,0 +1,103 @@
+// Package authz decides what a user may do with a resource. Resource types
+// declare rules per action in a Policy; users are additionally allowed any
+// action their roles grant as a "<type>:<action>" permission.
+package authz
+
+import (
+	"errors"
+
+	"goapp/internal/models"
+)
+
+// Action is something a user can do with a resource
+type Action string
+
+// Actions used by the built-in policy
+const (
+	ActionRead    Action = "read"
+	ActionCreate  Action = "create"
+	ActionUpdate  Action = "update"
+	ActionDelete  Action = "delete"
+	ActionPublish Action = "publish"
+)
+
+var (
+	// ErrUnauthenticated is returned when an anonymous user is denied
+	ErrUnauthenticated = errors.New("authentication required")
+	// ErrForbidden is returned when a signed-in user is denied
+	ErrForbidden = errors.New("permission denied")
+)
+
+// Resource is anything a policy can be declared for
+type Resource interface {
+	ResourceType() string
+}
+
+// Owned is implemented by resources that belong to a user
+type Owned interface {
+	OwnerID() uint
+}
+
+// Type refers to a whole resource type, for actions such as create that have
+// no instance to check
+type Type string
+
+// ResourceType implements Resource
+func (t Type) ResourceType() string {
+	return string(t)
+}
+
+// Permission returns the permission name granting action on resourceType
+func Permission(resourceType string, action Action) string {
+	return resourceType + ":" + string(action)
+}
+
+// Policy maps resource types and actions to the rules allowing them.
+// Anything without a matching rule or permission is denied.
+type Policy struct {
+	rules map[string]map[Action][]Rule
+}
+
+// NewPolicy creates an empty policy that denies everything
+func NewPolicy() *Policy {
+	return &Policy{rules: make(map[string]map[Action][]Rule)}
+}
+
+// Allow lets action be performed on resourceType when any of the rules match
+func (p *Policy) Allow(resourceType string, action Action, rules ...Rule) *Policy {
+	actions, ok := p.rules[resourceType]
+	if !ok {
+		actions = make(map[Action][]Rule)
+		p.rules[resourceType] = actions
+	}
+	actions[action] = append(actions[action], rules...)
+	return p
+}
+
+// Can reports whether user, nil for anonymous requests, may perform action on
+// resource. A nil policy denies everything.
+func (p *Policy) Can(user *models.User, action Action, resource Resource) bool {
+	if p == nil || resource == nil {
+		return false
+	}
+	if user != nil && user.HasPermission(Permission(resource.ResourceType(), action)) {
+		return true
+	}
+	for _, rule := range p.rules[resource.ResourceType()][action] {
+		if rule(user, resource) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize is like Can but explains a denial with ErrUnauthenticated or ErrForbidden
+func (p *Policy) Authorize(user *models.User, action Action, resource Resource) error {
+	if p.Can(user, action, resource) {
+		return nil
+	}
+	if user == nil {
+		return ErrUnauthenticated
+	}
+	return ErrForbidden
+}
@@ -0,0 +1,138 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"goapp/internal/auth"
+	"goapp/internal/models"
+)
+
+func userWithRoles(id uint, roles ...models.Role) *models.User {
+	return &models.User{BaseModel: models.BaseModel{ID: id}, Roles: roles}
+}
+
+func role(name string) models.Role {
+	for _, r := range DefaultRoles() {
+		if r.Name == name {
+			return r
+		}
+	}
+	return models.Role{Name: name}
+}
+
+func TestDefaultPolicy(t *testing.T) {
+	policy := DefaultPolicy()
+
+	author := userWithRoles(1)
+	other := userWithRoles(2)
+	editor := userWithRoles(3, role(RoleEditor))
+	moderator := userWithRoles(4, role(RoleModerator))
+	admin := userWithRoles(5, role(RoleAdmin))
+
+	draft := &models.Post{UserID: author.ID}
+	published := &models.Post{UserID: author.ID, Published: true}
+	comment := &models.Comment{UserID: author.ID}
+
+	tests := []struct {
+		name     string
+		user     *models.User
+		action   Action
+		resource Resource
+		expected bool
+	}{
+		{"anonymous reads published post", nil, ActionRead, published, true},
+		{"anonymous reads draft", nil, ActionRead, draft, false},
+		{"other user reads draft", other, ActionRead, draft, false},
+		{"author reads draft", author, ActionRead, draft, true},
+		{"editor reads draft", editor, ActionRead, draft, true},
+		{"anonymous creates post", nil, ActionCreate, Type(ResourcePosts), false},
+		{"user creates post", other, ActionCreate, Type(ResourcePosts), true},
+		{"author updates post", author, ActionUpdate, published, true},
+		{"other user updates post", other, ActionUpdate, published, false},
+		{"editor updates post", editor, ActionUpdate, published, true},
+		{"moderator updates post", moderator, ActionUpdate, published, false},
+		{"admin deletes post", admin, ActionDelete, published, true},
+		{"author updates comment", author, ActionUpdate, comment, true},
+		{"other user deletes comment", other, ActionDelete, comment, false},
+		{"moderator deletes comment", moderator, ActionDelete, comment, true},
+		{"editor deletes comment", editor, ActionDelete, comment, false},
+		{"anonymous reads tag", nil, ActionRead, &models.Tag{}, true},
+		{"user creates tag", other, ActionCreate, Type(ResourceTags), false},
+		{"editor merges tag", editor, ActionUpdate, &models.Tag{}, true},
+		{"user updates own profile", author, ActionUpdate, author, true},
+		{"user reads other profile", author, ActionRead, other, false},
+		{"user lists users", author, ActionRead, Type(ResourceUsers), false},
+		{"admin deletes user", admin, ActionDelete, other, true},
+		{"undeclared resource type", author, ActionRead, Type("widgets"), false},
+		{"admin on undeclared resource type", admin, ActionRead, Type("widgets"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Can(tt.user, tt.action, tt.resource); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	policy := DefaultPolicy()
+	post := &models.Post{UserID: 1}
+
+	if err := policy.Authorize(nil, ActionUpdate, post); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected ErrUnauthenticated, got %v", err)
+	}
+	if err := policy.Authorize(userWithRoles(2), ActionUpdate, post); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected ErrForbidden, got %v", err)
+	}
+	if err := policy.Authorize(userWithRoles(1), ActionUpdate, post); err != nil {
+		t.Errorf("Expected author to be allowed, got %v", err)
+	}
+
+	var nilPolicy *Policy
+	if nilPolicy.Can(userWithRoles(1, role(RoleAdmin)), ActionRead, post) {
+		t.Error("Expected nil policy to deny everything")
+	}
+}
+
+func TestRules(t *testing.T) {
+	user := userWithRoles(1, models.Role{Name: "staff"})
+
+	if !HasRole("guest", "staff")(user, nil) {
+		t.Error("Expected HasRole to match any of the names")
+	}
+	if HasRole("staff")(nil, nil) {
+		t.Error("Expected HasRole to deny anonymous users")
+	}
+	if !AllOf(Authenticated(), HasRole("staff"))(user, nil) {
+		t.Error("Expected AllOf to allow when every rule does")
+	}
+	if AllOf(Authenticated(), HasRole("admin"))(user, nil) {
+		t.Error("Expected AllOf to deny when a rule does")
+	}
+	if AllOf()(user, nil) {
+		t.Error("Expected empty AllOf to deny")
+	}
+	if Owner()(&models.User{}, &models.Post{}) {
+		t.Error("Expected Owner to deny unsaved users")
+	}
+}
+
+func TestCanFromContext(t *testing.T) {
+	post := &models.Post{UserID: 1}
+	ctx := auth.WithUser(context.Background(), userWithRoles(1))
+
+	if Can(ctx, ActionUpdate, post) {
+		t.Error("Expected denial without a policy in context")
+	}
+	ctx = WithPolicy(ctx, DefaultPolicy())
+	if !Can(ctx, ActionUpdate, post) {
+		t.Error("Expected author to be allowed")
+	}
+	if Can(WithPolicy(context.Background(), DefaultPolicy()), ActionUpdate, post) {
+		t.Error("Expected anonymous user to be denied")
+	}
+}
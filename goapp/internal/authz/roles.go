@@ -0,0 +1,137 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"goapp/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrRoleNotFound is returned when granting or revoking an unknown role
+	ErrRoleNotFound = errors.New("role not found")
+	// ErrUserNotFound is returned when granting or revoking a role of an unknown user
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// RoleStore manages roles and their assignment to users
+type RoleStore interface {
+	// Seed creates the built-in roles and adds any permissions they are missing
+	Seed(ctx context.Context) error
+	// Grant gives the user the named role; granting a held role is a no-op
+	Grant(ctx context.Context, userID uint, role string) error
+	// Revoke takes the named role away from the user
+	Revoke(ctx context.Context, userID uint, role string) error
+	// Roles returns the names of the roles granted to the user
+	Roles(ctx context.Context, userID uint) ([]string, error)
+	// List returns every role by name
+	List(ctx context.Context) ([]models.Role, error)
+}
+
+// roleStore implements RoleStore on the roles and user_roles tables
+type roleStore struct {
+	db *gorm.DB
+}
+
+// NewRoleStore creates a RoleStore backed by db
+func NewRoleStore(db *gorm.DB) RoleStore {
+	return &roleStore{db: db}
+}
+
+// Seed implements RoleStore
+func (s *roleStore) Seed(ctx context.Context) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, def := range DefaultRoles() {
+			role := models.Role{Name: def.Name}
+			if err := tx.Where(models.Role{Name: def.Name}).Attrs(models.Role{Description: def.Description}).FirstOrCreate(&role).Error; err != nil {
+				return fmt.Errorf("failed to seed role %s: %w", def.Name, err)
+			}
+			for _, p := range def.Permissions {
+				perm := models.Permission{Name: p.Name}
+				if err := tx.Where(models.Permission{Name: p.Name}).FirstOrCreate(&perm).Error; err != nil {
+					return fmt.Errorf("failed to seed permission %s: %w", p.Name, err)
+				}
+				if err := tx.Model(&role).Association("Permissions").Append(&perm); err != nil {
+					return fmt.Errorf("failed to grant %s to role %s: %w", p.Name, def.Name, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Grant implements RoleStore
+func (s *roleStore) Grant(ctx context.Context, userID uint, role string) error {
+	user, r, err := s.load(ctx, userID, role)
+	if err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Model(user).Association("Roles").Append(r); err != nil {
+		return fmt.Errorf("failed to grant role: %w", err)
+	}
+	return nil
+}
+
+// Revoke implements RoleStore
+func (s *roleStore) Revoke(ctx context.Context, userID uint, role string) error {
+	user, r, err := s.load(ctx, userID, role)
+	if err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Model(user).Association("Roles").Delete(r); err != nil {
+		return fmt.Errorf("failed to revoke role: %w", err)
+	}
+	return nil
+}
+
+// Roles implements RoleStore
+func (s *roleStore) Roles(ctx context.Context, userID uint) ([]string, error) {
+	var user models.User
+	err := s.db.WithContext(ctx).Preload("Roles", func(db *gorm.DB) *gorm.DB {
+		return db.Order("name")
+	}).First(&user, userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load roles: %w", err)
+	}
+
+	names := make([]string, len(user.Roles))
+	for i, r := range user.Roles {
+		names[i] = r.Name
+	}
+	return names, nil
+}
+
+// List implements RoleStore
+func (s *roleStore) List(ctx context.Context) ([]models.Role, error) {
+	var roles []models.Role
+	if err := s.db.WithContext(ctx).Order("name").Find(&roles).Error; err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	return roles, nil
+}
+
+func (s *roleStore) load(ctx context.Context, userID uint, role string) (*models.User, *models.Role, error) {
+	var user models.User
+	err := s.db.WithContext(ctx).First(&user, userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	var r models.Role
+	err = s.db.WithContext(ctx).Where("name = ?", role).First(&r).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load role: %w", err)
+	}
+	return &user, &r, nil
+}
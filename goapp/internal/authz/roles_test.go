@@ -0,0 +1,108 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"goapp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupRoleStore(t *testing.T) (*gorm.DB, RoleStore, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Permission{}, &models.Role{}, &models.User{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	user := &models.User{Email: "alice@example.com", Username: "alice", PasswordHash: "hash"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	store := NewRoleStore(db)
+	if err := store.Seed(context.Background()); err != nil {
+		t.Fatalf("Seed() error = %v", err)
+	}
+	return db, store, user
+}
+
+func TestRoleStore_Seed(t *testing.T) {
+	db, store, _ := setupRoleStore(t)
+
+	// Seeding again must not duplicate anything
+	if err := store.Seed(context.Background()); err != nil {
+		t.Fatalf("Seed() error = %v", err)
+	}
+
+	var roles, permissions int64
+	db.Model(&models.Role{}).Count(&roles)
+	db.Model(&models.Permission{}).Count(&permissions)
+	if roles != 3 {
+		t.Errorf("Expected 3 roles, got %d", roles)
+	}
+	if permissions != 10 {
+		t.Errorf("Expected 10 permissions, got %d", permissions)
+	}
+
+	list, err := store.List(context.Background())
+	if err != nil || len(list) != 3 || list[0].Name != RoleAdmin {
+		t.Errorf("Expected the roles by name, got %+v, %v", list, err)
+	}
+
+	var editor models.Role
+	db.Preload("Permissions").Where("name = ?", RoleEditor).First(&editor)
+	if !editor.HasPermission(Permission(ResourcePosts, ActionUpdate)) {
+		t.Error("Expected editor role to grant posts:update")
+	}
+}
+
+func TestRoleStore_GrantRevoke(t *testing.T) {
+	db, store, user := setupRoleStore(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := store.Grant(ctx, user.ID, RoleEditor); err != nil {
+			t.Fatalf("Grant() error = %v", err)
+		}
+	}
+	if err := store.Grant(ctx, user.ID, RoleModerator); err != nil {
+		t.Fatalf("Grant() error = %v", err)
+	}
+
+	roles, err := store.Roles(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Roles() error = %v", err)
+	}
+	if expected := []string{RoleEditor, RoleModerator}; !reflect.DeepEqual(roles, expected) {
+		t.Errorf("Expected roles %v, got %v", expected, roles)
+	}
+
+	var loaded models.User
+	db.Preload("Roles.Permissions").First(&loaded, user.ID)
+	if !DefaultPolicy().Can(&loaded, ActionDelete, &models.Post{UserID: 99}) {
+		t.Error("Expected editor to delete other users' posts")
+	}
+
+	if err := store.Revoke(ctx, user.ID, RoleEditor); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if roles, _ := store.Roles(ctx, user.ID); !reflect.DeepEqual(roles, []string{RoleModerator}) {
+		t.Errorf("Expected only moderator after revoke, got %v", roles)
+	}
+
+	if err := store.Grant(ctx, user.ID, "superuser"); !errors.Is(err, ErrRoleNotFound) {
+		t.Errorf("Expected ErrRoleNotFound, got %v", err)
+	}
+	if err := store.Grant(ctx, 999, RoleEditor); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+	if _, err := store.Roles(ctx, 999); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package authz
+
+import "goapp/internal/models"
+
+// Rule allows an action when it returns true. user is nil for anonymous requests.
+type Rule func(user *models.User, resource Resource) bool
+
+// Anyone allows every request, including anonymous ones
+func Anyone() Rule {
+	return func(*models.User, Resource) bool { return true }
+}
+
+// Authenticated allows any signed-in user
+func Authenticated() Rule {
+	return func(user *models.User, _ Resource) bool { return user != nil }
+}
+
+// Owner allows the user a resource belongs to
+func Owner() Rule {
+	return func(user *models.User, resource Resource) bool {
+		owned, ok := resource.(Owned)
+		return ok && user != nil && user.ID != 0 && owned.OwnerID() == user.ID
+	}
+}
+
+// HasRole allows users holding any of the named roles
+func HasRole(names ...string) Rule {
+	return func(user *models.User, _ Resource) bool {
+		if user == nil {
+			return false
+		}
+		for _, name := range names {
+			if user.HasRole(name) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// AllOf allows the request only when every rule does
+func AllOf(rules ...Rule) Rule {
+	return func(user *models.User, resource Resource) bool {
+		for _, rule := range rules {
+			if !rule(user, resource) {
+				return false
+			}
+		}
+		return len(rules) > 0
+	}
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"goapp/internal/apikeys"
+	"goapp/internal/container"
+	"goapp/internal/models"
+)
+
+// runAPIKeys creates, lists and revokes API keys. Creating a key for a
+// service account creates the account on first use.
+func runAPIKeys(ctx context.Context, c *container.Container, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return ErrUsage
+	}
+	db, err := database(c)
+	if err != nil {
+		return err
+	}
+	keys := apikeys.NewService(db, c.Config.APIKey)
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("apikeys create", flag.ContinueOnError)
+		fs.SetOutput(out)
+		name := fs.String("name", "", "name describing what the key is for")
+		user := fs.String("user", "", "email or username of the owner")
+		serviceAccount := fs.String("service", "", "service account that owns the key")
+		scopes := fs.String("scopes", "", "comma-separated scopes, e.g. posts:read,comments:*")
+		expires := fs.Duration("expires", 0, "key lifetime (default API_KEY_DEFAULT_EXPIRATION)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		var owner *models.User
+		switch {
+		case *user != "" && *serviceAccount == "":
+			owner, err = findUser(ctx, db, *user)
+		case *serviceAccount != "" && *user == "":
+			owner, err = keys.ServiceAccount(ctx, *serviceAccount)
+		default:
+			return fmt.Errorf("exactly one of --user and --service is required: %w", ErrUsage)
+		}
+		if err != nil {
+			return err
+		}
+
+		token, key, err := keys.Create(ctx, apikeys.CreateInput{
+			Name:      *name,
+			UserID:    owner.ID,
+			Scopes:    strings.Split(*scopes, ","),
+			ExpiresIn: *expires,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "created API key %d (%s) for %s\n", key.ID, key.KeyID, owner.Username)
+		fmt.Fprintln(out, token)
+		return nil
+
+	case "list":
+		var userID uint
+		if len(args) > 1 {
+			owner, err := findUser(ctx, db, args[1])
+			if err != nil {
+				return err
+			}
+			userID = owner.ID
+		}
+		list, err := keys.List(ctx, userID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, key := range list {
+			status := "active"
+			if key.RevokedAt != nil {
+				status = "revoked"
+			} else if !key.Active(now) {
+				status = "expired"
+			}
+			fmt.Fprintf(out, "%d\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.KeyID, key.User.Username, key.Name, key.Scopes, status)
+		}
+		return nil
+
+	case "revoke":
+		if len(args) != 2 {
+			return ErrUsage
+		}
+		id, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid API key ID %q: %w", args[1], ErrUsage)
+		}
+		if err := keys.Revoke(ctx, uint(id)); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "revoked API key %d\n", id)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown apikeys command %q: %w", args[0], ErrUsage)
+	}
+}
@@ -0,0 +1,40 @@
+// Package cli implements the administrative subcommands of the goapp binary
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"goapp/internal/container"
+)
+
+// ErrUsage is returned when the arguments do not name a known command
+var ErrUsage = errors.New("usage: goapp migrate | cleanup | maintenance on|off|status [flags] | roles list|grant|revoke <user> [role] | apikeys create|list|revoke | export users|posts|comments [--format jsonl|csv] [--output file] | import markdown <dir> [--author user] [--dry-run] [--upsert]")
+
+// Run executes the subcommand named by args against the container
+func Run(ctx context.Context, c *container.Container, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return ErrUsage
+	}
+
+	switch args[0] {
+	case "migrate":
+		return runMigrate(ctx, c, args[1:], out)
+	case "cleanup":
+		return runCleanup(ctx, c, args[1:], out)
+	case "maintenance":
+		return runMaintenance(ctx, c, args[1:], out)
+	case "roles":
+		return runRoles(ctx, c, args[1:], out)
+	case "apikeys":
+		return runAPIKeys(ctx, c, args[1:], out)
+	case "export":
+		return runExport(ctx, c, args[1:], out)
+	case "import":
+		return runImport(ctx, c, args[1:], out)
+	default:
+		return fmt.Errorf("unknown command %q: %w", args[0], ErrUsage)
+	}
+}
@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"goapp/internal/authz"
+	"goapp/internal/config"
+	"goapp/internal/container"
+	"goapp/internal/db/postgres"
+	"goapp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// sqliteDatabase adapts an in-memory SQLite connection to postgres.Database
+type sqliteDatabase struct {
+	db *gorm.DB
+}
+
+func (d *sqliteDatabase) DB() *gorm.DB                                              { return d.db }
+func (d *sqliteDatabase) Close() error                                              { return nil }
+func (d *sqliteDatabase) Ping(ctx context.Context) error                            { return nil }
+func (d *sqliteDatabase) AutoMigrate(ctx context.Context, dst ...interface{}) error { return nil }
+func (d *sqliteDatabase) Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return d.db.Transaction(fn)
+}
+func (d *sqliteDatabase) WithContext(ctx context.Context) postgres.Database { return d }
+func (d *sqliteDatabase) Health(ctx context.Context) error                  { return nil }
+
+func setupTestContainer(t *testing.T) *container.Container {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Setting{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	return &container.Container{
+		Config:   config.Config{Maintenance: config.MaintenanceConfig{DefaultRetryAfter: 5 * time.Minute}},
+		Database: &sqliteDatabase{db: db},
+	}
+}
+
+func run(t *testing.T, c *container.Container, args ...string) string {
+	var out bytes.Buffer
+	if err := Run(context.Background(), c, args, &out); err != nil {
+		t.Fatalf("Run(%v) error = %v", args, err)
+	}
+	return out.String()
+}
+
+func TestMaintenanceCommand(t *testing.T) {
+	c := setupTestContainer(t)
+
+	if out := run(t, c, "maintenance", "status"); !strings.Contains(out, "maintenance mode: off") {
+		t.Errorf("Expected maintenance mode off, got %q", out)
+	}
+
+	out := run(t, c, "maintenance", "on", "--message", "Upgrading", "--retry-after", "90s")
+	for _, expected := range []string{"maintenance mode: on", "message: Upgrading", "retry after: 1m30s"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected output to contain %q, got %q", expected, out)
+		}
+	}
+	if out := run(t, c, "maintenance", "status"); !strings.Contains(out, "maintenance mode: on") {
+		t.Errorf("Expected maintenance mode on, got %q", out)
+	}
+
+	if out := run(t, c, "maintenance", "off"); !strings.Contains(out, "maintenance mode: off") {
+		t.Errorf("Expected maintenance mode off, got %q", out)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	c := setupTestContainer(t)
+
+	for _, args := range [][]string{nil, {"unknown"}, {"maintenance"}, {"maintenance", "toggle"}, {"roles", "list"}} {
+		if err := Run(context.Background(), c, args, &bytes.Buffer{}); !errors.Is(err, ErrUsage) {
+			t.Errorf("Run(%v): expected ErrUsage, got %v", args, err)
+		}
+	}
+
+	if err := Run(context.Background(), &container.Container{}, []string{"maintenance", "status"}, &bytes.Buffer{}); err == nil {
+		t.Error("Expected error without a database")
+	}
+}
+
+func TestMigrateAndCleanupCommands(t *testing.T) {
+	c := setupTestContainer(t)
+
+	if out := run(t, c, "migrate"); !strings.Contains(out, "up to date") {
+		t.Errorf("Expected migrate confirmation, got %q", out)
+	}
+	if !c.Database.DB().Migrator().HasTable(&models.Session{}) {
+		t.Error("Expected sessions table to be created")
+	}
+
+	if out := run(t, c, "cleanup"); !strings.Contains(out, "deleted 0 expired sessions, 0 expired refresh tokens, 0 expired idempotency keys and 0 old login events") {
+		t.Errorf("Unexpected cleanup output %q", out)
+	}
+}
+
+func TestRolesCommand(t *testing.T) {
+	c := setupTestContainer(t)
+	run(t, c, "migrate")
+
+	user := &models.User{Email: "alice@example.com", Username: "alice", PasswordHash: "hash"}
+	if err := c.Database.DB().Create(user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if out := run(t, c, "roles", "list", "alice"); !strings.Contains(out, "alice has no roles") {
+		t.Errorf("Expected no roles, got %q", out)
+	}
+	if out := run(t, c, "roles", "grant", "Alice@Example.com", "editor"); !strings.Contains(out, "alice: editor") {
+		t.Errorf("Expected editor role, got %q", out)
+	}
+	if out := run(t, c, "roles", "revoke", "alice", "editor"); !strings.Contains(out, "alice has no roles") {
+		t.Errorf("Expected role to be revoked, got %q", out)
+	}
+
+	if err := Run(context.Background(), c, []string{"roles", "grant", "alice", "superuser"}, &bytes.Buffer{}); !errors.Is(err, authz.ErrRoleNotFound) {
+		t.Errorf("Expected ErrRoleNotFound, got %v", err)
+	}
+	if err := Run(context.Background(), c, []string{"roles", "list", "bob"}, &bytes.Buffer{}); !errors.Is(err, authz.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+	if err := Run(context.Background(), c, []string{"roles", "promote", "alice"}, &bytes.Buffer{}); !errors.Is(err, ErrUsage) {
+		t.Errorf("Expected ErrUsage, got %v", err)
+	}
+}
+
+func TestAPIKeysCommand(t *testing.T) {
+	c := setupTestContainer(t)
+	c.Config.APIKey = config.APIKeyConfig{TokenPrefix: "goapp"}
+	run(t, c, "migrate")
+
+	out := run(t, c, "apikeys", "create", "--service", "billing", "--name", "billing", "--scopes", "posts:read,comments:read")
+	if !strings.Contains(out, "for billing") || !strings.Contains(out, "goapp_") {
+		t.Errorf("Expected new key for billing, got %q", out)
+	}
+	if out := run(t, c, "apikeys", "list"); !strings.Contains(out, "billing\tbilling\tcomments:read posts:read\tactive") {
+		t.Errorf("Expected active key in list, got %q", out)
+	}
+	if out := run(t, c, "apikeys", "revoke", "1"); !strings.Contains(out, "revoked API key 1") {
+		t.Errorf("Expected revoke confirmation, got %q", out)
+	}
+	if out := run(t, c, "apikeys", "list", "billing"); !strings.Contains(out, "revoked") {
+		t.Errorf("Expected revoked key in list, got %q", out)
+	}
+
+	for _, args := range [][]string{{"apikeys", "create", "--name", "x"}, {"apikeys", "revoke", "one"}, {"apikeys", "rotate"}} {
+		if err := Run(context.Background(), c, args, &bytes.Buffer{}); !errors.Is(err, ErrUsage) {
+			t.Errorf("Run(%v): expected ErrUsage, got %v", args, err)
+		}
+	}
+}
+
+func TestImportAndExportCommands(t *testing.T) {
+	c := setupTestContainer(t)
+	run(t, c, "migrate")
+
+	user := &models.User{Email: "alice@example.com", Username: "alice", PasswordHash: "hash"}
+	if err := c.Database.DB().Create(user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "first-post.md"), []byte("---\ntitle: First Post\ntags: [go]\n---\nHello."), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if out := run(t, c, "import", "markdown", dir, "--author", "alice", "--dry-run"); !strings.Contains(out, "dry run: 1 created, 0 updated, 0 skipped, 0 failed") {
+		t.Errorf("Unexpected dry run output %q", out)
+	}
+	out := run(t, c, "import", "markdown", "--author", "alice", dir)
+	if !strings.Contains(out, "created\tfirst-post.md\tfirst-post") || !strings.Contains(out, "imported: 1 created") {
+		t.Errorf("Unexpected import output %q", out)
+	}
+	if out := run(t, c, "import", "markdown", dir, "--upsert"); !strings.Contains(out, "imported: 0 created, 1 updated") {
+		t.Errorf("Unexpected upsert output %q", out)
+	}
+
+	out = run(t, c, "export", "posts", "--format", "csv")
+	if !strings.HasPrefix(out, "id,slug,title") || !strings.Contains(out, "1,first-post,First Post") {
+		t.Errorf("Unexpected export output %q", out)
+	}
+	file := filepath.Join(dir, "users.jsonl")
+	if out := run(t, c, "export", "users", "--output", file); !strings.Contains(out, "exported 1 users to "+file) {
+		t.Errorf("Unexpected export output %q", out)
+	}
+	if data, err := os.ReadFile(file); err != nil || !strings.Contains(string(data), `"username":"alice"`) {
+		t.Errorf("Expected users in the export file, got %q, %v", data, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "broken.md"), []byte("---\ntitle: Broken\n"), 0o644)
+	if err := Run(context.Background(), c, []string{"import", "markdown", dir}, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "1 of 2 files failed") {
+		t.Errorf("Expected failed files to be reported, got %v", err)
+	}
+	for _, args := range [][]string{{"export"}, {"export", "secrets"}, {"export", "posts", "--format", "xml"}, {"import", "markdown"}, {"import", "html", dir}, {"import", "markdown", dir, "extra"}} {
+		if err := Run(context.Background(), c, args, &bytes.Buffer{}); !errors.Is(err, ErrUsage) {
+			t.Errorf("Run(%v): expected ErrUsage, got %v", args, err)
+		}
+	}
+}
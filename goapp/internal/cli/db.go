@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"goapp/internal/auth"
+	"goapp/internal/authz"
+	"goapp/internal/container"
+	"goapp/internal/db/migrations"
+	"goapp/internal/idempotency"
+	"gorm.io/gorm"
+)
+
+// runMigrate creates or updates the database schema for all models and
+// seeds the built-in roles
+func runMigrate(ctx context.Context, c *container.Container, args []string, out io.Writer) error {
+	db, err := database(c)
+	if err != nil {
+		return err
+	}
+
+	if err := migrations.NewMigrator(db.WithContext(ctx)).AutoMigrate(); err != nil {
+		return err
+	}
+	if err := authz.NewRoleStore(db).Seed(ctx); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "database schema is up to date")
+	return nil
+}
+
+// runCleanup deletes expired sessions, refresh tokens and idempotency keys,
+// and login history older than AUTH_LOGIN_HISTORY_RETENTION; run it
+// periodically, e.g. from cron
+func runCleanup(ctx context.Context, c *container.Container, args []string, out io.Writer) error {
+	db, err := database(c)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := auth.NewSessionStore(db, c.Config.Auth.SessionTTL).DeleteExpired(ctx)
+	if err != nil {
+		return err
+	}
+	keys, err := idempotency.NewStore(db, c.Config.Idempotency.TTL).DeleteExpired(ctx)
+	if err != nil {
+		return err
+	}
+	logins, err := auth.NewLoginHistory(db).DeleteBefore(ctx, time.Now().Add(-c.Config.Auth.LoginHistoryRetention))
+	if err != nil {
+		return err
+	}
+
+	var refreshTokens int64
+	if c.Tokens != nil {
+		if refreshTokens, err = c.Tokens.DeleteExpired(ctx); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(out, "deleted %d expired sessions, %d expired refresh tokens, %d expired idempotency keys and %d old login events\n", sessions, refreshTokens, keys, logins)
+	return nil
+}
+
+func database(c *container.Container) (*gorm.DB, error) {
+	if c.Database == nil || c.Database.DB() == nil {
+		return nil, errors.New("this command requires a database connection")
+	}
+	return c.Database.DB(), nil
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"goapp/internal/container"
+	"goapp/internal/maintenance"
+)
+
+// runMaintenance toggles or reports maintenance mode. The state lives in the
+// database so that running instances pick it up on their next refresh.
+func runMaintenance(ctx context.Context, c *container.Container, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return ErrUsage
+	}
+	db, err := database(c)
+	if err != nil {
+		return err
+	}
+	manager := maintenance.NewManager(maintenance.NewDBStore(db), 0)
+
+	fs := flag.NewFlagSet("maintenance "+args[0], flag.ContinueOnError)
+	fs.SetOutput(out)
+	message := fs.String("message", "", "message shown to visitors")
+	retryAfter := fs.Duration("retry-after", c.Config.Maintenance.DefaultRetryAfter, "Retry-After advertised to clients")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	var status maintenance.Status
+	switch args[0] {
+	case "on":
+		status, err = manager.Enable(ctx, *message, *retryAfter)
+	case "off":
+		status, err = manager.Disable(ctx)
+	case "status":
+		status, err = manager.Refresh(ctx)
+	default:
+		return fmt.Errorf("unknown maintenance command %q: %w", args[0], ErrUsage)
+	}
+	if err != nil {
+		return err
+	}
+
+	printStatus(out, status)
+	return nil
+}
+
+func printStatus(out io.Writer, status maintenance.Status) {
+	if !status.Enabled {
+		fmt.Fprintln(out, "maintenance mode: off")
+		return
+	}
+	fmt.Fprintln(out, "maintenance mode: on")
+	if status.Message != "" {
+		fmt.Fprintf(out, "message: %s\n", status.Message)
+	}
+	if status.RetryAfterSeconds > 0 {
+		fmt.Fprintf(out, "retry after: %s\n", status.RetryAfter())
+	}
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"goapp/internal/auth"
+	"goapp/internal/authz"
+	"goapp/internal/container"
+	"goapp/internal/models"
+	"gorm.io/gorm"
+)
+
+// runRoles lists, grants or revokes the roles of a user named by email or username
+func runRoles(ctx context.Context, c *container.Container, args []string, out io.Writer) error {
+	if len(args) < 2 {
+		return ErrUsage
+	}
+	db, err := database(c)
+	if err != nil {
+		return err
+	}
+	store := authz.NewRoleStore(db)
+
+	user, err := findUser(ctx, db, args[1])
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case args[0] == "list" && len(args) == 2:
+	case args[0] == "grant" && len(args) == 3:
+		err = store.Grant(ctx, user.ID, args[2])
+	case args[0] == "revoke" && len(args) == 3:
+		err = store.Revoke(ctx, user.ID, args[2])
+	default:
+		return fmt.Errorf("unknown roles command %q: %w", strings.Join(args, " "), ErrUsage)
+	}
+	if err != nil {
+		return err
+	}
+
+	roles, err := store.Roles(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if len(roles) == 0 {
+		fmt.Fprintf(out, "%s has no roles\n", user.Username)
+		return nil
+	}
+	fmt.Fprintf(out, "%s: %s\n", user.Username, strings.Join(roles, ", "))
+	return nil
+}
+
+func findUser(ctx context.Context, db *gorm.DB, identifier string) (*models.User, error) {
+	var user models.User
+	err := db.WithContext(ctx).Where("email = ? OR username = ?", auth.NormalizeEmail(identifier), identifier).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%q: %w", identifier, authz.ErrUserNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	return &user, nil
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"goapp/internal/container"
+	"goapp/internal/transfer"
+)
+
+// runExport writes every user, post or comment as JSON Lines or CSV to
+// standard output or a file
+func runExport(ctx context.Context, c *container.Container, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return ErrUsage
+	}
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(out)
+	format := fs.String("format", transfer.FormatJSONL, "jsonl or csv")
+	output := fs.String("output", "", "file to write instead of standard output")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if err := transfer.Check(args[0], *format); err != nil {
+		return fmt.Errorf("%v: %w", err, ErrUsage)
+	}
+	db, err := database(c)
+	if err != nil {
+		return err
+	}
+
+	w := out
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	count, err := transfer.NewExporter(db).Export(ctx, w, args[0], *format)
+	if err != nil {
+		return err
+	}
+	if *output != "" {
+		fmt.Fprintf(out, "exported %d %s to %s\n", count, args[0], *output)
+	}
+	return nil
+}
+
+// runImport imports a directory of Markdown files with front matter as posts
+func runImport(ctx context.Context, c *container.Container, args []string, out io.Writer) error {
+	if len(args) < 2 || args[0] != "markdown" {
+		return ErrUsage
+	}
+	// The directory may come before or after the flags
+	args, dir := args[1:], ""
+	if !strings.HasPrefix(args[0], "-") {
+		args, dir = args[1:], args[0]
+	}
+	fs := flag.NewFlagSet("import markdown", flag.ContinueOnError)
+	fs.SetOutput(out)
+	author := fs.String("author", "", "email or username of the author of files without an author field")
+	dryRun := fs.Bool("dry-run", false, "report what would be imported without saving anything")
+	upsert := fs.Bool("upsert", false, "update posts whose slug already exists instead of skipping them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	switch {
+	case dir == "" && fs.NArg() == 1:
+		dir = fs.Arg(0)
+	case dir == "" || fs.NArg() > 0:
+		return fmt.Errorf("import markdown needs exactly one directory: %w", ErrUsage)
+	}
+	db, err := database(c)
+	if err != nil {
+		return err
+	}
+
+	opts := transfer.ImportOptions{DryRun: *dryRun, Upsert: *upsert, AnyAuthor: true}
+	if *author != "" {
+		user, err := findUser(ctx, db, *author)
+		if err != nil {
+			return err
+		}
+		opts.AuthorID = user.ID
+	}
+	docs, err := transfer.ReadMarkdownDir(os.DirFS(dir))
+	if err != nil {
+		return err
+	}
+	report, err := transfer.NewImporter(db).ImportMarkdown(ctx, docs, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, result := range report.Results {
+		detail := result.Slug
+		if result.Error != "" {
+			detail = result.Error
+		}
+		fmt.Fprintf(out, "%s\t%s\t%s\n", result.Action, result.File, detail)
+	}
+	prefix := "imported"
+	if report.DryRun {
+		prefix = "dry run"
+	}
+	fmt.Fprintf(out, "%s: %d created, %d updated, %d skipped, %d failed\n", prefix, report.Created, report.Updated, report.Skipped, report.Failed)
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d files failed to import", report.Failed, len(report.Results))
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+// Package comments stores threaded comments on posts and loads each post's
+// comments as a tree with a single recursive query.
+package comments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"goapp/internal/auth"
+	"goapp/internal/config"
+	"goapp/internal/markdown"
+	"goapp/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when no comment has the requested ID
+var ErrNotFound = errors.New("comment not found")
+
+// CreateInput holds the fields of a new comment
+type CreateInput struct {
+	PostID   uint
+	ParentID *uint // nil for top-level comments
+	AuthorID uint
+	Content  string
+}
+
+// Service creates, edits, deletes and lists comments
+type Service interface {
+	// Tree returns the top-level comments of a post, oldest first, with
+	// depth levels of replies nested in Replies and their authors loaded.
+	// A negative depth, or one above MaxDepth, loads MaxDepth levels.
+	// Deleted comments are kept, without content, while they have replies.
+	Tree(ctx context.Context, postID uint, depth int) ([]models.Comment, error)
+	// Get returns the comment with id, with its author loaded
+	Get(ctx context.Context, id uint) (*models.Comment, error)
+	// Create validates input and stores a new comment or reply
+	Create(ctx context.Context, input CreateInput) (*models.Comment, error)
+	// Update validates and replaces the content of comment
+	Update(ctx context.Context, comment *models.Comment, content string) error
+	// Delete soft-deletes comment; its replies stay in the thread
+	Delete(ctx context.Context, comment *models.Comment) error
+	// MaxDepth is how many levels of replies may nest below a top-level comment
+	MaxDepth() int
+}
+
+// service implements Service on the comments table
+type service struct {
+	db  *gorm.DB
+	cfg config.CommentsConfig
+}
+
+// NewService creates a comments Service
+func NewService(db *gorm.DB, cfg config.CommentsConfig) Service {
+	return &service{db: db, cfg: cfg}
+}
+
+// MaxDepth implements Service
+func (s *service) MaxDepth() int {
+	return s.cfg.MaxDepth
+}
+
+// threadQuery selects a post's comments, including deleted ones, down to a
+// given number of reply levels. It works on PostgreSQL and SQLite.
+const threadQuery = `WITH RECURSIVE thread AS (
+	SELECT comments.*, 0 AS depth FROM comments
+	WHERE comments.post_id = ? AND comments.parent_id IS NULL
+	UNION ALL
+	SELECT comments.*, thread.depth + 1 FROM comments
+	JOIN thread ON comments.parent_id = thread.id
+	WHERE thread.depth < ?
+)
+SELECT * FROM thread ORDER BY created_at, id`
+
+// Tree implements Service
+func (s *service) Tree(ctx context.Context, postID uint, depth int) ([]models.Comment, error) {
+	if depth < 0 || depth > s.cfg.MaxDepth {
+		depth = s.cfg.MaxDepth
+	}
+
+	var rows []models.Comment
+	if err := s.db.WithContext(ctx).Raw(threadQuery, postID, depth).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load comments: %w", err)
+	}
+	if err := s.loadAuthors(ctx, rows); err != nil {
+		return nil, err
+	}
+
+	children := map[uint][]models.Comment{}
+	for _, row := range rows {
+		var parent uint
+		if row.ParentID != nil {
+			parent = *row.ParentID
+		}
+		children[parent] = append(children[parent], row)
+	}
+	return nest(children, 0), nil
+}
+
+// loadAuthors sets the User of each comment with one query
+func (s *service) loadAuthors(ctx context.Context, rows []models.Comment) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.UserID)
+	}
+	var users []models.User
+	if err := s.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return fmt.Errorf("failed to load comment authors: %w", err)
+	}
+	byID := make(map[uint]models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	for i := range rows {
+		rows[i].User = byID[rows[i].UserID]
+	}
+	return nil
+}
+
+// nest builds the replies of parentID from children, dropping deleted
+// comments that have no replies left
+func nest(children map[uint][]models.Comment, parentID uint) []models.Comment {
+	var nodes []models.Comment
+	for _, comment := range children[parentID] {
+		comment.Replies = nest(children, comment.ID)
+		if comment.DeletedAt.Valid {
+			if len(comment.Replies) == 0 {
+				continue
+			}
+			comment.Content, comment.ContentHTML = "", ""
+		}
+		nodes = append(nodes, comment)
+	}
+	return nodes
+}
+
+// Get implements Service
+func (s *service) Get(ctx context.Context, id uint) (*models.Comment, error) {
+	var comment models.Comment
+	err := s.db.WithContext(ctx).Preload("User").First(&comment, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load comment: %w", err)
+	}
+	return &comment, nil
+}
+
+// Create implements Service
+func (s *service) Create(ctx context.Context, input CreateInput) (*models.Comment, error) {
+	comment := &models.Comment{
+		Content:  strings.TrimSpace(input.Content),
+		UserID:   input.AuthorID,
+		PostID:   input.PostID,
+		ParentID: input.ParentID,
+	}
+	fields := s.validate(comment.Content)
+	if input.ParentID != nil {
+		msg, err := s.checkParent(ctx, input.PostID, *input.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if msg != "" {
+			fields["parent_id"] = msg
+		}
+	}
+	if len(fields) > 0 {
+		return nil, &auth.ValidationError{Fields: fields}
+	}
+
+	comment.ContentHTML = markdown.RenderSnippet(comment.Content)
+	if err := s.db.WithContext(ctx).Create(comment).Error; err != nil {
+		return nil, fmt.Errorf("failed to create comment: %w", err)
+	}
+	return s.Get(ctx, comment.ID)
+}
+
+// ancestorsQuery counts a comment and its ancestors
+const ancestorsQuery = `WITH RECURSIVE ancestors AS (
+	SELECT id, parent_id FROM comments WHERE id = ?
+	UNION ALL
+	SELECT comments.id, comments.parent_id FROM comments
+	JOIN ancestors ON comments.id = ancestors.parent_id
+)
+SELECT COUNT(*) FROM ancestors`
+
+// checkParent explains why a reply to parentID may not be added to postID,
+// or returns an empty message when it may
+func (s *service) checkParent(ctx context.Context, postID, parentID uint) (string, error) {
+	var parent models.Comment
+	err := s.db.WithContext(ctx).Select("id", "post_id").First(&parent, parentID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && parent.PostID != postID) {
+		return "does not exist", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load parent comment: %w", err)
+	}
+
+	// A top-level parent counts 1, so the reply sits at that depth
+	var depth int
+	if err := s.db.WithContext(ctx).Raw(ancestorsQuery, parentID).Scan(&depth).Error; err != nil {
+		return "", fmt.Errorf("failed to count ancestors: %w", err)
+	}
+	if depth > s.cfg.MaxDepth {
+		return fmt.Sprintf("replies cannot be nested more than %d levels deep", s.cfg.MaxDepth), nil
+	}
+	return "", nil
+}
+
+// Update implements Service
+func (s *service) Update(ctx context.Context, comment *models.Comment, content string) error {
+	content = strings.TrimSpace(content)
+	if fields := s.validate(content); len(fields) > 0 {
+		return &auth.ValidationError{Fields: fields}
+	}
+	err := s.db.WithContext(ctx).Model(comment).
+		Updates(map[string]interface{}{"content": content, "content_html": markdown.RenderSnippet(content)}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update comment: %w", err)
+	}
+	return nil
+}
+
+// Delete implements Service
+func (s *service) Delete(ctx context.Context, comment *models.Comment) error {
+	if err := s.db.WithContext(ctx).Delete(comment).Error; err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+	return nil
+}
+
+// ContentHTML returns the rendered content of comment, rendering it when
+// the comment was saved before the HTML was cached
+func ContentHTML(comment *models.Comment) string {
+	if comment.ContentHTML == "" && comment.Content != "" {
+		return markdown.RenderSnippet(comment.Content)
+	}
+	return comment.ContentHTML
+}
+
+func (s *service) validate(content string) map[string]string {
+	fields := map[string]string{}
+	if content == "" {
+		fields["content"] = "is required"
+	} else if s.cfg.MaxLength > 0 && utf8.RuneCountInString(content) > s.cfg.MaxLength {
+		fields["content"] = fmt.Sprintf("must be at most %d characters", s.cfg.MaxLength)
+	}
+	return fields
+}
@@ -0,0 +1,131 @@
+package comments
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"goapp/internal/auth"
+	"goapp/internal/config"
+	"goapp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestService(t *testing.T) (Service, *models.Post) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Post{}, &models.Comment{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	user := &models.User{Email: "jane@example.com", Username: "jane", PasswordHash: "hash", Active: true}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	post := &models.Post{Title: "Hello", Slug: "hello", Published: true, UserID: user.ID}
+	if err := db.Create(post).Error; err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	return NewService(db, config.CommentsConfig{MaxDepth: 2, MaxLength: 20}), post
+}
+
+// reply creates a comment on post, under parent when it is not nil
+func reply(t *testing.T, s Service, post *models.Post, parent *models.Comment, content string) *models.Comment {
+	t.Helper()
+	input := CreateInput{PostID: post.ID, AuthorID: post.UserID, Content: content}
+	if parent != nil {
+		input.ParentID = &parent.ID
+	}
+	comment, err := s.Create(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return comment
+}
+
+func TestTree(t *testing.T) {
+	ctx := context.Background()
+	s, post := setupTestService(t)
+
+	first := reply(t, s, post, nil, "first")
+	second := reply(t, s, post, nil, "second")
+	child := reply(t, s, post, first, "child")
+	reply(t, s, post, child, "grandchild")
+
+	tree, err := s.Tree(ctx, post.ID, -1)
+	if err != nil {
+		t.Fatalf("Tree() error = %v", err)
+	}
+	if len(tree) != 2 || tree[0].ID != first.ID || tree[1].ID != second.ID {
+		t.Fatalf("Expected the two top-level comments in order, got %+v", tree)
+	}
+	if len(tree[0].Replies) != 1 || len(tree[0].Replies[0].Replies) != 1 || tree[0].Replies[0].Replies[0].Content != "grandchild" {
+		t.Errorf("Expected first to hold child and grandchild, got %+v", tree[0].Replies)
+	}
+	if tree[0].User.Username != "jane" || tree[0].Replies[0].User.Username != "jane" {
+		t.Error("Expected authors to be loaded")
+	}
+
+	shallow, _ := s.Tree(ctx, post.ID, 1)
+	if len(shallow[0].Replies) != 1 || len(shallow[0].Replies[0].Replies) != 0 {
+		t.Errorf("Expected one level of replies, got %+v", shallow[0].Replies)
+	}
+
+	// Deleted comments keep their place while they have replies
+	if err := s.Delete(ctx, first); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := s.Delete(ctx, second); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	tree, _ = s.Tree(ctx, post.ID, -1)
+	if len(tree) != 1 || !tree[0].DeletedAt.Valid || tree[0].Content != "" || len(tree[0].Replies) != 1 {
+		t.Errorf("Expected only a content-less placeholder for first, got %+v", tree)
+	}
+	if _, err := s.Get(ctx, first.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a deleted comment, got %v", err)
+	}
+}
+
+func TestCreateValidation(t *testing.T) {
+	ctx := context.Background()
+	s, post := setupTestService(t)
+
+	top := reply(t, s, post, nil, "top")
+	child := reply(t, s, post, top, "child")
+	grandchild := reply(t, s, post, child, "grandchild")
+
+	tests := []struct {
+		name  string
+		input CreateInput
+		field string
+	}{
+		{"Empty", CreateInput{PostID: post.ID, AuthorID: 1, Content: "  "}, "content"},
+		{"TooLong", CreateInput{PostID: post.ID, AuthorID: 1, Content: "this comment is far too long"}, "content"},
+		{"UnknownParent", CreateInput{PostID: post.ID, AuthorID: 1, Content: "hi", ParentID: new(uint)}, "parent_id"},
+		{"OtherPost", CreateInput{PostID: post.ID + 1, AuthorID: 1, Content: "hi", ParentID: &top.ID}, "parent_id"},
+		{"TooDeep", CreateInput{PostID: post.ID, AuthorID: 1, Content: "hi", ParentID: &grandchild.ID}, "parent_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Create(ctx, tt.input)
+			var validationErr *auth.ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Fields[tt.field] == "" {
+				t.Errorf("Expected a validation error on %s, got %v", tt.field, err)
+			}
+		})
+	}
+
+	if top.ContentHTML != "<p>top</p>\n" {
+		t.Errorf("Expected the content to be rendered, got %q", top.ContentHTML)
+	}
+	if err := s.Update(ctx, child, "*edited*"); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got, _ := s.Get(ctx, child.ID); got.Content != "*edited*" || got.ContentHTML != "<p><em>edited</em></p>\n" {
+		t.Errorf("Expected content to be edited and rendered again, got %q, %q", got.Content, got.ContentHTML)
+	}
+}
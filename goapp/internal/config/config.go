@@ -16,6 +16,22 @@ type Config struct {
 	Kafka         KafkaConfig        `envconfig:"KAFKA"`
 	Observability ObservabilityConfig `envconfig:"OTEL"`
 	HTTPClient    HTTPClientConfig    `envconfig:"HTTP_CLIENT"`
+	Security      SecurityConfig      `envconfig:"SECURITY"`
+	Compression   CompressionConfig   `envconfig:"COMPRESSION"`
+	Idempotency   IdempotencyConfig   `envconfig:"IDEMPOTENCY"`
+	RateLimit     RateLimitConfig     `envconfig:"RATE_LIMIT"`
+	Maintenance   MaintenanceConfig   `envconfig:"MAINTENANCE"`
+	LoadShed      LoadShedConfig      `envconfig:"LOAD_SHED"`
+	Auth          AuthConfig          `envconfig:"AUTH"`
+	JWT           JWTConfig           `envconfig:"JWT"`
+	APIKey        APIKeyConfig        `envconfig:"API_KEY"`
+	OIDC          OIDCConfig          `envconfig:"OIDC"`
+	Mail          MailConfig          `envconfig:"MAIL"`
+	TwoFactor     TwoFactorConfig     `envconfig:"TWO_FACTOR"`
+	Comments      CommentsConfig      `envconfig:"COMMENTS"`
+	Bulk          BulkConfig          `envconfig:"BULK"`
+	Publishing    PublishingConfig    `envconfig:"PUBLISHING"`
+	Views         ViewsConfig         `envconfig:"VIEWS"`
 }
 
 // AppConfig holds application-specific configuration
@@ -120,6 +136,179 @@ type HTTPClientConfig struct {
 	Headers   map[string]string `envconfig:"HEADERS"`
 }
 
+// SecurityConfig holds HTTP security header and CSRF configuration
+type SecurityConfig struct {
+	// Response headers
+	HSTSMaxAge            int    `envconfig:"HSTS_MAX_AGE" default:"31536000"` // seconds, 0 disables HSTS
+	HSTSIncludeSubdomains bool   `envconfig:"HSTS_INCLUDE_SUBDOMAINS" default:"true"`
+	ContentSecurityPolicy string `envconfig:"CONTENT_SECURITY_POLICY" default:"default-src 'self'; script-src 'self' 'unsafe-eval' https://unpkg.com https://cdn.tailwindcss.com; style-src 'self' 'unsafe-inline'; img-src 'self' data:; connect-src 'self'; frame-ancestors 'none'; base-uri 'self'; form-action 'self'"`
+	FrameOptions          string `envconfig:"FRAME_OPTIONS" default:"DENY"`
+	ReferrerPolicy        string `envconfig:"REFERRER_POLICY" default:"strict-origin-when-cross-origin"`
+	PermissionsPolicy     string `envconfig:"PERMISSIONS_POLICY" default:"camera=(), microphone=(), geolocation=()"`
+
+	// CSRF protection
+	CSRFEnabled    bool   `envconfig:"CSRF_ENABLED" default:"true"`
+	CSRFCookieName string `envconfig:"CSRF_COOKIE_NAME" default:"_csrf"`
+	CSRFHeaderName string `envconfig:"CSRF_HEADER_NAME" default:"X-CSRF-Token"`
+	CSRFFieldName  string `envconfig:"CSRF_FIELD_NAME" default:"_csrf"`
+
+	// Cookies
+	CookieSecure bool `envconfig:"COOKIE_SECURE" default:"false"` // always true for TLS requests
+}
+
+// CompressionConfig holds HTTP response compression configuration
+type CompressionConfig struct {
+	Enabled       bool     `envconfig:"ENABLED" default:"true"`
+	BrotliEnabled bool     `envconfig:"BROTLI_ENABLED" default:"true"`
+	Level         int      `envconfig:"LEVEL" default:"-1"` // gzip level, mapped onto brotli's 0-11; -1 uses the library defaults
+	MinSize       int      `envconfig:"MIN_SIZE" default:"1024"` // bytes
+	ContentTypes  []string `envconfig:"CONTENT_TYPES" default:"text/html,text/css,text/plain,text/javascript,application/javascript,application/json,image/svg+xml"`
+}
+
+// IdempotencyConfig holds Idempotency-Key handling configuration
+type IdempotencyConfig struct {
+	Enabled bool          `envconfig:"ENABLED" default:"true"`
+	TTL     time.Duration `envconfig:"TTL" default:"24h"`
+}
+
+// RateLimitConfig holds API rate limiting configuration
+type RateLimitConfig struct {
+	Enabled           bool          `envconfig:"ENABLED" default:"true"`
+	RequestsPerMinute int           `envconfig:"REQUESTS_PER_MINUTE" default:"60"`
+	Burst             int           `envconfig:"BURST" default:"10"`
+	CleanupInterval   time.Duration `envconfig:"CLEANUP_INTERVAL" default:"60s"`
+}
+
+// MaintenanceConfig holds maintenance mode configuration
+type MaintenanceConfig struct {
+	RefreshInterval   time.Duration `envconfig:"REFRESH_INTERVAL" default:"5s"` // how often instances re-read the shared state
+	DefaultRetryAfter time.Duration `envconfig:"DEFAULT_RETRY_AFTER" default:"5m"`
+	AdminToken        string        `envconfig:"ADMIN_TOKEN"` // bearer token for the admin endpoint, empty disables it
+	ExemptPaths       []string      `envconfig:"EXEMPT_PATHS" default:"/health,/metrics,/static/,/api/v1/admin/"`
+}
+
+// LoadShedConfig holds in-flight request limiting configuration
+type LoadShedConfig struct {
+	Enabled      bool          `envconfig:"ENABLED" default:"true"`
+	MaxInFlight  int           `envconfig:"MAX_IN_FLIGHT" default:"256"`
+	MaxQueue     int           `envconfig:"MAX_QUEUE" default:"512"`
+	QueueTimeout time.Duration `envconfig:"QUEUE_TIMEOUT" default:"250ms"`
+	RetryAfter   time.Duration `envconfig:"RETRY_AFTER" default:"1s"`
+	ExemptPaths  []string      `envconfig:"EXEMPT_PATHS" default:"/health,/metrics"`
+}
+
+// AuthConfig holds password and session authentication configuration.
+// BCRYPT_COST is also read without the AUTH_ prefix.
+type AuthConfig struct {
+	PasswordHasher      string        `envconfig:"PASSWORD_HASHER" default:"bcrypt"` // bcrypt or argon2id
+	BcryptCost          int           `envconfig:"BCRYPT_COST" default:"12"`
+	Argon2Time          uint32        `envconfig:"ARGON2_TIME" default:"3"`
+	Argon2MemoryKiB     uint32        `envconfig:"ARGON2_MEMORY_KIB" default:"65536"`
+	Argon2Threads       uint8         `envconfig:"ARGON2_THREADS" default:"2"`
+	MinPasswordLength   int           `envconfig:"MIN_PASSWORD_LENGTH" default:"8"`
+	RegistrationEnabled bool          `envconfig:"REGISTRATION_ENABLED" default:"true"`
+	SessionCookieName   string        `envconfig:"SESSION_COOKIE_NAME" default:"goapp_session"`
+	SessionTTL          time.Duration `envconfig:"SESSION_TTL" default:"168h"` // sliding; extended while the session is used
+
+	// Email verification and password reset links
+	TokenSecret          string        `envconfig:"TOKEN_SECRET"` // signs the links; random per process when empty
+	EmailVerificationTTL time.Duration `envconfig:"EMAIL_VERIFICATION_TTL" default:"48h"`
+	PasswordResetTTL     time.Duration `envconfig:"PASSWORD_RESET_TTL" default:"1h"`
+
+	// Progressive lockout after failed sign-ins, and the login history
+	LockoutThreshold      int           `envconfig:"LOCKOUT_THRESHOLD" default:"5"` // failures before the first lock; 0 disables lockout
+	LockoutDuration       time.Duration `envconfig:"LOCKOUT_DURATION" default:"1m"` // doubles with each further failure
+	LockoutMaxDuration    time.Duration `envconfig:"LOCKOUT_MAX_DURATION" default:"1h"` // 0 means no cap
+	LoginHistoryRetention time.Duration `envconfig:"LOGIN_HISTORY_RETENTION" default:"2160h"` // deleted by ./goapp cleanup
+}
+
+// JWTConfig holds bearer token configuration for the JSON API. Access tokens
+// are signed with PRIVATE_KEY_FILE (RS256 or EdDSA, chosen by key type) or
+// else SECRET (HS256). Previous secrets and VERIFICATION_KEY_FILES stay valid
+// for verification, so keys can be rotated without invalidating tokens.
+type JWTConfig struct {
+	Secret               string        `envconfig:"SECRET"`
+	PreviousSecrets      []string      `envconfig:"PREVIOUS_SECRETS"`
+	PrivateKeyFile       string        `envconfig:"PRIVATE_KEY_FILE"`
+	VerificationKeyFiles []string      `envconfig:"VERIFICATION_KEY_FILES"` // PEM public or private keys
+	Issuer               string        `envconfig:"ISSUER" default:"goapp"`
+	Audience             string        `envconfig:"AUDIENCE" default:"goapp-api"`
+	Expiration           time.Duration `envconfig:"EXPIRATION" default:"15m"` // access token lifetime
+	RefreshExpiration    time.Duration `envconfig:"REFRESH_EXPIRATION" default:"720h"`
+	Leeway               time.Duration `envconfig:"LEEWAY" default:"30s"` // tolerated clock skew
+}
+
+// APIKeyConfig holds settings for API keys used by other services. Keys look
+// like <TOKEN_PREFIX>_<id>_<secret> so that they are easy to recognise in logs
+// and secret scanners.
+type APIKeyConfig struct {
+	TokenPrefix       string        `envconfig:"TOKEN_PREFIX" default:"goapp"`
+	DefaultExpiration time.Duration `envconfig:"DEFAULT_EXPIRATION" default:"2160h"` // 0 means keys never expire
+	MaxExpiration     time.Duration `envconfig:"MAX_EXPIRATION" default:"8760h"`     // 0 means no limit
+}
+
+// OIDCConfig holds settings for signing in with an OpenID Connect provider.
+// Single sign-on is enabled when ISSUER is set; the provider is found
+// through its discovery document at ISSUER/.well-known/openid-configuration.
+type OIDCConfig struct {
+	ProviderName string   `envconfig:"PROVIDER_NAME" default:"SSO"` // shown on the login button
+	Issuer       string   `envconfig:"ISSUER"`
+	ClientID     string   `envconfig:"CLIENT_ID"`
+	ClientSecret string   `envconfig:"CLIENT_SECRET"` // empty for public clients, which rely on PKCE alone
+	RedirectURL  string   `envconfig:"REDIRECT_URL"`  // e.g. https://app.example.com/auth/oidc/callback
+	Scopes       []string `envconfig:"SCOPES" default:"openid,email,profile"`
+	AutoRegister bool     `envconfig:"AUTO_REGISTER" default:"true"` // create accounts for unknown verified emails
+}
+
+// MailConfig holds outgoing email configuration. The file driver writes
+// each message to DIR as an .eml file and the memory driver keeps messages
+// in memory; both are meant for development and tests.
+type MailConfig struct {
+	Driver       string `envconfig:"DRIVER" default:"file"` // smtp, file or memory
+	From         string `envconfig:"FROM" default:"goapp <no-reply@localhost>"`
+	BaseURL      string `envconfig:"BASE_URL" default:"http://localhost:8080"` // prefix of links in emails
+	Dir          string `envconfig:"DIR" default:"tmp/mail"`
+	SMTPHost     string `envconfig:"SMTP_HOST" default:"localhost"`
+	SMTPPort     int    `envconfig:"SMTP_PORT" default:"587"`
+	SMTPUsername string `envconfig:"SMTP_USERNAME"`
+	SMTPPassword string `envconfig:"SMTP_PASSWORD"`
+}
+
+// TwoFactorConfig holds TOTP two-factor authentication settings. Users
+// holding one of REQUIRED_ROLES must enroll before using the site.
+type TwoFactorConfig struct {
+	Issuer        string        `envconfig:"ISSUER" default:"goapp"` // shown in authenticator apps
+	RequiredRoles []string      `envconfig:"REQUIRED_ROLES" default:"admin"`
+	MaxAttempts   int           `envconfig:"MAX_ATTEMPTS" default:"5"`
+	LockDuration  time.Duration `envconfig:"LOCK_DURATION" default:"15m"` // after MAX_ATTEMPTS failed codes
+	RecoveryCodes int           `envconfig:"RECOVERY_CODES" default:"10"`
+}
+
+// CommentsConfig holds settings for threaded comments on posts
+type CommentsConfig struct {
+	MaxDepth  int `envconfig:"MAX_DEPTH" default:"5"`     // levels of replies below a top-level comment
+	MaxLength int `envconfig:"MAX_LENGTH" default:"5000"` // characters
+}
+
+// BulkConfig holds limits for the bulk and batch API endpoints
+type BulkConfig struct {
+	MaxItems         int `envconfig:"MAX_ITEMS" default:"1000"`        // items per bulk request
+	MaxBatchRequests int `envconfig:"MAX_BATCH_REQUESTS" default:"50"` // sub-requests per batch
+}
+
+// PublishingConfig holds settings for the post publishing workflow
+type PublishingConfig struct {
+	SchedulerInterval time.Duration `envconfig:"SCHEDULER_INTERVAL" default:"1m"` // how often scheduled posts are published; 0 disables the scheduler
+}
+
+// ViewsConfig holds settings for counting post views
+type ViewsConfig struct {
+	FlushInterval time.Duration `envconfig:"FLUSH_INTERVAL" default:"10s"` // how often buffered views are written; 0 disables view counting
+	MaxBuffered   int           `envconfig:"MAX_BUFFERED" default:"10000"` // views that trigger a write before the interval
+	UniqueWindow  time.Duration `envconfig:"UNIQUE_WINDOW" default:"30m"`  // repeat views by a viewer within it count once; 0 counts every view
+	MaxViewers    int           `envconfig:"MAX_VIEWERS" default:"100000"` // viewers remembered for UniqueWindow; views by others count every time
+}
+
 // Load loads configuration from environment variables
 func Load() (Config, error) {
 	var cfg Config
@@ -136,6 +325,22 @@ func Load() (Config, error) {
 		{"KAFKA", &cfg.Kafka},
 		{"OTEL", &cfg.Observability},
 		{"HTTP_CLIENT", &cfg.HTTPClient},
+		{"SECURITY", &cfg.Security},
+		{"COMPRESSION", &cfg.Compression},
+		{"IDEMPOTENCY", &cfg.Idempotency},
+		{"RATE_LIMIT", &cfg.RateLimit},
+		{"MAINTENANCE", &cfg.Maintenance},
+		{"LOAD_SHED", &cfg.LoadShed},
+		{"AUTH", &cfg.Auth},
+		{"JWT", &cfg.JWT},
+		{"API_KEY", &cfg.APIKey},
+		{"OIDC", &cfg.OIDC},
+		{"MAIL", &cfg.Mail},
+		{"TWO_FACTOR", &cfg.TwoFactor},
+		{"COMMENTS", &cfg.Comments},
+		{"BULK", &cfg.Bulk},
+		{"PUBLISHING", &cfg.Publishing},
+		{"VIEWS", &cfg.Views},
 	}
 	
 	// Process each prefix
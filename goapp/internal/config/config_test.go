@@ -305,4 +305,323 @@ func TestLoadHTTPClientError(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error when HTTP client config is invalid")
 	}
-}
\ No newline at end of file
+}
+func TestLoadSecurityDefaults(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if !cfg.Security.CSRFEnabled {
+		t.Error("Expected CSRF to be enabled by default")
+	}
+	if cfg.Security.CSRFHeaderName != "X-CSRF-Token" {
+		t.Errorf("Expected default CSRF header 'X-CSRF-Token', got '%s'", cfg.Security.CSRFHeaderName)
+	}
+	if cfg.Security.HSTSMaxAge != 31536000 {
+		t.Errorf("Expected default HSTS max age 31536000, got %d", cfg.Security.HSTSMaxAge)
+	}
+	if cfg.Security.FrameOptions != "DENY" {
+		t.Errorf("Expected default frame options 'DENY', got '%s'", cfg.Security.FrameOptions)
+	}
+}
+
+func TestLoadSecurityError(t *testing.T) {
+	os.Setenv("SECURITY_HSTS_MAX_AGE", "invalid_number")
+	defer os.Unsetenv("SECURITY_HSTS_MAX_AGE")
+
+	_, err := Load()
+	if err == nil {
+		t.Error("Expected error when security config is invalid")
+	}
+}
+
+func TestLoadCompressionDefaults(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if !cfg.Compression.Enabled {
+		t.Error("Expected compression to be enabled by default")
+	}
+	if cfg.Compression.MinSize != 1024 {
+		t.Errorf("Expected default min size 1024, got %d", cfg.Compression.MinSize)
+	}
+	if len(cfg.Compression.ContentTypes) == 0 || cfg.Compression.ContentTypes[0] != "text/html" {
+		t.Errorf("Expected default content types to start with 'text/html', got %v", cfg.Compression.ContentTypes)
+	}
+}
+
+func TestLoadIdempotencyConfig(t *testing.T) {
+	os.Setenv("IDEMPOTENCY_TTL", "1h")
+	defer os.Unsetenv("IDEMPOTENCY_TTL")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if !cfg.Idempotency.Enabled {
+		t.Error("Expected idempotency to be enabled by default")
+	}
+	if cfg.Idempotency.TTL != time.Hour {
+		t.Errorf("Expected TTL 1h, got %v", cfg.Idempotency.TTL)
+	}
+}
+
+func TestLoadRateLimitDefaults(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if !cfg.RateLimit.Enabled {
+		t.Error("Expected rate limiting to be enabled by default")
+	}
+	if cfg.RateLimit.RequestsPerMinute != 60 {
+		t.Errorf("Expected default 60 requests per minute, got %d", cfg.RateLimit.RequestsPerMinute)
+	}
+	if cfg.RateLimit.Burst != 10 {
+		t.Errorf("Expected default burst 10, got %d", cfg.RateLimit.Burst)
+	}
+	if cfg.RateLimit.CleanupInterval != 60*time.Second {
+		t.Errorf("Expected default cleanup interval 60s, got %v", cfg.RateLimit.CleanupInterval)
+	}
+}
+
+func TestLoadMaintenanceDefaults(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Maintenance.RefreshInterval != 5*time.Second {
+		t.Errorf("Expected default refresh interval 5s, got %v", cfg.Maintenance.RefreshInterval)
+	}
+	if cfg.Maintenance.AdminToken != "" {
+		t.Error("Expected no admin token by default")
+	}
+	if len(cfg.Maintenance.ExemptPaths) == 0 || cfg.Maintenance.ExemptPaths[0] != "/health" {
+		t.Errorf("Expected /health to be exempt by default, got %v", cfg.Maintenance.ExemptPaths)
+	}
+
+	if !cfg.LoadShed.Enabled {
+		t.Error("Expected load shedding to be enabled by default")
+	}
+	if cfg.LoadShed.MaxInFlight != 256 {
+		t.Errorf("Expected default 256 in-flight requests, got %d", cfg.LoadShed.MaxInFlight)
+	}
+	if cfg.LoadShed.QueueTimeout != 250*time.Millisecond {
+		t.Errorf("Expected default queue timeout 250ms, got %v", cfg.LoadShed.QueueTimeout)
+	}
+}
+
+func TestLoadAuthConfig(t *testing.T) {
+	os.Setenv("BCRYPT_COST", "10")
+	defer os.Unsetenv("BCRYPT_COST")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Auth.PasswordHasher != "bcrypt" {
+		t.Errorf("Expected default hasher bcrypt, got %q", cfg.Auth.PasswordHasher)
+	}
+	if cfg.Auth.BcryptCost != 10 {
+		t.Errorf("Expected BCRYPT_COST to be read without prefix, got %d", cfg.Auth.BcryptCost)
+	}
+	if cfg.Auth.SessionCookieName != "goapp_session" {
+		t.Errorf("Expected default session cookie name, got %q", cfg.Auth.SessionCookieName)
+	}
+	if cfg.Auth.SessionTTL != 168*time.Hour {
+		t.Errorf("Expected default session TTL 168h, got %v", cfg.Auth.SessionTTL)
+	}
+	if cfg.Auth.LockoutThreshold != 5 || cfg.Auth.LockoutDuration != time.Minute || cfg.Auth.LockoutMaxDuration != time.Hour {
+		t.Errorf("Expected default lockout 5 failures, 1m up to 1h, got %d, %v up to %v", cfg.Auth.LockoutThreshold, cfg.Auth.LockoutDuration, cfg.Auth.LockoutMaxDuration)
+	}
+}
+
+func TestLoadJWTConfig(t *testing.T) {
+	os.Setenv("JWT_SECRET", "test-secret")
+	os.Setenv("JWT_PREVIOUS_SECRETS", "old-1,old-2")
+	defer os.Unsetenv("JWT_SECRET")
+	defer os.Unsetenv("JWT_PREVIOUS_SECRETS")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.JWT.Secret != "test-secret" {
+		t.Errorf("Expected JWT_SECRET to be loaded, got %q", cfg.JWT.Secret)
+	}
+	if len(cfg.JWT.PreviousSecrets) != 2 {
+		t.Errorf("Expected 2 previous secrets, got %v", cfg.JWT.PreviousSecrets)
+	}
+	if cfg.JWT.Expiration != 15*time.Minute {
+		t.Errorf("Expected default access token lifetime 15m, got %v", cfg.JWT.Expiration)
+	}
+	if cfg.JWT.RefreshExpiration != 720*time.Hour {
+		t.Errorf("Expected default refresh token lifetime 720h, got %v", cfg.JWT.RefreshExpiration)
+	}
+	if cfg.JWT.Issuer != "goapp" || cfg.JWT.Audience != "goapp-api" {
+		t.Errorf("Unexpected issuer/audience %q/%q", cfg.JWT.Issuer, cfg.JWT.Audience)
+	}
+}
+
+func TestLoadAPIKeyConfig(t *testing.T) {
+	os.Setenv("API_KEY_TOKEN_PREFIX", "acme")
+	os.Setenv("API_KEY_MAX_EXPIRATION", "0")
+	defer os.Unsetenv("API_KEY_TOKEN_PREFIX")
+	defer os.Unsetenv("API_KEY_MAX_EXPIRATION")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.APIKey.TokenPrefix != "acme" {
+		t.Errorf("Expected token prefix 'acme', got %q", cfg.APIKey.TokenPrefix)
+	}
+	if cfg.APIKey.DefaultExpiration != 2160*time.Hour {
+		t.Errorf("Expected default expiration 2160h, got %v", cfg.APIKey.DefaultExpiration)
+	}
+	if cfg.APIKey.MaxExpiration != 0 {
+		t.Errorf("Expected no maximum expiration, got %v", cfg.APIKey.MaxExpiration)
+	}
+}
+
+func TestLoadOIDCConfig(t *testing.T) {
+	os.Setenv("OIDC_ISSUER", "https://accounts.example.com")
+	os.Setenv("OIDC_CLIENT_ID", "goapp")
+	defer os.Unsetenv("OIDC_ISSUER")
+	defer os.Unsetenv("OIDC_CLIENT_ID")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.OIDC.Issuer != "https://accounts.example.com" || cfg.OIDC.ClientID != "goapp" {
+		t.Errorf("Unexpected issuer/client %q/%q", cfg.OIDC.Issuer, cfg.OIDC.ClientID)
+	}
+	if len(cfg.OIDC.Scopes) != 3 || cfg.OIDC.Scopes[0] != "openid" {
+		t.Errorf("Expected default scopes openid,email,profile, got %v", cfg.OIDC.Scopes)
+	}
+	if !cfg.OIDC.AutoRegister {
+		t.Error("Expected auto-registration to be enabled by default")
+	}
+}
+
+func TestLoadMailConfig(t *testing.T) {
+	os.Setenv("MAIL_DRIVER", "smtp")
+	os.Setenv("MAIL_SMTP_HOST", "smtp.example.com")
+	defer os.Unsetenv("MAIL_DRIVER")
+	defer os.Unsetenv("MAIL_SMTP_HOST")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Mail.Driver != "smtp" || cfg.Mail.SMTPHost != "smtp.example.com" {
+		t.Errorf("Unexpected driver/host %q/%q", cfg.Mail.Driver, cfg.Mail.SMTPHost)
+	}
+	if cfg.Mail.SMTPPort != 587 {
+		t.Errorf("Expected default SMTP port 587, got %d", cfg.Mail.SMTPPort)
+	}
+	if cfg.Auth.PasswordResetTTL != time.Hour || cfg.Auth.EmailVerificationTTL != 48*time.Hour {
+		t.Errorf("Unexpected token TTLs %v/%v", cfg.Auth.PasswordResetTTL, cfg.Auth.EmailVerificationTTL)
+	}
+}
+
+func TestLoadTwoFactorConfig(t *testing.T) {
+	os.Setenv("TWO_FACTOR_REQUIRED_ROLES", "admin,editor")
+	defer os.Unsetenv("TWO_FACTOR_REQUIRED_ROLES")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if len(cfg.TwoFactor.RequiredRoles) != 2 || cfg.TwoFactor.RequiredRoles[1] != "editor" {
+		t.Errorf("Expected required roles admin,editor, got %v", cfg.TwoFactor.RequiredRoles)
+	}
+	if cfg.TwoFactor.MaxAttempts != 5 || cfg.TwoFactor.LockDuration != 15*time.Minute {
+		t.Errorf("Unexpected rate limit %d/%v", cfg.TwoFactor.MaxAttempts, cfg.TwoFactor.LockDuration)
+	}
+}
+
+func TestLoadCommentsConfig(t *testing.T) {
+	os.Setenv("COMMENTS_MAX_DEPTH", "3")
+	defer os.Unsetenv("COMMENTS_MAX_DEPTH")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Comments.MaxDepth != 3 {
+		t.Errorf("Expected max depth 3, got %d", cfg.Comments.MaxDepth)
+	}
+	if cfg.Comments.MaxLength != 5000 {
+		t.Errorf("Expected max length 5000, got %d", cfg.Comments.MaxLength)
+	}
+}
+
+func TestLoadBulkConfig(t *testing.T) {
+	os.Setenv("BULK_MAX_ITEMS", "250")
+	defer os.Unsetenv("BULK_MAX_ITEMS")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Bulk.MaxItems != 250 {
+		t.Errorf("Expected max items 250, got %d", cfg.Bulk.MaxItems)
+	}
+	if cfg.Bulk.MaxBatchRequests != 50 {
+		t.Errorf("Expected max batch requests 50, got %d", cfg.Bulk.MaxBatchRequests)
+	}
+}
+
+func TestLoadPublishingConfig(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Publishing.SchedulerInterval != time.Minute {
+		t.Errorf("Expected scheduler interval 1m, got %v", cfg.Publishing.SchedulerInterval)
+	}
+
+	os.Setenv("PUBLISHING_SCHEDULER_INTERVAL", "0")
+	defer os.Unsetenv("PUBLISHING_SCHEDULER_INTERVAL")
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Publishing.SchedulerInterval != 0 {
+		t.Errorf("Expected the scheduler to be disabled, got %v", cfg.Publishing.SchedulerInterval)
+	}
+}
+
+func TestLoadViewsConfig(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Views.FlushInterval != 10*time.Second || cfg.Views.MaxBuffered != 10000 || cfg.Views.UniqueWindow != 30*time.Minute || cfg.Views.MaxViewers != 100000 {
+		t.Errorf("Unexpected views defaults %+v", cfg.Views)
+	}
+
+	os.Setenv("VIEWS_UNIQUE_WINDOW", "0")
+	defer os.Unsetenv("VIEWS_UNIQUE_WINDOW")
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Views.UniqueWindow != 0 {
+		t.Errorf("Expected every view to count, got %v", cfg.Views.UniqueWindow)
+	}
+}
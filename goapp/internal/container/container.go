@@ -1,19 +1,57 @@
 package container
 
 import (
+	"errors"
+	"net/http"
+
+	"goapp/internal/apikeys"
+	"goapp/internal/auth"
+	"goapp/internal/comments"
+	"goapp/internal/authz"
 	"goapp/internal/config"
 	"goapp/internal/db/postgres"
 	"goapp/internal/httpclient"
 	"goapp/internal/logging"
+	"goapp/internal/mail"
+	"goapp/internal/maintenance"
+	"goapp/internal/oidc"
+	"goapp/internal/posts"
+	"goapp/internal/search"
+	"goapp/internal/tags"
+	"goapp/internal/tokens"
+	"goapp/internal/twofactor"
+	"goapp/internal/users"
+	"goapp/internal/views"
 	"go.uber.org/zap"
 )
 
 // Container holds all application dependencies
 type Container struct {
-	Config     config.Config
-	Logger     logging.Logger
-	Database   postgres.Database
-	HTTPClient *httpclient.Client
+	Config       config.Config
+	Logger       logging.Logger
+	Database     postgres.Database
+	HTTPClient   *httpclient.Client
+	Maintenance  *maintenance.Manager
+	Policy       *authz.Policy
+	Mailer       mail.Mailer
+	Auth         auth.Service        // nil without a database
+	Sessions     auth.SessionStore   // nil without a database
+	Accounts     auth.AccountService // nil without a database
+	TwoFactor    twofactor.Service   // nil without a database
+	LoginHistory auth.LoginHistory   // nil without a database
+	Roles        authz.RoleStore     // nil without a database
+	Users        users.Service       // nil without a database
+	APIKeys      apikeys.Service     // nil without a database
+	JWTKeys      *tokens.KeySet      // nil without JWT_SECRET or JWT_PRIVATE_KEY_FILE
+	Tokens       tokens.Service      // nil without JWT keys or a database
+	OIDC         *oidc.Client        // nil without OIDC_ISSUER
+	Identities   oidc.IdentityStore  // nil without a database
+	Posts        posts.Service       // nil without a database
+	Comments     comments.Service    // nil without a database
+	Tags         tags.Service        // nil without a database
+	Search       search.Service      // nil without a database
+	Scheduler    *posts.Scheduler    // nil without a database or with PUBLISHING_SCHEDULER_INTERVAL=0
+	Views        *views.Counter      // nil without a database or with VIEWS_FLUSH_INTERVAL=0
 }
 
 // New creates a new dependency injection container
@@ -45,11 +83,116 @@ func New() (*Container, error) {
 		return nil, err
 	}
 
+	// Initialize password authentication, sessions, account links, two-factor, login history, roles, user administration and API keys
+	hasher, err := auth.NewHasher(cfg.Auth)
+	if err != nil {
+		return nil, err
+	}
+	var authService auth.Service
+	var sessions auth.SessionStore
+	var roles authz.RoleStore
+	var userService users.Service
+	var apiKeys apikeys.Service
+	var accounts auth.AccountService
+	var twoFactor twofactor.Service
+	var loginHistory auth.LoginHistory
+	if database != nil {
+		authService = auth.NewService(database.DB(), hasher, cfg.Auth)
+		sessions = auth.NewSessionStore(database.DB(), cfg.Auth.SessionTTL)
+		roles = authz.NewRoleStore(database.DB())
+		userService = users.NewService(database.DB(), hasher)
+		apiKeys = apikeys.NewService(database.DB(), cfg.APIKey)
+		twoFactor = twofactor.NewService(database.DB(), cfg.TwoFactor)
+		loginHistory = auth.NewLoginHistory(database.DB())
+		if accounts, err = auth.NewAccountService(database.DB(), hasher, cfg.Auth); err != nil {
+			return nil, err
+		}
+		if cfg.Auth.TokenSecret == "" {
+			logger.Warn("AUTH_TOKEN_SECRET is not set, email verification and password reset links will stop working on restart")
+		}
+	}
+
+	// Initialize outgoing email
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize JWT bearer tokens for the JSON API
+	jwtKeys, err := tokens.LoadKeySet(cfg.JWT)
+	if errors.Is(err, tokens.ErrNotConfigured) {
+		logger.Warn("No JWT signing key configured, bearer token authentication is disabled")
+		jwtKeys = nil
+	} else if err != nil {
+		return nil, err
+	}
+	var tokenService tokens.Service
+	if jwtKeys != nil && database != nil {
+		tokenService = tokens.NewService(database.DB(), jwtKeys, cfg.JWT)
+	}
+
+	// Initialize single sign-on with an OpenID Connect provider
+	var oidcClient *oidc.Client
+	var identities oidc.IdentityStore
+	if cfg.OIDC.Issuer != "" {
+		oidcClient = oidc.NewClient(cfg.OIDC, &http.Client{Timeout: cfg.HTTPClient.Timeout})
+	}
+	if database != nil {
+		identities = oidc.NewIdentityStore(database.DB(), cfg.OIDC.AutoRegister)
+	}
+
+	// Initialize content
+	var postService posts.Service
+	var commentService comments.Service
+	var tagService tags.Service
+	var searchService search.Service
+	var scheduler *posts.Scheduler
+	var viewCounter *views.Counter
+	if database != nil {
+		postService = posts.NewService(database.DB())
+		commentService = comments.NewService(database.DB(), cfg.Comments)
+		tagService = tags.NewService(database.DB())
+		searchService = search.NewService(database.DB())
+		if cfg.Publishing.SchedulerInterval > 0 {
+			scheduler = posts.NewScheduler(postService, cfg.Publishing.SchedulerInterval, logger)
+		}
+		if cfg.Views.FlushInterval > 0 {
+			viewCounter = views.NewCounter(database.DB(), cfg.Views, logger)
+		}
+	}
+
+	// Initialize maintenance mode, shared through the database when available
+	maintenanceStore := maintenance.NewMemoryStore()
+	if database != nil {
+		maintenanceStore = maintenance.NewDBStore(database.DB())
+	}
+
 	return &Container{
-		Config:     cfg,
-		Logger:     logger,
-		Database:   database,
-		HTTPClient: httpClient,
+		Config:       cfg,
+		Logger:       logger,
+		Database:     database,
+		HTTPClient:   httpClient,
+		Maintenance:  maintenance.NewManager(maintenanceStore, cfg.Maintenance.RefreshInterval),
+		Policy:       authz.DefaultPolicy(),
+		Mailer:       mailer,
+		Auth:         authService,
+		Sessions:     sessions,
+		Accounts:     accounts,
+		TwoFactor:    twoFactor,
+		LoginHistory: loginHistory,
+		Roles:        roles,
+		Users:        userService,
+		APIKeys:      apiKeys,
+		JWTKeys:      jwtKeys,
+		Tokens:       tokenService,
+		OIDC:         oidcClient,
+		Identities:   identities,
+		Posts:        postService,
+		Comments:     commentService,
+		Tags:         tagService,
+		Search:       searchService,
+		Scheduler:    scheduler,
+		Views:        viewCounter,
 	}, nil
 }
 
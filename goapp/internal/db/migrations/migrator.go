@@ -3,7 +3,9 @@ package migrations
 import (
 	"fmt"
 
+	"goapp/internal/markdown"
 	"goapp/internal/models"
+	"goapp/internal/search"
 	"gorm.io/gorm"
 )
 
@@ -20,10 +22,24 @@ func NewMigrator(db *gorm.DB) *Migrator {
 // AutoMigrate runs auto-migration for all models
 func (m *Migrator) AutoMigrate() error {
 	models := []interface{}{
+		&models.Permission{},
+		&models.Role{},
 		&models.User{},
 		&models.Post{},
+		&models.PostRevision{},
+		&models.SlugRedirect{},
+		&models.PostDailyView{},
 		&models.Comment{},
 		&models.Tag{},
+		&models.IdempotencyKey{},
+		&models.Setting{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.APIKey{},
+		&models.Identity{},
+		&models.TwoFactor{},
+		&models.RecoveryCode{},
+		&models.LoginEvent{},
 	}
 
 	for _, model := range models {
@@ -37,9 +53,80 @@ func (m *Migrator) AutoMigrate() error {
 		return fmt.Errorf("failed to create indexes: %w", err)
 	}
 
+	if err := search.Migrate(m.db); err != nil {
+		return fmt.Errorf("failed to create search index: %w", err)
+	}
+
+	if err := m.backfillPostStatus(); err != nil {
+		return fmt.Errorf("failed to backfill post status: %w", err)
+	}
+
+	if err := m.backfillContentHTML(); err != nil {
+		return fmt.Errorf("failed to backfill rendered content: %w", err)
+	}
+
+	if err := m.rerenderElementIDs(); err != nil {
+		return fmt.Errorf("failed to re-render post ids: %w", err)
+	}
+
 	return nil
 }
 
+// backfillPostStatus gives posts published before the status column was
+// added the published status, dated by their creation
+func (m *Migrator) backfillPostStatus() error {
+	return m.db.Exec("UPDATE posts SET status = ?, published_at = COALESCE(published_at, created_at) WHERE published AND status = ?",
+		models.PostPublished, models.PostDraft).Error
+}
+
+// backfillContentHTML renders the content of posts and comments saved
+// before rendered content was cached, a batch at a time
+func (m *Migrator) backfillContentHTML() error {
+	var posts []models.Post
+	err := m.db.Select("id", "content").Where("content <> '' AND (content_html IS NULL OR content_html = '')").
+		FindInBatches(&posts, 100, func(tx *gorm.DB, batch int) error {
+			for _, post := range posts {
+				if err := tx.Model(&post).UpdateColumn("content_html", markdown.Render(post.Content)).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	var comments []models.Comment
+	return m.db.Select("id", "content").Where("content <> '' AND (content_html IS NULL OR content_html = '')").
+		FindInBatches(&comments, 100, func(tx *gorm.DB, batch int) error {
+			for _, comment := range comments {
+				if err := tx.Model(&comment).UpdateColumn("content_html", markdown.RenderSnippet(comment.Content)).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// rerenderElementIDs renders again the posts cached while raw HTML could set
+// any id, so that they no longer keep ids other than their headings'
+func (m *Migrator) rerenderElementIDs() error {
+	var posts []models.Post
+	return m.db.Select("id", "content", "content_html").Where("content_html LIKE ?", `%id="%`).
+		FindInBatches(&posts, 100, func(tx *gorm.DB, batch int) error {
+			for _, post := range posts {
+				html := markdown.Render(post.Content)
+				if html == post.ContentHTML {
+					continue
+				}
+				if err := tx.Model(&post).UpdateColumn("content_html", html).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
 // createIndexes creates custom indexes for better performance
 func (m *Migrator) createIndexes() error {
 	// Add composite indexes
@@ -57,10 +144,27 @@ func (m *Migrator) createIndexes() error {
 // DropAllTables drops all tables (use with caution!)
 func (m *Migrator) DropAllTables() error {
 	return m.db.Migrator().DropTable(
+		"search_index", // SQLite full-text table
+		&models.LoginEvent{},
+		&models.RecoveryCode{},
+		&models.TwoFactor{},
+		&models.Identity{},
+		&models.APIKey{},
+		&models.RefreshToken{},
+		&models.Session{},
+		&models.Setting{},
+		&models.IdempotencyKey{},
 		&models.Tag{},
 		&models.Comment{},
+		&models.PostDailyView{},
+		&models.SlugRedirect{},
+		&models.PostRevision{},
 		&models.Post{},
 		&models.User{},
-		"post_tags", // many2many join table
+		&models.Role{},
+		&models.Permission{},
+		"post_tags",        // many2many join table
+		"user_roles",       // many2many join table
+		"role_permissions", // many2many join table
 	)
 }
\ No newline at end of file
@@ -65,6 +65,7 @@ func (m *mssql) connect(cfg config.MSSQLConfig) error {
 		SkipDefaultTransaction:                   false,
 		PrepareStmt:                             true,
 		QueryFields:                             true,
+		TranslateError:                          true, // e.g. unique violations become gorm.ErrDuplicatedKey
 	})
 	if err != nil {
 		return fmt.Errorf("failed to connect to SQL Server: %w", err)
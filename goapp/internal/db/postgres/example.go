@@ -148,13 +148,8 @@ func ExampleUsage() {
 		log.Fatal("Failed to query posts:", err)
 	}
 
-	// Example of using model methods
-	if len(posts) > 0 {
-		// Increment view count for the first post
-		if err := posts[0].IncrementViewCount(gormDB); err != nil {
-			log.Fatal("Failed to increment view count:", err)
-		}
-	}
+	// Views are counted by views.Counter, which batches them rather than
+	// updating a post on every view
 
 	fmt.Printf("Found %d published posts\n", len(posts))
 }
\ No newline at end of file
@@ -64,6 +64,7 @@ func (p *postgres) connect(cfg config.DatabaseConfig) error {
 		SkipDefaultTransaction:                   false,
 		PrepareStmt:                             true,
 		QueryFields:                             true,
+		TranslateError:                          true, // e.g. unique violations become gorm.ErrDuplicatedKey
 	})
 	if err != nil {
 		return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
@@ -0,0 +1,159 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"goapp/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrFingerprintMismatch is returned when a key is reused for a different request
+	ErrFingerprintMismatch = errors.New("idempotency key reused with a different request")
+	// ErrInProgress is returned when the original request for a key has not finished yet
+	ErrInProgress = errors.New("request with this idempotency key is still in progress")
+)
+
+// Response is a stored response that can be replayed to a retried request
+type Response struct {
+	StatusCode  int
+	ContentType string
+	Header      http.Header // headers such as Location and ETag, besides Content-Type
+	Body        []byte
+}
+
+// Store persists idempotency keys and the responses they produced
+type Store interface {
+	// Begin claims key for a request with the given fingerprint. It returns a
+	// stored response when the request has already completed, nil when the
+	// caller should execute the request, or an error.
+	Begin(ctx context.Context, key, fingerprint string) (*Response, error)
+	// Complete stores the response for a claimed key
+	Complete(ctx context.Context, key string, resp Response) error
+	// Release removes a claimed key so the request can be retried
+	Release(ctx context.Context, key string) error
+	// DeleteExpired removes all keys past their TTL
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// store implements Store using GORM
+type store struct {
+	db  *gorm.DB
+	ttl time.Duration
+	now func() time.Time
+}
+
+// NewStore creates a new GORM-backed idempotency store
+func NewStore(db *gorm.DB, ttl time.Duration) Store {
+	return &store{db: db, ttl: ttl, now: time.Now}
+}
+
+// Begin implements Store
+func (s *store) Begin(ctx context.Context, key, fingerprint string) (*Response, error) {
+	record := &models.IdempotencyKey{
+		Key:         key,
+		RequestHash: fingerprint,
+		ExpiresAt:   s.now().Add(s.ttl),
+	}
+
+	// Two attempts: the second runs after an expired record has been removed
+	for attempt := 0; attempt < 2; attempt++ {
+		result := s.db.WithContext(ctx).
+			Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "key"}}, DoNothing: true}).
+			Create(record)
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to claim idempotency key: %w", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			return nil, nil
+		}
+
+		var existing models.IdempotencyKey
+		if err := s.db.WithContext(ctx).Where("key = ?", key).First(&existing).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to load idempotency key: %w", err)
+		}
+
+		if existing.Expired(s.now()) {
+			if err := s.db.WithContext(ctx).Unscoped().Delete(&existing).Error; err != nil {
+				return nil, fmt.Errorf("failed to delete expired idempotency key: %w", err)
+			}
+			record.ID = 0
+			continue
+		}
+		if existing.RequestHash != fingerprint {
+			return nil, ErrFingerprintMismatch
+		}
+		if !existing.Completed {
+			return nil, ErrInProgress
+		}
+
+		resp := &Response{
+			StatusCode:  existing.StatusCode,
+			ContentType: existing.ContentType,
+			Body:        existing.ResponseBody,
+		}
+		if existing.ResponseHeaders != "" {
+			if err := json.Unmarshal([]byte(existing.ResponseHeaders), &resp.Header); err != nil {
+				return nil, fmt.Errorf("failed to decode idempotent response headers: %w", err)
+			}
+		}
+		return resp, nil
+	}
+
+	return nil, ErrInProgress
+}
+
+// Complete implements Store
+func (s *store) Complete(ctx context.Context, key string, resp Response) error {
+	var headers []byte
+	if len(resp.Header) > 0 {
+		var err error
+		if headers, err = json.Marshal(resp.Header); err != nil {
+			return fmt.Errorf("failed to encode idempotent response headers: %w", err)
+		}
+	}
+	err := s.db.WithContext(ctx).Model(&models.IdempotencyKey{}).
+		Where("key = ?", key).
+		Updates(map[string]interface{}{
+			"completed":        true,
+			"status_code":      resp.StatusCode,
+			"content_type":     resp.ContentType,
+			"response_headers": string(headers),
+			"response_body":    resp.Body,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release implements Store
+func (s *store) Release(ctx context.Context, key string) error {
+	err := s.db.WithContext(ctx).Unscoped().
+		Where("key = ?", key).
+		Delete(&models.IdempotencyKey{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired implements Store
+func (s *store) DeleteExpired(ctx context.Context) (int64, error) {
+	result := s.db.WithContext(ctx).Unscoped().
+		Where("expires_at <= ?", s.now()).
+		Delete(&models.IdempotencyKey{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
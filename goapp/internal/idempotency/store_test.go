@@ -0,0 +1,121 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"goapp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestStore(t *testing.T) (*store, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.IdempotencyKey{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	return NewStore(db, time.Hour).(*store), db
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	s, _ := setupTestStore(t)
+
+	t.Run("FirstRequestClaimsKey", func(t *testing.T) {
+		resp, err := s.Begin(ctx, "key-1", "hash-a")
+		if err != nil {
+			t.Fatalf("Begin() error = %v", err)
+		}
+		if resp != nil {
+			t.Error("Expected no stored response for a new key")
+		}
+	})
+
+	t.Run("ConcurrentDuplicateInProgress", func(t *testing.T) {
+		_, err := s.Begin(ctx, "key-1", "hash-a")
+		if !errors.Is(err, ErrInProgress) {
+			t.Errorf("Expected ErrInProgress, got %v", err)
+		}
+	})
+
+	t.Run("ReplayAfterComplete", func(t *testing.T) {
+		header := http.Header{"Location": {"/posts/1"}}
+		err := s.Complete(ctx, "key-1", Response{StatusCode: 201, ContentType: "application/json", Header: header, Body: []byte(`{"id":1}`)})
+		if err != nil {
+			t.Fatalf("Complete() error = %v", err)
+		}
+
+		resp, err := s.Begin(ctx, "key-1", "hash-a")
+		if err != nil {
+			t.Fatalf("Begin() error = %v", err)
+		}
+		if resp == nil || resp.StatusCode != 201 || string(resp.Body) != `{"id":1}` || resp.Header.Get("Location") != "/posts/1" {
+			t.Errorf("Expected stored response, got %+v", resp)
+		}
+	})
+
+	t.Run("FingerprintMismatch", func(t *testing.T) {
+		_, err := s.Begin(ctx, "key-1", "hash-b")
+		if !errors.Is(err, ErrFingerprintMismatch) {
+			t.Errorf("Expected ErrFingerprintMismatch, got %v", err)
+		}
+	})
+
+	t.Run("ReleaseAllowsRetry", func(t *testing.T) {
+		if _, err := s.Begin(ctx, "key-2", "hash-a"); err != nil {
+			t.Fatalf("Begin() error = %v", err)
+		}
+		if err := s.Release(ctx, "key-2"); err != nil {
+			t.Fatalf("Release() error = %v", err)
+		}
+
+		resp, err := s.Begin(ctx, "key-2", "hash-a")
+		if err != nil || resp != nil {
+			t.Errorf("Expected key to be claimable after release, got resp=%v err=%v", resp, err)
+		}
+	})
+}
+
+func TestStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	s, db := setupTestStore(t)
+
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	if _, err := s.Begin(ctx, "expiring", "hash-a"); err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err := s.Complete(ctx, "expiring", Response{StatusCode: 200}); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	// Past the TTL the key can be reused for any request
+	now = now.Add(2 * time.Hour)
+	resp, err := s.Begin(ctx, "expiring", "hash-b")
+	if err != nil || resp != nil {
+		t.Fatalf("Expected expired key to be reclaimed, got resp=%v err=%v", resp, err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	deleted, err := s.DeleteExpired(ctx)
+	if err != nil {
+		t.Fatalf("DeleteExpired() error = %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 expired key deleted, got %d", deleted)
+	}
+
+	var count int64
+	db.Unscoped().Model(&models.IdempotencyKey{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected no keys left, got %d", count)
+	}
+}
@@ -0,0 +1,134 @@
+// Package mail sends email. A Mailer delivers Messages over SMTP, drops
+// them into a directory as .eml files, or keeps them in memory for tests.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"goapp/internal/config"
+)
+
+// Mail drivers
+const (
+	DriverSMTP   = "smtp"
+	DriverFile   = "file"
+	DriverMemory = "memory"
+)
+
+// ErrNoRecipients is returned when a message has no recipients
+var ErrNoRecipients = errors.New("mail: message has no recipients")
+
+// Message is an email with a plain text body and an optional HTML alternative
+type Message struct {
+	From    string // defaults to MAIL_FROM
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer sends messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New creates the Mailer selected by cfg.Driver
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case DriverSMTP:
+		return NewSMTPMailer(cfg), nil
+	case DriverFile:
+		return NewFileMailer(cfg.Dir, cfg.From), nil
+	case DriverMemory:
+		return NewMemoryMailer(cfg.From), nil
+	default:
+		return nil, fmt.Errorf("mail: unknown driver %q", cfg.Driver)
+	}
+}
+
+// Render renders an HTML email body from a templ component
+func Render(ctx context.Context, component templ.Component) (string, error) {
+	var buf strings.Builder
+	if err := component.Render(ctx, &buf); err != nil {
+		return "", fmt.Errorf("mail: failed to render body: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// Bytes encodes the message in RFC 5322 format as multipart/alternative
+// when it has an HTML body
+func (m Message) Bytes(date time.Time) ([]byte, error) {
+	if len(m.To) == 0 {
+		return nil, ErrNoRecipients
+	}
+	for _, addr := range append([]string{m.From, m.Subject}, m.To...) {
+		// Reject header injection
+		if strings.ContainsAny(addr, "\r\n") {
+			return nil, errors.New("mail: header contains a line break")
+		}
+	}
+
+	var buf bytes.Buffer
+	header := func(key, value string) { fmt.Fprintf(&buf, "%s: %s\r\n", key, value) }
+	header("From", m.From)
+	header("To", strings.Join(m.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", date.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+
+	if m.HTML == "" {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		return buf.Bytes(), writeQuotedPrintable(&buf, m.Text)
+	}
+
+	writer := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/alternative; boundary="+writer.Boundary())
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// withDefaults fills in the sender
+func (m Message) withDefaults(from string) Message {
+	if m.From == "" {
+		m.From = from
+	}
+	return m
+}
@@ -0,0 +1,156 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"goapp/internal/config"
+)
+
+func TestMessageBytes(t *testing.T) {
+	msg := Message{From: "goapp <no-reply@example.com>", To: []string{"jane@example.com"}, Subject: "Welcome ✓", Text: "Hello", HTML: "<p>Hello</p>"}
+	data, err := msg.Bytes(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+	body := string(data)
+	for _, expected := range []string{"To: jane@example.com\r\n", "Subject: =?utf-8?q?Welcome_=E2=9C=93?=", "multipart/alternative", "text/plain; charset=utf-8", "<p>Hello</p>"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected message to contain %q, got:\n%s", expected, body)
+		}
+	}
+
+	if _, err := (Message{To: []string{"jane@example.com\r\nBcc: evil@example.com"}}).Bytes(time.Now()); err == nil {
+		t.Error("Expected header injection to be rejected")
+	}
+	if _, err := (Message{Subject: "No one"}).Bytes(time.Now()); !errors.Is(err, ErrNoRecipients) {
+		t.Errorf("Expected ErrNoRecipients, got %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, driver := range []string{DriverSMTP, DriverFile, DriverMemory} {
+		if _, err := New(config.MailConfig{Driver: driver}); err != nil {
+			t.Errorf("New(%q) error = %v", driver, err)
+		}
+	}
+	mailer, _ := New(config.MailConfig{Driver: DriverMemory})
+	if _, ok := mailer.(*MemoryMailer); !ok {
+		t.Errorf("Expected a *MemoryMailer, got %T", mailer)
+	}
+	if _, err := New(config.MailConfig{Driver: "carrier-pigeon"}); err == nil {
+		t.Error("Expected error for unknown driver")
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer := NewFileMailer(dir, "no-reply@example.com")
+
+	if err := mailer.Send(context.Background(), Message{To: []string{"jane@example.com"}, Subject: "Hi", Text: "Hello"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*jane@example.com.eml"))
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 .eml file, got %v", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(data), "From: no-reply@example.com") {
+		t.Errorf("Expected default sender, got:\n%s", data)
+	}
+}
+
+func TestMemoryMailer(t *testing.T) {
+	mailer := NewMemoryMailer("no-reply@example.com")
+	if _, ok := mailer.Last(); ok {
+		t.Error("Expected no messages")
+	}
+
+	_ = mailer.Send(context.Background(), Message{To: []string{"a@example.com"}, Subject: "First"})
+	_ = mailer.Send(context.Background(), Message{To: []string{"b@example.com"}, Subject: "Second"})
+	if last, _ := mailer.Last(); last.Subject != "Second" || last.From != "no-reply@example.com" {
+		t.Errorf("Unexpected last message %+v", last)
+	}
+	if len(mailer.Messages()) != 2 {
+		t.Errorf("Expected 2 messages, got %d", len(mailer.Messages()))
+	}
+
+	mailer.Reset()
+	if len(mailer.Messages()) != 0 {
+		t.Error("Expected Reset to forget messages")
+	}
+}
+
+func TestSMTPMailer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go serveSMTP(listener, received)
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	mailer := NewSMTPMailer(config.MailConfig{From: "goapp <no-reply@example.com>", SMTPHost: "127.0.0.1", SMTPPort: port})
+	if err := mailer.Send(context.Background(), Message{To: []string{"Jane <jane@example.com>"}, Subject: "Hi", Text: "Hello"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	transcript := <-received
+	for _, expected := range []string{"MAIL FROM:<no-reply@example.com>", "RCPT TO:<jane@example.com>", "Subject: Hi"} {
+		if !strings.Contains(transcript, expected) {
+			t.Errorf("Expected transcript to contain %q, got:\n%s", expected, transcript)
+		}
+	}
+}
+
+// serveSMTP accepts one SMTP session and reports everything the client sent
+func serveSMTP(listener net.Listener, received chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		received <- ""
+		return
+	}
+	defer conn.Close()
+
+	var transcript strings.Builder
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost ESMTP")
+	inData := false
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		transcript.WriteString(line)
+		switch {
+		case inData:
+			if line == ".\r\n" {
+				inData = false
+				reply("250 OK")
+			}
+		case strings.HasPrefix(line, "EHLO"), strings.HasPrefix(line, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(line, "DATA"):
+			inData = true
+			reply("354 Go ahead")
+		case strings.HasPrefix(line, "QUIT"):
+			reply("221 Bye")
+			received <- transcript.String()
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+	received <- transcript.String()
+}
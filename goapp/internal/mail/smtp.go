@@ -0,0 +1,66 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"goapp/internal/config"
+)
+
+// SMTPMailer sends messages through an SMTP server, upgrading the
+// connection with STARTTLS when the server offers it
+type SMTPMailer struct {
+	cfg config.MailConfig
+}
+
+// NewSMTPMailer creates a Mailer for the server in cfg
+func NewSMTPMailer(cfg config.MailConfig) *SMTPMailer {
+	return &SMTPMailer{cfg: cfg}
+}
+
+// Send implements Mailer
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	msg = msg.withDefaults(m.cfg.From)
+	data, err := msg.Bytes(time.Now())
+	if err != nil {
+		return err
+	}
+
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("mail: invalid sender: %w", err)
+	}
+	recipients := make([]string, 0, len(msg.To))
+	for _, to := range msg.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return fmt.Errorf("mail: invalid recipient: %w", err)
+		}
+		recipients = append(recipients, addr.Address)
+	}
+
+	var auth smtp.Auth
+	if m.cfg.SMTPUsername != "" {
+		// PlainAuth refuses to send credentials unless the connection uses TLS or goes to localhost
+		auth = smtp.PlainAuth("", m.cfg.SMTPUsername, m.cfg.SMTPPassword, m.cfg.SMTPHost)
+	}
+
+	// smtp.SendMail has no context, so run it in the background and stop waiting on cancellation
+	addr := net.JoinHostPort(m.cfg.SMTPHost, strconv.Itoa(m.cfg.SMTPPort))
+	done := make(chan error, 1)
+	go func() { done <- smtp.SendMail(addr, auth, from.Address, recipients, data) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("mail: failed to send: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
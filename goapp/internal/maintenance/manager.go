@@ -0,0 +1,126 @@
+package maintenance
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Status describes whether the application is in maintenance mode
+type Status struct {
+	Enabled           bool      `json:"enabled"`
+	Message           string    `json:"message,omitempty"`
+	RetryAfterSeconds int       `json:"retry_after_seconds,omitempty"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// RetryAfter returns the Retry-After duration clients should wait
+func (s Status) RetryAfter() time.Duration {
+	return time.Duration(s.RetryAfterSeconds) * time.Second
+}
+
+// Manager serves the maintenance state from a short-lived cache so the
+// request path does not hit the store on every request
+type Manager struct {
+	store           Store
+	refreshInterval time.Duration
+	now             func() time.Time
+
+	mu        sync.Mutex
+	cached    Status
+	fetchedAt time.Time
+	// refreshing is closed when the store read in progress, if any, ends
+	refreshing chan struct{}
+}
+
+// NewManager creates a Manager that re-reads the store at most once per refreshInterval
+func NewManager(store Store, refreshInterval time.Duration) *Manager {
+	return &Manager{
+		store:           store,
+		refreshInterval: refreshInterval,
+		now:             time.Now,
+	}
+}
+
+// Status returns the current maintenance state. If the store cannot be
+// read, the last known state is kept so an outage does not toggle the mode.
+// One request at a time reads the store, without holding the lock; others
+// get the last known state meanwhile, or wait for the first read.
+func (m *Manager) Status(ctx context.Context) Status {
+	m.mu.Lock()
+	now := m.now()
+	fetchedAt, cached, refreshing := m.fetchedAt, m.cached, m.refreshing
+	if !fetchedAt.IsZero() && (now.Sub(fetchedAt) < m.refreshInterval || refreshing != nil) {
+		m.mu.Unlock()
+		return cached
+	}
+	if refreshing != nil {
+		m.mu.Unlock()
+		select {
+		case <-refreshing:
+		case <-ctx.Done():
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.cached
+	}
+	done := make(chan struct{})
+	m.refreshing = done
+	m.mu.Unlock()
+
+	status, err := m.store.Get(ctx)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Enable, Disable and Refresh may have stored a newer state meanwhile
+	if m.fetchedAt.Equal(fetchedAt) {
+		if err == nil {
+			m.cached = status
+		}
+		m.fetchedAt = now
+	}
+	m.refreshing = nil
+	close(done)
+	return m.cached
+}
+
+// Enable turns maintenance mode on
+func (m *Manager) Enable(ctx context.Context, message string, retryAfter time.Duration) (Status, error) {
+	return m.set(ctx, Status{
+		Enabled:           true,
+		Message:           message,
+		RetryAfterSeconds: int(retryAfter / time.Second),
+	})
+}
+
+// Disable turns maintenance mode off
+func (m *Manager) Disable(ctx context.Context) (Status, error) {
+	return m.set(ctx, Status{})
+}
+
+// Refresh re-reads the state from the store, bypassing the cache
+func (m *Manager) Refresh(ctx context.Context) (Status, error) {
+	status, err := m.store.Get(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+
+	m.mu.Lock()
+	m.cached = status
+	m.fetchedAt = m.now()
+	m.mu.Unlock()
+	return status, nil
+}
+
+func (m *Manager) set(ctx context.Context, status Status) (Status, error) {
+	status.UpdatedAt = m.now().UTC()
+	if err := m.store.Set(ctx, status); err != nil {
+		return Status{}, err
+	}
+
+	m.mu.Lock()
+	m.cached = status
+	m.fetchedAt = m.now()
+	m.mu.Unlock()
+	return status, nil
+}
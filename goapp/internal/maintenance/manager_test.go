@@ -0,0 +1,144 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"goapp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Setting{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	return db
+}
+
+// failingStore simulates an unreachable store
+type failingStore struct{}
+
+func (failingStore) Get(ctx context.Context) (Status, error)      { return Status{}, errors.New("down") }
+func (failingStore) Set(ctx context.Context, status Status) error { return errors.New("down") }
+
+// blockingStore holds Get until release is closed
+type blockingStore struct {
+	Store
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s blockingStore) Get(ctx context.Context) (Status, error) {
+	s.started <- struct{}{}
+	<-s.release
+	return s.Store.Get(ctx)
+}
+
+func TestDBStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewDBStore(setupTestDB(t))
+
+	status, err := store.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if status.Enabled {
+		t.Error("Expected maintenance mode to be off without a stored setting")
+	}
+
+	for _, want := range []Status{
+		{Enabled: true, Message: "Upgrading", RetryAfterSeconds: 120},
+		{Enabled: false},
+	} {
+		if err := store.Set(ctx, want); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+		got, err := store.Get(ctx)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if got.Enabled != want.Enabled || got.Message != want.Message || got.RetryAfterSeconds != want.RetryAfterSeconds {
+			t.Errorf("Expected %+v, got %+v", want, got)
+		}
+	}
+}
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	manager := NewManager(store, time.Minute)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	manager.now = func() time.Time { return now }
+
+	t.Run("EnableAndDisable", func(t *testing.T) {
+		status, err := manager.Enable(ctx, "Back soon", 2*time.Minute)
+		if err != nil {
+			t.Fatalf("Enable() error = %v", err)
+		}
+		if !status.Enabled || status.Message != "Back soon" || status.RetryAfter() != 2*time.Minute {
+			t.Errorf("Unexpected status: %+v", status)
+		}
+		if !manager.Status(ctx).Enabled {
+			t.Error("Expected Status() to report maintenance mode on")
+		}
+
+		if _, err := manager.Disable(ctx); err != nil {
+			t.Fatalf("Disable() error = %v", err)
+		}
+		if manager.Status(ctx).Enabled {
+			t.Error("Expected Status() to report maintenance mode off")
+		}
+	})
+
+	t.Run("CachesUntilRefreshInterval", func(t *testing.T) {
+		// Another instance enables maintenance mode through the shared store
+		if err := store.Set(ctx, Status{Enabled: true}); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+		if manager.Status(ctx).Enabled {
+			t.Error("Expected cached state before the refresh interval elapsed")
+		}
+
+		now = now.Add(time.Minute)
+		if !manager.Status(ctx).Enabled {
+			t.Error("Expected refreshed state after the refresh interval elapsed")
+		}
+	})
+
+	t.Run("KeepsLastStateOnStoreError", func(t *testing.T) {
+		manager.store = failingStore{}
+		now = now.Add(time.Minute)
+		if !manager.Status(ctx).Enabled {
+			t.Error("Expected last known state when the store is unavailable")
+		}
+		if _, err := manager.Enable(ctx, "", 0); err == nil {
+			t.Error("Expected error when the store is unavailable")
+		}
+	})
+
+	t.Run("ServesLastStateWhileRefreshing", func(t *testing.T) {
+		store := blockingStore{Store: NewMemoryStore(), started: make(chan struct{}), release: make(chan struct{})}
+		manager.store = store
+		now = now.Add(time.Minute)
+
+		refreshed := make(chan Status)
+		go func() { refreshed <- manager.Status(ctx) }()
+		<-store.started
+		// The slow read holds no lock, and other requests do not queue behind it
+		if !manager.Status(ctx).Enabled {
+			t.Error("Expected the last known state while the store is read")
+		}
+		close(store.release)
+		if (<-refreshed).Enabled {
+			t.Error("Expected the refreshed state once the store was read")
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package maintenance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"goapp/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// settingKey is the models.Setting key holding the maintenance state
+const settingKey = "maintenance"
+
+// Store persists the maintenance state
+type Store interface {
+	Get(ctx context.Context) (Status, error)
+	Set(ctx context.Context, status Status) error
+}
+
+// dbStore implements Store on the settings table so that every instance and
+// the CLI share the same state
+type dbStore struct {
+	db *gorm.DB
+}
+
+// NewDBStore creates a Store backed by the settings table
+func NewDBStore(db *gorm.DB) Store {
+	return &dbStore{db: db}
+}
+
+// Get implements Store
+func (s *dbStore) Get(ctx context.Context) (Status, error) {
+	var setting models.Setting
+	err := s.db.WithContext(ctx).Where("key = ?", settingKey).First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Status{}, nil
+	}
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to load maintenance state: %w", err)
+	}
+
+	var status Status
+	if err := json.Unmarshal([]byte(setting.Value), &status); err != nil {
+		return Status{}, fmt.Errorf("failed to decode maintenance state: %w", err)
+	}
+	return status, nil
+}
+
+// Set implements Store
+func (s *dbStore) Set(ctx context.Context, status Status) error {
+	value, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to encode maintenance state: %w", err)
+	}
+
+	setting := models.Setting{Key: settingKey, Value: string(value)}
+	err = s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&setting).Error
+	if err != nil {
+		return fmt.Errorf("failed to save maintenance state: %w", err)
+	}
+	return nil
+}
+
+// memoryStore implements Store in process memory, for running without a database
+type memoryStore struct {
+	mu     sync.RWMutex
+	status Status
+}
+
+// NewMemoryStore creates a Store that only lives as long as the process
+func NewMemoryStore() Store {
+	return &memoryStore{}
+}
+
+// Get implements Store
+func (s *memoryStore) Get(ctx context.Context) (Status, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.status, nil
+}
+
+// Set implements Store
+func (s *memoryStore) Set(ctx context.Context, status Status) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+	return nil
+}
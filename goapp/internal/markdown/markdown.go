@@ -0,0 +1,220 @@
+// Package markdown renders the Markdown of posts and comments to sanitized
+// HTML, with syntax highlighting of fenced code blocks and, for posts,
+// heading anchors and a table of contents.
+package markdown
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// HeadingIDPrefix starts the id of every heading in a rendered post, so
+// headings never clash with the ids of the page around them
+const HeadingIDPrefix = "section-"
+
+// Heading is an entry in the table of contents of a document
+type Heading struct {
+	Level int    `json:"level"` // 1 to 6
+	ID    string `json:"id"`    // the anchor of the heading, without "#"
+	Text  string `json:"text"`
+}
+
+var (
+	// documents may contain raw HTML, which documentPolicy removes when unsafe
+	documents = goldmark.New(
+		goldmark.WithExtensions(extension.GFM, highlighter()),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+	// snippets omit raw HTML and heading ids
+	snippets = goldmark.New(
+		goldmark.WithExtensions(extension.GFM, highlighter()),
+	)
+)
+
+func highlighter() goldmark.Extender {
+	return highlighting.NewHighlighting(highlighting.WithStyle("github"))
+}
+
+// Render converts the Markdown of a post to sanitized HTML. Headings get
+// ids starting with HeadingIDPrefix and a link to themselves.
+func Render(source string) string {
+	src := []byte(source)
+	doc := parse(src)
+	for _, heading := range headings(doc, src) {
+		heading.node.AppendChild(heading.node, anchor(heading.ID))
+	}
+
+	var buf bytes.Buffer
+	if err := documents.Renderer().Render(&buf, src, doc); err != nil {
+		// The renderer only fails when writing to buf does
+		return ""
+	}
+	return documentPolicy.Sanitize(buf.String())
+}
+
+// RenderSnippet converts short Markdown, such as a comment, to sanitized
+// HTML. Raw HTML is dropped and headings get no ids.
+func RenderSnippet(source string) string {
+	var buf bytes.Buffer
+	if err := snippets.Convert([]byte(source), &buf); err != nil {
+		return ""
+	}
+	return snippetPolicy.Sanitize(buf.String())
+}
+
+// TableOfContents lists the headings of a post in order, with the ids
+// Render gives them
+func TableOfContents(source string) []Heading {
+	src := []byte(source)
+	found := headings(parse(src), src)
+	toc := make([]Heading, len(found))
+	for i, heading := range found {
+		toc[i] = heading.Heading
+	}
+	return toc
+}
+
+// Summary returns the text of the first paragraph of source, without
+// Markdown, cut at a word boundary to at most max characters
+func Summary(source string, max int) string {
+	src := []byte(source)
+	var summary string
+	_ = ast.Walk(parse(src), func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || n.Kind() != ast.KindParagraph {
+			return ast.WalkContinue, nil
+		}
+		summary = strings.Join(strings.Fields(plainText(n, src)), " ")
+		if summary == "" {
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkStop, nil
+	})
+	return truncate(summary, max)
+}
+
+// truncate cuts s to at most max characters, ending with an ellipsis after
+// the last whole word that fits
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)[:max-1]
+	cut := strings.TrimRightFunc(string(runes), func(r rune) bool { return !unicode.IsSpace(r) })
+	if strings.TrimSpace(cut) == "" {
+		cut = string(runes)
+	}
+	return strings.TrimRightFunc(cut, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsPunct(r) }) + "…"
+}
+
+func parse(src []byte) ast.Node {
+	ctx := parser.NewContext(parser.WithIDs(&headingIDs{seen: map[string]bool{}}))
+	return documents.Parser().Parse(text.NewReader(src), parser.WithContext(ctx))
+}
+
+type heading struct {
+	Heading
+	node *ast.Heading
+}
+
+func headings(doc ast.Node, src []byte) []heading {
+	var found []heading
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		node, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		id, _ := node.AttributeString("id")
+		idBytes, _ := id.([]byte)
+		found = append(found, heading{
+			Heading: Heading{Level: node.Level, ID: string(idBytes), Text: strings.TrimSpace(plainText(node, src))},
+			node:    node,
+		})
+		return ast.WalkSkipChildren, nil
+	})
+	return found
+}
+
+// plainText concatenates the text in n, including code spans and the
+// labels of links and images
+func plainText(n ast.Node, src []byte) string {
+	var b strings.Builder
+	_ = ast.Walk(n, func(child ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch child := child.(type) {
+		case *ast.Text:
+			b.Write(child.Segment.Value(src))
+			if child.SoftLineBreak() || child.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(child.Value)
+		case *ast.AutoLink:
+			b.Write(child.Label(src))
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
+
+// anchor links a heading to itself
+func anchor(id string) ast.Node {
+	link := ast.NewLink()
+	link.Destination = []byte("#" + id)
+	link.SetAttributeString("class", []byte("heading-anchor"))
+	link.SetAttributeString("aria-label", []byte("Link to this section"))
+	link.AppendChild(link, ast.NewString([]byte("#")))
+	return link
+}
+
+// headingIDs gives headings unique ids made of HeadingIDPrefix and the
+// lowercase letters and digits of their text
+type headingIDs struct {
+	seen map[string]bool
+}
+
+// Generate implements parser.IDs
+func (ids *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(string(value)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		default:
+			dash = true
+		}
+	}
+	base := HeadingIDPrefix + b.String()
+	if b.Len() == 0 {
+		base = HeadingIDPrefix + "heading"
+	}
+	id := base
+	for n := 1; ids.seen[id]; n++ {
+		id = base + "-" + strconv.Itoa(n)
+	}
+	ids.seen[id] = true
+	return []byte(id)
+}
+
+// Put implements parser.IDs
+func (ids *headingIDs) Put(value []byte) {
+	ids.seen[string(value)] = true
+}
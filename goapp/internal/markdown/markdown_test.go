@@ -0,0 +1,96 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+const document = "# Getting *Started*\n\nInstall the `goapp` binary.\n\n## Comments\n\n## Comments\n\n```go\nfunc main() {}\n```\n"
+
+func TestRender(t *testing.T) {
+	html := Render(document)
+	for _, want := range []string{
+		`<h1 id="section-getting-started">Getting <em>Started</em><a href="#section-getting-started" class="heading-anchor"`,
+		`<h2 id="section-comments">`,
+		`<h2 id="section-comments-1">`,
+		`<code>goapp</code>`,
+		`<span style="color: #000; font-weight: bold">func</span>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("Expected %q in %s", want, html)
+		}
+	}
+
+	for _, attack := range []string{
+		"<script>alert(1)</script>",
+		`<img src="x" onerror="alert(1)">`,
+		"[click](javascript:alert(1))",
+		`<a href="javascript:alert(1)">click</a>`,
+		`<p style="position: fixed">over</p>`,
+		`<iframe src="https://example.com"></iframe>`,
+	} {
+		html := Render(attack)
+		for _, bad := range []string{"<script", "onerror", "javascript:", "position", "<iframe"} {
+			if strings.Contains(html, bad) {
+				t.Errorf("Render(%q) = %q, expected %q to be removed", attack, html, bad)
+			}
+		}
+	}
+
+	if html := Render("<mark>kept</mark> [link](https://example.com)"); !strings.Contains(html, "<mark>kept</mark>") || !strings.Contains(html, `rel="nofollow noreferrer"`) {
+		t.Errorf("Expected safe raw HTML to be kept and links to be nofollow, got %q", html)
+	}
+
+	// Raw HTML must not take over the ids of the page, such as the comment form's target
+	if html := Render(`<div id="comments">mine</div><span id="section-ok">kept</span>`); strings.Contains(html, `id="comments"`) || !strings.Contains(html, `<div>mine</div>`) || !strings.Contains(html, `id="section-ok"`) {
+		t.Errorf("Expected only heading-style ids to be kept, got %q", html)
+	}
+	if html := snippetPolicy.Sanitize(`<h2 id="section-title">Title</h2>`); html != "<h2>Title</h2>" {
+		t.Errorf("Expected snippets to have no ids, got %q", html)
+	}
+}
+
+func TestRenderSnippet(t *testing.T) {
+	html := RenderSnippet("## Title\n\n**Bold** <b>raw</b>\n\n- [x] done")
+	if !strings.Contains(html, "<h2>Title</h2>") || !strings.Contains(html, "<strong>Bold</strong>") {
+		t.Errorf("Expected Markdown without heading ids, got %q", html)
+	}
+	if strings.Contains(html, "<b>") {
+		t.Errorf("Expected raw HTML to be dropped, got %q", html)
+	}
+	if !strings.Contains(html, `<input checked="" disabled="" type="checkbox">`) {
+		t.Errorf("Expected task list checkboxes, got %q", html)
+	}
+}
+
+func TestTableOfContents(t *testing.T) {
+	toc := TableOfContents(document)
+	if len(toc) != 3 {
+		t.Fatalf("Expected 3 headings, got %+v", toc)
+	}
+	if toc[0] != (Heading{Level: 1, ID: "section-getting-started", Text: "Getting Started"}) || toc[2].ID != "section-comments-1" {
+		t.Errorf("Unexpected table of contents %+v", toc)
+	}
+	if toc := TableOfContents("No headings"); len(toc) != 0 {
+		t.Errorf("Expected no headings, got %+v", toc)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		source string
+		max    int
+		want   string
+	}{
+		{document, 200, "Install the goapp binary."},
+		{"A [linked](https://example.com) and\n**bold** line.", 200, "A linked and bold line."},
+		{"One two three four", 12, "One two…"},
+		{"Supercalifragilistic", 6, "Super…"},
+		{"# Only a heading", 200, ""},
+	}
+	for _, tt := range tests {
+		if got := Summary(tt.source, tt.max); got != tt.want {
+			t.Errorf("Summary(%q, %d) = %q, expected %q", tt.source, tt.max, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package markdown
+
+import (
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+var (
+	// documentPolicy allows what Render produces, including heading anchors
+	documentPolicy = newPolicy(true)
+	// snippetPolicy allows what RenderSnippet produces
+	snippetPolicy = newPolicy(false)
+)
+
+// headingID matches the ids Render gives headings. Raw HTML may not set
+// other ids, or a post could take over those the page relies on, such as
+// the target of the comment form.
+var headingID = regexp.MustCompile(`^` + HeadingIDPrefix + `[a-z0-9-]+$`)
+
+// newPolicy extends the user generated content policy with the inline
+// styles of highlighted code and the checkboxes of task lists. Links open
+// without passing on the page and are marked nofollow.
+func newPolicy(anchors bool) *bluemonday.Policy {
+	p := ugcPolicy()
+	p.AllowStyles("color", "background-color", "font-weight", "font-style", "text-decoration", "display").
+		OnElements("pre", "span")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	p.RequireNoReferrerOnLinks(true)
+	if anchors {
+		p.AllowAttrs("id").Matching(headingID).Globally()
+		p.AllowAttrs("class").Matching(regexp.MustCompile(`^heading-anchor$`)).OnElements("a")
+		p.AllowAttrs("aria-label").OnElements("a")
+	}
+	return p
+}
+
+// ugcPolicy is bluemonday.UGCPolicy without its id attribute, which allows
+// any id on every element and cannot be narrowed once allowed
+func ugcPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowAttrs("dir").Matching(bluemonday.Direction).Globally()
+	p.AllowAttrs("lang").Matching(regexp.MustCompile(`[a-zA-Z]{2,20}`)).Globally()
+	p.AllowAttrs("title").Matching(bluemonday.Paragraph).Globally()
+	p.AllowStandardURLs()
+
+	p.AllowElements("article", "aside", "figure", "section", "summary", "hgroup")
+	p.AllowAttrs("open").Matching(regexp.MustCompile(`(?i)^(|open)$`)).OnElements("details")
+	p.AllowElements("h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowAttrs("cite").OnElements("blockquote")
+	p.AllowElements("br", "div", "hr", "p", "span", "wbr")
+
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowAttrs("name").Matching(regexp.MustCompile(`^([\p{L}\p{N}_-]+)$`)).OnElements("map")
+	p.AllowAttrs("alt").Matching(bluemonday.Paragraph).OnElements("area")
+	p.AllowAttrs("coords").Matching(regexp.MustCompile(`^([0-9]+,)+[0-9]+$`)).OnElements("area")
+	p.AllowAttrs("href").OnElements("area")
+	p.AllowAttrs("rel").Matching(bluemonday.SpaceSeparatedTokens).OnElements("area")
+	p.AllowAttrs("shape").Matching(regexp.MustCompile(`(?i)^(default|circle|rect|poly)$`)).OnElements("area")
+	p.AllowAttrs("usemap").Matching(regexp.MustCompile(`(?i)^#[\p{L}\p{N}_-]+$`)).OnElements("img")
+
+	p.AllowElements("abbr", "acronym", "cite", "code", "dfn", "em",
+		"figcaption", "mark", "s", "samp", "strong", "sub", "sup", "var")
+	p.AllowAttrs("cite").OnElements("q")
+	p.AllowAttrs("datetime").Matching(bluemonday.ISO8601).OnElements("time")
+	p.AllowElements("b", "i", "pre", "small", "strike", "tt", "u")
+	p.AllowAttrs("dir").Matching(bluemonday.Direction).OnElements("bdi", "bdo")
+	p.AllowElements("rp", "rt", "ruby")
+	p.AllowAttrs("cite").Matching(bluemonday.Paragraph).OnElements("del", "ins")
+	p.AllowAttrs("datetime").Matching(bluemonday.ISO8601).OnElements("del", "ins")
+
+	p.AllowLists()
+	p.AllowTables()
+	p.AllowAttrs("value", "min", "max", "low", "high", "optimum").Matching(bluemonday.Number).OnElements("meter")
+	p.AllowAttrs("value", "max").Matching(bluemonday.Number).OnElements("progress")
+	p.AllowImages()
+	return p
+}
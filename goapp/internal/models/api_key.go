@@ -0,0 +1,72 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey lets another service call the API as its owner, limited to the
+// key's scopes. The public KeyID identifies the key in logs and the admin
+// UI; only the SHA-256 hash of the full key is stored.
+type APIKey struct {
+	BaseModel
+	Name       string     `gorm:"not null" json:"name"`
+	KeyID      string     `gorm:"uniqueIndex;size:32;not null" json:"key_id"`
+	KeyHash    string     `gorm:"size:64;not null" json:"-"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	User       User       `gorm:"foreignKey:UserID" json:"-"`
+	Scopes     string     `gorm:"type:text;not null" json:"-"` // space-separated permission names
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `gorm:"size:45" json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// BeforeCreate hook for APIKey model
+func (k *APIKey) BeforeCreate(tx *gorm.DB) error {
+	if k.Name == "" {
+		return errors.New("name is required")
+	}
+	if k.KeyID == "" || k.KeyHash == "" {
+		return errors.New("key_id and key_hash are required")
+	}
+	if k.UserID == 0 {
+		return errors.New("user_id is required")
+	}
+	return nil
+}
+
+// ScopeList returns the permission names the key is limited to
+func (k *APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// HasScope reports whether the key may be used for the named permission.
+// "*" covers everything and "<type>:*" every action on a resource type.
+func (k *APIKey) HasScope(permission string) bool {
+	resourceType, _, _ := strings.Cut(permission, ":")
+	for _, scope := range k.ScopeList() {
+		if scope == permission || scope == PermissionAll || scope == resourceType+":*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the key can be used at the given time
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// ResourceType identifies API keys to the authorization policy
+func (k *APIKey) ResourceType() string {
+	return "api_keys"
+}
+
+// OwnerID returns the user the key acts as
+func (k *APIKey) OwnerID() uint {
+	return k.UserID
+}
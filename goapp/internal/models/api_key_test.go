@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAPIKeyScopes(t *testing.T) {
+	key := &APIKey{Scopes: "comments:* posts:read"}
+
+	tests := []struct {
+		permission string
+		expected   bool
+	}{
+		{"posts:read", true},
+		{"posts:update", false},
+		{"comments:delete", true},
+		{"api_keys:create", false},
+	}
+	for _, tt := range tests {
+		if got := key.HasScope(tt.permission); got != tt.expected {
+			t.Errorf("HasScope(%q): expected %v, got %v", tt.permission, tt.expected, got)
+		}
+	}
+
+	if !(&APIKey{Scopes: "*"}).HasScope("api_keys:create") {
+		t.Error("Expected * to cover every permission")
+	}
+}
+
+func TestAPIKeyActive(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	if !(&APIKey{}).Active(now) {
+		t.Error("Expected key without expiry to be active")
+	}
+	if !(&APIKey{ExpiresAt: &future}).Active(now) {
+		t.Error("Expected unexpired key to be active")
+	}
+	if (&APIKey{ExpiresAt: &past}).Active(now) {
+		t.Error("Expected expired key to be inactive")
+	}
+	if (&APIKey{RevokedAt: &past}).Active(now) {
+		t.Error("Expected revoked key to be inactive")
+	}
+}
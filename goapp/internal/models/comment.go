@@ -9,10 +9,11 @@ import (
 // Comment represents a comment on a post
 type Comment struct {
 	BaseModel
-	Content  string  `gorm:"type:text;not null" json:"content"`
-	UserID   uint    `gorm:"not null;index" json:"user_id"`
-	PostID   uint    `gorm:"not null;index" json:"post_id"`
-	ParentID *uint   `gorm:"index" json:"parent_id,omitempty"`
+	Content     string `gorm:"type:text;not null" json:"content"` // Markdown
+	ContentHTML string `gorm:"type:text" json:"-"`                // Content rendered by the comments service
+	UserID      uint   `gorm:"not null;index" json:"user_id"`
+	PostID      uint   `gorm:"not null;index" json:"post_id"`
+	ParentID    *uint  `gorm:"index" json:"parent_id,omitempty"`
 	
 	// Associations
 	User     User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
@@ -33,4 +34,14 @@ func (c *Comment) BeforeCreate(tx *gorm.DB) error {
 		return errors.New("post_id is required")
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// ResourceType identifies comments to the authorization policy
+func (c *Comment) ResourceType() string {
+	return "comments"
+}
+
+// OwnerID returns the author of the comment
+func (c *Comment) OwnerID() uint {
+	return c.UserID
+}
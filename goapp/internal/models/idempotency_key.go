@@ -0,0 +1,41 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IdempotencyKey records the outcome of a request sent with an Idempotency-Key
+// header so that retries can be answered without re-executing the handler.
+// Key is the client's key scoped to its caller and route.
+type IdempotencyKey struct {
+	BaseModel
+	Key          string    `gorm:"uniqueIndex;size:255;not null" json:"key"`
+	RequestHash  string    `gorm:"size:64;not null" json:"request_hash"`
+	Completed    bool      `gorm:"default:false" json:"completed"`
+	StatusCode   int       `json:"status_code"`
+	ContentType  string    `json:"content_type"`
+	ResponseBody []byte    `json:"-"`
+	ExpiresAt    time.Time `gorm:"not null;index" json:"expires_at"`
+
+	// ResponseHeaders holds the replayed headers of the response as JSON
+	ResponseHeaders string `gorm:"type:text" json:"-"`
+}
+
+// BeforeCreate hook for IdempotencyKey model
+func (k *IdempotencyKey) BeforeCreate(tx *gorm.DB) error {
+	if k.Key == "" {
+		return errors.New("key is required")
+	}
+	if k.RequestHash == "" {
+		return errors.New("request_hash is required")
+	}
+	return nil
+}
+
+// Expired reports whether the key has passed its expiry time
+func (k *IdempotencyKey) Expired(now time.Time) bool {
+	return !now.Before(k.ExpiresAt)
+}
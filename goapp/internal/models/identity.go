@@ -0,0 +1,31 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Identity links a user to their account at an external OpenID Connect
+// provider, identified by the provider's issuer and the subject it assigned
+type Identity struct {
+	BaseModel
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	User        User       `gorm:"foreignKey:UserID" json:"-"`
+	Issuer      string     `gorm:"uniqueIndex:idx_identities_issuer_subject;not null" json:"issuer"`
+	Subject     string     `gorm:"uniqueIndex:idx_identities_issuer_subject;not null" json:"subject"`
+	Email       string     `json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// BeforeCreate hook for Identity model
+func (i *Identity) BeforeCreate(tx *gorm.DB) error {
+	if i.Issuer == "" || i.Subject == "" {
+		return errors.New("issuer and subject are required")
+	}
+	if i.UserID == 0 {
+		return errors.New("user_id is required")
+	}
+	return nil
+}
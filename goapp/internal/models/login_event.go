@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Login methods recorded in LoginEvent.Method
+const (
+	LoginMethodPassword  = "password"
+	LoginMethodTwoFactor = "two_factor"
+	LoginMethodSSO       = "sso"
+)
+
+// Login outcomes recorded in LoginEvent.Outcome
+const (
+	LoginSucceeded         = "success"
+	LoginTwoFactorRequired = "two_factor_required" // the password was right; a code is still needed
+	LoginFailed            = "failed"
+	LoginLocked            = "locked"
+	LoginInactive          = "inactive"
+)
+
+// LoginEvent records one sign-in attempt. UserID is nil when the
+// identifier matched no account. Events are never updated, so the model
+// has no UpdatedAt or soft delete.
+type LoginEvent struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+	UserID     *uint     `gorm:"index" json:"user_id,omitempty"`
+	Identifier string    `gorm:"size:255" json:"identifier"`
+	Method     string    `gorm:"size:20;not null" json:"method"`
+	Outcome    string    `gorm:"size:20;not null" json:"outcome"`
+	IPAddress  string    `gorm:"size:45" json:"ip_address"`
+	UserAgent  string    `gorm:"size:255" json:"user_agent"`
+}
+
+// Succeeded reports whether the attempt got past the step it records
+func (e *LoginEvent) Succeeded() bool {
+	return e.Outcome == LoginSucceeded || e.Outcome == LoginTwoFactorRequired
+}
@@ -2,25 +2,40 @@ package models
 
 import (
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+// Post statuses; the posts package decides which changes are allowed
+const (
+	PostDraft     = "draft"
+	PostInReview  = "in_review"
+	PostScheduled = "scheduled" // published by the scheduler at ScheduledAt
+	PostPublished = "published"
+	PostArchived  = "archived"
+)
+
 // Post represents a blog post or article
 type Post struct {
 	BaseModel
-	Title       string `gorm:"not null" json:"title"`
-	Slug        string `gorm:"uniqueIndex;not null" json:"slug"`
-	Content     string `gorm:"type:text" json:"content"`
-	Summary     string `gorm:"type:text" json:"summary"`
-	Published   bool   `gorm:"default:false;index" json:"published"`
-	ViewCount   uint   `gorm:"default:0" json:"view_count"`
-	UserID      uint   `gorm:"not null;index" json:"user_id"`
+	Title       string     `gorm:"not null" json:"title"`
+	Slug        string     `gorm:"uniqueIndex;not null" json:"slug"`
+	Content     string     `gorm:"type:text" json:"content"` // Markdown
+	ContentHTML string     `gorm:"type:text" json:"-"`       // Content rendered by the posts service
+	Summary     string     `gorm:"type:text" json:"summary"`
+	Status      string     `gorm:"size:20;not null;default:draft;index" json:"status"`
+	Published   bool       `gorm:"default:false;index" json:"published"` // Status is PostPublished; kept for filtering and authorization
+	PublishedAt *time.Time `json:"published_at,omitempty"`               // first publication
+	ScheduledAt *time.Time `gorm:"index" json:"scheduled_at,omitempty"`  // set while Status is PostScheduled
+	ViewCount   uint       `gorm:"default:0" json:"view_count"`          // added to in batches by views.Counter
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
 	
 	// Associations
-	User     User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	Comments []Comment `gorm:"foreignKey:PostID" json:"comments,omitempty"`
-	Tags     []Tag     `gorm:"many2many:post_tags;" json:"tags,omitempty"`
+	User      User           `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Comments  []Comment      `gorm:"foreignKey:PostID" json:"comments,omitempty"`
+	Tags      []Tag          `gorm:"many2many:post_tags;" json:"tags,omitempty"`
+	Revisions []PostRevision `gorm:"foreignKey:PostID" json:"revisions,omitempty"`
 }
 
 // BeforeCreate hook for Post model
@@ -34,10 +49,34 @@ func (p *Post) BeforeCreate(tx *gorm.DB) error {
 	if p.UserID == 0 {
 		return errors.New("user_id is required")
 	}
+	if p.Status == "" {
+		p.Status = PostDraft
+		if p.Published {
+			p.Status = PostPublished
+		}
+	}
+	p.Published = p.Status == PostPublished
+	if p.Published && p.PublishedAt == nil {
+		now := time.Now()
+		p.PublishedAt = &now
+	}
 	return nil
 }
 
-// IncrementViewCount increments the view count for the post
+// IncrementViewCount increments the view count for the post.
+//
+// Deprecated: an UPDATE per view contends on popular posts; record views
+// with views.Counter, which adds them up in batches.
 func (p *Post) IncrementViewCount(db *gorm.DB) error {
 	return db.Model(p).Update("view_count", gorm.Expr("view_count + ?", 1)).Error
-}
\ No newline at end of file
+}
+
+// ResourceType identifies posts to the authorization policy
+func (p *Post) ResourceType() string {
+	return "posts"
+}
+
+// OwnerID returns the author of the post
+func (p *Post) OwnerID() uint {
+	return p.UserID
+}
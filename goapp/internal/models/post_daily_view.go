@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// PostDailyView holds the views of a post on one UTC day, for charts. Rows
+// are added to in batches by views.Counter and never deleted with the post.
+type PostDailyView struct {
+	PostID uint      `gorm:"primaryKey;autoIncrement:false" json:"post_id"`
+	Day    time.Time `gorm:"primaryKey;type:date" json:"day"`
+	Views  uint      `gorm:"not null;default:0" json:"views"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// PostRevision is a snapshot of a post saved by every edit. Revisions are
+// numbered from 1 for each post and never updated, so the model has no
+// UpdatedAt or soft delete.
+type PostRevision struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	PostID    uint      `gorm:"not null;uniqueIndex:idx_post_revisions_post_number" json:"post_id"`
+	Number    int       `gorm:"not null;uniqueIndex:idx_post_revisions_post_number" json:"number"`
+	UserID    *uint     `gorm:"index" json:"user_id,omitempty"` // who made the edit, nil when unknown
+	Title     string    `gorm:"not null" json:"title"`
+	Slug      string    `gorm:"not null" json:"slug"`
+	Summary   string    `gorm:"type:text" json:"summary"`
+	Content   string    `gorm:"type:text" json:"content"`
+	Status    string    `gorm:"size:20;not null" json:"status"`
+
+	// Associations
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
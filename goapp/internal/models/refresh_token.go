@@ -0,0 +1,42 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken is a single-use token exchanged for a new access token.
+// Tokens issued from the same login share a FamilyID; presenting a token
+// that was already used revokes the whole family. Only the SHA-256 hash of
+// the token is stored.
+type RefreshToken struct {
+	BaseModel
+	TokenHash string     `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	FamilyID  string     `gorm:"index;size:32;not null" json:"family_id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	User      User       `gorm:"foreignKey:UserID" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// BeforeCreate hook for RefreshToken model
+func (t *RefreshToken) BeforeCreate(tx *gorm.DB) error {
+	if t.TokenHash == "" {
+		return errors.New("token_hash is required")
+	}
+	if t.FamilyID == "" {
+		return errors.New("family_id is required")
+	}
+	if t.UserID == 0 {
+		return errors.New("user_id is required")
+	}
+	return nil
+}
+
+// Expired reports whether the token has expired at the given time
+func (t *RefreshToken) Expired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
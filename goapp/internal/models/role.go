@@ -0,0 +1,29 @@
+package models
+
+// PermissionAll grants every permission to the roles that hold it
+const PermissionAll = "*"
+
+// Role is a named set of permissions granted to users
+type Role struct {
+	BaseModel
+	Name        string       `gorm:"uniqueIndex;not null" json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `gorm:"many2many:role_permissions;" json:"permissions,omitempty"`
+}
+
+// Permission names an action on a resource type, e.g. "posts:update"
+type Permission struct {
+	BaseModel
+	Name        string `gorm:"uniqueIndex;not null" json:"name"`
+	Description string `json:"description"`
+}
+
+// HasPermission reports whether the role grants the named permission
+func (r *Role) HasPermission(name string) bool {
+	for _, p := range r.Permissions {
+		if p.Name == name || p.Name == PermissionAll {
+			return true
+		}
+	}
+	return false
+}
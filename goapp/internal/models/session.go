@@ -0,0 +1,37 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session is a server-side login session. Only the SHA-256 hash of the
+// session token is stored, so the table cannot be used to hijack sessions.
+type Session struct {
+	BaseModel
+	TokenHash  string    `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	UserID     uint      `gorm:"not null;index" json:"user_id"`
+	User       User      `gorm:"foreignKey:UserID" json:"-"`
+	IPAddress  string    `gorm:"size:45" json:"ip_address"`
+	UserAgent  string    `gorm:"size:255" json:"user_agent"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `gorm:"not null;index" json:"expires_at"`
+}
+
+// BeforeCreate hook for Session model
+func (s *Session) BeforeCreate(tx *gorm.DB) error {
+	if s.TokenHash == "" {
+		return errors.New("token_hash is required")
+	}
+	if s.UserID == 0 {
+		return errors.New("user_id is required")
+	}
+	return nil
+}
+
+// Expired reports whether the session has expired at the given time
+func (s *Session) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
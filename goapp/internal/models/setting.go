@@ -0,0 +1,22 @@
+package models
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Setting stores a runtime application setting shared by all instances
+type Setting struct {
+	BaseModel
+	Key   string `gorm:"uniqueIndex;size:100;not null" json:"key"`
+	Value string `gorm:"type:text" json:"value"`
+}
+
+// BeforeCreate hook for Setting model
+func (s *Setting) BeforeCreate(tx *gorm.DB) error {
+	if s.Key == "" {
+		return errors.New("key is required")
+	}
+	return nil
+}
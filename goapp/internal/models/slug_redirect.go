@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// SlugRedirect remembers a slug a post or tag used to have, so that links
+// to it keep working. Each old slug points at one resource; the row is
+// removed when that resource takes the slug back.
+type SlugRedirect struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	ResourceType string    `gorm:"size:20;not null;uniqueIndex:idx_slug_redirects_slug" json:"resource_type"` // "posts" or "tags"
+	Slug         string    `gorm:"size:255;not null;uniqueIndex:idx_slug_redirects_slug" json:"slug"`
+	ResourceID   uint      `gorm:"not null;index" json:"resource_id"`
+}
@@ -8,4 +8,9 @@ type Tag struct {
 	
 	// Associations
 	Posts []Post `gorm:"many2many:post_tags;" json:"posts,omitempty"`
-}
\ No newline at end of file
+}
+
+// ResourceType identifies tags to the authorization policy
+func (t *Tag) ResourceType() string {
+	return "tags"
+}
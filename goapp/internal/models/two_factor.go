@@ -0,0 +1,62 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TwoFactor holds a user's TOTP secret. It is unconfirmed until the user
+// proves their authenticator app produces matching codes.
+type TwoFactor struct {
+	BaseModel
+	UserID         uint       `gorm:"not null;uniqueIndex" json:"user_id"`
+	User           User       `gorm:"foreignKey:UserID" json:"-"`
+	Secret         string     `gorm:"size:64;not null" json:"-"` // base32 TOTP secret
+	ConfirmedAt    *time.Time `json:"confirmed_at,omitempty"`
+	LastUsedStep   int64      `json:"-"` // rejects replaying a code within its time step
+	FailedAttempts int        `gorm:"default:0" json:"-"`
+	LockedUntil    *time.Time `json:"locked_until,omitempty"`
+}
+
+// BeforeCreate hook for TwoFactor model
+func (t *TwoFactor) BeforeCreate(tx *gorm.DB) error {
+	if t.UserID == 0 {
+		return errors.New("user_id is required")
+	}
+	if t.Secret == "" {
+		return errors.New("secret is required")
+	}
+	return nil
+}
+
+// Confirmed reports whether enrollment was completed
+func (t *TwoFactor) Confirmed() bool {
+	return t.ConfirmedAt != nil
+}
+
+// Locked reports whether too many failed attempts block verification at the given time
+func (t *TwoFactor) Locked(now time.Time) bool {
+	return t.LockedUntil != nil && now.Before(*t.LockedUntil)
+}
+
+// RecoveryCode is a single-use code that replaces a TOTP code when the
+// authenticator is lost. Only the SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	BaseModel
+	UserID   uint       `gorm:"not null;index" json:"user_id"`
+	CodeHash string     `gorm:"size:64;not null" json:"-"`
+	UsedAt   *time.Time `json:"used_at,omitempty"`
+}
+
+// BeforeCreate hook for RecoveryCode model
+func (r *RecoveryCode) BeforeCreate(tx *gorm.DB) error {
+	if r.UserID == 0 {
+		return errors.New("user_id is required")
+	}
+	if r.CodeHash == "" {
+		return errors.New("code_hash is required")
+	}
+	return nil
+}
@@ -18,10 +18,32 @@ type User struct {
 	Active        bool       `gorm:"default:true" json:"active"`
 	EmailVerified bool       `gorm:"default:false" json:"email_verified"`
 	LastLoginAt   *time.Time `json:"last_login_at,omitempty"`
+
+	// ServiceAccount users represent other services; they authenticate
+	// with API keys only and cannot sign in with a password
+	ServiceAccount bool `gorm:"default:false" json:"service_account"`
+
+	// TwoFactorEnabled is set once TOTP enrollment is confirmed, so sign-in
+	// and the enrollment policy need not load the TwoFactor record
+	TwoFactorEnabled bool `gorm:"default:false" json:"two_factor_enabled"`
+
+	// Failed password sign-ins since the last successful one; once they
+	// reach the configured threshold, sign-in is refused until LockedUntil
+	FailedLoginAttempts int        `gorm:"default:0" json:"-"`
+	LockedUntil         *time.Time `json:"-"`
+
+	// TokenVersion is carried by access tokens; bumping it, on sign-out
+	// everywhere or a password reset, invalidates those already issued
+	TokenVersion uint `gorm:"default:0;not null" json:"-"`
+
+	// PendingEmail is the address the user asked to change to. It replaces
+	// Email only once confirmed with a link sent to it.
+	PendingEmail string `gorm:"default:'';not null" json:"-"`
 	
 	// Associations
 	Posts    []Post    `gorm:"foreignKey:UserID" json:"posts,omitempty"`
 	Comments []Comment `gorm:"foreignKey:UserID" json:"comments,omitempty"`
+	Roles    []Role    `gorm:"many2many:user_roles;" json:"roles,omitempty"`
 }
 
 // BeforeCreate hook for User model
@@ -44,4 +66,40 @@ func (u *User) FullName() string {
 		return u.Username
 	}
 	return u.FirstName + " " + u.LastName
-}
\ No newline at end of file
+}
+
+// ResourceType identifies users to the authorization policy
+func (u *User) ResourceType() string {
+	return "users"
+}
+
+// OwnerID returns the user itself, which owns its profile
+func (u *User) OwnerID() uint {
+	return u.ID
+}
+
+// Locked reports whether password sign-in is locked at the given time
+func (u *User) Locked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+// HasRole reports whether the user has been granted the named role; Roles must be loaded
+func (u *User) HasRole(name string) bool {
+	for _, r := range u.Roles {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether any of the user's roles grants the named
+// permission; Roles and their Permissions must be loaded
+func (u *User) HasPermission(name string) bool {
+	for i := range u.Roles {
+		if u.Roles[i].HasPermission(name) {
+			return true
+		}
+	}
+	return false
+}
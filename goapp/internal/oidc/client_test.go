@@ -0,0 +1,170 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"goapp/internal/config"
+	"goapp/internal/oidc/oidctest"
+)
+
+func setupTestClient(t *testing.T) (*Client, *oidctest.Server) {
+	server := oidctest.NewServer()
+	t.Cleanup(server.Close)
+
+	client := NewClient(config.OIDCConfig{
+		ProviderName: "Test",
+		Issuer:       server.Issuer(),
+		ClientID:     server.ClientID,
+		ClientSecret: server.ClientSecret,
+		RedirectURL:  "http://localhost/auth/oidc/callback",
+		Scopes:       []string{"openid", "email", "profile"},
+	}, &http.Client{Timeout: 5 * time.Second})
+	return client, server
+}
+
+func TestClientFlow(t *testing.T) {
+	ctx := context.Background()
+	client, server := setupTestClient(t)
+
+	verifier, err := NewCodeVerifier()
+	if err != nil {
+		t.Fatalf("NewCodeVerifier() error = %v", err)
+	}
+	authURL, err := client.AuthCodeURL(ctx, "state-1", "nonce-1", CodeChallenge(verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	for _, param := range []string{"code_challenge_method=S256", "nonce=nonce-1", "scope=openid+email+profile"} {
+		if !strings.Contains(authURL, param) {
+			t.Errorf("Expected auth URL to contain %q, got %s", param, authURL)
+		}
+	}
+
+	callback, err := server.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+	if callback.Query().Get("state") != "state-1" {
+		t.Errorf("Expected state to round-trip, got %q", callback.Query().Get("state"))
+	}
+	code := callback.Query().Get("code")
+
+	if _, err := client.Exchange(ctx, code, "wrong-verifier"); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("Expected wrong PKCE verifier to be rejected, got %v", err)
+	}
+
+	// The failed attempt consumed the code
+	callback, _ = server.Authorize(authURL)
+	tokens, err := client.Exchange(ctx, callback.Query().Get("code"), verifier)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+
+	claims, err := client.Verify(ctx, tokens.IDToken, "nonce-1")
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if claims.Subject != "user-1" || claims.Email != "jane@example.com" || !bool(claims.EmailVerified) {
+		t.Errorf("Unexpected claims %+v", claims)
+	}
+
+	if _, err := client.Verify(ctx, tokens.IDToken, "other-nonce"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("Expected nonce mismatch to be rejected, got %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	user := oidctest.User{Subject: "user-1", Email: "jane@example.com", EmailVerified: true}
+
+	tests := []struct {
+		name   string
+		modify func(jwt.MapClaims)
+	}{
+		{"WrongIssuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }},
+		{"WrongAudience", func(c jwt.MapClaims) { c["aud"] = "another-client" }},
+		{"Expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{"MissingExpiry", func(c jwt.MapClaims) { delete(c, "exp") }},
+		{"WrongAuthorizedParty", func(c jwt.MapClaims) { c["aud"] = []string{"goapp-test", "other"}; c["azp"] = "other" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := setupTestClient(t)
+			server.ModifyClaims = tt.modify
+			token, err := server.IDToken(user, "nonce")
+			if err != nil {
+				t.Fatalf("IDToken() error = %v", err)
+			}
+			if _, err := client.Verify(ctx, token, "nonce"); !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("Expected ErrInvalidIDToken, got %v", err)
+			}
+		})
+	}
+
+	t.Run("ForgedSignature", func(t *testing.T) {
+		client, server := setupTestClient(t)
+		token, _ := server.IDToken(user, "nonce")
+		parts := strings.Split(token, ".")
+		forged, _ := server.IDToken(oidctest.User{Subject: "admin"}, "nonce")
+		forgedParts := strings.Split(forged, ".")
+		if _, err := client.Verify(ctx, forgedParts[0]+"."+forgedParts[1]+"."+parts[2], "nonce"); !errors.Is(err, ErrInvalidIDToken) {
+			t.Errorf("Expected ErrInvalidIDToken, got %v", err)
+		}
+	})
+
+	t.Run("UnsignedToken", func(t *testing.T) {
+		client, server := setupTestClient(t)
+		token := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{
+			"iss": server.Issuer(), "sub": "admin", "aud": server.ClientID, "exp": time.Now().Add(time.Hour).Unix(), "nonce": "nonce",
+		})
+		raw, _ := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+		if _, err := client.Verify(ctx, raw, "nonce"); !errors.Is(err, ErrInvalidIDToken) {
+			t.Errorf("Expected ErrInvalidIDToken, got %v", err)
+		}
+	})
+
+	t.Run("KeyRotation", func(t *testing.T) {
+		client, server := setupTestClient(t)
+		token, _ := server.IDToken(user, "nonce")
+		if _, err := client.Verify(ctx, token, "nonce"); err != nil {
+			t.Fatalf("Verify() error = %v", err)
+		}
+
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("GenerateKey() error = %v", err)
+		}
+		server.Key = key
+		// Pretend the cached keys are old enough to refetch
+		client.keys.fetchedAt = time.Time{}
+
+		token, _ = server.IDToken(user, "nonce")
+		if _, err := client.Verify(ctx, token, "nonce"); err != nil {
+			t.Errorf("Expected token signed with rotated key to verify, got %v", err)
+		}
+	})
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	server := oidctest.NewServer()
+	defer server.Close()
+
+	client := NewClient(config.OIDCConfig{Issuer: server.Issuer() + "/other", ClientID: server.ClientID}, http.DefaultClient)
+	if _, err := client.Provider(context.Background()); err == nil {
+		t.Error("Expected discovery to fail for an unreachable issuer path")
+	}
+
+	client = NewClient(config.OIDCConfig{Issuer: strings.Replace(server.Issuer(), "127.0.0.1", "localhost", 1), ClientID: server.ClientID}, http.DefaultClient)
+	if _, err := client.Provider(context.Background()); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("Expected issuer mismatch error, got %v", err)
+	}
+}
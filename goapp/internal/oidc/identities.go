@@ -0,0 +1,179 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"goapp/internal/auth"
+	"goapp/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrEmailNotVerified is returned when a new identity has no verified
+	// email to link it to a local account
+	ErrEmailNotVerified = errors.New("the provider did not return a verified email address")
+	// ErrRegistrationDisabled is returned when no local account matches and
+	// OIDC_AUTO_REGISTER is off
+	ErrRegistrationDisabled = errors.New("no account exists for this email address")
+	// ErrAccountNotVerified is returned when the local account with the
+	// identity's email never verified it. Anyone may have registered it, so
+	// linking would let them keep signing in with their password.
+	ErrAccountNotVerified = errors.New("the account with this email address is not verified")
+)
+
+var usernameInvalid = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// IdentityStore maps provider identities to local users
+type IdentityStore interface {
+	// SignIn returns the user linked to the identity in claims. An unknown
+	// identity is linked to the user with the same email when both the
+	// provider and the user verified it, or to a newly registered user when
+	// auto-registration is enabled.
+	SignIn(ctx context.Context, claims *Claims) (*models.User, error)
+}
+
+// identityStore implements IdentityStore on the identities table
+type identityStore struct {
+	db           *gorm.DB
+	autoRegister bool
+	now          func() time.Time
+}
+
+// NewIdentityStore creates an IdentityStore
+func NewIdentityStore(db *gorm.DB, autoRegister bool) IdentityStore {
+	return &identityStore{db: db, autoRegister: autoRegister, now: time.Now}
+}
+
+// SignIn implements IdentityStore
+func (s *identityStore) SignIn(ctx context.Context, claims *Claims) (*models.User, error) {
+	var user *models.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var identity models.Identity
+		err := tx.Preload("User").Where("issuer = ? AND subject = ?", claims.Issuer, claims.Subject).First(&identity).Error
+		if err == nil {
+			user = &identity.User
+			return s.touch(tx, &identity, user, claims)
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to load identity: %w", err)
+		}
+
+		// Only a verified email proves the person owns the local account
+		email := auth.NormalizeEmail(claims.Email)
+		if email == "" || !bool(claims.EmailVerified) {
+			return ErrEmailNotVerified
+		}
+
+		user, err = s.linkUser(tx, email, claims)
+		if err != nil {
+			return err
+		}
+		identity = models.Identity{UserID: user.ID, Issuer: claims.Issuer, Subject: claims.Subject, Email: email}
+		if err := tx.Create(&identity).Error; err != nil {
+			return fmt.Errorf("failed to link identity: %w", err)
+		}
+		return s.touch(tx, &identity, user, claims)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// linkUser finds the user with email or registers one
+func (s *identityStore) linkUser(tx *gorm.DB, email string, claims *Claims) (*models.User, error) {
+	var user models.User
+	err := tx.Where("email = ?", email).First(&user).Error
+	if err == nil {
+		if user.ServiceAccount {
+			return nil, ErrRegistrationDisabled
+		}
+		if !user.EmailVerified {
+			return nil, ErrAccountNotVerified
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	if !s.autoRegister {
+		return nil, ErrRegistrationDisabled
+	}
+
+	username, err := s.availableUsername(tx, claims, email)
+	if err != nil {
+		return nil, err
+	}
+	user = models.User{
+		Email:         email,
+		Username:      username,
+		FirstName:     claims.GivenName,
+		LastName:      claims.FamilyName,
+		PasswordHash:  auth.NoPassword,
+		Active:        true,
+		EmailVerified: true,
+	}
+	if user.FirstName == "" && user.LastName == "" && claims.Name != "" {
+		user.FirstName, user.LastName, _ = strings.Cut(claims.Name, " ")
+	}
+	if err := tx.Create(&user).Error; err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	return &user, nil
+}
+
+// availableUsername derives a username from the claims that no other user has
+func (s *identityStore) availableUsername(tx *gorm.DB, claims *Claims, email string) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(email, "@")
+	}
+	base = strings.Trim(usernameInvalid.ReplaceAllString(base, "_"), "_.-")
+	if len(base) > 40 {
+		base = base[:40]
+	}
+	for len(base) < 3 {
+		base += "_"
+	}
+
+	var taken []string
+	if err := tx.Model(&models.User{}).Unscoped().Where("username LIKE ?", base+"%").Pluck("username", &taken).Error; err != nil {
+		return "", fmt.Errorf("failed to check usernames: %w", err)
+	}
+	used := make(map[string]bool, len(taken))
+	for _, name := range taken {
+		used[strings.ToLower(name)] = true
+	}
+
+	candidate := base
+	for i := 2; used[strings.ToLower(candidate)]; i++ {
+		candidate = fmt.Sprintf("%s%d", base, i)
+	}
+	return candidate, nil
+}
+
+// touch records the login on the identity and the user
+func (s *identityStore) touch(tx *gorm.DB, identity *models.Identity, user *models.User, claims *Claims) error {
+	if !user.Active {
+		return auth.ErrInactiveUser
+	}
+
+	now := s.now()
+	updates := map[string]interface{}{"last_login_at": now}
+	if email := auth.NormalizeEmail(claims.Email); email != "" && email != identity.Email {
+		updates["email"] = email
+	}
+	if err := tx.Model(identity).UpdateColumns(updates).Error; err != nil {
+		return fmt.Errorf("failed to record login: %w", err)
+	}
+	if err := tx.Model(user).UpdateColumn("last_login_at", now).Error; err != nil {
+		return fmt.Errorf("failed to record login: %w", err)
+	}
+	user.LastLoginAt = &now
+	return nil
+}
@@ -0,0 +1,158 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"goapp/internal/auth"
+	"goapp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestStore(t *testing.T, autoRegister bool) (IdentityStore, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Identity{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	return NewIdentityStore(db, autoRegister), db
+}
+
+func testClaims(subject, email string, verified bool) *Claims {
+	return &Claims{
+		RegisteredClaims:  jwt.RegisteredClaims{Issuer: "https://idp.example", Subject: subject},
+		Email:             email,
+		EmailVerified:     flexBool(verified),
+		Name:              "Jane Doe",
+		PreferredUsername: "jane",
+	}
+}
+
+func TestSignInLinksExistingUser(t *testing.T) {
+	ctx := context.Background()
+	store, db := setupTestStore(t, false)
+
+	existing := &models.User{Email: "jane@example.com", Username: "jane", PasswordHash: "hash", Active: true, EmailVerified: true}
+	if err := db.Create(existing).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if _, err := store.SignIn(ctx, testClaims("sub-1", "jane@example.com", false)); !errors.Is(err, ErrEmailNotVerified) {
+		t.Errorf("Expected ErrEmailNotVerified, got %v", err)
+	}
+
+	user, err := store.SignIn(ctx, testClaims("sub-1", "Jane@Example.com", true))
+	if err != nil {
+		t.Fatalf("SignIn() error = %v", err)
+	}
+	if user.ID != existing.ID {
+		t.Errorf("Expected user %d, got %d", existing.ID, user.ID)
+	}
+
+	var reloaded models.User
+	db.First(&reloaded, existing.ID)
+	if reloaded.LastLoginAt == nil {
+		t.Errorf("Expected login recorded, got %+v", reloaded)
+	}
+
+	// Later sign-ins use the link, even if the provider's email changes
+	user, err = store.SignIn(ctx, testClaims("sub-1", "jane@new.example", false))
+	if err != nil || user.ID != existing.ID {
+		t.Errorf("Expected linked user %d, got %v, %v", existing.ID, user, err)
+	}
+	var identity models.Identity
+	db.Where("subject = ?", "sub-1").First(&identity)
+	if identity.Email != "jane@new.example" || identity.LastLoginAt == nil {
+		t.Errorf("Expected identity to be updated, got %+v", identity)
+	}
+
+	if _, err := store.SignIn(ctx, testClaims("sub-2", "bob@example.com", true)); !errors.Is(err, ErrRegistrationDisabled) {
+		t.Errorf("Expected ErrRegistrationDisabled, got %v", err)
+	}
+
+	db.Model(existing).Update("active", false)
+	if _, err := store.SignIn(ctx, testClaims("sub-1", "jane@example.com", true)); !errors.Is(err, auth.ErrInactiveUser) {
+		t.Errorf("Expected ErrInactiveUser, got %v", err)
+	}
+}
+
+func TestSignInRefusesUnverifiedAccounts(t *testing.T) {
+	store, db := setupTestStore(t, true)
+
+	// Registered by someone who never proved they own the address
+	squatter := &models.User{Email: "jane@example.com", Username: "squatter", PasswordHash: "hash", Active: true}
+	if err := db.Create(squatter).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if _, err := store.SignIn(context.Background(), testClaims("sub-1", "jane@example.com", true)); !errors.Is(err, ErrAccountNotVerified) {
+		t.Errorf("Expected ErrAccountNotVerified, got %v", err)
+	}
+	var reloaded models.User
+	db.First(&reloaded, squatter.ID)
+	if reloaded.EmailVerified {
+		t.Error("Expected the account to stay unverified")
+	}
+	var count int64
+	db.Model(&models.Identity{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected no identity to be linked, got %d", count)
+	}
+}
+
+func TestSignInAutoRegisters(t *testing.T) {
+	ctx := context.Background()
+	store, db := setupTestStore(t, true)
+
+	if err := db.Create(&models.User{Email: "other@example.com", Username: "jane", PasswordHash: "hash"}).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	user, err := store.SignIn(ctx, testClaims("sub-1", "jane@example.com", true))
+	if err != nil {
+		t.Fatalf("SignIn() error = %v", err)
+	}
+	if user.Username != "jane2" {
+		t.Errorf("Expected unique username jane2, got %q", user.Username)
+	}
+	if user.FirstName != "Jane" || user.LastName != "Doe" || !user.EmailVerified || user.PasswordHash != auth.NoPassword {
+		t.Errorf("Unexpected user %+v", user)
+	}
+
+	var count int64
+	db.Model(&models.Identity{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected 1 identity, got %d", count)
+	}
+}
+
+func TestSignInRefusesServiceAccounts(t *testing.T) {
+	store, db := setupTestStore(t, true)
+
+	if err := db.Create(&models.User{Email: "ci@example.com", Username: "ci", PasswordHash: auth.NoPassword, ServiceAccount: true}).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if _, err := store.SignIn(context.Background(), testClaims("sub-1", "ci@example.com", true)); !errors.Is(err, ErrRegistrationDisabled) {
+		t.Errorf("Expected ErrRegistrationDisabled, got %v", err)
+	}
+}
+
+func TestFlexBool(t *testing.T) {
+	tests := map[string]bool{`{"email_verified":true}`: true, `{"email_verified":"true"}`: true, `{"email_verified":"false"}`: false, `{}`: false}
+	for input, want := range tests {
+		var claims Claims
+		if err := json.Unmarshal([]byte(input), &claims); err != nil {
+			t.Fatalf("Unmarshal(%s) error = %v", input, err)
+		}
+		if bool(claims.EmailVerified) != want {
+			t.Errorf("Unmarshal(%s) = %v, want %v", input, claims.EmailVerified, want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package posts
+
+import (
+	"goapp/internal/markdown"
+	"goapp/internal/models"
+)
+
+// SummaryLength is how many characters of their content summarize posts
+// saved without a summary
+const SummaryLength = 200
+
+// render caches the HTML of post's content, unless it is unchanged since
+// previous, nil for new posts, and summarizes posts without a summary
+func render(post, previous *models.Post) {
+	if previous == nil || post.Content != previous.Content || post.ContentHTML == "" {
+		post.ContentHTML = markdown.Render(post.Content)
+	}
+	if post.Summary == "" {
+		post.Summary = markdown.Summary(post.Content, SummaryLength)
+	}
+}
+
+// ContentHTML returns the rendered content of post, rendering it when the
+// post was saved before the HTML was cached or without this package
+func ContentHTML(post *models.Post) string {
+	if post.ContentHTML == "" && post.Content != "" {
+		return markdown.Render(post.Content)
+	}
+	return post.ContentHTML
+}
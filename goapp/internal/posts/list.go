@@ -0,0 +1,285 @@
+package posts
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"goapp/internal/auth"
+	"goapp/internal/models"
+	"goapp/internal/query"
+	"gorm.io/gorm"
+)
+
+// Page size limits for List
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// ErrInvalidCursor is returned for cursors that are malformed or were
+// issued for a different sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// sortColumns maps the sort keys accepted by List to their columns
+var sortColumns = map[string]string{
+	"created_at": "posts.created_at",
+	"updated_at": "posts.updated_at",
+	"title":      "posts.title",
+	"view_count": "posts.view_count",
+}
+
+// DefaultSort lists the newest posts first, matching idx_posts_published_created_at
+const DefaultSort = "-created_at"
+
+// Schema declares the filter[...], sort and page parameters List accepts
+var Schema = query.Schema{
+	Filters: map[string]query.Field{
+		"title":        {Column: "posts.title", Kind: query.String},
+		"slug":         {Column: "posts.slug", Kind: query.String},
+		"status":       {Column: "posts.status", Kind: query.String, Values: Statuses()},
+		"published":    {Column: "posts.published", Kind: query.Bool},
+		"user_id":      {Column: "posts.user_id", Kind: query.Int},
+		"view_count":   {Column: "posts.view_count", Kind: query.Int},
+		"created_at":   {Column: "posts.created_at", Kind: query.Time},
+		"updated_at":   {Column: "posts.updated_at", Kind: query.Time},
+		"published_at": {Column: "posts.published_at", Kind: query.Time},
+	},
+	Sorts:       sortColumns,
+	DefaultSort: DefaultSort,
+	TieBreaker:  "posts.id",
+	DefaultSize: DefaultLimit,
+	MaxSize:     MaxLimit,
+	Aliases: map[string]string{
+		"published": "filter[published]",
+		"status":    "filter[status]",
+		"from":      "filter[created_at][gte]",
+		"to":        "filter[created_at][lt]",
+		"limit":     "page[size]",
+		"offset":    "page[offset]",
+	},
+}
+
+// ListOptions selects, orders and paginates posts. Zero values mean no filter.
+type ListOptions struct {
+	Published      *bool
+	Status         string
+	AuthorID       uint
+	AuthorUsername string
+	Tag            string // tag slug
+	From           time.Time
+	To             time.Time // exclusive
+
+	// Filter adds the conditions of a request parsed with Schema
+	Filter *query.Query
+
+	// Visibility: drafts are listed only when IncludeDrafts is set, or when
+	// written by ViewerID
+	IncludeDrafts bool
+	ViewerID      uint
+
+	// Sort is a comma separated list of keys of sortColumns, each prefixed
+	// with "-" for descending order; ties are broken by ID in the direction
+	// of the last key. Cursors are only issued for a single key.
+	Sort string
+
+	// Limit caps the page size at MaxLimit. Cursor, when set, continues
+	// after the last post of a previous page and takes precedence over Offset.
+	Limit  int
+	Offset int
+	Cursor string
+}
+
+// Page is one page of List results
+type Page struct {
+	Posts []models.Post
+	// Total counts all matching posts; it is only computed for offset pagination
+	Total *int64
+	// NextCursor continues after the last post, or is empty on the last page
+	NextCursor string
+}
+
+// cursor is the position after the last post of a page, valid only for the same sort
+type cursor struct {
+	Sort  string          `json:"s"`
+	Value json.RawMessage `json:"v"`
+	ID    uint            `json:"id"`
+}
+
+// List implements Service
+func (s *service) List(ctx context.Context, opts ListOptions) (*Page, error) {
+	if opts.Sort == "" {
+		opts.Sort = DefaultSort
+	}
+	sorted, err := parseSort(opts.Sort)
+	if err != nil {
+		return nil, err
+	}
+	// Cursors seek on the first key, so they only follow a single key
+	first := sorted.Orders[0]
+	seekable := len(sorted.Orders) == 1
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	query := s.filter(s.db.WithContext(ctx).Model(&models.Post{}), opts)
+
+	page := &Page{}
+	if opts.Cursor == "" {
+		var total int64
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, fmt.Errorf("failed to count posts: %w", err)
+		}
+		page.Total = &total
+		query = query.Offset(opts.Offset)
+	} else {
+		after, err := decodeCursor(opts.Cursor, opts.Sort)
+		if err != nil || !seekable {
+			return nil, ErrInvalidCursor
+		}
+		if query, err = seek(query, first, after); err != nil {
+			return nil, err
+		}
+	}
+
+	err = query.Preload("User").Preload("Tags").Scopes(sorted.Order).
+		Limit(limit + 1).Find(&page.Posts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list posts: %w", err)
+	}
+
+	if len(page.Posts) > limit {
+		page.Posts = page.Posts[:limit]
+		last := page.Posts[limit-1]
+		if seekable {
+			if page.NextCursor, err = encodeCursor(opts.Sort, first.Field, &last); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return page, nil
+}
+
+// filter applies the filters and visibility of opts
+func (s *service) filter(query *gorm.DB, opts ListOptions) *gorm.DB {
+	if opts.Published != nil {
+		query = query.Where("posts.published = ?", *opts.Published)
+	}
+	if opts.Status != "" {
+		query = query.Where("posts.status = ?", opts.Status)
+	}
+	if !opts.IncludeDrafts {
+		if opts.ViewerID != 0 {
+			query = query.Where("(posts.published = ? OR posts.user_id = ?)", true, opts.ViewerID)
+		} else {
+			query = query.Where("posts.published = ?", true)
+		}
+	}
+	if opts.AuthorID != 0 {
+		query = query.Where("posts.user_id = ?", opts.AuthorID)
+	}
+	if opts.AuthorUsername != "" {
+		query = query.Where("posts.user_id IN (?)", s.db.Model(&models.User{}).Select("id").Where("username = ?", opts.AuthorUsername))
+	}
+	if opts.Tag != "" {
+		query = query.Where("posts.id IN (?)", s.db.Table("post_tags").
+			Select("post_tags.post_id").
+			Joins("JOIN tags ON tags.id = post_tags.tag_id AND tags.deleted_at IS NULL").
+			Where("tags.slug = ?", opts.Tag))
+	}
+	if !opts.From.IsZero() {
+		query = query.Where("posts.created_at >= ?", opts.From)
+	}
+	if !opts.To.IsZero() {
+		query = query.Where("posts.created_at < ?", opts.To)
+	}
+	if opts.Filter != nil {
+		query = query.Scopes(opts.Filter.Where)
+	}
+	return query
+}
+
+// seek restricts query to posts after the cursor position in order
+func seek(query *gorm.DB, order query.Order, after *cursor) (*gorm.DB, error) {
+	var value interface{}
+	var err error
+	switch order.Field {
+	case "created_at", "updated_at":
+		var t time.Time
+		err = json.Unmarshal(after.Value, &t)
+		value = t
+	case "title":
+		var title string
+		err = json.Unmarshal(after.Value, &title)
+		value = title
+	case "view_count":
+		var count uint
+		err = json.Unmarshal(after.Value, &count)
+		value = count
+	}
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	op := ">"
+	if order.Desc {
+		op = "<"
+	}
+	return query.Where(fmt.Sprintf("(%s %s ? OR (%s = ? AND posts.id %s ?))", order.Column, op, order.Column, op), value, value, after.ID), nil
+}
+
+// parseSort parses a sort parameter with Schema; the result's Order scope
+// applies it
+func parseSort(sort string) (*query.Query, error) {
+	sorted, err := Schema.Parse(url.Values{"sort": {sort}})
+	if err != nil {
+		return nil, err
+	}
+	if len(sorted.Orders) == 0 {
+		return nil, &auth.ValidationError{Fields: map[string]string{"sort": "is empty"}}
+	}
+	return sorted, nil
+}
+
+func encodeCursor(sort, sortKey string, post *models.Post) (string, error) {
+	var value interface{}
+	switch sortKey {
+	case "created_at":
+		value = post.CreatedAt
+	case "updated_at":
+		value = post.UpdatedAt
+	case "title":
+		value = post.Title
+	case "view_count":
+		value = post.ViewCount
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(cursor{Sort: sort, Value: raw, ID: post.ID})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeCursor(s, sort string) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Sort != sort || c.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
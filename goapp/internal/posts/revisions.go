@@ -0,0 +1,82 @@
+package posts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"goapp/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrRevisionNotFound is returned when a post has no revision with the requested number
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// Revisions implements Service
+func (s *service) Revisions(ctx context.Context, post *models.Post) ([]models.PostRevision, error) {
+	var revisions []models.PostRevision
+	err := s.db.WithContext(ctx).Preload("User").Where("post_id = ?", post.ID).Order("number DESC").Find(&revisions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// Revision implements Service
+func (s *service) Revision(ctx context.Context, post *models.Post, number int) (*models.PostRevision, error) {
+	var revision models.PostRevision
+	err := s.db.WithContext(ctx).Preload("User").Where("post_id = ? AND number = ?", post.ID, number).First(&revision).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load revision: %w", err)
+	}
+	return &revision, nil
+}
+
+// Restore implements Service. The slug and status are left alone, so
+// restoring never breaks links or changes what readers see.
+func (s *service) Restore(ctx context.Context, post *models.Post, number int, editorID uint) error {
+	revision, err := s.Revision(ctx, post, number)
+	if err != nil {
+		return err
+	}
+	return s.Update(ctx, post, UpdateInput{
+		Title:    &revision.Title,
+		Summary:  &revision.Summary,
+		Content:  &revision.Content,
+		EditorID: editorID,
+	})
+}
+
+// revised reports whether an update changed any field kept in revisions
+func revised(before, after *models.Post) bool {
+	return before.Title != after.Title || before.Slug != after.Slug || before.Summary != after.Summary ||
+		before.Content != after.Content || before.Status != after.Status
+}
+
+// saveRevision records the current fields of post as its next revision
+func saveRevision(tx *gorm.DB, post *models.Post, editorID uint) error {
+	var last int
+	err := tx.Model(&models.PostRevision{}).Where("post_id = ?", post.ID).Select("COALESCE(MAX(number), 0)").Scan(&last).Error
+	if err != nil {
+		return fmt.Errorf("failed to number revision: %w", err)
+	}
+	revision := &models.PostRevision{
+		PostID:  post.ID,
+		Number:  last + 1,
+		Title:   post.Title,
+		Slug:    post.Slug,
+		Summary: post.Summary,
+		Content: post.Content,
+		Status:  post.Status,
+	}
+	if editorID != 0 {
+		revision.UserID = &editorID
+	}
+	if err := tx.Create(revision).Error; err != nil {
+		return fmt.Errorf("failed to save revision: %w", err)
+	}
+	return nil
+}
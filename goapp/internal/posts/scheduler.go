@@ -0,0 +1,69 @@
+package posts
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"goapp/internal/logging"
+	"goapp/internal/models"
+	"gorm.io/gorm"
+)
+
+// PublishDue implements Service. It is a single UPDATE, so several
+// instances may run schedulers at once. Posts are dated by their schedule
+// rather than by when the scheduler noticed them.
+func (s *service) PublishDue(ctx context.Context, now time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).Model(&models.Post{}).
+		Where("status = ? AND scheduled_at <= ?", models.PostScheduled, now).
+		Updates(map[string]interface{}{
+			"status":       models.PostPublished,
+			"published":    true,
+			"published_at": gorm.Expr("COALESCE(published_at, scheduled_at)"),
+			"scheduled_at": nil,
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to publish scheduled posts: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// Scheduler publishes scheduled posts when they are due
+type Scheduler struct {
+	posts    Service
+	interval time.Duration
+	logger   logging.Logger
+}
+
+// NewScheduler creates a Scheduler checking for due posts every interval
+func NewScheduler(service Service, interval time.Duration, logger logging.Logger) *Scheduler {
+	return &Scheduler{posts: service, interval: interval, logger: logger}
+}
+
+// Run publishes due posts at once and then every interval until ctx is done
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.tick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context) {
+	published, err := s.posts.PublishDue(ctx, time.Now())
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Error("Failed to publish scheduled posts", zap.Error(err))
+		}
+		return
+	}
+	if published > 0 {
+		s.logger.Info("Scheduled posts published", zap.Int64("count", published))
+	}
+}
@@ -0,0 +1,321 @@
+// Package posts stores blog posts and lists them with filters, sorting and
+// offset or cursor pagination for the JSON API and the web pages. Posts move
+// through a publishing workflow, and every edit is kept as a revision.
+package posts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"goapp/internal/auth"
+	"goapp/internal/models"
+	"goapp/internal/slugs"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when no post has the requested ID or slug
+var ErrNotFound = errors.New("post not found")
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// resourceType names posts in slug history
+const resourceType = "posts"
+
+// maxSlugAttempts bounds how often a save is retried when another post
+// takes its generated slug first
+const maxSlugAttempts = 5
+
+// CreateInput holds the fields of a new post
+type CreateInput struct {
+	Title       string
+	Slug        string // derived from Title when empty, with a suffix such as "-2" when taken
+	Content     string
+	Summary     string
+	Published   bool
+	Status      string     // one of Statuses(); when empty, draft or, with Published, published
+	ScheduledAt *time.Time // required for scheduled posts
+	AuthorID    uint
+}
+
+// UpdateInput holds the fields to change; nil fields are left alone
+type UpdateInput struct {
+	Title       *string
+	Slug        *string // derived from Title again when empty; the old slug redirects
+	Content     *string
+	Summary     *string
+	Published   *bool      // moves the post to published or draft; ignored when Status is set
+	Status      *string    // must be allowed by CanTransition
+	ScheduledAt *time.Time // required to schedule a post; reschedules a scheduled post
+	EditorID    uint       // recorded in the revision; zero when unknown
+}
+
+// StatusFor returns the status input moves post to
+func (input UpdateInput) StatusFor(post *models.Post) string {
+	switch {
+	case input.Status != nil:
+		return strings.TrimSpace(*input.Status)
+	case input.Published != nil && *input.Published != post.Published:
+		if *input.Published {
+			return models.PostPublished
+		}
+		return models.PostDraft
+	}
+	return post.Status
+}
+
+// Service creates, finds, lists, updates and deletes posts
+type Service interface {
+	// List returns a page of posts with their author and tags loaded
+	List(ctx context.Context, opts ListOptions) (*Page, error)
+	// Get returns the post with id, with its author and tags loaded
+	Get(ctx context.Context, id uint) (*models.Post, error)
+	// GetBySlug returns the post with slug, with its author and tags loaded
+	GetBySlug(ctx context.Context, slug string) (*models.Post, error)
+	// GetByOldSlug returns the post that used to have slug, with its author
+	// and tags loaded, or ErrNotFound
+	GetByOldSlug(ctx context.Context, slug string) (*models.Post, error)
+	// Create validates input and stores a new post with its content rendered
+	// and, when input has none, a summary of its content
+	Create(ctx context.Context, input CreateInput) (*models.Post, error)
+	// Update validates and applies input to post, rendering changed content
+	// and summarizing it again when the summary is cleared
+	Update(ctx context.Context, post *models.Post, input UpdateInput) error
+	// Delete soft-deletes post
+	Delete(ctx context.Context, post *models.Post) error
+	// Revisions returns the revisions of post, newest first, with their editors loaded
+	Revisions(ctx context.Context, post *models.Post) ([]models.PostRevision, error)
+	// Revision returns revision number of post, with its editor loaded
+	Revision(ctx context.Context, post *models.Post, number int) (*models.PostRevision, error)
+	// Restore copies the title, summary and content of revision number back
+	// into post, which records a new revision
+	Restore(ctx context.Context, post *models.Post, number int, editorID uint) error
+	// PublishDue publishes the scheduled posts that are due at now and
+	// returns how many it published
+	PublishDue(ctx context.Context, now time.Time) (int64, error)
+}
+
+// service implements Service on the posts table
+type service struct {
+	db *gorm.DB
+}
+
+// NewService creates a posts Service
+func NewService(db *gorm.DB) Service {
+	return &service{db: db}
+}
+
+// Get implements Service
+func (s *service) Get(ctx context.Context, id uint) (*models.Post, error) {
+	return s.find(ctx, "posts.id = ?", id)
+}
+
+// GetBySlug implements Service
+func (s *service) GetBySlug(ctx context.Context, slug string) (*models.Post, error) {
+	return s.find(ctx, "posts.slug = ?", slug)
+}
+
+// GetByOldSlug implements Service
+func (s *service) GetByOldSlug(ctx context.Context, slug string) (*models.Post, error) {
+	id, err := slugs.Resolve(s.db.WithContext(ctx), resourceType, slug)
+	if errors.Is(err, slugs.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, id)
+}
+
+func (s *service) find(ctx context.Context, query string, arg interface{}) (*models.Post, error) {
+	var post models.Post
+	err := s.db.WithContext(ctx).Preload("User").Preload("Tags").Where(query, arg).First(&post).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load post: %w", err)
+	}
+	return &post, nil
+}
+
+// Create implements Service
+func (s *service) Create(ctx context.Context, input CreateInput) (*models.Post, error) {
+	post := &models.Post{
+		Title:   strings.TrimSpace(input.Title),
+		Slug:    strings.TrimSpace(input.Slug),
+		Content: input.Content,
+		Summary: strings.TrimSpace(input.Summary),
+		UserID:  input.AuthorID,
+	}
+	render(post, nil)
+	status := strings.TrimSpace(input.Status)
+	if status == "" {
+		status = models.PostDraft
+		if input.Published {
+			status = models.PostPublished
+		}
+	}
+	now := time.Now()
+	applyStatus(post, status, input.ScheduledAt, now)
+	fields := checkStatus("", post, true, now)
+
+	generated := post.Slug == ""
+	err := s.save(ctx, generated, func(tx *gorm.DB) error {
+		if generated {
+			slug, err := slugs.Unique(tx, resourceType, baseSlug(post.Title), 0)
+			if err != nil {
+				return err
+			}
+			post.Slug = slug
+		}
+		if err := validate(tx, post, fields); err != nil {
+			return err
+		}
+		if err := tx.Create(post).Error; err != nil {
+			return fmt.Errorf("failed to create post: %w", err)
+		}
+		return saveRevision(tx, post, input.AuthorID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, post.ID)
+}
+
+// Update implements Service
+func (s *service) Update(ctx context.Context, post *models.Post, input UpdateInput) error {
+	updated := *post
+	if input.Title != nil {
+		updated.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.Slug != nil {
+		updated.Slug = strings.TrimSpace(*input.Slug)
+	}
+	if input.Content != nil {
+		updated.Content = *input.Content
+	}
+	if input.Summary != nil {
+		updated.Summary = strings.TrimSpace(*input.Summary)
+	}
+	render(&updated, post)
+	status := input.StatusFor(post)
+	scheduledAt := post.ScheduledAt
+	if input.ScheduledAt != nil {
+		scheduledAt = input.ScheduledAt
+	}
+	now := time.Now()
+	applyStatus(&updated, status, scheduledAt, now)
+	rescheduled := input.ScheduledAt != nil || status != post.Status
+	fields := checkStatus(post.Status, &updated, rescheduled, now)
+
+	generated := updated.Slug == ""
+	err := s.save(ctx, generated, func(tx *gorm.DB) error {
+		if generated {
+			slug, err := slugs.Unique(tx, resourceType, baseSlug(updated.Title), post.ID)
+			if err != nil {
+				return err
+			}
+			updated.Slug = slug
+		}
+		if err := validate(tx, &updated, fields); err != nil {
+			return err
+		}
+		err := tx.Model(&updated).
+			Select("title", "slug", "content", "content_html", "summary", "status", "published", "published_at", "scheduled_at").
+			Updates(&updated).Error
+		if err != nil {
+			return fmt.Errorf("failed to update post: %w", err)
+		}
+		if err := slugs.Move(tx, resourceType, post.ID, post.Slug, updated.Slug); err != nil {
+			return err
+		}
+		if !revised(post, &updated) {
+			return nil
+		}
+		return saveRevision(tx, &updated, input.EditorID)
+	})
+	if err != nil {
+		return err
+	}
+	*post = updated
+	return nil
+}
+
+// Delete implements Service
+func (s *service) Delete(ctx context.Context, post *models.Post) error {
+	if err := s.db.WithContext(ctx).Delete(post).Error; err != nil {
+		return fmt.Errorf("failed to delete post: %w", err)
+	}
+	return nil
+}
+
+// validate checks post's fields, including that no other post has or had
+// its slug, and reports them along with fields, the errors found by checkStatus
+func validate(tx *gorm.DB, post *models.Post, fields map[string]string) error {
+	if post.Title == "" {
+		fields["title"] = "is required"
+	} else if utf8.RuneCountInString(post.Title) > 255 {
+		fields["title"] = "must be at most 255 characters"
+	}
+	switch {
+	case post.Slug == "":
+		fields["slug"] = "is required"
+	case len(post.Slug) > slugs.MaxLength || !slugPattern.MatchString(post.Slug):
+		fields["slug"] = "must be lowercase letters, digits and single dashes"
+	case strings.Trim(post.Slug, "0123456789") == "":
+		// Numeric slugs would be mistaken for IDs in /posts/{id or slug}
+		fields["slug"] = "must contain a letter"
+	case slugs.IsReserved(post.Slug):
+		fields["slug"] = "is reserved"
+	}
+
+	if _, ok := fields["slug"]; !ok {
+		// Soft-deleted posts keep their slug in the unique index
+		taken, err := slugs.Taken(tx, resourceType, post.Slug, post.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			fields["slug"] = "is already taken"
+		}
+	}
+
+	if len(fields) > 0 {
+		return &auth.ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// baseSlug makes the slug of a post from its title; a slug with no letter
+// gets a "post" prefix so that it is not taken for an ID
+func baseSlug(title string) string {
+	slug := slugs.Make(title)
+	if strings.Trim(slug, "0123456789-") == "" {
+		return strings.TrimRight("post-"+slug, "-")
+	}
+	return slug
+}
+
+// save runs fn in a transaction. A slug another post took since it was
+// checked is reported as a validation error when the client chose it; a
+// generated slug is not the client's fault, so fn runs again and generates
+// the next free one.
+func (s *service) save(ctx context.Context, generatedSlug bool, fn func(tx *gorm.DB) error) error {
+	for attempt := 1; ; attempt++ {
+		err := s.db.WithContext(ctx).Transaction(fn)
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return err
+		}
+		if !generatedSlug {
+			return &auth.ValidationError{Fields: map[string]string{"slug": "is already taken"}}
+		}
+		if attempt == maxSlugAttempts {
+			return err
+		}
+	}
+}
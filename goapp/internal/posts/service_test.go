@@ -0,0 +1,516 @@
+package posts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"goapp/internal/auth"
+	"goapp/internal/config"
+	"goapp/internal/logging"
+	"goapp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestService(t *testing.T) (Service, *gorm.DB, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Post{}, &models.PostRevision{}, &models.Tag{}, &models.SlugRedirect{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	user := &models.User{Email: "jane@example.com", Username: "jane", PasswordHash: "hash", Active: true}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	return NewService(db), db, user
+}
+
+// seedPosts creates n posts an hour apart; post i is published when i is even
+func seedPosts(t *testing.T, db *gorm.DB, authorID uint, n int) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		post := &models.Post{
+			BaseModel: models.BaseModel{CreatedAt: start.Add(time.Duration(i) * time.Hour)},
+			Title:     fmt.Sprintf("Post %02d", i),
+			Slug:      fmt.Sprintf("post-%02d", i),
+			Published: i%2 == 0,
+			ViewCount: uint(i % 3),
+			UserID:    authorID,
+		}
+		if err := db.Create(post).Error; err != nil {
+			t.Fatalf("Failed to create post: %v", err)
+		}
+	}
+}
+
+func TestCreateUpdateDelete(t *testing.T) {
+	ctx := context.Background()
+	s, _, user := setupTestService(t)
+
+	post, err := s.Create(ctx, CreateInput{Title: "  Hello, World! ", Content: "Body", AuthorID: user.ID})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if post.Title != "Hello, World!" || post.Slug != "hello-world" || post.User.Username != "jane" {
+		t.Errorf("Unexpected post: %+v", post)
+	}
+
+	second, err := s.Create(ctx, CreateInput{Title: "Hello world", AuthorID: user.ID})
+	if err != nil || second.Slug != "hello-world-2" {
+		t.Errorf("Expected a suffixed slug for a taken title, got %+v, %v", second, err)
+	}
+	var validationErr *auth.ValidationError
+	_, err = s.Create(ctx, CreateInput{Title: "Hello world", Slug: "hello-world", AuthorID: user.ID})
+	if !errors.As(err, &validationErr) || validationErr.Fields["slug"] != "is already taken" {
+		t.Errorf("Expected duplicate slug to be rejected, got %v", err)
+	}
+	_, err = s.Create(ctx, CreateInput{Title: "Reserved", Slug: "new", AuthorID: user.ID})
+	if !errors.As(err, &validationErr) || validationErr.Fields["slug"] != "is reserved" {
+		t.Errorf("Expected reserved slug to be rejected, got %v", err)
+	}
+	_, err = s.Create(ctx, CreateInput{Title: "", Slug: "2024", AuthorID: user.ID})
+	if !errors.As(err, &validationErr) || validationErr.Fields["title"] == "" || validationErr.Fields["slug"] != "must contain a letter" {
+		t.Errorf("Expected title and numeric slug to be rejected, got %v", err)
+	}
+
+	title, published := "Updated", true
+	if err := s.Update(ctx, post, UpdateInput{Title: &title, Published: &published}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	got, err := s.GetBySlug(ctx, "hello-world")
+	if err != nil || got.Title != "Updated" || !got.Published || got.Content != "Body" {
+		t.Errorf("Expected title and published to change only, got %+v, %v", got, err)
+	}
+
+	if err := s.Delete(ctx, post); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.Get(ctx, post.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestListFilters(t *testing.T) {
+	ctx := context.Background()
+	s, db, user := setupTestService(t)
+	seedPosts(t, db, user.ID, 6)
+
+	other := &models.User{Email: "john@example.com", Username: "john", PasswordHash: "hash", Active: true}
+	db.Create(other)
+	tagged := &models.Post{Title: "Tagged", Slug: "tagged", Published: true, UserID: other.ID, Tags: []models.Tag{{Name: "Go", Slug: "go"}}}
+	db.Create(tagged)
+
+	published := true
+	tests := []struct {
+		name string
+		opts ListOptions
+		want int
+	}{
+		{"Anonymous", ListOptions{}, 4},
+		{"OwnDrafts", ListOptions{ViewerID: user.ID}, 7},
+		{"OthersDrafts", ListOptions{ViewerID: other.ID}, 4},
+		{"AllDrafts", ListOptions{IncludeDrafts: true}, 7},
+		{"Published", ListOptions{IncludeDrafts: true, Published: &published}, 4},
+		{"Author", ListOptions{IncludeDrafts: true, AuthorID: other.ID}, 1},
+		{"AuthorUsername", ListOptions{IncludeDrafts: true, AuthorUsername: "jane"}, 6},
+		{"Tag", ListOptions{Tag: "go"}, 1},
+		{"DateRange", ListOptions{IncludeDrafts: true, From: time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC), To: time.Date(2024, 1, 1, 4, 0, 0, 0, time.UTC)}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := s.List(ctx, tt.opts)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(page.Posts) != tt.want || page.Total == nil || *page.Total != int64(tt.want) {
+				t.Errorf("Expected %d posts, got %d", tt.want, len(page.Posts))
+			}
+		})
+	}
+
+	var validationErr *auth.ValidationError
+	if _, err := s.List(ctx, ListOptions{Sort: "password"}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected validation error for unknown sort, got %v", err)
+	}
+}
+
+func TestListPagination(t *testing.T) {
+	ctx := context.Background()
+	s, db, user := setupTestService(t)
+	seedPosts(t, db, user.ID, 7)
+
+	t.Run("Offset", func(t *testing.T) {
+		page, err := s.List(ctx, ListOptions{IncludeDrafts: true, Limit: 3, Offset: 3})
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		if *page.Total != 7 || len(page.Posts) != 3 || page.Posts[0].Slug != "post-03" {
+			t.Errorf("Expected posts 03-01 of 7, got %d posts starting %q of %d", len(page.Posts), page.Posts[0].Slug, *page.Total)
+		}
+	})
+
+	for _, sort := range []string{"-created_at", "title", "-view_count"} {
+		t.Run("Cursor"+sort, func(t *testing.T) {
+			all, err := s.List(ctx, ListOptions{IncludeDrafts: true, Sort: sort, Limit: 10})
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+
+			var walked []string
+			opts := ListOptions{IncludeDrafts: true, Sort: sort, Limit: 2}
+			for i := 0; i < 10; i++ {
+				page, err := s.List(ctx, opts)
+				if err != nil {
+					t.Fatalf("List() error = %v", err)
+				}
+				for _, post := range page.Posts {
+					walked = append(walked, post.Slug)
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				opts.Cursor = page.NextCursor
+			}
+
+			if len(walked) != len(all.Posts) {
+				t.Fatalf("Expected %d posts across pages, got %v", len(all.Posts), walked)
+			}
+			for i := range walked {
+				if walked[i] != all.Posts[i].Slug {
+					t.Errorf("Expected %q at %d, got %q", all.Posts[i].Slug, i, walked[i])
+				}
+			}
+		})
+	}
+
+	t.Run("MultipleKeys", func(t *testing.T) {
+		page, err := s.List(ctx, ListOptions{IncludeDrafts: true, Sort: "-view_count,title", Limit: 7})
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		var slugs []string
+		for _, post := range page.Posts {
+			slugs = append(slugs, post.Slug)
+		}
+		if got := strings.Join(slugs, " "); got != "post-02 post-05 post-01 post-04 post-00 post-03 post-06" {
+			t.Errorf("Expected posts by views then title, got %s", got)
+		}
+
+		// Cursors seek on one key, so offsets page through several
+		page, _ = s.List(ctx, ListOptions{IncludeDrafts: true, Sort: "-view_count,title", Limit: 2})
+		if page.NextCursor != "" || *page.Total != 7 {
+			t.Errorf("Expected offset pagination only, got cursor %q", page.NextCursor)
+		}
+		next, _ := s.List(ctx, ListOptions{IncludeDrafts: true, Sort: "-view_count", Limit: 2})
+		if _, err := s.List(ctx, ListOptions{IncludeDrafts: true, Sort: "-view_count,title", Cursor: next.NextCursor}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Expected ErrInvalidCursor with several keys, got %v", err)
+		}
+	})
+
+	page, _ := s.List(ctx, ListOptions{IncludeDrafts: true, Limit: 2})
+	if _, err := s.List(ctx, ListOptions{IncludeDrafts: true, Sort: "title", Cursor: page.NextCursor}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor for a cursor of another sort, got %v", err)
+	}
+	if _, err := s.List(ctx, ListOptions{Cursor: "not a cursor"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestSlugHistory(t *testing.T) {
+	ctx := context.Background()
+	s, _, user := setupTestService(t)
+
+	post, err := s.Create(ctx, CreateInput{Title: "First", AuthorID: user.ID})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	slug := "second"
+	if err := s.Update(ctx, post, UpdateInput{Slug: &slug}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	got, err := s.GetByOldSlug(ctx, "first")
+	if err != nil || got.ID != post.ID {
+		t.Errorf("Expected old slug to find the post, got %+v, %v", got, err)
+	}
+
+	// Old slugs stay with their post
+	other, err := s.Create(ctx, CreateInput{Title: "First", AuthorID: user.ID})
+	if err != nil || other.Slug != "first-2" {
+		t.Errorf("Expected old slug to be skipped, got %+v, %v", other, err)
+	}
+
+	// Moving back to an old slug stops it redirecting
+	slug = "first"
+	if err := s.Update(ctx, post, UpdateInput{Slug: &slug}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := s.GetByOldSlug(ctx, "first"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected current slug not to redirect, got %v", err)
+	}
+	if got, err := s.GetByOldSlug(ctx, "second"); err != nil || got.ID != post.ID {
+		t.Errorf("Expected second slug to redirect, got %+v, %v", got, err)
+	}
+
+	// Titles without letters get a prefix
+	numeric, err := s.Create(ctx, CreateInput{Title: "2024", AuthorID: user.ID})
+	if err != nil || numeric.Slug != "post-2024" {
+		t.Errorf("Expected a prefixed slug, got %+v, %v", numeric, err)
+	}
+}
+
+func TestCreate_SlugRace(t *testing.T) {
+	ctx := context.Background()
+	s, db, user := setupTestService(t)
+
+	// Fail post inserts as if a concurrent Create had just committed the same slug
+	races, raced := 1, 0
+	err := db.Callback().Create().Before("gorm:create").Register("test:slug_race", func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Dest.(*models.Post); ok && raced < races {
+			raced++
+			_ = tx.AddError(gorm.ErrDuplicatedKey)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	post, err := s.Create(ctx, CreateInput{Title: "Race", AuthorID: user.ID})
+	if err != nil || post.Slug != "race" || raced != 1 {
+		t.Fatalf("Expected a generated slug to be retried, got %+v, %v after %d races", post, err, raced)
+	}
+
+	raced = 0
+	_, err = s.Create(ctx, CreateInput{Title: "Race", Slug: "chosen", AuthorID: user.ID})
+	var validationErr *auth.ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Fields["slug"] != "is already taken" {
+		t.Errorf("Expected a chosen slug to be reported as taken, got %v", err)
+	}
+
+	races, raced = maxSlugAttempts+1, 0
+	if _, err := s.Create(ctx, CreateInput{Title: "Race", AuthorID: user.ID}); !errors.Is(err, gorm.ErrDuplicatedKey) || raced != maxSlugAttempts {
+		t.Errorf("Expected to give up after %d attempts, got %v after %d", maxSlugAttempts, err, raced)
+	}
+}
+
+func TestRenderContent(t *testing.T) {
+	ctx := context.Background()
+	s, _, user := setupTestService(t)
+
+	post, err := s.Create(ctx, CreateInput{Title: "Markdown", Content: "# Intro\n\nSome **bold** text.", AuthorID: user.ID})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !strings.Contains(post.ContentHTML, "<strong>bold</strong>") || post.Summary != "Some bold text." {
+		t.Errorf("Expected rendered content and a summary, got %q, %q", post.ContentHTML, post.Summary)
+	}
+
+	content, summary := "Changed\n\n<script>alert(1)</script>", "Kept"
+	if err := s.Update(ctx, post, UpdateInput{Content: &content, Summary: &summary}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	got, _ := s.Get(ctx, post.ID)
+	if !strings.Contains(got.ContentHTML, "<p>Changed") || strings.Contains(got.ContentHTML, "script") || got.Summary != "Kept" {
+		t.Errorf("Expected the cached HTML to be replaced and sanitized, got %q, %q", got.ContentHTML, got.Summary)
+	}
+
+	summary = ""
+	if err := s.Update(ctx, got, UpdateInput{Summary: &summary}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got.Summary != "Changed" {
+		t.Errorf("Expected a cleared summary to be generated again, got %q", got.Summary)
+	}
+
+	if html := ContentHTML(&models.Post{Content: "*legacy*"}); html != "<p><em>legacy</em></p>\n" {
+		t.Errorf("Expected content without cached HTML to be rendered, got %q", html)
+	}
+}
+
+func TestWorkflow(t *testing.T) {
+	ctx := context.Background()
+	s, _, user := setupTestService(t)
+
+	post, err := s.Create(ctx, CreateInput{Title: "Workflow", AuthorID: user.ID})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if post.Status != models.PostDraft || post.Published || post.PublishedAt != nil {
+		t.Errorf("Expected a new draft, got %+v", post)
+	}
+
+	status := func(s string) *string { return &s }
+	fieldErr := func(err error, field string) string {
+		var validationErr *auth.ValidationError
+		if !errors.As(err, &validationErr) {
+			return ""
+		}
+		return validationErr.Fields[field]
+	}
+
+	if err := s.Update(ctx, post, UpdateInput{Status: status(models.PostInReview)}); err != nil || post.Status != models.PostInReview {
+		t.Fatalf("Expected the post in review, got %q, %v", post.Status, err)
+	}
+	if err := s.Update(ctx, post, UpdateInput{Status: status(models.PostArchived)}); fieldErr(err, "status") != "cannot change from in_review to archived" {
+		t.Errorf("Expected the transition to be refused, got %v", err)
+	}
+	if err := s.Update(ctx, post, UpdateInput{Status: status("deleted")}); fieldErr(err, "status") == "" {
+		t.Errorf("Expected unknown statuses to be refused, got %v", err)
+	}
+	if err := s.Update(ctx, post, UpdateInput{Status: status(models.PostScheduled)}); fieldErr(err, "scheduled_at") != "is required to schedule a post" {
+		t.Errorf("Expected a schedule to be required, got %v", err)
+	}
+	past := time.Now().Add(-time.Hour)
+	if err := s.Update(ctx, post, UpdateInput{Status: status(models.PostScheduled), ScheduledAt: &past}); fieldErr(err, "scheduled_at") != "must be in the future" {
+		t.Errorf("Expected past schedules to be refused, got %v", err)
+	}
+
+	future := time.Now().Add(time.Hour)
+	if err := s.Update(ctx, post, UpdateInput{Status: status(models.PostScheduled), ScheduledAt: &future}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if post.Status != models.PostScheduled || post.Published || post.ScheduledAt == nil {
+		t.Errorf("Expected a scheduled post, got %+v", post)
+	}
+
+	published := true
+	if err := s.Update(ctx, post, UpdateInput{Published: &published}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if post.Status != models.PostPublished || !post.Published || post.PublishedAt == nil || post.ScheduledAt != nil {
+		t.Errorf("Expected published to publish the post, got %+v", post)
+	}
+	first := *post.PublishedAt
+
+	for _, next := range []string{models.PostArchived, models.PostPublished} {
+		if err := s.Update(ctx, post, UpdateInput{Status: status(next)}); err != nil {
+			t.Fatalf("Update(%s) error = %v", next, err)
+		}
+	}
+	if !post.PublishedAt.Equal(first) {
+		t.Errorf("Expected republishing to keep the first publication date %v, got %v", first, post.PublishedAt)
+	}
+
+	if _, err := s.Create(ctx, CreateInput{Title: "Later", Status: models.PostScheduled, AuthorID: user.ID}); fieldErr(err, "scheduled_at") == "" {
+		t.Errorf("Expected a schedule to be required, got %v", err)
+	}
+	if !NeedsPublish(models.PostDraft, models.PostScheduled) || NeedsPublish(models.PostInReview, models.PostDraft) || NeedsPublish(models.PostPublished, models.PostPublished) {
+		t.Error("Expected only status changes beyond draft and review to need the publish permission")
+	}
+}
+
+func TestPublishDue(t *testing.T) {
+	ctx := context.Background()
+	s, db, user := setupTestService(t)
+
+	now := time.Now()
+	due, later := now.Add(time.Minute), now.Add(time.Hour)
+	for _, input := range []CreateInput{
+		{Title: "Due", Status: models.PostScheduled, ScheduledAt: &due, AuthorID: user.ID},
+		{Title: "Later", Status: models.PostScheduled, ScheduledAt: &later, AuthorID: user.ID},
+		{Title: "Draft", AuthorID: user.ID},
+	} {
+		if _, err := s.Create(ctx, input); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	count, err := s.PublishDue(ctx, now.Add(2*time.Minute))
+	if err != nil || count != 1 {
+		t.Fatalf("PublishDue() = %d, %v", count, err)
+	}
+	post, _ := s.GetBySlug(ctx, "due")
+	if post.Status != models.PostPublished || !post.Published || post.ScheduledAt != nil || post.PublishedAt == nil || !post.PublishedAt.Equal(due) {
+		t.Errorf("Expected the due post to be published at its schedule, got %+v", post)
+	}
+	var scheduled int64
+	db.Model(&models.Post{}).Where("status = ?", models.PostScheduled).Count(&scheduled)
+	if scheduled != 1 {
+		t.Errorf("Expected the later post to stay scheduled, got %d scheduled posts", scheduled)
+	}
+
+	logger, err := logging.New(config.LoggerConfig{Environment: "test", WriteStdout: true})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	db.Model(&models.Post{}).Where("slug = ?", "later").Update("scheduled_at", now.Add(-time.Second))
+	runCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		NewScheduler(s, time.Hour, logger).Run(runCtx)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if post, _ := s.GetBySlug(ctx, "later"); post.Published {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the scheduler to publish the due post")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stop()
+	<-done
+}
+
+func TestRevisions(t *testing.T) {
+	ctx := context.Background()
+	s, _, user := setupTestService(t)
+
+	post, err := s.Create(ctx, CreateInput{Title: "Draft", Content: "one\ntwo\n", AuthorID: user.ID})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	content, title := "one\nthree\n", "Final"
+	if err := s.Update(ctx, post, UpdateInput{Title: &title, Content: &content, EditorID: user.ID}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := s.Update(ctx, post, UpdateInput{Title: &title}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	revisions, err := s.Revisions(ctx, post)
+	if err != nil {
+		t.Fatalf("Revisions() error = %v", err)
+	}
+	if len(revisions) != 2 || revisions[0].Number != 2 || revisions[0].Title != "Final" || revisions[1].Content != "one\ntwo\n" {
+		t.Fatalf("Expected one revision per change, newest first, got %+v", revisions)
+	}
+	if revisions[0].User == nil || revisions[0].User.Username != "jane" {
+		t.Errorf("Expected the editor to be loaded, got %+v", revisions[0].User)
+	}
+
+	if err := s.Restore(ctx, post, 1, user.ID); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if post.Title != "Draft" || post.Content != "one\ntwo\n" || post.Slug != "draft" {
+		t.Errorf("Expected the first revision to be restored with the slug kept, got %+v", post)
+	}
+	if revision, err := s.Revision(ctx, post, 3); err != nil || revision.Title != "Draft" {
+		t.Errorf("Expected the restore to be recorded, got %+v, %v", revision, err)
+	}
+	if _, err := s.Revision(ctx, post, 9); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("Expected ErrRevisionNotFound, got %v", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	got := Diff("a\nb\nc\nd\n", "a\nc\nx\nd")
+	want := []DiffLine{{DiffEqual, "a"}, {DiffDelete, "b"}, {DiffEqual, "c"}, {DiffInsert, "x"}, {DiffEqual, "d"}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+	if got := Diff("", "new"); len(got) != 1 || got[0].Op != DiffInsert {
+		t.Errorf("Expected an insertion, got %v", got)
+	}
+	if got := Diff("same\r\n", "same\n"); len(got) != 1 || got[0].Op != DiffEqual {
+		t.Errorf("Expected line endings to be ignored, got %v", got)
+	}
+}
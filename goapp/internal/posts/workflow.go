@@ -0,0 +1,92 @@
+package posts
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"goapp/internal/models"
+)
+
+// transitions lists the statuses each status may change to. Scheduled
+// posts are also published by the Scheduler when they are due.
+var transitions = map[string][]string{
+	models.PostDraft:     {models.PostInReview, models.PostScheduled, models.PostPublished, models.PostArchived},
+	models.PostInReview:  {models.PostDraft, models.PostScheduled, models.PostPublished},
+	models.PostScheduled: {models.PostDraft, models.PostPublished},
+	models.PostPublished: {models.PostDraft, models.PostArchived},
+	models.PostArchived:  {models.PostDraft, models.PostPublished},
+}
+
+// Statuses lists every post status in workflow order
+func Statuses() []string {
+	return []string{models.PostDraft, models.PostInReview, models.PostScheduled, models.PostPublished, models.PostArchived}
+}
+
+// NextStatuses returns the statuses a post in status may change to
+func NextStatuses(status string) []string {
+	return transitions[status]
+}
+
+// CanTransition reports whether a post may change from one status to another.
+// Keeping the same status is always allowed.
+func CanTransition(from, to string) bool {
+	if from == to {
+		return true
+	}
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsPublish reports whether changing from one status to another needs
+// the publish permission: anything but moving between draft and review
+// does, since it changes what readers see or when.
+func NeedsPublish(from, to string) bool {
+	if from == to {
+		return false
+	}
+	author := func(status string) bool {
+		return status == models.PostDraft || status == models.PostInReview
+	}
+	return !author(from) || !author(to)
+}
+
+// applyStatus moves post to status, keeping Published, PublishedAt and
+// ScheduledAt consistent with it. scheduledAt is only kept for scheduled posts.
+func applyStatus(post *models.Post, status string, scheduledAt *time.Time, now time.Time) {
+	post.Status = status
+	post.Published = status == models.PostPublished
+	if post.Published && post.PublishedAt == nil {
+		post.PublishedAt = &now
+	}
+	post.ScheduledAt = nil
+	if status == models.PostScheduled {
+		post.ScheduledAt = scheduledAt
+	}
+}
+
+// checkStatus validates the status of post, which was from before the
+// change, or is new when from is empty. The schedule must be in the future
+// when it was set by this change.
+func checkStatus(from string, post *models.Post, rescheduled bool, now time.Time) map[string]string {
+	fields := map[string]string{}
+	if _, ok := transitions[post.Status]; !ok {
+		fields["status"] = "must be one of " + strings.Join(Statuses(), ", ")
+		return fields
+	}
+	if from != "" && !CanTransition(from, post.Status) {
+		fields["status"] = fmt.Sprintf("cannot change from %s to %s", from, post.Status)
+	}
+	if post.Status == models.PostScheduled {
+		if post.ScheduledAt == nil {
+			fields["scheduled_at"] = "is required to schedule a post"
+		} else if rescheduled && !post.ScheduledAt.After(now) {
+			fields["scheduled_at"] = "must be in the future"
+		}
+	}
+	return fields
+}
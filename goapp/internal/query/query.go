@@ -0,0 +1,432 @@
+// Package query turns the filter, sort and page parameters of list requests
+// into GORM scopes. Each endpoint declares a Schema naming the fields that
+// may be filtered and sorted on, so clients can only reach whitelisted
+// columns:
+//
+//	?filter[published]=true&filter[created_at][gte]=2024-01-01&sort=-created_at,title&page[number]=2&page[size]=20
+//
+// page[offset] skips rows instead of selecting a page[number].
+package query
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"goapp/internal/auth"
+	"gorm.io/gorm"
+)
+
+// Page size limits used when a Schema sets none
+const (
+	DefaultSize = 20
+	MaxSize     = 100
+)
+
+// MaxPage bounds page[number], so the offset of a page cannot overflow
+const MaxPage = 100000
+
+// Kind is the type of a filterable field. It decides how values are parsed
+// and which operators apply.
+type Kind int
+
+// Field kinds
+const (
+	String Kind = iota
+	Bool
+	Int
+	Time
+)
+
+// Filter operators, given as filter[field][op]; filter[field] means OpEq
+const (
+	OpEq       = "eq"
+	OpNe       = "ne"
+	OpLt       = "lt"
+	OpLte      = "lte"
+	OpGt       = "gt"
+	OpGte      = "gte"
+	OpIn       = "in"       // comma separated values
+	OpContains = "contains" // case-insensitive substring, String fields only
+)
+
+// operators maps the operators to their SQL, with %s standing for the column
+var operators = map[string]string{
+	OpEq:       "%s = ?",
+	OpNe:       "%s <> ?",
+	OpLt:       "%s < ?",
+	OpLte:      "%s <= ?",
+	OpGt:       "%s > ?",
+	OpGte:      "%s >= ?",
+	OpIn:       "%s IN ?",
+	OpContains: `LOWER(%s) LIKE ? ESCAPE '\'`,
+}
+
+// filterKey matches filter[field] and filter[field][op]
+var filterKey = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[([^\[\]]+)\])?$`)
+
+// Field is a column clients may filter on
+type Field struct {
+	Column string // qualified with the table when the query joins others
+	Kind   Kind
+	// Values, when set, are the only values a String field may take
+	Values []string
+}
+
+// Schema declares the filters, sorts and page sizes a list endpoint accepts
+type Schema struct {
+	Filters map[string]Field
+	// Sorts maps sort keys to columns
+	Sorts map[string]string
+	// DefaultSort applies when the request has no sort parameter, e.g. "-created_at"
+	DefaultSort string
+	// TieBreaker is a unique column, such as "posts.id", appended to every
+	// order so that pages are stable
+	TieBreaker string
+	// DefaultSize and MaxSize bound page[size]; zero means DefaultSize and MaxSize
+	DefaultSize int
+	MaxSize     int
+	// Aliases maps older parameter names, such as "limit", to the parameters
+	// they stand for, such as "page[size]". An alias overrides its target,
+	// and its errors are reported under the alias.
+	Aliases map[string]string
+}
+
+// Condition is one parsed filter
+type Condition struct {
+	Field  string
+	Column string
+	Op     string
+	Value  interface{}
+}
+
+// Order is one parsed sort key
+type Order struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+// Query is a parsed list request. Its methods are GORM scopes:
+//
+//	db.Model(&models.Post{}).Scopes(q.Where, q.Order, q.Paginate).Find(&posts)
+type Query struct {
+	Conditions []Condition
+	Orders     []Order
+	Page       int // 1-based
+	Size       int
+
+	tieBreaker string
+	skip       *int // page[offset], which takes precedence over Page
+}
+
+// Meta describes the page a Query selected
+type Meta struct {
+	Page  int   `json:"page"`
+	Size  int   `json:"size"`
+	Total int64 `json:"total"`
+	Pages int   `json:"pages"`
+}
+
+// Parse reads the filter, sort and page parameters of values. Other
+// parameters are ignored. Unknown fields, operators not allowed for a
+// field's kind and malformed values are reported as an *auth.ValidationError
+// keyed by parameter name.
+func (s Schema) Parse(values url.Values) (*Query, error) {
+	values, aliased := s.resolve(values)
+	q := &Query{Page: 1, Size: s.DefaultSize, tieBreaker: s.TieBreaker}
+	if q.Size <= 0 {
+		q.Size = DefaultSize
+	}
+	maxSize := s.MaxSize
+	if maxSize <= 0 {
+		maxSize = MaxSize
+	}
+	fields := map[string]string{}
+
+	for key, vals := range values {
+		m := filterKey.FindStringSubmatch(key)
+		if m == nil {
+			if strings.HasPrefix(key, "filter[") {
+				fields[key] = "is not a valid filter"
+			}
+			continue
+		}
+		cond, msg := s.condition(m[1], m[2], vals[len(vals)-1])
+		if msg != "" {
+			fields[key] = msg
+			continue
+		}
+		q.Conditions = append(q.Conditions, cond)
+	}
+
+	sort := s.DefaultSort
+	if _, ok := values["sort"]; ok {
+		sort = values.Get("sort")
+	}
+	seen := map[string]bool{}
+	for _, key := range strings.Split(sort, ",") {
+		key = strings.TrimSpace(key)
+		desc := strings.HasPrefix(key, "-")
+		name := strings.TrimPrefix(key, "-")
+		if name == "" || seen[name] {
+			continue
+		}
+		column, ok := s.Sorts[name]
+		if !ok {
+			fields["sort"] = "cannot sort by " + name
+			continue
+		}
+		seen[name] = true
+		q.Orders = append(q.Orders, Order{Field: name, Column: column, Desc: desc})
+	}
+
+	if v := values.Get("page[number]"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxPage {
+			fields["page[number]"] = "must be between 1 and " + strconv.Itoa(MaxPage)
+		}
+		q.Page = n
+	}
+	if v := values.Get("page[size]"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSize {
+			fields["page[size]"] = "must be between 1 and " + strconv.Itoa(maxSize)
+		}
+		q.Size = n
+	}
+	if v := values.Get("page[offset]"); v != "" {
+		maxOffset := MaxPage * maxSize
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxOffset {
+			fields["page[offset]"] = "must be between 0 and " + strconv.Itoa(maxOffset)
+		}
+		q.skip = &n
+	}
+
+	if len(fields) > 0 {
+		for name, alias := range aliased {
+			if msg, ok := fields[name]; ok {
+				delete(fields, name)
+				fields[alias] = msg
+			}
+		}
+		return nil, &auth.ValidationError{Fields: fields}
+	}
+	// Conditions come from a map; keep the SQL deterministic
+	sortConditions(q.Conditions)
+	return q, nil
+}
+
+// resolve copies values with each alias given replacing its target, and
+// returns the targets that were aliased, mapped to their alias
+func (s Schema) resolve(values url.Values) (url.Values, map[string]string) {
+	if len(s.Aliases) == 0 {
+		return values, nil
+	}
+	resolved := url.Values{}
+	for key, vals := range values {
+		resolved[key] = vals
+	}
+	aliased := map[string]string{}
+	for alias, name := range s.Aliases {
+		if vals, ok := values[alias]; ok {
+			resolved[name] = vals
+			aliased[name] = alias
+		}
+	}
+	return resolved, aliased
+}
+
+// condition parses one filter, returning a message when it is invalid
+func (s Schema) condition(name, op, raw string) (Condition, string) {
+	field, ok := s.Filters[name]
+	if !ok {
+		return Condition{}, "is not filterable"
+	}
+	if op == "" {
+		op = OpEq
+	}
+	if _, ok := operators[op]; !ok {
+		return Condition{}, "has an unknown operator"
+	}
+	ordered := field.Kind == Int || field.Kind == Time
+	switch {
+	case op == OpContains && field.Kind != String,
+		(op == OpLt || op == OpLte || op == OpGt || op == OpGte) && !ordered,
+		op == OpIn && field.Kind == Bool:
+		return Condition{}, "does not support " + op
+	}
+
+	cond := Condition{Field: name, Column: field.Column, Op: op}
+	switch op {
+	case OpIn:
+		var list []interface{}
+		for _, part := range strings.Split(raw, ",") {
+			v, msg := field.parse(strings.TrimSpace(part))
+			if msg != "" {
+				return Condition{}, msg
+			}
+			list = append(list, v)
+		}
+		cond.Value = list
+	case OpContains:
+		cond.Value = "%" + EscapeLike(strings.ToLower(raw)) + "%"
+	default:
+		v, msg := field.parse(raw)
+		if msg != "" {
+			return Condition{}, msg
+		}
+		cond.Value = v
+	}
+	return cond, ""
+}
+
+// parse parses one value of the field, returning a message when it is invalid
+func (f Field) parse(raw string) (interface{}, string) {
+	switch f.Kind {
+	case Bool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, "must be true or false"
+		}
+		return v, ""
+	case Int:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, "must be a whole number"
+		}
+		return v, ""
+	case Time:
+		if t, err := time.Parse(time.RFC3339, raw); err == nil {
+			return t, ""
+		}
+		t, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return nil, "must be an RFC 3339 time or a YYYY-MM-DD date"
+		}
+		return t, ""
+	}
+	if len(f.Values) > 0 && !slices.Contains(f.Values, raw) {
+		return nil, "must be one of " + strings.Join(f.Values, ", ")
+	}
+	return raw, ""
+}
+
+// Where is a scope applying the filters
+func (q *Query) Where(db *gorm.DB) *gorm.DB {
+	for _, cond := range q.Conditions {
+		db = db.Where(fmt.Sprintf(operators[cond.Op], cond.Column), cond.Value)
+	}
+	return db
+}
+
+// Order is a scope applying the sort, then the schema's tie-breaker
+func (q *Query) Order(db *gorm.DB) *gorm.DB {
+	desc := false
+	for _, order := range q.Orders {
+		db = db.Order(orderBy(order.Column, order.Desc))
+		desc = order.Desc
+	}
+	if q.tieBreaker != "" {
+		db = db.Order(orderBy(q.tieBreaker, desc))
+	}
+	return db
+}
+
+// Paginate is a scope selecting the requested page
+func (q *Query) Paginate(db *gorm.DB) *gorm.DB {
+	return db.Limit(q.Size).Offset(q.Offset())
+}
+
+// Offset returns the rows before the requested page
+func (q *Query) Offset() int {
+	if q.skip != nil {
+		return *q.skip
+	}
+	return (q.Page - 1) * q.Size
+}
+
+// Numbered reports whether the page was selected by page[number] rather
+// than page[offset], so that Links can point to the pages around it
+func (q *Query) Numbered() bool {
+	return q.skip == nil
+}
+
+// Meta describes the requested page of total rows
+func (q *Query) Meta(total int64) Meta {
+	return Meta{Page: q.Page, Size: q.Size, Total: total, Pages: int(math.Ceil(float64(total) / float64(q.Size)))}
+}
+
+// Find counts the rows db matches with the filters and loads the requested
+// page of them into dest. db must have a model, e.g. db.Model(&models.Post{}).
+func (q *Query) Find(db *gorm.DB, dest interface{}) (Meta, error) {
+	filtered := db.Scopes(q.Where).Session(&gorm.Session{})
+
+	var total int64
+	if err := filtered.Count(&total).Error; err != nil {
+		return q.Meta(0), fmt.Errorf("failed to count rows: %w", err)
+	}
+	meta := q.Meta(total)
+	if err := filtered.Scopes(q.Order, q.Paginate).Find(dest).Error; err != nil {
+		return meta, fmt.Errorf("failed to load rows: %w", err)
+	}
+	return meta, nil
+}
+
+// Links returns an RFC 8288 Link header with the first, previous, next and
+// last pages of the request to u. Other query parameters are kept.
+func Links(u *url.URL, meta Meta) string {
+	link := func(page int, rel string) string {
+		next := *u
+		values := u.Query()
+		values.Set("page[number]", strconv.Itoa(page))
+		values.Set("page[size]", strconv.Itoa(meta.Size))
+		next.RawQuery = values.Encode()
+		return fmt.Sprintf(`<%s>; rel="%s"`, next.String(), rel)
+	}
+
+	last := meta.Pages
+	if last < 1 {
+		last = 1
+	}
+	links := []string{link(1, "first")}
+	if meta.Page > 1 {
+		links = append(links, link(min(meta.Page-1, last), "prev"))
+	}
+	if meta.Page < last {
+		links = append(links, link(meta.Page+1, "next"))
+	}
+	links = append(links, link(last, "last"))
+	return strings.Join(links, ", ")
+}
+
+func orderBy(column string, desc bool) string {
+	if desc {
+		return column + " DESC"
+	}
+	return column + " ASC"
+}
+
+func sortConditions(conds []Condition) {
+	for i := 1; i < len(conds); i++ {
+		for j := i; j > 0 && conditionKey(conds[j]) < conditionKey(conds[j-1]); j-- {
+			conds[j], conds[j-1] = conds[j-1], conds[j]
+		}
+	}
+}
+
+func conditionKey(cond Condition) string {
+	return cond.Field + "\x00" + cond.Op
+}
+
+// EscapeLike escapes the wildcards of a LIKE pattern, which must then be
+// matched with ESCAPE '\'
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,210 @@
+package query
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"goapp/internal/auth"
+	"goapp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var postSchema = Schema{
+	Filters: map[string]Field{
+		"title":      {Column: "posts.title", Kind: String},
+		"status":     {Column: "posts.status", Kind: String, Values: []string{"draft", "published"}},
+		"published":  {Column: "posts.published", Kind: Bool},
+		"user_id":    {Column: "posts.user_id", Kind: Int},
+		"created_at": {Column: "posts.created_at", Kind: Time},
+	},
+	Sorts: map[string]string{
+		"title":      "posts.title",
+		"created_at": "posts.created_at",
+	},
+	DefaultSort: "-created_at",
+	TieBreaker:  "posts.id",
+	MaxSize:     50,
+	Aliases: map[string]string{
+		"published": "filter[published]",
+		"limit":     "page[size]",
+		"offset":    "page[offset]",
+	},
+}
+
+func parse(t *testing.T, rawQuery string) *Query {
+	t.Helper()
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+	q, err := postSchema.Parse(values)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", rawQuery, err)
+	}
+	return q
+}
+
+func TestParse(t *testing.T) {
+	q := parse(t, "filter[published]=true&filter[user_id][in]=1,2&filter[created_at][gte]=2024-01-02&sort=title,-created_at&page[number]=3&page[size]=10&q=ignored")
+
+	if len(q.Conditions) != 3 {
+		t.Fatalf("Expected 3 conditions, got %d", len(q.Conditions))
+	}
+	created := q.Conditions[0]
+	if created.Op != OpGte || !created.Value.(time.Time).Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected created_at >= 2024-01-02, got %s %v", created.Op, created.Value)
+	}
+	if published := q.Conditions[1]; published.Column != "posts.published" || published.Op != OpEq || published.Value != true {
+		t.Errorf("Expected published = true, got %s %s %v", published.Column, published.Op, published.Value)
+	}
+	if ids := q.Conditions[2].Value.([]interface{}); len(ids) != 2 || ids[1] != int64(2) {
+		t.Errorf("Expected user_id in (1, 2), got %v", ids)
+	}
+
+	if len(q.Orders) != 2 || q.Orders[0].Column != "posts.title" || q.Orders[0].Desc || !q.Orders[1].Desc {
+		t.Errorf("Expected title ascending then created_at descending, got %+v", q.Orders)
+	}
+	if q.Page != 3 || q.Size != 10 {
+		t.Errorf("Expected page 3 of size 10, got %d of %d", q.Page, q.Size)
+	}
+}
+
+func TestParse_Defaults(t *testing.T) {
+	q := parse(t, "")
+	if q.Page != 1 || q.Size != DefaultSize {
+		t.Errorf("Expected page 1 of size %d, got %d of %d", DefaultSize, q.Page, q.Size)
+	}
+	if len(q.Orders) != 1 || q.Orders[0].Field != "created_at" || !q.Orders[0].Desc {
+		t.Errorf("Expected the default sort, got %+v", q.Orders)
+	}
+	if q := parse(t, "sort="); len(q.Orders) != 0 {
+		t.Errorf("Expected an empty sort to clear the default, got %+v", q.Orders)
+	}
+}
+
+func TestParse_Aliases(t *testing.T) {
+	q := parse(t, "published=false&filter[published]=true&limit=5&page[size]=10&offset=7")
+	if len(q.Conditions) != 1 || q.Conditions[0].Value != false {
+		t.Errorf("Expected published to override filter[published], got %+v", q.Conditions)
+	}
+	if q.Size != 5 || q.Offset() != 7 || q.Numbered() {
+		t.Errorf("Expected 5 rows after 7, got %d after %d", q.Size, q.Offset())
+	}
+	if q := parse(t, "page[number]=3&page[size]=10"); q.Offset() != 20 || !q.Numbered() {
+		t.Errorf("Expected page 3 to skip 20 rows, got %d", q.Offset())
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		query string
+		field string
+	}{
+		{"filter[password_hash]=x", "filter[password_hash]"},
+		{"filter[title][gt]=a", "filter[title][gt]"},
+		{"filter[published][contains]=t", "filter[published][contains]"},
+		{"filter[published]=maybe", "filter[published]"},
+		{"filter[user_id][in]=1,two", "filter[user_id][in]"},
+		{"filter[created_at]=yesterday", "filter[created_at]"},
+		{"filter[title][like]=a", "filter[title][like]"},
+		{"filter[status]=deleted", "filter[status]"},
+		{"filter[status][in]=draft,deleted", "filter[status][in]"},
+		{"filter[title][a][b]=c", "filter[title][a][b]"},
+		{"sort=-password_hash", "sort"},
+		{"page[number]=0", "page[number]"},
+		{"page[number]=100001", "page[number]"},
+		{"page[number]=9223372036854775807", "page[number]"},
+		{"page[size]=51", "page[size]"},
+		{"page[offset]=-1", "page[offset]"},
+		{"page[offset]=5000001", "page[offset]"},
+		{"published=maybe", "published"},
+		{"limit=0", "limit"},
+	}
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		_, err := postSchema.Parse(values)
+		var validationErr *auth.ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("Parse(%q): expected a validation error, got %v", tt.query, err)
+			continue
+		}
+		if _, ok := validationErr.Fields[tt.field]; !ok {
+			t.Errorf("Parse(%q): expected an error for %s, got %v", tt.query, tt.field, validationErr.Fields)
+		}
+	}
+}
+
+func TestQuery_Find(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Post{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, title := range []string{"Go 100%", "Gophers", "Rust", "go_fmt", "Zig"} {
+		post := &models.Post{Title: title, Slug: strings.ToLower(title), Content: "...", Published: i != 2, UserID: uint(i%2 + 1)}
+		post.CreatedAt = start.AddDate(0, 0, i)
+		if err := db.Create(post).Error; err != nil {
+			t.Fatalf("Failed to create post: %v", err)
+		}
+	}
+
+	var posts []models.Post
+	q := parse(t, "filter[published]=true&sort=title&page[size]=2&page[number]=2")
+	meta, err := q.Find(db.Model(&models.Post{}), &posts)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if meta.Total != 4 || meta.Pages != 2 || meta.Page != 2 {
+		t.Errorf("Expected page 2 of 2 with 4 posts, got %+v", meta)
+	}
+	if len(posts) != 2 || posts[0].Title != "Zig" || posts[1].Title != "go_fmt" {
+		t.Errorf("Expected Zig and go_fmt, got %v", posts)
+	}
+
+	posts = nil
+	q = parse(t, "filter[title][contains]=GO&filter[created_at][lt]=2024-01-04")
+	if _, err := q.Find(db.Model(&models.Post{}), &posts); err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(posts) != 2 || posts[0].Title != "Gophers" {
+		t.Errorf("Expected the Go posts before January 4th, newest first, got %v", posts)
+	}
+
+	posts = nil
+	q = parse(t, "filter[title][contains]=_")
+	if _, err := q.Find(db.Model(&models.Post{}), &posts); err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(posts) != 1 || posts[0].Title != "go_fmt" {
+		t.Errorf("Expected wildcards to match literally, got %v", posts)
+	}
+}
+
+func TestLinks(t *testing.T) {
+	u, _ := url.Parse("https://example.com/api/v1/posts?filter%5Bpublished%5D=true&page%5Bnumber%5D=2")
+	links := Links(u, Meta{Page: 2, Size: 10, Total: 35, Pages: 4})
+
+	for _, want := range []string{
+		`page%5Bnumber%5D=1&page%5Bsize%5D=10>; rel="first"`,
+		`page%5Bnumber%5D=1&page%5Bsize%5D=10>; rel="prev"`,
+		`page%5Bnumber%5D=3&page%5Bsize%5D=10>; rel="next"`,
+		`page%5Bnumber%5D=4&page%5Bsize%5D=10>; rel="last"`,
+		"<https://example.com/api/v1/posts?filter%5Bpublished%5D=true&",
+	} {
+		if !strings.Contains(links, want) {
+			t.Errorf("Expected %s in %s", want, links)
+		}
+	}
+
+	links = Links(u, Meta{Page: 1, Size: 10})
+	if strings.Contains(links, "prev") || strings.Contains(links, "next") || !strings.Contains(links, `page%5Bnumber%5D=1&page%5Bsize%5D=10>; rel="last"`) {
+		t.Errorf("Expected only first and last links for an empty list, got %s", links)
+	}
+}
@@ -0,0 +1,103 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// dialect builds the search query of a database. Queries return hits and,
+// to order them, a created_at column.
+type dialect interface {
+	query(terms []string, q Query) (string, map[string]interface{})
+}
+
+// detect returns the dialect of db
+func detect(db *gorm.DB) (dialect, error) {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return postgresDialect{}, nil
+	case "sqlite":
+		var schema string
+		err := db.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", sqliteTable).Scan(&schema).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to find search index: %w", err)
+		}
+		if schema == "" {
+			return nil, ErrUnsupported
+		}
+		return sqliteDialect{fts5: strings.Contains(strings.ToLower(schema), "fts5")}, nil
+	}
+	return nil, ErrUnsupported
+}
+
+// postgresDialect matches the search_vector columns of posts and comments. Snippets
+// are only made for the page of results, as ts_headline reparses the text.
+type postgresDialect struct{}
+
+// headlineOptions shows up to two fragments of the text around matches
+const headlineOptions = `StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=12, MaxFragments=2, FragmentDelimiter=" … "`
+
+func (postgresDialect) query(terms []string, q Query) (string, map[string]interface{}) {
+	// Terms hold only letters and digits, so they are safe in to_tsquery
+	args := map[string]interface{}{
+		"query":   strings.Join(terms, " & ") + ":*",
+		"options": headlineOptions,
+	}
+	var arms []string
+	if q.Kind != KindComment {
+		arms = append(arms, "SELECT 'post' AS kind, p.id AS post_id, 0 AS comment_id, p.user_id, p.created_at,"+
+			" ts_rank_cd(p.search_vector, to_tsquery('english', @query)) AS score,"+
+			" concat_ws(' ', p.title, p.summary, p.content) AS body"+
+			" FROM posts p WHERE p.search_vector @@ to_tsquery('english', @query)"+filters(q, KindPost, args))
+	}
+	if q.Kind != KindPost {
+		arms = append(arms, "SELECT 'comment' AS kind, c.post_id, c.id AS comment_id, c.user_id, c.created_at,"+
+			" ts_rank_cd(c.search_vector, to_tsquery('english', @query)) AS score, c.content AS body"+
+			" FROM comments c JOIN posts p ON p.id = c.post_id WHERE c.search_vector @@ to_tsquery('english', @query)"+
+			filters(q, KindComment, args))
+	}
+	return "SELECT m.kind, m.post_id, m.comment_id, m.user_id, m.score, m.total," +
+		" ts_headline('english', m.body, to_tsquery('english', @query), @options) AS snippet" +
+		" FROM (" + page(arms, q, args) + ") m ORDER BY m.score DESC, m.created_at DESC, m.comment_id", args
+}
+
+// sqliteTable is the full-text table of SQLite. Posts are stored with
+// rowid 2*id and comments with 2*id+1; comments have an empty title.
+const sqliteTable = "search_index"
+
+// sqliteDialect matches the search_index table with FTS5, ranked by bm25, or with
+// FTS4, ranked by the number of matches and whether the title matches
+type sqliteDialect struct {
+	fts5 bool
+}
+
+func (d sqliteDialect) query(terms []string, q Query) (string, map[string]interface{}) {
+	// Lowercase words are never operators, which FTS spells in capitals
+	args := map[string]interface{}{"query": strings.Join(terms, " ") + "*"}
+	score := "-bm25(search_index, 4.0, 1.0)"
+	snippet := "snippet(search_index, -1, '<mark>', '</mark>', '…', 16)"
+	if !d.fts5 {
+		// offsets lists a "column term offset size" quadruple per match,
+		// title matches first, which are weighted like bm25 weights them
+		score = "(length(offsets(search_index)) - length(replace(offsets(search_index), ' ', '')) + 1) / 4.0" +
+			" + CASE WHEN offsets(search_index) LIKE '0 %' THEN 3 ELSE 0 END"
+		snippet = "snippet(search_index, '<mark>', '</mark>', '…', -1, 16)"
+	}
+
+	var arms []string
+	if q.Kind != KindComment {
+		arms = append(arms, "SELECT 'post' AS kind, p.id AS post_id, 0 AS comment_id, p.user_id, p.created_at,"+
+			" "+score+" AS score, "+snippet+" AS snippet"+
+			" FROM search_index JOIN posts p ON p.id = search_index.rowid / 2"+
+			" WHERE search_index MATCH @query AND search_index.rowid % 2 = 0"+filters(q, KindPost, args))
+	}
+	if q.Kind != KindPost {
+		arms = append(arms, "SELECT 'comment' AS kind, c.post_id, c.id AS comment_id, c.user_id, c.created_at,"+
+			" "+score+" AS score, "+snippet+" AS snippet"+
+			" FROM search_index JOIN comments c ON c.id = search_index.rowid / 2 JOIN posts p ON p.id = c.post_id"+
+			" WHERE search_index MATCH @query AND search_index.rowid % 2 = 1"+filters(q, KindComment, args))
+	}
+	return page(arms, q, args), args
+}
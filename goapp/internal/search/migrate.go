@@ -0,0 +1,95 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// postgresSchema adds generated tsvector columns to posts and comments,
+// weighting post titles over summaries over content, and indexes them
+var postgresSchema = []string{
+	`ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+		setweight(to_tsvector('english', coalesce(summary, '')), 'B') ||
+		setweight(to_tsvector('english', coalesce(content, '')), 'C')) STORED`,
+	"CREATE INDEX IF NOT EXISTS idx_posts_search_vector ON posts USING GIN (search_vector)",
+	`ALTER TABLE comments ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		to_tsvector('english', coalesce(content, ''))) STORED`,
+	"CREATE INDEX IF NOT EXISTS idx_comments_search_vector ON comments USING GIN (search_vector)",
+}
+
+// sqliteTriggers keep search_index in step with posts and comments
+var sqliteTriggers = []string{
+	`CREATE TRIGGER IF NOT EXISTS search_posts_insert AFTER INSERT ON posts BEGIN
+		INSERT INTO search_index(rowid, title, body) VALUES (new.id * 2, new.title, coalesce(new.summary, '') || ' ' || coalesce(new.content, ''));
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS search_posts_update AFTER UPDATE OF title, summary, content ON posts BEGIN
+		DELETE FROM search_index WHERE rowid = old.id * 2;
+		INSERT INTO search_index(rowid, title, body) VALUES (new.id * 2, new.title, coalesce(new.summary, '') || ' ' || coalesce(new.content, ''));
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS search_posts_delete AFTER DELETE ON posts BEGIN
+		DELETE FROM search_index WHERE rowid = old.id * 2;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS search_comments_insert AFTER INSERT ON comments BEGIN
+		INSERT INTO search_index(rowid, title, body) VALUES (new.id * 2 + 1, '', coalesce(new.content, ''));
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS search_comments_update AFTER UPDATE OF content ON comments BEGIN
+		DELETE FROM search_index WHERE rowid = old.id * 2 + 1;
+		INSERT INTO search_index(rowid, title, body) VALUES (new.id * 2 + 1, '', coalesce(new.content, ''));
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS search_comments_delete AFTER DELETE ON comments BEGIN
+		DELETE FROM search_index WHERE rowid = old.id * 2 + 1;
+	END`,
+}
+
+// Migrate creates the search index of db, after the posts and comments
+// tables. On SQLite the index is rebuilt, as recreating a table to alter
+// it drops its triggers. Other databases are left alone.
+func Migrate(db *gorm.DB) error {
+	switch db.Dialector.Name() {
+	case "postgres":
+		for _, statement := range postgresSchema {
+			if err := db.Exec(statement).Error; err != nil {
+				return fmt.Errorf("failed to create search columns: %w", err)
+			}
+		}
+	case "sqlite":
+		return migrateSQLite(db)
+	}
+	return nil
+}
+
+func migrateSQLite(db *gorm.DB) error {
+	// Trying FTS5 is expected to fail on some builds, so it is not logged
+	quiet := db.Session(&gorm.Session{Logger: db.Logger.LogMode(logger.Silent)})
+	err := quiet.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(title, body, tokenize = 'porter unicode61 remove_diacritics 2')").Error
+	if err != nil && strings.Contains(err.Error(), "no such module") {
+		// SQLite built without FTS5, such as go-sqlite3 without the sqlite_fts5 tag
+		err = db.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts4(title, body, tokenize=porter)").Error
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create search index: %w", err)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range sqliteTriggers {
+			if err := tx.Exec(statement).Error; err != nil {
+				return fmt.Errorf("failed to create search trigger: %w", err)
+			}
+		}
+		statements := []string{
+			"DELETE FROM search_index",
+			"INSERT INTO search_index(rowid, title, body) SELECT id * 2, title, coalesce(summary, '') || ' ' || coalesce(content, '') FROM posts",
+			"INSERT INTO search_index(rowid, title, body) SELECT id * 2 + 1, '', coalesce(content, '') FROM comments",
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return fmt.Errorf("failed to rebuild search index: %w", err)
+			}
+		}
+		return nil
+	})
+}
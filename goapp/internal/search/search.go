@@ -0,0 +1,326 @@
+// Package search finds published posts and comments by their text. On
+// PostgreSQL it matches weighted tsvector columns backed by GIN indexes; on
+// SQLite, used in tests and development, an FTS5 table (FTS4 when SQLite is
+// built without FTS5) kept up to date by triggers. Migrate creates either.
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"goapp/internal/auth"
+	"goapp/internal/models"
+	"goapp/internal/query"
+	"gorm.io/gorm"
+)
+
+// Page size limits for Search
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Schema declares the page parameters Search requests accept; limit and
+// offset stand for page[size] and page[offset]
+var Schema = query.Schema{
+	DefaultSize: DefaultLimit,
+	MaxSize:     MaxLimit,
+	Aliases: map[string]string{
+		"limit":  "page[size]",
+		"offset": "page[offset]",
+	},
+}
+
+// Kinds of results
+const (
+	KindPost    = "post"
+	KindComment = "comment"
+)
+
+// maxTerms limits the words of a query that are searched for
+const maxTerms = 10
+
+// ErrUnsupported is returned by Search on databases other than PostgreSQL
+// and SQLite, and on SQLite before Migrate has created the index
+var ErrUnsupported = errors.New("search is not supported by this database")
+
+// Query selects and paginates search results. Zero values mean no filter.
+type Query struct {
+	// Text is split into words, all of which must match; the last one
+	// also matches longer words starting with it, for searching as you type
+	Text   string
+	Kind   string // KindPost or KindComment
+	Tag    string // slug of a tag of the post
+	Author string // ID or username of the author of the post or comment
+
+	// Limit caps the page size at MaxLimit
+	Limit  int
+	Offset int
+}
+
+// Result is a post or comment matching a query
+type Result struct {
+	Kind      string    `json:"kind"` // KindPost or KindComment
+	PostID    uint      `json:"post_id"`
+	PostSlug  string    `json:"post_slug"`
+	PostTitle string    `json:"post_title"`
+	CommentID uint      `json:"comment_id,omitempty"`
+	Author    string    `json:"author"`  // username
+	Snippet   string    `json:"snippet"` // escaped HTML with the matches in <mark>
+	Score     float64   `json:"score"`   // higher is better; only comparable within a search
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Page is one page of Search results, best first
+type Page struct {
+	Results []Result
+	Total   int64 // all matching posts and comments
+}
+
+// Service searches posts and comments
+type Service interface {
+	// Search returns a page of the published posts, and the comments on
+	// them, that match q. A query without words matches nothing.
+	Search(ctx context.Context, q Query) (*Page, error)
+}
+
+type service struct {
+	db *gorm.DB
+
+	mu      sync.Mutex
+	dialect dialect // detected on first use
+}
+
+// NewService creates a search service for the database of db
+func NewService(db *gorm.DB) Service {
+	return &service{db: db}
+}
+
+// hit is a match as returned by a dialect
+type hit struct {
+	Kind      string
+	PostID    uint
+	CommentID uint
+	UserID    uint
+	Score     float64
+	Snippet   string
+	Total     int64
+}
+
+// Search implements Service
+func (s *service) Search(ctx context.Context, q Query) (*Page, error) {
+	if q.Kind != "" && q.Kind != KindPost && q.Kind != KindComment {
+		return nil, &auth.ValidationError{Fields: map[string]string{"type": "must be post or comment"}}
+	}
+	terms := Terms(q.Text)
+	if len(terms) == 0 {
+		return &Page{Results: []Result{}}, nil
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+
+	d, err := s.detect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sql, args := d.query(terms, q)
+	var hits []hit
+	if err := s.db.WithContext(ctx).Raw(sql, args).Scan(&hits).Error; err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+	page := &Page{Results: make([]Result, len(hits))}
+	if len(hits) == 0 {
+		return page, nil
+	}
+	page.Total = hits[0].Total
+	if err := s.describe(ctx, hits, page.Results); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// detect returns the dialect of the database, once its index exists
+func (s *service) detect(ctx context.Context) (dialect, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dialect == nil {
+		d, err := detect(s.db.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		s.dialect = d
+	}
+	return s.dialect, nil
+}
+
+// describe fills results with hits and the posts, comments and authors they refer to
+func (s *service) describe(ctx context.Context, hits []hit, results []Result) error {
+	var postIDs, commentIDs, userIDs []uint
+	for _, h := range hits {
+		postIDs = append(postIDs, h.PostID)
+		userIDs = append(userIDs, h.UserID)
+		if h.Kind == KindComment {
+			commentIDs = append(commentIDs, h.CommentID)
+		}
+	}
+
+	db := s.db.WithContext(ctx)
+	var posts []models.Post
+	if err := db.Select("id", "slug", "title", "created_at").Find(&posts, postIDs).Error; err != nil {
+		return fmt.Errorf("failed to load posts: %w", err)
+	}
+	var users []models.User
+	if err := db.Unscoped().Select("id", "username").Find(&users, userIDs).Error; err != nil {
+		return fmt.Errorf("failed to load authors: %w", err)
+	}
+	var comments []models.Comment
+	if len(commentIDs) > 0 {
+		if err := db.Select("id", "created_at").Find(&comments, commentIDs).Error; err != nil {
+			return fmt.Errorf("failed to load comments: %w", err)
+		}
+	}
+	postsByID := make(map[uint]models.Post, len(posts))
+	for _, post := range posts {
+		postsByID[post.ID] = post
+	}
+	usernames := make(map[uint]string, len(users))
+	for _, user := range users {
+		usernames[user.ID] = user.Username
+	}
+	commentDates := make(map[uint]time.Time, len(comments))
+	for _, comment := range comments {
+		commentDates[comment.ID] = comment.CreatedAt
+	}
+
+	for i, h := range hits {
+		post := postsByID[h.PostID]
+		results[i] = Result{
+			Kind:      h.Kind,
+			PostID:    h.PostID,
+			PostSlug:  post.Slug,
+			PostTitle: post.Title,
+			Author:    usernames[h.UserID],
+			Snippet:   highlight(h.Snippet),
+			Score:     h.Score,
+			CreatedAt: post.CreatedAt,
+		}
+		if h.Kind == KindComment {
+			results[i].CommentID = h.CommentID
+			results[i].CreatedAt = commentDates[h.CommentID]
+		}
+	}
+	return nil
+}
+
+// Terms splits text into the lowercase words a search matches, without
+// repeats and at most maxTerms of them
+func Terms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]bool, len(words))
+	var terms []string
+	for _, word := range words {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+		if len(terms) == maxTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// filters returns the conditions on post p, and on comment c for comments,
+// that q adds to the search, with their arguments in args
+func filters(q Query, kind string, args map[string]interface{}) string {
+	var b strings.Builder
+	b.WriteString(" AND p.published AND p.deleted_at IS NULL")
+	author := "p.user_id"
+	if kind == KindComment {
+		b.WriteString(" AND c.deleted_at IS NULL")
+		author = "c.user_id"
+	}
+	if q.Tag != "" {
+		b.WriteString(" AND EXISTS (SELECT 1 FROM post_tags pt JOIN tags t ON t.id = pt.tag_id WHERE pt.post_id = p.id AND t.slug = @tag)")
+		args["tag"] = q.Tag
+	}
+	if q.Author != "" {
+		if id, err := strconv.ParseUint(q.Author, 10, 64); err == nil {
+			b.WriteString(" AND " + author + " = @author_id")
+			args["author_id"] = id
+		} else {
+			b.WriteString(" AND " + author + " IN (SELECT id FROM users WHERE username = @author)")
+			args["author"] = q.Author
+		}
+	}
+	return b.String()
+}
+
+// page wraps the matches of arms, SELECTs joined with UNION ALL, with their
+// total and orders and limits them
+func page(arms []string, q Query, args map[string]interface{}) string {
+	args["limit"] = q.Limit
+	args["offset"] = q.Offset
+	return "SELECT u.*, count(*) OVER () AS total FROM (" + strings.Join(arms, " UNION ALL ") + ") u" +
+		" ORDER BY u.score DESC, u.created_at DESC, u.comment_id LIMIT @limit OFFSET @offset"
+}
+
+// highlight escapes snippet, keeping the <mark> tags the database put
+// around matches and closing any left open
+func highlight(snippet string) string {
+	var b strings.Builder
+	open := false
+	for snippet != "" {
+		i := strings.IndexByte(snippet, '<')
+		if i < 0 {
+			b.WriteString(escape(snippet))
+			break
+		}
+		b.WriteString(escape(snippet[:i]))
+		snippet = snippet[i:]
+		switch {
+		case strings.HasPrefix(snippet, "<mark>"):
+			if !open {
+				b.WriteString("<mark>")
+				open = true
+			}
+			snippet = snippet[len("<mark>"):]
+		case strings.HasPrefix(snippet, "</mark>"):
+			if open {
+				b.WriteString("</mark>")
+				open = false
+			}
+			snippet = snippet[len("</mark>"):]
+		default:
+			b.WriteString("&lt;")
+			snippet = snippet[1:]
+		}
+	}
+	if open {
+		b.WriteString("</mark>")
+	}
+	return b.String()
+}
+
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&#34;", "'", "&#39;")
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
@@ -0,0 +1,189 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"goapp/internal/auth"
+	"goapp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestService indexes posts by jane and a comment by bob. Only the
+// first post is tagged go; the draft and the deleted post are never found.
+func setupTestService(t *testing.T) (Service, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Post{}, &models.Comment{}, &models.Tag{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	// Rows from before the index was created are indexed too
+	jane := &models.User{Email: "jane@example.com", Username: "jane", PasswordHash: "hash", Active: true}
+	if err := db.Create(jane).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	bob := &models.User{Email: "bob@example.com", Username: "bob", PasswordHash: "hash", Active: true}
+	if err := db.Create(bob).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	posts := []*models.Post{
+		{Title: "Concurrency in Go", Slug: "concurrency", Summary: "Goroutines & channels", Content: "Channels connect goroutines.", Published: true, UserID: jane.ID},
+		{Title: "Testing", Slug: "testing", Content: "Table tests make concurrency bugs <rare>.", Published: true, UserID: jane.ID},
+		{Title: "Concurrency draft", Slug: "draft", Content: "Unfinished", UserID: jane.ID},
+		{Title: "Concurrency deleted", Slug: "deleted", Content: "Gone", Published: true, UserID: jane.ID},
+	}
+	for _, post := range posts {
+		if err := db.Create(post).Error; err != nil {
+			t.Fatalf("Failed to create post: %v", err)
+		}
+	}
+	if err := db.Model(posts[0]).Association("Tags").Append(&models.Tag{Name: "Go", Slug: "go"}); err != nil {
+		t.Fatalf("Failed to tag post: %v", err)
+	}
+	if err := db.Delete(posts[3]).Error; err != nil {
+		t.Fatalf("Failed to delete post: %v", err)
+	}
+	comments := []*models.Comment{
+		{Content: "Great post on concurrency", UserID: bob.ID, PostID: posts[1].ID},
+		{Content: "Concurrency in drafts", UserID: bob.ID, PostID: posts[2].ID},
+	}
+	for _, comment := range comments {
+		if err := db.Create(comment).Error; err != nil {
+			t.Fatalf("Failed to create comment: %v", err)
+		}
+	}
+	return NewService(db), db
+}
+
+func search(t *testing.T, s Service, q Query) *Page {
+	t.Helper()
+	page, err := s.Search(context.Background(), q)
+	if err != nil {
+		t.Fatalf("Search(%+v) error = %v", q, err)
+	}
+	return page
+}
+
+func TestSearch(t *testing.T) {
+	s, db := setupTestService(t)
+
+	page := search(t, s, Query{Text: "Concurrency"})
+	if page.Total != 3 || len(page.Results) != 3 {
+		t.Fatalf("Expected 3 published matches, got %d: %+v", page.Total, page.Results)
+	}
+	first := page.Results[0]
+	if first.Kind != KindPost || first.PostSlug != "concurrency" || first.Author != "jane" {
+		t.Errorf("Expected the post matching in its title first, got %+v", first)
+	}
+	if !strings.Contains(first.Snippet, "<mark>Concurrency</mark>") {
+		t.Errorf("Expected the match to be highlighted, got %q", first.Snippet)
+	}
+	var comment Result
+	for _, result := range page.Results {
+		if result.Kind == KindComment {
+			comment = result
+		}
+	}
+	if comment.CommentID == 0 || comment.PostSlug != "testing" || comment.Author != "bob" || comment.CreatedAt.IsZero() {
+		t.Errorf("Expected the comment on the published post, got %+v", comment)
+	}
+
+	// Stemming, prefixes and escaping
+	if page := search(t, s, Query{Text: "channel"}); page.Total != 1 {
+		t.Errorf("Expected channel to match channels, got %d", page.Total)
+	}
+	if page := search(t, s, Query{Text: "goro"}); page.Total != 1 {
+		t.Errorf("Expected the last word to match as a prefix, got %d", page.Total)
+	}
+	page = search(t, s, Query{Text: "table bugs", Kind: KindPost})
+	if page.Total != 1 || strings.Contains(page.Results[0].Snippet, "<rare>") {
+		t.Errorf("Expected an escaped snippet of the testing post, got %+v", page.Results)
+	}
+	if page := search(t, s, Query{Text: "concurrency unfinished"}); page.Total != 0 {
+		t.Errorf("Expected drafts not to match, got %d", page.Total)
+	}
+
+	// Filters
+	if page := search(t, s, Query{Text: "concurrency", Kind: KindComment}); page.Total != 1 || page.Results[0].Kind != KindComment {
+		t.Errorf("Expected the comment only, got %+v", page.Results)
+	}
+	if page := search(t, s, Query{Text: "concurrency", Tag: "go"}); page.Total != 1 || page.Results[0].PostSlug != "concurrency" {
+		t.Errorf("Expected the tagged post only, got %+v", page.Results)
+	}
+	if page := search(t, s, Query{Text: "concurrency", Author: "bob"}); page.Total != 1 || page.Results[0].Author != "bob" {
+		t.Errorf("Expected bob's comment only, got %+v", page.Results)
+	}
+	if page := search(t, s, Query{Text: "concurrency", Author: "1"}); page.Total != 2 {
+		t.Errorf("Expected jane's posts by ID, got %d", page.Total)
+	}
+
+	// Pages keep the total
+	page = search(t, s, Query{Text: "concurrency", Limit: 1, Offset: 1})
+	if page.Total != 3 || len(page.Results) != 1 || page.Results[0].PostSlug == "concurrency" {
+		t.Errorf("Expected the second of 3 results, got %d: %+v", page.Total, page.Results)
+	}
+
+	// Edits are indexed by the triggers
+	if err := db.Model(&models.Post{}).Where("slug = ?", "testing").Update("content", "Fuzzing").Error; err != nil {
+		t.Fatalf("Failed to update post: %v", err)
+	}
+	if page := search(t, s, Query{Text: "fuzzing"}); page.Total != 1 {
+		t.Errorf("Expected updated content to match, got %d", page.Total)
+	}
+	if page := search(t, s, Query{Text: "table"}); page.Total != 0 {
+		t.Errorf("Expected old content not to match, got %d", page.Total)
+	}
+
+	if page := search(t, s, Query{Text: " ?! "}); page.Total != 0 || page.Results == nil {
+		t.Errorf("Expected no results without words, got %+v", page)
+	}
+	var validationErr *auth.ValidationError
+	if _, err := s.Search(context.Background(), Query{Text: "go", Kind: "user"}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected an unknown type to be rejected, got %v", err)
+	}
+}
+
+func TestSearch_Unsupported(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if _, err := NewService(db).Search(context.Background(), Query{Text: "go"}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported before Migrate, got %v", err)
+	}
+}
+
+func TestTerms(t *testing.T) {
+	got := Terms("Go's GO-routines, \"AND\" ü*")
+	want := []string{"go", "s", "routines", "and", "ü"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := Terms(strings.Repeat("a b c d e f g h i j k l ", 2)); len(got) != maxTerms {
+		t.Errorf("Expected %d terms, got %d", maxTerms, len(got))
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := map[string]string{
+		"<mark>go</mark> & <b>": "<mark>go</mark> &amp; &lt;b&gt;",
+		"<mark>open":            "<mark>open</mark>",
+		"</mark>x<mark><mark>y": "x<mark>y</mark>",
+		`"quoted"`:              "&#34;quoted&#34;",
+	}
+	for in, want := range tests {
+		if got := highlight(in); got != want {
+			t.Errorf("highlight(%q): expected %q, got %q", in, want, got)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package security
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewCSRFToken(t *testing.T) {
+	token1, err := NewCSRFToken()
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	token2, err := NewCSRFToken()
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	if token1 == "" || token1 == token2 {
+		t.Error("Expected distinct non-empty tokens")
+	}
+}
+
+func TestValidCSRFToken(t *testing.T) {
+	testCases := []struct {
+		name      string
+		expected  string
+		submitted string
+		want      bool
+	}{
+		{"Match", "abc", "abc", true},
+		{"Mismatch", "abc", "abd", false},
+		{"EmptySubmitted", "abc", "", false},
+		{"BothEmpty", "", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ValidCSRFToken(tc.expected, tc.submitted); got != tc.want {
+				t.Errorf("ValidCSRFToken() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCSRFTokenContext(t *testing.T) {
+	if token := CSRFToken(context.Background()); token != "" {
+		t.Errorf("Expected empty token from bare context, got '%s'", token)
+	}
+
+	ctx := WithCSRFToken(context.Background(), "token")
+	if token := CSRFToken(ctx); token != "token" {
+		t.Errorf("Expected 'token', got '%s'", token)
+	}
+}
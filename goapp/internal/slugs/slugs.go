@@ -0,0 +1,216 @@
+// Package slugs makes URL slugs from titles and names, keeps them unique
+// within a table and remembers old slugs so that links to them can be
+// redirected.
+package slugs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"goapp/internal/models"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+// MaxLength is the longest slug Make and Unique return
+const MaxLength = 255
+
+// ErrNotFound is returned by Resolve for slugs nothing used to have
+var ErrNotFound = errors.New("slug not found")
+
+// reserved slugs name pages and actions next to posts and tags, such as
+// /posts/new, rather than content
+var reserved = map[string]bool{
+	"admin": true, "api": true, "comments": true, "create": true, "delete": true,
+	"edit": true, "feed": true, "new": true, "posts": true, "revisions": true,
+	"rss": true, "search": true, "settings": true, "static": true, "status": true,
+	"tags": true, "users": true,
+}
+
+// IsReserved reports whether slug may not be used for content
+func IsReserved(slug string) bool {
+	return reserved[slug]
+}
+
+// transliterations spell letters that do not decompose into ASCII letters
+// and accents
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'þ': "th",
+	'ł': "l", 'ı': "i", 'ħ': "h", 'ŋ': "ng", '&': " and ",
+	// Greek
+	'α': "a", 'β': "b", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th",
+	'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p",
+	'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+	'ω': "o",
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g",
+}
+
+// Make turns text into a slug: lowercase ASCII letters and digits separated
+// by single dashes. Accents are dropped, Greek and Cyrillic are spelled in
+// Latin letters and other characters separate words.
+func Make(text string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(text) {
+		for _, c := range transliterate(r) {
+			switch {
+			case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+				if dash && b.Len() > 0 {
+					b.WriteByte('-')
+				}
+				b.WriteRune(c)
+				dash = false
+			default:
+				dash = true
+			}
+		}
+	}
+	return truncate(b.String(), MaxLength)
+}
+
+// transliterate spells r in ASCII, or returns a space when it cannot
+func transliterate(r rune) string {
+	if s, ok := transliterations[r]; ok {
+		return s
+	}
+	if r < utf8.RuneSelf {
+		return string(r)
+	}
+	var b strings.Builder
+	for _, d := range norm.NFKD.String(string(r)) {
+		if s, ok := transliterations[d]; ok {
+			b.WriteString(s)
+		} else if d < utf8.RuneSelf {
+			b.WriteRune(d)
+		}
+	}
+	if b.Len() == 0 {
+		return " "
+	}
+	return b.String()
+}
+
+func truncate(slug string, max int) string {
+	if len(slug) <= max {
+		return slug
+	}
+	return strings.TrimRight(slug[:max], "-")
+}
+
+// Unique returns base, or base with the lowest suffix from "-2" on, that is
+// not reserved and that no other row of kind has now or had before. kind
+// names both the table and the resource type of old slugs; id is the row
+// the slug is for, 0 for new rows. Soft-deleted rows keep their slugs.
+func Unique(tx *gorm.DB, kind, base string, id uint) (string, error) {
+	pattern := base + "-%"
+	var taken []string
+	err := tx.Table(kind).Where("(slug = ? OR slug LIKE ?) AND id <> ?", base, pattern, id).Pluck("slug", &taken).Error
+	if err != nil {
+		return "", fmt.Errorf("failed to check slugs: %w", err)
+	}
+	var old []string
+	err = tx.Model(&models.SlugRedirect{}).
+		Where("resource_type = ? AND (slug = ? OR slug LIKE ?) AND resource_id <> ?", kind, base, pattern, id).
+		Pluck("slug", &old).Error
+	if err != nil {
+		return "", fmt.Errorf("failed to check old slugs: %w", err)
+	}
+
+	used := make(map[string]bool, len(taken)+len(old))
+	for _, slug := range append(taken, old...) {
+		used[slug] = true
+	}
+	slug := base
+	for n := 2; used[slug] || IsReserved(slug); n++ {
+		suffix := "-" + strconv.Itoa(n)
+		slug = truncate(base, MaxLength-len(suffix)) + suffix
+	}
+	return slug, nil
+}
+
+// Taken reports whether a row of kind other than id has slug now or had it before
+func Taken(tx *gorm.DB, kind, slug string, id uint) (bool, error) {
+	var count int64
+	err := tx.Table(kind).Where("slug = ? AND id <> ?", slug, id).Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check slug: %w", err)
+	}
+	if count > 0 {
+		return true, nil
+	}
+	err = tx.Model(&models.SlugRedirect{}).
+		Where("resource_type = ? AND slug = ? AND resource_id <> ?", kind, slug, id).Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check old slugs: %w", err)
+	}
+	return count > 0, nil
+}
+
+// Move records that row id of kind changed its slug from one to another:
+// the old slug redirects to the row, and the new one no longer redirects
+func Move(tx *gorm.DB, kind string, id uint, from, to string) error {
+	if from == to {
+		return nil
+	}
+	if err := Release(tx, kind, to); err != nil {
+		return err
+	}
+	if err := tx.Create(&models.SlugRedirect{ResourceType: kind, Slug: from, ResourceID: id}).Error; err != nil {
+		return fmt.Errorf("failed to record old slug: %w", err)
+	}
+	return nil
+}
+
+// Transfer points the old slugs of row from of kind, and its current slug,
+// at row to, as when merging one into the other
+func Transfer(tx *gorm.DB, kind string, from, to uint, slug string) error {
+	err := tx.Model(&models.SlugRedirect{}).Where("resource_type = ? AND resource_id = ?", kind, from).
+		Update("resource_id", to).Error
+	if err != nil {
+		return fmt.Errorf("failed to move old slugs: %w", err)
+	}
+	if err := tx.Create(&models.SlugRedirect{ResourceType: kind, Slug: slug, ResourceID: to}).Error; err != nil {
+		return fmt.Errorf("failed to record old slug: %w", err)
+	}
+	return nil
+}
+
+// Release stops slug redirecting to whichever row of kind had it before,
+// so that a row may have it now
+func Release(tx *gorm.DB, kind, slug string) error {
+	err := tx.Where("resource_type = ? AND slug = ?", kind, slug).Delete(&models.SlugRedirect{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to remove old slug: %w", err)
+	}
+	return nil
+}
+
+// Forget removes the old slugs of row id of kind, so others may use them
+func Forget(tx *gorm.DB, kind string, id uint) error {
+	err := tx.Where("resource_type = ? AND resource_id = ?", kind, id).Delete(&models.SlugRedirect{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to remove old slugs: %w", err)
+	}
+	return nil
+}
+
+// Resolve returns the id of the row of kind that used to have slug
+func Resolve(tx *gorm.DB, kind, slug string) (uint, error) {
+	var redirect models.SlugRedirect
+	err := tx.Where("resource_type = ? AND slug = ?", kind, slug).Take(&redirect).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve old slug: %w", err)
+	}
+	return redirect.ResourceID, nil
+}
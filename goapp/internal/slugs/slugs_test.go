@@ -0,0 +1,89 @@
+package slugs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"goapp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMake(t *testing.T) {
+	tests := map[string]string{
+		"Hello, World!":          "hello-world",
+		"  Go 1.23 -- news ":     "go-1-23-news",
+		"Ünïcode":                "unicode",
+		"Straße & Smørrebrød":    "strasse-and-smorrebrod",
+		"Привет, мир":            "privet-mir",
+		"Λάμδα":                  "lamda",
+		"日本語":                    "",
+		strings.Repeat("a", 300): strings.Repeat("a", MaxLength),
+	}
+	for text, want := range tests {
+		if got := Make(text); got != want {
+			t.Errorf("Make(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestUnique(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Post{}, &models.SlugRedirect{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	user := &models.User{Email: "jane@example.com", Username: "jane", PasswordHash: "hash"}
+	db.Create(user)
+	for _, slug := range []string{"hello", "hello-2"} {
+		db.Create(&models.Post{Title: slug, Slug: slug, UserID: user.ID})
+	}
+
+	tests := []struct {
+		base string
+		id   uint
+		want string
+	}{
+		{"hello", 0, "hello-3"},
+		{"hello", 1, "hello"},
+		{"world", 0, "world"},
+		{"new", 0, "new-2"},
+	}
+	for _, tt := range tests {
+		got, err := Unique(db, "posts", tt.base, tt.id)
+		if err != nil || got != tt.want {
+			t.Errorf("Unique(%q, %d) = %q, %v, want %q", tt.base, tt.id, got, err, tt.want)
+		}
+	}
+
+	// Old slugs stay with their row
+	if err := Move(db, "posts", 1, "hello", "hi"); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	db.Model(&models.Post{}).Where("id = ?", 1).Update("slug", "hi")
+	if got, _ := Unique(db, "posts", "hello", 0); got != "hello-3" {
+		t.Errorf("Expected old slug to be skipped, got %q", got)
+	}
+	if taken, _ := Taken(db, "posts", "hello", 2); !taken {
+		t.Error("Expected old slug to be taken for other rows")
+	}
+	if id, err := Resolve(db, "posts", "hello"); err != nil || id != 1 {
+		t.Errorf("Resolve() = %d, %v, want 1", id, err)
+	}
+
+	if err := Transfer(db, "posts", 1, 2, "hi"); err != nil {
+		t.Fatalf("Transfer() error = %v", err)
+	}
+	if id, _ := Resolve(db, "posts", "hello"); id != 2 {
+		t.Errorf("Expected old slugs to move to row 2, got %d", id)
+	}
+	if err := Forget(db, "posts", 2); err != nil {
+		t.Fatalf("Forget() error = %v", err)
+	}
+	if _, err := Resolve(db, "posts", "hi"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after Forget, got %v", err)
+	}
+}
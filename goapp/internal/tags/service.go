@@ -0,0 +1,311 @@
+// Package tags manages the tags of posts: creating, renaming, merging and
+// deleting them, attaching them to posts and suggesting them while typing.
+package tags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"goapp/internal/auth"
+	"goapp/internal/models"
+	"goapp/internal/query"
+	"goapp/internal/slugs"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when no tag has the requested ID or slug
+var ErrNotFound = errors.New("tag not found")
+
+// maxNameLength limits tag names, in characters
+const maxNameLength = 50
+
+// resourceType names tags in slug history
+const resourceType = "tags"
+
+// TagCount is a tag with the number of published posts carrying it
+type TagCount struct {
+	models.Tag
+	PostCount int64 `json:"post_count"`
+}
+
+// Service manages tags and their assignment to posts
+type Service interface {
+	// List returns every tag by name, with the number of published posts carrying it
+	List(ctx context.Context) ([]TagCount, error)
+	// GetBySlug returns the tag with slug
+	GetBySlug(ctx context.Context, slug string) (*models.Tag, error)
+	// GetByOldSlug returns the tag that used to have slug, before it was
+	// renamed or merged into another, or ErrNotFound
+	GetByOldSlug(ctx context.Context, slug string) (*models.Tag, error)
+	// Suggest returns up to limit tags whose name starts with prefix, by name
+	Suggest(ctx context.Context, prefix string, limit int) ([]models.Tag, error)
+	// Create stores a tag named name, slugged automatically. Tags whose
+	// names make the same slug are the same tag.
+	Create(ctx context.Context, name string) (*models.Tag, error)
+	// Rename changes the name and slug of tag. A name or slug another tag
+	// already has is rejected; use Merge to combine the two.
+	Rename(ctx context.Context, tag *models.Tag, name string) (*models.Tag, error)
+	// Merge moves the posts of source to target and deletes source
+	Merge(ctx context.Context, source, target *models.Tag) error
+	// Delete removes tag from its posts and deletes it
+	Delete(ctx context.Context, tag *models.Tag) error
+	// Attach adds the tags named in names to post, creating missing ones,
+	// and returns the post's tags. Names of renamed tags attach the tag
+	// under its new name.
+	Attach(ctx context.Context, post *models.Post, names []string) ([]models.Tag, error)
+	// Detach removes tag from post and returns the post's remaining tags
+	Detach(ctx context.Context, post *models.Post, tag *models.Tag) ([]models.Tag, error)
+}
+
+// service implements Service on the tags and post_tags tables
+type service struct {
+	db *gorm.DB
+}
+
+// NewService creates a tags Service
+func NewService(db *gorm.DB) Service {
+	return &service{db: db}
+}
+
+// List implements Service
+func (s *service) List(ctx context.Context) ([]TagCount, error) {
+	var counts []TagCount
+	err := s.db.WithContext(ctx).Model(&models.Tag{}).
+		Select("tags.*, COUNT(posts.id) AS post_count").
+		Joins("LEFT JOIN post_tags ON post_tags.tag_id = tags.id").
+		Joins("LEFT JOIN posts ON posts.id = post_tags.post_id AND posts.published = ? AND posts.deleted_at IS NULL", true).
+		Group("tags.id").
+		Order("tags.name").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	return counts, nil
+}
+
+// GetBySlug implements Service
+func (s *service) GetBySlug(ctx context.Context, slug string) (*models.Tag, error) {
+	var tag models.Tag
+	err := s.db.WithContext(ctx).Where("slug = ?", slug).First(&tag).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tag: %w", err)
+	}
+	return &tag, nil
+}
+
+// GetByOldSlug implements Service
+func (s *service) GetByOldSlug(ctx context.Context, slug string) (*models.Tag, error) {
+	id, err := slugs.Resolve(s.db.WithContext(ctx), resourceType, slug)
+	if errors.Is(err, slugs.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tag models.Tag
+	if err := s.db.WithContext(ctx).First(&tag, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to load tag: %w", err)
+	}
+	return &tag, nil
+}
+
+// Suggest implements Service
+func (s *service) Suggest(ctx context.Context, prefix string, limit int) ([]models.Tag, error) {
+	var tags []models.Tag
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" {
+		return tags, nil
+	}
+	err := s.db.WithContext(ctx).
+		Where("LOWER(name) LIKE ? ESCAPE '\\'", query.EscapeLike(prefix)+"%").
+		Order("name").Limit(limit).Find(&tags).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest tags: %w", err)
+	}
+	return tags, nil
+}
+
+// Create implements Service
+func (s *service) Create(ctx context.Context, name string) (*models.Tag, error) {
+	tag := &models.Tag{Name: strings.TrimSpace(name)}
+	tag.Slug = Slug(tag.Name)
+	if err := s.validate(ctx, tag); err != nil {
+		return nil, err
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// A new tag takes its slug back from a tag renamed away from it
+		if err := slugs.Release(tx, resourceType, tag.Slug); err != nil {
+			return err
+		}
+		if err := tx.Create(tag).Error; err != nil {
+			return saveError(err, "failed to create tag")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// Rename implements Service
+func (s *service) Rename(ctx context.Context, tag *models.Tag, name string) (*models.Tag, error) {
+	renamed := *tag
+	renamed.Name = strings.TrimSpace(name)
+	renamed.Slug = Slug(renamed.Name)
+
+	if err := s.validate(ctx, &renamed); err != nil {
+		return nil, err
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&renamed).Select("name", "slug").Updates(&renamed).Error; err != nil {
+			return saveError(err, "failed to rename tag")
+		}
+		return slugs.Move(tx, resourceType, tag.ID, tag.Slug, renamed.Slug)
+	})
+	if err != nil {
+		return nil, err
+	}
+	*tag = renamed
+	return tag, nil
+}
+
+// Merge implements Service
+func (s *service) Merge(ctx context.Context, source, target *models.Tag) error {
+	if source.ID == target.ID {
+		return &auth.ValidationError{Fields: map[string]string{"into": "must be another tag"}}
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Posts carrying both tags keep a single row for target
+		err := tx.Exec(`INSERT INTO post_tags (post_id, tag_id)
+			SELECT post_id, ? FROM post_tags
+			WHERE tag_id = ? AND post_id NOT IN (SELECT post_id FROM post_tags WHERE tag_id = ?)`,
+			target.ID, source.ID, target.ID).Error
+		if err != nil {
+			return fmt.Errorf("failed to move posts to tag: %w", err)
+		}
+		// Links to source lead to target
+		if err := slugs.Transfer(tx, resourceType, source.ID, target.ID, source.Slug); err != nil {
+			return err
+		}
+		return s.delete(tx, source)
+	})
+}
+
+// Delete implements Service
+func (s *service) Delete(ctx context.Context, tag *models.Tag) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return s.delete(tx, tag)
+	})
+}
+
+// delete removes tag and its post_tags rows. Tags are deleted for good so
+// that their name and slug can be used again.
+func (s *service) delete(tx *gorm.DB, tag *models.Tag) error {
+	if err := tx.Exec("DELETE FROM post_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+		return fmt.Errorf("failed to detach tag: %w", err)
+	}
+	if err := tx.Unscoped().Delete(tag).Error; err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+	return slugs.Forget(tx, resourceType, tag.ID)
+}
+
+// Attach implements Service
+func (s *service) Attach(ctx context.Context, post *models.Post, names []string) ([]models.Tag, error) {
+	var attach []models.Tag
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		tag, err := s.GetBySlug(ctx, Slug(name))
+		if errors.Is(err, ErrNotFound) {
+			tag, err = s.GetByOldSlug(ctx, Slug(name))
+		}
+		if errors.Is(err, ErrNotFound) {
+			tag, err = s.Create(ctx, name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		attach = append(attach, *tag)
+	}
+
+	if len(attach) > 0 {
+		if err := s.db.WithContext(ctx).Model(post).Association("Tags").Append(attach); err != nil {
+			return nil, fmt.Errorf("failed to attach tags: %w", err)
+		}
+	}
+	return s.tagsOf(ctx, post)
+}
+
+// Detach implements Service
+func (s *service) Detach(ctx context.Context, post *models.Post, tag *models.Tag) ([]models.Tag, error) {
+	if err := s.db.WithContext(ctx).Model(post).Association("Tags").Delete(tag); err != nil {
+		return nil, fmt.Errorf("failed to detach tag: %w", err)
+	}
+	return s.tagsOf(ctx, post)
+}
+
+func (s *service) tagsOf(ctx context.Context, post *models.Post) ([]models.Tag, error) {
+	var tags []models.Tag
+	if err := s.db.WithContext(ctx).Model(post).Order("name").Association("Tags").Find(&tags); err != nil {
+		return nil, fmt.Errorf("failed to load tags: %w", err)
+	}
+	post.Tags = tags
+	return tags, nil
+}
+
+// validate checks the name of tag and that no other tag has its name or slug
+func (s *service) validate(ctx context.Context, tag *models.Tag) error {
+	fields := map[string]string{}
+	switch {
+	case tag.Name == "":
+		fields["name"] = "is required"
+	case utf8.RuneCountInString(tag.Name) > maxNameLength:
+		fields["name"] = fmt.Sprintf("must be at most %d characters", maxNameLength)
+	case tag.Slug == "":
+		fields["name"] = "must contain a letter or digit"
+	default:
+		var count int64
+		err := s.db.WithContext(ctx).Model(&models.Tag{}).
+			Where("(slug = ? OR name = ?) AND id <> ?", tag.Slug, tag.Name, tag.ID).Count(&count).Error
+		if err != nil {
+			return fmt.Errorf("failed to check tag name: %w", err)
+		}
+		if count > 0 {
+			fields["name"] = "is already taken"
+		}
+	}
+
+	if len(fields) > 0 {
+		return &auth.ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// Slug returns the slug of a tag named name. Reserved slugs get a "-2"
+// suffix, so that names always make the same slug.
+func Slug(name string) string {
+	slug := slugs.Make(name)
+	if slugs.IsReserved(slug) {
+		return slug + "-2"
+	}
+	return slug
+}
+
+// saveError reports a name another tag took since it was checked as a
+// validation error, and wraps other errors with message
+func saveError(err error, message string) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return &auth.ValidationError{Fields: map[string]string{"name": "is already taken"}}
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
@@ -0,0 +1,153 @@
+package tags
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"goapp/internal/auth"
+	"goapp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestService(t *testing.T) (Service, *gorm.DB, []*models.Post) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Post{}, &models.Tag{}, &models.SlugRedirect{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	user := &models.User{Email: "jane@example.com", Username: "jane", PasswordHash: "hash", Active: true}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	var created []*models.Post
+	for _, slug := range []string{"first", "second", "draft"} {
+		post := &models.Post{Title: slug, Slug: slug, Published: slug != "draft", UserID: user.ID}
+		if err := db.Create(post).Error; err != nil {
+			t.Fatalf("Failed to create post: %v", err)
+		}
+		created = append(created, post)
+	}
+	return NewService(db), db, created
+}
+
+func counts(t *testing.T, s Service) map[string]int64 {
+	t.Helper()
+	list, err := s.List(context.Background())
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	result := map[string]int64{}
+	for _, tag := range list {
+		result[tag.Slug] = tag.PostCount
+	}
+	return result
+}
+
+func TestAttachAndCounts(t *testing.T) {
+	ctx := context.Background()
+	s, _, posts := setupTestService(t)
+
+	tags, err := s.Attach(ctx, posts[0], []string{"Go", " Web Dev ", ""})
+	if err != nil {
+		t.Fatalf("Attach() error = %v", err)
+	}
+	if len(tags) != 2 || tags[0].Slug != "go" || tags[1].Slug != "web-dev" {
+		t.Errorf("Expected go and web-dev, got %+v", tags)
+	}
+	// Names matching an existing slug reuse the tag
+	if _, err := s.Attach(ctx, posts[1], []string{"go"}); err != nil {
+		t.Fatalf("Attach() error = %v", err)
+	}
+	if _, err := s.Attach(ctx, posts[2], []string{"Go"}); err != nil {
+		t.Fatalf("Attach() error = %v", err)
+	}
+
+	got := counts(t, s)
+	if len(got) != 2 || got["go"] != 2 || got["web-dev"] != 1 {
+		t.Errorf("Expected go to count the published posts only, got %v", got)
+	}
+
+	go1, _ := s.GetBySlug(ctx, "go")
+	tags, err = s.Detach(ctx, posts[0], go1)
+	if err != nil || len(tags) != 1 || tags[0].Slug != "web-dev" {
+		t.Errorf("Expected only web-dev after detaching, got %+v, %v", tags, err)
+	}
+
+	suggestions, err := s.Suggest(ctx, "W", 5)
+	if err != nil || len(suggestions) != 1 || suggestions[0].Name != "Web Dev" {
+		t.Errorf("Expected Web Dev to be suggested, got %+v, %v", suggestions, err)
+	}
+	if suggestions, _ := s.Suggest(ctx, "%", 5); len(suggestions) != 0 {
+		t.Errorf("Expected wildcards to match literally, got %+v", suggestions)
+	}
+}
+
+func TestRenameAndMerge(t *testing.T) {
+	ctx := context.Background()
+	s, _, posts := setupTestService(t)
+	s.Attach(ctx, posts[0], []string{"golang", "Go"})
+	s.Attach(ctx, posts[1], []string{"golang"})
+
+	var validationErr *auth.ValidationError
+	if _, err := s.Create(ctx, "GO"); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a duplicate slug to be rejected, got %v", err)
+	}
+	if _, err := s.Create(ctx, "!!!"); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a name without letters to be rejected, got %v", err)
+	}
+
+	golang, _ := s.GetBySlug(ctx, "golang")
+	renamed, err := s.Rename(ctx, golang, "Go Lang")
+	if err != nil || renamed.Slug != "go-lang" || renamed.ID != golang.ID {
+		t.Fatalf("Expected the tag to be renamed, got %+v, %v", renamed, err)
+	}
+
+	// Renaming onto an existing tag is rejected rather than merging
+	if _, err := s.Rename(ctx, golang, "go"); !errors.As(err, &validationErr) || validationErr.Fields["name"] != "is already taken" {
+		t.Fatalf("Expected the name to be taken, got %v", err)
+	}
+	if golang.Slug != "go-lang" {
+		t.Errorf("Expected a rejected rename to leave the tag alone, got %+v", golang)
+	}
+
+	// Merging does not duplicate posts carrying both tags
+	merged, _ := s.GetBySlug(ctx, "go")
+	if err := s.Merge(ctx, golang, merged); err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	got := counts(t, s)
+	if len(got) != 1 || got["go"] != 2 {
+		t.Errorf("Expected go on both posts, got %v", got)
+	}
+	if _, err := s.GetBySlug(ctx, "go-lang"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the merged tag to be gone, got %v", err)
+	}
+	// Old slugs of both the renamed and the merged tag lead to the target
+	for _, slug := range []string{"golang", "go-lang"} {
+		if old, err := s.GetByOldSlug(ctx, slug); err != nil || old.ID != merged.ID {
+			t.Errorf("Expected %q to lead to go, got %+v, %v", slug, old, err)
+		}
+	}
+	if tags, err := s.Attach(ctx, posts[2], []string{"Golang"}); err != nil || len(tags) != 1 || tags[0].ID != merged.ID {
+		t.Errorf("Expected an old name to attach go, got %+v, %v", tags, err)
+	}
+	if tag, err := s.Create(ctx, "Admin"); err != nil || tag.Slug != "admin-2" {
+		t.Errorf("Expected a reserved slug to get a suffix, got %+v, %v", tag, err)
+	}
+
+	// Deleted names can be used again
+	if err := s.Delete(ctx, merged); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.Create(ctx, "Go"); err != nil {
+		t.Errorf("Expected the name to be free after delete, got %v", err)
+	}
+	if _, err := s.GetByOldSlug(ctx, "golang"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected old slugs to be forgotten after delete, got %v", err)
+	}
+}
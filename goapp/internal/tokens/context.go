@@ -0,0 +1,16 @@
+package tokens
+
+import "context"
+
+type claimsContextKey struct{}
+
+// WithClaims returns a copy of ctx carrying verified access token claims
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext returns the access token claims stored in ctx, or nil
+func ClaimsFromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(claimsContextKey{}).(*Claims)
+	return claims
+}
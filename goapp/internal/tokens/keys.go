@@ -0,0 +1,262 @@
+// Package tokens issues and verifies the JWT access tokens and opaque refresh
+// tokens used to authenticate JSON API clients
+package tokens
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+	"goapp/internal/config"
+)
+
+// minRSABits is the smallest RSA modulus accepted for signing or verification
+const minRSABits = 2048
+
+// ErrNotConfigured is returned by LoadKeySet when neither a secret nor a private key is configured
+var ErrNotConfigured = errors.New("no JWT signing key configured")
+
+// key is a single signing or verification key
+type key struct {
+	id        string
+	method    jwt.SigningMethod
+	signKey   interface{} // nil for verification-only keys
+	verifyKey interface{}
+	public    crypto.PublicKey // nil for symmetric keys, which are never published
+}
+
+// KeySet signs tokens with its current key and verifies tokens signed by any
+// of its keys, identified by the kid header
+type KeySet struct {
+	current *key
+	keys    map[string]*key
+}
+
+// NewKeySet creates a KeySet signing with signingKey and additionally
+// accepting verificationKeys. Keys are []byte HMAC secrets, RSA or Ed25519
+// private keys, or (for verification only) RSA or Ed25519 public keys.
+func NewKeySet(signingKey interface{}, verificationKeys ...interface{}) (*KeySet, error) {
+	current, err := newKey(signingKey)
+	if err != nil {
+		return nil, err
+	}
+	if current.signKey == nil {
+		return nil, errors.New("signing key must be a secret or private key")
+	}
+
+	ks := &KeySet{current: current, keys: map[string]*key{current.id: current}}
+	for _, raw := range verificationKeys {
+		k, err := newKey(raw)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := ks.keys[k.id]; !exists {
+			ks.keys[k.id] = k
+		}
+	}
+	return ks, nil
+}
+
+// LoadKeySet builds a KeySet from configuration, reading PEM key files from disk
+func LoadKeySet(cfg config.JWTConfig) (*KeySet, error) {
+	var verification []interface{}
+	for _, secret := range cfg.PreviousSecrets {
+		if secret != "" {
+			verification = append(verification, []byte(secret))
+		}
+	}
+	for _, path := range cfg.VerificationKeyFiles {
+		k, err := readPEMKey(path)
+		if err != nil {
+			return nil, err
+		}
+		verification = append(verification, k)
+	}
+
+	switch {
+	case cfg.PrivateKeyFile != "":
+		signing, err := readPEMKey(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Secret != "" {
+			verification = append(verification, []byte(cfg.Secret))
+		}
+		return NewKeySet(signing, verification...)
+	case cfg.Secret != "":
+		return NewKeySet([]byte(cfg.Secret), verification...)
+	default:
+		return nil, ErrNotConfigured
+	}
+}
+
+// Sign returns the compact serialization of claims signed with the current key
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.current.method, claims)
+	token.Header["kid"] = ks.current.id
+	signed, err := token.SignedString(ks.current.signKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return signed, nil
+}
+
+// Keyfunc resolves the verification key for a parsed token by its kid header
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	k, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %q for key %q", token.Method.Alg(), kid)
+	}
+	return k.verifyKey, nil
+}
+
+// Algorithms lists the signing algorithms of all keys in the set
+func (ks *KeySet) Algorithms() []string {
+	seen := map[string]bool{}
+	var algs []string
+	for _, k := range ks.keys {
+		if alg := k.method.Alg(); !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	return algs
+}
+
+// JWKS returns the public keys of the set as a JSON Web Key Set (RFC 7517).
+// HMAC secrets are never published.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	// The current key is listed first so clients that pick the first key find it
+	ordered := []*key{ks.current}
+	for _, k := range ks.keys {
+		if k != ks.current {
+			ordered = append(ordered, k)
+		}
+	}
+
+	for _, k := range ordered {
+		switch pub := k.public.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA", Use: "sig", Alg: k.method.Alg(), Kid: k.id,
+				N: base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "OKP", Use: "sig", Alg: k.method.Alg(), Kid: k.id,
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return set
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK is a public JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+func newKey(raw interface{}) (*key, error) {
+	switch k := raw.(type) {
+	case []byte:
+		if len(k) < 32 {
+			return nil, errors.New("HMAC secret must be at least 32 bytes")
+		}
+		return &key{id: keyID("hs256", k), method: jwt.SigningMethodHS256, signKey: k, verifyKey: k}, nil
+	case *rsa.PrivateKey:
+		pub, err := newKey(&k.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		pub.signKey = k
+		return pub, nil
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits", minRSABits)
+		}
+		return &key{id: publicKeyID(k), method: jwt.SigningMethodRS256, verifyKey: k, public: k}, nil
+	case ed25519.PrivateKey:
+		pub, err := newKey(k.Public())
+		if err != nil {
+			return nil, err
+		}
+		pub.signKey = k
+		return pub, nil
+	case ed25519.PublicKey:
+		return &key{id: publicKeyID(k), method: jwt.SigningMethodEdDSA, verifyKey: k, public: k}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", raw)
+	}
+}
+
+// publicKeyID derives a stable kid from the DER encoding of a public key
+func publicKeyID(pub crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return ""
+	}
+	return keyID("pk", der)
+}
+
+func keyID(kind string, material []byte) string {
+	sum := sha256.Sum256(append([]byte(kind+":"), material...))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+// readPEMKey reads an RSA or Ed25519 key from a PEM file
+func readPEMKey(path string) (interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+
+	var k interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		k, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		k, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		k, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		k, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in %s", block.Type, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT key %s: %w", path, err)
+	}
+	return k, nil
+}
@@ -0,0 +1,189 @@
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"goapp/internal/config"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func testJWTConfig() config.JWTConfig {
+	return config.JWTConfig{
+		Issuer:            "goapp",
+		Audience:          "goapp-api",
+		Expiration:        15 * time.Minute,
+		RefreshExpiration: 24 * time.Hour,
+		Leeway:            time.Second,
+	}
+}
+
+func testClaims() *Claims {
+	now := time.Now()
+	return &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "goapp",
+			Subject:   "42",
+			Audience:  jwt.ClaimStrings{"goapp-api"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+		Username: "jane",
+	}
+}
+
+func generateKeys(t *testing.T) (*rsa.PrivateKey, ed25519.PrivateKey) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+	return rsaKey, edKey
+}
+
+func TestKeySetSignAndVerify(t *testing.T) {
+	rsaKey, edKey := generateKeys(t)
+
+	tests := []struct {
+		name string
+		key  interface{}
+		alg  string
+	}{
+		{"HS256", testSecret, "HS256"},
+		{"RS256", rsaKey, "RS256"},
+		{"EdDSA", edKey, "EdDSA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := NewKeySet(tt.key)
+			if err != nil {
+				t.Fatalf("NewKeySet() error = %v", err)
+			}
+
+			signed, err := keys.Sign(testClaims())
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+			if !LooksLikeJWT(signed) {
+				t.Errorf("Expected compact JWT, got %q", signed)
+			}
+
+			claims, err := NewVerifier(keys, testJWTConfig()).Verify(signed)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if claims.Username != "jane" {
+				t.Errorf("Expected username jane, got %q", claims.Username)
+			}
+
+			token, _, _ := jwt.NewParser().ParseUnverified(signed, &Claims{})
+			if token.Method.Alg() != tt.alg {
+				t.Errorf("Expected alg %s, got %s", tt.alg, token.Method.Alg())
+			}
+		})
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	rsaKey, edKey := generateKeys(t)
+
+	oldKeys, _ := NewKeySet(rsaKey)
+	oldToken, _ := oldKeys.Sign(testClaims())
+
+	// The new set signs with Ed25519 and still accepts the old RSA public key
+	newKeys, err := NewKeySet(edKey, &rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("NewKeySet() error = %v", err)
+	}
+	if _, err := NewVerifier(newKeys, testJWTConfig()).Verify(oldToken); err != nil {
+		t.Errorf("Expected token signed with the previous key to verify, got %v", err)
+	}
+
+	jwks := newKeys.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("Expected 2 published keys, got %d", len(jwks.Keys))
+	}
+	if jwks.Keys[0].Kty != "OKP" || jwks.Keys[0].Crv != "Ed25519" || jwks.Keys[0].X == "" {
+		t.Errorf("Expected current Ed25519 key first, got %+v", jwks.Keys[0])
+	}
+	if jwks.Keys[1].Kty != "RSA" || jwks.Keys[1].N == "" || jwks.Keys[1].E != "AQAB" {
+		t.Errorf("Expected previous RSA key, got %+v", jwks.Keys[1])
+	}
+
+	// Once the old key is dropped its tokens are rejected
+	rotated, _ := NewKeySet(edKey)
+	if _, err := NewVerifier(rotated, testJWTConfig()).Verify(oldToken); err == nil {
+		t.Error("Expected token signed with a removed key to be rejected")
+	}
+}
+
+func TestKeySetJWKSExcludesSecrets(t *testing.T) {
+	keys, _ := NewKeySet(testSecret)
+	if jwks := keys.JWKS(); len(jwks.Keys) != 0 {
+		t.Errorf("Expected HMAC secrets not to be published, got %+v", jwks.Keys)
+	}
+}
+
+func TestNewKeySetInvalid(t *testing.T) {
+	rsaKey, _ := generateKeys(t)
+
+	for name, key := range map[string]interface{}{
+		"ShortSecret":   []byte("too short"),
+		"PublicSigning": &rsaKey.PublicKey,
+		"Unsupported":   "string",
+	} {
+		if _, err := NewKeySet(key); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, edKey := generateKeys(t)
+
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	current := filepath.Join(dir, "current.pem")
+	os.WriteFile(current, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edDER}), 0o600)
+
+	rsaDER, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	previous := filepath.Join(dir, "previous.pem")
+	os.WriteFile(previous, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaDER}), 0o600)
+
+	cfg := testJWTConfig()
+	if _, err := LoadKeySet(cfg); err != ErrNotConfigured {
+		t.Errorf("Expected ErrNotConfigured, got %v", err)
+	}
+
+	cfg.Secret = string(testSecret)
+	cfg.PrivateKeyFile = current
+	cfg.VerificationKeyFiles = []string{previous}
+	keys, err := LoadKeySet(cfg)
+	if err != nil {
+		t.Fatalf("LoadKeySet() error = %v", err)
+	}
+	if keys.current.method.Alg() != "EdDSA" {
+		t.Errorf("Expected private key file to take precedence, got %s", keys.current.method.Alg())
+	}
+	if len(keys.keys) != 3 {
+		t.Errorf("Expected 3 keys (Ed25519, RSA, HMAC), got %d", len(keys.keys))
+	}
+
+	cfg.VerificationKeyFiles = []string{filepath.Join(dir, "missing.pem")}
+	if _, err := LoadKeySet(cfg); err == nil {
+		t.Error("Expected error for missing key file")
+	}
+}
@@ -0,0 +1,311 @@
+package tokens
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"goapp/internal/config"
+	"goapp/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	refreshTokenBytes = 32
+	familyIDBytes     = 16
+)
+
+var (
+	// ErrInvalidToken is returned when an access token fails verification
+	ErrInvalidToken = errors.New("invalid access token")
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when a rotated refresh token is presented
+	// again; the token family is revoked because the token has likely leaked
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+)
+
+// Claims are the claims of an access token. The subject is the user ID.
+type Claims struct {
+	jwt.RegisteredClaims
+	Username string `json:"username,omitempty"`
+	Version  uint   `json:"ver,omitempty"` // the user's TokenVersion when issued
+}
+
+// UserID returns the user ID from the subject claim
+func (c *Claims) UserID() (uint, error) {
+	id, err := strconv.ParseUint(c.Subject, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid subject %q: %w", c.Subject, err)
+	}
+	return uint(id), nil
+}
+
+// Pair is an access token with the refresh token that renews it
+type Pair struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int    `json:"expires_in"`
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresIn int    `json:"refresh_expires_in"`
+}
+
+// Verifier verifies access tokens
+type Verifier interface {
+	Verify(accessToken string) (*Claims, error)
+}
+
+// Service issues, refreshes and revokes token pairs
+type Service interface {
+	Verifier
+	Subjects
+	// Issue starts a new token family for the user
+	Issue(ctx context.Context, user *models.User) (*Pair, error)
+	// Refresh exchanges a refresh token for a new pair, rotating the refresh token
+	Refresh(ctx context.Context, refreshToken string) (*Pair, error)
+	// Revoke revokes the family of the refresh token, e.g. on logout
+	Revoke(ctx context.Context, refreshToken string) error
+	// DeleteExpired removes expired refresh tokens and returns how many were deleted
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// Subjects loads the users access tokens were issued to
+type Subjects interface {
+	// Subject returns the user of verified claims with their roles and
+	// permissions, or ErrInvalidToken when the user is gone, inactive or
+	// has signed out everywhere since the token was issued
+	Subject(ctx context.Context, claims *Claims) (*models.User, error)
+}
+
+// verifier implements Verifier; it needs no database
+type verifier struct {
+	keys   *KeySet
+	parser *jwt.Parser
+	now    func() time.Time
+}
+
+// NewVerifier creates a Verifier accepting tokens signed by any key in keys
+// for the configured issuer and audience
+func NewVerifier(keys *KeySet, cfg config.JWTConfig) Verifier {
+	return newVerifier(keys, cfg)
+}
+
+func newVerifier(keys *KeySet, cfg config.JWTConfig) *verifier {
+	v := &verifier{keys: keys, now: time.Now}
+	v.parser = jwt.NewParser(
+		jwt.WithValidMethods(keys.Algorithms()),
+		jwt.WithIssuer(cfg.Issuer),
+		jwt.WithAudience(cfg.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+		jwt.WithTimeFunc(func() time.Time { return v.now() }),
+	)
+	return v
+}
+
+// Verify implements Verifier
+func (v *verifier) Verify(accessToken string) (*Claims, error) {
+	claims := &Claims{}
+	if _, err := v.parser.ParseWithClaims(accessToken, claims, v.keys.Keyfunc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if _, err := claims.UserID(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+// service implements Service with refresh tokens in the refresh_tokens table
+type service struct {
+	*verifier
+	db  *gorm.DB
+	cfg config.JWTConfig
+}
+
+// NewService creates a token Service signing with keys
+func NewService(db *gorm.DB, keys *KeySet, cfg config.JWTConfig) Service {
+	return &service{verifier: newVerifier(keys, cfg), db: db, cfg: cfg}
+}
+
+// Issue implements Service
+func (s *service) Issue(ctx context.Context, user *models.User) (*Pair, error) {
+	familyID, err := randomString(familyIDBytes, hex.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(ctx, user, familyID)
+}
+
+// Refresh implements Service
+func (s *service) Refresh(ctx context.Context, refreshToken string) (*Pair, error) {
+	var stored models.RefreshToken
+	err := s.db.WithContext(ctx).Preload("User").Where("token_hash = ?", hashToken(refreshToken)).First(&stored).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load refresh token: %w", err)
+	}
+
+	now := s.now()
+	if stored.RevokedAt != nil || stored.Expired(now) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if stored.UsedAt != nil {
+		return nil, s.reused(ctx, stored.FamilyID)
+	}
+
+	// Mark the token used; a concurrent refresh with the same token loses the race and counts as reuse
+	result := s.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", stored.ID).
+		UpdateColumn("used_at", now)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, s.reused(ctx, stored.FamilyID)
+	}
+
+	if stored.User.ID == 0 || !stored.User.Active {
+		if err := s.revokeFamily(ctx, stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return s.issue(ctx, &stored.User, stored.FamilyID)
+}
+
+// Subject implements Subjects
+func (s *service) Subject(ctx context.Context, claims *Claims) (*models.User, error) {
+	id, err := claims.UserID()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	var user models.User
+	err = s.db.WithContext(ctx).Preload("Roles.Permissions").First(&user, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: unknown subject", ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load token subject: %w", err)
+	}
+	if !user.Active || user.TokenVersion != claims.Version {
+		return nil, fmt.Errorf("%w: revoked", ErrInvalidToken)
+	}
+	return &user, nil
+}
+
+// Revoke implements Service
+func (s *service) Revoke(ctx context.Context, refreshToken string) error {
+	var stored models.RefreshToken
+	err := s.db.WithContext(ctx).Where("token_hash = ?", hashToken(refreshToken)).First(&stored).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load refresh token: %w", err)
+	}
+	return s.revokeFamily(ctx, stored.FamilyID)
+}
+
+// DeleteExpired implements Service
+func (s *service) DeleteExpired(ctx context.Context) (int64, error) {
+	result := s.db.WithContext(ctx).Unscoped().Where("expires_at <= ?", s.now()).Delete(&models.RefreshToken{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete expired refresh tokens: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func (s *service) issue(ctx context.Context, user *models.User, familyID string) (*Pair, error) {
+	now := s.now()
+	jti, err := randomString(familyIDBytes, hex.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+
+	access, err := s.keys.Sign(&Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.cfg.Issuer,
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			Audience:  jwt.ClaimStrings{s.cfg.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.cfg.Expiration)),
+			ID:        jti,
+		},
+		Username: user.Username,
+		Version:  user.TokenVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := randomString(refreshTokenBytes, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.WithContext(ctx).Create(&models.RefreshToken{
+		TokenHash: hashToken(refresh),
+		FamilyID:  familyID,
+		UserID:    user.ID,
+		ExpiresAt: now.Add(s.cfg.RefreshExpiration),
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return &Pair{
+		AccessToken:      access,
+		TokenType:        "Bearer",
+		ExpiresIn:        int(s.cfg.Expiration / time.Second),
+		RefreshToken:     refresh,
+		RefreshExpiresIn: int(s.cfg.RefreshExpiration / time.Second),
+	}, nil
+}
+
+func (s *service) reused(ctx context.Context, familyID string) error {
+	if err := s.revokeFamily(ctx, familyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+func (s *service) revokeFamily(ctx context.Context, familyID string) error {
+	err := s.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		UpdateColumn("revoked_at", s.now()).Error
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// LooksLikeJWT reports whether a bearer token has the shape of a compact JWS,
+// so that other bearer credentials can be told apart without parsing
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2 && strings.HasPrefix(token, "eyJ")
+}
+
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return encode(b), nil
+}
+
+// hashToken returns the hex SHA-256 of a refresh token as stored in the database
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
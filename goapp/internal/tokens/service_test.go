@@ -0,0 +1,148 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"goapp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestService(t *testing.T) (*service, *gorm.DB, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.RefreshToken{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	user := &models.User{Email: "jane@example.com", Username: "jane", PasswordHash: "hash", Active: true}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	keys, err := NewKeySet(testSecret)
+	if err != nil {
+		t.Fatalf("NewKeySet() error = %v", err)
+	}
+	return NewService(db, keys, testJWTConfig()).(*service), db, user
+}
+
+func TestServiceIssueAndVerify(t *testing.T) {
+	s, _, user := setupTestService(t)
+
+	pair, err := s.Issue(context.Background(), user)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if pair.TokenType != "Bearer" || pair.ExpiresIn != 900 || pair.RefreshToken == "" {
+		t.Errorf("Unexpected pair: %+v", pair)
+	}
+
+	claims, err := s.Verify(pair.AccessToken)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if id, _ := claims.UserID(); id != user.ID || claims.Username != "jane" {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+
+	// Tokens expire
+	s.now = func() time.Time { return time.Now().Add(time.Hour) }
+	if _, err := s.Verify(pair.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for expired token, got %v", err)
+	}
+}
+
+func TestServiceVerifyRejectsOtherAudience(t *testing.T) {
+	s, _, _ := setupTestService(t)
+
+	claims := testClaims()
+	claims.Audience = []string{"another-api"}
+	signed, _ := s.keys.Sign(claims)
+
+	if _, err := s.Verify(signed); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for foreign audience, got %v", err)
+	}
+}
+
+func TestServiceRefreshRotation(t *testing.T) {
+	ctx := context.Background()
+	s, db, user := setupTestService(t)
+
+	first, _ := s.Issue(ctx, user)
+
+	second, err := s.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Error("Expected refresh token to be rotated")
+	}
+
+	// Replaying the rotated token revokes the whole family
+	if _, err := s.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Expected ErrRefreshTokenReused, got %v", err)
+	}
+	if _, err := s.Refresh(ctx, second.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Expected latest token to be revoked after reuse, got %v", err)
+	}
+
+	var revoked int64
+	db.Model(&models.RefreshToken{}).Where("revoked_at IS NOT NULL").Count(&revoked)
+	if revoked != 2 {
+		t.Errorf("Expected 2 revoked tokens, got %d", revoked)
+	}
+
+	if _, err := s.Refresh(ctx, "unknown"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Expected ErrInvalidRefreshToken, got %v", err)
+	}
+}
+
+func TestServiceRevoke(t *testing.T) {
+	ctx := context.Background()
+	s, _, user := setupTestService(t)
+
+	pair, _ := s.Issue(ctx, user)
+	other, _ := s.Issue(ctx, user)
+
+	if err := s.Revoke(ctx, pair.RefreshToken); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if _, err := s.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Expected revoked token to be rejected, got %v", err)
+	}
+	if _, err := s.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Errorf("Expected other logins to be unaffected, got %v", err)
+	}
+}
+
+func TestServiceRefreshExpiredAndInactive(t *testing.T) {
+	ctx := context.Background()
+	s, db, user := setupTestService(t)
+
+	pair, _ := s.Issue(ctx, user)
+	db.Model(user).UpdateColumn("active", false)
+	if _, err := s.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Expected ErrInvalidRefreshToken for inactive user, got %v", err)
+	}
+
+	db.Model(user).UpdateColumn("active", true)
+	pair, _ = s.Issue(ctx, user)
+	s.now = func() time.Time { return time.Now().Add(48 * time.Hour) }
+	if _, err := s.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Expected ErrInvalidRefreshToken for expired token, got %v", err)
+	}
+
+	deleted, err := s.DeleteExpired(ctx)
+	if err != nil {
+		t.Fatalf("DeleteExpired() error = %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("Expected 2 expired tokens deleted, got %d", deleted)
+	}
+}
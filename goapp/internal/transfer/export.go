@@ -0,0 +1,366 @@
+// Package transfer moves content in and out of the database: it streams
+// users, posts and comments as JSON Lines or CSV, and imports posts from
+// Markdown files with front matter.
+package transfer
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"goapp/internal/models"
+	"gorm.io/gorm"
+)
+
+// Export formats
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+// Exportable kinds of records
+const (
+	KindUsers    = "users"
+	KindPosts    = "posts"
+	KindComments = "comments"
+)
+
+// batchSize is the number of rows loaded and written at a time
+const batchSize = 500
+
+var (
+	// ErrUnknownFormat is returned for formats other than FormatJSONL and FormatCSV
+	ErrUnknownFormat = errors.New("unknown format, expected jsonl or csv")
+	// ErrUnknownKind is returned for kinds other than users, posts and comments
+	ErrUnknownKind = errors.New("unknown kind, expected users, posts or comments")
+)
+
+// UserRecord is an exported user. Password hashes and other secrets are
+// never exported.
+type UserRecord struct {
+	ID            uint      `json:"id"`
+	Email         string    `json:"email"`
+	Username      string    `json:"username"`
+	FirstName     string    `json:"first_name"`
+	LastName      string    `json:"last_name"`
+	Active        bool      `json:"active"`
+	EmailVerified bool      `json:"email_verified"`
+	Roles         []string  `json:"roles"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// PostRecord is an exported post. Comments are only included in JSON Lines;
+// CSV exports list them separately.
+type PostRecord struct {
+	ID        uint            `json:"id"`
+	Slug      string          `json:"slug"`
+	Title     string          `json:"title"`
+	Summary   string          `json:"summary"`
+	Content   string          `json:"content"`
+	Published bool            `json:"published"`
+	Status    string          `json:"status"`
+	Author    string          `json:"author"` // username
+	Tags      []string        `json:"tags"`   // slugs
+	ViewCount uint            `json:"view_count"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Comments  []CommentRecord `json:"comments"`
+}
+
+// CommentRecord is an exported comment
+type CommentRecord struct {
+	ID        uint      `json:"id"`
+	PostID    uint      `json:"post_id"`
+	ParentID  *uint     `json:"parent_id"`
+	Author    string    `json:"author"` // username
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var (
+	userHeader    = []string{"id", "email", "username", "first_name", "last_name", "active", "email_verified", "roles", "created_at"}
+	postHeader    = []string{"id", "slug", "title", "summary", "content", "published", "status", "author", "tags", "view_count", "created_at", "updated_at"}
+	commentHeader = []string{"id", "post_id", "parent_id", "author", "content", "created_at"}
+)
+
+func (r UserRecord) csvRow() []string {
+	return []string{uintString(r.ID), r.Email, r.Username, r.FirstName, r.LastName,
+		strconv.FormatBool(r.Active), strconv.FormatBool(r.EmailVerified), strings.Join(r.Roles, ";"), timeString(r.CreatedAt)}
+}
+
+func (r PostRecord) csvRow() []string {
+	return []string{uintString(r.ID), r.Slug, r.Title, r.Summary, r.Content, strconv.FormatBool(r.Published), r.Status, r.Author,
+		strings.Join(r.Tags, ";"), uintString(r.ViewCount), timeString(r.CreatedAt), timeString(r.UpdatedAt)}
+}
+
+func (r CommentRecord) csvRow() []string {
+	parent := ""
+	if r.ParentID != nil {
+		parent = uintString(*r.ParentID)
+	}
+	return []string{uintString(r.ID), uintString(r.PostID), parent, r.Author, r.Content, timeString(r.CreatedAt)}
+}
+
+// Exporter streams records out of the database
+type Exporter struct {
+	db *gorm.DB
+}
+
+// NewExporter creates an Exporter reading from db
+func NewExporter(db *gorm.DB) *Exporter {
+	return &Exporter{db: db}
+}
+
+// ContentType returns the media type of format
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// Check reports whether kind and format can be exported
+func Check(kind, format string) error {
+	if format != FormatJSONL && format != FormatCSV {
+		return fmt.Errorf("%q: %w", format, ErrUnknownFormat)
+	}
+	if kind != KindUsers && kind != KindPosts && kind != KindComments {
+		return fmt.Errorf("%q: %w", kind, ErrUnknownKind)
+	}
+	return nil
+}
+
+// Export writes every record of kind to w in format, ordered by ID, and
+// returns how many it wrote. Records are loaded in batches and w is flushed
+// after each batch when it has a Flush method, so large exports stream.
+// Deleted records are left out.
+func (e *Exporter) Export(ctx context.Context, w io.Writer, kind, format string) (int, error) {
+	if err := Check(kind, format); err != nil {
+		return 0, err
+	}
+
+	var count int
+	var err error
+	switch kind {
+	case KindUsers:
+		out := newRecordWriter(w, format, userHeader)
+		if count, err = e.users(ctx, out); err == nil {
+			err = out.flush()
+		}
+	case KindPosts:
+		out := newRecordWriter(w, format, postHeader)
+		if count, err = e.posts(ctx, out, format == FormatJSONL); err == nil {
+			err = out.flush()
+		}
+	default:
+		out := newRecordWriter(w, format, commentHeader)
+		if count, err = e.comments(ctx, out); err == nil {
+			err = out.flush()
+		}
+	}
+	return count, err
+}
+
+func (e *Exporter) users(ctx context.Context, out *recordWriter) (int, error) {
+	var users []models.User
+	count := 0
+	err := e.db.WithContext(ctx).Preload("Roles").FindInBatches(&users, batchSize, func(tx *gorm.DB, _ int) error {
+		for _, user := range users {
+			roles := make([]string, len(user.Roles))
+			for i, role := range user.Roles {
+				roles[i] = role.Name
+			}
+			err := out.write(UserRecord{
+				ID:            user.ID,
+				Email:         user.Email,
+				Username:      user.Username,
+				FirstName:     user.FirstName,
+				LastName:      user.LastName,
+				Active:        user.Active,
+				EmailVerified: user.EmailVerified,
+				Roles:         roles,
+				CreatedAt:     user.CreatedAt,
+			})
+			if err != nil {
+				return err
+			}
+			count++
+		}
+		return out.flush()
+	}).Error
+	if err != nil {
+		return count, fmt.Errorf("failed to export users: %w", err)
+	}
+	return count, nil
+}
+
+func (e *Exporter) posts(ctx context.Context, out *recordWriter, withComments bool) (int, error) {
+	var posts []models.Post
+	count := 0
+	err := e.db.WithContext(ctx).Preload("User", unscoped).Preload("Tags", byName).FindInBatches(&posts, batchSize, func(tx *gorm.DB, _ int) error {
+		comments := map[uint][]CommentRecord{}
+		if withComments {
+			var err error
+			if comments, err = e.commentsOf(ctx, posts); err != nil {
+				return err
+			}
+		}
+		for _, post := range posts {
+			tags := make([]string, len(post.Tags))
+			for i, tag := range post.Tags {
+				tags[i] = tag.Slug
+			}
+			record := PostRecord{
+				ID:        post.ID,
+				Slug:      post.Slug,
+				Title:     post.Title,
+				Summary:   post.Summary,
+				Content:   post.Content,
+				Published: post.Published,
+				Status:    post.Status,
+				Author:    post.User.Username,
+				Tags:      tags,
+				ViewCount: post.ViewCount,
+				CreatedAt: post.CreatedAt,
+				UpdatedAt: post.UpdatedAt,
+				Comments:  comments[post.ID],
+			}
+			if record.Comments == nil {
+				record.Comments = []CommentRecord{}
+			}
+			if err := out.write(record); err != nil {
+				return err
+			}
+			count++
+		}
+		return out.flush()
+	}).Error
+	if err != nil {
+		return count, fmt.Errorf("failed to export posts: %w", err)
+	}
+	return count, nil
+}
+
+// commentsOf loads the comments of posts, grouped by post ID
+func (e *Exporter) commentsOf(ctx context.Context, posts []models.Post) (map[uint][]CommentRecord, error) {
+	ids := make([]uint, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	var comments []models.Comment
+	err := e.db.WithContext(ctx).Preload("User", unscoped).Where("post_id IN ?", ids).Order("id").Find(&comments).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load comments: %w", err)
+	}
+	byPost := map[uint][]CommentRecord{}
+	for i := range comments {
+		byPost[comments[i].PostID] = append(byPost[comments[i].PostID], newCommentRecord(&comments[i]))
+	}
+	return byPost, nil
+}
+
+func (e *Exporter) comments(ctx context.Context, out *recordWriter) (int, error) {
+	var comments []models.Comment
+	count := 0
+	err := e.db.WithContext(ctx).Preload("User", unscoped).FindInBatches(&comments, batchSize, func(tx *gorm.DB, _ int) error {
+		for i := range comments {
+			if err := out.write(newCommentRecord(&comments[i])); err != nil {
+				return err
+			}
+			count++
+		}
+		return out.flush()
+	}).Error
+	if err != nil {
+		return count, fmt.Errorf("failed to export comments: %w", err)
+	}
+	return count, nil
+}
+
+func newCommentRecord(comment *models.Comment) CommentRecord {
+	return CommentRecord{
+		ID:        comment.ID,
+		PostID:    comment.PostID,
+		ParentID:  comment.ParentID,
+		Author:    comment.User.Username,
+		Content:   comment.Content,
+		CreatedAt: comment.CreatedAt,
+	}
+}
+
+// unscoped preloads deleted authors too, so their content keeps its author
+func unscoped(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+func byName(db *gorm.DB) *gorm.DB {
+	return db.Order("name")
+}
+
+// csvRecord is a record that can be written as a CSV row
+type csvRecord interface {
+	csvRow() []string
+}
+
+// recordWriter writes records as JSON Lines or as CSV with a header row
+type recordWriter struct {
+	w      io.Writer
+	json   *json.Encoder
+	csv    *csv.Writer
+	header []string
+}
+
+func newRecordWriter(w io.Writer, format string, header []string) *recordWriter {
+	if format == FormatCSV {
+		return &recordWriter{w: w, csv: csv.NewWriter(w), header: header}
+	}
+	return &recordWriter{w: w, json: json.NewEncoder(w)}
+}
+
+func (rw *recordWriter) write(record csvRecord) error {
+	if rw.json != nil {
+		return rw.json.Encode(record)
+	}
+	if rw.header != nil {
+		if err := rw.csv.Write(rw.header); err != nil {
+			return err
+		}
+		rw.header = nil
+	}
+	return rw.csv.Write(record.csvRow())
+}
+
+// flush pushes the records written so far to the client
+func (rw *recordWriter) flush() error {
+	if rw.csv != nil {
+		if rw.header != nil {
+			// An empty export still gets its header
+			if err := rw.csv.Write(rw.header); err != nil {
+				return err
+			}
+			rw.header = nil
+		}
+		rw.csv.Flush()
+		if err := rw.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if f, ok := rw.w.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+func uintString(n uint) string {
+	return strconv.FormatUint(uint64(n), 10)
+}
+
+func timeString(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
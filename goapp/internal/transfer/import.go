@@ -0,0 +1,263 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"goapp/internal/auth"
+	"goapp/internal/models"
+	"goapp/internal/posts"
+	"goapp/internal/slugs"
+	"goapp/internal/tags"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// Import actions reported per document
+const (
+	ActionCreated = "created"
+	ActionUpdated = "updated"
+	ActionSkipped = "skipped" // the slug exists and upserting is off
+	ActionFailed  = "failed"
+)
+
+// errDryRun rolls back the transaction of a dry run
+var errDryRun = errors.New("dry run")
+
+// Document is a Markdown file to import
+type Document struct {
+	Name string // file name; its base names the post when the front matter has no slug
+	Data []byte
+}
+
+// FrontMatter is the YAML header of a Markdown document, between lines of "---"
+type FrontMatter struct {
+	Title     string     `yaml:"title"` // defaults to the first "# " heading, which is then removed
+	Slug      string     `yaml:"slug"`
+	Summary   string     `yaml:"summary"`
+	Published *bool      `yaml:"published"`
+	Tags      []string   `yaml:"tags"`
+	Author    string     `yaml:"author"` // username or email
+	Date      *time.Time `yaml:"date"`   // creation date of new posts
+}
+
+// ImportOptions controls a Markdown import
+type ImportOptions struct {
+	// AuthorID writes documents without an author field, and is recorded as
+	// the editor of updated posts
+	AuthorID uint
+	// AnyAuthor lets the author field name users other than AuthorID
+	AnyAuthor bool
+	// DryRun validates every document and reports what would happen, then rolls back
+	DryRun bool
+	// Upsert updates posts whose slug already exists instead of skipping them
+	Upsert bool
+}
+
+// ImportResult is the outcome of one document
+type ImportResult struct {
+	File   string `json:"file"`
+	Slug   string `json:"slug,omitempty"`
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport lists the outcome of every document of an import
+type ImportReport struct {
+	DryRun  bool           `json:"dry_run"`
+	Created int            `json:"created"`
+	Updated int            `json:"updated"`
+	Skipped int            `json:"skipped"`
+	Failed  int            `json:"failed"`
+	Results []ImportResult `json:"results"`
+}
+
+// Importer creates and updates posts from Markdown documents
+type Importer struct {
+	db *gorm.DB
+}
+
+// NewImporter creates an Importer writing to db
+func NewImporter(db *gorm.DB) *Importer {
+	return &Importer{db: db}
+}
+
+// ReadMarkdownDir reads the .md and .markdown files below the root of fsys,
+// ordered by path
+func ReadMarkdownDir(fsys fs.FS) ([]Document, error) {
+	var docs []Document
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(path.Ext(name))
+		if entry.IsDir() || (ext != ".md" && ext != ".markdown") {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, Document{Name: name, Data: data})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read markdown files: %w", err)
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Name < docs[j].Name })
+	return docs, nil
+}
+
+// ParseMarkdown splits a document into its front matter and body
+func ParseMarkdown(data []byte) (FrontMatter, string, error) {
+	var meta FrontMatter
+	text := strings.ReplaceAll(string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))), "\r\n", "\n")
+
+	if rest, ok := strings.CutPrefix(text, "---\n"); ok {
+		header, body, found := strings.Cut(rest, "\n---\n")
+		if !found {
+			if header, found = strings.CutSuffix(rest, "\n---"); !found {
+				return meta, "", errors.New("front matter is not closed by ---")
+			}
+		}
+		if err := yaml.Unmarshal([]byte(header), &meta); err != nil {
+			return meta, "", fmt.Errorf("invalid front matter: %w", err)
+		}
+		text = body
+	}
+
+	text = strings.TrimLeft(text, "\n")
+	if meta.Title == "" && strings.HasPrefix(text, "# ") {
+		heading, body, _ := strings.Cut(text, "\n")
+		meta.Title = strings.TrimSpace(strings.TrimPrefix(heading, "# "))
+		text = strings.TrimLeft(body, "\n")
+	}
+	return meta, strings.TrimRight(text, "\n") + "\n", nil
+}
+
+// ImportMarkdown creates a post from each document, or with Upsert updates
+// the post that has its slug, and attaches the tags it lists. The import
+// runs in one transaction with a savepoint per document, so a failed
+// document is reported and skipped without affecting the others.
+func (i *Importer) ImportMarkdown(ctx context.Context, docs []Document, opts ImportOptions) (*ImportReport, error) {
+	report := &ImportReport{DryRun: opts.DryRun, Results: make([]ImportResult, 0, len(docs))}
+
+	err := i.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, doc := range docs {
+			result := ImportResult{File: doc.Name}
+			err := tx.Transaction(func(savepoint *gorm.DB) error {
+				var err error
+				result.Slug, result.Action, err = i.importDocument(ctx, savepoint, doc, opts)
+				return err
+			})
+			if err != nil {
+				result.Action, result.Error = ActionFailed, err.Error()
+			}
+			switch result.Action {
+			case ActionCreated:
+				report.Created++
+			case ActionUpdated:
+				report.Updated++
+			case ActionSkipped:
+				report.Skipped++
+			default:
+				report.Failed++
+			}
+			report.Results = append(report.Results, result)
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, fmt.Errorf("failed to import posts: %w", err)
+	}
+	return report, nil
+}
+
+// importDocument creates or updates the post of doc and returns its slug and the action taken
+func (i *Importer) importDocument(ctx context.Context, tx *gorm.DB, doc Document, opts ImportOptions) (string, string, error) {
+	meta, body, err := ParseMarkdown(doc.Data)
+	if err != nil {
+		return "", "", err
+	}
+	slug := strings.TrimSpace(meta.Slug)
+	if slug == "" {
+		base := path.Base(doc.Name)
+		slug = slugs.Make(strings.TrimSuffix(base, path.Ext(base)))
+	}
+
+	authorID := opts.AuthorID
+	if meta.Author != "" {
+		var author models.User
+		err := tx.Where("email = ? OR username = ?", auth.NormalizeEmail(meta.Author), meta.Author).First(&author).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return slug, "", fmt.Errorf("unknown author %q", meta.Author)
+		}
+		if err != nil {
+			return slug, "", fmt.Errorf("failed to load author: %w", err)
+		}
+		if author.ID != opts.AuthorID && !opts.AnyAuthor {
+			return slug, "", fmt.Errorf("may not import posts by %q", meta.Author)
+		}
+		authorID = author.ID
+	}
+
+	postService := posts.NewService(tx)
+	post, err := postService.GetBySlug(ctx, slug)
+	action := ActionUpdated
+	switch {
+	case errors.Is(err, posts.ErrNotFound):
+		if authorID == 0 {
+			return slug, "", errors.New("author is required")
+		}
+		post, err = postService.Create(ctx, posts.CreateInput{
+			Title:     meta.Title,
+			Slug:      slug,
+			Content:   body,
+			Summary:   meta.Summary,
+			Published: meta.Published != nil && *meta.Published,
+			AuthorID:  authorID,
+		})
+		if err != nil {
+			return slug, "", err
+		}
+		if meta.Date != nil {
+			if err := tx.Model(post).UpdateColumn("created_at", *meta.Date).Error; err != nil {
+				return slug, "", fmt.Errorf("failed to set post date: %w", err)
+			}
+		}
+		action = ActionCreated
+	case err != nil:
+		return slug, "", err
+	case !opts.Upsert:
+		return slug, ActionSkipped, nil
+	default:
+		err = postService.Update(ctx, post, posts.UpdateInput{
+			Title:     &meta.Title,
+			Content:   &body,
+			Summary:   &meta.Summary,
+			Published: meta.Published,
+			EditorID:  opts.AuthorID,
+		})
+		if err != nil {
+			return slug, "", err
+		}
+		if err := tx.Model(post).Association("Tags").Clear(); err != nil {
+			return slug, "", fmt.Errorf("failed to replace tags: %w", err)
+		}
+	}
+
+	if _, err := tags.NewService(tx).Attach(ctx, post, meta.Tags); err != nil {
+		return slug, "", err
+	}
+	return slug, action, nil
+}
@@ -0,0 +1,196 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"goapp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Permission{}, &models.Role{}, &models.User{}, &models.Post{}, &models.PostRevision{}, &models.Tag{}, &models.SlugRedirect{}, &models.Comment{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	for _, name := range []string{"jane", "bob"} {
+		user := &models.User{Email: name + "@example.com", Username: name, PasswordHash: "secret-hash", Active: true}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	return db
+}
+
+func TestParseMarkdown(t *testing.T) {
+	doc := "---\r\ntitle: Hello World\r\npublished: true\r\ntags: [go, web]\r\ndate: 2024-03-01\r\n---\r\n\r\nBody text.\r\n"
+	meta, body, err := ParseMarkdown([]byte(doc))
+	if err != nil {
+		t.Fatalf("ParseMarkdown() error = %v", err)
+	}
+	if meta.Title != "Hello World" || meta.Published == nil || !*meta.Published || len(meta.Tags) != 2 {
+		t.Errorf("Unexpected front matter %+v", meta)
+	}
+	if meta.Date == nil || !meta.Date.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the date to be parsed, got %v", meta.Date)
+	}
+	if body != "Body text.\n" {
+		t.Errorf("Expected the body without front matter, got %q", body)
+	}
+
+	meta, body, err = ParseMarkdown([]byte("# From Heading\n\nText"))
+	if err != nil || meta.Title != "From Heading" || body != "Text\n" {
+		t.Errorf("Expected the title from the heading, got %q, %q, %v", meta.Title, body, err)
+	}
+
+	for _, doc := range []string{"---\ntitle: Open\n", "---\ntags: [unclosed\n---\n"} {
+		if _, _, err := ParseMarkdown([]byte(doc)); err == nil {
+			t.Errorf("ParseMarkdown(%q): expected an error", doc)
+		}
+	}
+}
+
+func TestReadMarkdownDir(t *testing.T) {
+	docs, err := ReadMarkdownDir(fstest.MapFS{
+		"b.md":             {Data: []byte("B")},
+		"notes/a.markdown": {Data: []byte("A")},
+		"image.png":        {Data: []byte("PNG")},
+	})
+	if err != nil {
+		t.Fatalf("ReadMarkdownDir() error = %v", err)
+	}
+	if len(docs) != 2 || docs[0].Name != "b.md" || docs[1].Name != "notes/a.markdown" {
+		t.Errorf("Expected the Markdown files by path, got %+v", docs)
+	}
+}
+
+func TestImportMarkdown(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	importer := NewImporter(db)
+
+	docs := []Document{
+		{Name: "hello-world.md", Data: []byte("---\ntitle: Hello World\npublished: true\ntags: [Go]\ndate: 2020-05-01\n---\nHello.")},
+		{Name: "by-bob.md", Data: []byte("---\ntitle: By Bob\nauthor: bob\n---\nBob's post.")},
+		{Name: "untitled.md", Data: []byte("No title here.")},
+		{Name: "stranger.md", Data: []byte("---\ntitle: Stranger\nauthor: nobody\n---\n")},
+	}
+
+	report, err := importer.ImportMarkdown(ctx, docs, ImportOptions{AuthorID: 1, DryRun: true})
+	if err != nil {
+		t.Fatalf("ImportMarkdown() error = %v", err)
+	}
+	if !report.DryRun || report.Created != 1 || report.Failed != 3 || !strings.Contains(report.Results[1].Error, "may not import posts by") {
+		t.Errorf("Expected only the caller's posts to be created, got %+v", report)
+	}
+	var count int64
+	db.Model(&models.Post{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected a dry run to save nothing, got %d posts", count)
+	}
+
+	report, err = importer.ImportMarkdown(ctx, docs, ImportOptions{AuthorID: 1, AnyAuthor: true})
+	if err != nil {
+		t.Fatalf("ImportMarkdown() error = %v", err)
+	}
+	if report.Created != 2 || report.Results[2].Error == "" || !strings.Contains(report.Results[3].Error, "unknown author") {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	var post models.Post
+	db.Preload("Tags").Where("slug = ?", "hello-world").First(&post)
+	if !post.Published || post.UserID != 1 || len(post.Tags) != 1 || post.Tags[0].Slug != "go" || post.CreatedAt.Year() != 2020 {
+		t.Errorf("Unexpected imported post %+v", post)
+	}
+	var byBob models.Post
+	db.Where("slug = ?", "by-bob").First(&byBob)
+	if byBob.UserID != 2 {
+		t.Errorf("Expected the author from the front matter, got user %d", byBob.UserID)
+	}
+
+	changed := []Document{{Name: "hello-world.md", Data: []byte("---\ntitle: Hello Again\ntags: [web]\n---\nUpdated.")}}
+	if report, _ := importer.ImportMarkdown(ctx, changed, ImportOptions{AuthorID: 1}); report.Skipped != 1 {
+		t.Errorf("Expected existing slugs to be skipped, got %+v", report)
+	}
+	if report, _ := importer.ImportMarkdown(ctx, changed, ImportOptions{AuthorID: 1, Upsert: true}); report.Updated != 1 {
+		t.Errorf("Expected existing slugs to be updated, got %+v", report)
+	}
+	var revision models.PostRevision
+	db.Order("id DESC").First(&revision)
+	if revision.UserID == nil || *revision.UserID != 1 {
+		t.Errorf("Expected the importer to be recorded as the editor, got %v", revision.UserID)
+	}
+	post = models.Post{}
+	db.Preload("Tags").Where("slug = ?", "hello-world").First(&post)
+	if post.Title != "Hello Again" || !post.Published || len(post.Tags) != 1 || post.Tags[0].Slug != "web" {
+		t.Errorf("Expected the post to be updated with its tags replaced, got %+v", post)
+	}
+}
+
+func TestExport(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	if _, err := NewImporter(db).ImportMarkdown(ctx, []Document{
+		{Name: "first.md", Data: []byte("---\ntitle: First\ntags: [go, web]\n---\nLine one,\nline \"two\".")},
+	}, ImportOptions{AuthorID: 1}); err != nil {
+		t.Fatalf("ImportMarkdown() error = %v", err)
+	}
+	for _, content := range []string{"Nice", "Thanks"} {
+		if err := db.Create(&models.Comment{Content: content, UserID: 2, PostID: 1}).Error; err != nil {
+			t.Fatalf("Failed to create comment: %v", err)
+		}
+	}
+	exporter := NewExporter(db)
+
+	var out bytes.Buffer
+	if n, err := exporter.Export(ctx, &out, KindPosts, FormatJSONL); err != nil || n != 1 {
+		t.Fatalf("Export() = %d, %v", n, err)
+	}
+	var post PostRecord
+	if err := json.Unmarshal(out.Bytes(), &post); err != nil {
+		t.Fatalf("Failed to decode post: %v", err)
+	}
+	if post.Author != "jane" || len(post.Tags) != 2 || len(post.Comments) != 2 || post.Comments[1].Author != "bob" {
+		t.Errorf("Expected the post with its tags and comments, got %+v", post)
+	}
+
+	out.Reset()
+	if n, err := exporter.Export(ctx, &out, KindUsers, FormatJSONL); err != nil || n != 2 {
+		t.Fatalf("Export() = %d, %v", n, err)
+	}
+	if strings.Contains(out.String(), "secret-hash") || strings.Count(out.String(), "\n") != 2 {
+		t.Errorf("Expected one line per user without password hashes, got %s", out.String())
+	}
+
+	out.Reset()
+	if _, err := exporter.Export(ctx, &out, KindPosts, FormatCSV); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	if len(rows) != 2 || rows[0][1] != "slug" || rows[1][4] != "Line one,\nline \"two\".\n" || rows[1][6] != "draft" || rows[1][8] != "go;web" {
+		t.Errorf("Unexpected CSV rows %q", rows)
+	}
+
+	out.Reset()
+	db.Where("1 = 1").Delete(&models.Comment{})
+	if _, err := exporter.Export(ctx, &out, KindComments, FormatCSV); err != nil || out.String() != "id,post_id,parent_id,author,content,created_at\n" {
+		t.Errorf("Expected only the header of an empty export, got %q, %v", out.String(), err)
+	}
+
+	if _, err := exporter.Export(ctx, &out, "secrets", FormatCSV); err == nil {
+		t.Error("Expected unknown kinds to be refused")
+	}
+}
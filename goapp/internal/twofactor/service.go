@@ -0,0 +1,319 @@
+package twofactor
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"goapp/internal/config"
+	"goapp/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidCode is returned for a wrong, reused or malformed code
+	ErrInvalidCode = errors.New("invalid two-factor code")
+	// ErrTooManyAttempts is returned while verification is locked after repeated failures
+	ErrTooManyAttempts = errors.New("too many failed two-factor attempts, try again later")
+	// ErrNotEnrolled is returned when the user has no confirmed TOTP secret
+	ErrNotEnrolled = errors.New("two-factor authentication is not enabled")
+	// ErrAlreadyEnrolled is returned when enrolling a user who already has 2FA
+	ErrAlreadyEnrolled = errors.New("two-factor authentication is already enabled")
+)
+
+var recoveryEncoding = base32.NewEncoding("abcdefghijkmnpqrstuvwxyz23456789").WithPadding(base32.NoPadding)
+
+// Enrollment is a TOTP secret waiting for the user to confirm it
+type Enrollment struct {
+	Secret string
+	URI    string // otpauth:// provisioning URI for QR codes
+}
+
+// Service enrolls users in TOTP two-factor authentication and checks codes
+type Service interface {
+	// Begin creates a new unconfirmed secret for user, replacing any earlier unconfirmed one
+	Begin(ctx context.Context, user *models.User) (*Enrollment, error)
+	// Pending returns the unconfirmed enrollment started by Begin
+	Pending(ctx context.Context, user *models.User) (*Enrollment, error)
+	// Confirm enables 2FA when code matches the pending secret and returns new recovery codes
+	Confirm(ctx context.Context, userID uint, code string) ([]string, error)
+	// Verify checks a TOTP or recovery code at sign-in. Failed attempts are
+	// counted, and verification locks after too many.
+	Verify(ctx context.Context, userID uint, code string) error
+	// RegenerateRecoveryCodes replaces the user's recovery codes
+	RegenerateRecoveryCodes(ctx context.Context, userID uint) ([]string, error)
+	// RemainingRecoveryCodes counts the user's unused recovery codes
+	RemainingRecoveryCodes(ctx context.Context, userID uint) (int64, error)
+	// Disable removes the user's secret and recovery codes
+	Disable(ctx context.Context, userID uint) error
+	// Required reports whether the user's roles require 2FA; Roles must be loaded
+	Required(user *models.User) bool
+}
+
+// service implements Service on the two_factors and recovery_codes tables
+type service struct {
+	db  *gorm.DB
+	cfg config.TwoFactorConfig
+	now func() time.Time
+}
+
+// NewService creates a two-factor Service
+func NewService(db *gorm.DB, cfg config.TwoFactorConfig) Service {
+	return &service{db: db, cfg: cfg, now: time.Now}
+}
+
+// Begin implements Service
+func (s *service) Begin(ctx context.Context, user *models.User) (*Enrollment, error) {
+	if user.TwoFactorEnabled {
+		return nil, ErrAlreadyEnrolled
+	}
+	secret, err := NewSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.TwoFactor{UserID: user.ID, Secret: secret}
+	err = s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"secret": secret, "confirmed_at": nil, "last_used_step": 0, "failed_attempts": 0, "locked_until": nil}),
+	}).Create(&record).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to start enrollment: %w", err)
+	}
+	return s.enrollment(user, secret), nil
+}
+
+// Pending implements Service
+func (s *service) Pending(ctx context.Context, user *models.User) (*Enrollment, error) {
+	record, err := s.load(s.db.WithContext(ctx), user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if record.Confirmed() {
+		return nil, ErrAlreadyEnrolled
+	}
+	return s.enrollment(user, record.Secret), nil
+}
+
+// Confirm implements Service
+func (s *service) Confirm(ctx context.Context, userID uint, code string) ([]string, error) {
+	var codes []string
+	var failed bool
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		record, err := s.load(tx, userID)
+		if err != nil {
+			return err
+		}
+		if record.Confirmed() {
+			return ErrAlreadyEnrolled
+		}
+		now := s.now()
+		if record.Locked(now) {
+			return ErrTooManyAttempts
+		}
+
+		matched, ok := Validate(record.Secret, code, now)
+		if !ok {
+			failed = true
+			return s.fail(tx, record, now)
+		}
+		err = tx.Model(record).UpdateColumns(map[string]interface{}{
+			"confirmed_at": now, "last_used_step": matched, "failed_attempts": 0, "locked_until": nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("two_factor_enabled", true).Error; err != nil {
+			return err
+		}
+		codes, err = s.replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err == nil && failed {
+		return nil, ErrInvalidCode
+	}
+	return codes, s.wrap(err, "failed to confirm enrollment")
+}
+
+// Verify implements Service
+func (s *service) Verify(ctx context.Context, userID uint, code string) error {
+	var failed bool
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		record, err := s.load(tx, userID)
+		if err != nil {
+			return err
+		}
+		if !record.Confirmed() {
+			return ErrNotEnrolled
+		}
+		now := s.now()
+		if record.Locked(now) {
+			return ErrTooManyAttempts
+		}
+
+		if matched, ok := Validate(record.Secret, code, now); ok && matched > record.LastUsedStep {
+			// The condition also refuses a code another request used meanwhile
+			result := tx.Model(record).Where("last_used_step < ?", matched).UpdateColumns(map[string]interface{}{
+				"last_used_step": matched, "failed_attempts": 0, "locked_until": nil,
+			})
+			if result.Error != nil || result.RowsAffected == 1 {
+				return result.Error
+			}
+		}
+
+		// Anything that is not a TOTP code may be a recovery code
+		used, err := s.useRecoveryCode(tx, userID, code, now)
+		if err != nil {
+			return err
+		}
+		if used {
+			return tx.Model(record).UpdateColumns(map[string]interface{}{"failed_attempts": 0, "locked_until": nil}).Error
+		}
+		failed = true
+		return s.fail(tx, record, now)
+	})
+	if err == nil && failed {
+		return ErrInvalidCode
+	}
+	return s.wrap(err, "failed to verify code")
+}
+
+// RegenerateRecoveryCodes implements Service
+func (s *service) RegenerateRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	var codes []string
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		record, err := s.load(tx, userID)
+		if err != nil {
+			return err
+		}
+		if !record.Confirmed() {
+			return ErrNotEnrolled
+		}
+		codes, err = s.replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	return codes, s.wrap(err, "failed to regenerate recovery codes")
+}
+
+// RemainingRecoveryCodes implements Service
+func (s *service) RemainingRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return count, nil
+}
+
+// Disable implements Service
+func (s *service) Disable(ctx context.Context, userID uint) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.TwoFactor{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("two_factor_enabled", false).Error
+	})
+	return s.wrap(err, "failed to disable two-factor authentication")
+}
+
+// Required implements Service
+func (s *service) Required(user *models.User) bool {
+	if user == nil || user.ServiceAccount {
+		return false
+	}
+	for _, role := range s.cfg.RequiredRoles {
+		if user.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *service) enrollment(user *models.User, secret string) *Enrollment {
+	return &Enrollment{Secret: secret, URI: ProvisioningURI(s.cfg.Issuer, user.Email, secret)}
+}
+
+// load reads the user's record and locks it until tx ends, so concurrent
+// attempts see each other's used step and failure count
+func (s *service) load(tx *gorm.DB, userID uint) (*models.TwoFactor, error) {
+	var record models.TwoFactor
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// fail records a failed attempt, locking verification after MaxAttempts.
+// The caller reports ErrInvalidCode after the transaction commits, since
+// returning an error from it would roll back the count. record must have
+// been loaded in tx, so that its count is current.
+func (s *service) fail(tx *gorm.DB, record *models.TwoFactor, now time.Time) error {
+	updates := map[string]interface{}{"failed_attempts": gorm.Expr("failed_attempts + 1")}
+	if record.FailedAttempts+1 >= s.cfg.MaxAttempts {
+		updates = map[string]interface{}{"failed_attempts": 0, "locked_until": now.Add(s.cfg.LockDuration)}
+	}
+	return tx.Model(record).UpdateColumns(updates).Error
+}
+
+func (s *service) replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, s.cfg.RecoveryCodes)
+	records := make([]models.RecoveryCode, s.cfg.RecoveryCodes)
+	for i := range codes {
+		buf := make([]byte, 10)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		encoded := recoveryEncoding.EncodeToString(buf)
+		codes[i] = encoded[:8] + "-" + encoded[8:]
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(codes[i])}
+	}
+	if len(records) > 0 {
+		if err := tx.Create(&records).Error; err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+func (s *service) useRecoveryCode(tx *gorm.DB, userID uint, code string, now time.Time) (bool, error) {
+	result := tx.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashRecoveryCode(code)).
+		Update("used_at", now)
+	return result.RowsAffected == 1, result.Error
+}
+
+// hashRecoveryCode ignores case, spaces and dashes so codes can be typed loosely
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *service) wrap(err error, msg string) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrTooManyAttempts), errors.Is(err, ErrNotEnrolled), errors.Is(err, ErrAlreadyEnrolled):
+		return err
+	default:
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+}
@@ -0,0 +1,201 @@
+package twofactor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"goapp/internal/config"
+	"goapp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestService(t *testing.T) (*service, *gorm.DB, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.TwoFactor{}, &models.RecoveryCode{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	user := &models.User{Email: "jane@example.com", Username: "jane", PasswordHash: "hash", Active: true}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	cfg := config.TwoFactorConfig{Issuer: "goapp", RequiredRoles: []string{"admin"}, MaxAttempts: 3, LockDuration: 15 * time.Minute, RecoveryCodes: 4}
+	return NewService(db, cfg).(*service), db, user
+}
+
+// enroll confirms 2FA for user at now and returns the secret and recovery codes
+func enroll(t *testing.T, s *service, user *models.User, now time.Time) (string, []string) {
+	enrollment, err := s.Begin(context.Background(), user)
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	code, _ := Code(enrollment.Secret, now)
+	codes, err := s.Confirm(context.Background(), user.ID, code)
+	if err != nil {
+		t.Fatalf("Confirm() error = %v", err)
+	}
+	return enrollment.Secret, codes
+}
+
+func TestEnrollment(t *testing.T) {
+	ctx := context.Background()
+	s, db, user := setupTestService(t)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	if err := s.Verify(ctx, user.ID, "123456"); !errors.Is(err, ErrNotEnrolled) {
+		t.Errorf("Expected ErrNotEnrolled, got %v", err)
+	}
+
+	enrollment, err := s.Begin(ctx, user)
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if pending, err := s.Pending(ctx, user); err != nil || pending.Secret != enrollment.Secret {
+		t.Errorf("Expected pending enrollment, got %v, %v", pending, err)
+	}
+	if _, err := s.Confirm(ctx, user.ID, "000000"); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Expected ErrInvalidCode, got %v", err)
+	}
+
+	code, _ := Code(enrollment.Secret, now)
+	codes, err := s.Confirm(ctx, user.ID, code)
+	if err != nil {
+		t.Fatalf("Confirm() error = %v", err)
+	}
+	if len(codes) != 4 || len(codes[0]) != 17 {
+		t.Errorf("Expected 4 recovery codes like xxxxxxxx-xxxxxxxx, got %v", codes)
+	}
+
+	var reloaded models.User
+	db.First(&reloaded, user.ID)
+	if !reloaded.TwoFactorEnabled {
+		t.Error("Expected TwoFactorEnabled to be set")
+	}
+	if _, err := s.Begin(ctx, &reloaded); !errors.Is(err, ErrAlreadyEnrolled) {
+		t.Errorf("Expected ErrAlreadyEnrolled, got %v", err)
+	}
+
+	if err := s.Disable(ctx, user.ID); err != nil {
+		t.Fatalf("Disable() error = %v", err)
+	}
+	db.First(&reloaded, user.ID)
+	if remaining, _ := s.RemainingRecoveryCodes(ctx, user.ID); reloaded.TwoFactorEnabled || remaining != 0 {
+		t.Errorf("Expected 2FA to be removed, got enabled=%v codes=%d", reloaded.TwoFactorEnabled, remaining)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	s, _, user := setupTestService(t)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	secret, codes := enroll(t, s, user, now)
+
+	// The code used to confirm cannot be replayed
+	code, _ := Code(secret, now)
+	if err := s.Verify(ctx, user.ID, code); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Expected replayed code to be rejected, got %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	code, _ = Code(secret, now)
+	if err := s.Verify(ctx, user.ID, code); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	// Recovery codes work once, however they are typed
+	if err := s.Verify(ctx, user.ID, " "+codes[0]+" "); err != nil {
+		t.Errorf("Expected recovery code to work, got %v", err)
+	}
+	if err := s.Verify(ctx, user.ID, codes[0]); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Expected used recovery code to be rejected, got %v", err)
+	}
+	if remaining, _ := s.RemainingRecoveryCodes(ctx, user.ID); remaining != 3 {
+		t.Errorf("Expected 3 remaining recovery codes, got %d", remaining)
+	}
+
+	fresh, err := s.RegenerateRecoveryCodes(ctx, user.ID)
+	if err != nil || len(fresh) != 4 {
+		t.Fatalf("RegenerateRecoveryCodes() = %v, %v", fresh, err)
+	}
+	if err := s.Verify(ctx, user.ID, codes[1]); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Expected old recovery code to be replaced, got %v", err)
+	}
+}
+
+func TestVerifyLocksAfterFailures(t *testing.T) {
+	ctx := context.Background()
+	s, _, user := setupTestService(t)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	secret, _ := enroll(t, s, user, now)
+
+	for i := 0; i < 3; i++ {
+		if err := s.Verify(ctx, user.ID, "000000"); !errors.Is(err, ErrInvalidCode) {
+			t.Fatalf("Attempt %d: expected ErrInvalidCode, got %v", i+1, err)
+		}
+	}
+
+	now = now.Add(time.Minute)
+	code, _ := Code(secret, now)
+	if err := s.Verify(ctx, user.ID, code); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("Expected ErrTooManyAttempts while locked, got %v", err)
+	}
+
+	now = now.Add(15 * time.Minute)
+	code, _ = Code(secret, now)
+	if err := s.Verify(ctx, user.ID, code); err != nil {
+		t.Errorf("Expected verification after the lock expires, got %v", err)
+	}
+}
+
+func TestVerifyRefusesConcurrentReplay(t *testing.T) {
+	ctx := context.Background()
+	s, db, user := setupTestService(t)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	secret, _ := enroll(t, s, user, now)
+
+	now = now.Add(time.Minute)
+	code, _ := Code(secret, now)
+	step, _ := Validate(secret, code, now)
+
+	// Another request uses the same code between this one's read and write
+	err := db.Callback().Query().After("gorm:query").Register("test:replay", func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Dest.(*models.TwoFactor); ok {
+			tx.Session(&gorm.Session{NewDB: true}).Model(&models.TwoFactor{}).
+				Where("user_id = ?", user.ID).UpdateColumn("last_used_step", step)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	if err := s.Verify(ctx, user.ID, code); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Expected a code used meanwhile to be rejected, got %v", err)
+	}
+}
+
+func TestRequired(t *testing.T) {
+	s, _, _ := setupTestService(t)
+
+	admin := &models.User{Roles: []models.Role{{Name: "admin"}}}
+	if !s.Required(admin) {
+		t.Error("Expected 2FA to be required for admins")
+	}
+	if s.Required(&models.User{Roles: []models.Role{{Name: "editor"}}}) {
+		t.Error("Expected 2FA to be optional for editors")
+	}
+	admin.ServiceAccount = true
+	if s.Required(admin) {
+		t.Error("Expected service accounts to be exempt")
+	}
+}
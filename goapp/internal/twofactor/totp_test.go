@@ -0,0 +1,56 @@
+package twofactor
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the base32 form of the RFC 6238 test key "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// RFC 6238 Appendix B, truncated to 6 digits
+	tests := map[int64]string{59: "287082", 1111111109: "081804", 1234567890: "005924", 2000000000: "279037"}
+	for unix, want := range tests {
+		got, err := Code(rfcSecret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatalf("Code() error = %v", err)
+		}
+		if got != want {
+			t.Errorf("Code(%d) = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	code, _ := Code(rfcSecret, now)
+
+	if step, ok := Validate(rfcSecret, code, now); !ok || step != now.Unix()/30 {
+		t.Errorf("Expected current code to validate at step %d, got %d %v", now.Unix()/30, step, ok)
+	}
+	if _, ok := Validate(rfcSecret, code, now.Add(30*time.Second)); !ok {
+		t.Error("Expected previous step to be accepted for clock drift")
+	}
+	if _, ok := Validate(rfcSecret, code, now.Add(90*time.Second)); ok {
+		t.Error("Expected code from three steps ago to be rejected")
+	}
+	if _, ok := Validate(rfcSecret, "12345", now); ok {
+		t.Error("Expected short code to be rejected")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("goapp", "jane@example.com", "SECRET")
+	for _, expected := range []string{"otpauth://totp/goapp:jane@example.com?", "secret=SECRET", "issuer=goapp", "digits=6", "period=30"} {
+		if !strings.Contains(uri, expected) {
+			t.Errorf("Expected %q in %s", expected, uri)
+		}
+	}
+
+	secret, err := NewSecret()
+	if err != nil || len(secret) != 32 {
+		t.Errorf("Expected 32 character secret, got %q, %v", secret, err)
+	}
+}
@@ -0,0 +1,335 @@
+// Package users administers user accounts: searching them, deactivating,
+// deleting and restoring them, and letting users edit their own profile.
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"goapp/internal/auth"
+	"goapp/internal/models"
+	"goapp/internal/query"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when no user, deleted or not, has the requested ID
+var ErrNotFound = errors.New("user not found")
+
+// Page size limits for List
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Statuses accepted by ListOptions.Status
+const (
+	StatusActive   = "active"
+	StatusInactive = "inactive"
+	StatusDeleted  = "deleted"
+)
+
+// Schema declares the filter[...], sort and page parameters List accepts
+var Schema = query.Schema{
+	Filters: map[string]query.Field{
+		"username":       {Column: "users.username", Kind: query.String},
+		"email":          {Column: "users.email", Kind: query.String},
+		"first_name":     {Column: "users.first_name", Kind: query.String},
+		"last_name":      {Column: "users.last_name", Kind: query.String},
+		"active":         {Column: "users.active", Kind: query.Bool},
+		"email_verified": {Column: "users.email_verified", Kind: query.Bool},
+		"created_at":     {Column: "users.created_at", Kind: query.Time},
+		"last_login_at":  {Column: "users.last_login_at", Kind: query.Time},
+	},
+	Sorts: map[string]string{
+		"username":      "users.username",
+		"email":         "users.email",
+		"created_at":    "users.created_at",
+		"last_login_at": "users.last_login_at",
+	},
+	DefaultSort: "username",
+	TieBreaker:  "users.id",
+	DefaultSize: DefaultLimit,
+	MaxSize:     MaxLimit,
+	Aliases: map[string]string{
+		"limit":  "page[size]",
+		"offset": "page[offset]",
+	},
+}
+
+// maxNameLength limits first and last names, in characters
+const maxNameLength = 100
+
+// ListOptions selects and paginates users. Zero values mean no filter.
+type ListOptions struct {
+	// Query matches the username, email or full name, case-insensitively
+	Query string
+	// Status is one of the Status constants; deleted users are only listed
+	// with StatusDeleted
+	Status string
+	Role   string // role name
+
+	// Filter adds the conditions and sort of a request parsed with Schema;
+	// without it users are listed by username
+	Filter *query.Query
+
+	// Limit caps the page size at MaxLimit
+	Limit  int
+	Offset int
+}
+
+// Page is one page of List results
+type Page struct {
+	Users []models.User
+	Total int64 // all matching users
+}
+
+// ProfileInput holds the details users may change about themselves
+type ProfileInput struct {
+	Email     string
+	Username  string
+	FirstName string
+	LastName  string
+
+	// CurrentPassword must be given to change Email
+	CurrentPassword string
+}
+
+// Service administers user accounts
+type Service interface {
+	// List returns a page of users, by username unless opts.Filter sorts
+	// them otherwise, with their roles
+	List(ctx context.Context, opts ListOptions) (*Page, error)
+	// Get returns the user with id and its roles, including deleted users
+	Get(ctx context.Context, id uint) (*models.User, error)
+	// SetActive deactivates or reactivates user. Deactivated users are
+	// signed out everywhere and cannot sign in or use their API keys.
+	SetActive(ctx context.Context, user *models.User, active bool) error
+	// Delete soft-deletes user and signs them out everywhere. Their email
+	// and username stay reserved until the user is restored.
+	Delete(ctx context.Context, user *models.User) error
+	// Restore undeletes user
+	Restore(ctx context.Context, user *models.User) error
+	// UpdateProfile validates and saves the user's own details. A new email
+	// requires the current password, and only becomes the user's
+	// PendingEmail until confirmed with auth.AccountService.ConfirmEmailChange.
+	UpdateProfile(ctx context.Context, user *models.User, input ProfileInput) error
+}
+
+// service implements Service on the users table
+type service struct {
+	db     *gorm.DB
+	hasher auth.Hasher
+}
+
+// NewService creates a users Service; hasher checks the current password
+// of users changing their email
+func NewService(db *gorm.DB, hasher auth.Hasher) Service {
+	return &service{db: db, hasher: hasher}
+}
+
+// List implements Service
+func (s *service) List(ctx context.Context, opts ListOptions) (*Page, error) {
+	list := s.db.WithContext(ctx).Model(&models.User{})
+	switch opts.Status {
+	case "":
+	case StatusActive:
+		list = list.Where("users.active = ?", true)
+	case StatusInactive:
+		list = list.Where("users.active = ?", false)
+	case StatusDeleted:
+		list = list.Unscoped().Where("users.deleted_at IS NOT NULL")
+	default:
+		return nil, &auth.ValidationError{Fields: map[string]string{"status": "must be active, inactive or deleted"}}
+	}
+
+	if q := strings.ToLower(strings.TrimSpace(opts.Query)); q != "" {
+		pattern := "%" + query.EscapeLike(q) + "%"
+		list = list.Where(`LOWER(users.username) LIKE ? ESCAPE '\' OR LOWER(users.email) LIKE ? ESCAPE '\'
+			OR LOWER(users.first_name || ' ' || users.last_name) LIKE ? ESCAPE '\'`, pattern, pattern, pattern)
+	}
+	if opts.Role != "" {
+		list = list.Where(`users.id IN (SELECT user_roles.user_id FROM user_roles
+			JOIN roles ON roles.id = user_roles.role_id WHERE roles.name = ?)`, opts.Role)
+	}
+	order := func(db *gorm.DB) *gorm.DB { return db.Order("users.username") }
+	if opts.Filter != nil {
+		list = list.Scopes(opts.Filter.Where)
+		order = opts.Filter.Order
+	}
+
+	page := &Page{}
+	if err := list.Count(&page.Total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	err := list.Preload("Roles", func(db *gorm.DB) *gorm.DB {
+		return db.Order("name")
+	}).Scopes(order).Limit(limit).Offset(opts.Offset).Find(&page.Users).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	return page, nil
+}
+
+// Get implements Service
+func (s *service) Get(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	err := s.db.WithContext(ctx).Unscoped().Preload("Roles", func(db *gorm.DB) *gorm.DB {
+		return db.Order("name")
+	}).First(&user, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	return &user, nil
+}
+
+// SetActive implements Service
+func (s *service) SetActive(ctx context.Context, user *models.User, active bool) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(user).Update("active", active).Error; err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		if active {
+			return nil
+		}
+		return signOut(tx, user)
+	})
+}
+
+// Delete implements Service
+func (s *service) Delete(ctx context.Context, user *models.User) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(user).Error; err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		return signOut(tx, user)
+	})
+}
+
+// Restore implements Service
+func (s *service) Restore(ctx context.Context, user *models.User) error {
+	if err := s.db.WithContext(ctx).Unscoped().Model(user).Update("deleted_at", nil).Error; err != nil {
+		return fmt.Errorf("failed to restore user: %w", err)
+	}
+	user.DeletedAt = gorm.DeletedAt{}
+	return nil
+}
+
+// UpdateProfile implements Service
+func (s *service) UpdateProfile(ctx context.Context, user *models.User, input ProfileInput) error {
+	input.Email = auth.NormalizeEmail(input.Email)
+	input.Username = strings.TrimSpace(input.Username)
+	input.FirstName = strings.TrimSpace(input.FirstName)
+	input.LastName = strings.TrimSpace(input.LastName)
+
+	fields := map[string]string{}
+	auth.ValidateIdentity(input.Email, input.Username, fields)
+	if len([]rune(input.FirstName)) > maxNameLength {
+		fields["first_name"] = fmt.Sprintf("must be at most %d characters", maxNameLength)
+	}
+	if len([]rune(input.LastName)) > maxNameLength {
+		fields["last_name"] = fmt.Sprintf("must be at most %d characters", maxNameLength)
+	}
+	emailChanged := input.Email != user.Email
+	if emailChanged {
+		if err := s.checkPassword(user, input.CurrentPassword, fields); err != nil {
+			return err
+		}
+	}
+	if len(fields) == 0 {
+		if err := s.checkAvailable(ctx, user.ID, input, fields); err != nil {
+			return err
+		}
+	}
+	if len(fields) > 0 {
+		return &auth.ValidationError{Fields: fields}
+	}
+
+	updates := map[string]interface{}{
+		"username":   input.Username,
+		"first_name": input.FirstName,
+		"last_name":  input.LastName,
+	}
+	if emailChanged {
+		// The old address stays until the new one is confirmed
+		updates["pending_email"] = input.Email
+	}
+	if err := s.db.WithContext(ctx).Model(user).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update profile: %w", err)
+	}
+	if emailChanged {
+		user.PendingEmail = input.Email
+	}
+	user.Username = input.Username
+	user.FirstName, user.LastName = input.FirstName, input.LastName
+	return nil
+}
+
+// checkPassword reports a missing or wrong current password in fields
+func (s *service) checkPassword(user *models.User, password string, fields map[string]string) error {
+	switch {
+	case user.PasswordHash == auth.NoPassword:
+		fields["current_password"] = "is required; set a password with a password reset first"
+		return nil
+	case password == "":
+		fields["current_password"] = "is required to change the email address"
+		return nil
+	}
+	ok, err := s.hasher.Verify(user.PasswordHash, password)
+	if err != nil {
+		return fmt.Errorf("failed to check password: %w", err)
+	}
+	if !ok {
+		fields["current_password"] = "is incorrect"
+	}
+	return nil
+}
+
+// checkAvailable reports an email or username used by another account,
+// including deleted ones, which may still be restored
+func (s *service) checkAvailable(ctx context.Context, id uint, input ProfileInput, fields map[string]string) error {
+	var taken []models.User
+	err := s.db.WithContext(ctx).Unscoped().Select("email", "username").
+		Where("(email = ? OR username = ?) AND id <> ?", input.Email, input.Username, id).Find(&taken).Error
+	if err != nil {
+		return fmt.Errorf("failed to check profile: %w", err)
+	}
+	for _, other := range taken {
+		if other.Email == input.Email {
+			fields["email"] = "is already registered"
+		}
+		if strings.EqualFold(other.Username, input.Username) {
+			fields["username"] = "is already taken"
+		}
+	}
+	return nil
+}
+
+// signOut ends every session of user and invalidates its access and
+// refresh tokens, so that none works again if the user is reactivated
+func signOut(tx *gorm.DB, user *models.User) error {
+	if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.Session{}).Error; err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	if err := tx.Unscoped().Model(user).UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error; err != nil {
+		return fmt.Errorf("failed to revoke access tokens: %w", err)
+	}
+	err := tx.Model(&models.RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"goapp/internal/auth"
+	"goapp/internal/authz"
+	"goapp/internal/config"
+	"goapp/internal/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// testHasher hashes quickly for tests
+func testHasher(t *testing.T) auth.Hasher {
+	hasher, err := auth.NewHasher(config.AuthConfig{PasswordHasher: auth.HasherBcrypt, BcryptCost: bcrypt.MinCost})
+	if err != nil {
+		t.Fatalf("NewHasher() error = %v", err)
+	}
+	return hasher
+}
+
+func setupTestService(t *testing.T) (Service, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Role{}, &models.Permission{}, &models.Session{}, &models.RefreshToken{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	if err := authz.NewRoleStore(db).Seed(context.Background()); err != nil {
+		t.Fatalf("Failed to seed roles: %v", err)
+	}
+
+	for _, user := range []*models.User{
+		{Email: "jane@example.com", Username: "jane", FirstName: "Jane", LastName: "Doe", PasswordHash: "hash", Active: true},
+		{Email: "bob@example.com", Username: "bob", FirstName: "Bob", LastName: "Smith", PasswordHash: "hash", Active: true},
+		{Email: "carol@example.org", Username: "carol", PasswordHash: "hash", Active: true},
+	} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	if err := authz.NewRoleStore(db).Grant(context.Background(), 1, authz.RoleEditor); err != nil {
+		t.Fatalf("Failed to grant role: %v", err)
+	}
+	return NewService(db, testHasher(t)), db
+}
+
+func usernames(t *testing.T, s Service, opts ListOptions) []string {
+	t.Helper()
+	page, err := s.List(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	names := make([]string, len(page.Users))
+	for i, user := range page.Users {
+		names[i] = user.Username
+	}
+	return names
+}
+
+func TestList(t *testing.T) {
+	s, _ := setupTestService(t)
+
+	tests := []struct {
+		name string
+		opts ListOptions
+		want string
+	}{
+		{"all by username", ListOptions{}, "bob carol jane"},
+		{"username or email", ListOptions{Query: "EXAMPLE.ORG"}, "carol"},
+		{"full name", ListOptions{Query: "jane doe"}, "jane"},
+		{"wildcards match literally", ListOptions{Query: "%"}, ""},
+		{"role", ListOptions{Role: authz.RoleEditor}, "jane"},
+		{"page", ListOptions{Limit: 1, Offset: 1}, "carol"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := usernames(t, s, tt.opts)
+			if joined := strings.Join(got, " "); joined != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, joined)
+			}
+		})
+	}
+
+	page, _ := s.List(context.Background(), ListOptions{Limit: 1})
+	if page.Total != 3 {
+		t.Errorf("Expected a total of 3, got %d", page.Total)
+	}
+	if page, _ := s.List(context.Background(), ListOptions{Query: "jane"}); len(page.Users[0].Roles) != 1 {
+		t.Error("Expected roles to be loaded")
+	}
+
+	var validationErr *auth.ValidationError
+	if _, err := s.List(context.Background(), ListOptions{Status: "banned"}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected an unknown status to be rejected, got %v", err)
+	}
+}
+
+func TestDeactivateDeleteAndRestore(t *testing.T) {
+	ctx := context.Background()
+	s, db := setupTestService(t)
+	bob, _ := s.Get(ctx, 2)
+	if err := db.Create(&models.Session{UserID: bob.ID, TokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour)}).Error; err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	refresh := &models.RefreshToken{UserID: bob.ID, TokenHash: "hash", FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := db.Create(refresh).Error; err != nil {
+		t.Fatalf("Failed to create refresh token: %v", err)
+	}
+
+	if err := s.SetActive(ctx, bob, false); err != nil {
+		t.Fatalf("SetActive() error = %v", err)
+	}
+	var sessions int64
+	db.Model(&models.Session{}).Where("user_id = ?", bob.ID).Count(&sessions)
+	if sessions != 0 {
+		t.Errorf("Expected deactivation to end sessions, got %d", sessions)
+	}
+	db.First(refresh, refresh.ID)
+	if refresh.RevokedAt == nil {
+		t.Error("Expected deactivation to revoke refresh tokens")
+	}
+	if got := strings.Join(usernames(t, s, ListOptions{Status: StatusInactive}), " "); got != "bob" {
+		t.Errorf("Expected bob to be inactive, got %q", got)
+	}
+
+	if err := s.Delete(ctx, bob); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got := strings.Join(usernames(t, s, ListOptions{}), " "); got != "carol jane" {
+		t.Errorf("Expected deleted users to be hidden, got %q", got)
+	}
+	if got := strings.Join(usernames(t, s, ListOptions{Status: StatusDeleted}), " "); got != "bob" {
+		t.Errorf("Expected bob to be listed as deleted, got %q", got)
+	}
+	deleted, err := s.Get(ctx, bob.ID)
+	if err != nil || !deleted.DeletedAt.Valid {
+		t.Fatalf("Expected to load the deleted user, got %+v, %v", deleted, err)
+	}
+
+	if err := s.Restore(ctx, deleted); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if err := s.SetActive(ctx, deleted, true); err != nil {
+		t.Fatalf("SetActive() error = %v", err)
+	}
+	if got := strings.Join(usernames(t, s, ListOptions{Status: StatusActive}), " "); got != "bob carol jane" {
+		t.Errorf("Expected bob to be back, got %q", got)
+	}
+
+	if _, err := s.Get(ctx, 99); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	ctx := context.Background()
+	s, db := setupTestService(t)
+	jane, _ := s.Get(ctx, 1)
+	hash, _ := testHasher(t).Hash("current password")
+	db.Model(jane).Updates(map[string]interface{}{"email_verified": true, "password_hash": hash})
+
+	var validationErr *auth.ValidationError
+	err := s.UpdateProfile(ctx, jane, ProfileInput{Email: "BOB@example.com", Username: "Bob", CurrentPassword: "current password"})
+	if !errors.As(err, &validationErr) || validationErr.Fields["email"] == "" || validationErr.Fields["username"] == "" {
+		t.Errorf("Expected taken email and username to be rejected, got %v", err)
+	}
+	if err := s.UpdateProfile(ctx, jane, ProfileInput{Email: "jane", Username: "j"}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected invalid input to be rejected, got %v", err)
+	}
+
+	// Keeping the email keeps it verified
+	if err := s.UpdateProfile(ctx, jane, ProfileInput{Email: "jane@example.com", Username: "jane", FirstName: " Janet "}); err != nil {
+		t.Fatalf("UpdateProfile() error = %v", err)
+	}
+	var saved models.User
+	db.First(&saved, jane.ID)
+	if saved.FirstName != "Janet" || !saved.EmailVerified {
+		t.Errorf("Expected the name to change and the email to stay verified, got %+v", saved)
+	}
+
+	// A new email needs the current password and waits for confirmation
+	for password, want := range map[string]string{"": "is required to change the email address", "wrong": "is incorrect"} {
+		err := s.UpdateProfile(ctx, jane, ProfileInput{Email: "janet@example.com", Username: "jane", CurrentPassword: password})
+		if !errors.As(err, &validationErr) || validationErr.Fields["current_password"] != want {
+			t.Errorf("Password %q: expected current_password %q, got %v", password, want, err)
+		}
+	}
+	err = s.UpdateProfile(ctx, jane, ProfileInput{Email: "Janet@Example.com", Username: "janet", CurrentPassword: "current password"})
+	if err != nil {
+		t.Fatalf("UpdateProfile() error = %v", err)
+	}
+	db.First(&saved, jane.ID)
+	if saved.Email != "jane@example.com" || !saved.EmailVerified || saved.PendingEmail != "janet@example.com" || saved.Username != "janet" {
+		t.Errorf("Expected the old email to stay until the new one is confirmed, got %+v", saved)
+	}
+	if jane.Email != "jane@example.com" || jane.PendingEmail != "janet@example.com" {
+		t.Errorf("Expected the pending email on the user, got %+v", jane)
+	}
+}
@@ -0,0 +1,14 @@
+package views
+
+import "regexp"
+
+// botPattern matches the user agents of crawlers, link previews, uptime
+// monitors and HTTP libraries
+var botPattern = regexp.MustCompile(`(?i)bot|crawl|spider|slurp|archiver|facebookexternalhit|embedly|preview|` +
+	`monitor|pingdom|uptime|lighthouse|headless|phantomjs|curl|wget|httpie|python-|go-http-client|okhttp|java/|libwww|scrapy`)
+
+// IsBot reports whether a request with userAgent comes from a program
+// rather than a reader. Requests without a user agent count as bots.
+func IsBot(userAgent string) bool {
+	return userAgent == "" || botPattern.MatchString(userAgent)
+}
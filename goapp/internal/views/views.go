@@ -0,0 +1,219 @@
+// Package views counts post views. Views are buffered in memory and written
+// in batches, so that popular posts are not updated on every view, and are
+// kept both as a total on the post and per day for charts.
+package views
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"goapp/internal/config"
+	"goapp/internal/logging"
+	"goapp/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// key identifies the views of a post on a day
+type key struct {
+	postID uint
+	day    time.Time
+}
+
+// Counter buffers views and adds them to the database in batches. Repeat
+// views by a viewer within the unique window count once; the viewers seen
+// are kept in memory only, as salted hashes, so each instance of the
+// application deduplicates the views it serves. At most MaxViewers are
+// kept; once that many are seen, views by new viewers are counted without
+// being remembered.
+type Counter struct {
+	db     *gorm.DB
+	cfg    config.ViewsConfig
+	logger logging.Logger
+	salt   []byte
+	now    func() time.Time
+	full   chan struct{}
+
+	mu       sync.Mutex
+	pending  map[key]uint
+	buffered int
+	seen     map[string]time.Time // viewer hash to when its view was counted
+}
+
+// NewCounter creates a Counter writing to db
+func NewCounter(db *gorm.DB, cfg config.ViewsConfig, logger logging.Logger) *Counter {
+	salt := make([]byte, 16)
+	_, _ = rand.Read(salt)
+	return &Counter{
+		db:      db,
+		cfg:     cfg,
+		logger:  logger,
+		salt:    salt,
+		now:     time.Now,
+		full:    make(chan struct{}, 1),
+		pending: make(map[key]uint),
+		seen:    make(map[string]time.Time),
+	}
+}
+
+// Record counts a view of post postID by viewer, an identifier such as a
+// session or IP address, and reports whether it was counted. Bots and
+// repeat views are not.
+func (c *Counter) Record(postID uint, viewer, userAgent string) bool {
+	if IsBot(userAgent) {
+		return false
+	}
+	now := c.now().UTC()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cfg.UniqueWindow > 0 && viewer != "" {
+		hash := c.hash(postID, viewer)
+		at, ok := c.seen[hash]
+		if ok && now.Sub(at) < c.cfg.UniqueWindow {
+			return false
+		}
+		if ok || c.cfg.MaxViewers <= 0 || len(c.seen) < c.cfg.MaxViewers {
+			c.seen[hash] = now
+		}
+	}
+	c.pending[key{postID, Day(now)}]++
+	c.buffered++
+	if c.cfg.MaxBuffered > 0 && c.buffered >= c.cfg.MaxBuffered {
+		select {
+		case c.full <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+func (c *Counter) hash(postID uint, viewer string) string {
+	sum := sha256.Sum256([]byte(string(c.salt) + strconv.FormatUint(uint64(postID), 10) + "\x00" + viewer))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Run writes buffered views every flush interval, and sooner when the
+// buffer is full, until ctx is done. Flush once more after stopping it to
+// write the views recorded since.
+func (c *Counter) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-c.full:
+		}
+		if err := c.Flush(ctx); err != nil && ctx.Err() == nil {
+			c.logger.Error("Failed to write post views", zap.Error(err))
+		}
+	}
+}
+
+// Flush writes the buffered views in one transaction: an UPDATE per post
+// and an upsert per post and day. Views that fail to be written stay
+// buffered for the next flush.
+func (c *Counter) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = make(map[key]uint)
+	c.buffered = 0
+	now := c.now().UTC()
+	for hash, at := range c.seen {
+		if now.Sub(at) >= c.cfg.UniqueWindow {
+			delete(c.seen, hash)
+		}
+	}
+	c.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if err := c.write(ctx, pending); err != nil {
+		c.mu.Lock()
+		for k, n := range pending {
+			c.pending[k] += n
+			c.buffered += int(n)
+		}
+		c.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (c *Counter) write(ctx context.Context, pending map[key]uint) error {
+	totals := make(map[uint]uint)
+	rows := make([]models.PostDailyView, 0, len(pending))
+	for k, n := range pending {
+		totals[k.postID] += n
+		rows = append(rows, models.PostDailyView{PostID: k.postID, Day: k.day, Views: n})
+	}
+	// A stable order keeps concurrent flushes from deadlocking on row locks
+	ids := make([]uint, 0, len(totals))
+	for id := range totals {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].PostID != rows[j].PostID {
+			return rows[i].PostID < rows[j].PostID
+		}
+		return rows[i].Day.Before(rows[j].Day)
+	})
+
+	return c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			err := tx.Model(&models.Post{}).Where("id = ?", id).
+				UpdateColumn("view_count", gorm.Expr("view_count + ?", totals[id])).Error
+			if err != nil {
+				return fmt.Errorf("failed to add post views: %w", err)
+			}
+		}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "post_id"}, {Name: "day"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"views": gorm.Expr("post_daily_views.views + excluded.views")}),
+		}).Create(&rows).Error
+		if err != nil {
+			return fmt.Errorf("failed to add daily post views: %w", err)
+		}
+		return nil
+	})
+}
+
+// Daily returns the views of post postID on each day from from to to,
+// inclusive, with zero for days without views
+func (c *Counter) Daily(ctx context.Context, postID uint, from, to time.Time) ([]models.PostDailyView, error) {
+	from, to = Day(from), Day(to)
+	var rows []models.PostDailyView
+	err := c.db.WithContext(ctx).Where("post_id = ? AND day >= ? AND day <= ?", postID, from, to).
+		Order("day").Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load daily post views: %w", err)
+	}
+	counted := make(map[time.Time]uint, len(rows))
+	for _, row := range rows {
+		counted[Day(row.Day)] = row.Views
+	}
+
+	var days []models.PostDailyView
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		days = append(days, models.PostDailyView{PostID: postID, Day: day, Views: counted[day]})
+	}
+	return days, nil
+}
+
+// Day returns the start of the UTC day of t
+func Day(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,179 @@
+package views
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"goapp/internal/config"
+	"goapp/internal/logging"
+	"goapp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const browser = "Mozilla/5.0 (X11; Linux x86_64) Firefox/130.0"
+
+func setupTestCounter(t *testing.T, cfg config.ViewsConfig) (*Counter, *gorm.DB, *time.Time) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Post{}, &models.PostDailyView{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	user := &models.User{Email: "jane@example.com", Username: "jane", PasswordHash: "hash", Active: true}
+	db.Create(user)
+	for _, slug := range []string{"first", "second"} {
+		if err := db.Create(&models.Post{Title: slug, Slug: slug, Published: true, UserID: user.ID}).Error; err != nil {
+			t.Fatalf("Failed to create post: %v", err)
+		}
+	}
+
+	logger, err := logging.New(config.LoggerConfig{Environment: "test", WriteStdout: true})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	counter := NewCounter(db, cfg, logger)
+	now := time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)
+	counter.now = func() time.Time { return now }
+	return counter, db, &now
+}
+
+func viewCount(t *testing.T, db *gorm.DB, id uint) uint {
+	var post models.Post
+	if err := db.First(&post, id).Error; err != nil {
+		t.Fatalf("Failed to load post: %v", err)
+	}
+	return post.ViewCount
+}
+
+func TestIsBot(t *testing.T) {
+	tests := map[string]bool{
+		browser: false,
+		"":      true,
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)": true,
+		"curl/8.5.0":         true,
+		"Go-http-client/1.1": true,
+		"facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)": true,
+	}
+	for userAgent, want := range tests {
+		if got := IsBot(userAgent); got != want {
+			t.Errorf("IsBot(%q) = %v, want %v", userAgent, got, want)
+		}
+	}
+}
+
+func TestCounter(t *testing.T) {
+	ctx := context.Background()
+	counter, db, now := setupTestCounter(t, config.ViewsConfig{UniqueWindow: 30 * time.Minute})
+
+	if !counter.Record(1, "ip:1", browser) || counter.Record(1, "ip:1", browser) {
+		t.Error("Expected a repeat view within the window to count once")
+	}
+	if counter.Record(1, "ip:2", "curl/8.5.0") {
+		t.Error("Expected bots not to count")
+	}
+	counter.Record(1, "ip:2", browser)
+	counter.Record(2, "ip:1", browser)
+	if got := viewCount(t, db, 1); got != 0 {
+		t.Errorf("Expected views to be buffered, got %d", got)
+	}
+
+	if err := counter.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := viewCount(t, db, 1); got != 2 {
+		t.Errorf("Expected 2 views of the first post, got %d", got)
+	}
+
+	// The next day, after the window, the same viewer counts again
+	*now = now.Add(time.Hour)
+	counter.Record(1, "ip:1", browser)
+	counter.Record(1, "ip:3", browser)
+	if err := counter.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := viewCount(t, db, 1); got != 4 {
+		t.Errorf("Expected 4 views of the first post, got %d", got)
+	}
+
+	days, err := counter.Daily(ctx, 1, time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC), time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Daily() error = %v", err)
+	}
+	want := []uint{0, 2, 2}
+	if len(days) != len(want) {
+		t.Fatalf("Expected %d days, got %+v", len(want), days)
+	}
+	for i, day := range days {
+		if day.Views != want[i] || !day.Day.Equal(time.Date(2024, 2, 29+i, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("Expected %d views on day %d, got %+v", want[i], i, day)
+		}
+	}
+}
+
+func TestCounterWithoutWindow(t *testing.T) {
+	counter, db, _ := setupTestCounter(t, config.ViewsConfig{MaxBuffered: 3})
+
+	for i := 0; i < 3; i++ {
+		if !counter.Record(1, "ip:1", browser) {
+			t.Error("Expected every view to count without a unique window")
+		}
+	}
+	select {
+	case <-counter.full:
+	default:
+		t.Error("Expected a full buffer to ask for a flush")
+	}
+	if err := counter.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := viewCount(t, db, 1); got != 3 {
+		t.Errorf("Expected 3 views, got %d", got)
+	}
+}
+
+func TestCounterMaxViewers(t *testing.T) {
+	counter, db, _ := setupTestCounter(t, config.ViewsConfig{UniqueWindow: time.Hour, MaxViewers: 1})
+
+	counter.Record(1, "ip:1", browser)
+	counter.Record(1, "ip:1", browser)
+	// The second viewer is not remembered, so each of its views counts
+	counter.Record(1, "ip:2", browser)
+	counter.Record(1, "ip:2", browser)
+	if len(counter.seen) != 1 {
+		t.Errorf("Expected 1 viewer to be remembered, got %d", len(counter.seen))
+	}
+	if err := counter.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := viewCount(t, db, 1); got != 3 {
+		t.Errorf("Expected 3 views, got %d", got)
+	}
+}
+
+func TestCounterKeepsViewsOnError(t *testing.T) {
+	counter, db, _ := setupTestCounter(t, config.ViewsConfig{})
+	counter.Record(1, "ip:1", browser)
+
+	if err := db.Migrator().DropTable(&models.PostDailyView{}); err != nil {
+		t.Fatalf("Failed to drop table: %v", err)
+	}
+	if err := counter.Flush(context.Background()); err == nil {
+		t.Fatal("Expected Flush() to fail without its table")
+	}
+	if got := viewCount(t, db, 1); got != 0 {
+		t.Errorf("Expected the failed flush to be rolled back, got %d views", got)
+	}
+
+	if err := db.AutoMigrate(&models.PostDailyView{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	if err := counter.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := viewCount(t, db, 1); got != 1 {
+		t.Errorf("Expected the view to be written by the next flush, got %d", got)
+	}
+}
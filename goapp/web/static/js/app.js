@@ -11,10 +11,19 @@ document.addEventListener('alpine:init', () => {
     }));
 });
 
+// Read the CSRF token rendered by BaseLayout
+function getCsrfToken() {
+    const meta = document.querySelector('meta[name="csrf-token"]');
+    return meta ? meta.getAttribute('content') : '';
+}
+
 // HTMX event handlers
 document.body.addEventListener('htmx:configRequest', (event) => {
-    // Add CSRF token if needed
-    // event.detail.headers['X-CSRF-Token'] = getCsrfToken();
+    // Attach the CSRF token to every HTMX request
+    const token = getCsrfToken();
+    if (token) {
+        event.detail.headers['X-CSRF-Token'] = token;
+    }
 });
 
 document.body.addEventListener('htmx:afterSwap', (event) => {
@@ -24,6 +33,14 @@ document.body.addEventListener('htmx:afterSwap', (event) => {
     }
 });
 
+// Forms answer validation errors with 422 and the re-rendered form, which should be swapped in
+document.body.addEventListener('htmx:beforeSwap', (event) => {
+    if (event.detail.xhr.status === 422) {
+        event.detail.shouldSwap = true;
+        event.detail.isError = false;
+    }
+});
+
 // Handle HTMX errors
 document.body.addEventListener('htmx:responseError', (event) => {
     console.error('HTMX request failed:', event.detail);
@@ -64,4 +81,20 @@ document.addEventListener('DOMContentLoaded', () => {
     alpineScript.src = 'https://unpkg.com/alpinejs@3.x.x/dist/cdn.min.js';
     alpineScript.defer = true;
     document.head.appendChild(alpineScript);
-});
\ No newline at end of file
+});
+// Draw QR codes for elements with a data-qr attribute, such as the
+// two-factor enrollment page. Needs qrcode-generator to be loaded.
+function renderQRCodes(root) {
+    if (typeof qrcode === 'undefined') {
+        return;
+    }
+    root.querySelectorAll('[data-qr]').forEach((el) => {
+        const qr = qrcode(0, 'M');
+        qr.addData(el.getAttribute('data-qr'));
+        qr.make();
+        el.innerHTML = qr.createSvgTag({ cellSize: 4, margin: 0, scalable: true });
+    });
+}
+
+document.addEventListener('DOMContentLoaded', () => renderQRCodes(document));
+document.body.addEventListener('htmx:afterSwap', (event) => renderQRCodes(event.detail.target));